@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	cleanupNamespace  string
+	cleanupKubeconfig string
+	cleanupDryRun     bool
+	cleanupJSON       bool
+)
+
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Remove every resource vllm-chill manages in a namespace",
+	Long: `Remove the vLLM pods, services, generated ConfigMaps and pod events vllm-chill
+manages in a namespace, and release its finalizer on the VLLMModels. Run it when
+uninstalling, after the proxy is stopped, so no GPU pod is left running.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		ctx := context.Background()
+		// Same lookup as kubectl: --kubeconfig, $KUBECONFIG, ~/.kube/config, then the in-cluster config
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = cleanupKubeconfig
+		restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return fmt.Errorf("failed to load the cluster config: %w", err)
+		}
+		clientset, err := k8sclient.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create clientset: %w", err)
+		}
+		dynamicClient, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create dynamic client: %w", err)
+		}
+
+		manager := kubernetes.NewK8sManager(clientset, &kubernetes.Config{Namespace: cleanupNamespace})
+		report, err := manager.Cleanup(ctx, cleanupDryRun)
+		if err != nil {
+			return fmt.Errorf("failed to clean up %s: %w", cleanupNamespace, err)
+		}
+		models, err := releaseModelFinalizers(ctx, kubernetes.NewCRDClient(dynamicClient))
+		if err != nil {
+			return err
+		}
+
+		if cleanupJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(struct {
+				*kubernetes.CleanupReport
+				Finalizers []string `json:"finalizers"`
+			}{report, models})
+		}
+
+		action := "Deleted"
+		if cleanupDryRun {
+			action = "Would delete"
+		}
+		for _, group := range []struct {
+			kind  string
+			names []string
+		}{
+			{"pod", report.Pods},
+			{"service", report.Services},
+			{"configmap", report.ConfigMaps},
+			{"event", report.Events},
+		} {
+			for _, name := range group.names {
+				fmt.Printf("%s %s %s/%s\n", action, group.kind, cleanupNamespace, name)
+			}
+		}
+		release := "Released"
+		if cleanupDryRun {
+			release = "Would release"
+		}
+		for _, model := range models {
+			fmt.Printf("%s finalizer on vllmmodel %s\n", release, model)
+		}
+		return nil
+	},
+}
+
+// releaseModelFinalizers removes the namespace's cleanup finalizer from the VLLMModels, so they can
+// be deleted once no proxy handles them, and returns the names of the models it was set on
+func releaseModelFinalizers(ctx context.Context, crdClient *kubernetes.CRDClient) ([]string, error) {
+	models, err := crdClient.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	finalizer := kubernetes.CleanupFinalizer(cleanupNamespace)
+	released := []string{}
+	for _, model := range models {
+		for _, f := range model.Finalizers {
+			if f != finalizer {
+				continue
+			}
+			released = append(released, model.Name)
+			if !cleanupDryRun {
+				if err := crdClient.RemoveFinalizer(ctx, model.Name, finalizer); err != nil {
+					return released, err
+				}
+			}
+		}
+	}
+	return released, nil
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().StringVar(&cleanupNamespace, "namespace", getEnvOrDefault("VLLM_NAMESPACE", "vllm"), "Kubernetes namespace")
+	cleanupCmd.Flags().StringVar(&cleanupKubeconfig, "kubeconfig", "", "Path to the kubeconfig file (default: $KUBECONFIG, ~/.kube/config, then the in-cluster config)")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "List the resources that would be removed without removing them")
+	cleanupCmd.Flags().BoolVar(&cleanupJSON, "json", false, "Print the result as JSON")
+}
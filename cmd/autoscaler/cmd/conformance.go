@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/conformance"
+	"github.com/spf13/cobra"
+)
+
+var (
+	conformanceTarget     string
+	conformanceModel      string
+	conformanceAPIKey     string
+	conformanceAdminToken string
+	conformanceTimeout    time.Duration
+	conformanceJSON       bool
+)
+
+var conformanceCmd = &cobra.Command{
+	Use:   "conformance",
+	Short: "Run end-to-end conformance checks against a live deployment",
+	Long: `Exercise a running vllm-chill deployment and report pass/fail for each check:
+health, status, error shapes, cold start, models listing, chat completions
+(streaming and non-streaming), tool calls and Anthropic messages.
+
+Useful after upgrades and when porting vllm-chill to a new cluster.
+Set --admin-token to scale the model down first and measure a real cold start.`,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		runner := conformance.NewRunner(&conformance.Config{
+			Target:     conformanceTarget,
+			Model:      conformanceModel,
+			APIKey:     conformanceAPIKey,
+			AdminToken: conformanceAdminToken,
+			Timeout:    conformanceTimeout,
+		})
+		report := runner.Run(context.Background())
+
+		if conformanceJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+		} else if err := report.WriteText(os.Stdout); err != nil {
+			return err
+		}
+
+		if report.Failed > 0 {
+			return fmt.Errorf("%d conformance checks failed", report.Failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(conformanceCmd)
+
+	conformanceCmd.Flags().StringVar(&conformanceTarget, "target", getEnvOrDefault("CONFORMANCE_TARGET", "http://localhost:8080"), "Base URL of the vllm-chill deployment")
+	conformanceCmd.Flags().StringVar(&conformanceModel, "model", "", "Model to test (defaults to the active model)")
+	conformanceCmd.Flags().StringVar(&conformanceAPIKey, "api-key", getEnvOrDefault("VLLM_API_KEY", ""), "API key sent on /v1 requests")
+	conformanceCmd.Flags().StringVar(&conformanceAdminToken, "admin-token", getEnvOrDefault("ADMIN_TOKEN", ""), "Admin API token, scales the model down first to check a cold start")
+	conformanceCmd.Flags().DurationVar(&conformanceTimeout, "timeout", 10*time.Minute, "Per-request timeout, must cover a cold start")
+	conformanceCmd.Flags().BoolVar(&conformanceJSON, "json", false, "Print the report as JSON")
+}
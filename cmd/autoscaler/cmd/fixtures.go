@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/fixtures"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fixturesTarget    string
+	fixturesModel     string
+	fixturesAPIKey    string
+	fixturesOutputDir string
+	fixturesScenarios []string
+	fixturesTimeout   time.Duration
+)
+
+var recordFixturesCmd = &cobra.Command{
+	Use:   "record-fixtures",
+	Short: "Record raw SSE transcripts from a live vLLM instance as test fixtures",
+	Long: `Send a curated set of streaming requests (tool calls, parallel tool calls,
+reasoning, long outputs, Anthropic tool use) directly to a vLLM instance and
+save the raw SSE transcripts under test/data, keeping the regression corpus
+in step with vLLM's output formats.
+
+Point --target at vLLM itself (e.g., kubectl port-forward svc/vllm-api 8000:80),
+not at the proxy, so the transcripts are unmodified.`,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		recorder := fixtures.NewRecorder(&fixtures.Config{
+			Target:    fixturesTarget,
+			Model:     fixturesModel,
+			APIKey:    fixturesAPIKey,
+			OutputDir: fixturesOutputDir,
+			Scenarios: fixturesScenarios,
+			Timeout:   fixturesTimeout,
+		})
+		results, err := recorder.Record(context.Background())
+		if err != nil {
+			return err
+		}
+
+		var failed []string
+		for _, result := range results {
+			if result.Error != "" {
+				fmt.Printf("  FAIL  %-22s %s\n", result.Scenario, result.Error)
+				failed = append(failed, result.Scenario)
+				continue
+			}
+			fmt.Printf("  OK    %-22s %s (%d bytes)\n", result.Scenario, result.File, result.Bytes)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("failed to record %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recordFixturesCmd)
+
+	recordFixturesCmd.Flags().StringVar(&fixturesTarget, "target", getEnvOrDefault("FIXTURES_TARGET", "http://localhost:8000"), "Base URL of the vLLM instance")
+	recordFixturesCmd.Flags().StringVar(&fixturesModel, "model", "", "Served model name to record")
+	recordFixturesCmd.Flags().StringVar(&fixturesAPIKey, "api-key", getEnvOrDefault("VLLM_API_KEY", ""), "vLLM API key")
+	recordFixturesCmd.Flags().StringVar(&fixturesOutputDir, "output", "test/data/recorded", "Directory the transcripts are written to")
+	recordFixturesCmd.Flags().StringSliceVar(&fixturesScenarios, "scenario", nil, "Scenarios to record (default all: tool-call, parallel-tool-calls, reasoning, long-output, anthropic-tool-call)")
+	recordFixturesCmd.Flags().DurationVar(&fixturesTimeout, "timeout", 5*time.Minute, "Per-request timeout")
+	_ = recordFixturesCmd.MarkFlagRequired("model")
+}
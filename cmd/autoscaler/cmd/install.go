@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	installNamespace   string
+	installKubeconfig  string
+	installKubeContext string
+	installSampleModel bool
+	installDryRun      bool
+	installJSON        bool
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install the VLLMModel CRD, the proxy's RBAC and Service, and a sample model",
+	Long: `Apply the manifests embedded in the binary: the namespace, the VLLMModel CRD, the
+proxy's ServiceAccount, Role, ClusterRole, their bindings and its Service, moved
+to --namespace, then create a sample VLLMModel unless one with its name exists.
+Objects are applied server-side, so running install again after an upgrade
+updates them.
+
+The API key Secret and the proxy's Deployment are not installed: apply them from
+manifests/kubernetes-with-model-switching.yaml once the key is set.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		// A dry run doesn't reach the cluster
+		var dynamicClient dynamic.Interface
+		if !installDryRun {
+			restConfig, _, err := kubernetes.RESTConfig(installKubeconfig, installKubeContext)
+			if err != nil {
+				return err
+			}
+			if dynamicClient, err = dynamic.NewForConfig(restConfig); err != nil {
+				return fmt.Errorf("failed to create dynamic client: %w", err)
+			}
+		}
+
+		report, err := kubernetes.Install(context.Background(), dynamicClient, installNamespace, installSampleModel, installDryRun)
+		if err != nil {
+			return fmt.Errorf("failed to install into %s: %w", installNamespace, err)
+		}
+
+		if installJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+
+		action := "Applied"
+		if installDryRun {
+			action = "Would apply"
+		}
+		for _, object := range report.Applied {
+			fmt.Printf("%s %s\n", action, object)
+		}
+		if report.SampleModel != "" {
+			create := "Created"
+			if installDryRun {
+				create = "Would create"
+			}
+			fmt.Printf("%s VLLMModel/%s\n", create, report.SampleModel)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(installCmd)
+
+	installCmd.Flags().StringVar(&installNamespace, "namespace", getEnvOrDefault("VLLM_NAMESPACE", "vllm"), "Kubernetes namespace of the proxy")
+	installCmd.Flags().StringVar(&installKubeconfig, "kubeconfig", "", "Path to the kubeconfig used outside a cluster (default: the in-cluster config, then $KUBECONFIG and ~/.kube/config)")
+	installCmd.Flags().StringVar(&installKubeContext, "context", getEnvOrDefault("KUBE_CONTEXT", ""), "kubeconfig context to use (default: the current context)")
+	installCmd.Flags().BoolVar(&installSampleModel, "sample-model", true, "Create the sample VLLMModel (Qwen3 Coder) if it doesn't exist")
+	installCmd.Flags().BoolVar(&installDryRun, "dry-run", false, "List the objects that would be applied without applying them")
+	installCmd.Flags().BoolVar(&installJSON, "json", false, "Print the result as JSON")
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	modelTarget     string
+	modelAdminToken string
+	modelTimeout    time.Duration
+	modelJSON       bool
+)
+
+var modelCmd = &cobra.Command{
+	Use:   "model",
+	Short: "Switch, preload and inspect the model of a running proxy",
+	Long: `Control the model of a running vllm-chill proxy through its admin API, e.g. to
+warm a model before a demo or in a CI pipeline without sending a dummy
+completion request. The admin API must be enabled on the proxy (ADMIN_TOKEN).`,
+}
+
+var modelSwitchCmd = &cobra.Command{
+	Use:   "switch <model-id>",
+	Short: "Make a VLLMModel the active model, it starts on the next request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		response, err := newAdminClient().SwitchModel(context.Background(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to switch to %s: %w", args[0], err)
+		}
+		return printModelResult(response, response.Message)
+	},
+}
+
+var modelPreloadCmd = &cobra.Command{
+	Use:   "preload <model-id>",
+	Short: "Switch to a VLLMModel if needed and wait until it is ready to serve requests",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(_ *cobra.Command, args []string) error {
+		start := time.Now()
+		response, err := newAdminClient().PreloadModel(context.Background(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to preload %s: %w", args[0], err)
+		}
+		return printModelResult(response, fmt.Sprintf("Model %s ready (%s)", response.ActiveModel, time.Since(start).Round(time.Second)))
+	},
+}
+
+var modelStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the active model and whether it is ready",
+	Args:  cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		status, err := newAdminClient().Status(context.Background())
+		if err != nil {
+			return fmt.Errorf("failed to get the proxy status: %w", err)
+		}
+
+		state := "stopped"
+		switch {
+		case status.Ready:
+			state = "ready"
+		case status.GPUDriverNotReady:
+			state = "gpu_driver_not_ready"
+		}
+		return printModelResult(status, fmt.Sprintf("Active model: %s\nState:        %s\nStrategy:     %s", status.ActiveModel, state, status.ScaleStrategy))
+	},
+}
+
+// newAdminClient creates a proxy API client from the model command flags
+func newAdminClient() *client.Client {
+	return client.New(modelTarget, modelAdminToken, modelTimeout)
+}
+
+// printModelResult prints the result as JSON with --json, or the text otherwise
+func printModelResult(result interface{}, text string) error {
+	if !modelJSON {
+		fmt.Println(text)
+		return nil
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func init() {
+	rootCmd.AddCommand(modelCmd)
+	modelCmd.AddCommand(modelSwitchCmd, modelPreloadCmd, modelStatusCmd)
+
+	modelCmd.PersistentFlags().StringVar(&modelTarget, "target", getEnvOrDefault("VLLM_CHILL_TARGET", "http://localhost:8080"), "Base URL of the vllm-chill proxy")
+	modelCmd.PersistentFlags().StringVar(&modelAdminToken, "admin-token", getEnvOrDefault("ADMIN_TOKEN", ""), "Admin API token")
+	modelCmd.PersistentFlags().DurationVar(&modelTimeout, "timeout", 10*time.Minute, "Request timeout, must cover a cold start for preload")
+	modelCmd.PersistentFlags().BoolVar(&modelJSON, "json", false, "Print the result as JSON")
+}
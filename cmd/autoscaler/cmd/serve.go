@@ -7,22 +7,166 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
 	"github.com/efortin/vllm-chill/pkg/proxy"
 	"github.com/efortin/vllm-chill/pkg/rbac"
+	"github.com/efortin/vllm-chill/pkg/tracing"
 	"github.com/spf13/cobra"
 )
 
 var (
+	kubeconfig     string
+	kubeContext    string
+	backend        string
+	localPort      int
+	modelsDir      string
+	dockerHost     string
+	hfCacheDir     string
+	vllmPython     string
+	systemdUnit    string
 	namespace      string
 	deployment     string
 	configMapName  string
 	idleTimeout    string
+	shutdownGrace  string
 	port           string
 	logOutput      bool
 	modelID        string
 	gpuCount       int
 	cpuOffloadGB   int
 	publicEndpoint string
+	xmlFallback    string
+
+	toolArgumentValidation string
+
+	responseAnnotations bool
+
+	dropToolsOnNone    bool
+	requestValidation  bool
+	relabelVLLMMetrics bool
+	rawPassthrough     bool
+	passthroughPaths   string
+
+	targetHost          string
+	targetPort          string
+	embeddingTargetHost string
+	shadowTargetHost    string
+
+	scaleStrategy   string
+	sleepLevel      int
+	deepIdleTimeout string
+
+	maxConcurrentColdStarts int
+
+	coldStartRetryWindow string
+	coldStartRetries     int
+
+	sseHeartbeatInterval string
+	streamIdleTimeout    string
+
+	warmSchedule          string
+	aggressiveSchedule    string
+	aggressiveIdleTimeout string
+	scheduleTimezone      string
+
+	adminToken string
+
+	maxRequestBodySize string
+	maxImageSize       string
+
+	responseCacheSize string
+	responseCacheTTL  string
+
+	pageCacheModels int
+	pageCacheBudget string
+
+	modelPrefetch        bool
+	modelPrefetchTimeout string
+
+	rateLimitRPM       int
+	rateLimitTPM       int
+	rateLimitConfigMap string
+
+	userTracking     bool
+	maxUserLabels    int
+	rateLimitPerUser bool
+
+	apiKeysSecret string
+
+	sessionTokenBudget int
+	sessionTTL         string
+
+	sessionAffinity     bool
+	sessionAffinityIdle string
+	sessionAffinityWait string
+
+	stickySessions bool
+
+	usageConfigMap string
+
+	readyzRequiresVLLM bool
+
+	drainDelay      string
+	drainTimeout    string
+	scaleDownOnExit bool
+
+	modelFinalizers bool
+
+	configDriftAction string
+
+	switchPolicy       string
+	switchAllowlist    string
+	switchDrainTimeout string
+	gpuBusyUtilization int
+
+	modelStatus bool
+
+	leaderElection bool
+	advertiseURL   string
+	leaderToken    string
+
+	notifyWebhooks string
+	notifyEvents   string
+	notifyTemplate string
+
+	auditLog          string
+	auditBodySize     string
+	auditRedactFields string
+
+	tracingEndpoint string
+
+	messageBatches   bool
+	batchConcurrency int
+
+	embeddingModelID  string
+	embeddingGPUCount int
+
+	shadowModelID       string
+	shadowGPUCount      int
+	shadowSamplePercent int
+	shadowLogResponses  bool
+
+	tenantNamespaces  string
+	namespaceSelector string
+
+	federationPeers   string
+	federationTLSCert string
+	federationTLSKey  string
+	federationTLSCA   string
+
+	tlsCert   string
+	tlsKey    string
+	tlsSecret string
+
+	vllmTLSSecret     string
+	vllmTLSCA         string
+	vllmTLSCert       string
+	vllmTLSKey        string
+	vllmTLSServerName string
+
+	vllmH2C bool
+
+	vllmAPIKeySecret string
 )
 
 var serveCmd = &cobra.Command{
@@ -35,29 +179,167 @@ The proxy will:
 - Buffer connections during scale-up (max 2 minutes)
 - Track activity and scale to 0 after idle timeout
 - Proxy all requests to the vLLM backend`,
-	RunE: func(_ *cobra.Command, _ []string) error {
-		// Verify RBAC permissions at startup
-		log.Println("Verifying RBAC permissions...")
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		if err := rbac.VerifyPermissions(ctx, namespace); err != nil {
-			log.Printf("RBAC permission check failed: %v", err)
-			return err
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		// The docker and process backends run vLLM locally, without a cluster to reach
+		if backend != proxy.BackendDocker && backend != proxy.BackendProcess {
+			if err := verifyCluster(cmd); err != nil {
+				return err
+			}
 		}
-		log.Println("RBAC permissions verified successfully")
 
 		config := &proxy.Config{
+			Kubeconfig:     kubeconfig,
+			KubeContext:    kubeContext,
+			Backend:        backend,
+			LocalPort:      localPort,
+			ModelsDir:      modelsDir,
+			DockerHost:     dockerHost,
+			HFCacheDir:     hfCacheDir,
+			VLLMPython:     vllmPython,
+			SystemdUnit:    systemdUnit,
 			Namespace:      namespace,
 			Deployment:     deployment,
 			ConfigMapName:  configMapName,
 			IdleTimeout:    idleTimeout,
+			ShutdownGrace:  shutdownGrace,
 			Port:           port,
 			LogOutput:      logOutput,
 			ModelID:        modelID,
 			GPUCount:       gpuCount,
 			CPUOffloadGB:   cpuOffloadGB,
 			PublicEndpoint: publicEndpoint,
+			XMLFallback:    xmlFallback,
+
+			ToolArgumentValidation: toolArgumentValidation,
+
+			ResponseAnnotations: responseAnnotations,
+
+			DropToolsOnNone:    dropToolsOnNone,
+			RequestValidation:  requestValidation,
+			RelabelVLLMMetrics: relabelVLLMMetrics,
+			RawPassthrough:     rawPassthrough,
+			PassthroughPaths:   passthroughPaths,
+
+			TargetHost:          targetHost,
+			TargetPort:          targetPort,
+			EmbeddingTargetHost: embeddingTargetHost,
+			ShadowTargetHost:    shadowTargetHost,
+
+			ScaleStrategy:   scaleStrategy,
+			SleepLevel:      sleepLevel,
+			DeepIdleTimeout: deepIdleTimeout,
+
+			MaxConcurrentColdStarts: maxConcurrentColdStarts,
+
+			ColdStartRetryWindow: coldStartRetryWindow,
+			ColdStartRetries:     coldStartRetries,
+
+			SSEHeartbeatInterval: sseHeartbeatInterval,
+			StreamIdleTimeout:    streamIdleTimeout,
+
+			WarmSchedule:          warmSchedule,
+			AggressiveSchedule:    aggressiveSchedule,
+			AggressiveIdleTimeout: aggressiveIdleTimeout,
+			ScheduleTimezone:      scheduleTimezone,
+
+			AdminToken: adminToken,
+
+			MaxRequestBodySize: maxRequestBodySize,
+			MaxImageSize:       maxImageSize,
+
+			ResponseCacheSize: responseCacheSize,
+			ResponseCacheTTL:  responseCacheTTL,
+
+			PageCacheModels: pageCacheModels,
+			PageCacheBudget: pageCacheBudget,
+
+			ModelPrefetch:        modelPrefetch,
+			ModelPrefetchTimeout: modelPrefetchTimeout,
+
+			RateLimitRPM:       rateLimitRPM,
+			RateLimitTPM:       rateLimitTPM,
+			RateLimitConfigMap: rateLimitConfigMap,
+
+			UserTracking:     userTracking,
+			MaxUserLabels:    maxUserLabels,
+			RateLimitPerUser: rateLimitPerUser,
+
+			APIKeysSecret: apiKeysSecret,
+
+			SessionTokenBudget: sessionTokenBudget,
+			SessionTTL:         sessionTTL,
+
+			SessionAffinity:     sessionAffinity,
+			SessionAffinityIdle: sessionAffinityIdle,
+			SessionAffinityWait: sessionAffinityWait,
+
+			StickySessions: stickySessions,
+
+			UsageConfigMap: usageConfigMap,
+
+			ReadyzRequiresVLLM: readyzRequiresVLLM,
+
+			DrainDelay:      drainDelay,
+			DrainTimeout:    drainTimeout,
+			ScaleDownOnExit: scaleDownOnExit,
+
+			ModelFinalizers: modelFinalizers,
+
+			ConfigDriftAction: configDriftAction,
+
+			SwitchPolicy:       switchPolicy,
+			SwitchAllowlist:    switchAllowlist,
+			SwitchDrainTimeout: switchDrainTimeout,
+			GPUBusyUtilization: gpuBusyUtilization,
+
+			ModelStatus: modelStatus,
+
+			LeaderElection: leaderElection,
+			AdvertiseURL:   advertiseURL,
+			LeaderToken:    leaderToken,
+
+			NotifyWebhooks: notifyWebhooks,
+			NotifyEvents:   notifyEvents,
+			NotifyTemplate: notifyTemplate,
+
+			AuditLog:          auditLog,
+			AuditBodySize:     auditBodySize,
+			AuditRedactFields: auditRedactFields,
+
+			TracingEndpoint: tracingEndpoint,
+
+			MessageBatches:   messageBatches,
+			BatchConcurrency: batchConcurrency,
+
+			EmbeddingModelID:  embeddingModelID,
+			EmbeddingGPUCount: embeddingGPUCount,
+
+			ShadowModelID:       shadowModelID,
+			ShadowGPUCount:      shadowGPUCount,
+			ShadowSamplePercent: shadowSamplePercent,
+			ShadowLogResponses:  shadowLogResponses,
+
+			Namespaces:        tenantNamespaces,
+			NamespaceSelector: namespaceSelector,
+
+			FederationPeers:   federationPeers,
+			FederationTLSCert: federationTLSCert,
+			FederationTLSKey:  federationTLSKey,
+			FederationTLSCA:   federationTLSCA,
+
+			TLSCert:   tlsCert,
+			TLSKey:    tlsKey,
+			TLSSecret: tlsSecret,
+
+			VLLMTLSSecret:     vllmTLSSecret,
+			VLLMTLSCA:         vllmTLSCA,
+			VLLMTLSCert:       vllmTLSCert,
+			VLLMTLSKey:        vllmTLSKey,
+			VLLMTLSServerName: vllmTLSServerName,
+
+			VLLMH2C: vllmH2C,
+
+			VLLMAPIKeySecret: vllmAPIKeySecret,
 		}
 
 		scaler, err := proxy.NewAutoScaler(config)
@@ -68,34 +350,322 @@ The proxy will:
 		// Set version information
 		scaler.SetVersion(version, commit, buildDate)
 
+		if tracingEndpoint != "" {
+			shutdownTracing, err := tracing.Setup(context.Background(), tracingEndpoint, version)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				if err := shutdownTracing(ctx); err != nil {
+					log.Printf("Failed to flush the traces: %v", err)
+				}
+			}()
+		}
+
 		log.Printf("Starting vLLM AutoScaler on :%s", port)
-		targetHost := getEnvOrDefault("VLLM_TARGET", "vllm-api")
-		targetPort := getEnvOrDefault("VLLM_PORT", "80")
-		log.Printf("   Target: http://%s:%s", targetHost, targetPort)
+		switch backend {
+		case proxy.BackendDocker:
+			log.Printf("   Backend: docker (container %s on 127.0.0.1:%d, models from %s)", deployment, config.LocalPort, modelsDir)
+		case proxy.BackendProcess:
+			log.Printf("   Backend: process (%s on 127.0.0.1:%d, models from %s)", config.VLLMPython, config.LocalPort, modelsDir)
+			if systemdUnit != "" {
+				log.Printf("   Systemd unit: %s", systemdUnit)
+			}
+		default:
+			log.Printf("   Target: http://%s:%s", config.TargetHost, config.TargetPort)
+		}
+		if kubeconfig != "" {
+			log.Printf("   Kubeconfig: %s", kubeconfig)
+		}
+		if kubeContext != "" {
+			log.Printf("   Kubeconfig context: %s", kubeContext)
+		}
 		log.Printf("   Deployment: %s/%s", namespace, deployment)
 		log.Printf("   ConfigMap: %s/%s", namespace, configMapName)
 		log.Printf("   Model ID: %s", modelID)
 		log.Printf("   Idle timeout: %s", idleTimeout)
+		log.Printf("   Shutdown grace period: %s", shutdownGrace)
+		log.Printf("   Drain delay: %s, drain timeout: %s", drainDelay, drainTimeout)
+		if readyzRequiresVLLM {
+			log.Printf("   /readyz: not ready while vLLM isn't")
+		}
+		if scaleDownOnExit {
+			log.Printf("   vLLM released on exit")
+		}
+		if modelFinalizers {
+			log.Printf("   VLLMModel finalizers: enabled")
+		}
+		log.Printf("   Config drift action: %s", configDriftAction)
+		log.Printf("   Model switch policy: %s", switchPolicy)
+		if switchPolicy == proxy.SwitchPolicyAllowlist {
+			log.Printf("   Model switch allowlist: %s", switchAllowlist)
+		}
+		log.Printf("   Model switch drain timeout: %s", switchDrainTimeout)
+		if gpuBusyUtilization > 0 {
+			log.Printf("   Scale-down deferred while GPU utilization >= %d%%", gpuBusyUtilization)
+		}
+		if modelStatus {
+			log.Printf("   VLLMModel status: written")
+		}
+		if leaderElection {
+			log.Printf("   Leader election: enabled, advertised as %s", advertiseURL)
+		}
+		if notifyWebhooks != "" {
+			events := notifyEvents
+			if events == "" {
+				events = "all"
+			}
+			log.Printf("   Notifications: %s events", events)
+		}
+		if auditLog != "" {
+			bodies := "not recorded"
+			if auditBodySize != "" && auditBodySize != "0" {
+				bodies = "up to " + auditBodySize
+			}
+			log.Printf("   Audit log: enabled, bodies %s", bodies)
+		}
+		if tracingEndpoint != "" {
+			log.Printf("   Tracing: exported to %s", tracingEndpoint)
+		}
+		if messageBatches {
+			log.Printf("   Message batches: %d requests at once", batchConcurrency)
+		}
+		log.Printf("   Scale strategy: %s", scaleStrategy)
+		if scaleStrategy == proxy.ScaleStrategyVLLMSleep {
+			log.Printf("   Sleep level: %d, deep idle timeout: %s", sleepLevel, deepIdleTimeout)
+		}
+		if maxConcurrentColdStarts > 0 {
+			log.Printf("   Concurrent cold starts: %d", maxConcurrentColdStarts)
+		}
+		if coldStartRetries > 0 && coldStartRetryWindow != "0" && coldStartRetryWindow != "" {
+			log.Printf("   Cold start retries: %d within %s of startup", coldStartRetries, coldStartRetryWindow)
+		}
+		if sseHeartbeatInterval != "0" && sseHeartbeatInterval != "" {
+			log.Printf("   SSE heartbeats during startup: every %s", sseHeartbeatInterval)
+		}
+		if streamIdleTimeout != "0" && streamIdleTimeout != "" {
+			log.Printf("   Streams cut after %s without data", streamIdleTimeout)
+		}
+		if warmSchedule != "" {
+			log.Printf("   Warm-up windows: %s (%s)", warmSchedule, scheduleTimezone)
+		}
+		if aggressiveSchedule != "" {
+			log.Printf("   Aggressive idle windows: %s (%s, idle timeout %s)", aggressiveSchedule, scheduleTimezone, aggressiveIdleTimeout)
+		}
 		if logOutput {
 			log.Printf("   Output logging: enabled")
 		}
+		log.Printf("   XML tool call fallback: %s", xmlFallback)
+		log.Printf("   Tool argument validation: %s", toolArgumentValidation)
+		log.Printf("   Max request body size: %s", maxRequestBodySize)
+		log.Printf("   Max image size: %s", maxImageSize)
+		if responseCacheSize != "" && responseCacheSize != "0" {
+			log.Printf("   Response cache: %s of deterministic responses, kept %s", responseCacheSize, responseCacheTTL)
+		}
+		if pageCacheModels > 0 {
+			log.Printf("   Page cache warm pool: %d most recently used models (budget %s)", pageCacheModels, pageCacheBudget)
+		}
+		if modelPrefetch {
+			log.Printf("   Model prefetch: missing models downloaded by a Job first (timeout %s)", modelPrefetchTimeout)
+		}
+		if rateLimitRPM > 0 || rateLimitTPM > 0 || rateLimitConfigMap != "" {
+			log.Printf("   Rate limits: %d requests/min, %d tokens/min per API key (overrides: %s)", rateLimitRPM, rateLimitTPM, rateLimitConfigMap)
+		}
+		if userTracking {
+			log.Printf("   User tracking: %d metrics labels (per-user rate limits: %t)", maxUserLabels, rateLimitPerUser)
+		}
+		if sessionTokenBudget > 0 {
+			log.Printf("   Session token budget: %d tokens (forgotten after %s idle)", sessionTokenBudget, sessionTTL)
+		}
+		if sessionAffinity {
+			log.Printf("   Session affinity: enabled (sessions idle after %s, switches wait up to %s)", sessionAffinityIdle, sessionAffinityWait)
+		}
+		if stickySessions {
+			log.Printf("   Sticky sessions: enabled")
+		}
+		if usageConfigMap != "" {
+			log.Printf("   Usage accounting: persisted to ConfigMap %s/%s", namespace, usageConfigMap)
+		}
+		if apiKeysSecret != "" {
+			log.Printf("   API keys: validated against Secret %s/%s", namespace, apiKeysSecret)
+		}
+		if responseAnnotations {
+			log.Printf("   Response annotations: enabled")
+		}
+		if dropToolsOnNone {
+			log.Printf("   Tools dropped from requests with tool_choice none")
+		}
+		if !requestValidation {
+			log.Printf("   Request validation: disabled, invalid requests are left to vLLM")
+		}
+		if !relabelVLLMMetrics {
+			log.Printf("   vLLM metrics bundled in /proxy/metrics under their own names")
+		}
+		if rawPassthrough {
+			log.Printf("   Raw passthrough: enabled, responses are proxied untouched")
+		}
+		if adminToken != "" {
+			log.Printf("   Admin API: enabled on /proxy/admin")
+		}
+		if embeddingModelID != "" {
+			log.Printf("   Embedding model ID: %s", embeddingModelID)
+		}
+		if shadowModelID != "" {
+			log.Printf("   Shadow model ID: %s (%d%% of the completion requests mirrored)", shadowModelID, shadowSamplePercent)
+		}
+		if tenantNamespaces != "" || namespaceSelector != "" {
+			log.Printf("   Tenant namespaces: %s (selector: %q)", tenantNamespaces, namespaceSelector)
+		}
+		if federationPeers != "" {
+			log.Printf("   Federation peers: %s", federationPeers)
+		}
 
 		return scaler.Run()
 	},
 }
 
+// verifyCluster loads the config to reach the API server, applies the namespace of the kubeconfig
+// context and verifies the RBAC permissions of the proxy
+func verifyCluster(cmd *cobra.Command) error {
+	// In-cluster config, or the kubeconfig when running outside the cluster
+	restConfig, contextNamespace, err := kubernetes.RESTConfig(kubeconfig, kubeContext)
+	if err != nil {
+		return err
+	}
+	// Outside a cluster, the namespace of the kubeconfig context applies unless one is given
+	if contextNamespace != "" && !cmd.Flags().Changed("namespace") && os.Getenv("VLLM_NAMESPACE") == "" {
+		namespace = contextNamespace
+	}
+
+	// Verify RBAC permissions at startup
+	log.Println("Verifying RBAC permissions...")
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := rbac.VerifyPermissions(ctx, restConfig, namespace); err != nil {
+		log.Printf("RBAC permission check failed: %v", err)
+		return err
+	}
+	log.Println("RBAC permissions verified successfully")
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
 
+	serveCmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "Path to the kubeconfig used outside a cluster (default: the in-cluster config, then $KUBECONFIG and ~/.kube/config)")
+	serveCmd.Flags().StringVar(&kubeContext, "context", getEnvOrDefault("KUBE_CONTEXT", ""), "kubeconfig context to use (default: the current context)")
+	serveCmd.Flags().StringVar(&backend, "backend", getEnvOrDefault("BACKEND", proxy.BackendKubernetes), "Backend running vLLM: kubernetes, docker for a local Docker or Podman container, or process for vLLM installed on the host")
+	serveCmd.Flags().IntVar(&localPort, "local-port", getEnvOrDefaultInt("LOCAL_PORT", 8000), "docker and process backends: port vLLM listens on, on 127.0.0.1")
+	serveCmd.Flags().StringVar(&modelsDir, "models-dir", getEnvOrDefault("MODELS_DIR", ""), "docker and process backends: directory of the VLLMModel manifests, read instead of the CRDs")
+	serveCmd.Flags().StringVar(&dockerHost, "docker-host", getEnvOrDefault("DOCKER_HOST", ""), "docker backend: Engine API endpoint, e.g. unix:///run/podman/podman.sock for Podman (default: unix:///var/run/docker.sock)")
+	serveCmd.Flags().StringVar(&hfCacheDir, "hf-cache-dir", getEnvOrDefault("HF_CACHE_DIR", ""), "docker backend: Hugging Face cache mounted in the container (default: ~/.cache/huggingface)")
+	serveCmd.Flags().StringVar(&vllmPython, "vllm-python", getEnvOrDefault("VLLM_PYTHON", "python3"), "process backend: Python interpreter vLLM is installed for, e.g. a virtualenv's bin/python")
+	serveCmd.Flags().StringVar(&systemdUnit, "systemd-unit", getEnvOrDefault("SYSTEMD_UNIT", ""), "process backend: run vLLM as this transient systemd user unit, surviving proxy restarts (default: a child process of the proxy)")
 	serveCmd.Flags().StringVar(&namespace, "namespace", getEnvOrDefault("VLLM_NAMESPACE", "vllm"), "Kubernetes namespace")
 	serveCmd.Flags().StringVar(&deployment, "deployment", getEnvOrDefault("VLLM_DEPLOYMENT", "vllm"), "Deployment name")
 	serveCmd.Flags().StringVar(&configMapName, "configmap", getEnvOrDefault("VLLM_CONFIGMAP", "vllm-config"), "ConfigMap name for model configuration")
 	serveCmd.Flags().StringVar(&idleTimeout, "idle-timeout", getEnvOrDefault("IDLE_TIMEOUT", "5m"), "Idle timeout before scaling to 0")
+	serveCmd.Flags().StringVar(&shutdownGrace, "shutdown-grace-period", getEnvOrDefault("SHUTDOWN_GRACE_PERIOD", "30s"), "Time vLLM gets to finish in-flight requests on pod deletion before being force-killed (0 = immediate)")
+	serveCmd.Flags().BoolVar(&readyzRequiresVLLM, "readyz-requires-vllm", getEnvOrDefault("READYZ_REQUIRES_VLLM", "false") == "true", "Report /readyz not ready while vLLM isn't, for load balancers that should only route to a warm backend")
+	serveCmd.Flags().StringVar(&drainDelay, "drain-delay", getEnvOrDefault("DRAIN_DELAY", "5s"), "Time /readyz reports not ready after SIGTERM before the proxy stops accepting connections, so a replacement pod takes over first")
+	serveCmd.Flags().StringVar(&drainTimeout, "drain-timeout", getEnvOrDefault("DRAIN_TIMEOUT", "60s"), "Time in-flight requests, SSE streams included, get to complete after the listener closes before being cut (0 = wait indefinitely)")
+	serveCmd.Flags().BoolVar(&scaleDownOnExit, "scale-down-on-exit", getEnvOrDefault("SCALE_DOWN_ON_EXIT", "false") == "true", "Release vLLM with the scale strategy when the proxy exits, instead of leaving it running for the next proxy")
+	serveCmd.Flags().BoolVar(&modelFinalizers, "model-finalizers", getEnvOrDefault("MODEL_FINALIZERS", "false") == "true", "Set a finalizer on the VLLMModels, so deleting the active model's VLLMModel removes its pods instead of leaving them running")
+	serveCmd.Flags().StringVar(&switchPolicy, "switch-policy", getEnvOrDefault("SWITCH_POLICY", "auto"), "Whether requests for another model switch the active model: auto, manual (only the admin and model APIs switch) or allowlist")
+	serveCmd.Flags().StringVar(&switchAllowlist, "switch-allowlist", getEnvOrDefault("SWITCH_ALLOWLIST", ""), "Comma-separated served model names requests may switch to with the allowlist switch policy")
+	serveCmd.Flags().StringVar(&switchDrainTimeout, "switch-drain-timeout", getEnvOrDefault("SWITCH_DRAIN_TIMEOUT", "60s"), "Time a model switch waits for the in-flight requests of the current model, SSE streams included, before deleting its pod (0 = switch at once)")
+	serveCmd.Flags().IntVar(&gpuBusyUtilization, "gpu-busy-utilization", getEnvOrDefaultInt("GPU_BUSY_UTILIZATION", 0), "GPU utilization percent at or above which an idle scale-down is deferred, read through NVML on the proxy's node (0 = HTTP activity only)")
+	serveCmd.Flags().StringVar(&configDriftAction, "config-drift-action", getEnvOrDefault("CONFIG_DRIFT_ACTION", "restart"), "What a drift of the vLLM pod from its VLLMModel triggers: restart, or warn (log and report vllm_chill_config_drift only)")
+	serveCmd.Flags().BoolVar(&modelStatus, "model-status", getEnvOrDefault("MODEL_STATUS", "true") == "true", "Write the active model's phase, conditions, pod and last activity to its VLLMModel status")
+	serveCmd.Flags().BoolVar(&leaderElection, "leader-election", getEnvOrDefault("LEADER_ELECTION", "false") == "true", "Elect a leader among the proxy replicas with a Lease: only the leader creates and deletes pods, the others ask it to scale up")
+	serveCmd.Flags().StringVar(&advertiseURL, "advertise-url", getEnvOrDefault("ADVERTISE_URL", ""), "URL the other proxy replicas reach this one at when it leads (e.g., http://$(POD_IP):8080), required with --leader-election")
+	serveCmd.Flags().StringVar(&leaderToken, "leader-token", getEnvOrDefault("LEADER_TOKEN", ""), "Bearer token the proxy replicas authenticate their scale-ups to the leader with (default: --admin-token)")
+	serveCmd.Flags().StringVar(&notifyWebhooks, "notify-webhooks", getEnvOrDefault("NOTIFY_WEBHOOKS", ""), "Comma-separated webhooks notified of scale events and failures, as format=url with format slack, discord or generic (default), e.g. slack=https://hooks.slack.com/services/...")
+	serveCmd.Flags().StringVar(&notifyEvents, "notify-events", getEnvOrDefault("NOTIFY_EVENTS", ""), "Comma-separated events notified: scale_up, scale_down, model_switch, startup_failure, config_drift_restart (empty = all)")
+	serveCmd.Flags().StringVar(&notifyTemplate, "notify-template", getEnvOrDefault("NOTIFY_TEMPLATE", ""), "Go template of the notification text, with .Type, .Model, .Message and .Time (default \"[vllm-chill] {{.Model}}: {{.Message}}\")")
+	serveCmd.Flags().StringVar(&auditLog, "audit-log", getEnvOrDefault("AUDIT_LOG", ""), "File the /v1 requests are recorded to as JSON lines, - for stdout, or http(s) URL the records are posted to (empty disables the audit log)")
+	serveCmd.Flags().StringVar(&auditBodySize, "audit-body-size", getEnvOrDefault("AUDIT_BODY_SIZE", ""), "Bytes of the request and response bodies kept in each audit record (e.g., 4Ki, empty or 0 = bodies not recorded)")
+	serveCmd.Flags().StringVar(&auditRedactFields, "audit-redact-fields", getEnvOrDefault("AUDIT_REDACT_FIELDS", ""), "Comma-separated JSON fields whose values are redacted in the recorded bodies, at any depth (e.g., content,text,system)")
+	serveCmd.Flags().StringVar(&tracingEndpoint, "tracing-endpoint", getEnvOrDefault("TRACING_ENDPOINT", ""), "OTLP/HTTP endpoint the OpenTelemetry traces of the requests, model switches and scale-ups are exported to (e.g., http://otel-collector:4318, empty disables tracing)")
+	serveCmd.Flags().BoolVar(&messageBatches, "message-batches", getEnvOrDefault("MESSAGE_BATCHES", "false") == "true", "Serve the Anthropic Message Batches API on /v1/messages/batches, running the batched requests through the proxy")
+	serveCmd.Flags().IntVar(&batchConcurrency, "batch-concurrency", getEnvOrDefaultInt("BATCH_CONCURRENCY", 1), "Batched requests running at once across the batches of all clients")
+	serveCmd.Flags().StringVar(&scaleStrategy, "scale-strategy", getEnvOrDefault("SCALE_STRATEGY", "delete"), "How vLLM is released when idle: delete (frees the GPU), pause-image (keeps the pod scheduled for faster restarts) or vllm-sleep (vLLM sleep mode, fastest wake up)")
+	serveCmd.Flags().IntVar(&sleepLevel, "sleep-level", getEnvOrDefaultInt("SLEEP_LEVEL", 1), "vLLM sleep level for the vllm-sleep strategy (1 offloads weights to CPU RAM, 2 discards them)")
+	serveCmd.Flags().StringVar(&deepIdleTimeout, "deep-idle-timeout", getEnvOrDefault("DEEP_IDLE_TIMEOUT", "1h"), "vllm-sleep strategy: idle time after which the sleeping pod is deleted to release the GPU (empty disables)")
+	serveCmd.Flags().IntVar(&maxConcurrentColdStarts, "max-concurrent-cold-starts", getEnvOrDefaultInt("MAX_CONCURRENT_COLD_STARTS", 1), "Models allowed to start at once, the chat and embedding models included; the others queue in turn (0 = unlimited)")
+	serveCmd.Flags().StringVar(&coldStartRetryWindow, "cold-start-retry-window", getEnvOrDefault("COLD_START_RETRY_WINDOW", "30s"), "Time after vLLM became ready during which completions failing to connect (or getting a 502/503) are retried with their buffered body (0 disables)")
+	serveCmd.Flags().IntVar(&coldStartRetries, "cold-start-retries", getEnvOrDefaultInt("COLD_START_RETRIES", 3), "Retries per completion within the cold start retry window, with exponential backoff from 500ms")
+	serveCmd.Flags().StringVar(&sseHeartbeatInterval, "sse-heartbeat-interval", getEnvOrDefault("SSE_HEARTBEAT_INTERVAL", "10s"), "Interval of the heartbeats sent to streaming completions while vLLM starts (Anthropic ping events, SSE comments for OpenAI), so clients don't time out (0 disables)")
+	serveCmd.Flags().StringVar(&streamIdleTimeout, "stream-idle-timeout", getEnvOrDefault("STREAM_IDLE_TIMEOUT", "5m"), "Time a stream from vLLM may go without data before it is cut and ended with an error event (0 disables)")
+	serveCmd.Flags().StringVar(&warmSchedule, "warm-schedule", getEnvOrDefault("WARM_SCHEDULE", ""), "Windows during which the model is started and kept running regardless of traffic: a cron expression and a duration, separated by semicolons (e.g., \"0 8 * * 1-5 10h\")")
+	serveCmd.Flags().StringVar(&aggressiveSchedule, "aggressive-schedule", getEnvOrDefault("AGGRESSIVE_SCHEDULE", ""), "Windows during which the idle timeout is --aggressive-idle-timeout, in the --warm-schedule format (e.g., \"0 20 * * * 12h\")")
+	serveCmd.Flags().StringVar(&aggressiveIdleTimeout, "aggressive-idle-timeout", getEnvOrDefault("AGGRESSIVE_IDLE_TIMEOUT", "1m"), "Idle timeout during aggressive windows")
+	serveCmd.Flags().StringVar(&scheduleTimezone, "schedule-timezone", getEnvOrDefault("SCHEDULE_TIMEZONE", "UTC"), "Time zone the schedules are evaluated in (e.g., Europe/Paris)")
+	serveCmd.Flags().StringVar(&targetHost, "vllm-target", getEnvOrDefault("VLLM_TARGET", "vllm-api"), "Host of the vLLM service requests are proxied to")
+	serveCmd.Flags().StringVar(&targetPort, "vllm-port", getEnvOrDefault("VLLM_PORT", "80"), "Port of the vLLM services")
+	serveCmd.Flags().StringVar(&embeddingTargetHost, "embedding-target", getEnvOrDefault("VLLM_EMBEDDING_TARGET", "vllm-embed-api"), "Host of the embedding vLLM service")
+	serveCmd.Flags().StringVar(&shadowTargetHost, "shadow-target", getEnvOrDefault("VLLM_SHADOW_TARGET", "vllm-shadow-api"), "Host of the shadow vLLM service")
 	serveCmd.Flags().StringVar(&port, "port", getEnvOrDefault("PORT", "8080"), "HTTP server port")
 	serveCmd.Flags().StringVar(&modelID, "model-id", getEnvOrDefault("MODEL_ID", ""), "Model ID to load from VLLMModel CRD (required)")
 	serveCmd.Flags().IntVar(&gpuCount, "gpu-count", getEnvOrDefaultInt("GPU_COUNT", 2), "Number of GPUs to allocate (infrastructure-level)")
 	serveCmd.Flags().IntVar(&cpuOffloadGB, "cpu-offload-gb", getEnvOrDefaultInt("CPU_OFFLOAD_GB", 0), "CPU offload in GB (infrastructure-level)")
 	serveCmd.Flags().StringVar(&publicEndpoint, "public-endpoint", getEnvOrDefault("PUBLIC_ENDPOINT", ""), "Public-facing endpoint URL (e.g., https://vllm.sir-alfred.io)")
+	serveCmd.Flags().StringVar(&xmlFallback, "xml-fallback", getEnvOrDefault("XML_FALLBACK", "on"), "Convert XML tool calls to native tool_calls: on, off, or auto (only after a tool-call parser mismatch is detected)")
+	serveCmd.Flags().StringVar(&toolArgumentValidation, "tool-argument-validation", getEnvOrDefault("TOOL_ARGUMENT_VALIDATION", "coerce"), "Check the tool calls converted from content against the request's tool schemas: coerce (arguments of the wrong simple type), strict (also replace calls still not matching by an error text), or off")
+	serveCmd.Flags().StringVar(&embeddingModelID, "embedding-model-id", getEnvOrDefault("EMBEDDING_MODEL_ID", ""), "Embedding model ID to serve on /v1/embeddings from a dedicated pod (optional)")
+	serveCmd.Flags().IntVar(&embeddingGPUCount, "embedding-gpu-count", getEnvOrDefaultInt("EMBEDDING_GPU_COUNT", 1), "Number of GPUs to allocate to the embedding pod")
+	serveCmd.Flags().StringVar(&shadowModelID, "shadow-model-id", getEnvOrDefault("SHADOW_MODEL_ID", ""), "Model ID mirroring a sample of the completion traffic from a dedicated pod, its responses discarded or logged (optional)")
+	serveCmd.Flags().IntVar(&shadowGPUCount, "shadow-gpu-count", getEnvOrDefaultInt("SHADOW_GPU_COUNT", 1), "Number of GPUs to allocate to the shadow pod")
+	serveCmd.Flags().IntVar(&shadowSamplePercent, "shadow-sample-percent", getEnvOrDefaultInt("SHADOW_SAMPLE_PERCENT", 100), "Percentage of the completion requests mirrored to the shadow model")
+	serveCmd.Flags().BoolVar(&shadowLogResponses, "shadow-log-responses", getEnvOrDefault("SHADOW_LOG_RESPONSES", "false") == "true", "Log the responses of the shadow model instead of discarding them")
+	serveCmd.Flags().StringVar(&tenantNamespaces, "namespaces", getEnvOrDefault("NAMESPACES", ""), "Tenant namespaces running their own vLLM pod, picked by the API key's namespace or the X-Chill-Namespace header (comma-separated)")
+	serveCmd.Flags().StringVar(&namespaceSelector, "namespace-selector", getEnvOrDefault("NAMESPACE_SELECTOR", ""), "Label selector of tenant namespaces running their own vLLM pod, read again every 30s (e.g., vllm-chill/tenant=true)")
+	serveCmd.Flags().StringVar(&federationPeers, "federation-peers", getEnvOrDefault("FEDERATION_PEERS", ""), "Remote vllm-chill peers to route to when the local model is cold (name=https://host:port,...)")
+	serveCmd.Flags().StringVar(&federationTLSCert, "federation-tls-cert", getEnvOrDefault("FEDERATION_TLS_CERT", ""), "Client certificate for mTLS to federation peers")
+	serveCmd.Flags().StringVar(&federationTLSKey, "federation-tls-key", getEnvOrDefault("FEDERATION_TLS_KEY", ""), "Client key for mTLS to federation peers")
+	serveCmd.Flags().StringVar(&federationTLSCA, "federation-tls-ca", getEnvOrDefault("FEDERATION_TLS_CA", ""), "CA bundle used to verify federation peers")
+	serveCmd.Flags().StringVar(&tlsCert, "tls-cert", getEnvOrDefault("TLS_CERT", ""), "Certificate served on the proxy's port, reloaded when the file changes (empty serves plain HTTP)")
+	serveCmd.Flags().StringVar(&tlsKey, "tls-key", getEnvOrDefault("TLS_KEY", ""), "Key of the certificate served on the proxy's port")
+	serveCmd.Flags().StringVar(&tlsSecret, "tls-secret", getEnvOrDefault("TLS_SECRET", ""), "kubernetes.io/tls Secret whose certificate is served on the proxy's port, instead of --tls-cert and --tls-key")
+	serveCmd.Flags().StringVar(&vllmTLSSecret, "vllm-tls-secret", getEnvOrDefault("VLLM_TLS_SECRET", ""), "kubernetes.io/tls Secret mounted in the vLLM pods, which then serve HTTPS with its certificate (empty serves plain HTTP)")
+	serveCmd.Flags().StringVar(&vllmTLSCA, "vllm-tls-ca", getEnvOrDefault("VLLM_TLS_CA", ""), "CA bundle verifying vLLM's certificate (defaults to the ca.crt of --vllm-tls-secret, then the system roots)")
+	serveCmd.Flags().StringVar(&vllmTLSCert, "vllm-tls-cert", getEnvOrDefault("VLLM_TLS_CERT", ""), "Client certificate presented to vLLM, which then requires a client certificate signed by the ca.crt of --vllm-tls-secret")
+	serveCmd.Flags().StringVar(&vllmTLSKey, "vllm-tls-key", getEnvOrDefault("VLLM_TLS_KEY", ""), "Client key presented to vLLM")
+	serveCmd.Flags().StringVar(&vllmTLSServerName, "vllm-tls-server-name", getEnvOrDefault("VLLM_TLS_SERVER_NAME", ""), "Name sent as SNI and verified in vLLM's certificate (defaults to the host dialed)")
+	serveCmd.Flags().BoolVar(&vllmH2C, "vllm-h2c", getEnvOrDefault("VLLM_H2C", "false") == "true", "Reach vLLM over HTTP/2 cleartext with prior knowledge instead of HTTP/1.1, e.g., for a vLLM gRPC server (over TLS, HTTP/2 is negotiated)")
+	serveCmd.Flags().StringVar(&vllmAPIKeySecret, "vllm-api-key-secret", getEnvOrDefault("VLLM_API_KEY_SECRET", ""), "Secret holding, under api-key, the API key of the vLLM pods, sent to vLLM in place of the client's key (defaults to vllm-api-key, overridden by a model's apiKeySecret)")
+	serveCmd.Flags().BoolVar(&responseAnnotations, "response-annotations", getEnvOrDefault("RESPONSE_ANNOTATIONS", "false") == "true", "Add a vllm_chill object (cold_start, startup_ms, model_switched) to non-streaming responses")
+	serveCmd.Flags().BoolVar(&relabelVLLMMetrics, "relabel-vllm-metrics", getEnvOrDefault("RELABEL_VLLM_METRICS", "true") == "true", "Prefix the vLLM metrics bundled in /proxy/metrics with upstream_ and add a model label, so they don't collide with the proxy's metrics")
+	serveCmd.Flags().BoolVar(&requestValidation, "request-validation", getEnvOrDefault("REQUEST_VALIDATION", "true") == "true", "Reject chat and text completions missing their model, messages or prompt, or with sampling parameters out of vLLM's bounds, without waking the model")
+	serveCmd.Flags().BoolVar(&dropToolsOnNone, "drop-tools-on-none", getEnvOrDefault("DROP_TOOLS_ON_NONE", "false") == "true", "Remove the tools of requests whose tool_choice is none (\"none\" or {\"type\":\"none\"}), saving the prompt tokens of their schemas")
+	serveCmd.Flags().BoolVar(&rawPassthrough, "raw-passthrough", getEnvOrDefault("RAW_PASSTHROUGH", "false") == "true", "Proxy requests and responses untouched, without XML tool call conversion, response rewrites or logging, for maximum throughput")
+	serveCmd.Flags().StringVar(&passthroughPaths, "passthrough-paths", getEnvOrDefault("PASSTHROUGH_PATHS", ""), "Comma-separated paths outside /v1 passed through to vLLM, a path ending in / passing the paths under it (default /tokenize,/detokenize,/pooling,/classify,/score,/rerank)")
+	serveCmd.Flags().StringVar(&maxRequestBodySize, "max-request-body-size", getEnvOrDefault("MAX_REQUEST_BODY_SIZE", "32Mi"), "Largest request body accepted, larger requests get a 413 (e.g., 32Mi, 0 = unlimited)")
+	serveCmd.Flags().StringVar(&maxImageSize, "max-image-size", getEnvOrDefault("MAX_IMAGE_SIZE", "5Mi"), "Largest decoded image accepted in /v1/messages image blocks, larger ones get a 400 (e.g., 5Mi, 0 = unlimited)")
+	serveCmd.Flags().StringVar(&responseCacheSize, "response-cache-size", getEnvOrDefault("RESPONSE_CACHE_SIZE", ""), "Total size of the cached responses of deterministic requests (non-streamed, temperature 0), answered without waking the model (e.g., 64Mi, empty or 0 = disabled)")
+	serveCmd.Flags().StringVar(&responseCacheTTL, "response-cache-ttl", getEnvOrDefault("RESPONSE_CACHE_TTL", "10m"), "Time a response is served from the response cache")
+	serveCmd.Flags().IntVar(&pageCacheModels, "page-cache-models", getEnvOrDefaultInt("PAGE_CACHE_MODELS", 0), "Most recently used models whose weights are kept in the node's page cache after scale-down (0 = disabled)")
+	serveCmd.Flags().StringVar(&pageCacheBudget, "page-cache-budget", getEnvOrDefault("PAGE_CACHE_BUDGET", "64Gi"), "Largest amount of model files kept in the page cache (e.g., 64Gi, 0 = unlimited)")
+	serveCmd.Flags().BoolVar(&modelPrefetch, "model-prefetch", getEnvOrDefault("MODEL_PREFETCH", "false") == "true", "Download models missing from the Hugging Face cache with a Job before creating the vLLM pod")
+	serveCmd.Flags().StringVar(&modelPrefetchTimeout, "model-prefetch-timeout", getEnvOrDefault("MODEL_PREFETCH_TIMEOUT", "1h"), "Time the model download Job gets before vLLM downloads the model itself")
+	serveCmd.Flags().IntVar(&rateLimitRPM, "rate-limit-rpm", getEnvOrDefaultInt("RATE_LIMIT_RPM", 0), "Requests per minute allowed per API key (0 = unlimited)")
+	serveCmd.Flags().IntVar(&rateLimitTPM, "rate-limit-tpm", getEnvOrDefaultInt("RATE_LIMIT_TPM", 0), "Tokens per minute allowed per API key (0 = unlimited)")
+	serveCmd.Flags().StringVar(&rateLimitConfigMap, "rate-limit-configmap", getEnvOrDefault("RATE_LIMIT_CONFIGMAP", ""), "ConfigMap with per API key rate limits, reloaded every 30s (empty disables)")
+	serveCmd.Flags().BoolVar(&userTracking, "user-tracking", getEnvOrDefault("USER_TRACKING", "false") == "true", "Read the user of /v1 requests (OpenAI user field, Anthropic metadata.user_id) for per-user metrics and usage accounting")
+	serveCmd.Flags().IntVar(&maxUserLabels, "max-user-labels", getEnvOrDefaultInt("MAX_USER_LABELS", 20), "Users with their own metrics label, the following ones share the \"other\" label")
+	serveCmd.Flags().BoolVar(&rateLimitPerUser, "rate-limit-per-user", getEnvOrDefault("RATE_LIMIT_PER_USER", "false") == "true", "Rate limit each user instead of each API key, requires --user-tracking")
+	serveCmd.Flags().IntVar(&sessionTokenBudget, "session-token-budget", getEnvOrDefaultInt("SESSION_TOKEN_BUDGET", 0), "Cumulative tokens a client session (X-Session-ID or X-Conversation-ID header) may use, stops runaway agent loops (0 = unlimited)")
+	serveCmd.Flags().StringVar(&sessionTTL, "session-ttl", getEnvOrDefault("SESSION_TTL", "1h"), "Idle time after which a session's token usage is forgotten")
+	serveCmd.Flags().BoolVar(&sessionAffinity, "session-affinity", getEnvOrDefault("SESSION_AFFINITY", "false") == "true", "Refuse requests switching the active model while other sessions (X-Session-ID or X-Conversation-ID header, or metadata.user_id on /v1/messages) use it")
+	serveCmd.Flags().StringVar(&sessionAffinityIdle, "session-affinity-idle", getEnvOrDefault("SESSION_AFFINITY_IDLE", "5m"), "Time without requests after which a session no longer holds the active model")
+	serveCmd.Flags().StringVar(&sessionAffinityWait, "session-affinity-wait", getEnvOrDefault("SESSION_AFFINITY_WAIT", "0s"), "Time a model switch waits for the sessions using the active model to go idle before being refused (0 = refuse at once)")
+	serveCmd.Flags().BoolVar(&stickySessions, "sticky-sessions", getEnvOrDefault("STICKY_SESSIONS", "false") == "true", "Route each session (X-Session-ID or X-Conversation-ID header) to the same replica to reuse its prefix cache")
+	serveCmd.Flags().StringVar(&apiKeysSecret, "api-keys-secret", getEnvOrDefault("API_KEYS_SECRET", ""), "Secret listing the API keys allowed on /v1 endpoints, with their models and quotas, reloaded every 30s (empty accepts any key)")
+	serveCmd.Flags().StringVar(&usageConfigMap, "usage-configmap", getEnvOrDefault("USAGE_CONFIGMAP", ""), "ConfigMap persisting the per model and per API key usage served on /proxy/usage, saved every 30s (empty keeps it in memory)")
+	serveCmd.Flags().StringVar(&adminToken, "admin-token", getEnvOrDefault("ADMIN_TOKEN", ""), "Bearer token enabling the /proxy/admin lifecycle API (empty disables it)")
 	// vLLM is now always managed by the autoscaler
 	serveCmd.Flags().BoolVar(&logOutput, "log-output", getEnvOrDefault("LOG_OUTPUT", "false") == "true", "Log response bodies (use with caution, can be verbose)")
 }
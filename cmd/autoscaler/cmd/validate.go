@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/spf13/cobra"
+	"k8s.io/client-go/dynamic"
+)
+
+var (
+	validateModel        string
+	validateAll          bool
+	validateModelsDir    string
+	validateKubeconfig   string
+	validateKubeContext  string
+	validateGPUCount     int
+	validateCPUOffloadGB int
+	validateJSON         bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Dry-run VLLMModels and print the vLLM command of their pods",
+	Long: `Build the pods of VLLMModels as the proxy would, without creating anything, and
+check them against what vLLM accepts at startup: GPUs of the pod against the
+tensor-parallel-size, batching limits against the context length, dtype and the
+memory it takes. Prints the vLLM command of each model and fails on errors.
+
+With --models-dir, the VLLMModel manifests of a directory are validated instead
+of the cluster's, e.g. in CI before applying a new model.`,
+	Args: cobra.NoArgs,
+	RunE: func(_ *cobra.Command, _ []string) error {
+		ctx := context.Background()
+		crdClient, err := newValidateCRDClient()
+		if err != nil {
+			return err
+		}
+
+		config := &kubernetes.Config{GPUCount: validateGPUCount, CPUOffloadGB: validateCPUOffloadGB}
+		validations, err := crdClient.ValidateModels(ctx, config, validateModel)
+		if err != nil {
+			return err
+		}
+
+		invalid := 0
+		for _, validation := range validations {
+			if !validation.Valid() {
+				invalid++
+			}
+		}
+
+		if validateJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(validations); err != nil {
+				return err
+			}
+		} else if len(validations) == 0 {
+			fmt.Println("No VLLMModels found")
+		} else {
+			for _, validation := range validations {
+				printModelValidation(validation)
+			}
+		}
+
+		if invalid > 0 {
+			return fmt.Errorf("%d of %d VLLMModels are invalid", invalid, len(validations))
+		}
+		return nil
+	},
+}
+
+// newValidateCRDClient returns the CRD client of the manifests directory, or of the cluster
+func newValidateCRDClient() (*kubernetes.CRDClient, error) {
+	if validateModelsDir != "" {
+		return kubernetes.NewManifestCRDClient(validateModelsDir)
+	}
+	restConfig, _, err := kubernetes.RESTConfig(validateKubeconfig, validateKubeContext)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+	return kubernetes.NewCRDClient(dynamicClient), nil
+}
+
+// printModelValidation prints the result, command, errors and warnings of a model
+func printModelValidation(validation *kubernetes.ModelValidation) {
+	status := "OK"
+	if !validation.Valid() {
+		status = "INVALID"
+	}
+	fmt.Printf("%-8s %s (%s)\n", status, validation.Name, validation.Model)
+	if len(validation.Command) > 0 {
+		fmt.Printf("  image:   %s\n", validation.Image)
+		fmt.Printf("  command: %s\n", strings.Join(validation.Command, " "))
+	}
+	for _, message := range validation.Errors {
+		fmt.Printf("  error:   %s\n", message)
+	}
+	for _, message := range validation.Warnings {
+		fmt.Printf("  warning: %s\n", message)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVar(&validateModel, "model", "", "Resource or served model name of the VLLMModel to validate")
+	validateCmd.Flags().BoolVar(&validateAll, "all", false, "Validate every VLLMModel")
+	validateCmd.Flags().StringVar(&validateModelsDir, "models-dir", "", "Directory of VLLMModel manifests to validate instead of the cluster's")
+	validateCmd.Flags().StringVar(&validateKubeconfig, "kubeconfig", "", "Path to the kubeconfig used outside a cluster (default: the in-cluster config, then $KUBECONFIG and ~/.kube/config)")
+	validateCmd.Flags().StringVar(&validateKubeContext, "context", getEnvOrDefault("KUBE_CONTEXT", ""), "kubeconfig context to use (default: the current context)")
+	validateCmd.Flags().IntVar(&validateGPUCount, "gpu-count", getEnvOrDefaultInt("GPU_COUNT", 2), "GPUs allocated to a vLLM pod, as configured on the proxy")
+	validateCmd.Flags().IntVar(&validateCPUOffloadGB, "cpu-offload-gb", getEnvOrDefaultInt("CPU_OFFLOAD_GB", 0), "CPU offload in GB, as configured on the proxy")
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the result as JSON")
+
+	validateCmd.MarkFlagsMutuallyExclusive("model", "all")
+	validateCmd.MarkFlagsOneRequired("model", "all")
+}
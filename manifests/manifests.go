@@ -0,0 +1,20 @@
+// Package manifests embeds the Kubernetes manifests of vllm-chill, so the binary can install them.
+package manifests
+
+import _ "embed"
+
+// CRD is the VLLMModel CustomResourceDefinition
+//
+//go:embed crds/vllmmodel.yaml
+var CRD []byte
+
+// Deployment is the reference deployment of the proxy in the vllm namespace: its API key Secret,
+// RBAC, Deployment, Service and Ingress
+//
+//go:embed kubernetes-with-model-switching.yaml
+var Deployment []byte
+
+// SampleModel is an example VLLMModel
+//
+//go:embed examples/qwen3-coder-model.yaml
+var SampleModel []byte
@@ -0,0 +1,282 @@
+// Package admin provides the authenticated admin API for manual model lifecycle control.
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/efortin/vllm-chill/pkg/benchmark"
+	"github.com/efortin/vllm-chill/pkg/compare"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/gin-gonic/gin"
+)
+
+// Manager defines the lifecycle operations exposed by the admin API
+type Manager interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+	Restart(ctx context.Context) error
+	SwitchModel(ctx context.Context, modelID string) error
+	GetModelConfig(ctx context.Context, modelID string) (*kubernetes.ModelConfig, error)
+	GetActiveModel() string
+	UpdateActivity()
+	// Benchmark runs the standardized benchmark against the active, ready model and records the result
+	Benchmark(ctx context.Context, modelID string) (*benchmark.Result, error)
+	// SendToModel sends a chat completion request directly to the active, ready model
+	SendToModel(ctx context.Context, modelID string, request map[string]interface{}) (*compare.Response, error)
+}
+
+// Handler handles admin API requests
+type Handler struct {
+	manager Manager
+	token   string
+}
+
+// NewHandler creates a new admin handler authenticated with the given bearer token
+func NewHandler(manager Manager, token string) *Handler {
+	return &Handler{
+		manager: manager,
+		token:   token,
+	}
+}
+
+// Register adds the admin endpoints to the group, all behind token authentication
+func (h *Handler) Register(group *gin.RouterGroup) {
+	group.Use(h.authenticate)
+	group.POST("/start", h.StartHandler)
+	group.POST("/stop", h.StopHandler)
+	group.POST("/scale-down", h.StopHandler)
+	group.POST("/restart", h.RestartHandler)
+	group.POST("/models/:id/switch", h.SwitchHandler)
+	group.POST("/models/:id/activate", h.ActivateHandler)
+	group.POST("/models/:id/benchmark", h.BenchmarkHandler)
+	group.POST("/compare", h.CompareHandler)
+}
+
+// authenticate rejects requests without a valid "Authorization: Bearer <token>" header
+func (h *Handler) authenticate(c *gin.Context) {
+	if Authenticate(c, h.token) {
+		c.Next()
+	}
+}
+
+// Authenticate reports whether the request sent the admin token as "Authorization: Bearer <token>",
+// otherwise it aborts it with a 401. Every request is rejected when the token is empty.
+func Authenticate(c *gin.Context, token string) bool {
+	sent, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": gin.H{
+				"message": "Missing or invalid admin token",
+				"type":    "authentication_error",
+				"code":    "invalid_admin_token",
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// StartHandler scales the active model up and waits until it is ready
+func (h *Handler) StartHandler(c *gin.Context) {
+	log.Printf("Admin start requested")
+
+	// Update activity to prevent immediate scale-down
+	h.manager.UpdateActivity()
+
+	if err := h.manager.Start(c.Request.Context()); err != nil {
+		h.fail(c, "start_failed", err)
+		return
+	}
+	h.succeed(c, "vLLM started successfully")
+}
+
+// StopHandler scales the active model down using the configured scale strategy
+func (h *Handler) StopHandler(c *gin.Context) {
+	log.Printf("Admin stop requested")
+
+	if err := h.manager.Stop(c.Request.Context()); err != nil {
+		h.fail(c, "stop_failed", err)
+		return
+	}
+	h.succeed(c, "vLLM stopped successfully")
+}
+
+// RestartHandler recreates the vLLM pod and waits until it is ready
+func (h *Handler) RestartHandler(c *gin.Context) {
+	log.Printf("Admin restart requested")
+
+	h.manager.UpdateActivity()
+
+	if err := h.manager.Restart(c.Request.Context()); err != nil {
+		h.fail(c, "restart_failed", err)
+		return
+	}
+	h.succeed(c, "vLLM restarted successfully")
+}
+
+// SwitchHandler makes the given model the active one without starting it, the current pod is
+// released and the model starts on the next request
+func (h *Handler) SwitchHandler(c *gin.Context) {
+	modelID := c.Param("id")
+	log.Printf("Admin switch requested for model: %s", modelID)
+
+	if !h.switchTo(c, modelID) {
+		return
+	}
+	h.succeed(c, "Switched to model "+modelID)
+}
+
+// ActivateHandler switches to the given model and starts it
+func (h *Handler) ActivateHandler(c *gin.Context) {
+	modelID := c.Param("id")
+	log.Printf("Admin activation requested for model: %s", modelID)
+
+	if !h.activate(c, modelID) {
+		return
+	}
+	h.succeed(c, "Model "+modelID+" activated successfully")
+}
+
+// BenchmarkHandler activates the given model, runs the standardized benchmark once it is ready
+// and records the result in the VLLMModel status
+func (h *Handler) BenchmarkHandler(c *gin.Context) {
+	modelID := c.Param("id")
+	log.Printf("Admin benchmark requested for model: %s", modelID)
+
+	if !h.activate(c, modelID) {
+		return
+	}
+
+	result, err := h.manager.Benchmark(c.Request.Context(), modelID)
+	if err != nil {
+		h.fail(c, "benchmark_failed", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "success",
+		"message":      "Model " + modelID + " benchmarked successfully",
+		"active_model": h.manager.GetActiveModel(),
+		"benchmark":    result,
+	})
+}
+
+// compareRequest is the body of a model comparison
+type compareRequest struct {
+	Models  []string               `json:"models"`
+	Request map[string]interface{} `json:"request"` // Chat completion request, its model is replaced
+}
+
+// CompareHandler sends the same chat completion request to two models in turn, waking each one
+// as needed, and returns both responses with simple diff metrics
+func (h *Handler) CompareHandler(c *gin.Context) {
+	var req compareRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Models) != 2 || len(req.Request) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": `Expected {"models": ["<first>", "<second>"], "request": {<chat completion request>}}`,
+				"type":    "invalid_request_error",
+				"code":    "invalid_request",
+			},
+		})
+		return
+	}
+	log.Printf("Admin comparison requested between models %s and %s", req.Models[0], req.Models[1])
+
+	// Check both models first, so an unknown second model doesn't wake the first one for nothing
+	for _, modelID := range req.Models {
+		if _, err := h.manager.GetModelConfig(c.Request.Context(), modelID); err != nil {
+			h.modelNotFound(c, err)
+			return
+		}
+	}
+
+	responses := make([]*compare.Response, 0, len(req.Models))
+	for _, modelID := range req.Models {
+		if !h.activate(c, modelID) {
+			return
+		}
+		response, err := h.manager.SendToModel(c.Request.Context(), modelID, req.Request)
+		if err != nil {
+			h.fail(c, "compare_failed", err)
+			return
+		}
+		responses = append(responses, response)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "success",
+		"message":      "Compared models " + req.Models[0] + " and " + req.Models[1],
+		"active_model": h.manager.GetActiveModel(),
+		"comparison":   compare.NewResult(responses[0], responses[1]),
+	})
+}
+
+// activate switches to the given model if needed and waits until it is ready. It writes the
+// error response and returns false on failure.
+func (h *Handler) activate(c *gin.Context, modelID string) bool {
+	if !h.switchTo(c, modelID) {
+		return false
+	}
+
+	h.manager.UpdateActivity()
+
+	if err := h.manager.Start(c.Request.Context()); err != nil {
+		h.fail(c, "start_failed", err)
+		return false
+	}
+	return true
+}
+
+// switchTo makes the given model the active one if it is not already. It writes the error
+// response and returns false on failure.
+func (h *Handler) switchTo(c *gin.Context, modelID string) bool {
+	ctx := c.Request.Context()
+	if _, err := h.manager.GetModelConfig(ctx, modelID); err != nil {
+		h.modelNotFound(c, err)
+		return false
+	}
+
+	if modelID != h.manager.GetActiveModel() {
+		if err := h.manager.SwitchModel(ctx, modelID); err != nil {
+			h.fail(c, "switch_failed", err)
+			return false
+		}
+	}
+	return true
+}
+
+// succeed writes a success response including the active model
+func (h *Handler) succeed(c *gin.Context, message string) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "success",
+		"message":      message,
+		"active_model": h.manager.GetActiveModel(),
+	})
+}
+
+// modelNotFound writes the error of an unknown model
+func (h *Handler) modelNotFound(c *gin.Context, err error) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": gin.H{
+			"message": err.Error(),
+			"type":    "invalid_request_error",
+			"code":    "model_not_found",
+		},
+	})
+}
+
+// fail logs and writes an operation error
+func (h *Handler) fail(c *gin.Context, code string, err error) {
+	log.Printf("Admin operation failed (%s): %v", code, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": gin.H{
+			"message": err.Error(),
+			"type":    "server_error",
+			"code":    code,
+		},
+	})
+}
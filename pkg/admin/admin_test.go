@@ -0,0 +1,309 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/efortin/vllm-chill/pkg/admin"
+	"github.com/efortin/vllm-chill/pkg/benchmark"
+	"github.com/efortin/vllm-chill/pkg/compare"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/gin-gonic/gin"
+)
+
+const testToken = "s3cret"
+
+// MockManager implements the admin.Manager interface for testing
+type MockManager struct {
+	activeModel   string
+	models        map[string]bool
+	startError    error
+	stopError     error
+	restartError  error
+	startCalled   bool
+	stopCalled    bool
+	restartCalled bool
+	switchedTo    string
+	benchmarked   string
+	benchErr      error
+	sent          []string
+	sendErr       error
+}
+
+func (m *MockManager) Start(_ context.Context) error {
+	m.startCalled = true
+	return m.startError
+}
+
+func (m *MockManager) Stop(_ context.Context) error {
+	m.stopCalled = true
+	return m.stopError
+}
+
+func (m *MockManager) Restart(_ context.Context) error {
+	m.restartCalled = true
+	return m.restartError
+}
+
+func (m *MockManager) SwitchModel(_ context.Context, modelID string) error {
+	m.switchedTo = modelID
+	m.activeModel = modelID
+	return nil
+}
+
+func (m *MockManager) GetModelConfig(_ context.Context, modelID string) (*kubernetes.ModelConfig, error) {
+	if !m.models[modelID] {
+		return nil, errors.New("VLLMModel not found")
+	}
+	return &kubernetes.ModelConfig{ServedModelName: modelID}, nil
+}
+
+func (m *MockManager) GetActiveModel() string {
+	return m.activeModel
+}
+
+func (m *MockManager) UpdateActivity() {}
+
+func (m *MockManager) Benchmark(_ context.Context, modelID string) (*benchmark.Result, error) {
+	m.benchmarked = modelID
+	if m.benchErr != nil {
+		return nil, m.benchErr
+	}
+	return &benchmark.Result{Model: modelID, Prompts: 5, TokensPerSecond: 87.5}, nil
+}
+
+func (m *MockManager) SendToModel(_ context.Context, modelID string, _ map[string]interface{}) (*compare.Response, error) {
+	if m.sendErr != nil {
+		return nil, m.sendErr
+	}
+	m.sent = append(m.sent, modelID)
+	completionTokens := 10 * len(m.sent)
+	body := []byte(`{"choices":[{"message":{"content":"Hi"},"finish_reason":"stop"}],"usage":{"completion_tokens":` + strconv.Itoa(completionTokens) + `}}`)
+	return &compare.Response{Model: modelID, StatusCode: http.StatusOK, Metrics: compare.Measure(body), Body: body}, nil
+}
+
+var _ = Describe("Handler", func() {
+	var (
+		mockManager *MockManager
+		router      *gin.Engine
+	)
+
+	BeforeEach(func() {
+		gin.SetMode(gin.TestMode)
+		mockManager = &MockManager{
+			activeModel: "qwen",
+			models:      map[string]bool{"qwen": true, "deepseek": true},
+		}
+		router = gin.New()
+		admin.NewHandler(mockManager, testToken).Register(router.Group("/proxy/admin"))
+	})
+
+	post := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	Describe("authentication", func() {
+		It("should reject requests without a token", func() {
+			w := post("/proxy/admin/start", "")
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(w.Body.String()).To(ContainSubstring("invalid_admin_token"))
+			Expect(mockManager.startCalled).To(BeFalse())
+		})
+
+		It("should reject requests with a wrong token", func() {
+			w := post("/proxy/admin/stop", "wrong")
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+			Expect(mockManager.stopCalled).To(BeFalse())
+		})
+
+		It("should reject every request when no token is configured", func() {
+			router = gin.New()
+			admin.NewHandler(mockManager, "").Register(router.Group("/proxy/admin"))
+			w := post("/proxy/admin/start", "")
+			Expect(w.Code).To(Equal(http.StatusUnauthorized))
+		})
+	})
+
+	Describe("lifecycle operations", func() {
+		It("should start the model", func() {
+			w := post("/proxy/admin/start", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.startCalled).To(BeTrue())
+		})
+
+		It("should scale down the model", func() {
+			w := post("/proxy/admin/scale-down", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.stopCalled).To(BeTrue())
+		})
+
+		It("should restart the model", func() {
+			w := post("/proxy/admin/restart", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.restartCalled).To(BeTrue())
+		})
+
+		It("should report operation failures", func() {
+			mockManager.stopError = errors.New("pod deletion failed")
+			w := post("/proxy/admin/stop", testToken)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+
+			var response map[string]map[string]string
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			Expect(response["error"]["code"]).To(Equal("stop_failed"))
+			Expect(response["error"]["message"]).To(Equal("pod deletion failed"))
+		})
+	})
+
+	Describe("model switch", func() {
+		It("should switch to the requested model without starting it", func() {
+			w := post("/proxy/admin/models/deepseek/switch", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.switchedTo).To(Equal("deepseek"))
+			Expect(mockManager.startCalled).To(BeFalse())
+
+			var response map[string]string
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			Expect(response["active_model"]).To(Equal("deepseek"))
+		})
+
+		It("should return 404 for unknown models", func() {
+			w := post("/proxy/admin/models/unknown/switch", testToken)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+			Expect(mockManager.switchedTo).To(BeEmpty())
+		})
+	})
+
+	Describe("model activation", func() {
+		It("should switch to and start the requested model", func() {
+			w := post("/proxy/admin/models/deepseek/activate", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.switchedTo).To(Equal("deepseek"))
+			Expect(mockManager.startCalled).To(BeTrue())
+
+			var response map[string]string
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			Expect(response["active_model"]).To(Equal("deepseek"))
+		})
+
+		It("should not switch when the model is already active", func() {
+			w := post("/proxy/admin/models/qwen/activate", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.switchedTo).To(BeEmpty())
+			Expect(mockManager.startCalled).To(BeTrue())
+		})
+
+		It("should return 404 for unknown models", func() {
+			w := post("/proxy/admin/models/unknown/activate", testToken)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+			Expect(mockManager.switchedTo).To(BeEmpty())
+			Expect(mockManager.startCalled).To(BeFalse())
+		})
+	})
+
+	Describe("model comparison", func() {
+		compareRequest := func(body string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/proxy/admin/compare", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+		const chatRequest = `"request": {"messages": [{"role": "user", "content": "Hello"}]}`
+
+		It("should send the request to both models in turn and diff the responses", func() {
+			w := compareRequest(`{"models": ["qwen", "deepseek"], ` + chatRequest + `}`)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.sent).To(Equal([]string{"qwen", "deepseek"}))
+			Expect(mockManager.switchedTo).To(Equal("deepseek"))
+
+			var response struct {
+				ActiveModel string         `json:"active_model"`
+				Comparison  compare.Result `json:"comparison"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.ActiveModel).To(Equal("deepseek"))
+			Expect(response.Comparison.Responses).To(HaveLen(2))
+			Expect(response.Comparison.Diff.CompletionTokensDelta).To(Equal(10))
+			Expect(response.Comparison.Diff.SameFinishReason).To(BeTrue())
+		})
+
+		It("should reject requests without two models and a request", func() {
+			for _, body := range []string{
+				`{"models": ["qwen"], ` + chatRequest + `}`,
+				`{"models": ["qwen", "deepseek"]}`,
+				`not json`,
+			} {
+				w := compareRequest(body)
+				Expect(w.Code).To(Equal(http.StatusBadRequest), body)
+			}
+			Expect(mockManager.startCalled).To(BeFalse())
+		})
+
+		It("should return 404 for unknown models before sending anything", func() {
+			w := compareRequest(`{"models": ["qwen", "unknown"], ` + chatRequest + `}`)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+			Expect(mockManager.sent).To(BeEmpty())
+			Expect(mockManager.startCalled).To(BeFalse())
+		})
+
+		It("should report send failures", func() {
+			mockManager.sendErr = errors.New("connection refused")
+			w := compareRequest(`{"models": ["qwen", "deepseek"], ` + chatRequest + `}`)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+			Expect(w.Body.String()).To(ContainSubstring("compare_failed"))
+		})
+	})
+
+	Describe("model benchmark", func() {
+		It("should activate the model before benchmarking it", func() {
+			w := post("/proxy/admin/models/deepseek/benchmark", testToken)
+			Expect(w.Code).To(Equal(http.StatusOK))
+			Expect(mockManager.switchedTo).To(Equal("deepseek"))
+			Expect(mockManager.startCalled).To(BeTrue())
+			Expect(mockManager.benchmarked).To(Equal("deepseek"))
+
+			var response struct {
+				Benchmark benchmark.Result `json:"benchmark"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Benchmark.TokensPerSecond).To(Equal(87.5))
+		})
+
+		It("should not benchmark a model that failed to start", func() {
+			mockManager.startError = errors.New("scale up timed out")
+			w := post("/proxy/admin/models/qwen/benchmark", testToken)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+			Expect(w.Body.String()).To(ContainSubstring("start_failed"))
+			Expect(mockManager.benchmarked).To(BeEmpty())
+		})
+
+		It("should report benchmark failures", func() {
+			mockManager.benchErr = errors.New("prompt 1: no tokens generated")
+			w := post("/proxy/admin/models/qwen/benchmark", testToken)
+			Expect(w.Code).To(Equal(http.StatusInternalServerError))
+			Expect(w.Body.String()).To(ContainSubstring("benchmark_failed"))
+		})
+
+		It("should return 404 for unknown models", func() {
+			w := post("/proxy/admin/models/unknown/benchmark", testToken)
+			Expect(w.Code).To(Equal(http.StatusNotFound))
+			Expect(mockManager.benchmarked).To(BeEmpty())
+		})
+	})
+})
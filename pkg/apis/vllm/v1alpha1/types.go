@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -21,11 +22,18 @@ type VLLMModelSpec struct {
 	// Model Identification
 	ModelName       string `json:"modelName"`
 	ServedModelName string `json:"servedModelName"`
+	// Aliases are other names clients may request the model by (e.g., gpt-4o), or patterns of them
+	// where * matches any characters (e.g., claude-*)
+	Aliases []string `json:"aliases,omitempty"`
 
 	// Parsing Configuration
 	ToolCallParser  string `json:"toolCallParser,omitempty"`
 	ReasoningParser string `json:"reasoningParser,omitempty"`
 
+	// FallbackToolParser selects the proxy-side parser for tool calls written as text: xml, json, mistral or
+	// llama3. When empty, it follows ToolCallParser (mistral, llama3_json) and defaults to xml.
+	FallbackToolParser string `json:"fallbackToolParser,omitempty"`
+
 	// vLLM Runtime Parameters (model-specific)
 	// Note: gpuCount and cpuOffloadGB are infrastructure-level, configured in vllm-chill
 	MaxModelLen            int     `json:"maxModelLen,omitempty"`
@@ -37,6 +45,74 @@ type VLLMModelSpec struct {
 	DisableCustomAllReduce *bool   `json:"disableCustomAllReduce,omitempty"`
 	EnablePrefixCaching    *bool   `json:"enablePrefixCaching,omitempty"`
 	EnableAutoToolChoice   *bool   `json:"enableAutoToolChoice,omitempty"`
+
+	// Serving Mode
+	// Embedding marks the model as an embedding model served on /v1/embeddings by a dedicated pod
+	Embedding *bool `json:"embedding,omitempty"`
+
+	// Horizontal Scaling
+	// MinReplicas pods are kept running even when idle (0 allows scale to zero)
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+	// MaxReplicas bounds the number of pods started under load (defaults to 1)
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// LoRA
+	// LoRAAdapter is served under servedModelName on top of modelName. Switching between models
+	// sharing the same base swaps the adapter instead of restarting the pod.
+	LoRAAdapter string `json:"loraAdapter,omitempty"`
+	// MaxLoRARank is the largest adapter rank the pod accepts
+	MaxLoRARank int `json:"maxLoraRank,omitempty"`
+
+	// Speculative Decoding
+	// SpeculativeModel is the draft model (Hugging Face repository or local path) proposing tokens
+	SpeculativeModel string `json:"speculativeModel,omitempty"`
+	// SpeculativeMethod is vLLM's speculative decoding method (e.g., ngram, eagle, mtp), inferred
+	// from the draft model when empty
+	SpeculativeMethod string `json:"speculativeMethod,omitempty"`
+	// NumSpeculativeTokens is the number of tokens proposed per step
+	NumSpeculativeTokens int `json:"numSpeculativeTokens,omitempty"`
+
+	// Request Limits, enforced by the proxy
+	// MaxOutputTokens caps max_tokens (and max_completion_tokens) of completion and message requests
+	MaxOutputTokens int `json:"maxOutputTokens,omitempty"`
+	// RequestTimeout bounds the time vLLM takes to answer a request once the model is up (e.g., 10m)
+	RequestTimeout string `json:"requestTimeout,omitempty"`
+	// MaxStreamDuration bounds the time a streamed response lasts once the model is up (e.g., 30m)
+	MaxStreamDuration string `json:"maxStreamDuration,omitempty"`
+
+	// Structured Outputs
+	// GuidedDecoding constrains forced /v1/messages tool calls to the tool's input_schema with vLLM's guided_json
+	GuidedDecoding *bool `json:"guidedDecoding,omitempty"`
+	// GuidedDecodingBackend selects vLLM's guided decoding backend: auto, outlines, lm-format-enforcer, xgrammar or guidance
+	GuidedDecodingBackend string `json:"guidedDecodingBackend,omitempty"`
+
+	// Backend Authentication
+	// APIKeySecret names the Secret holding the API key the model's vLLM pods require, under api-key
+	APIKeySecret string `json:"apiKeySecret,omitempty"`
+
+	// Pod Template
+	// PodTemplate overrides parts of the vLLM pod spec built by vllm-chill
+	PodTemplate *VLLMPodTemplate `json:"podTemplate,omitempty"`
+
+	// Scheduling
+	// GPUType selects the nodes whose nvidia.com/gpu.product label has this value (e.g., NVIDIA-A100-SXM4-80GB)
+	GPUType string `json:"gpuType,omitempty"`
+	// NodeSelector and Tolerations place the model on a GPU node pool, on top of the pod template's
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// VLLMPodTemplate overrides parts of the vLLM pod spec. Env, volumes and volume mounts are added to
+// the defaults, replacing the entries of the same name; resources replace the listed defaults.
+type VLLMPodTemplate struct {
+	Image        string                      `json:"image,omitempty"`
+	Resources    corev1.ResourceRequirements `json:"resources,omitempty"`
+	Env          []corev1.EnvVar             `json:"env,omitempty"`
+	Volumes      []corev1.Volume             `json:"volumes,omitempty"`
+	VolumeMounts []corev1.VolumeMount        `json:"volumeMounts,omitempty"`
+	NodeSelector map[string]string           `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration         `json:"tolerations,omitempty"`
+	Affinity     *corev1.Affinity            `json:"affinity,omitempty"`
 }
 
 // VLLMModelStatus defines the observed state of VLLMModel
@@ -44,6 +120,40 @@ type VLLMModelStatus struct {
 	Phase       string      `json:"phase,omitempty"`
 	LastUpdated metav1.Time `json:"lastUpdated,omitempty"`
 	Message     string      `json:"message,omitempty"`
+
+	// State of the model's pod, written by the proxy serving the model
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"` // Generation of the spec the status reflects
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`         // Loaded, Loading and Failed
+	CurrentPodName     string             `json:"currentPodName,omitempty"`     // vLLM pod of the model, empty when it isn't loaded
+	LastActivity       *metav1.Time       `json:"lastActivity,omitempty"`       // Last request to the model
+
+	// Benchmark holds the last standardized benchmark run against the model
+	Benchmark *BenchmarkStatus `json:"benchmark,omitempty"`
+}
+
+// Phases of the VLLMModel status
+const (
+	PhasePending = "Pending" // Not served by a proxy yet
+	PhaseIdle    = "Idle"    // Released, the next request starts it
+	PhaseLoading = "Loading" // Downloading or starting
+	PhaseReady   = "Ready"   // Serving requests
+	PhaseFailed  = "Failed"  // The last start failed
+)
+
+// Condition types of the VLLMModel status
+const (
+	ConditionLoaded  = "Loaded"  // The model's pod is ready to serve
+	ConditionLoading = "Loading" // The model is downloading or its pod is starting
+	ConditionFailed  = "Failed"  // The last start of the model failed
+)
+
+// BenchmarkStatus records the throughput, latency and memory headroom measured by a benchmark run
+type BenchmarkStatus struct {
+	CompletedAt            metav1.Time `json:"completedAt,omitempty"`
+	Prompts                int32       `json:"prompts,omitempty"`                // Prompts in the standardized set
+	TokensPerSecond        float64     `json:"tokensPerSecond,omitempty"`        // Generated tokens per second of generation time
+	TimeToFirstTokenMs     int64       `json:"timeToFirstTokenMs,omitempty"`     // Mean time to the first streamed token
+	KVCacheHeadroomPercent float64     `json:"kvCacheHeadroomPercent,omitempty"` // KV cache left unused at peak, from vLLM metrics
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
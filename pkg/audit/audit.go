@@ -0,0 +1,334 @@
+// Package audit records the requests served through the proxy to a file or an HTTP endpoint, with
+// their metadata, token usage and latency, and optionally their redacted and truncated bodies.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Stdout is the sink writing the records to the standard output
+const Stdout = "-"
+
+// Redacted replaces the values of the redacted fields
+const Redacted = "[REDACTED]"
+
+const (
+	defaultRetries = 3
+	defaultBackoff = time.Second
+	defaultTimeout = 10 * time.Second
+	queueSize      = 1000
+	maxBatch       = 100 // Records posted at once to an HTTP sink
+)
+
+// Record is the audit trail of a request
+type Record struct {
+	Time             time.Time `json:"time"`
+	Method           string    `json:"method"`
+	Path             string    `json:"path"`
+	Status           int       `json:"status"`
+	Client           string    `json:"client"` // Tenant name, or SHA-256 digest of the API key
+	User             string    `json:"user,omitempty"`
+	Session          string    `json:"session,omitempty"`
+	Model            string    `json:"model,omitempty"` // Empty when no model served the request
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+	Request          string    `json:"request,omitempty"`
+	Response         string    `json:"response,omitempty"`
+}
+
+// ParseSink checks the sink of the records: a file path, Stdout, or an http or https URL
+func ParseSink(sink string) error {
+	if !strings.Contains(sink, "://") {
+		return nil
+	}
+	u, err := url.Parse(sink)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid audit log URL") // The URL may hold a token
+	}
+	return nil
+}
+
+// ParseFields parses a comma-separated list of JSON field names
+func ParseFields(spec string) map[string]bool {
+	fields := make(map[string]bool)
+	for _, field := range strings.Split(spec, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields[field] = true
+		}
+	}
+	return fields
+}
+
+// Logger writes the records in the background, in order. A nil Logger drops the records.
+type Logger struct {
+	bodySize int             // Bytes of the bodies kept, 0 when bodies are not recorded
+	redact   map[string]bool // Fields whose values are redacted in the bodies
+	file     io.WriteCloser  // File sink, nil with an HTTP sink
+	url      string          // HTTP sink, empty with a file sink
+	client   *http.Client
+	retries  int
+	backoff  time.Duration
+	mu       sync.Mutex // Guards queue and closed
+	queue    chan Record
+	closed   bool
+	done     chan struct{}
+}
+
+// New creates a logger appending the records to the sink as JSON lines, or posting them to it as
+// newline-delimited JSON when it is a URL. Bodies are kept up to bodySize bytes, the values of the
+// redact fields replaced.
+func New(sink string, bodySize int, redact map[string]bool) (*Logger, error) {
+	if err := ParseSink(sink); err != nil {
+		return nil, err
+	}
+	l := &Logger{
+		bodySize: bodySize,
+		redact:   redact,
+		client:   &http.Client{Timeout: defaultTimeout},
+		retries:  defaultRetries,
+		backoff:  defaultBackoff,
+		queue:    make(chan Record, queueSize),
+		done:     make(chan struct{}),
+	}
+	switch {
+	case strings.Contains(sink, "://"):
+		l.url = sink
+	case sink == Stdout:
+		l.file = nopCloser{os.Stdout}
+	default:
+		file, err := os.OpenFile(sink, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		l.file = file
+	}
+	go l.run()
+	return l, nil
+}
+
+// nopCloser leaves the standard output open
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// Bodies reports whether the records hold the request and response bodies
+func (l *Logger) Bodies() bool {
+	return l != nil && l.bodySize > 0
+}
+
+// Body returns a body as recorded: redacted, then truncated
+func (l *Logger) Body(body []byte) string {
+	if !l.Bodies() || len(body) == 0 {
+		return ""
+	}
+	if len(l.redact) > 0 {
+		body = Redact(body, l.redact)
+	}
+	return Truncate(body, l.bodySize)
+}
+
+// Log queues the record without waiting. Records are dropped when the queue is full.
+func (l *Logger) Log(record Record) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.queue <- record:
+	default:
+		log.Printf("Audit queue full, dropped the record of %s %s", record.Method, record.Path)
+	}
+}
+
+// Close writes the queued records, giving up when ctx is done
+func (l *Logger) Close(ctx context.Context) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.queue)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.done:
+	case <-ctx.Done():
+		log.Printf("Gave up writing the queued audit records: %v", ctx.Err())
+	}
+}
+
+// run writes the queued records, in batches of those already queued
+func (l *Logger) run() {
+	defer close(l.done)
+	if l.file != nil {
+		defer func() {
+			_ = l.file.Close()
+		}()
+	}
+
+	for record := range l.queue {
+		batch := []Record{record}
+	batching:
+		for len(batch) < maxBatch {
+			select {
+			case record, ok := <-l.queue:
+				if !ok {
+					break batching
+				}
+				batch = append(batch, record)
+			default:
+				break batching
+			}
+		}
+		if err := l.write(batch); err != nil {
+			log.Printf("Failed to write %d audit record(s): %v", len(batch), err)
+		}
+	}
+}
+
+// write writes a batch of records as JSON lines to the sink
+func (l *Logger) write(batch []Record) error {
+	var lines bytes.Buffer
+	encoder := json.NewEncoder(&lines)
+	for _, record := range batch {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+	if l.file != nil {
+		_, err := l.file.Write(lines.Bytes())
+		return err
+	}
+
+	backoff := l.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := l.postOnce(lines.Bytes())
+		if err == nil || !retry || attempt >= l.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postOnce posts the records once and reports whether a failure is worth retrying. Errors don't
+// include the URL, which may hold a token.
+func (l *Logger) postOnce(data []byte) (bool, error) {
+	resp, err := l.client.Post(l.url, "application/x-ndjson", bytes.NewReader(data))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("audit endpoint answered %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Redact replaces the values of the named fields, at any depth, of a JSON body or of each event
+// of a server-sent event stream. A body that is neither, e.g. cut short, is replaced as a whole,
+// so no field meant to be redacted is ever recorded.
+func Redact(body []byte, fields map[string]bool) []byte {
+	if redacted, ok := redactJSON(body, fields); ok {
+		return redacted
+	}
+	if !bytes.HasPrefix(body, []byte("data:")) && !bytes.HasPrefix(body, []byte("event:")) {
+		return fmt.Appendf(nil, "%s (%d bytes)", Redacted, len(body))
+	}
+
+	var out bytes.Buffer
+	for _, line := range bytes.SplitAfter(body, []byte("\n")) {
+		data, isData := bytes.CutPrefix(line, []byte("data:"))
+		payload := bytes.TrimSpace(data)
+		if !isData || len(payload) == 0 || payload[0] != '{' {
+			out.Write(line)
+			continue
+		}
+		// An event cut short by the capture limit can't be parsed, it is left out
+		if redacted, ok := redactJSON(payload, fields); ok {
+			out.WriteString("data: ")
+			out.Write(redacted)
+			out.WriteString("\n")
+		}
+	}
+	return out.Bytes()
+}
+
+// redactJSON redacts the fields of a JSON value, reporting whether it parsed
+func redactJSON(body []byte, fields map[string]bool) ([]byte, bool) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil || decoder.More() {
+		return nil, false
+	}
+	redacted, err := json.Marshal(redactValue(value, fields))
+	if err != nil {
+		return nil, false
+	}
+	return redacted, true
+}
+
+// redactValue replaces the values of the fields in the objects of a decoded JSON value
+func redactValue(value interface{}, fields map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if fields[key] {
+				v[key] = Redacted
+			} else {
+				v[key] = redactValue(field, fields)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i], fields)
+		}
+	}
+	return value
+}
+
+// Truncate returns the body, cut to limit bytes on a character boundary with the size of the
+// part left out when longer
+func Truncate(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(body[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... [%d bytes truncated]", body[:cut], len(body)-cut)
+}
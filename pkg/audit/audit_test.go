@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSink(t *testing.T) {
+	assert.NoError(t, ParseSink(""))
+	assert.NoError(t, ParseSink(Stdout))
+	assert.NoError(t, ParseSink("/var/log/vllm-chill/audit.jsonl"))
+	assert.NoError(t, ParseSink("https://audit.example.com/ingest?token=secret"))
+
+	err := ParseSink("ftp://audit.example.com/token-secret")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "secret", "the URL may hold a token")
+	assert.Error(t, ParseSink("https://"))
+}
+
+func TestParseFields(t *testing.T) {
+	assert.Equal(t, map[string]bool{"content": true, "text": true}, ParseFields(" content, ,text"))
+	assert.Empty(t, ParseFields(""))
+}
+
+func TestRedact(t *testing.T) {
+	fields := ParseFields("content,system")
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "nested fields",
+			body: `{"model":"qwen","system":"be brief","messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`,
+			want: `{"messages":[{"content":"[REDACTED]","role":"user"}],"model":"qwen","system":"[REDACTED]"}`,
+		},
+		{
+			name: "numbers kept as is",
+			body: `{"max_tokens":12345678901234567890,"content":"x"}`,
+			want: `{"content":"[REDACTED]","max_tokens":12345678901234567890}`,
+		},
+		{
+			name: "stream events",
+			body: "event: delta\ndata: {\"delta\":{\"content\":\"hi\"}}\n\ndata: [DONE]\n\ndata: {\"delta\":{\"cont",
+			want: "event: delta\ndata: {\"delta\":{\"content\":\"[REDACTED]\"}}\n\ndata: [DONE]\n\n",
+		},
+		{
+			name: "not JSON",
+			body: `{"content":"cut sh`,
+			want: "[REDACTED] (18 bytes)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(Redact([]byte(tt.body), fields)))
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", Truncate([]byte("short"), 10))
+	assert.Equal(t, "0123... [6 bytes truncated]", Truncate([]byte("0123456789"), 4))
+	assert.Equal(t, "a... [4 bytes truncated]", Truncate([]byte("aéé"), 2), "characters are not split")
+}
+
+func TestLoggerBody(t *testing.T) {
+	var l *Logger
+	assert.False(t, l.Bodies())
+	assert.Empty(t, l.Body([]byte(`{"content":"hi"}`)), "a nil logger records no bodies")
+
+	l = &Logger{bodySize: 20, redact: ParseFields("content")}
+	assert.True(t, l.Bodies())
+	assert.Equal(t, `{"content":"[REDACTE... [4 bytes truncated]`, l.Body([]byte(`{"content":"secret"}`)), "redacted before it is truncated")
+}
+
+func TestLoggerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := New(path, 0, nil)
+	require.NoError(t, err)
+
+	l.Log(Record{Method: http.MethodPost, Path: "/v1/messages", Status: http.StatusOK, Model: "qwen", PromptTokens: 10})
+	l.Log(Record{Method: http.MethodPost, Path: "/v1/chat/completions", Status: http.StatusTooManyRequests})
+	l.Close(context.Background())
+	l.Log(Record{Path: "/v1/late"}) // Dropped once closed
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer func() {
+		_ = file.Close()
+	}()
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "qwen", records[0].Model)
+	assert.Equal(t, 10, records[0].PromptTokens)
+	assert.Equal(t, http.StatusTooManyRequests, records[1].Status)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm(), "the records may hold prompts")
+}
+
+func TestLoggerHTTP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		attempts int
+		lines    []string
+	)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		lines = append(lines, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+	}))
+	defer sink.Close()
+
+	l, err := New(sink.URL, 0, nil)
+	require.NoError(t, err)
+	l.backoff = time.Millisecond
+
+	l.Log(Record{Path: "/v1/messages", Model: "qwen"})
+	l.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, 2, attempts, "5xx answers are retried")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"model":"qwen"`)
+}
@@ -0,0 +1,159 @@
+// Package auth validates the API keys clients send to the proxy and maps them to tenants.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tenant is a client allowed to use the proxy with its own API key
+type Tenant struct {
+	Name   string   `json:"-"`
+	Key    string   `json:"key"`
+	Models []string `json:"models,omitempty"` // Models the key may use (empty allows all)
+	RPM    int      `json:"rpm,omitempty"`    // Requests per minute quota (0 = global limit)
+	TPM    int      `json:"tpm,omitempty"`    // Tokens per minute quota (0 = global limit)
+	Admin  bool     `json:"admin,omitempty"`  // May enable per-request debugging (X-Chill-Debug)
+	// Namespace whose vLLM pod serves the tenant (empty = the proxy's own, or the X-Chill-Namespace header)
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// AllowsModel reports whether the tenant may use any of the given names of a model
+func (t *Tenant) AllowsModel(names ...string) bool {
+	if len(t.Models) == 0 {
+		return true
+	}
+	for _, name := range names {
+		if name != "" && slices.Contains(t.Models, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTenants reads the tenants of a Secret: each entry is named after the tenant and holds a
+// JSON object with the key, and optionally the allowed models, quotas, admin flag and namespace, e.g.
+// {"key":"sk-...","models":["qwen"],"rpm":60,"tpm":100000,"admin":true,"namespace":"team-a"}
+func ParseTenants(data map[string][]byte) (map[string]*Tenant, error) {
+	tenants := make(map[string]*Tenant, len(data))
+	for name, raw := range data {
+		tenant := &Tenant{}
+		if err := json.Unmarshal(raw, tenant); err != nil {
+			return nil, fmt.Errorf("tenant %s: invalid JSON: %w", name, err)
+		}
+		if tenant.Key == "" {
+			return nil, fmt.Errorf("tenant %s: key cannot be empty", name)
+		}
+		if tenant.RPM < 0 || tenant.TPM < 0 {
+			return nil, fmt.Errorf("tenant %s: quotas cannot be negative", name)
+		}
+		tenant.Name = name
+
+		digest := Digest(tenant.Key)
+		if other, ok := tenants[digest]; ok {
+			return nil, fmt.Errorf("tenants %s and %s share the same key", other.Name, name)
+		}
+		tenants[digest] = tenant
+	}
+	return tenants, nil
+}
+
+// KeyStore holds the allowed API keys, indexed by digest
+type KeyStore struct {
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+}
+
+// NewKeyStore creates an empty store, which rejects every key until loaded
+func NewKeyStore() *KeyStore {
+	return &KeyStore{tenants: make(map[string]*Tenant)}
+}
+
+// Load replaces the tenants with the ones of a Secret, keeping the current ones if it is invalid
+func (s *KeyStore) Load(data map[string][]byte) error {
+	tenants, err := ParseTenants(data)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tenants = tenants
+	return nil
+}
+
+// Lookup returns the tenant owning a key
+func (s *KeyStore) Lookup(key string) (*Tenant, bool) {
+	if key == "" {
+		return nil, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tenant, ok := s.tenants[Digest(key)]
+	return tenant, ok
+}
+
+// Tenant returns the tenant with the given name
+func (s *KeyStore) Tenant(name string) (*Tenant, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, tenant := range s.tenants {
+		if tenant.Name == name {
+			return tenant, true
+		}
+	}
+	return nil, false
+}
+
+// Len returns the number of tenants
+func (s *KeyStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tenants)
+}
+
+// Quotas returns the tenants' quotas by key digest, for tenants that set one
+func (s *KeyStore) Quotas() map[string]*Tenant {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	quotas := make(map[string]*Tenant)
+	for digest, tenant := range s.tenants {
+		if tenant.RPM > 0 || tenant.TPM > 0 {
+			quotas[digest] = tenant
+		}
+	}
+	return quotas
+}
+
+// KeyFromRequest returns the API key sent as an Authorization bearer token or x-api-key header
+func KeyFromRequest(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	return r.Header.Get("x-api-key")
+}
+
+// Digest returns the SHA-256 hex digest of a key, the only form keys are compared and configured in
+func Digest(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a context carrying the authenticated tenant
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the authenticated tenant of a request context, nil when auth is disabled
+func TenantFrom(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return tenant
+}
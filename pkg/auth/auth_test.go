@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTenants(t *testing.T) {
+	tenants, err := ParseTenants(map[string][]byte{
+		"team-a": []byte(`{"key":"sk-a","models":["qwen"],"rpm":60}`),
+		"team-b": []byte(`{"key":"sk-b","admin":true,"namespace":"team-b"}`),
+	})
+	require.NoError(t, err)
+	require.Len(t, tenants, 2)
+
+	teamA := tenants[Digest("sk-a")]
+	require.NotNil(t, teamA)
+	assert.Equal(t, "team-a", teamA.Name)
+	assert.Equal(t, []string{"qwen"}, teamA.Models)
+	assert.Equal(t, 60, teamA.RPM)
+	assert.False(t, teamA.Admin)
+	assert.True(t, tenants[Digest("sk-b")].Admin)
+	assert.Empty(t, teamA.Namespace)
+	assert.Equal(t, "team-b", tenants[Digest("sk-b")].Namespace)
+}
+
+func TestParseTenantsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string][]byte
+		err  string
+	}{
+		{name: "invalid json", data: map[string][]byte{"a": []byte(`sk-a`)}, err: "tenant a: invalid JSON"},
+		{name: "empty key", data: map[string][]byte{"a": []byte(`{"models":["qwen"]}`)}, err: "tenant a: key cannot be empty"},
+		{name: "negative quota", data: map[string][]byte{"a": []byte(`{"key":"sk-a","tpm":-1}`)}, err: "quotas cannot be negative"},
+		{name: "shared key", data: map[string][]byte{"a": []byte(`{"key":"sk"}`), "b": []byte(`{"key":"sk"}`)}, err: "share the same key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTenants(tt.data)
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	store := NewKeyStore()
+	_, ok := store.Lookup("sk-a")
+	assert.False(t, ok, "an empty store rejects every key")
+
+	require.NoError(t, store.Load(map[string][]byte{
+		"team-a": []byte(`{"key":"sk-a","tpm":1000}`),
+		"team-b": []byte(`{"key":"sk-b"}`),
+	}))
+	tenant, ok := store.Lookup("sk-a")
+	require.True(t, ok)
+	assert.Equal(t, "team-a", tenant.Name)
+	tenant, ok = store.Tenant("team-b")
+	require.True(t, ok)
+	assert.Equal(t, "sk-b", tenant.Key)
+	_, ok = store.Tenant("team-c")
+	assert.False(t, ok)
+	_, ok = store.Lookup("")
+	assert.False(t, ok)
+	assert.Equal(t, 2, store.Len())
+	assert.Len(t, store.Quotas(), 1)
+
+	assert.Error(t, store.Load(map[string][]byte{"bad": []byte(`{}`)}))
+	_, ok = store.Lookup("sk-b")
+	assert.True(t, ok, "an invalid Secret keeps the current keys")
+}
+
+func TestTenantAllowsModel(t *testing.T) {
+	assert.True(t, (&Tenant{}).AllowsModel("anything"))
+
+	tenant := &Tenant{Models: []string{"qwen"}}
+	assert.True(t, tenant.AllowsModel("Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8", "qwen"))
+	assert.False(t, tenant.AllowsModel("llama", ""))
+}
+
+func TestKeyFromRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	assert.Empty(t, KeyFromRequest(r))
+
+	r.Header.Set("x-api-key", "sk-anthropic")
+	assert.Equal(t, "sk-anthropic", KeyFromRequest(r))
+
+	r.Header.Set("Authorization", "Bearer sk-openai")
+	assert.Equal(t, "sk-openai", KeyFromRequest(r))
+}
+
+func TestTenantContext(t *testing.T) {
+	assert.Nil(t, TenantFrom(context.Background()))
+
+	tenant := &Tenant{Name: "team-a"}
+	assert.Same(t, tenant, TenantFrom(WithTenant(context.Background(), tenant)))
+}
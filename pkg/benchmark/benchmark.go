@@ -0,0 +1,241 @@
+// Package benchmark runs a short standardized benchmark against a vLLM model.
+package benchmark
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standardized run settings, fixed so results are comparable across models and configurations
+const (
+	maxTokens = 256
+	baseSeed  = 1234
+)
+
+// prompts is the standardized prompt set, each sent with seed baseSeed+index at temperature 0
+var prompts = []string{
+	"Explain in three short paragraphs how a hash map handles collisions.",
+	"Write a Python function that returns the n-th Fibonacci number iteratively, with a docstring.",
+	"Summarize the causes of the French Revolution in five bullet points.",
+	"Translate to French: The quick brown fox jumps over the lazy dog while the cat sleeps.",
+	"List ten common Linux commands with a one-line description of each.",
+}
+
+// kvCacheMetrics are the vLLM gauges reporting KV cache usage (0-1), by vLLM version
+var kvCacheMetrics = []string{"vllm:kv_cache_usage_perc", "vllm:gpu_cache_usage_perc"}
+
+// Result holds the measurements of a benchmark run
+type Result struct {
+	Model              string    `json:"model"`
+	Prompts            int       `json:"prompts"`
+	CompletionTokens   int       `json:"completion_tokens"`
+	TokensPerSecond    float64   `json:"tokens_per_second"`      // Completion tokens per second after the first token
+	TimeToFirstTokenMs int64     `json:"time_to_first_token_ms"` // Mean over the prompt set
+	KVCacheHeadroom    float64   `json:"kv_cache_headroom_percent"`
+	KVCacheMeasured    bool      `json:"kv_cache_measured"` // Whether vLLM exposed KV cache usage metrics
+	CompletedAt        time.Time `json:"completed_at"`
+}
+
+// Runner sends the prompt set to a vLLM server
+type Runner struct {
+	target string
+	apiKey string
+	client *http.Client
+}
+
+// NewRunner creates a runner for the vLLM server at target (e.g., http://vllm-api:80)
+func NewRunner(target string, timeout time.Duration) *Runner {
+	return &Runner{
+		target: strings.TrimSuffix(target, "/"),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// SetTransport sets the transport the requests are sent through, e.g., with the TLS settings of
+// a vLLM serving HTTPS
+func (r *Runner) SetTransport(transport http.RoundTripper) {
+	r.client.Transport = transport
+}
+
+// SetAPIKey sets the API key the completions are sent with, the one vLLM was started with
+func (r *Runner) SetAPIKey(apiKey string) {
+	r.apiKey = apiKey
+}
+
+// Run sends each prompt in order, one at a time, to the model under the name vLLM serves it as,
+// and aggregates the measurements
+func (r *Runner) Run(ctx context.Context, model string) (*Result, error) {
+	result := &Result{Model: model, Prompts: len(prompts)}
+	var firstTokenTotal, generationTotal time.Duration
+	peakUsage := 0.0
+
+	for i, prompt := range prompts {
+		run, err := r.complete(ctx, model, prompt, baseSeed+i)
+		if err != nil {
+			return nil, fmt.Errorf("prompt %d: %w", i+1, err)
+		}
+		firstTokenTotal += run.firstToken
+		generationTotal += run.generation
+		result.CompletionTokens += run.tokens
+
+		if usage, ok := r.kvCacheUsage(ctx); ok {
+			result.KVCacheMeasured = true
+			peakUsage = max(peakUsage, usage)
+		}
+	}
+
+	result.TimeToFirstTokenMs = (firstTokenTotal / time.Duration(len(prompts))).Milliseconds()
+	if generationTotal > 0 {
+		result.TokensPerSecond = float64(result.CompletionTokens) / generationTotal.Seconds()
+	}
+	if result.KVCacheMeasured {
+		result.KVCacheHeadroom = (1 - peakUsage) * 100
+	}
+	result.CompletedAt = time.Now()
+	return result, nil
+}
+
+// completion holds the timings of one streamed completion
+type completion struct {
+	firstToken time.Duration // From request to first generated token
+	generation time.Duration // From first token to end of stream
+	tokens     int
+}
+
+// streamChunk is the subset of a streamed chat completion chunk the benchmark reads
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *struct {
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+// complete streams one chat completion and times it
+func (r *Runner) complete(ctx context.Context, model, prompt string, seed int) (*completion, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"model":          model,
+		"messages":       []map[string]string{{"role": "user", "content": prompt}},
+		"max_tokens":     maxTokens,
+		"temperature":    0,
+		"seed":           seed,
+		"stream":         true,
+		"stream_options": map[string]bool{"include_usage": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.target+"/v1/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	start := time.Now()
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("chat completion failed: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("chat completion returned %d: %s", resp.StatusCode, body)
+	}
+
+	run := &completion{}
+	var firstToken time.Time
+	deltas := 0
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("invalid SSE chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			run.tokens = chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" && choice.Delta.ReasoningContent == "" {
+				continue
+			}
+			if firstToken.IsZero() {
+				firstToken = time.Now()
+			}
+			deltas++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if firstToken.IsZero() {
+		return nil, fmt.Errorf("no tokens generated")
+	}
+
+	// Servers without usage reporting stream about one token per delta
+	if run.tokens == 0 {
+		run.tokens = deltas
+	}
+	run.firstToken = firstToken.Sub(start)
+	run.generation = time.Since(firstToken)
+	return run, nil
+}
+
+// kvCacheUsage reads the KV cache usage (0-1) from vLLM's Prometheus metrics
+func (r *Runner) kvCacheUsage(ctx context.Context) (float64, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.target+"/metrics", nil)
+	if err != nil {
+		return 0, false
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, false
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+
+	usage, found := 0.0, false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		for _, metric := range kvCacheMetrics {
+			if !strings.HasPrefix(line, metric+"{") && !strings.HasPrefix(line, metric+" ") {
+				continue
+			}
+			fields := strings.Fields(line)
+			value, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+			if err != nil {
+				continue
+			}
+			// Several GPUs or engines report separately, the fullest one bounds the headroom
+			usage, found = max(usage, value), true
+		}
+	}
+	return usage, found
+}
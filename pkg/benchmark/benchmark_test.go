@@ -0,0 +1,121 @@
+package benchmark
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVLLM streams a fixed completion and exposes KV cache metrics
+func fakeVLLM(t *testing.T, usage bool, metrics string) *httptest.Server {
+	seeds := make(map[int]bool)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Model       string  `json:"model"`
+			Seed        int     `json:"seed"`
+			Temperature float64 `json:"temperature"`
+			Stream      bool    `json:"stream"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "qwen", request.Model)
+		assert.True(t, request.Stream)
+		assert.Zero(t, request.Temperature)
+		assert.False(t, seeds[request.Seed], "each prompt uses its own seed")
+		seeds[request.Seed] = true
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"Hello", " world", "!"} {
+			_, _ = fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", token)
+		}
+		if usage {
+			_, _ = fmt.Fprint(w, `data: {"choices":[],"usage":{"completion_tokens":4}}`+"\n\n")
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		if metrics == "" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprint(w, metrics)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRunner_Run(t *testing.T) {
+	server := fakeVLLM(t, true, "# HELP vllm:kv_cache_usage_perc KV cache usage\n"+
+		`vllm:kv_cache_usage_perc{engine="0",model_name="qwen"} 0.25`+"\n"+
+		`vllm:kv_cache_usage_perc{engine="1",model_name="qwen"} 0.1`+"\n")
+	defer server.Close()
+
+	result, err := NewRunner(server.URL, 5*time.Second).Run(context.Background(), "qwen")
+	require.NoError(t, err)
+
+	assert.Equal(t, "qwen", result.Model)
+	assert.Equal(t, len(prompts), result.Prompts)
+	assert.Equal(t, 4*len(prompts), result.CompletionTokens, "usage reported by vLLM is preferred")
+	assert.Positive(t, result.TokensPerSecond)
+	assert.True(t, result.KVCacheMeasured)
+	assert.InDelta(t, 75.0, result.KVCacheHeadroom, 0.001)
+	assert.False(t, result.CompletedAt.IsZero())
+}
+
+func TestRunner_RunWithoutUsageOrMetrics(t *testing.T) {
+	server := fakeVLLM(t, false, "")
+	defer server.Close()
+
+	result, err := NewRunner(server.URL, 5*time.Second).Run(context.Background(), "qwen")
+	require.NoError(t, err)
+
+	assert.Equal(t, 3*len(prompts), result.CompletionTokens, "tokens are counted from deltas")
+	assert.False(t, result.KVCacheMeasured)
+	assert.Zero(t, result.KVCacheHeadroom)
+}
+
+func TestRunner_SendsAPIKey(t *testing.T) {
+	var models, authorizations []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var request struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		models = append(models, request.Model)
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"ok"}}]}`+"\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	runner := NewRunner(server.URL, 5*time.Second)
+	runner.SetAPIKey("sk-vllm")
+	_, err := runner.Run(context.Background(), "qwen3-coder")
+	require.NoError(t, err)
+
+	require.Len(t, models, len(prompts))
+	for i := range models {
+		assert.Equal(t, "qwen3-coder", models[i])
+		assert.Equal(t, "Bearer sk-vllm", authorizations[i])
+	}
+}
+
+func TestRunner_RunFailsOnErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"error":{"message":"model not loaded"}}`, http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := NewRunner(server.URL, 5*time.Second).Run(context.Background(), "qwen")
+	assert.ErrorContains(t, err, "prompt 1: chat completion returned 503")
+}
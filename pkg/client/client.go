@@ -0,0 +1,192 @@
+// Package client is a Go client for the status and admin APIs of a running vllm-chill proxy, for
+// services and operators integrating with it instead of hand-rolling HTTP calls.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/usage"
+)
+
+// Client calls the API of a running proxy
+type Client struct {
+	target string
+	token  string
+	apiKey string
+	client *http.Client
+}
+
+// New creates a client for the proxy at target (e.g., http://vllm-chill:8080) authenticated with
+// the admin token. The timeout must cover a cold start for the operations waiting until the
+// model is ready.
+func New(target, token string, timeout time.Duration) *Client {
+	return &Client{
+		target: strings.TrimSuffix(target, "/"),
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// WithAPIKey sets the API key sent to the /v1 endpoints, needed when the proxy validates keys
+// (API_KEYS_SECRET). The admin token is sent otherwise.
+func (c *Client) WithAPIKey(apiKey string) *Client {
+	c.apiKey = apiKey
+	return c
+}
+
+// Response is the outcome of an admin operation
+type Response struct {
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	ActiveModel string `json:"active_model"`
+}
+
+// Status is the state of the proxy reported by /proxy/status
+type Status struct {
+	ActiveModel       string    `json:"active_model"`
+	Ready             bool      `json:"ready"`
+	VLLMState         string    `json:"vllm_state"` // stopped, starting, running, stopping, gpu_driver_not_ready or downloading
+	LastActivity      time.Time `json:"last_activity"`
+	IdleSeconds       float64   `json:"idle_seconds"`
+	ColdStartQueue    int       `json:"cold_start_queue"`
+	ScaleStrategy     string    `json:"scale_strategy"`
+	XMLFallback       string    `json:"xml_fallback"`
+	GPUDriverNotReady bool      `json:"gpu_driver_not_ready"`
+}
+
+// Model is a model listed by /v1/models
+type Model struct {
+	ID          string `json:"id"` // The name vLLM serves the model as
+	Created     int64  `json:"created"`
+	OwnedBy     string `json:"owned_by"`
+	Root        string `json:"root,omitempty"` // Hugging Face model name
+	MaxModelLen int    `json:"max_model_len,omitempty"`
+	Active      bool   `json:"active"` // The model requests without a model field go to
+	Loaded      bool   `json:"loaded"` // Active and its pod is ready, requests are served without a cold start
+}
+
+// APIError is an error response of the proxy
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("proxy returned %d: %s", e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Status returns the active model and whether it is ready, /proxy/status needs no token
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	status := &Status{}
+	if err := c.do(ctx, http.MethodGet, "/proxy/status", c.token, status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListModels returns the models the proxy serves, from its VLLMModels, without waking vLLM
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	apiKey := c.apiKey
+	if apiKey == "" {
+		apiKey = c.token
+	}
+	var list struct {
+		Data []Model `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/v1/models", apiKey, &list); err != nil {
+		return nil, err
+	}
+	return list.Data, nil
+}
+
+// Usage returns the requests, tokens and GPU-seconds per model, per API key and per user
+func (c *Client) Usage(ctx context.Context) (*usage.Summary, error) {
+	summary := &usage.Summary{}
+	if err := c.do(ctx, http.MethodGet, "/proxy/usage", c.token, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// SwitchModel makes the model the active one without starting it
+func (c *Client) SwitchModel(ctx context.Context, modelID string) (*Response, error) {
+	return c.operation(ctx, "/proxy/admin/models/"+url.PathEscape(modelID)+"/switch")
+}
+
+// PreloadModel switches to the model if needed and waits until it is ready to serve requests
+func (c *Client) PreloadModel(ctx context.Context, modelID string) (*Response, error) {
+	return c.operation(ctx, "/proxy/admin/models/"+url.PathEscape(modelID)+"/activate")
+}
+
+// Start starts the active model and waits until it is ready
+func (c *Client) Start(ctx context.Context) (*Response, error) {
+	return c.operation(ctx, "/proxy/admin/start")
+}
+
+// Stop releases the active model using the proxy's scale strategy
+func (c *Client) Stop(ctx context.Context) (*Response, error) {
+	return c.operation(ctx, "/proxy/admin/stop")
+}
+
+// operation sends an admin operation
+func (c *Client) operation(ctx context.Context, path string) (*Response, error) {
+	response := &Response{}
+	if err := c.do(ctx, http.MethodPost, path, c.token, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// do sends a request with the bearer token and decodes its JSON response into out, or returns
+// the error response
+func (c *Client) do(ctx context.Context, method, path, token string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.target+path, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+		var errorResponse struct {
+			Error struct {
+				Message string `json:"message"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(body, &errorResponse) == nil && errorResponse.Error.Message != "" {
+			apiErr.Code = errorResponse.Error.Code
+			apiErr.Message = errorResponse.Error.Message
+		}
+		return apiErr
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid response from %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "s3cret"
+
+// fakeProxy serves the proxy endpoints the client calls, with models qwen and deepseek
+type fakeProxy struct {
+	activeModel string
+	ready       bool
+	stopped     bool
+	apiKeys     []string // Authorization headers of the /v1/models requests
+}
+
+func newFakeProxy(t *testing.T) (*fakeProxy, *httptest.Server) {
+	proxy := &fakeProxy{activeModel: "qwen"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /proxy/status", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"active_model":   proxy.activeModel,
+			"ready":          proxy.ready,
+			"vllm_state":     "running",
+			"last_activity":  "2026-01-02T03:04:05Z",
+			"scale_strategy": "delete",
+		})
+	})
+	mux.HandleFunc("GET /v1/models", func(w http.ResponseWriter, r *http.Request) {
+		proxy.apiKeys = append(proxy.apiKeys, r.Header.Get("Authorization"))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"object": "list",
+			"data": []map[string]interface{}{
+				{"id": "deepseek", "object": "model", "owned_by": "vllm-chill", "active": proxy.activeModel == "deepseek"},
+				{"id": "qwen", "object": "model", "owned_by": "vllm-chill", "root": "Qwen/Qwen3", "active": proxy.activeModel == "qwen", "loaded": proxy.ready},
+			},
+		})
+	})
+	mux.HandleFunc("GET /proxy/usage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			writeError(w, http.StatusUnauthorized, "Missing or invalid admin token", "invalid_admin_token")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"total":  map[string]interface{}{"requests": 3, "prompt_tokens": 120},
+			"models": map[string]interface{}{"qwen": map[string]interface{}{"requests": 3, "gpu_seconds": 1.5}},
+		})
+	})
+
+	admin := http.NewServeMux()
+	admin.HandleFunc("POST /proxy/admin/start", func(w http.ResponseWriter, _ *http.Request) {
+		proxy.ready, proxy.stopped = true, false
+		proxy.succeed(w, "vLLM started successfully")
+	})
+	admin.HandleFunc("POST /proxy/admin/stop", func(w http.ResponseWriter, _ *http.Request) {
+		proxy.ready, proxy.stopped = false, true
+		proxy.succeed(w, "vLLM stopped successfully")
+	})
+	admin.HandleFunc("POST /proxy/admin/models/{id}/{operation}", func(w http.ResponseWriter, r *http.Request) {
+		modelID := r.PathValue("id")
+		if modelID != "qwen" && modelID != "deepseek" {
+			writeError(w, http.StatusNotFound, "model '"+modelID+"' not found", "model_not_found")
+			return
+		}
+		proxy.activeModel = modelID
+		proxy.ready = r.PathValue("operation") == "activate"
+		proxy.succeed(w, "Switched to model "+modelID)
+	})
+	mux.Handle("/proxy/admin/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			writeError(w, http.StatusUnauthorized, "Missing or invalid admin token", "invalid_admin_token")
+			return
+		}
+		admin.ServeHTTP(w, r)
+	}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return proxy, server
+}
+
+func (p *fakeProxy) succeed(w http.ResponseWriter, message string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"status": "success", "message": message, "active_model": p.activeModel})
+}
+
+func writeError(w http.ResponseWriter, status int, message, code string) {
+	writeJSON(w, status, map[string]interface{}{"error": map[string]interface{}{"message": message, "code": code}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func TestClient_SwitchModel(t *testing.T) {
+	proxy, server := newFakeProxy(t)
+
+	response, err := New(server.URL+"/", testToken, time.Second).SwitchModel(context.Background(), "deepseek")
+	require.NoError(t, err)
+	assert.Equal(t, "success", response.Status)
+	assert.Equal(t, "deepseek", response.ActiveModel)
+	assert.False(t, proxy.ready, "a switch doesn't start the model")
+}
+
+func TestClient_PreloadModel(t *testing.T) {
+	_, server := newFakeProxy(t)
+	client := New(server.URL, testToken, time.Second)
+
+	response, err := client.PreloadModel(context.Background(), "deepseek")
+	require.NoError(t, err)
+	assert.Equal(t, "deepseek", response.ActiveModel)
+
+	status, err := client.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "deepseek", status.ActiveModel)
+	assert.True(t, status.Ready)
+	assert.Equal(t, "running", status.VLLMState)
+	assert.Equal(t, "delete", status.ScaleStrategy)
+	assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), status.LastActivity)
+}
+
+func TestClient_StartStop(t *testing.T) {
+	proxy, server := newFakeProxy(t)
+	client := New(server.URL, testToken, time.Second)
+
+	response, err := client.Start(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "vLLM started successfully", response.Message)
+	assert.True(t, proxy.ready)
+
+	response, err = client.Stop(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "qwen", response.ActiveModel)
+	assert.True(t, proxy.stopped)
+}
+
+func TestClient_ListModels(t *testing.T) {
+	proxy, server := newFakeProxy(t)
+	proxy.ready = true
+
+	models, err := New(server.URL, testToken, time.Second).ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, "deepseek", models[0].ID)
+	assert.False(t, models[0].Active)
+	assert.Equal(t, Model{ID: "qwen", OwnedBy: "vllm-chill", Root: "Qwen/Qwen3", Active: true, Loaded: true}, models[1])
+
+	_, err = New(server.URL, testToken, time.Second).WithAPIKey("sk-tenant").ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer " + testToken, "Bearer sk-tenant"}, proxy.apiKeys, "the API key replaces the admin token")
+}
+
+func TestClient_Usage(t *testing.T) {
+	_, server := newFakeProxy(t)
+
+	summary, err := New(server.URL, testToken, time.Second).Usage(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Total.Requests)
+	assert.Equal(t, int64(120), summary.Total.PromptTokens)
+	require.Contains(t, summary.Models, "qwen")
+	assert.Equal(t, 1.5, summary.Models["qwen"].GPUSeconds)
+}
+
+func TestClient_APIErrors(t *testing.T) {
+	proxy, server := newFakeProxy(t)
+
+	_, err := New(server.URL, testToken, time.Second).PreloadModel(context.Background(), "unknown")
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "model_not_found", apiErr.Code)
+
+	_, err = New(server.URL, "wrong", time.Second).SwitchModel(context.Background(), "deepseek")
+	assert.ErrorContains(t, err, "invalid_admin_token")
+	assert.Equal(t, "qwen", proxy.activeModel)
+
+	_, err = New(server.URL, testToken, time.Second).Status(context.Background())
+	require.NoError(t, err)
+	_, err = New(server.URL+"/missing", testToken, time.Second).Status(context.Background())
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, "proxy returned 404: 404 page not found", apiErr.Error())
+}
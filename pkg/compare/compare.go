@@ -0,0 +1,177 @@
+// Package compare sends the same chat completion request to several models and measures how
+// their responses differ, for model evaluation.
+package compare
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// chatCompletionsPath is the endpoint compared requests are sent to
+const chatCompletionsPath = "/v1/chat/completions"
+
+// Response is a model's response to the compared request
+type Response struct {
+	Model      string          `json:"model"`
+	StatusCode int             `json:"status_code"`
+	DurationMs int64           `json:"duration_ms"`
+	Metrics    Metrics         `json:"metrics"`
+	Body       json.RawMessage `json:"response"`
+}
+
+// Metrics summarizes a chat completion response
+type Metrics struct {
+	ContentLength    int      `json:"content_length"` // Characters of the assistant message content
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	ToolCalls        []string `json:"tool_calls"` // Names of the called tools, in order
+	FinishReason     string   `json:"finish_reason"`
+}
+
+// Diff compares the second response to the first one
+type Diff struct {
+	ContentLengthDelta    int     `json:"content_length_delta"`
+	CompletionTokensDelta int     `json:"completion_tokens_delta"`
+	DurationMsDelta       int64   `json:"duration_ms_delta"`
+	CompletionTokensRatio float64 `json:"completion_tokens_ratio"` // Second over first, 0 when the first has none
+	SameToolCalls         bool    `json:"same_tool_calls"`
+	SameFinishReason      bool    `json:"same_finish_reason"`
+}
+
+// Result holds the responses of both models and their diff
+type Result struct {
+	Responses []*Response `json:"responses"`
+	Diff      Diff        `json:"diff"`
+}
+
+// NewResult compares the responses of two models to the same request
+func NewResult(first, second *Response) *Result {
+	diff := Diff{
+		ContentLengthDelta:    second.Metrics.ContentLength - first.Metrics.ContentLength,
+		CompletionTokensDelta: second.Metrics.CompletionTokens - first.Metrics.CompletionTokens,
+		DurationMsDelta:       second.DurationMs - first.DurationMs,
+		SameToolCalls:         strings.Join(first.Metrics.ToolCalls, ",") == strings.Join(second.Metrics.ToolCalls, ","),
+		SameFinishReason:      first.Metrics.FinishReason == second.Metrics.FinishReason,
+	}
+	if first.Metrics.CompletionTokens > 0 {
+		diff.CompletionTokensRatio = float64(second.Metrics.CompletionTokens) / float64(first.Metrics.CompletionTokens)
+	}
+	return &Result{Responses: []*Response{first, second}, Diff: diff}
+}
+
+// Measure extracts the metrics of a non-streaming chat completion response
+func Measure(body []byte) Metrics {
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content   string `json:"content"`
+				ToolCalls []struct {
+					Function struct {
+						Name string `json:"name"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	metrics := Metrics{ToolCalls: []string{}}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return metrics
+	}
+
+	metrics.PromptTokens = completion.Usage.PromptTokens
+	metrics.CompletionTokens = completion.Usage.CompletionTokens
+	if len(completion.Choices) > 0 {
+		choice := completion.Choices[0]
+		metrics.ContentLength = len([]rune(choice.Message.Content))
+		metrics.FinishReason = choice.FinishReason
+		for _, call := range choice.Message.ToolCalls {
+			metrics.ToolCalls = append(metrics.ToolCalls, call.Function.Name)
+		}
+	}
+	return metrics
+}
+
+// Client sends compared requests to a vLLM server
+type Client struct {
+	target string
+	apiKey string
+	client *http.Client
+}
+
+// NewClient creates a client for the vLLM server at target (e.g., http://vllm-api:80)
+func NewClient(target, apiKey string, timeout time.Duration) *Client {
+	return &Client{
+		target: strings.TrimSuffix(target, "/"),
+		apiKey: apiKey,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// SetTransport sets the transport the requests are sent through, e.g., with the TLS settings of
+// a vLLM serving HTTPS
+func (c *Client) SetTransport(transport http.RoundTripper) {
+	c.client.Transport = transport
+}
+
+// Send sends the chat completion request to the model, without streaming, and measures the response.
+// Error responses are returned as responses, only transport failures are errors.
+func (c *Client) Send(ctx context.Context, model string, request map[string]interface{}) (*Response, error) {
+	payload := make(map[string]interface{}, len(request)+1)
+	for k, v := range request {
+		payload[k] = v
+	}
+	payload["model"] = model
+	payload["stream"] = false
+	delete(payload, "stream_options")
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.target+chatCompletionsPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response of %s: %w", model, err)
+	}
+
+	response := &Response{
+		Model:      model,
+		StatusCode: resp.StatusCode,
+		DurationMs: time.Since(start).Milliseconds(),
+		Metrics:    Measure(respBody),
+		Body:       respBody,
+	}
+	if !json.Valid(respBody) {
+		// Keep the response valid JSON when vLLM answers with plain text
+		quoted, _ := json.Marshal(string(respBody))
+		response.Body = quoted
+	}
+	return response, nil
+}
@@ -0,0 +1,103 @@
+package compare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const toolCallCompletion = `{
+	"choices": [{
+		"message": {"role": "assistant", "content": "", "tool_calls": [
+			{"id": "c1", "type": "function", "function": {"name": "get_weather", "arguments": "{}"}},
+			{"id": "c2", "type": "function", "function": {"name": "get_time", "arguments": "{}"}}
+		]},
+		"finish_reason": "tool_calls"
+	}],
+	"usage": {"prompt_tokens": 120, "completion_tokens": 40}
+}`
+
+func TestMeasure(t *testing.T) {
+	metrics := Measure([]byte(toolCallCompletion))
+	assert.Equal(t, Metrics{
+		PromptTokens:     120,
+		CompletionTokens: 40,
+		ToolCalls:        []string{"get_weather", "get_time"},
+		FinishReason:     "tool_calls",
+	}, metrics)
+
+	metrics = Measure([]byte(`{"choices":[{"message":{"content":"Très bien"},"finish_reason":"stop"}]}`))
+	assert.Equal(t, 9, metrics.ContentLength, "content is measured in characters")
+	assert.Empty(t, metrics.ToolCalls)
+
+	assert.Equal(t, Metrics{ToolCalls: []string{}}, Measure([]byte("Internal Server Error")))
+}
+
+func TestNewResult(t *testing.T) {
+	first := &Response{Model: "qwen", DurationMs: 800, Metrics: Measure([]byte(toolCallCompletion))}
+	second := &Response{Model: "deepseek", DurationMs: 1200, Metrics: Metrics{
+		ContentLength:    42,
+		CompletionTokens: 60,
+		ToolCalls:        []string{"get_weather"},
+		FinishReason:     "tool_calls",
+	}}
+
+	result := NewResult(first, second)
+	assert.Equal(t, []*Response{first, second}, result.Responses)
+	assert.Equal(t, Diff{
+		ContentLengthDelta:    42,
+		CompletionTokensDelta: 20,
+		DurationMsDelta:       400,
+		CompletionTokensRatio: 1.5,
+		SameToolCalls:         false,
+		SameFinishReason:      true,
+	}, result.Diff)
+}
+
+func TestClientSend(t *testing.T) {
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, chatCompletionsPath, r.URL.Path)
+		assert.Equal(t, "Bearer sk-vllm", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "qwen", body["model"])
+		assert.Equal(t, false, body["stream"], "responses are compared whole")
+		assert.NotContains(t, body, "stream_options")
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(toolCallCompletion))
+	}))
+	defer vllm.Close()
+
+	request := map[string]interface{}{
+		"model":          "ignored",
+		"stream":         true,
+		"stream_options": map[string]interface{}{"include_usage": true},
+		"messages":       []interface{}{map[string]interface{}{"role": "user", "content": "Weather in Paris?"}},
+	}
+	response, err := NewClient(vllm.URL, "sk-vllm", time.Second).Send(context.Background(), "qwen", request)
+	require.NoError(t, err)
+	assert.Equal(t, "qwen", response.Model)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 40, response.Metrics.CompletionTokens)
+	assert.JSONEq(t, toolCallCompletion, string(response.Body))
+	assert.Equal(t, true, request["stream"], "the caller's request is not modified")
+}
+
+func TestClientSendPlainTextError(t *testing.T) {
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+	}))
+	defer vllm.Close()
+
+	response, err := NewClient(vllm.URL, "", time.Second).Send(context.Background(), "qwen", map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.JSONEq(t, `"model overloaded\n"`, string(response.Body))
+}
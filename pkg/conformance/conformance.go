@@ -0,0 +1,509 @@
+// Package conformance runs end-to-end checks against a live vllm-chill deployment.
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// unknownModel is requested to exercise the model-not-found error path
+const unknownModel = "vllm-chill-conformance-unknown-model"
+
+// Config holds the conformance run settings
+type Config struct {
+	Target     string        // Base URL of the vllm-chill proxy (e.g., http://vllm-chill:8080)
+	Model      string        // Model to test, defaults to the active model reported by /proxy/status
+	APIKey     string        // Bearer token sent on /v1 requests (optional)
+	AdminToken string        // Admin API token, used to scale down first and force a cold start (optional)
+	Timeout    time.Duration // Per-request timeout, must cover a cold start
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Name     string        `json:"name"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Report is the outcome of a conformance run
+type Report struct {
+	Target  string   `json:"target"`
+	Model   string   `json:"model"`
+	Results []Result `json:"results"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+	Skipped int      `json:"skipped"`
+}
+
+// Runner executes the conformance checks
+type Runner struct {
+	config *Config
+	client *http.Client
+	model  string
+	warm   bool // Whether the model was ready before the run
+}
+
+// skipError marks a check as skipped rather than failed
+type skipError struct {
+	reason string
+}
+
+func (e *skipError) Error() string {
+	return e.reason
+}
+
+// skip returns an error that marks the check as skipped
+func skip(format string, args ...interface{}) error {
+	return &skipError{reason: fmt.Sprintf(format, args...)}
+}
+
+// NewRunner creates a runner for the given configuration
+func NewRunner(config *Config) *Runner {
+	return &Runner{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+		model:  config.Model,
+	}
+}
+
+// Run executes all checks in order and returns the report
+func (r *Runner) Run(ctx context.Context) *Report {
+	checks := []struct {
+		name string
+		run  func(ctx context.Context) (string, error)
+	}{
+		{"health", r.checkHealth},
+		{"status", r.checkStatus},
+		{"error_shape", r.checkErrorShape},
+		{"cold_start", r.checkColdStart},
+		{"models_listing", r.checkModelsListing},
+		{"chat_completion", r.checkChatCompletion},
+		{"streaming_chat", r.checkStreamingChat},
+		{"tool_calls", r.checkToolCalls},
+		{"anthropic_messages", r.checkAnthropicMessages},
+	}
+
+	report := &Report{Target: r.config.Target}
+	for _, check := range checks {
+		start := time.Now()
+		detail, err := check.run(ctx)
+		result := Result{Name: check.name, Status: StatusPass, Detail: detail, Duration: time.Since(start)}
+		if err != nil {
+			result.Status = StatusFail
+			if _, ok := err.(*skipError); ok {
+				result.Status = StatusSkip
+			}
+			result.Detail = err.Error()
+		}
+
+		switch result.Status {
+		case StatusPass:
+			report.Passed++
+		case StatusFail:
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Model = r.model
+	return report
+}
+
+// WriteText writes a human readable pass/fail report
+func (rep *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Conformance report for %s (model: %s)\n\n", rep.Target, rep.Model)
+	for _, result := range rep.Results {
+		fmt.Fprintf(&b, "  %-4s  %-20s %8s  %s\n",
+			strings.ToUpper(result.Status), result.Name, result.Duration.Round(time.Millisecond), result.Detail)
+	}
+	fmt.Fprintf(&b, "\n%d passed, %d failed, %d skipped\n", rep.Passed, rep.Failed, rep.Skipped)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r *Runner) checkHealth(ctx context.Context) (string, error) {
+	resp, body, err := r.do(ctx, http.MethodGet, "/health", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET /health returned %d: %s", resp.StatusCode, truncate(body))
+	}
+	return "proxy is healthy", nil
+}
+
+func (r *Runner) checkStatus(ctx context.Context) (string, error) {
+	var status struct {
+		ActiveModel string `json:"active_model"`
+		Ready       bool   `json:"ready"`
+	}
+	if err := r.getJSON(ctx, "/proxy/status", &status); err != nil {
+		return "", err
+	}
+	if status.ActiveModel == "" {
+		return "", fmt.Errorf("/proxy/status has no active_model")
+	}
+
+	r.warm = status.Ready
+	if r.model == "" {
+		r.model = status.ActiveModel
+	}
+	return fmt.Sprintf("active model %s, ready=%t", status.ActiveModel, status.Ready), nil
+}
+
+func (r *Runner) checkErrorShape(ctx context.Context) (string, error) {
+	resp, body, err := r.do(ctx, http.MethodPost, "/v1/chat/completions", r.chatRequest(unknownModel, false, nil), nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return "", fmt.Errorf("unknown model returned %d, expected 404: %s", resp.StatusCode, truncate(body))
+	}
+	if err := checkOpenAIError(body); err != nil {
+		return "", err
+	}
+	return "unknown model returns an OpenAI error object", nil
+}
+
+func (r *Runner) checkColdStart(ctx context.Context) (string, error) {
+	if r.config.AdminToken != "" && r.warm {
+		headers := map[string]string{"Authorization": "Bearer " + r.config.AdminToken}
+		resp, body, err := r.do(ctx, http.MethodPost, "/proxy/admin/scale-down", nil, headers)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("admin scale-down returned %d: %s", resp.StatusCode, truncate(body))
+		}
+		r.warm = false
+	}
+	if r.warm {
+		return "", skip("model already warm (set --admin-token to force a cold start)")
+	}
+
+	start := time.Now()
+	if _, err := r.chat(ctx); err != nil {
+		return "", fmt.Errorf("first request after scale-down failed: %w", err)
+	}
+	r.warm = true
+	return fmt.Sprintf("cold start served in %s", time.Since(start).Round(time.Second)), nil
+}
+
+func (r *Runner) checkModelsListing(ctx context.Context) (string, error) {
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := r.getJSON(ctx, "/v1/models", &models); err != nil {
+		return "", err
+	}
+	for _, model := range models.Data {
+		if model.ID == r.model {
+			return fmt.Sprintf("%d models listed", len(models.Data)), nil
+		}
+	}
+	return "", fmt.Errorf("model %s not in /v1/models (%d models listed)", r.model, len(models.Data))
+}
+
+func (r *Runner) checkChatCompletion(ctx context.Context) (string, error) {
+	body, err := r.chat(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var completion struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Message struct {
+				Role string `json:"role"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return "", fmt.Errorf("invalid chat completion JSON: %w", err)
+	}
+	if completion.Object != "chat.completion" || len(completion.Choices) == 0 || completion.Choices[0].Message.Role != "assistant" {
+		return "", fmt.Errorf("unexpected chat completion: %s", truncate(body))
+	}
+	return "non-streaming chat completion returned an assistant message", nil
+}
+
+func (r *Runner) checkStreamingChat(ctx context.Context) (string, error) {
+	chunks, err := r.stream(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d chunks streamed before [DONE]", len(chunks)), nil
+}
+
+func (r *Runner) checkToolCalls(ctx context.Context) (string, error) {
+	tools := []map[string]interface{}{{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        "get_weather",
+			"description": "Get the current weather for a city",
+			"parameters": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+				"required":   []string{"city"},
+			},
+		},
+	}}
+	chunks, err := r.stream(ctx, tools)
+	if err != nil {
+		return "", err
+	}
+
+	// Tool call arguments may be split across deltas
+	var name string
+	var arguments strings.Builder
+	for _, chunk := range chunks {
+		for _, choice := range chunk.Choices {
+			for _, call := range choice.Delta.ToolCalls {
+				if call.Function.Name != "" {
+					name = call.Function.Name
+				}
+				arguments.WriteString(call.Function.Arguments)
+			}
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("no tool_calls in the streamed response")
+	}
+	if name != "get_weather" {
+		return "", fmt.Errorf("unexpected tool call %q", name)
+	}
+	if !json.Valid([]byte(arguments.String())) {
+		return "", fmt.Errorf("tool call arguments are not valid JSON: %s", truncate([]byte(arguments.String())))
+	}
+	return "streamed tool call " + name + arguments.String(), nil
+}
+
+func (r *Runner) checkAnthropicMessages(ctx context.Context) (string, error) {
+	request := map[string]interface{}{
+		"model":      r.model,
+		"max_tokens": 16,
+		"messages":   []map[string]string{{"role": "user", "content": "Say hello."}},
+	}
+	resp, body, err := r.do(ctx, http.MethodPost, "/v1/messages", request, r.authHeaders())
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return "", skip("/v1/messages not supported by this deployment")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("POST /v1/messages returned %d: %s", resp.StatusCode, truncate(body))
+	}
+
+	var message struct {
+		Type    string            `json:"type"`
+		Content []json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil || message.Type != "message" || len(message.Content) == 0 {
+		return "", fmt.Errorf("unexpected Anthropic message: %s", truncate(body))
+	}
+	return "Anthropic message returned", nil
+}
+
+// streamChunk is the subset of a chat completion chunk the checks inspect
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// stream sends a streaming chat request and returns the parsed chunks, failing without [DONE]
+func (r *Runner) stream(ctx context.Context, tools []map[string]interface{}) ([]streamChunk, error) {
+	resp, err := r.send(ctx, http.MethodPost, "/v1/chat/completions", r.chatRequest(r.model, true, tools), r.authHeaders())
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("streaming chat returned %d: %s", resp.StatusCode, truncate(body))
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return nil, fmt.Errorf("streaming chat returned Content-Type %q", resp.Header.Get("Content-Type"))
+	}
+
+	var chunks []streamChunk
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			return chunks, nil
+		}
+		var chunk streamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("invalid SSE chunk: %s", truncate([]byte(data)))
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("stream ended without [DONE] after %d chunks", len(chunks))
+}
+
+// chat sends a non-streaming chat request for the tested model and returns the body of a 200 response
+func (r *Runner) chat(ctx context.Context) ([]byte, error) {
+	resp, body, err := r.do(ctx, http.MethodPost, "/v1/chat/completions", r.chatRequest(r.model, false, nil), r.authHeaders())
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("chat completion returned %d: %s", resp.StatusCode, truncate(body))
+	}
+	return body, nil
+}
+
+// chatRequest builds a small chat completion request
+func (r *Runner) chatRequest(model string, stream bool, tools []map[string]interface{}) map[string]interface{} {
+	prompt := "Reply with the single word: pong"
+	if len(tools) > 0 {
+		prompt = "What is the weather in Paris? Use the get_weather tool."
+	}
+	request := map[string]interface{}{
+		"model":      model,
+		"stream":     stream,
+		"max_tokens": 256,
+		"messages":   []map[string]string{{"role": "user", "content": prompt}},
+	}
+	if len(tools) > 0 {
+		request["tools"] = tools
+		request["tool_choice"] = "auto"
+	}
+	return request
+}
+
+// getJSON decodes the JSON body of a 200 GET response
+func (r *Runner) getJSON(ctx context.Context, path string, out interface{}) error {
+	resp, body, err := r.do(ctx, http.MethodGet, path, nil, r.authHeaders())
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %d: %s", path, resp.StatusCode, truncate(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid JSON from %s: %w", path, err)
+	}
+	return nil
+}
+
+// authHeaders returns the API key header for /v1 requests
+func (r *Runner) authHeaders() map[string]string {
+	if r.config.APIKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + r.config.APIKey}
+}
+
+// do sends a request and reads the whole response body
+func (r *Runner) do(ctx context.Context, method, path string, payload interface{}, headers map[string]string) (*http.Response, []byte, error) {
+	resp, err := r.send(ctx, method, path, payload, headers)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s response: %w", path, err)
+	}
+	return resp, body, nil
+}
+
+// send sends a request with an optional JSON payload
+func (r *Runner) send(ctx context.Context, method, path string, payload interface{}, headers map[string]string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(r.config.Target, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+// checkOpenAIError verifies body is an OpenAI error object with message, type and code
+func checkOpenAIError(body []byte) error {
+	var response struct {
+		Error *struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Error == nil {
+		return fmt.Errorf("error response is not an OpenAI error object: %s", truncate(body))
+	}
+	if response.Error.Message == "" || response.Error.Type == "" || response.Error.Code == "" {
+		return fmt.Errorf("error object is missing message, type or code: %s", truncate(body))
+	}
+	return nil
+}
+
+// truncate shortens a response body for report details
+func truncate(body []byte) string {
+	const maxLen = 200
+	s := strings.TrimSpace(string(body))
+	if len(s) > maxLen {
+		return s[:maxLen] + "..."
+	}
+	return s
+}
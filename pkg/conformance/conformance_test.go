@@ -0,0 +1,155 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDeployment simulates a vllm-chill proxy in front of a vLLM pod
+type fakeDeployment struct {
+	ready      bool
+	messages   bool // Whether /v1/messages is served
+	scaledDown bool
+}
+
+func (d *fakeDeployment) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/proxy/status", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"active_model": "qwen", "ready": d.ready})
+	})
+	mux.HandleFunc("/proxy/admin/scale-down", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer admin" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		d.scaledDown = true
+		d.ready = false
+		_ = json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	})
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, _ *http.Request) {
+		d.ready = true
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": []map[string]string{{"id": "qwen"}}})
+	})
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Model  string        `json:"model"`
+			Stream bool          `json:"stream"`
+			Tools  []interface{} `json:"tools"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Header().Set("Content-Type", "application/json")
+
+		if request.Model != "qwen" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"error":{"message":"Model not found","type":"invalid_request_error","code":"model_not_found"}}`)
+			return
+		}
+		d.ready = true
+
+		if !request.Stream {
+			_, _ = fmt.Fprint(w, `{"object":"chat.completion","choices":[{"message":{"role":"assistant","content":"pong"}}]}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(request.Tools) > 0 {
+			_, _ = fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"function":{"name":"get_weather","arguments":"{\"city\":"}}]}}]}`+"\n\n")
+			_, _ = fmt.Fprint(w, `data: {"choices":[{"delta":{"tool_calls":[{"function":{"arguments":"\"Paris\"}"}}]}}]}`+"\n\n")
+		} else {
+			_, _ = fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"po"}}]}`+"\n\n")
+			_, _ = fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"ng"}}]}`+"\n\n")
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+	mux.HandleFunc("/v1/messages", func(w http.ResponseWriter, _ *http.Request) {
+		if !d.messages {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"type":"message","content":[{"type":"text","text":"Hello"}]}`)
+	})
+	return mux
+}
+
+func resultsByName(report *Report) map[string]Result {
+	results := make(map[string]Result, len(report.Results))
+	for _, result := range report.Results {
+		results[result.Name] = result
+	}
+	return results
+}
+
+func TestRunner_PassesAgainstConformantDeployment(t *testing.T) {
+	deployment := &fakeDeployment{ready: true, messages: true}
+	server := httptest.NewServer(deployment.handler(t))
+	defer server.Close()
+
+	report := NewRunner(&Config{Target: server.URL, AdminToken: "admin", Timeout: 5 * time.Second}).Run(context.Background())
+
+	for _, result := range report.Results {
+		assert.Equal(t, StatusPass, result.Status, "%s: %s", result.Name, result.Detail)
+	}
+	assert.Equal(t, "qwen", report.Model)
+	assert.Equal(t, len(report.Results), report.Passed)
+	assert.True(t, deployment.scaledDown)
+	assert.Contains(t, resultsByName(report)["tool_calls"].Detail, `get_weather{"city":"Paris"}`)
+}
+
+func TestRunner_SkipsUnsupportedChecks(t *testing.T) {
+	deployment := &fakeDeployment{ready: true}
+	server := httptest.NewServer(deployment.handler(t))
+	defer server.Close()
+
+	report := NewRunner(&Config{Target: server.URL, Timeout: 5 * time.Second}).Run(context.Background())
+	results := resultsByName(report)
+
+	assert.Equal(t, StatusSkip, results["cold_start"].Status)
+	assert.Equal(t, StatusSkip, results["anthropic_messages"].Status)
+	assert.False(t, deployment.scaledDown)
+	assert.Equal(t, 0, report.Failed)
+	assert.Equal(t, 2, report.Skipped)
+}
+
+func TestRunner_ReportsFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	report := NewRunner(&Config{Target: server.URL, Model: "qwen", Timeout: 5 * time.Second}).Run(context.Background())
+	results := resultsByName(report)
+
+	assert.Equal(t, StatusPass, results["health"].Status)
+	assert.Equal(t, StatusFail, results["status"].Status)
+	assert.Equal(t, StatusFail, results["error_shape"].Status)
+	assert.Contains(t, results["streaming_chat"].Detail, "502")
+	assert.Positive(t, report.Failed)
+
+	var text strings.Builder
+	require.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "FAIL  status")
+	assert.Contains(t, text.String(), fmt.Sprintf("1 passed, %d failed", report.Failed))
+}
+
+func TestCheckOpenAIError(t *testing.T) {
+	assert.NoError(t, checkOpenAIError([]byte(`{"error":{"message":"m","type":"t","code":"c"}}`)))
+	assert.Error(t, checkOpenAIError([]byte(`{"error":{"message":"m"}}`)))
+	assert.Error(t, checkOpenAIError([]byte(`{"detail":"Not Found"}`)))
+	assert.Error(t, checkOpenAIError([]byte(`Bad Gateway`)))
+}
@@ -0,0 +1,176 @@
+// Package docker runs the vLLM server in a local Docker or Podman container instead of a
+// Kubernetes pod, for single GPU workstations without a cluster.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DefaultHost is the Docker Engine API socket. Podman serves the same API on
+// unix:///run/podman/podman.sock, or $XDG_RUNTIME_DIR/podman/podman.sock when rootless.
+const DefaultHost = "unix:///var/run/docker.sock"
+
+// apiVersion is the Engine API version the requests are made with, served by Docker 20.10 and
+// Podman 3 onwards
+const apiVersion = "v1.41"
+
+// client calls the Engine API over a unix socket or TCP
+type client struct {
+	http    *http.Client
+	baseURL string
+}
+
+// newClient returns a client of the Engine API at host: unix:///path/to.sock, tcp://host:port
+// or an http(s) URL
+func newClient(host string) (*client, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+		}
+		return &client{http: &http.Client{Transport: transport}, baseURL: "http://docker/" + apiVersion}, nil
+	case "tcp", "http":
+		return &client{http: &http.Client{}, baseURL: "http://" + u.Host + "/" + apiVersion}, nil
+	case "https":
+		return &client{http: &http.Client{}, baseURL: "https://" + u.Host + "/" + apiVersion}, nil
+	default:
+		return nil, fmt.Errorf("invalid docker host %q (expected unix://, tcp:// or http(s)://)", host)
+	}
+}
+
+// APIError is an error response of the Engine API
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("docker API error (%d): %s", e.StatusCode, e.Message)
+}
+
+// isNotFound reports whether err is a 404 of the Engine API, for a missing container or image
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends a request with an optional JSON body, returning the response of a successful status.
+// The caller closes its body.
+func (c *client) do(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the docker API: %w", err)
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotModified {
+		defer func() { _ = resp.Body.Close() }()
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: apiErr.Message}
+	}
+	return resp, nil
+}
+
+// call sends a request and decodes the JSON response into out when set
+func (c *client) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// pullImage pulls an image, the Engine API streaming the progress and any failure as JSON messages
+func (c *client) pullImage(ctx context.Context, image string) error {
+	resp, err := c.do(ctx, http.MethodPost, "/images/create", url.Values{"fromImage": {image}}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to pull image %s: %w", image, err)
+		}
+		if message.Error != "" {
+			return fmt.Errorf("failed to pull image %s: %s", image, message.Error)
+		}
+	}
+}
+
+// demuxLogs returns the text of a container log stream, which the Engine API multiplexes in
+// frames of an 8-byte header (stream type, then the big-endian payload size) for containers
+// without a TTY
+func demuxLogs(data []byte) string {
+	var out strings.Builder
+	for len(data) >= 8 {
+		if data[0] > 2 || data[1] != 0 || data[2] != 0 || data[3] != 0 {
+			break // Not a frame header, the stream is raw
+		}
+		size := int(binary.BigEndian.Uint32(data[4:8]))
+		data = data[8:]
+		if size > len(data) {
+			size = len(data)
+		}
+		out.Write(data[:size])
+		data = data[size:]
+	}
+	out.Write(data)
+	return out.String()
+}
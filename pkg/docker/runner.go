@@ -0,0 +1,354 @@
+package docker
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+const (
+	// SpecHashLabel holds the hash of the container config a vLLM container was created from, so
+	// drift is detected like for pods
+	SpecHashLabel = kubernetes.SpecHashAnnotation
+
+	vllmPort       = "8000/tcp"
+	hfCacheMount   = "/root/.cache/huggingface"
+	defaultHFCache = ".cache/huggingface" // Under the home directory
+	maxLogBytes    = 1 << 20
+)
+
+// Config holds the settings of the vLLM container
+type Config struct {
+	Host          string        // Engine API endpoint (defaults to DefaultHost)
+	ContainerName string        // Name of the vLLM container
+	Port          int           // Port the vLLM API is published on, on 127.0.0.1
+	GPUCount      int           // Number of GPUs passed to the container (0 = all)
+	CPUOffloadGB  int           // CPU offload in GB
+	SleepMode     bool          // Start vLLM with sleep mode
+	HFCacheDir    string        // Host directory mounted as the Hugging Face cache (defaults to ~/.cache/huggingface)
+	StopTimeout   time.Duration // Time vLLM gets to drain on stop before being killed
+	Env           []string      // Additional variables as NAME=value, e.g. HF_TOKEN
+}
+
+// Runner drives the vLLM container of the active model, the way K8sManager drives its pod
+type Runner struct {
+	client *client
+	config *Config
+}
+
+// NewRunner creates a runner for the container described by config
+func NewRunner(config *Config) (*Runner, error) {
+	host := config.Host
+	if host == "" {
+		host = DefaultHost
+	}
+	c, err := newClient(host)
+	if err != nil {
+		return nil, err
+	}
+	if config.HFCacheDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate the Hugging Face cache: %w", err)
+		}
+		config.HFCacheDir = filepath.Join(home, defaultHFCache)
+	}
+	return &Runner{client: c, config: config}, nil
+}
+
+// containerConfig is the body of a container creation
+type containerConfig struct {
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd"`
+	Env          []string            `json:"Env,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts"`
+	Healthcheck  *healthcheck        `json:"Healthcheck,omitempty"`
+	HostConfig   hostConfig          `json:"HostConfig"`
+}
+
+// healthcheck mirrors the readiness probe of the vLLM pod, durations are in nanoseconds
+type healthcheck struct {
+	Test        []string `json:"Test"`
+	Interval    int64    `json:"Interval"`
+	Timeout     int64    `json:"Timeout"`
+	StartPeriod int64    `json:"StartPeriod"`
+	Retries     int      `json:"Retries"`
+}
+
+type hostConfig struct {
+	Binds          []string                 `json:"Binds,omitempty"`
+	PortBindings   map[string][]portBinding `json:"PortBindings"`
+	DeviceRequests []deviceRequest          `json:"DeviceRequests,omitempty"`
+	IpcMode        string                   `json:"IpcMode,omitempty"`
+	RestartPolicy  restartPolicy            `json:"RestartPolicy"`
+}
+
+type portBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type deviceRequest struct {
+	Driver       string     `json:"Driver"`
+	Count        int        `json:"Count"`
+	Capabilities [][]string `json:"Capabilities"`
+}
+
+type restartPolicy struct {
+	Name string `json:"Name"`
+}
+
+// containerState is the part of a container inspection the runner reads
+type containerState struct {
+	State struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+		Health  *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+	} `json:"State"`
+	Config struct {
+		Image  string            `json:"Image"`
+		Cmd    []string          `json:"Cmd"`
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		DeviceRequests []deviceRequest `json:"DeviceRequests"`
+	} `json:"HostConfig"`
+}
+
+// healthScript polls the vLLM /health endpoint from inside the container, the vLLM image has no curl
+const healthScript = "import urllib.request; urllib.request.urlopen('http://localhost:8000/health', timeout=5)"
+
+// buildContainerConfig builds the container running the model, with the args of its pod. vLLM
+// only listens on 127.0.0.1 of the host, so it runs without an API key.
+func (r *Runner) buildContainerConfig(modelConfig *kubernetes.ModelConfig) *containerConfig {
+	args := kubernetes.VLLMArgs(&kubernetes.Config{
+		GPUCount:     r.config.GPUCount,
+		CPUOffloadGB: r.config.CPUOffloadGB,
+		SleepMode:    r.config.SleepMode,
+	}, modelConfig)
+	args = withoutAPIKey(args)
+
+	env := []string{"HF_HUB_ENABLE_HF_TRANSFER=1"}
+	if modelConfig.LoRAAdapter != "" {
+		env = append(env, "VLLM_ALLOW_RUNTIME_LORA_UPDATING=True")
+	}
+	if r.config.SleepMode {
+		env = append(env, "VLLM_SERVER_DEV_MODE=1")
+	}
+	if modelConfig.PodTemplate != nil {
+		// Variables read from Secrets or fields only exist in pods
+		for _, e := range modelConfig.PodTemplate.Env {
+			if e.ValueFrom == nil {
+				env = append(env, e.Name+"="+e.Value)
+			}
+		}
+	}
+	env = append(env, r.config.Env...)
+
+	gpus := r.config.GPUCount
+	if gpus == 0 {
+		gpus = -1 // All the GPUs of the host
+	}
+
+	return &containerConfig{
+		Image:        modelConfig.VLLMImage(),
+		Cmd:          args,
+		Env:          env,
+		ExposedPorts: map[string]struct{}{vllmPort: {}},
+		Healthcheck: &healthcheck{
+			Test:        []string{"CMD", "python3", "-c", healthScript},
+			Interval:    int64(5 * time.Second),
+			Timeout:     int64(5 * time.Second),
+			StartPeriod: int64(30 * time.Minute), // Failures while the weights download don't count
+			Retries:     3,
+		},
+		HostConfig: hostConfig{
+			Binds: []string{r.config.HFCacheDir + ":" + hfCacheMount},
+			PortBindings: map[string][]portBinding{
+				vllmPort: {{HostIP: "127.0.0.1", HostPort: strconv.Itoa(r.config.Port)}},
+			},
+			DeviceRequests: []deviceRequest{{Driver: "nvidia", Count: gpus, Capabilities: [][]string{{"gpu"}}}},
+			IpcMode:        "host", // PyTorch shares tensors between processes through /dev/shm
+			RestartPolicy:  restartPolicy{Name: "on-failure"},
+		},
+	}
+}
+
+// withoutAPIKey removes the --api-key argument and its value
+func withoutAPIKey(args []string) []string {
+	filtered := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--api-key" {
+			i++
+			continue
+		}
+		filtered = append(filtered, args[i])
+	}
+	return filtered
+}
+
+// SpecHash returns the hash of the container config built for the model
+func (r *Runner) SpecHash(modelConfig *kubernetes.ModelConfig) string {
+	data, err := json.Marshal(r.buildContainerConfig(modelConfig))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// inspect returns the state of the vLLM container, nil when it doesn't exist
+func (r *Runner) inspect(ctx context.Context) (*containerState, error) {
+	var state containerState
+	err := r.client.call(ctx, http.MethodGet, "/containers/"+r.config.ContainerName+"/json", nil, nil, &state)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to inspect container %s: %w", r.config.ContainerName, err)
+	}
+	return &state, nil
+}
+
+// CreatePod creates and starts the vLLM container, pulling its image when missing. A stopped
+// container left from a previous run is replaced.
+func (r *Runner) CreatePod(ctx context.Context, modelConfig *kubernetes.ModelConfig) error {
+	if err := r.remove(ctx); err != nil {
+		return err
+	}
+
+	config := r.buildContainerConfig(modelConfig)
+	config.Labels = map[string]string{
+		"managed-by":  "vllm-chill",
+		SpecHashLabel: r.SpecHash(modelConfig),
+	}
+	query := url.Values{"name": {r.config.ContainerName}}
+	err := r.client.call(ctx, http.MethodPost, "/containers/create", query, config, nil)
+	if isNotFound(err) {
+		log.Printf("Pulling image %s", config.Image)
+		if err := r.client.pullImage(ctx, config.Image); err != nil {
+			return err
+		}
+		err = r.client.call(ctx, http.MethodPost, "/containers/create", query, config, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create container: %w", err)
+	}
+
+	if err := r.client.call(ctx, http.MethodPost, "/containers/"+r.config.ContainerName+"/start", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+	log.Printf("Started container %s", r.config.ContainerName)
+	return nil
+}
+
+// DeletePod stops the vLLM container, giving it the stop timeout to shut down cleanly, and removes it
+func (r *Runner) DeletePod(ctx context.Context) error {
+	query := url.Values{"t": {strconv.Itoa(int(r.config.StopTimeout / time.Second))}}
+	err := r.client.call(ctx, http.MethodPost, "/containers/"+r.config.ContainerName+"/stop", query, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	if err := r.remove(ctx); err != nil {
+		return err
+	}
+	log.Printf("Deleted container %s", r.config.ContainerName)
+	return nil
+}
+
+// remove force removes the vLLM container, if any
+func (r *Runner) remove(ctx context.Context) error {
+	err := r.client.call(ctx, http.MethodDelete, "/containers/"+r.config.ContainerName, url.Values{"force": {"true"}}, nil, nil)
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to remove container: %w", err)
+	}
+	return nil
+}
+
+// PodExists checks if the vLLM container exists and hasn't exited, an exited container being
+// replaced on the next scale-up
+func (r *Runner) PodExists(ctx context.Context) (bool, error) {
+	state, err := r.inspect(ctx)
+	if err != nil || state == nil {
+		return false, err
+	}
+	switch state.State.Status {
+	case "exited", "dead":
+		return false, nil
+	}
+	return true, nil
+}
+
+// IsPodReady checks if the vLLM container is running and passes its health check
+func (r *Runner) IsPodReady(ctx context.Context) (bool, error) {
+	state, err := r.inspect(ctx)
+	if err != nil || state == nil {
+		return false, err
+	}
+	return state.State.Running && state.State.Health != nil && state.State.Health.Status == "healthy", nil
+}
+
+// VLLMLogTail returns the last lines of the vLLM container's log
+func (r *Runner) VLLMLogTail(ctx context.Context, lines int64) (string, error) {
+	query := url.Values{"stdout": {"true"}, "stderr": {"true"}, "tail": {strconv.FormatInt(lines, 10)}}
+	resp, err := r.client.do(ctx, http.MethodGet, "/containers/"+r.config.ContainerName+"/logs", query, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get vLLM logs: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxLogBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to get vLLM logs: %w", err)
+	}
+	return demuxLogs(data), nil
+}
+
+// ConfigDrift lists the differences between the vLLM container and the container built for the
+// model, in the order of kubernetes.DriftFields, when the spec hash label differs
+func (r *Runner) ConfigDrift(ctx context.Context, modelConfig *kubernetes.ModelConfig) ([]kubernetes.Drift, error) {
+	state, err := r.inspect(ctx)
+	if err != nil || state == nil {
+		return nil, err // No container means no drift
+	}
+
+	actualHash := state.Config.Labels[SpecHashLabel]
+	expectedHash := r.SpecHash(modelConfig)
+	if actualHash == expectedHash {
+		return nil, nil
+	}
+	expected := r.buildContainerConfig(modelConfig)
+
+	var drifts []kubernetes.Drift
+	add := func(field, actual, expected string) {
+		if actual != expected {
+			drifts = append(drifts, kubernetes.Drift{Field: field, Change: fmt.Sprintf("%q -> %q", actual, expected)})
+		}
+	}
+	add("image", state.Config.Image, expected.Image)
+	add("args", strings.Join(state.Config.Cmd, " "), strings.Join(expected.Cmd, " "))
+	var gpus int
+	if len(state.HostConfig.DeviceRequests) > 0 {
+		gpus = state.HostConfig.DeviceRequests[0].Count
+	}
+	add("gpus", strconv.Itoa(gpus), strconv.Itoa(expected.HostConfig.DeviceRequests[0].Count))
+	if len(drifts) == 0 {
+		add("spec", actualHash, expectedHash)
+	}
+	return drifts, nil
+}
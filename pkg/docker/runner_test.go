@@ -0,0 +1,236 @@
+package docker
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeEngine serves the Engine API calls of the runner for a single container
+type fakeEngine struct {
+	mu       sync.Mutex
+	pulled   map[string]bool
+	created  *containerConfig
+	status   string // Empty when the container doesn't exist
+	health   string
+	pulls    int
+	stopWait string
+	logs     []byte
+}
+
+func (e *fakeEngine) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	notFound := func(message string) {
+		w.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(w).Encode(map[string]string{"message": message})
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/"+apiVersion)
+	switch {
+	case r.Method == http.MethodPost && path == "/images/create":
+		e.pulls++
+		e.pulled[r.URL.Query().Get("fromImage")] = true
+		_, _ = w.Write([]byte(`{"status":"Pulling"}` + "\n" + `{"status":"Downloaded"}` + "\n"))
+	case r.Method == http.MethodPost && path == "/containers/create":
+		var config containerConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if !e.pulled[config.Image] {
+			notFound("No such image: " + config.Image)
+			return
+		}
+		e.created, e.status = &config, "created"
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"abc"}`))
+	case e.status == "":
+		notFound("No such container: vllm")
+	case r.Method == http.MethodPost && path == "/containers/vllm/start":
+		e.status, e.health = "running", "starting"
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && path == "/containers/vllm/stop":
+		e.status, e.stopWait = "exited", r.URL.Query().Get("t")
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodDelete && path == "/containers/vllm":
+		e.status = ""
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && path == "/containers/vllm/logs":
+		_, _ = w.Write(e.logs)
+	case r.Method == http.MethodGet && path == "/containers/vllm/json":
+		var state containerState
+		state.State.Status, state.State.Running = e.status, e.status == "running"
+		state.State.Health = &struct {
+			Status string `json:"Status"`
+		}{Status: e.health}
+		state.Config.Image, state.Config.Cmd, state.Config.Labels = e.created.Image, e.created.Cmd, e.created.Labels
+		state.HostConfig.DeviceRequests = e.created.HostConfig.DeviceRequests
+		_ = json.NewEncoder(w).Encode(state)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func newTestRunner(t *testing.T) (*Runner, *fakeEngine) {
+	t.Helper()
+	engine := &fakeEngine{pulled: map[string]bool{}}
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+
+	runner, err := NewRunner(&Config{
+		Host:          server.URL,
+		ContainerName: "vllm",
+		Port:          8000,
+		GPUCount:      1,
+		HFCacheDir:    "/data/hf",
+		StopTimeout:   30 * time.Second,
+		Env:           []string{"HF_TOKEN=hf_test"},
+	})
+	require.NoError(t, err)
+	return runner, engine
+}
+
+func testModel() *kubernetes.ModelConfig {
+	return &kubernetes.ModelConfig{
+		ModelName:            "Qwen/Qwen3-8B",
+		ServedModelName:      "qwen3-8b",
+		ToolCallParser:       "hermes",
+		MaxModelLen:          "32768",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "16",
+		Dtype:                "auto",
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		host    string
+		baseURL string
+	}{
+		{host: "unix:///var/run/docker.sock", baseURL: "http://docker/" + apiVersion},
+		{host: "tcp://192.168.1.10:2375", baseURL: "http://192.168.1.10:2375/" + apiVersion},
+		{host: "https://docker.lan:2376", baseURL: "https://docker.lan:2376/" + apiVersion},
+	}
+	for _, tt := range tests {
+		c, err := newClient(tt.host)
+		require.NoError(t, err, tt.host)
+		assert.Equal(t, tt.baseURL, c.baseURL)
+	}
+
+	_, err := newClient("ssh://user@gpu-box")
+	assert.Error(t, err)
+}
+
+func TestRunner_Lifecycle(t *testing.T) {
+	runner, engine := newTestRunner(t)
+	ctx := context.Background()
+
+	exists, err := runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// The image is pulled on the first creation
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	assert.Equal(t, 1, engine.pulls)
+	created := engine.created
+	assert.Equal(t, "vllm/vllm-openai:latest", created.Image)
+	assert.Contains(t, strings.Join(created.Cmd, " "), "--model Qwen/Qwen3-8B --served-model-name qwen3-8b --tensor-parallel-size 1")
+	assert.NotContains(t, created.Cmd, "--api-key", "vLLM only listens on localhost")
+	assert.Contains(t, created.Env, "HF_TOKEN=hf_test")
+	assert.Equal(t, []string{"/data/hf:/root/.cache/huggingface"}, created.HostConfig.Binds)
+	assert.Equal(t, []portBinding{{HostIP: "127.0.0.1", HostPort: "8000"}}, created.HostConfig.PortBindings[vllmPort])
+	assert.Equal(t, 1, created.HostConfig.DeviceRequests[0].Count)
+	assert.Equal(t, runner.SpecHash(testModel()), created.Labels[SpecHashLabel])
+
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Ready once the health check passes
+	ready, err := runner.IsPodReady(ctx)
+	require.NoError(t, err)
+	assert.False(t, ready)
+	engine.health = "healthy"
+	ready, err = runner.IsPodReady(ctx)
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	require.NoError(t, runner.DeletePod(ctx))
+	assert.Equal(t, "30", engine.stopWait)
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, runner.DeletePod(ctx), "deleting a missing container succeeds")
+
+	// A container that exited is replaced, with the image already pulled
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	engine.status = "exited"
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	assert.Equal(t, 1, engine.pulls)
+}
+
+func TestRunner_ConfigDrift(t *testing.T) {
+	runner, _ := newTestRunner(t)
+	ctx := context.Background()
+
+	drifts, err := runner.ConfigDrift(ctx, testModel())
+	require.NoError(t, err)
+	assert.Empty(t, drifts, "no container means no drift")
+
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	drifts, err = runner.ConfigDrift(ctx, testModel())
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+
+	changed := testModel()
+	changed.MaxModelLen = "65536"
+	changed.PodTemplate = &kubernetes.PodTemplate{Image: "vllm/vllm-openai:v0.11.0"}
+	drifts, err = runner.ConfigDrift(ctx, changed)
+	require.NoError(t, err)
+	require.Len(t, drifts, 2)
+	assert.Equal(t, "image", drifts[0].Field)
+	assert.Equal(t, `"vllm/vllm-openai:latest" -> "vllm/vllm-openai:v0.11.0"`, drifts[0].Change)
+	assert.Equal(t, "args", drifts[1].Field)
+
+	// Another environment only shows in the hash
+	runner.config.Env = nil
+	drifts, err = runner.ConfigDrift(ctx, testModel())
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, "spec", drifts[0].Field)
+}
+
+func TestRunner_VLLMLogTail(t *testing.T) {
+	runner, engine := newTestRunner(t)
+	require.NoError(t, runner.CreatePod(context.Background(), testModel()))
+
+	frame := func(stream byte, text string) []byte {
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(text)))
+		return append(header, text...)
+	}
+	engine.logs = append(frame(1, "INFO Loading weights\n"), frame(2, "WARNING Slow tokenizer\n")...)
+
+	logs, err := runner.VLLMLogTail(context.Background(), 50)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO Loading weights\nWARNING Slow tokenizer\n", logs)
+
+	// Containers with a TTY stream raw text
+	assert.Equal(t, "INFO raw\n", demuxLogs([]byte("INFO raw\n")))
+}
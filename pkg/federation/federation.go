@@ -0,0 +1,222 @@
+// Package federation lets several vllm-chill instances route to each other when a model is warm elsewhere.
+package federation
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ForwardedHeader marks requests already routed by a peer to prevent forwarding loops
+	ForwardedHeader = "X-VLLM-Chill-Federated"
+	// StatusPath is the path peers expose their warm state on
+	StatusPath = "/proxy/federation/status"
+
+	defaultStatusTTL     = 5 * time.Second
+	defaultProbeTimeout  = 2 * time.Second
+	peerSpecSeparator    = ","
+	peerNameURLSeparator = "="
+)
+
+// Peer is a remote vllm-chill instance
+type Peer struct {
+	Name string
+	URL  *url.URL
+}
+
+// Status is the warm state a peer advertises
+type Status struct {
+	ActiveModel string `json:"active_model"`
+	Ready       bool   `json:"ready"`
+}
+
+// TLSConfig holds the mTLS material used to talk to peers
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+}
+
+type cachedStatus struct {
+	status    Status
+	err       error
+	fetchedAt time.Time
+}
+
+// Registry tracks remote peers and their last known state
+type Registry struct {
+	peers     []Peer
+	client    *http.Client
+	transport http.RoundTripper
+	statusTTL time.Duration
+	mu        sync.Mutex
+	cache     map[string]cachedStatus
+}
+
+// ParsePeers parses a peer list of the form "name=https://host:port,other=https://..."
+// Entries without a name use the URL host as name.
+func ParsePeers(spec string) ([]Peer, error) {
+	var peers []Peer
+	for _, entry := range strings.Split(spec, peerSpecSeparator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rawURL := "", entry
+		if idx := strings.Index(entry, peerNameURLSeparator); idx > 0 && !strings.Contains(entry[:idx], "://") {
+			name, rawURL = entry[:idx], entry[idx+1:]
+		}
+
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid peer URL %q", rawURL)
+		}
+		if name == "" {
+			name = u.Host
+		}
+		peers = append(peers, Peer{Name: name, URL: u})
+	}
+	return peers, nil
+}
+
+// NewRegistry creates a peer registry. tlsConfig may be nil for plaintext peers.
+func NewRegistry(peers []Peer, tlsConfig *TLSConfig) (*Registry, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		cfg, err := tlsConfig.build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = cfg
+	}
+
+	return &Registry{
+		peers:     peers,
+		client:    &http.Client{Timeout: defaultProbeTimeout, Transport: transport},
+		transport: transport,
+		statusTTL: defaultStatusTTL,
+		cache:     make(map[string]cachedStatus),
+	}, nil
+}
+
+// build loads the client certificate and CA pool
+func (c *TLSConfig) build() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load federation client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if c.CAFile != "" {
+		caPEM, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read federation CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in federation CA %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	return cfg, nil
+}
+
+// Peers returns the registered peers
+func (r *Registry) Peers() []Peer {
+	return r.peers
+}
+
+// FindWarmPeer returns the first peer that has the requested model loaded and ready.
+// Peers are tried in registration order, so operators control preference by ordering.
+func (r *Registry) FindWarmPeer(ctx context.Context, model string) (*Peer, bool) {
+	for i := range r.peers {
+		peer := &r.peers[i]
+		status, err := r.peerStatus(ctx, peer)
+		if err != nil {
+			continue
+		}
+		if status.Ready && status.ActiveModel == model {
+			return peer, true
+		}
+	}
+	return nil, false
+}
+
+// peerStatus returns the cached status of a peer, refreshing it when stale
+func (r *Registry) peerStatus(ctx context.Context, peer *Peer) (Status, error) {
+	r.mu.Lock()
+	cached, ok := r.cache[peer.Name]
+	r.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < r.statusTTL {
+		return cached.status, cached.err
+	}
+
+	status, err := r.fetchStatus(ctx, peer)
+	if err != nil {
+		log.Printf("[FEDERATION] Failed to probe peer %s: %v", peer.Name, err)
+	}
+
+	r.mu.Lock()
+	r.cache[peer.Name] = cachedStatus{status: status, err: err, fetchedAt: time.Now()}
+	r.mu.Unlock()
+
+	return status, err
+}
+
+// fetchStatus queries the status endpoint of a peer
+func (r *Registry) fetchStatus(ctx context.Context, peer *Peer) (Status, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, peer.URL.JoinPath(StatusPath).String(), nil)
+	if err != nil {
+		return Status{}, err
+	}
+	req.Header.Set(ForwardedHeader, "1")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return Status{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return Status{}, fmt.Errorf("status endpoint returned %d", resp.StatusCode)
+	}
+
+	var status Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return Status{}, fmt.Errorf("failed to decode peer status: %w", err)
+	}
+	return status, nil
+}
+
+// Forward proxies the request to the given peer
+func (r *Registry) Forward(w http.ResponseWriter, req *http.Request, peer *Peer) {
+	proxy := httputil.NewSingleHostReverseProxy(peer.URL)
+	proxy.Transport = r.transport
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		log.Printf("[FEDERATION] Proxy error to peer %s: %v", peer.Name, err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}
+
+	req.Header.Set(ForwardedHeader, "1")
+	req.Host = peer.URL.Host
+	proxy.ServeHTTP(w, req)
+}
@@ -0,0 +1,111 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeers(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", spec: "", want: nil},
+		{name: "named peers", spec: "big=https://big-box:8443, lab=http://10.0.0.2:8080", want: []string{"big", "lab"}},
+		{name: "unnamed peer uses host", spec: "https://big-box:8443", want: []string{"big-box:8443"}},
+		{name: "invalid URL", spec: "big=not-a-url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peers, err := ParsePeers(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var names []string
+			for _, p := range peers {
+				names = append(names, p.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func newPeerServer(t *testing.T, status Status) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case StatusPath:
+			_ = json.NewEncoder(w).Encode(status)
+		default:
+			w.Header().Set("X-Forwarded-Seen", r.Header.Get(ForwardedHeader))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("from " + status.ActiveModel))
+		}
+	}))
+}
+
+func TestRegistry_FindWarmPeer(t *testing.T) {
+	cold := newPeerServer(t, Status{ActiveModel: "qwen", Ready: false})
+	defer cold.Close()
+	warm := newPeerServer(t, Status{ActiveModel: "qwen", Ready: true})
+	defer warm.Close()
+	other := newPeerServer(t, Status{ActiveModel: "deepseek", Ready: true})
+	defer other.Close()
+
+	peers, err := ParsePeers("cold=" + cold.URL + ",other=" + other.URL + ",warm=" + warm.URL)
+	require.NoError(t, err)
+	registry, err := NewRegistry(peers, nil)
+	require.NoError(t, err)
+
+	peer, ok := registry.FindWarmPeer(context.Background(), "qwen")
+	require.True(t, ok)
+	assert.Equal(t, "warm", peer.Name)
+
+	_, ok = registry.FindWarmPeer(context.Background(), "mistral")
+	assert.False(t, ok)
+}
+
+func TestRegistry_FindWarmPeerSkipsUnreachable(t *testing.T) {
+	unreachable, _ := url.Parse("http://127.0.0.1:1")
+	registry, err := NewRegistry([]Peer{{Name: "down", URL: unreachable}}, nil)
+	require.NoError(t, err)
+
+	_, ok := registry.FindWarmPeer(context.Background(), "qwen")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Forward(t *testing.T) {
+	warm := newPeerServer(t, Status{ActiveModel: "qwen", Ready: true})
+	defer warm.Close()
+
+	peers, err := ParsePeers("warm=" + warm.URL)
+	require.NoError(t, err)
+	registry, err := NewRegistry(peers, nil)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	w := httptest.NewRecorder()
+	registry.Forward(w, req, &registry.Peers()[0])
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "from qwen", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("X-Forwarded-Seen"))
+}
+
+func TestNewRegistry_InvalidTLS(t *testing.T) {
+	_, err := NewRegistry(nil, &TLSConfig{CertFile: "/nonexistent/cert.pem", KeyFile: "/nonexistent/key.pem"})
+	assert.Error(t, err)
+}
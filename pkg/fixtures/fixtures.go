@@ -0,0 +1,254 @@
+// Package fixtures records raw SSE transcripts from a live vLLM instance into test/data fixtures,
+// keeping the regression corpus in step with vLLM's output formats.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config holds the recording settings
+type Config struct {
+	Target    string        // Base URL of vLLM (e.g., http://localhost:8000), not the proxy
+	Model     string        // Served model name to record
+	APIKey    string        // vLLM API key (optional)
+	OutputDir string        // Directory the transcripts are written to
+	Scenarios []string      // Scenario names to record, all when empty
+	Timeout   time.Duration // Per-request timeout
+}
+
+// Scenario is a curated request exercising an output format the proxy has to handle
+type Scenario struct {
+	Name string
+	Path string
+	Body map[string]interface{}
+	End  string // Line that terminates a complete stream
+}
+
+// Result is the outcome of recording a scenario
+type Result struct {
+	Scenario string `json:"scenario"`
+	File     string `json:"file,omitempty"`
+	Bytes    int    `json:"bytes,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Stream terminators
+const (
+	openAIStreamEnd    = "data: [DONE]"
+	anthropicStreamEnd = "event: message_stop"
+)
+
+var weatherTool = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "get_weather",
+		"description": "Get the current weather for a city",
+		"parameters": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+			"required":   []string{"city"},
+		},
+	},
+}
+
+var timeTool = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "get_time",
+		"description": "Get the current local time for a city",
+		"parameters": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+			"required":   []string{"city"},
+		},
+	},
+}
+
+// Scenarios returns the curated scenarios for a model
+func Scenarios(model string) []Scenario {
+	chat := func(prompt string, extra map[string]interface{}) map[string]interface{} {
+		body := map[string]interface{}{
+			"model":          model,
+			"stream":         true,
+			"stream_options": map[string]interface{}{"include_usage": true},
+			"messages":       []map[string]interface{}{{"role": "user", "content": prompt}},
+		}
+		for k, v := range extra {
+			body[k] = v
+		}
+		return body
+	}
+
+	return []Scenario{
+		{
+			Name: "tool-call",
+			Path: "/v1/chat/completions",
+			Body: chat("What is the weather in Paris?", map[string]interface{}{
+				"tools": []interface{}{weatherTool}, "tool_choice": "auto",
+			}),
+			End: openAIStreamEnd,
+		},
+		{
+			Name: "parallel-tool-calls",
+			Path: "/v1/chat/completions",
+			Body: chat("What are the weather and the local time in Tokyo? Call both tools.", map[string]interface{}{
+				"tools": []interface{}{weatherTool, timeTool}, "tool_choice": "auto",
+			}),
+			End: openAIStreamEnd,
+		},
+		{
+			Name: "reasoning",
+			Path: "/v1/chat/completions",
+			Body: chat("A bat and a ball cost 1.10 in total. The bat costs 1.00 more than the ball. How much does the ball cost? Think it through step by step.", nil),
+			End:  openAIStreamEnd,
+		},
+		{
+			Name: "long-output",
+			Path: "/v1/chat/completions",
+			Body: chat("Write a detailed, multi-section tutorial on implementing a rate limiter in Go, with code.", map[string]interface{}{
+				"max_tokens": 2048,
+			}),
+			End: openAIStreamEnd,
+		},
+		{
+			Name: "anthropic-tool-call",
+			Path: "/v1/messages",
+			Body: map[string]interface{}{
+				"model":      model,
+				"stream":     true,
+				"max_tokens": 512,
+				"messages":   []map[string]interface{}{{"role": "user", "content": "What is the weather in Paris?"}},
+				"tools": []map[string]interface{}{{
+					"name":         "get_weather",
+					"description":  "Get the current weather for a city",
+					"input_schema": weatherTool["function"].(map[string]interface{})["parameters"],
+				}},
+			},
+			End: anthropicStreamEnd,
+		},
+	}
+}
+
+// Recorder replays the scenarios against vLLM and writes the raw transcripts
+type Recorder struct {
+	config *Config
+	client *http.Client
+}
+
+// NewRecorder creates a recorder for the given configuration
+func NewRecorder(config *Config) *Recorder {
+	return &Recorder{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// Record records every selected scenario, a failed scenario does not stop the others
+func (r *Recorder) Record(ctx context.Context) ([]Result, error) {
+	if err := os.MkdirAll(r.config.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	scenarios, err := r.selected()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		result := Result{Scenario: scenario.Name}
+		transcript, err := r.record(ctx, scenario)
+		if err == nil {
+			result.File = filepath.Join(r.config.OutputDir, fileName(r.config.Model, scenario.Name))
+			result.Bytes = len(transcript)
+			err = os.WriteFile(result.File, transcript, 0o644)
+		}
+		if err != nil {
+			result.File = ""
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// selected returns the scenarios to record, failing on unknown names
+func (r *Recorder) selected() ([]Scenario, error) {
+	all := Scenarios(r.config.Model)
+	if len(r.config.Scenarios) == 0 {
+		return all, nil
+	}
+
+	byName := make(map[string]Scenario, len(all))
+	for _, scenario := range all {
+		byName[scenario.Name] = scenario
+	}
+	scenarios := make([]Scenario, 0, len(r.config.Scenarios))
+	for _, name := range r.config.Scenarios {
+		scenario, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown scenario %q", name)
+		}
+		scenarios = append(scenarios, scenario)
+	}
+	return scenarios, nil
+}
+
+// record sends a scenario's request and returns the raw stream, failing on incomplete streams
+func (r *Recorder) record(ctx context.Context, scenario Scenario) ([]byte, error) {
+	body, err := json.Marshal(scenario.Body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(r.config.Target, "/")+scenario.Path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.config.APIKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	transcript, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("stream interrupted: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("POST %s returned %d: %s", scenario.Path, resp.StatusCode, truncate(transcript))
+	}
+	if !bytes.Contains(transcript, []byte(scenario.End)) {
+		return nil, fmt.Errorf("incomplete stream, %q not found", scenario.End)
+	}
+	return transcript, nil
+}
+
+// fileName names a transcript after the model and scenario, like the existing fixtures
+func fileName(model, scenario string) string {
+	name := strings.NewReplacer("/", "-", ":", "-", " ", "-").Replace(strings.ToLower(model))
+	return fmt.Sprintf("%s-%s.txt", name, scenario)
+}
+
+func truncate(body []byte) string {
+	const limit = 200
+	if len(body) > limit {
+		return string(body[:limit]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,75 @@
+package fixtures
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const openAIStream = "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: [DONE]\n\n"
+
+func TestRecorderRecord(t *testing.T) {
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-vllm", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Qwen/Qwen3-Coder", body["model"])
+		assert.Equal(t, true, body["stream"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte("event: message_start\ndata: {}\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(openAIStream))
+	}))
+	defer vllm.Close()
+
+	dir := t.TempDir()
+	recorder := NewRecorder(&Config{
+		Target:    vllm.URL,
+		Model:     "Qwen/Qwen3-Coder",
+		APIKey:    "sk-vllm",
+		OutputDir: dir,
+		Scenarios: []string{"tool-call", "anthropic-tool-call"},
+		Timeout:   time.Second,
+	})
+
+	results, err := recorder.Record(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, filepath.Join(dir, "qwen-qwen3-coder-tool-call.txt"), results[0].File)
+	transcript, err := os.ReadFile(results[0].File)
+	require.NoError(t, err)
+	assert.Equal(t, openAIStream, string(transcript), "transcripts are saved raw")
+
+	assert.Contains(t, results[1].Error, "incomplete stream", "streams without their terminator are not saved")
+	assert.Empty(t, results[1].File)
+}
+
+func TestRecorderUnknownScenario(t *testing.T) {
+	recorder := NewRecorder(&Config{Model: "qwen", OutputDir: t.TempDir(), Scenarios: []string{"poetry"}})
+	_, err := recorder.Record(context.Background())
+	assert.ErrorContains(t, err, `unknown scenario "poetry"`)
+}
+
+func TestScenarios(t *testing.T) {
+	names := make(map[string]bool)
+	for _, scenario := range Scenarios("qwen") {
+		assert.False(t, names[scenario.Name], "duplicate scenario %s", scenario.Name)
+		names[scenario.Name] = true
+		assert.Equal(t, "qwen", scenario.Body["model"])
+		assert.Equal(t, true, scenario.Body["stream"])
+		assert.NotEmpty(t, scenario.End)
+	}
+}
@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managedSelector selects every resource vllm-chill created in a namespace, whatever its deployment
+const managedSelector = "managed-by=vllm-chill"
+
+// CleanupFinalizer is the finalizer a proxy sets on the VLLMModels, so it can release the model's pods
+// before the VLLMModel is deleted. It is scoped to the proxy's namespace, as several proxies share the
+// cluster-scoped VLLMModels.
+func CleanupFinalizer(namespace string) string {
+	return "vllm.sir-alfred.io/cleanup-" + namespace
+}
+
+// CleanupReport lists the resources removed by Cleanup, or that a dry run would remove
+type CleanupReport struct {
+	Pods       []string `json:"pods"`
+	Services   []string `json:"services"`
+	ConfigMaps []string `json:"configmaps"`
+	Events     []string `json:"events"`
+}
+
+// Cleanup deletes every resource vllm-chill manages in the namespace, so an uninstall does not leave
+// GPU pods running: the vLLM pods (primary, replicas and embedding), their services, the generated
+// ConfigMaps and the pods' events. With dryRun, the resources are listed but not deleted.
+func (m *K8sManager) Cleanup(ctx context.Context, dryRun bool) (*CleanupReport, error) {
+	report := &CleanupReport{Pods: []string{}, Services: []string{}, ConfigMaps: []string{}, Events: []string{}}
+	core := m.clientset.CoreV1()
+	ns := m.config.Namespace
+	selector := metav1.ListOptions{LabelSelector: managedSelector}
+
+	pods, err := core.Pods(ns).List(ctx, selector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	podNames := make(map[string]bool, len(pods.Items))
+	for _, pod := range pods.Items {
+		podNames[pod.Name] = true
+		report.Pods = append(report.Pods, pod.Name)
+		if !dryRun {
+			if err := m.deletePodNamed(ctx, pod.Name, m.config.gracePeriodSeconds()); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	services, err := core.Services(ns).List(ctx, selector)
+	if err != nil {
+		return report, fmt.Errorf("failed to list services: %w", err)
+	}
+	for _, service := range services.Items {
+		report.Services = append(report.Services, service.Name)
+		if !dryRun {
+			if err := core.Services(ns).Delete(ctx, service.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return report, fmt.Errorf("failed to delete service %s: %w", service.Name, err)
+			}
+		}
+	}
+
+	configMaps, err := core.ConfigMaps(ns).List(ctx, selector)
+	if err != nil {
+		return report, fmt.Errorf("failed to list ConfigMaps: %w", err)
+	}
+	for _, configMap := range configMaps.Items {
+		report.ConfigMaps = append(report.ConfigMaps, configMap.Name)
+		if !dryRun {
+			if err := core.ConfigMaps(ns).Delete(ctx, configMap.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return report, fmt.Errorf("failed to delete ConfigMap %s: %w", configMap.Name, err)
+			}
+		}
+	}
+
+	// Events are not labeled, they are matched on the pods they report about
+	events, err := core.Events(ns).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return report, fmt.Errorf("failed to list events: %w", err)
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind != "Pod" || !podNames[event.InvolvedObject.Name] {
+			continue
+		}
+		report.Events = append(report.Events, event.Name)
+		if !dryRun {
+			if err := core.Events(ns).Delete(ctx, event.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+				return report, fmt.Errorf("failed to delete event %s: %w", event.Name, err)
+			}
+		}
+	}
+
+	if !dryRun {
+		log.Printf("Cleaned up %d pods, %d services, %d ConfigMaps and %d events in %s",
+			len(report.Pods), len(report.Services), len(report.ConfigMaps), len(report.Events), ns)
+	}
+	return report, nil
+}
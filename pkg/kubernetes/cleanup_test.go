@@ -0,0 +1,75 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sManager_Cleanup(t *testing.T) {
+	managed := map[string]string{"managed-by": "vllm-chill"}
+	meta := func(name string, labels map[string]string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "test-ns", Labels: labels}
+	}
+	podEvent := func(name, pod string) *corev1.Event {
+		return &corev1.Event{ObjectMeta: meta(name, nil), InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: pod}}
+	}
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: meta("vllm", managed)},
+		&corev1.Pod{ObjectMeta: meta("vllm-replica-1", managed)},
+		&corev1.Pod{ObjectMeta: meta("other-app", nil)},
+		&corev1.Service{ObjectMeta: meta("vllm-api", managed)},
+		&corev1.ConfigMap{ObjectMeta: meta("vllm-config", managed)},
+		&corev1.ConfigMap{ObjectMeta: meta("user-config", nil)},
+		podEvent("vllm.started", "vllm"),
+		podEvent("other-app.started", "other-app"),
+	)
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns"})
+	ctx := context.Background()
+
+	want := &CleanupReport{
+		Pods:       []string{"vllm", "vllm-replica-1"},
+		Services:   []string{"vllm-api"},
+		ConfigMaps: []string{"vllm-config"},
+		Events:     []string{"vllm.started"},
+	}
+
+	report, err := manager.Cleanup(ctx, true)
+	if err != nil {
+		t.Fatalf("Cleanup(dryRun) error = %v", err)
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Cleanup(dryRun) = %+v, want %+v", report, want)
+	}
+	if pods, _ := clientset.CoreV1().Pods("test-ns").List(ctx, metav1.ListOptions{}); len(pods.Items) != 3 {
+		t.Errorf("dry run deleted pods, %d left", len(pods.Items))
+	}
+
+	report, err = manager.Cleanup(ctx, false)
+	if err != nil {
+		t.Fatalf("Cleanup() error = %v", err)
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Cleanup() = %+v, want %+v", report, want)
+	}
+
+	pods, _ := clientset.CoreV1().Pods("test-ns").List(ctx, metav1.ListOptions{})
+	if len(pods.Items) != 1 || pods.Items[0].Name != "other-app" {
+		t.Errorf("pods left = %v, want only other-app", pods.Items)
+	}
+	if services, _ := clientset.CoreV1().Services("test-ns").List(ctx, metav1.ListOptions{}); len(services.Items) != 0 {
+		t.Errorf("services left = %d, want 0", len(services.Items))
+	}
+	configMaps, _ := clientset.CoreV1().ConfigMaps("test-ns").List(ctx, metav1.ListOptions{})
+	if len(configMaps.Items) != 1 || configMaps.Items[0].Name != "user-config" {
+		t.Errorf("ConfigMaps left = %v, want only user-config", configMaps.Items)
+	}
+	events, _ := clientset.CoreV1().Events("test-ns").List(ctx, metav1.ListOptions{})
+	if len(events.Items) != 1 || events.Items[0].Name != "other-app.started" {
+		t.Errorf("events left = %v, want only other-app.started", events.Items)
+	}
+}
@@ -1,11 +1,88 @@
 // Package kubernetes provides Kubernetes client and resource management functionality.
 package kubernetes
 
+import "time"
+
+const (
+	defaultServiceName = "vllm-api"
+	defaultAppLabel    = "vllm"
+
+	// DefaultPauseImage replaces the vLLM image while the container is paused
+	DefaultPauseImage = "registry.k8s.io/pause:3.10"
+
+	// VLLMAPIKeySecret holds the API key vLLM requires on /v1 endpoints, under VLLMAPIKeySecretKey
+	VLLMAPIKeySecret    = "vllm-api-key"
+	VLLMAPIKeySecretKey = "api-key"
+
+	// VLLMTLSMountPath is where the TLS Secret of the vLLM pod is mounted, with its tls.crt,
+	// tls.key and ca.crt
+	VLLMTLSMountPath = "/etc/vllm-tls"
+)
+
 // Config holds the Kubernetes-specific configuration
 type Config struct {
 	Namespace     string
 	Deployment    string
 	ConfigMapName string
-	GPUCount      int // Number of GPUs to allocate (infrastructure-level)
-	CPUOffloadGB  int // CPU offload in GB (infrastructure-level)
+	GPUCount      int    // Number of GPUs to allocate (infrastructure-level)
+	CPUOffloadGB  int    // CPU offload in GB (infrastructure-level)
+	ServiceName   string // Service fronting the pod (defaults to vllm-api)
+	AppLabel      string // Value of the "app" label used by the service selector (defaults to vllm)
+	// Time vLLM gets to drain in-flight requests on deletion before being force-killed (0 = immediate)
+	ShutdownGracePeriod time.Duration
+	// Image swapped in by PauseVLLMContainer. When set, the pod runs the vLLM image entrypoint
+	// instead of an explicit command so that the pause image can start with the same spec.
+	PauseImage string
+	// Start vLLM with sleep mode so it can release GPU memory through /sleep and /wake_up
+	SleepMode bool
+	// Time a prefetch Job gets to download a model (0 = no deadline)
+	PrefetchTimeout time.Duration
+	// kubernetes.io/tls Secret mounted in the pod, vLLM then serves HTTPS with its tls.crt and
+	// tls.key (empty serves plain HTTP)
+	TLSSecret string
+	// Require client certificates signed by the ca.crt of TLSSecret
+	TLSClientAuth bool
+	// Secret holding the API key the pods require, under VLLMAPIKeySecretKey, unless their model
+	// names its own (defaults to VLLMAPIKeySecret)
+	APIKeySecret string
+}
+
+// serviceName returns the configured service name or the default
+func (c *Config) serviceName() string {
+	if c.ServiceName != "" {
+		return c.ServiceName
+	}
+	return defaultServiceName
+}
+
+// appLabel returns the configured app label or the default
+func (c *Config) appLabel() string {
+	if c.AppLabel != "" {
+		return c.AppLabel
+	}
+	return defaultAppLabel
+}
+
+// apiKeySecret returns the Secret holding the API key the model's pods require
+func (c *Config) apiKeySecret(modelConfig *ModelConfig) string {
+	if modelConfig.APIKeySecret != "" {
+		return modelConfig.APIKeySecret
+	}
+	if c.APIKeySecret != "" {
+		return c.APIKeySecret
+	}
+	return VLLMAPIKeySecret
+}
+
+// gpuCount returns the GPUs allocated to a vLLM pod, 2 when unset
+func (c *Config) gpuCount() int {
+	if c.GPUCount == 0 {
+		return 2
+	}
+	return c.GPUCount
+}
+
+// gracePeriodSeconds returns the shutdown grace period in whole seconds
+func (c *Config) gracePeriodSeconds() int64 {
+	return int64(c.ShutdownGracePeriod / time.Second)
 }
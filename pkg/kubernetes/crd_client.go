@@ -2,14 +2,20 @@ package kubernetes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"strconv"
+	"strings"
 
 	"github.com/efortin/vllm-chill/pkg/apis/vllm/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 )
@@ -69,6 +75,77 @@ func (c *CRDClient) GetModel(ctx context.Context, servedModelName string) (*Mode
 	return nil, fmt.Errorf("VLLMModel with servedModelName '%s' not found", servedModelName)
 }
 
+// ResolveModel retrieves a VLLMModel by served model name, falling back to its Hugging Face
+// model name, resource name or aliases so clients can address a model by any of them
+func (c *CRDClient) ResolveModel(ctx context.Context, name string) (*ModelConfig, error) {
+	list, err := c.dynamicClient.Resource(vllmModelGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VLLMModels: %w", err)
+	}
+
+	// An exact served name match wins over the other names, which win over aliases, the most
+	// specific alias winning over the others
+	var named, aliased *unstructured.Unstructured
+	bestAlias := -1
+	for i := range list.Items {
+		item := &list.Items[i]
+		spec, found, err := unstructured.NestedMap(item.Object, "spec")
+		if err != nil || !found {
+			continue
+		}
+
+		if served, _, _ := unstructured.NestedString(spec, "servedModelName"); served == name {
+			return c.convertToModelConfig(item)
+		}
+		if modelName, _, _ := unstructured.NestedString(spec, "modelName"); named == nil && (modelName == name || item.GetName() == name) {
+			named = item
+		}
+		aliases, _, _ := unstructured.NestedStringSlice(spec, "aliases")
+		for _, pattern := range aliases {
+			if score := matchAlias(pattern, name); score > bestAlias {
+				aliased, bestAlias = item, score
+			}
+		}
+	}
+
+	switch {
+	case named != nil:
+		return c.convertToModelConfig(named)
+	case aliased != nil:
+		return c.convertToModelConfig(aliased)
+	}
+	return nil, fmt.Errorf("VLLMModel with servedModelName, modelName, name or alias '%s' not found", name)
+}
+
+// matchAlias reports how specifically an alias matches a model name: the number of characters of
+// the pattern other than *, exact aliases above any pattern, and -1 when it doesn't match
+func matchAlias(pattern, name string) int {
+	if !strings.Contains(pattern, "*") {
+		if pattern == name {
+			return math.MaxInt
+		}
+		return -1
+	}
+
+	parts := strings.Split(pattern, "*")
+	first, last := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(name, first) {
+		return -1
+	}
+	rest := name[len(first):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return -1
+		}
+		rest = rest[i+len(part):]
+	}
+	if !strings.HasSuffix(rest, last) {
+		return -1
+	}
+	return len(pattern) - len(parts) + 1
+}
+
 // convertToModelConfig converts an unstructured VLLMModel to ModelConfig
 func (c *CRDClient) convertToModelConfig(u *unstructured.Unstructured) (*ModelConfig, error) {
 	spec, found, err := unstructured.NestedMap(u.Object, "spec")
@@ -93,6 +170,9 @@ func (c *CRDClient) convertToModelConfig(u *unstructured.Unstructured) (*ModelCo
 	if reasoningParser, found, _ := unstructured.NestedString(spec, "reasoningParser"); found {
 		config.ReasoningParser = reasoningParser
 	}
+	if fallbackToolParser, found, _ := unstructured.NestedString(spec, "fallbackToolParser"); found {
+		config.FallbackToolParser = fallbackToolParser
+	}
 
 	// vLLM runtime parameters (model-specific only)
 	if maxModelLen, found, _ := unstructured.NestedInt64(spec, "maxModelLen"); found {
@@ -123,6 +203,89 @@ func (c *CRDClient) convertToModelConfig(u *unstructured.Unstructured) (*ModelCo
 		config.EnableAutoToolChoice = strconv.FormatBool(enableAutoToolChoice)
 	}
 
+	// Serving mode
+	if embedding, found, _ := unstructured.NestedBool(spec, "embedding"); found {
+		config.Embedding = strconv.FormatBool(embedding)
+	}
+
+	// Horizontal scaling
+	if minReplicas, found, _ := unstructured.NestedInt64(spec, "minReplicas"); found {
+		config.MinReplicas = strconv.FormatInt(minReplicas, 10)
+	}
+	if maxReplicas, found, _ := unstructured.NestedInt64(spec, "maxReplicas"); found {
+		config.MaxReplicas = strconv.FormatInt(maxReplicas, 10)
+	}
+
+	// LoRA adapter
+	if loraAdapter, found, _ := unstructured.NestedString(spec, "loraAdapter"); found {
+		config.LoRAAdapter = loraAdapter
+	}
+	if maxLoRARank, found, _ := unstructured.NestedInt64(spec, "maxLoraRank"); found {
+		config.MaxLoRARank = strconv.FormatInt(maxLoRARank, 10)
+	}
+
+	// Speculative decoding
+	if speculativeModel, found, _ := unstructured.NestedString(spec, "speculativeModel"); found {
+		config.SpeculativeModel = speculativeModel
+	}
+	if speculativeMethod, found, _ := unstructured.NestedString(spec, "speculativeMethod"); found {
+		config.SpeculativeMethod = speculativeMethod
+	}
+	if numSpeculativeTokens, found, _ := unstructured.NestedInt64(spec, "numSpeculativeTokens"); found {
+		config.NumSpeculativeTokens = strconv.FormatInt(numSpeculativeTokens, 10)
+	}
+
+	// Request limits
+	if maxOutputTokens, found, _ := unstructured.NestedInt64(spec, "maxOutputTokens"); found {
+		config.MaxOutputTokens = strconv.FormatInt(maxOutputTokens, 10)
+	}
+	if requestTimeout, found, _ := unstructured.NestedString(spec, "requestTimeout"); found {
+		config.RequestTimeout = requestTimeout
+	}
+	if maxStreamDuration, found, _ := unstructured.NestedString(spec, "maxStreamDuration"); found {
+		config.MaxStreamDuration = maxStreamDuration
+	}
+
+	// Structured outputs
+	if guidedDecoding, found, _ := unstructured.NestedBool(spec, "guidedDecoding"); found {
+		config.GuidedDecoding = strconv.FormatBool(guidedDecoding)
+	}
+	if guidedDecodingBackend, found, _ := unstructured.NestedString(spec, "guidedDecodingBackend"); found {
+		config.GuidedDecodingBackend = guidedDecodingBackend
+	}
+	if apiKeySecret, found, _ := unstructured.NestedString(spec, "apiKeySecret"); found {
+		config.APIKeySecret = apiKeySecret
+	}
+
+	// Pod template
+	if podTemplate, found, _ := unstructured.NestedMap(spec, "podTemplate"); found {
+		config.PodTemplate = &PodTemplate{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(podTemplate, config.PodTemplate); err != nil {
+			return nil, fmt.Errorf("invalid VLLMModel podTemplate: %w", err)
+		}
+	}
+
+	// Scheduling
+	if gpuType, found, _ := unstructured.NestedString(spec, "gpuType"); found {
+		config.GPUType = gpuType
+	}
+	if nodeSelector, found, _ := unstructured.NestedStringMap(spec, "nodeSelector"); found {
+		config.NodeSelector = nodeSelector
+	}
+	if tolerations, found, _ := unstructured.NestedSlice(spec, "tolerations"); found {
+		for _, item := range tolerations {
+			toleration, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid VLLMModel tolerations: %v is not an object", item)
+			}
+			var t corev1.Toleration
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(toleration, &t); err != nil {
+				return nil, fmt.Errorf("invalid VLLMModel tolerations: %w", err)
+			}
+			config.Tolerations = append(config.Tolerations, t)
+		}
+	}
+
 	// Note: gpuCount and cpuOffloadGB are now infrastructure-level config, not model-level
 
 	// Validate that all mandatory fields are present
@@ -133,6 +296,72 @@ func (c *CRDClient) convertToModelConfig(u *unstructured.Unstructured) (*ModelCo
 	return config, nil
 }
 
+// UpdateBenchmarkStatus records a benchmark run in the status of the VLLMModel with the given served name
+func (c *CRDClient) UpdateBenchmarkStatus(ctx context.Context, servedModelName string, benchmark *v1alpha1.BenchmarkStatus) error {
+	item, err := c.findModel(ctx, servedModelName)
+	if err != nil {
+		return err
+	}
+	return c.patchStatus(ctx, item.GetName(), map[string]interface{}{"benchmark": benchmark})
+}
+
+// UpdateModelStatus applies update to the status of the VLLMModel with the given served name,
+// recording the generation of its spec and the time of the update. The benchmark is left as is.
+func (c *CRDClient) UpdateModelStatus(ctx context.Context, servedModelName string, update func(*v1alpha1.VLLMModelStatus)) error {
+	item, err := c.findModel(ctx, servedModelName)
+	if err != nil {
+		return err
+	}
+
+	var status v1alpha1.VLLMModelStatus
+	if current, ok, _ := unstructured.NestedMap(item.Object, "status"); ok {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(current, &status); err != nil {
+			return fmt.Errorf("invalid status of VLLMModel %s: %w", item.GetName(), err)
+		}
+	}
+	update(&status)
+	status.ObservedGeneration = item.GetGeneration()
+	status.LastUpdated = metav1.Now()
+
+	// A merge patch replaces the conditions as a whole
+	return c.patchStatus(ctx, item.GetName(), map[string]interface{}{
+		"phase":              status.Phase,
+		"message":            status.Message,
+		"lastUpdated":        status.LastUpdated,
+		"observedGeneration": status.ObservedGeneration,
+		"conditions":         status.Conditions,
+		"currentPodName":     status.CurrentPodName,
+		"lastActivity":       status.LastActivity,
+	})
+}
+
+// findModel returns the VLLMModel with the given served name
+func (c *CRDClient) findModel(ctx context.Context, servedModelName string) (*unstructured.Unstructured, error) {
+	list, err := c.dynamicClient.Resource(vllmModelGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VLLMModels: %w", err)
+	}
+	for i := range list.Items {
+		if served, _, _ := unstructured.NestedString(list.Items[i].Object, "spec", "servedModelName"); served == servedModelName {
+			return &list.Items[i], nil
+		}
+	}
+	return nil, &ModelNotFoundError{ModelID: servedModelName}
+}
+
+// patchStatus merges fields into the status subresource of the VLLMModel name
+func (c *CRDClient) patchStatus(ctx context.Context, name string, fields map[string]interface{}) error {
+	patch, err := json.Marshal(map[string]interface{}{"status": fields})
+	if err != nil {
+		return err
+	}
+	_, err = c.dynamicClient.Resource(vllmModelGVR).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}, "status")
+	if err != nil {
+		return fmt.Errorf("failed to update status of VLLMModel %s: %w", name, err)
+	}
+	return nil
+}
+
 // ListModels returns all VLLMModels (cluster-scoped)
 func (c *CRDClient) ListModels(ctx context.Context) ([]*v1alpha1.VLLMModel, error) {
 	list, err := c.dynamicClient.Resource(vllmModelGVR).List(ctx, metav1.ListOptions{})
@@ -152,6 +381,58 @@ func (c *CRDClient) ListModels(ctx context.Context) ([]*v1alpha1.VLLMModel, erro
 	return models, nil
 }
 
+// AddFinalizer adds the finalizer to the VLLMModel with the given name, if it is not set yet
+func (c *CRDClient) AddFinalizer(ctx context.Context, name, finalizer string) error {
+	return c.updateFinalizers(ctx, name, func(finalizers []string) ([]string, bool) {
+		for _, f := range finalizers {
+			if f == finalizer {
+				return finalizers, false
+			}
+		}
+		return append(finalizers, finalizer), true
+	})
+}
+
+// RemoveFinalizer removes the finalizer from the VLLMModel with the given name, so its deletion can complete
+func (c *CRDClient) RemoveFinalizer(ctx context.Context, name, finalizer string) error {
+	return c.updateFinalizers(ctx, name, func(finalizers []string) ([]string, bool) {
+		kept := make([]string, 0, len(finalizers))
+		for _, f := range finalizers {
+			if f != finalizer {
+				kept = append(kept, f)
+			}
+		}
+		return kept, len(kept) != len(finalizers)
+	})
+}
+
+// updateFinalizers patches the finalizers of a VLLMModel when update changed them. The patch carries
+// the resource version, so a concurrent change of the finalizers is rejected instead of overwritten.
+func (c *CRDClient) updateFinalizers(ctx context.Context, name string, update func([]string) ([]string, bool)) error {
+	item, err := c.dynamicClient.Resource(vllmModelGVR).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get VLLMModel %s: %w", name, err)
+	}
+	finalizers, changed := update(item.GetFinalizers())
+	if !changed {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": item.GetResourceVersion(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := c.dynamicClient.Resource(vllmModelGVR).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to update finalizers of VLLMModel %s: %w", name, err)
+	}
+	return nil
+}
+
 // convertUnstructuredToVLLMModel converts unstructured to typed VLLMModel
 func convertUnstructuredToVLLMModel(u *unstructured.Unstructured, model *v1alpha1.VLLMModel) error {
 	gvk := u.GetObjectKind().GroupVersionKind()
@@ -160,8 +441,11 @@ func convertUnstructuredToVLLMModel(u *unstructured.Unstructured, model *v1alpha
 		Kind:       gvk.Kind,
 	}
 	model.ObjectMeta = metav1.ObjectMeta{
-		Name:      u.GetName(),
-		Namespace: u.GetNamespace(),
+		Name:              u.GetName(),
+		Namespace:         u.GetNamespace(),
+		CreationTimestamp: u.GetCreationTimestamp(),
+		DeletionTimestamp: u.GetDeletionTimestamp(),
+		Finalizers:        u.GetFinalizers(),
 	}
 
 	spec, found, err := unstructured.NestedMap(u.Object, "spec")
@@ -176,6 +460,14 @@ func convertUnstructuredToVLLMModel(u *unstructured.Unstructured, model *v1alpha
 	if servedModelName, found, _ := unstructured.NestedString(spec, "servedModelName"); found {
 		model.Spec.ServedModelName = servedModelName
 	}
+	if maxModelLen, found, _ := unstructured.NestedInt64(spec, "maxModelLen"); found {
+		model.Spec.MaxModelLen = int(maxModelLen)
+	}
+
+	// The status written by the proxies, a malformed one is left empty
+	if status, found, _ := unstructured.NestedMap(u.Object, "status"); found {
+		_ = runtime.DefaultUnstructuredConverter.FromUnstructured(status, &model.Status)
+	}
 
 	return nil
 }
@@ -189,11 +481,13 @@ func (c *CRDClient) WatchModel(ctx context.Context, modelName string, callback f
 		return fmt.Errorf("failed to list models: %w", err)
 	}
 
-	// Find the model's resource version
+	// Find the model's resource version and spec generation
 	var resourceVersion string
+	var generation int64
 	for _, item := range list.Items {
 		if item.GetName() == modelName {
 			resourceVersion = item.GetResourceVersion()
+			generation = item.GetGeneration()
 			break
 		}
 	}
@@ -231,6 +525,13 @@ func (c *CRDClient) WatchModel(ctx context.Context, modelName string, callback f
 				}
 
 				if event.Type == watch.Modified {
+					// Status updates (e.g. benchmark results) leave the generation unchanged
+					if u, ok := event.Object.(*unstructured.Unstructured); ok {
+						if u.GetGeneration() == generation && generation != 0 {
+							continue
+						}
+						generation = u.GetGeneration()
+					}
 					log.Printf("VLLMModel %s was modified, triggering callback", modelName)
 					callback()
 				}
@@ -1,10 +1,17 @@
 package kubernetes
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	"github.com/efortin/vllm-chill/pkg/apis/vllm/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 )
 
 func TestModelNotFoundError(t *testing.T) {
@@ -127,6 +134,7 @@ func TestConvertUnstructuredToVLLMModel(t *testing.T) {
 					"spec": map[string]interface{}{
 						"modelName":       "test/model",
 						"servedModelName": "test-model",
+						"maxModelLen":     int64(32768),
 					},
 				},
 			},
@@ -162,6 +170,9 @@ func TestConvertUnstructuredToVLLMModel(t *testing.T) {
 				if model.Spec.ModelName != "test/model" {
 					t.Errorf("ModelName = %v, want test/model", model.Spec.ModelName)
 				}
+				if model.Spec.MaxModelLen != 32768 {
+					t.Errorf("MaxModelLen = %v, want 32768", model.Spec.MaxModelLen)
+				}
 			}
 		})
 	}
@@ -181,6 +192,7 @@ func TestCRDClient_ConvertToModelConfig_AllFields(t *testing.T) {
 				"servedModelName":        "full-model",
 				"toolCallParser":         "hermes",
 				"reasoningParser":        "deepseek_r1",
+				"fallbackToolParser":     "json",
 				"maxModelLen":            int64(32768),
 				"gpuMemoryUtilization":   0.95,
 				"enableChunkedPrefill":   true,
@@ -191,6 +203,28 @@ func TestCRDClient_ConvertToModelConfig_AllFields(t *testing.T) {
 				"enablePrefixCaching":    true,
 				"cpuOffloadGB":           int64(4),
 				"enableAutoToolChoice":   false,
+				"loraAdapter":            "acme/full-model-lora",
+				"maxLoraRank":            int64(32),
+				"speculativeModel":       "test/draft-model",
+				"numSpeculativeTokens":   int64(4),
+				"maxOutputTokens":        int64(4096),
+				"requestTimeout":         "10m",
+				"maxStreamDuration":      "30m",
+				"guidedDecoding":         true,
+				"guidedDecodingBackend":  "xgrammar",
+				"apiKeySecret":           "full-model-api-key",
+				"podTemplate": map[string]interface{}{
+					"image":        "vllm/vllm-openai:v0.11.0",
+					"nodeSelector": map[string]interface{}{"gpu": "rtx3090"},
+					"resources": map[string]interface{}{
+						"limits": map[string]interface{}{"memory": "96Gi"},
+					},
+				},
+				"gpuType":      "NVIDIA-A100-SXM4-80GB",
+				"nodeSelector": map[string]interface{}{"pool": "a100"},
+				"tolerations": []interface{}{
+					map[string]interface{}{"key": "nvidia.com/gpu", "operator": "Exists", "effect": "NoSchedule"},
+				},
 			},
 		},
 	}
@@ -201,6 +235,36 @@ func TestCRDClient_ConvertToModelConfig_AllFields(t *testing.T) {
 		t.Fatalf("convertToModelConfig() error = %v", err)
 	}
 
+	if config.LoRAAdapter != "acme/full-model-lora" || config.MaxLoRARank != "32" {
+		t.Errorf("LoRA fields = %v/%v, want acme/full-model-lora/32", config.LoRAAdapter, config.MaxLoRARank)
+	}
+	if config.SpeculativeModel != "test/draft-model" || config.SpeculativeMethod != "" || config.NumSpeculativeTokens != "4" {
+		t.Errorf("speculative decoding = %v/%v/%v, want test/draft-model//4", config.SpeculativeModel, config.SpeculativeMethod, config.NumSpeculativeTokens)
+	}
+	if config.MaxOutputTokens != "4096" || config.RequestTimeout != "10m" {
+		t.Errorf("request limits = %v/%v, want 4096/10m", config.MaxOutputTokens, config.RequestTimeout)
+	}
+	if config.MaxStreamDuration != "30m" {
+		t.Errorf("maxStreamDuration = %q, want 30m", config.MaxStreamDuration)
+	}
+	if config.GuidedDecoding != "true" || config.GuidedDecodingBackend != "xgrammar" {
+		t.Errorf("guided decoding = %v/%v, want true/xgrammar", config.GuidedDecoding, config.GuidedDecodingBackend)
+	}
+	if config.APIKeySecret != "full-model-api-key" {
+		t.Errorf("apiKeySecret = %q, want full-model-api-key", config.APIKeySecret)
+	}
+	if config.PodTemplate == nil || config.PodTemplate.Image != "vllm/vllm-openai:v0.11.0" || config.PodTemplate.NodeSelector["gpu"] != "rtx3090" {
+		t.Errorf("PodTemplate = %+v, want the image and node selector", config.PodTemplate)
+	} else if memory := config.PodTemplate.Resources.Limits.Memory(); memory.String() != "96Gi" {
+		t.Errorf("PodTemplate memory limit = %v, want 96Gi", memory)
+	}
+	if config.GPUType != "NVIDIA-A100-SXM4-80GB" || config.NodeSelector["pool"] != "a100" {
+		t.Errorf("scheduling = %v/%v, want the GPU type and node selector", config.GPUType, config.NodeSelector)
+	}
+	if len(config.Tolerations) != 1 || config.Tolerations[0].Effect != corev1.TaintEffectNoSchedule {
+		t.Errorf("Tolerations = %v, want the nvidia.com/gpu toleration", config.Tolerations)
+	}
+
 	// Verify all fields
 	if config.ModelName != "test/full-model" {
 		t.Errorf("ModelName = %v, want test/full-model", config.ModelName)
@@ -214,6 +278,9 @@ func TestCRDClient_ConvertToModelConfig_AllFields(t *testing.T) {
 	if config.ReasoningParser != "deepseek_r1" {
 		t.Errorf("ReasoningParser = %v, want deepseek_r1", config.ReasoningParser)
 	}
+	if config.FallbackToolParser != "json" {
+		t.Errorf("FallbackToolParser = %v, want json", config.FallbackToolParser)
+	}
 	if config.MaxModelLen != "32768" {
 		t.Errorf("MaxModelLen = %v, want 32768", config.MaxModelLen)
 	}
@@ -243,3 +310,200 @@ func TestCRDClient_ConvertToModelConfig_AllFields(t *testing.T) {
 	}
 	// Note: cpuOffloadGB is now infrastructure-level, not in ModelConfig
 }
+
+// newFakeCRDClient returns a CRD client serving VLLMModels built from name and spec pairs
+func newFakeCRDClient(t *testing.T, models map[string]map[string]interface{}) *CRDClient {
+	t.Helper()
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{vllmModelGVR: "VLLMModelList"})
+
+	for name, spec := range models {
+		for key, value := range map[string]interface{}{
+			"maxModelLen":            int64(32768),
+			"gpuMemoryUtilization":   0.9,
+			"enableChunkedPrefill":   true,
+			"maxNumBatchedTokens":    int64(8192),
+			"maxNumSeqs":             int64(16),
+			"dtype":                  "auto",
+			"disableCustomAllReduce": false,
+			"enablePrefixCaching":    true,
+			"enableAutoToolChoice":   true,
+		} {
+			spec[key] = value
+		}
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vllm.sir-alfred.io/v1alpha1",
+			"kind":       "VLLMModel",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec":       spec,
+		}}
+		if _, err := client.Resource(vllmModelGVR).Create(context.Background(), obj, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("failed to create VLLMModel %s: %v", name, err)
+		}
+	}
+	return NewCRDClient(client)
+}
+
+func TestCRDClient_ResolveModel(t *testing.T) {
+	client := newFakeCRDClient(t, map[string]map[string]interface{}{
+		"qwen3-coder": {"modelName": "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8", "servedModelName": "qwen",
+			"aliases": []interface{}{"gpt-4o", "claude-*"}},
+		"deepseek-r1": {"modelName": "deepseek-ai/DeepSeek-R1", "servedModelName": "deepseek-r1",
+			"aliases": []interface{}{"claude-*-opus-*", "o1*"}},
+		"gpt-oss": {"modelName": "openai/gpt-oss-20b", "servedModelName": "gpt-4o"},
+	})
+
+	tests := []struct {
+		name       string
+		wantServed string
+		wantErr    bool
+	}{
+		{name: "qwen", wantServed: "qwen"},
+		{name: "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8", wantServed: "qwen"},
+		{name: "qwen3-coder", wantServed: "qwen"},
+		{name: "deepseek-r1", wantServed: "deepseek-r1"},
+		{name: "gpt-4o", wantServed: "gpt-4o"},
+		{name: "claude-3-5-sonnet-20241022", wantServed: "qwen"},
+		{name: "claude-3-opus-20240229", wantServed: "deepseek-r1"},
+		{name: "o1-mini", wantServed: "deepseek-r1"},
+		{name: "claude", wantErr: true},
+		{name: "unknown", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := client.ResolveModel(context.Background(), tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveModel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && config.ServedModelName != tt.wantServed {
+				t.Errorf("ServedModelName = %v, want %v", config.ServedModelName, tt.wantServed)
+			}
+		})
+	}
+}
+
+func TestCRDClient_UpdateBenchmarkStatus(t *testing.T) {
+	client := newFakeCRDClient(t, map[string]map[string]interface{}{
+		"qwen3-coder": {"modelName": "Qwen/Qwen3-Coder", "servedModelName": "qwen"},
+	})
+	ctx := context.Background()
+
+	benchmark := &v1alpha1.BenchmarkStatus{
+		Prompts:                5,
+		TokensPerSecond:        87.5,
+		TimeToFirstTokenMs:     120,
+		KVCacheHeadroomPercent: 92.5,
+	}
+	if err := client.UpdateBenchmarkStatus(ctx, "qwen", benchmark); err != nil {
+		t.Fatalf("UpdateBenchmarkStatus() error = %v", err)
+	}
+
+	model, err := client.dynamicClient.Resource(vllmModelGVR).Get(ctx, "qwen3-coder", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get VLLMModel: %v", err)
+	}
+	tokensPerSecond, _, _ := unstructured.NestedFloat64(model.Object, "status", "benchmark", "tokensPerSecond")
+	if tokensPerSecond != 87.5 {
+		t.Errorf("status.benchmark.tokensPerSecond = %v, want 87.5", tokensPerSecond)
+	}
+	ttft, _, _ := unstructured.NestedInt64(model.Object, "status", "benchmark", "timeToFirstTokenMs")
+	if ttft != 120 {
+		t.Errorf("status.benchmark.timeToFirstTokenMs = %v, want 120", ttft)
+	}
+
+	var notFound *ModelNotFoundError
+	if err := client.UpdateBenchmarkStatus(ctx, "missing", benchmark); !errors.As(err, &notFound) {
+		t.Errorf("UpdateBenchmarkStatus() for a missing model error = %v, want ModelNotFoundError", err)
+	}
+}
+
+func TestCRDClient_UpdateModelStatus(t *testing.T) {
+	client := newFakeCRDClient(t, map[string]map[string]interface{}{
+		"qwen3-coder": {"modelName": "Qwen/Qwen3-Coder", "servedModelName": "qwen"},
+	})
+	ctx := context.Background()
+
+	if err := client.UpdateBenchmarkStatus(ctx, "qwen", &v1alpha1.BenchmarkStatus{TokensPerSecond: 87.5}); err != nil {
+		t.Fatalf("UpdateBenchmarkStatus() error = %v", err)
+	}
+	loaded := metav1.Condition{Type: v1alpha1.ConditionLoaded, Status: metav1.ConditionTrue, Reason: "Running", LastTransitionTime: metav1.Now()}
+	err := client.UpdateModelStatus(ctx, "qwen", func(status *v1alpha1.VLLMModelStatus) {
+		status.Phase = v1alpha1.PhaseReady
+		status.CurrentPodName = "vllm"
+		status.Conditions = []metav1.Condition{loaded}
+	})
+	if err != nil {
+		t.Fatalf("UpdateModelStatus() error = %v", err)
+	}
+
+	// Updates start from the current status
+	err = client.UpdateModelStatus(ctx, "qwen", func(status *v1alpha1.VLLMModelStatus) {
+		if len(status.Conditions) != 1 || status.Conditions[0].Type != v1alpha1.ConditionLoaded {
+			t.Errorf("status.conditions = %v, want the Loaded condition", status.Conditions)
+		}
+		status.Phase = v1alpha1.PhaseIdle
+		status.CurrentPodName = ""
+	})
+	if err != nil {
+		t.Fatalf("UpdateModelStatus() error = %v", err)
+	}
+
+	model, err := client.dynamicClient.Resource(vllmModelGVR).Get(ctx, "qwen3-coder", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get VLLMModel: %v", err)
+	}
+	if phase, _, _ := unstructured.NestedString(model.Object, "status", "phase"); phase != v1alpha1.PhaseIdle {
+		t.Errorf("status.phase = %q, want Idle", phase)
+	}
+	if pod, found, _ := unstructured.NestedString(model.Object, "status", "currentPodName"); found && pod != "" {
+		t.Errorf("status.currentPodName = %q, want it cleared", pod)
+	}
+	if conditions, _, _ := unstructured.NestedSlice(model.Object, "status", "conditions"); len(conditions) != 1 {
+		t.Errorf("status.conditions = %v, want the Loaded condition kept", conditions)
+	}
+	if tokensPerSecond, _, _ := unstructured.NestedFloat64(model.Object, "status", "benchmark", "tokensPerSecond"); tokensPerSecond != 87.5 {
+		t.Errorf("status.benchmark.tokensPerSecond = %v, want the benchmark kept", tokensPerSecond)
+	}
+
+	var notFound *ModelNotFoundError
+	if err := client.UpdateModelStatus(ctx, "missing", func(*v1alpha1.VLLMModelStatus) {}); !errors.As(err, &notFound) {
+		t.Errorf("UpdateModelStatus() for a missing model error = %v, want ModelNotFoundError", err)
+	}
+}
+
+func TestCRDClient_Finalizers(t *testing.T) {
+	client := newFakeCRDClient(t, map[string]map[string]interface{}{
+		"qwen3-coder": {"modelName": "Qwen/Qwen3-Coder", "servedModelName": "qwen"},
+	})
+	ctx := context.Background()
+	finalizer := CleanupFinalizer("vllm")
+
+	finalizers := func() []string {
+		models, err := client.ListModels(ctx)
+		if err != nil || len(models) != 1 {
+			t.Fatalf("ListModels() = %v, %v", models, err)
+		}
+		return models[0].Finalizers
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.AddFinalizer(ctx, "qwen3-coder", finalizer); err != nil {
+			t.Fatalf("AddFinalizer() error = %v", err)
+		}
+	}
+	if got := finalizers(); len(got) != 1 || got[0] != "vllm.sir-alfred.io/cleanup-vllm" {
+		t.Errorf("finalizers = %v, want the cleanup finalizer once", got)
+	}
+
+	if err := client.RemoveFinalizer(ctx, "qwen3-coder", finalizer); err != nil {
+		t.Fatalf("RemoveFinalizer() error = %v", err)
+	}
+	if got := finalizers(); len(got) != 0 {
+		t.Errorf("finalizers = %v, want none", got)
+	}
+
+	if err := client.AddFinalizer(ctx, "missing", finalizer); err == nil {
+		t.Error("AddFinalizer() on a missing model should fail")
+	}
+}
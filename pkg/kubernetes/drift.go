@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// DriftFields are the parts of the vLLM pod compared with the pod built for its model. "spec" is
+// a change of the desired spec none of the others explains, such as new probes after an upgrade.
+var DriftFields = []string{"image", "args", "gpus", "memory", "env", "volumes", "scheduling", "spec"}
+
+// criticalArgs are the vLLM args compared on pods created without the spec hash annotation, the
+// others may differ between vllm-chill versions
+var criticalArgs = []string{
+	"--model",
+	"--served-model-name",
+	"--max-model-len",
+	"--gpu-memory-utilization",
+	"--max-num-batched-tokens",
+	"--max-num-seqs",
+	"--dtype",
+	"--cpu-offload-gb",
+	"--tool-call-parser",
+	"--speculative-config",
+}
+
+// Drift is a difference between the running vLLM pod and the pod built for its model
+type Drift struct {
+	Field  string // One of DriftFields
+	Change string // Values as "actual -> expected", or the items added (+), removed (-) and changed (~)
+}
+
+func (d Drift) String() string {
+	return d.Field + ": " + d.Change
+}
+
+// ConfigDrift lists the differences between the running vLLM pod and the pod built for the model,
+// in the order of DriftFields. Pods with the spec hash annotation are compared when their hash
+// differs, whether they are running or still starting; pods created without it once running.
+func (m *K8sManager) ConfigDrift(ctx context.Context, modelConfig *ModelConfig) ([]Drift, error) {
+	pod, err := m.GetPod(ctx)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil // No pod means no drift
+		}
+		return nil, fmt.Errorf("failed to get pod: %w", err)
+	}
+
+	actualHash, hashed := pod.Annotations[SpecHashAnnotation]
+	expectedHash := m.SpecHash(modelConfig)
+	if hashed && actualHash == expectedHash {
+		return nil, nil
+	}
+	if !hashed && pod.Status.Phase != corev1.PodRunning {
+		return nil, nil // Pod not running yet, skip verification
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("no containers in pod")
+	}
+
+	expected := m.buildPodSpec(modelConfig)
+	actual := pod.Spec
+	actualContainer, expectedContainer := actual.Containers[0], expected.Containers[0]
+
+	var drifts []Drift
+	add := func(field, actual, expected string) {
+		if actual != expected {
+			drifts = append(drifts, Drift{Field: field, Change: fmt.Sprintf("%q -> %q", actual, expected)})
+		}
+	}
+
+	// Paused pods run the pause image, the vLLM image they resume with is annotated
+	image := actualContainer.Image
+	if m.config.PauseImage != "" && image == m.config.PauseImage && pod.Annotations[ImageAnnotation] != "" {
+		image = pod.Annotations[ImageAnnotation]
+	}
+	add("image", image, expectedContainer.Image)
+
+	if hashed {
+		add("args", strings.Join(actualContainer.Args, " "), strings.Join(expectedContainer.Args, " "))
+	} else {
+		actualArgs, expectedArgs := argsToMap(actualContainer.Args), argsToMap(expectedContainer.Args)
+		var changed []string
+		for _, arg := range criticalArgs {
+			if actualArgs[arg] != expectedArgs[arg] {
+				changed = append(changed, fmt.Sprintf("%s %q -> %q", arg, actualArgs[arg], expectedArgs[arg]))
+			}
+		}
+		if len(changed) > 0 {
+			drifts = append(drifts, Drift{Field: "args", Change: strings.Join(changed, ", ")})
+		}
+	}
+
+	add("gpus", quantity(actualContainer.Resources.Limits, GPUResource), quantity(expectedContainer.Resources.Limits, GPUResource))
+	add("memory", quantity(actualContainer.Resources.Limits, corev1.ResourceMemory), quantity(expectedContainer.Resources.Limits, corev1.ResourceMemory))
+
+	if changed := changedByName(actualContainer.Env, expectedContainer.Env, func(e corev1.EnvVar) string { return e.Name }); changed != "" {
+		drifts = append(drifts, Drift{Field: "env", Change: changed})
+	}
+	if changed := changedByName(actual.Volumes, expected.Volumes, func(v corev1.Volume) string { return v.Name }); changed != "" {
+		drifts = append(drifts, Drift{Field: "volumes", Change: changed})
+	}
+
+	// Tolerations aren't compared, admission adds some
+	if !equality.Semantic.DeepEqual(actual.NodeSelector, expected.NodeSelector) {
+		drifts = append(drifts, Drift{Field: "scheduling", Change: fmt.Sprintf("nodeSelector %v -> %v", actual.NodeSelector, expected.NodeSelector)})
+	}
+
+	if hashed && len(drifts) == 0 {
+		add("spec", actualHash, expectedHash)
+	}
+	return drifts, nil
+}
+
+// quantity returns a resource of the list, empty when unset
+func quantity(resources corev1.ResourceList, name corev1.ResourceName) string {
+	if q, ok := resources[name]; ok {
+		return q.String()
+	}
+	return ""
+}
+
+// changedByName describes the items added, removed and changed from actual to expected, empty
+// when they match whatever their order
+func changedByName[T any](actual, expected []T, name func(T) string) string {
+	index := func(items []T) map[string]T {
+		byName := make(map[string]T, len(items))
+		for _, item := range items {
+			byName[name(item)] = item
+		}
+		return byName
+	}
+	actualByName, expectedByName := index(actual), index(expected)
+
+	var changes []string
+	for n, item := range expectedByName {
+		current, ok := actualByName[n]
+		switch {
+		case !ok:
+			changes = append(changes, "+"+n)
+		case !equality.Semantic.DeepEqual(current, item):
+			changes = append(changes, "~"+n)
+		}
+	}
+	for n := range actualByName {
+		if _, ok := expectedByName[n]; !ok {
+			changes = append(changes, "-"+n)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i][1:] < changes[j][1:] })
+	return strings.Join(changes, " ")
+}
@@ -0,0 +1,82 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sManager_ConfigDrift(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm", GPUCount: 1, PauseImage: DefaultPauseImage})
+	ctx := context.Background()
+
+	modelConfig := &ModelConfig{ModelName: "test/model", ServedModelName: "test-model", MaxModelLen: "8192"}
+	if err := manager.CreatePod(ctx, modelConfig); err != nil {
+		t.Fatalf("CreatePod() error = %v", err)
+	}
+	if drifts, err := manager.ConfigDrift(ctx, modelConfig); err != nil || len(drifts) != 0 {
+		t.Fatalf("ConfigDrift() = %v, %v, want none for the pod's own config", drifts, err)
+	}
+
+	// A paused pod resumes with its own image
+	if err := manager.PauseVLLMContainer(ctx); err != nil {
+		t.Fatalf("PauseVLLMContainer() error = %v", err)
+	}
+	if drifts, err := manager.ConfigDrift(ctx, modelConfig); err != nil || len(drifts) != 0 {
+		t.Errorf("ConfigDrift() = %v, %v, want none for a paused pod", drifts, err)
+	}
+
+	changed := *modelConfig
+	changed.MaxModelLen = "16384"
+	changed.PodTemplate = &PodTemplate{
+		Image:     "vllm/vllm-openai:v0.11.0",
+		Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("96Gi")}},
+		Env:       []corev1.EnvVar{{Name: "OMP_NUM_THREADS", Value: "32"}, {Name: "VLLM_LOGGING_LEVEL", Value: "DEBUG"}},
+	}
+	changed.GPUType = "NVIDIA-A100-SXM4-80GB"
+
+	drifts, err := manager.ConfigDrift(ctx, &changed)
+	if err != nil {
+		t.Fatalf("ConfigDrift() error = %v", err)
+	}
+	want := map[string]string{
+		"image":      `"vllm/vllm-openai:latest" -> "vllm/vllm-openai:v0.11.0"`,
+		"memory":     `"64Gi" -> "96Gi"`,
+		"env":        "~OMP_NUM_THREADS +VLLM_LOGGING_LEVEL",
+		"scheduling": "nodeSelector map[] -> map[nvidia.com/gpu.product:NVIDIA-A100-SXM4-80GB]",
+	}
+	fields := map[string]bool{}
+	for _, drift := range drifts {
+		fields[drift.Field] = true
+		if expected, ok := want[drift.Field]; ok && drift.Change != expected {
+			t.Errorf("%s drift = %q, want %q", drift.Field, drift.Change, expected)
+		}
+	}
+	for _, field := range []string{"image", "args", "memory", "env", "scheduling"} {
+		if !fields[field] {
+			t.Errorf("ConfigDrift() = %v, want a %s drift", drifts, field)
+		}
+	}
+	if fields["gpus"] || fields["volumes"] || fields["spec"] {
+		t.Errorf("ConfigDrift() = %v, want only the changed fields", drifts)
+	}
+
+	// Changes none of the fields explain are reported as a spec drift
+	pod, err := manager.GetPod(ctx)
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	pod.Annotations[SpecHashAnnotation] = "0000000000000000"
+	if _, err := clientset.CoreV1().Pods("test-ns").Update(ctx, pod, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	drifts, err = manager.ConfigDrift(ctx, modelConfig)
+	if err != nil || len(drifts) != 1 || drifts[0].Field != "spec" {
+		t.Errorf("ConfigDrift() = %v, %v, want a spec drift", drifts, err)
+	}
+}
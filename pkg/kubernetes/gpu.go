@@ -0,0 +1,56 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GPUResource is the extended resource advertised by the NVIDIA device plugin
+const GPUResource corev1.ResourceName = "nvidia.com/gpu"
+
+// admissionErrorReason is the pod status reason set by the kubelet when it rejects a pod whose
+// devices it cannot allocate, typically because the device plugin has not registered yet
+const admissionErrorReason = "UnexpectedAdmissionError"
+
+// GPUSchedulingFailure reports whether the pod is blocked because no GPU is allocatable, as after a
+// node reboot until the device plugin registers: the scheduler finds too few nvidia.com/gpu, or the
+// kubelet rejects the pod at admission. The returned message explains the failure.
+func GPUSchedulingFailure(pod *corev1.Pod) (string, bool) {
+	switch pod.Status.Phase {
+	case corev1.PodPending:
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse &&
+				cond.Reason == corev1.PodReasonUnschedulable && strings.Contains(cond.Message, "Insufficient "+string(GPUResource)) {
+				return cond.Message, true
+			}
+		}
+	case corev1.PodFailed:
+		// The kubelet does not retry rejected pods, they have to be recreated
+		if pod.Status.Reason == admissionErrorReason || strings.Contains(pod.Status.Reason, string(GPUResource)) {
+			if pod.Status.Message == "" {
+				return pod.Status.Reason, true
+			}
+			return fmt.Sprintf("%s: %s", pod.Status.Reason, pod.Status.Message), true
+		}
+	}
+	return "", false
+}
+
+// GPUsAvailable reports whether a node advertises enough allocatable GPUs for a vLLM pod, which
+// means its device plugin is ready
+func (m *K8sManager) GPUsAvailable(ctx context.Context) (bool, error) {
+	nodes, err := m.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	for _, node := range nodes.Items {
+		if gpus, ok := node.Status.Allocatable[GPUResource]; ok && gpus.Value() >= int64(m.config.gpuCount()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGPUSchedulingFailure(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  corev1.PodStatus
+		blocked bool
+	}{
+		{
+			name: "insufficient GPUs",
+			status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+					Message: "0/1 nodes are available: 1 Insufficient nvidia.com/gpu.",
+				}},
+			},
+			blocked: true,
+		},
+		{
+			name: "unschedulable for another reason",
+			status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+					Message: "0/1 nodes are available: 1 Insufficient memory.",
+				}},
+			},
+		},
+		{
+			name: "rejected at admission",
+			status: corev1.PodStatus{
+				Phase:   corev1.PodFailed,
+				Reason:  "UnexpectedAdmissionError",
+				Message: "Allocate failed due to no healthy devices present; cannot allocate unhealthy devices nvidia.com/gpu",
+			},
+			blocked: true,
+		},
+		{
+			name:   "crashed",
+			status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Error"},
+		},
+		{
+			name:   "running",
+			status: corev1.PodStatus{Phase: corev1.PodRunning},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, blocked := GPUSchedulingFailure(&corev1.Pod{Status: tt.status})
+			if blocked != tt.blocked {
+				t.Errorf("GPUSchedulingFailure() = %v, want %v", blocked, tt.blocked)
+			}
+			if blocked && message == "" {
+				t.Error("GPUSchedulingFailure() should explain the failure")
+			}
+		})
+	}
+}
+
+func TestK8sManager_GPUsAvailable(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "gpu-node"}}
+	clientset := fake.NewSimpleClientset(node)
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", GPUCount: 2})
+	ctx := context.Background()
+
+	available, err := manager.GPUsAvailable(ctx)
+	if err != nil {
+		t.Fatalf("GPUsAvailable() error = %v", err)
+	}
+	if available {
+		t.Error("GPUsAvailable() = true before the device plugin registers the GPUs")
+	}
+
+	node.Status.Allocatable = corev1.ResourceList{GPUResource: resource.MustParse("2")}
+	if _, err := clientset.CoreV1().Nodes().Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("failed to update node: %v", err)
+	}
+	available, err = manager.GPUsAvailable(ctx)
+	if err != nil {
+		t.Fatalf("GPUsAvailable() error = %v", err)
+	}
+	if !available {
+		t.Error("GPUsAvailable() = false with 2 allocatable GPUs")
+	}
+}
@@ -0,0 +1,165 @@
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/efortin/vllm-chill/manifests"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// installFieldManager owns the fields Install applies
+const installFieldManager = "vllm-chill-install"
+
+// manifestNamespace is the namespace of the reference deployment, replaced by the target namespace
+const manifestNamespace = "vllm"
+
+// crdEstablishTimeout bounds the wait for the API server to serve VLLMModels once the CRD is applied
+const crdEstablishTimeout = 30 * time.Second
+
+// installResource is the API resource of a kind Install applies
+type installResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+// installKinds are the kinds Install applies, the other objects of the reference deployment (API key
+// Secret, ConfigMap, Deployment, Ingress) are left to the user
+var installKinds = map[string]installResource{
+	"Namespace":                {schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}, false},
+	"CustomResourceDefinition": {schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}, false},
+	"ServiceAccount":           {schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}, true},
+	"Role":                     {schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}, true},
+	"RoleBinding":              {schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}, true},
+	"ClusterRole":              {schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}, false},
+	"ClusterRoleBinding":       {schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"}, false},
+	"Service":                  {schema.GroupVersionResource{Version: "v1", Resource: "services"}, true},
+}
+
+// InstallReport lists the objects applied by Install as kind/name, or that a dry run would apply
+type InstallReport struct {
+	Applied     []string `json:"applied"`
+	SampleModel string   `json:"sampleModel,omitempty"` // VLLMModel created, empty when skipped or already there
+}
+
+// InstallObjects returns the objects Install applies to namespace: the namespace, the VLLMModel CRD,
+// and the ServiceAccount, RBAC and Service of the proxy from the reference deployment, moved to namespace
+func InstallObjects(namespace string) ([]*unstructured.Unstructured, error) {
+	crds, err := decodeManifests(bytes.NewReader(manifests.CRD))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the embedded CRD: %w", err)
+	}
+	deployment, err := decodeManifests(bytes.NewReader(manifests.Deployment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the embedded deployment: %w", err)
+	}
+
+	ns := &unstructured.Unstructured{}
+	ns.SetAPIVersion("v1")
+	ns.SetKind("Namespace")
+	ns.SetName(namespace)
+
+	objects := append([]*unstructured.Unstructured{ns}, crds...)
+	for _, obj := range deployment {
+		resource, ok := installKinds[obj.GetKind()]
+		if !ok {
+			continue
+		}
+		if resource.namespaced {
+			obj.SetNamespace(namespace)
+		}
+		if obj.GetKind() == "RoleBinding" || obj.GetKind() == "ClusterRoleBinding" {
+			if err := moveSubjects(obj, namespace); err != nil {
+				return nil, err
+			}
+		}
+		// The cluster role binding grants the namespace's service account, each namespace gets its own
+		if obj.GetKind() == "ClusterRoleBinding" && namespace != manifestNamespace {
+			obj.SetName(obj.GetName() + "-" + namespace)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// moveSubjects moves the service accounts a binding grants to namespace
+func moveSubjects(binding *unstructured.Unstructured, namespace string) error {
+	subjects, _, err := unstructured.NestedSlice(binding.Object, "subjects")
+	if err != nil {
+		return fmt.Errorf("invalid subjects of %s %s: %w", binding.GetKind(), binding.GetName(), err)
+	}
+	for _, item := range subjects {
+		if subject, ok := item.(map[string]interface{}); ok && subject["kind"] == "ServiceAccount" {
+			subject["namespace"] = namespace
+		}
+	}
+	return unstructured.SetNestedSlice(binding.Object, subjects, "subjects")
+}
+
+// Install applies the objects of InstallObjects with server-side apply, so running it again updates
+// them, then creates the sample VLLMModel unless sampleModel is false or it already exists, leaving
+// an existing one as the user edited it. With dryRun, the objects are listed but not applied.
+func Install(ctx context.Context, client dynamic.Interface, namespace string, sampleModel, dryRun bool) (*InstallReport, error) {
+	objects, err := InstallObjects(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &InstallReport{Applied: []string{}}
+	for _, obj := range objects {
+		report.Applied = append(report.Applied, obj.GetKind()+"/"+obj.GetName())
+		if dryRun {
+			continue
+		}
+		resource := installKinds[obj.GetKind()]
+		var resourceClient dynamic.ResourceInterface = client.Resource(resource.gvr)
+		if resource.namespaced {
+			resourceClient = client.Resource(resource.gvr).Namespace(obj.GetNamespace())
+		}
+		if _, err := resourceClient.Apply(ctx, obj.GetName(), obj, metav1.ApplyOptions{FieldManager: installFieldManager, Force: true}); err != nil {
+			return report, fmt.Errorf("failed to apply %s %s: %w", obj.GetKind(), obj.GetName(), err)
+		}
+	}
+
+	if !sampleModel {
+		return report, nil
+	}
+	models, err := decodeManifests(bytes.NewReader(manifests.SampleModel))
+	if err != nil {
+		return report, fmt.Errorf("failed to parse the embedded sample model: %w", err)
+	}
+	if len(models) != 1 {
+		return report, fmt.Errorf("the embedded sample model holds %d objects, expected 1", len(models))
+	}
+	model := models[0]
+	if dryRun {
+		report.SampleModel = model.GetName()
+		return report, nil
+	}
+
+	// The API server serves VLLMModels shortly after the CRD is applied, until then they are not found
+	err = wait.PollUntilContextTimeout(ctx, time.Second, crdEstablishTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := client.Resource(vllmModelGVR).Create(ctx, model, metav1.CreateOptions{FieldManager: installFieldManager})
+		switch {
+		case err == nil:
+			report.SampleModel = model.GetName()
+			return true, nil
+		case apierrors.IsAlreadyExists(err):
+			return true, nil
+		case apierrors.IsNotFound(err):
+			return false, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return report, fmt.Errorf("failed to create VLLMModel %s: %w", model.GetName(), err)
+	}
+	return report, nil
+}
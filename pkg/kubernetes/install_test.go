@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestInstallObjects(t *testing.T) {
+	objects, err := InstallObjects("llm")
+	if err != nil {
+		t.Fatalf("InstallObjects() error = %v", err)
+	}
+
+	byName := map[string]*unstructured.Unstructured{}
+	for _, obj := range objects {
+		byName[obj.GetKind()+"/"+obj.GetName()] = obj
+		if installKinds[obj.GetKind()].namespaced && obj.GetNamespace() != "llm" {
+			t.Errorf("%s/%s namespace = %q, want llm", obj.GetKind(), obj.GetName(), obj.GetNamespace())
+		}
+	}
+	for _, want := range []string{
+		"Namespace/llm",
+		"CustomResourceDefinition/models.vllm.sir-alfred.io",
+		"ServiceAccount/vllm-chill",
+		"Role/vllm-chill",
+		"RoleBinding/vllm-chill",
+		"ClusterRole/vllm-chill-models",
+		"ClusterRoleBinding/vllm-chill-models-llm",
+		"Service/vllm-chill-svc",
+	} {
+		if byName[want] == nil {
+			t.Errorf("InstallObjects() is missing %s", want)
+		}
+	}
+	if len(objects) != 8 {
+		t.Errorf("InstallObjects() returned %d objects, want 8 (no Secret, ConfigMap, Deployment or Ingress)", len(objects))
+	}
+
+	for _, binding := range []string{"RoleBinding/vllm-chill", "ClusterRoleBinding/vllm-chill-models-llm"} {
+		subjects, _, _ := unstructured.NestedSlice(byName[binding].Object, "subjects")
+		if len(subjects) != 1 || subjects[0].(map[string]interface{})["namespace"] != "llm" {
+			t.Errorf("%s subjects = %v, want the vllm-chill service account of llm", binding, subjects)
+		}
+	}
+
+	objects, err = InstallObjects("vllm")
+	if err != nil {
+		t.Fatalf("InstallObjects(vllm) error = %v", err)
+	}
+	for _, obj := range objects {
+		if obj.GetKind() == "ClusterRoleBinding" && obj.GetName() != "vllm-chill-models" {
+			t.Errorf("ClusterRoleBinding name = %q, want the one of the reference deployment", obj.GetName())
+		}
+	}
+}
+
+func TestInstall(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{vllmModelGVR: "VLLMModelList"})
+	// The fake tracker doesn't implement server-side apply
+	applied := map[string]string{}
+	client.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		applied[patch.GetResource().Resource+"/"+patch.GetName()] = patch.GetNamespace()
+		return true, &unstructured.Unstructured{}, nil
+	})
+	ctx := context.Background()
+
+	report, err := Install(ctx, client, "llm", false, true)
+	if err != nil {
+		t.Fatalf("Install(dry run) error = %v", err)
+	}
+	if len(applied) != 0 || len(report.Applied) != 8 || report.SampleModel != "" {
+		t.Errorf("Install(dry run) applied %v, report %+v, want nothing applied and 8 objects listed", applied, report)
+	}
+
+	report, err = Install(ctx, client, "llm", true, false)
+	if err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+	if len(applied) != 8 {
+		t.Errorf("Install() applied %v, want 8 objects", applied)
+	}
+	if ns, ok := applied["services/vllm-chill-svc"]; !ok || ns != "llm" {
+		t.Errorf("Service applied to %q, want llm", ns)
+	}
+	if ns, ok := applied["customresourcedefinitions/models.vllm.sir-alfred.io"]; !ok || ns != "" {
+		t.Errorf("CRD applied to %q, want cluster scope", ns)
+	}
+	if report.SampleModel != "qwen3-coder-30b-fp8" {
+		t.Errorf("SampleModel = %q, want qwen3-coder-30b-fp8", report.SampleModel)
+	}
+
+	// An existing sample model is left as is
+	report, err = Install(ctx, client, "llm", true, false)
+	if err != nil {
+		t.Fatalf("Install() again error = %v", err)
+	}
+	if report.SampleModel != "" {
+		t.Errorf("SampleModel = %q, want none created", report.SampleModel)
+	}
+}
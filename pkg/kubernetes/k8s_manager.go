@@ -4,15 +4,30 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	vllmContainerName = "vllm"
+	vllmImage         = "vllm/vllm-openai:latest"
+	vllmTLSVolume     = "vllm-tls"
+
+	// forceDeleteMargin is how long past the grace period we wait before force deleting a pod
+	forceDeleteMargin = 10 * time.Second
+	// preStopReserve is left to vLLM's own SIGTERM handling after the preStop drain
+	preStopReserve = 5 * time.Second
+	// deletePollInterval is how often pod removal is checked during a graceful deletion
+	deletePollInterval = time.Second
+)
+
 // K8sManager handles Kubernetes resource management for vLLM
 type K8sManager struct {
 	clientset kubernetes.Interface
@@ -53,19 +68,19 @@ func (m *K8sManager) ensureConfigMap(ctx context.Context, modelConfig *ModelConf
 // ensureService creates the vLLM service if it doesn't exist
 // Note: Service name must NOT be "vllm" to avoid K8s env var conflicts (VLLM_SERVICE_HOST, etc.)
 func (m *K8sManager) ensureService(ctx context.Context) error {
-	serviceName := "vllm-api"
+	serviceName := m.config.serviceName()
 	service := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      serviceName,
 			Namespace: m.config.Namespace,
 			Labels: map[string]string{
-				"app":        "vllm",
+				"app":        m.config.appLabel(),
 				"managed-by": "vllm-chill",
 			},
 		},
 		Spec: corev1.ServiceSpec{
 			Selector: map[string]string{
-				"app": "vllm",
+				"app": m.config.appLabel(),
 			},
 			Ports: []corev1.ServicePort{
 				{
@@ -111,9 +126,10 @@ func (m *K8sManager) CreatePod(ctx context.Context, modelConfig *ModelConfig) er
 			Name:      m.config.Deployment,
 			Namespace: m.config.Namespace,
 			Labels: map[string]string{
-				"app":        "vllm",
+				"app":        m.config.appLabel(),
 				"managed-by": "vllm-chill",
 			},
+			Annotations: m.podAnnotations(modelConfig),
 		},
 		Spec: m.buildPodSpec(modelConfig),
 	}
@@ -127,23 +143,68 @@ func (m *K8sManager) CreatePod(ctx context.Context, modelConfig *ModelConfig) er
 	return nil
 }
 
-// DeletePod deletes the vLLM pod
+// DeletePod deletes the vLLM pod, giving it the configured grace period to shut down cleanly.
+// The pod is force deleted if it is still present once the grace period has elapsed.
 func (m *K8sManager) DeletePod(ctx context.Context) error {
+	gracePeriod := m.config.gracePeriodSeconds()
+	if err := m.deletePod(ctx, gracePeriod); err != nil {
+		return err
+	}
+
+	if gracePeriod > 0 {
+		timeout := time.Duration(gracePeriod)*time.Second + forceDeleteMargin
+		if err := m.waitForPodDeletion(ctx, timeout); err != nil {
+			log.Printf("Pod %s/%s still terminating after %v, forcing deletion", m.config.Namespace, m.config.Deployment, timeout)
+			if err := m.deletePod(ctx, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	log.Printf("Deleted Pod %s/%s", m.config.Namespace, m.config.Deployment)
+	return nil
+}
+
+// deletePod issues the pod deletion with the given grace period
+func (m *K8sManager) deletePod(ctx context.Context, gracePeriodSeconds int64) error {
+	return m.deletePodNamed(ctx, m.config.Deployment, gracePeriodSeconds)
+}
+
+// deletePodNamed issues the deletion of the named pod with the given grace period
+func (m *K8sManager) deletePodNamed(ctx context.Context, name string, gracePeriodSeconds int64) error {
 	err := m.clientset.CoreV1().Pods(m.config.Namespace).Delete(
 		ctx,
-		m.config.Deployment,
+		name,
 		metav1.DeleteOptions{
-			GracePeriodSeconds: func() *int64 { t := int64(0); return &t }(),
+			GracePeriodSeconds: &gracePeriodSeconds,
 		},
 	)
 	if err != nil && !errors.IsNotFound(err) {
 		return fmt.Errorf("failed to delete pod: %w", err)
 	}
-
-	log.Printf("Deleted Pod %s/%s", m.config.Namespace, m.config.Deployment)
 	return nil
 }
 
+// waitForPodDeletion waits until the pod no longer exists
+func (m *K8sManager) waitForPodDeletion(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(deletePollInterval)
+	defer ticker.Stop()
+
+	for {
+		if exists, err := m.PodExists(ctx); err == nil && !exists {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for pod deletion")
+		case <-ticker.C:
+		}
+	}
+}
+
 // GetPod gets the vLLM pod
 func (m *K8sManager) GetPod(ctx context.Context) (*corev1.Pod, error) {
 	pod, err := m.clientset.CoreV1().Pods(m.config.Namespace).Get(
@@ -157,6 +218,67 @@ func (m *K8sManager) GetPod(ctx context.Context) (*corev1.Pod, error) {
 	return pod, nil
 }
 
+// VLLMLogTail returns the last lines of the vLLM container's log
+func (m *K8sManager) VLLMLogTail(ctx context.Context, lines int64) (string, error) {
+	logs, err := m.clientset.CoreV1().Pods(m.config.Namespace).GetLogs(m.config.Deployment, &corev1.PodLogOptions{
+		Container: vllmContainerName,
+		TailLines: &lines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get vLLM logs: %w", err)
+	}
+	return string(logs), nil
+}
+
+// GetConfigMapData returns the data of a ConfigMap in the namespace
+func (m *K8sManager) GetConfigMapData(ctx context.Context, name string) (map[string]string, error) {
+	configMap, err := m.clientset.CoreV1().ConfigMaps(m.config.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return configMap.Data, nil
+}
+
+// SaveConfigMapData creates a ConfigMap in the namespace or replaces the data of an existing one
+func (m *K8sManager) SaveConfigMapData(ctx context.Context, name string, data map[string]string) error {
+	configMaps := m.clientset.CoreV1().ConfigMaps(m.config.Namespace)
+	configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: m.config.Namespace,
+				Labels: map[string]string{
+					"managed-by": "vllm-chill",
+				},
+			},
+			Data: data,
+		}
+		if _, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create ConfigMap %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get ConfigMap %s: %w", name, err)
+	}
+
+	configMap.Data = data
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s: %w", name, err)
+	}
+	return nil
+}
+
+// GetSecretData returns the data of a Secret in the namespace
+func (m *K8sManager) GetSecretData(ctx context.Context, name string) (map[string][]byte, error) {
+	secret, err := m.clientset.CoreV1().Secrets(m.config.Namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return secret.Data, nil
+}
+
 // PodExists checks if the vLLM pod exists
 func (m *K8sManager) PodExists(ctx context.Context) (bool, error) {
 	_, err := m.GetPod(ctx)
@@ -169,58 +291,93 @@ func (m *K8sManager) PodExists(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
-// VerifyPodConfig checks if the running pod configuration matches the expected model config
-// Returns true if config matches, false if there's a drift
-func (m *K8sManager) VerifyPodConfig(ctx context.Context, modelConfig *ModelConfig) (bool, error) {
+// IsPodReady checks if the vLLM pod exists and reports the Ready condition
+func (m *K8sManager) IsPodReady(ctx context.Context) (bool, error) {
 	pod, err := m.GetPod(ctx)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			return true, nil // No pod means no drift
+			return false, nil
 		}
-		return false, fmt.Errorf("failed to get pod: %w", err)
+		return false, err
 	}
+	return isReady(pod), nil
+}
 
-	// Check if pod is running
-	if pod.Status.Phase != corev1.PodRunning {
-		return true, nil // Pod not running yet, skip verification
+// PauseVLLMContainer swaps the vLLM container image for the pause image.
+// The vLLM process exits and releases GPU memory while the pod keeps its node and GPU allocation.
+func (m *K8sManager) PauseVLLMContainer(ctx context.Context) error {
+	if m.config.PauseImage == "" {
+		return fmt.Errorf("no pause image configured")
 	}
-
-	// Get container args from running pod
-	if len(pod.Spec.Containers) == 0 {
-		return false, fmt.Errorf("no containers in pod")
+	if err := m.setVLLMImage(ctx, m.config.PauseImage); err != nil {
+		return err
 	}
-	container := pod.Spec.Containers[0]
-
-	// Build expected args from model config
-	expectedArgs := m.buildVLLMArgs(modelConfig)
-
-	// Compare key parameters
-	actualArgsMap := argsToMap(container.Args)
-	expectedArgsMap := argsToMap(expectedArgs)
+	log.Printf("Paused vLLM container in pod %s/%s", m.config.Namespace, m.config.Deployment)
+	return nil
+}
 
-	// Check critical parameters that should match
-	criticalParams := []string{
-		"--model",
-		"--served-model-name",
-		"--max-model-len",
-		"--gpu-memory-utilization",
-		"--max-num-batched-tokens",
-		"--max-num-seqs",
-		"--dtype",
-		"--cpu-offload-gb",
-		"--tool-call-parser",
+// ResumeVLLMContainer restores the vLLM image on a paused pod, the one it was created with
+func (m *K8sManager) ResumeVLLMContainer(ctx context.Context) error {
+	pod, err := m.GetPod(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get pod: %w", err)
 	}
+	image := pod.Annotations[ImageAnnotation]
+	if image == "" {
+		image = vllmImage
+	}
+	if err := m.setVLLMImage(ctx, image); err != nil {
+		return err
+	}
+	log.Printf("Resumed vLLM container in pod %s/%s", m.config.Namespace, m.config.Deployment)
+	return nil
+}
 
-	for _, param := range criticalParams {
-		actual := actualArgsMap[param]
-		expected := expectedArgsMap[param]
-		if actual != expected {
-			log.Printf("Config drift detected: %s actual=%s expected=%s", param, actual, expected)
+// IsVLLMContainerPaused reports whether the pod exists with the pause image in place of vLLM
+func (m *K8sManager) IsVLLMContainerPaused(ctx context.Context) (bool, error) {
+	pod, err := m.GetPod(ctx)
+	if err != nil {
+		if errors.IsNotFound(err) {
 			return false, nil
 		}
+		return false, err
 	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name == vllmContainerName {
+			return m.config.PauseImage != "" && container.Image == m.config.PauseImage, nil
+		}
+	}
+	return false, nil
+}
 
-	return true, nil
+// setVLLMImage patches the image of the vLLM container (the only mutable container field)
+func (m *K8sManager) setVLLMImage(ctx context.Context, image string) error {
+	patch := fmt.Sprintf(`{"spec":{"containers":[{"name":%q,"image":%q}]}}`, vllmContainerName, image)
+	_, err := m.clientset.CoreV1().Pods(m.config.Namespace).Patch(
+		ctx,
+		m.config.Deployment,
+		types.StrategicMergePatchType,
+		[]byte(patch),
+		metav1.PatchOptions{},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set vLLM container image: %w", err)
+	}
+	return nil
+}
+
+// VerifyPodConfig checks if the running pod configuration matches the expected model config
+// Returns true if config matches, false if there's a drift, which is logged field by field (see
+// ConfigDrift).
+func (m *K8sManager) VerifyPodConfig(ctx context.Context, modelConfig *ModelConfig) (bool, error) {
+	drifts, err := m.ConfigDrift(ctx, modelConfig)
+	if err != nil {
+		return false, err
+	}
+	for _, drift := range drifts {
+		log.Printf("Config drift detected: %s", drift)
+	}
+	return len(drifts) == 0, nil
 }
 
 // argsToMap converts args slice to map for easier comparison
@@ -239,15 +396,25 @@ func argsToMap(args []string) map[string]string {
 
 // buildVLLMArgs builds the vLLM command-line arguments from ModelConfig
 func (m *K8sManager) buildVLLMArgs(modelConfig *ModelConfig) []string {
+	return VLLMArgs(m.config, modelConfig)
+}
+
+// VLLMArgs returns the vLLM server arguments for the model with the infrastructure settings of
+// config, requiring the API key of the VLLM_API_KEY variable
+func VLLMArgs(config *Config, modelConfig *ModelConfig) []string {
 	// Use GPU count from infrastructure config for tensor-parallel-size
-	gpuCount := m.config.GPUCount
-	if gpuCount == 0 {
-		gpuCount = 2 // Default to 2 GPUs
+	gpuCount := config.gpuCount()
+
+	// Adapters are served under their own name, the base model under its Hugging Face name so
+	// every adapter sharing it runs in identical pods
+	servedModelName := modelConfig.ServedModelName
+	if modelConfig.LoRAAdapter != "" {
+		servedModelName = modelConfig.ModelName
 	}
 
 	args := []string{
 		"--model", modelConfig.ModelName,
-		"--served-model-name", modelConfig.ServedModelName,
+		"--served-model-name", servedModelName,
 		"--tensor-parallel-size", fmt.Sprintf("%d", gpuCount),
 		"--max-model-len", modelConfig.MaxModelLen,
 		"--gpu-memory-utilization", modelConfig.GPUMemoryUtilization,
@@ -273,20 +440,55 @@ func (m *K8sManager) buildVLLMArgs(modelConfig *ModelConfig) []string {
 	}
 
 	// Use CPU offload from infrastructure config
-	cpuOffloadGB := m.config.CPUOffloadGB
+	cpuOffloadGB := config.CPUOffloadGB
 	args = append(args, "--cpu-offload-gb", fmt.Sprintf("%d", cpuOffloadGB))
 
-	if modelConfig.EnableAutoToolChoice == "true" {
-		args = append(args, "--enable-auto-tool-choice")
+	if modelConfig.Embedding == "true" {
+		// Embedding models don't generate text, so tool and reasoning parsers don't apply
+		args = append(args, "--task", "embed")
+	} else {
+		if modelConfig.EnableAutoToolChoice == "true" {
+			args = append(args, "--enable-auto-tool-choice")
+		}
+
+		args = append(args,
+			"--tool-call-parser", modelConfig.ToolCallParser,
+		)
+
+		// Add reasoning parser if specified
+		if modelConfig.ReasoningParser != "" {
+			args = append(args, "--reasoning-parser", modelConfig.ReasoningParser)
+		}
+
+		if speculativeConfig := modelConfig.SpeculativeConfig(); speculativeConfig != "" {
+			args = append(args, "--speculative-config", speculativeConfig)
+		}
+
+		if modelConfig.GuidedDecodingBackend != "" {
+			args = append(args, "--guided-decoding-backend", modelConfig.GuidedDecodingBackend)
+		}
 	}
 
-	args = append(args,
-		"--tool-call-parser", modelConfig.ToolCallParser,
-	)
+	if modelConfig.LoRAAdapter != "" {
+		args = append(args, "--enable-lora", "--lora-modules", modelConfig.ServedModelName+"="+modelConfig.LoRAAdapter)
+		if modelConfig.MaxLoRARank != "" {
+			args = append(args, "--max-lora-rank", modelConfig.MaxLoRARank)
+		}
+	}
+
+	if config.SleepMode {
+		args = append(args, "--enable-sleep-mode")
+	}
 
-	// Add reasoning parser if specified
-	if modelConfig.ReasoningParser != "" {
-		args = append(args, "--reasoning-parser", modelConfig.ReasoningParser)
+	if config.TLSSecret != "" {
+		args = append(args,
+			"--ssl-certfile", VLLMTLSMountPath+"/tls.crt",
+			"--ssl-keyfile", VLLMTLSMountPath+"/tls.key",
+		)
+		if config.TLSClientAuth {
+			// ssl.CERT_REQUIRED
+			args = append(args, "--ssl-ca-certs", VLLMTLSMountPath+"/ca.crt", "--ssl-cert-reqs", "2")
+		}
 	}
 
 	args = append(args,
@@ -298,23 +500,36 @@ func (m *K8sManager) buildVLLMArgs(modelConfig *ModelConfig) []string {
 	return args
 }
 
-// buildPodSpec builds the pod specification for vLLM
+// buildVLLMCommand returns the container command, or nil to use the image entrypoint
+// when the container may be swapped for the pause image
+func (m *K8sManager) buildVLLMCommand() []string {
+	if m.config.PauseImage != "" {
+		return nil
+	}
+	return []string{"python3", "-m", "vllm.entrypoints.openai.api_server"}
+}
+
+// buildPodSpec builds the pod specification for vLLM, with the model's pod template applied
 func (m *K8sManager) buildPodSpec(modelConfig *ModelConfig) corev1.PodSpec {
 	// Use GPU count from infrastructure config (not model config)
-	gpuCount := m.config.GPUCount
-	if gpuCount == 0 {
-		gpuCount = 2 // Default to 2 GPUs
+	gpuCountStr := fmt.Sprintf("%d", m.config.gpuCount())
+
+	gracePeriod := m.config.gracePeriodSeconds()
+
+	envVars := m.buildVLLMEnvVars(modelConfig)
+	if modelConfig.LoRAAdapter != "" {
+		// The load and unload adapter endpoints are only exposed when runtime updates are allowed
+		envVars = append(envVars, corev1.EnvVar{Name: "VLLM_ALLOW_RUNTIME_LORA_UPDATING", Value: "True"})
 	}
-	gpuCountStr := fmt.Sprintf("%d", gpuCount)
 
-	return corev1.PodSpec{
-		TerminationGracePeriodSeconds: func() *int64 { t := int64(0); return &t }(),
+	spec := corev1.PodSpec{
+		TerminationGracePeriodSeconds: &gracePeriod,
 		Volumes: []corev1.Volume{
 			{
 				Name: "hf-cache",
 				VolumeSource: corev1.VolumeSource{
 					HostPath: &corev1.HostPathVolumeSource{
-						Path: "/home/manu/.cache/huggingface",
+						Path: hfCacheHostPath,
 						Type: func() *corev1.HostPathType { t := corev1.HostPathDirectoryOrCreate; return &t }(),
 					},
 				},
@@ -340,12 +555,12 @@ func (m *K8sManager) buildPodSpec(modelConfig *ModelConfig) corev1.PodSpec {
 		},
 		Containers: []corev1.Container{
 			{
-				Name:            "vllm",
-				Image:           "vllm/vllm-openai:latest",
+				Name:            vllmContainerName,
+				Image:           modelConfig.PodTemplate.vllmImage(),
 				ImagePullPolicy: corev1.PullIfNotPresent,
-				Command:         []string{"python3", "-m", "vllm.entrypoints.openai.api_server"},
+				Command:         m.buildVLLMCommand(),
 				Args:            m.buildVLLMArgs(modelConfig),
-				Env:             m.buildVLLMEnvVars(),
+				Env:             envVars,
 				Ports: []corev1.ContainerPort{
 					{
 						ContainerPort: 8000,
@@ -359,11 +574,11 @@ func (m *K8sManager) buildPodSpec(modelConfig *ModelConfig) corev1.PodSpec {
 				Resources: corev1.ResourceRequirements{
 					Limits: corev1.ResourceList{
 						corev1.ResourceMemory: resource.MustParse("64Gi"),
-						"nvidia.com/gpu":      resource.MustParse(gpuCountStr),
+						GPUResource:           resource.MustParse(gpuCountStr),
 					},
 					Requests: corev1.ResourceList{
 						corev1.ResourceMemory: resource.MustParse("32Gi"),
-						"nvidia.com/gpu":      resource.MustParse(gpuCountStr),
+						GPUResource:           resource.MustParse(gpuCountStr),
 					},
 				},
 				VolumeMounts: []corev1.VolumeMount{
@@ -380,46 +595,23 @@ func (m *K8sManager) buildPodSpec(modelConfig *ModelConfig) corev1.PodSpec {
 						MountPath: "/dev/shm",
 					},
 				},
+				Lifecycle: m.buildLifecycle(),
 				StartupProbe: &corev1.Probe{
-					ProbeHandler: corev1.ProbeHandler{
-						HTTPGet: &corev1.HTTPGetAction{
-							Path: "/health",
-							Port: intstr.FromString("http"),
-							HTTPHeaders: []corev1.HTTPHeader{
-								{Name: "Authorization", Value: "Bearer $(VLLM_API_KEY)"},
-							},
-						},
-					},
+					ProbeHandler:        m.healthProbe(),
 					InitialDelaySeconds: 10,
 					PeriodSeconds:       5,
 					TimeoutSeconds:      5,
 					FailureThreshold:    24,
 				},
 				ReadinessProbe: &corev1.Probe{
-					ProbeHandler: corev1.ProbeHandler{
-						HTTPGet: &corev1.HTTPGetAction{
-							Path: "/health",
-							Port: intstr.FromString("http"),
-							HTTPHeaders: []corev1.HTTPHeader{
-								{Name: "Authorization", Value: "Bearer $(VLLM_API_KEY)"},
-							},
-						},
-					},
+					ProbeHandler:        m.healthProbe(),
 					InitialDelaySeconds: 5,
 					PeriodSeconds:       10,
 					TimeoutSeconds:      5,
 					FailureThreshold:    12,
 				},
 				LivenessProbe: &corev1.Probe{
-					ProbeHandler: corev1.ProbeHandler{
-						HTTPGet: &corev1.HTTPGetAction{
-							Path: "/health",
-							Port: intstr.FromString("http"),
-							HTTPHeaders: []corev1.HTTPHeader{
-								{Name: "Authorization", Value: "Bearer $(VLLM_API_KEY)"},
-							},
-						},
-					},
+					ProbeHandler:        m.healthProbe(),
 					InitialDelaySeconds: 60,
 					PeriodSeconds:       30,
 					TimeoutSeconds:      5,
@@ -428,18 +620,114 @@ func (m *K8sManager) buildPodSpec(modelConfig *ModelConfig) corev1.PodSpec {
 			},
 		},
 	}
+	if m.config.TLSSecret != "" {
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name: vllmTLSVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: m.config.TLSSecret},
+			},
+		})
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      vllmTLSVolume,
+			MountPath: VLLMTLSMountPath,
+			ReadOnly:  true,
+		})
+	}
+	modelConfig.PodTemplate.apply(&spec)
+	applyScheduling(&spec, modelConfig)
+	return spec
 }
 
-// buildVLLMEnvVars builds environment variables for the vLLM container
-func (m *K8sManager) buildVLLMEnvVars() []corev1.EnvVar {
+// healthProbe returns the probe handler of vLLM's /health endpoint, over HTTPS when vLLM serves
+// TLS. vLLM only requires its API key on /v1, which the kubelet couldn't send anyway: variables are
+// not expanded in probe headers. The kubelet has no client certificate either, so a vLLM requiring
+// one is only probed on its port.
+func (m *K8sManager) healthProbe() corev1.ProbeHandler {
+	if m.config.TLSSecret != "" && m.config.TLSClientAuth {
+		return corev1.ProbeHandler{
+			TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")},
+		}
+	}
+	action := &corev1.HTTPGetAction{
+		Path: "/health",
+		Port: intstr.FromString("http"),
+	}
+	if m.config.TLSSecret != "" {
+		action.Scheme = corev1.URISchemeHTTPS
+	}
+	return corev1.ProbeHandler{HTTPGet: action}
+}
+
+// preStopDrainScript waits for vLLM to finish in-flight requests (up to the given seconds)
+// so SIGTERM does not abort them mid-generation or interrupt torch compile cache writes
+const preStopDrainScript = `import ssl, time, urllib.request
+context = None
+%s
+deadline = time.time() + %d
+while time.time() < deadline:
+    try:
+        metrics = urllib.request.urlopen("%s://localhost:8000/metrics", timeout=2, context=context).read().decode()
+    except Exception:
+        break
+    running = [l for l in metrics.splitlines() if l.startswith("vllm:num_requests_running")]
+    if not any(float(l.split()[-1]) > 0 for l in running):
+        break
+    time.sleep(1)
+`
+
+// buildLifecycle builds the preStop hook draining vLLM before shutdown.
+// No hook is installed when pods are deleted immediately.
+func (m *K8sManager) buildLifecycle() *corev1.Lifecycle {
+	if m.config.gracePeriodSeconds() == 0 {
+		return nil
+	}
+
+	drainSeconds := int64((m.config.ShutdownGracePeriod - preStopReserve) / time.Second)
+	if drainSeconds < 1 {
+		drainSeconds = 1
+	}
+
+	return &corev1.Lifecycle{
+		PreStop: &corev1.LifecycleHandler{
+			Exec: &corev1.ExecAction{
+				Command: []string{"python3", "-c", fmt.Sprintf(preStopDrainScript, m.preStopTLSContext(), drainSeconds, m.vllmScheme())},
+			},
+		},
+	}
+}
+
+// preStopTLSContext returns the Python statements setting the TLS context of the drain script
+// when vLLM serves HTTPS: localhost isn't a name of the certificate, and the pod's own certificate
+// is presented when vLLM requires client certificates
+func (m *K8sManager) preStopTLSContext() string {
+	if m.config.TLSSecret == "" {
+		return ""
+	}
+	statements := "context = ssl._create_unverified_context()"
+	if m.config.TLSClientAuth {
+		statements += fmt.Sprintf("\ncontext.load_cert_chain(%q, %q)", VLLMTLSMountPath+"/tls.crt", VLLMTLSMountPath+"/tls.key")
+	}
+	return statements
+}
+
+// vllmScheme returns the URL scheme vLLM serves
+func (m *K8sManager) vllmScheme() string {
+	if m.config.TLSSecret != "" {
+		return "https"
+	}
+	return "http"
+}
+
+// buildVLLMEnvVars builds environment variables for the vLLM container of the model
+func (m *K8sManager) buildVLLMEnvVars(modelConfig *ModelConfig) []corev1.EnvVar {
 	envVars := []corev1.EnvVar{
-		// vLLM API key from secret
+		// vLLM API key from the model's secret
 		{
 			Name: "VLLM_API_KEY",
 			ValueFrom: &corev1.EnvVarSource{
 				SecretKeyRef: &corev1.SecretKeySelector{
-					LocalObjectReference: corev1.LocalObjectReference{Name: "vllm-api-key"},
-					Key:                  "api-key",
+					LocalObjectReference: corev1.LocalObjectReference{Name: m.config.apiKeySecret(modelConfig)},
+					Key:                  VLLMAPIKeySecretKey,
 				},
 			},
 		},
@@ -483,5 +771,10 @@ func (m *K8sManager) buildVLLMEnvVars() []corev1.EnvVar {
 		},
 	}
 
+	if m.config.SleepMode {
+		// The /sleep and /wake_up endpoints are only exposed in dev mode
+		envVars = append(envVars, corev1.EnvVar{Name: "VLLM_SERVER_DEV_MODE", Value: "1"})
+	}
+
 	return envVars
 }
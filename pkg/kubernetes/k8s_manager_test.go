@@ -2,11 +2,15 @@ package kubernetes
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestK8sManager_EnsureConfigMap(t *testing.T) {
@@ -174,7 +178,7 @@ func TestK8sManager_BuildSystemEnvVars(t *testing.T) {
 	}
 	manager := NewK8sManager(nil, config)
 
-	envVars := manager.buildVLLMEnvVars()
+	envVars := manager.buildVLLMEnvVars(&ModelConfig{})
 
 	// Check that system env vars are present (no model-specific vars)
 	requiredSystemVars := []string{
@@ -385,6 +389,120 @@ func TestK8sManager_DeletePod(t *testing.T) {
 	})
 }
 
+func TestK8sManager_DeletePodGracePeriod(t *testing.T) {
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "test-ns"},
+	}
+	clientset := fake.NewSimpleClientset(existingPod)
+	manager := NewK8sManager(clientset, &Config{
+		Namespace:           "test-ns",
+		Deployment:          "vllm",
+		ShutdownGracePeriod: 30 * time.Second,
+	})
+
+	if err := manager.DeletePod(context.Background()); err != nil {
+		t.Fatalf("DeletePod() error = %v", err)
+	}
+
+	var gracePeriods []int64
+	for _, action := range clientset.Actions() {
+		if deleteAction, ok := action.(k8stesting.DeleteAction); ok {
+			gracePeriods = append(gracePeriods, *deleteAction.GetDeleteOptions().GracePeriodSeconds)
+		}
+	}
+	if len(gracePeriods) != 1 || gracePeriods[0] != 30 {
+		t.Errorf("delete grace periods = %v, want [30] (no forced deletion)", gracePeriods)
+	}
+}
+
+func TestK8sManager_WaitForPodDeletionTimeout(t *testing.T) {
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "test-ns"},
+	}
+	manager := NewK8sManager(fake.NewSimpleClientset(existingPod), &Config{
+		Namespace:  "test-ns",
+		Deployment: "vllm",
+	})
+
+	if err := manager.waitForPodDeletion(context.Background(), 50*time.Millisecond); err == nil {
+		t.Error("waitForPodDeletion() should time out while the pod still exists")
+	}
+}
+
+func TestK8sManager_BuildPodSpecShutdown(t *testing.T) {
+	modelConfig := &ModelConfig{
+		ModelName:            "test/model",
+		ServedModelName:      "test-model",
+		MaxModelLen:          "8192",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "256",
+		Dtype:                "auto",
+	}
+
+	t.Run("immediate deletion has no preStop hook", func(t *testing.T) {
+		podSpec := NewK8sManager(nil, &Config{}).buildPodSpec(modelConfig)
+		if *podSpec.TerminationGracePeriodSeconds != 0 {
+			t.Errorf("TerminationGracePeriodSeconds = %d, want 0", *podSpec.TerminationGracePeriodSeconds)
+		}
+		if podSpec.Containers[0].Lifecycle != nil {
+			t.Error("Lifecycle should be nil without a grace period")
+		}
+	})
+
+	t.Run("grace period installs drain hook", func(t *testing.T) {
+		podSpec := NewK8sManager(nil, &Config{ShutdownGracePeriod: time.Minute}).buildPodSpec(modelConfig)
+		if *podSpec.TerminationGracePeriodSeconds != 60 {
+			t.Errorf("TerminationGracePeriodSeconds = %d, want 60", *podSpec.TerminationGracePeriodSeconds)
+		}
+		lifecycle := podSpec.Containers[0].Lifecycle
+		if lifecycle == nil || lifecycle.PreStop == nil || lifecycle.PreStop.Exec == nil {
+			t.Fatal("expected a preStop exec hook")
+		}
+		script := lifecycle.PreStop.Exec.Command[len(lifecycle.PreStop.Exec.Command)-1]
+		if !strings.Contains(script, "time.time() + 55") {
+			t.Errorf("preStop drain should leave time for SIGTERM handling, got script:\n%s", script)
+		}
+	})
+}
+
+func TestK8sManager_PauseResumeVLLMContainer(t *testing.T) {
+	existingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "test-ns"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "vllm", Image: "vllm/vllm-openai:latest"}},
+		},
+	}
+	manager := NewK8sManager(fake.NewSimpleClientset(existingPod), &Config{
+		Namespace:  "test-ns",
+		Deployment: "vllm",
+		PauseImage: DefaultPauseImage,
+	})
+	ctx := context.Background()
+
+	if err := manager.PauseVLLMContainer(ctx); err != nil {
+		t.Fatalf("PauseVLLMContainer() error = %v", err)
+	}
+	if paused, err := manager.IsVLLMContainerPaused(ctx); err != nil || !paused {
+		t.Errorf("IsVLLMContainerPaused() = %v, %v, want true", paused, err)
+	}
+
+	if err := manager.ResumeVLLMContainer(ctx); err != nil {
+		t.Fatalf("ResumeVLLMContainer() error = %v", err)
+	}
+	if paused, err := manager.IsVLLMContainerPaused(ctx); err != nil || paused {
+		t.Errorf("IsVLLMContainerPaused() = %v, %v, want false", paused, err)
+	}
+
+	// The pause image cannot run an explicit python command
+	if cmd := manager.buildVLLMCommand(); cmd != nil {
+		t.Errorf("buildVLLMCommand() = %v, want nil with a pause image", cmd)
+	}
+	if err := NewK8sManager(nil, &Config{}).PauseVLLMContainer(ctx); err == nil {
+		t.Error("PauseVLLMContainer() should fail without a pause image")
+	}
+}
+
 func TestK8sManager_GetPod(t *testing.T) {
 	existingPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -467,6 +585,396 @@ func TestK8sManager_PodExists(t *testing.T) {
 	})
 }
 
+func TestK8sManager_IsPodReady(t *testing.T) {
+	config := &Config{
+		Namespace:  "test-ns",
+		Deployment: "vllm",
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		pods []runtime.Object
+		want bool
+	}{
+		{name: "no pod", pods: nil, want: false},
+		{
+			name: "pod not ready",
+			pods: []runtime.Object{&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "test-ns"},
+			}},
+			want: false,
+		},
+		{
+			name: "pod ready",
+			pods: []runtime.Object{&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "test-ns"},
+				Status: corev1.PodStatus{
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				},
+			}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := NewK8sManager(fake.NewSimpleClientset(tt.pods...), config)
+			ready, err := manager.IsPodReady(ctx)
+			if err != nil {
+				t.Fatalf("IsPodReady() error = %v", err)
+			}
+			if ready != tt.want {
+				t.Errorf("IsPodReady() = %v, want %v", ready, tt.want)
+			}
+		})
+	}
+}
+
+func TestK8sManager_GetConfigMapData(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-rate-limits", Namespace: "test-ns"},
+		Data:       map[string]string{"default": "rpm=60"},
+	})
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns"})
+	ctx := context.Background()
+
+	data, err := manager.GetConfigMapData(ctx, "vllm-rate-limits")
+	if err != nil {
+		t.Fatalf("GetConfigMapData() error = %v", err)
+	}
+	if data["default"] != "rpm=60" {
+		t.Errorf("GetConfigMapData() = %v, want default entry rpm=60", data)
+	}
+
+	if _, err := manager.GetConfigMapData(ctx, "missing"); err == nil {
+		t.Error("GetConfigMapData() should fail for a missing ConfigMap")
+	}
+}
+
+func TestK8sManager_SaveConfigMapData(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns"})
+	ctx := context.Background()
+
+	if err := manager.SaveConfigMapData(ctx, "vllm-usage", map[string]string{"usage.json": "{}"}); err != nil {
+		t.Fatalf("SaveConfigMapData() create error = %v", err)
+	}
+	if err := manager.SaveConfigMapData(ctx, "vllm-usage", map[string]string{"usage.json": `{"total":{}}`}); err != nil {
+		t.Fatalf("SaveConfigMapData() update error = %v", err)
+	}
+
+	data, err := manager.GetConfigMapData(ctx, "vllm-usage")
+	if err != nil {
+		t.Fatalf("GetConfigMapData() error = %v", err)
+	}
+	if data["usage.json"] != `{"total":{}}` {
+		t.Errorf("GetConfigMapData() = %v, want the updated usage", data)
+	}
+}
+
+func TestK8sManager_GetSecretData(t *testing.T) {
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-api-keys", Namespace: "test-ns"},
+		Data:       map[string][]byte{"team-a": []byte(`{"key":"sk-a"}`)},
+	})
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns"})
+	ctx := context.Background()
+
+	data, err := manager.GetSecretData(ctx, "vllm-api-keys")
+	if err != nil {
+		t.Fatalf("GetSecretData() error = %v", err)
+	}
+	if string(data["team-a"]) != `{"key":"sk-a"}` {
+		t.Errorf("GetSecretData() = %v, want the team-a entry", data)
+	}
+
+	if _, err := manager.GetSecretData(ctx, "missing"); err == nil {
+		t.Error("GetSecretData() should fail for a missing Secret")
+	}
+}
+
+func TestK8sManager_CustomServiceAndAppLabel(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	config := &Config{
+		Namespace:   "test-ns",
+		Deployment:  "vllm-embed",
+		ServiceName: "vllm-embed-api",
+		AppLabel:    "vllm-embed",
+	}
+	manager := NewK8sManager(clientset, config)
+	ctx := context.Background()
+
+	if err := manager.ensureService(ctx); err != nil {
+		t.Fatalf("ensureService() error = %v", err)
+	}
+	svc, err := clientset.CoreV1().Services("test-ns").Get(ctx, "vllm-embed-api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Service: %v", err)
+	}
+	if svc.Spec.Selector["app"] != "vllm-embed" {
+		t.Errorf("Service selector app = %v, want vllm-embed", svc.Spec.Selector["app"])
+	}
+
+	if err := manager.CreatePod(ctx, &ModelConfig{ModelName: "BAAI/bge-m3", ServedModelName: "bge-m3", Embedding: "true"}); err != nil {
+		t.Fatalf("CreatePod() error = %v", err)
+	}
+	pod, err := clientset.CoreV1().Pods("test-ns").Get(ctx, "vllm-embed", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Failed to get Pod: %v", err)
+	}
+	if pod.Labels["app"] != "vllm-embed" {
+		t.Errorf("Pod label app = %v, want vllm-embed", pod.Labels["app"])
+	}
+}
+
+func TestK8sManager_BuildVLLMArgsEmbedding(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 1})
+
+	args := argsToMap(manager.buildVLLMArgs(&ModelConfig{
+		ModelName:            "BAAI/bge-m3",
+		ServedModelName:      "bge-m3",
+		MaxModelLen:          "8192",
+		GPUMemoryUtilization: "0.3",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "64",
+		Dtype:                "auto",
+		EnableAutoToolChoice: "true",
+		ToolCallParser:       "hermes",
+		Embedding:            "true",
+	}))
+
+	if args["--task"] != "embed" {
+		t.Errorf("--task = %v, want embed", args["--task"])
+	}
+	if _, ok := args["--tool-call-parser"]; ok {
+		t.Error("embedding models should not get a tool call parser")
+	}
+	if _, ok := args["--enable-auto-tool-choice"]; ok {
+		t.Error("embedding models should not enable auto tool choice")
+	}
+}
+
+func TestK8sManager_LoRAAdapter(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 1})
+	modelConfig := &ModelConfig{
+		ModelName:            "Qwen/Qwen3-8B",
+		ServedModelName:      "qwen-sql",
+		MaxModelLen:          "32768",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "16",
+		Dtype:                "auto",
+		ToolCallParser:       "hermes",
+		LoRAAdapter:          "acme/qwen-sql-lora",
+		MaxLoRARank:          "64",
+	}
+
+	args := argsToMap(manager.buildVLLMArgs(modelConfig))
+	if args["--served-model-name"] != "Qwen/Qwen3-8B" {
+		t.Errorf("--served-model-name = %v, want the base model name", args["--served-model-name"])
+	}
+	if args["--lora-modules"] != "qwen-sql=acme/qwen-sql-lora" {
+		t.Errorf("--lora-modules = %v, want qwen-sql=acme/qwen-sql-lora", args["--lora-modules"])
+	}
+	if args["--enable-lora"] != "true" || args["--max-lora-rank"] != "64" {
+		t.Errorf("LoRA flags missing: %v", args)
+	}
+
+	runtimeUpdates := false
+	for _, env := range manager.buildPodSpec(modelConfig).Containers[0].Env {
+		if env.Name == "VLLM_ALLOW_RUNTIME_LORA_UPDATING" && env.Value == "True" {
+			runtimeUpdates = true
+		}
+	}
+	if !runtimeUpdates {
+		t.Error("LoRA pods should allow runtime adapter updates")
+	}
+}
+
+func TestK8sManager_SpeculativeDecoding(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 1})
+	modelConfig := &ModelConfig{
+		ModelName:            "meta-llama/Llama-3.1-70B-Instruct",
+		ServedModelName:      "llama-70b",
+		MaxModelLen:          "32768",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "16",
+		Dtype:                "auto",
+		ToolCallParser:       "llama3_json",
+	}
+
+	if _, ok := argsToMap(manager.buildVLLMArgs(modelConfig))["--speculative-config"]; ok {
+		t.Error("speculative decoding should be disabled without a draft model or method")
+	}
+
+	modelConfig.SpeculativeModel = "yuhuili/EAGLE-LLaMA3.1-Instruct-70B"
+	modelConfig.SpeculativeMethod = "eagle"
+	modelConfig.NumSpeculativeTokens = "5"
+	args := argsToMap(manager.buildVLLMArgs(modelConfig))
+	want := `{"method":"eagle","model":"yuhuili/EAGLE-LLaMA3.1-Instruct-70B","num_speculative_tokens":5}`
+	if args["--speculative-config"] != want {
+		t.Errorf("--speculative-config = %v, want %v", args["--speculative-config"], want)
+	}
+
+	modelConfig.SpeculativeModel = ""
+	modelConfig.SpeculativeMethod = "ngram"
+	args = argsToMap(manager.buildVLLMArgs(modelConfig))
+	if want := `{"method":"ngram","num_speculative_tokens":5}`; args["--speculative-config"] != want {
+		t.Errorf("--speculative-config = %v, want %v", args["--speculative-config"], want)
+	}
+}
+
+func TestK8sManager_GuidedDecodingBackend(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 1})
+	modelConfig := &ModelConfig{
+		ModelName:            "Qwen/Qwen3-8B",
+		ServedModelName:      "qwen",
+		MaxModelLen:          "32768",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "16",
+		Dtype:                "auto",
+		ToolCallParser:       "hermes",
+	}
+	if _, ok := argsToMap(manager.buildVLLMArgs(modelConfig))["--guided-decoding-backend"]; ok {
+		t.Error("vLLM should pick its default guided decoding backend")
+	}
+
+	modelConfig.GuidedDecodingBackend = "outlines"
+	if backend := argsToMap(manager.buildVLLMArgs(modelConfig))["--guided-decoding-backend"]; backend != "outlines" {
+		t.Errorf("--guided-decoding-backend = %v, want outlines", backend)
+	}
+}
+
+func TestK8sManager_APIKeySecret(t *testing.T) {
+	apiKeySecret := func(manager *K8sManager, modelConfig *ModelConfig) string {
+		for _, env := range manager.buildVLLMEnvVars(modelConfig) {
+			if env.Name == "VLLM_API_KEY" {
+				return env.ValueFrom.SecretKeyRef.Name
+			}
+		}
+		return ""
+	}
+
+	manager := NewK8sManager(nil, &Config{GPUCount: 1})
+	if secret := apiKeySecret(manager, &ModelConfig{}); secret != VLLMAPIKeySecret {
+		t.Errorf("API key Secret = %q, want %s by default", secret, VLLMAPIKeySecret)
+	}
+	manager = NewK8sManager(nil, &Config{GPUCount: 1, APIKeySecret: "team-api-key"})
+	if secret := apiKeySecret(manager, &ModelConfig{}); secret != "team-api-key" {
+		t.Errorf("API key Secret = %q, want the configured team-api-key", secret)
+	}
+	if secret := apiKeySecret(manager, &ModelConfig{APIKeySecret: "qwen-api-key"}); secret != "qwen-api-key" {
+		t.Errorf("API key Secret = %q, want the model's qwen-api-key", secret)
+	}
+
+	// The kubelet doesn't expand variables in probe headers, and vLLM doesn't require its key on /health
+	if probe := manager.healthProbe().HTTPGet; probe == nil || len(probe.HTTPHeaders) != 0 {
+		t.Errorf("health probe = %+v, want /health without headers", probe)
+	}
+}
+
+func TestK8sManager_TLSSecret(t *testing.T) {
+	modelConfig := &ModelConfig{
+		ModelName:            "Qwen/Qwen3-8B",
+		ServedModelName:      "qwen",
+		MaxModelLen:          "32768",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "16",
+		Dtype:                "auto",
+		ToolCallParser:       "hermes",
+	}
+
+	t.Run("serves HTTPS with the mounted Secret", func(t *testing.T) {
+		manager := NewK8sManager(nil, &Config{GPUCount: 1, TLSSecret: "vllm-tls", ShutdownGracePeriod: time.Minute})
+		args := argsToMap(manager.buildVLLMArgs(modelConfig))
+		if args["--ssl-certfile"] != "/etc/vllm-tls/tls.crt" || args["--ssl-keyfile"] != "/etc/vllm-tls/tls.key" {
+			t.Errorf("--ssl-certfile = %v, --ssl-keyfile = %v, want the mounted Secret", args["--ssl-certfile"], args["--ssl-keyfile"])
+		}
+		if _, ok := args["--ssl-cert-reqs"]; ok {
+			t.Error("vLLM should not require client certificates")
+		}
+
+		podSpec := manager.buildPodSpec(modelConfig)
+		mounted := false
+		for _, volume := range podSpec.Volumes {
+			if volume.Name == "vllm-tls" && volume.Secret != nil && volume.Secret.SecretName == "vllm-tls" {
+				mounted = true
+			}
+		}
+		if !mounted {
+			t.Error("the TLS Secret should be a volume of the pod")
+		}
+		probe := podSpec.Containers[0].ReadinessProbe.HTTPGet
+		if probe == nil || probe.Scheme != corev1.URISchemeHTTPS {
+			t.Errorf("readiness probe = %+v, want an HTTPS /health probe", probe)
+		}
+		script := podSpec.Containers[0].Lifecycle.PreStop.Exec.Command[2]
+		if !strings.Contains(script, "https://localhost:8000/metrics") || strings.Contains(script, "load_cert_chain") {
+			t.Errorf("preStop drain should scrape vLLM over HTTPS, got script:\n%s", script)
+		}
+	})
+
+	t.Run("client certificates", func(t *testing.T) {
+		manager := NewK8sManager(nil, &Config{GPUCount: 1, TLSSecret: "vllm-tls", TLSClientAuth: true, ShutdownGracePeriod: time.Minute})
+		args := argsToMap(manager.buildVLLMArgs(modelConfig))
+		if args["--ssl-ca-certs"] != "/etc/vllm-tls/ca.crt" || args["--ssl-cert-reqs"] != "2" {
+			t.Errorf("--ssl-ca-certs = %v, --ssl-cert-reqs = %v, want client certificates required", args["--ssl-ca-certs"], args["--ssl-cert-reqs"])
+		}
+
+		podSpec := manager.buildPodSpec(modelConfig)
+		if podSpec.Containers[0].StartupProbe.TCPSocket == nil {
+			t.Error("vLLM requiring client certificates should be probed on its port")
+		}
+		script := podSpec.Containers[0].Lifecycle.PreStop.Exec.Command[2]
+		if !strings.Contains(script, `context.load_cert_chain("/etc/vllm-tls/tls.crt", "/etc/vllm-tls/tls.key")`) {
+			t.Errorf("preStop drain should present the pod's certificate, got script:\n%s", script)
+		}
+	})
+
+	t.Run("plain HTTP without a Secret", func(t *testing.T) {
+		podSpec := NewK8sManager(nil, &Config{GPUCount: 1, ShutdownGracePeriod: time.Minute}).buildPodSpec(modelConfig)
+		if probe := podSpec.Containers[0].ReadinessProbe.HTTPGet; probe == nil || probe.Scheme != "" {
+			t.Errorf("readiness probe = %+v, want an HTTP /health probe", probe)
+		}
+		script := podSpec.Containers[0].Lifecycle.PreStop.Exec.Command[2]
+		if !strings.Contains(script, "http://localhost:8000/metrics") {
+			t.Errorf("preStop drain should scrape vLLM over HTTP, got script:\n%s", script)
+		}
+	})
+}
+
+func TestK8sManager_SleepMode(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 1, SleepMode: true})
+
+	args := argsToMap(manager.buildVLLMArgs(&ModelConfig{
+		ModelName:            "test/model",
+		ServedModelName:      "test-model",
+		MaxModelLen:          "8192",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "256",
+		Dtype:                "auto",
+		ToolCallParser:       "hermes",
+	}))
+	if _, ok := args["--enable-sleep-mode"]; !ok {
+		t.Error("Args should contain --enable-sleep-mode")
+	}
+
+	devMode := false
+	for _, env := range manager.buildVLLMEnvVars(&ModelConfig{}) {
+		if env.Name == "VLLM_SERVER_DEV_MODE" && env.Value == "1" {
+			devMode = true
+		}
+	}
+	if !devMode {
+		t.Error("VLLM_SERVER_DEV_MODE=1 is required for the sleep endpoints")
+	}
+}
+
 func TestK8sManager_VerifyPodConfig(t *testing.T) {
 	modelConfig := &ModelConfig{
 		ModelName:              "test/model",
@@ -492,24 +1000,14 @@ func TestK8sManager_VerifyPodConfig(t *testing.T) {
 	}
 	manager := NewK8sManager(nil, config)
 
-	// Build the actual args that would be generated
-	expectedArgs := manager.buildVLLMArgs(modelConfig)
-
-	// Create a pod with matching args - must be Running to trigger verification
+	// Create a pod with the spec that would be generated, without the spec hash annotation of
+	// older versions - must be Running to trigger verification
 	existingPod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "vllm",
 			Namespace: "test-ns",
 		},
-		Spec: corev1.PodSpec{
-			Containers: []corev1.Container{
-				{
-					Name:  "vllm",
-					Image: "vllm/vllm-openai:latest",
-					Args:  expectedArgs,
-				},
-			},
-		},
+		Spec: manager.buildPodSpec(modelConfig),
 		Status: corev1.PodStatus{
 			Phase: corev1.PodRunning,
 		},
@@ -572,6 +1070,18 @@ func TestK8sManager_VerifyPodConfig(t *testing.T) {
 			t.Error("Config should not match")
 		}
 	})
+
+	t.Run("different node pool", func(t *testing.T) {
+		a100 := *modelConfig
+		a100.GPUType = "NVIDIA-A100-SXM4-80GB"
+		matches, err := manager.VerifyPodConfig(ctx, &a100)
+		if err != nil {
+			t.Fatalf("VerifyPodConfig() error = %v", err)
+		}
+		if matches {
+			t.Error("A pod without the GPU type node selector should not match")
+		}
+	})
 }
 
 func TestArgsToMap(t *testing.T) {
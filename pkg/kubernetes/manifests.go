@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// NewManifestCRDClient returns a CRD client serving the VLLMModels of the YAML manifests in dir,
+// for the proxy running without a cluster. The models are kept in memory: status updates and
+// finalizers aren't written back to the files.
+func NewManifestCRDClient(dir string) (*CRDClient, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	ymlPaths, err := filepath.Glob(filepath.Join(dir, "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, ymlPaths...)
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{vllmModelGVR: "VLLMModelList"})
+	for _, path := range paths {
+		models, err := readModelManifests(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, model := range models {
+			if _, err := client.Resource(vllmModelGVR).Create(context.Background(), model, metav1.CreateOptions{}); err != nil {
+				return nil, fmt.Errorf("failed to load VLLMModel %s from %s: %w", model.GetName(), path, err)
+			}
+		}
+	}
+	return NewCRDClient(client), nil
+}
+
+// readModelManifests returns the VLLMModels of a manifest file, skipping its other objects
+func readModelManifests(path string) ([]*unstructured.Unstructured, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	objects, err := decodeManifests(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	var models []*unstructured.Unstructured
+	for _, obj := range objects {
+		if obj.GroupVersionKind() == vllmModelGVR.GroupVersion().WithKind("VLLMModel") {
+			models = append(models, obj)
+		}
+	}
+	return models, nil
+}
+
+// decodeManifests returns the objects of a multi-document YAML or JSON stream
+func decodeManifests(r io.Reader) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, err
+		}
+		if len(raw.Raw) == 0 || string(raw.Raw) == "null" {
+			continue // Empty document
+		}
+		// Decoded like API responses, with integers as int64
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(raw.Raw); err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+}
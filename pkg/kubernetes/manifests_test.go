@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewManifestCRDClient(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"qwen.yaml": `apiVersion: vllm.sir-alfred.io/v1alpha1
+kind: VLLMModel
+metadata:
+  name: qwen3-8b
+spec:
+  modelName: Qwen/Qwen3-8B
+  servedModelName: qwen3-8b
+  toolCallParser: hermes
+  maxModelLen: 32768
+  gpuMemoryUtilization: 0.9
+  enableChunkedPrefill: true
+  maxNumBatchedTokens: 8192
+  maxNumSeqs: 16
+  dtype: auto
+  disableCustomAllReduce: false
+  enablePrefixCaching: true
+  enableAutoToolChoice: true
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+`,
+		"mistral.yml": `apiVersion: vllm.sir-alfred.io/v1alpha1
+kind: VLLMModel
+metadata:
+  name: mistral
+spec:
+  modelName: mistralai/Mistral-7B-Instruct-v0.3
+  servedModelName: mistral
+  aliases: ["mistral-*"]
+  toolCallParser: mistral
+  maxModelLen: 32768
+  gpuMemoryUtilization: 0.9
+  enableChunkedPrefill: true
+  maxNumBatchedTokens: 8192
+  maxNumSeqs: 16
+  dtype: auto
+  disableCustomAllReduce: false
+  enablePrefixCaching: true
+  enableAutoToolChoice: true
+`,
+		"notes.txt": "not a manifest",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	client, err := NewManifestCRDClient(dir)
+	if err != nil {
+		t.Fatalf("NewManifestCRDClient() error = %v", err)
+	}
+	ctx := context.Background()
+
+	models, err := client.ListModels(ctx)
+	if err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if len(models) != 2 {
+		t.Errorf("ListModels() = %d models, want 2", len(models))
+	}
+
+	model, err := client.GetModel(ctx, "qwen3-8b")
+	if err != nil {
+		t.Fatalf("GetModel() error = %v", err)
+	}
+	if model.ModelName != "Qwen/Qwen3-8B" || model.MaxModelLen != "32768" {
+		t.Errorf("GetModel() = %s with max model len %s", model.ModelName, model.MaxModelLen)
+	}
+	if _, err := client.ResolveModel(ctx, "mistral-latest"); err != nil {
+		t.Errorf("ResolveModel() through an alias error = %v", err)
+	}
+}
+
+func TestNewManifestCRDClient_InvalidManifest(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("kind: [VLLMModel"), 0o600); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	if _, err := NewManifestCRDClient(dir); err == nil {
+		t.Error("NewManifestCRDClient() with an invalid manifest succeeded")
+	}
+}
@@ -1,32 +1,82 @@
 package kubernetes
 
 import (
+	"encoding/json"
 	"fmt"
+	"slices"
 	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // ModelConfig represents a model configuration profile
 type ModelConfig struct {
 	// Model identification
-	ModelName       string
-	ServedModelName string
+	ModelName       string `json:"modelName"`
+	ServedModelName string `json:"servedModelName"`
 
 	// Parsing configuration
-	ToolCallParser  string
-	ReasoningParser string
+	ToolCallParser  string `json:"toolCallParser,omitempty"`
+	ReasoningParser string `json:"reasoningParser,omitempty"`
+
+	// Proxy-side parser for tool calls written as text: xml (default) or json
+	FallbackToolParser string `json:"fallbackToolParser,omitempty"`
 
 	// vLLM runtime parameters (model-specific)
-	MaxModelLen            string
-	GPUMemoryUtilization   string
-	EnableChunkedPrefill   string
-	MaxNumBatchedTokens    string
-	MaxNumSeqs             string
-	Dtype                  string
-	DisableCustomAllReduce string
-	EnablePrefixCaching    string
-	EnableAutoToolChoice   string
+	MaxModelLen            string `json:"maxModelLen,omitempty"`
+	GPUMemoryUtilization   string `json:"gpuMemoryUtilization,omitempty"`
+	EnableChunkedPrefill   string `json:"enableChunkedPrefill,omitempty"`
+	MaxNumBatchedTokens    string `json:"maxNumBatchedTokens,omitempty"`
+	MaxNumSeqs             string `json:"maxNumSeqs,omitempty"`
+	Dtype                  string `json:"dtype,omitempty"`
+	DisableCustomAllReduce string `json:"disableCustomAllReduce,omitempty"`
+	EnablePrefixCaching    string `json:"enablePrefixCaching,omitempty"`
+	EnableAutoToolChoice   string `json:"enableAutoToolChoice,omitempty"`
+
+	// Serving mode
+	Embedding string `json:"embedding,omitempty"` // "true" for embedding models served on /v1/embeddings
+
+	// Horizontal scaling
+	MinReplicas string `json:"minReplicas,omitempty"` // Pods kept running even when idle (0 allows scale to zero)
+	MaxReplicas string `json:"maxReplicas,omitempty"` // Upper bound of pods under load (defaults to 1)
+
+	// LoRA adapter served under servedModelName on top of modelName, hot-swapped between models sharing the base
+	LoRAAdapter string `json:"loraAdapter,omitempty"` // Hugging Face repository or local path of the adapter
+	MaxLoRARank string `json:"maxLoraRank,omitempty"` // Largest adapter rank the pod accepts (vLLM default when empty)
+
+	// Speculative decoding, disabled when no draft model or method is set
+	SpeculativeModel     string `json:"speculativeModel,omitempty"`     // Draft model, Hugging Face repository or local path
+	SpeculativeMethod    string `json:"speculativeMethod,omitempty"`    // vLLM method (e.g., ngram, eagle, mtp), inferred from the draft model when empty
+	NumSpeculativeTokens string `json:"numSpeculativeTokens,omitempty"` // Tokens proposed per step
+
+	// Request limits enforced by the proxy
+	MaxOutputTokens   string `json:"maxOutputTokens,omitempty"`   // Cap of max_tokens, uncapped when empty
+	RequestTimeout    string `json:"requestTimeout,omitempty"`    // Time vLLM gets to answer once up (e.g., 10m), none when empty
+	MaxStreamDuration string `json:"maxStreamDuration,omitempty"` // Time a streamed response may last once up (e.g., 30m), none when empty
+
+	// Structured outputs
+	GuidedDecoding        string `json:"guidedDecoding,omitempty"`        // "true" to decode forced Anthropic tool calls against their input_schema
+	GuidedDecodingBackend string `json:"guidedDecodingBackend,omitempty"` // vLLM guided decoding backend (e.g., outlines), vLLM default when empty
+
+	// Secret holding the API key the model's pods require, under api-key, which the proxy sends in
+	// place of the client's (defaults to the proxy's vLLM API key Secret)
+	APIKeySecret string `json:"apiKeySecret,omitempty"`
+
+	// Overrides of the vLLM pod spec (image, resources, volumes, scheduling, env)
+	PodTemplate *PodTemplate `json:"podTemplate,omitempty"`
+
+	// GPU node pool the model runs on
+	GPUType      string              `json:"gpuType,omitempty"` // Value of the GPUTypeLabel node label (e.g., NVIDIA-A100-SXM4-80GB)
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
 }
 
+// GuidedDecodingBackends are the values of vLLM's --guided-decoding-backend
+var GuidedDecodingBackends = []string{"auto", "outlines", "lm-format-enforcer", "xgrammar", "guidance"}
+
 // ToConfigMapData converts ModelConfig to ConfigMap data format
 //
 // Deprecated: ConfigMaps are no longer used, config read directly from CRD
@@ -69,6 +119,65 @@ func FromConfigMapData(data map[string]string) *ModelConfig {
 	}
 }
 
+// ReplicaBounds returns the replica range of the model, defaulting to 0..1 (scale to zero, single pod)
+func (m *ModelConfig) ReplicaBounds() (minReplicas, maxReplicas int) {
+	minReplicas, _ = strconv.Atoi(m.MinReplicas)
+	maxReplicas, _ = strconv.Atoi(m.MaxReplicas)
+	maxReplicas = max(maxReplicas, 1)
+	minReplicas = min(max(minReplicas, 0), maxReplicas)
+	return minReplicas, maxReplicas
+}
+
+// RequestLimits returns the max_tokens cap and the request timeout of the model, 0 when unset
+func (m *ModelConfig) RequestLimits() (maxOutputTokens int, requestTimeout time.Duration) {
+	maxOutputTokens, _ = strconv.Atoi(m.MaxOutputTokens)
+	requestTimeout, _ = time.ParseDuration(m.RequestTimeout)
+	return max(maxOutputTokens, 0), max(requestTimeout, 0)
+}
+
+// StreamLimit returns the time a streamed response of the model may last, 0 when unbounded
+func (m *ModelConfig) StreamLimit() time.Duration {
+	d, _ := time.ParseDuration(m.MaxStreamDuration)
+	return max(d, 0)
+}
+
+// SpeculativeConfig returns the JSON value of vLLM's --speculative-config, empty when speculative
+// decoding is disabled
+func (m *ModelConfig) SpeculativeConfig() string {
+	if m.SpeculativeModel == "" && m.SpeculativeMethod == "" {
+		return ""
+	}
+	numTokens, _ := strconv.Atoi(m.NumSpeculativeTokens)
+	config, _ := json.Marshal(struct {
+		Method    string `json:"method,omitempty"`
+		Model     string `json:"model,omitempty"`
+		NumTokens int    `json:"num_speculative_tokens"`
+	}{m.SpeculativeMethod, m.SpeculativeModel, numTokens})
+	return string(config)
+}
+
+// SharesBaseWith reports whether both models are LoRA adapters on the same base model with the
+// same runtime parameters, so the pod of one can serve the other by swapping adapters
+func (m *ModelConfig) SharesBaseWith(other *ModelConfig) bool {
+	if m.LoRAAdapter == "" || other.LoRAAdapter == "" {
+		return false
+	}
+	a, b := *m, *other
+	for _, c := range []*ModelConfig{&a, &b} {
+		// Fields that don't change how the base model is served
+		c.ServedModelName = ""
+		c.LoRAAdapter = ""
+		c.FallbackToolParser = ""
+		c.MinReplicas = ""
+		c.MaxReplicas = ""
+		c.MaxOutputTokens = ""
+		c.RequestTimeout = ""
+		c.MaxStreamDuration = ""
+		c.GuidedDecoding = ""
+	}
+	return equality.Semantic.DeepEqual(a, b)
+}
+
 // boolToString converts a bool pointer to string
 func boolToString(b *bool) string {
 	if b == nil {
@@ -115,5 +224,37 @@ func (m *ModelConfig) Validate() error {
 		return fmt.Errorf("enableAutoToolChoice is required")
 	}
 
+	if m.RequestTimeout != "" {
+		if d, err := time.ParseDuration(m.RequestTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid requestTimeout %q", m.RequestTimeout)
+		}
+	}
+	if m.MaxStreamDuration != "" {
+		if d, err := time.ParseDuration(m.MaxStreamDuration); err != nil || d <= 0 {
+			return fmt.Errorf("invalid maxStreamDuration %q", m.MaxStreamDuration)
+		}
+	}
+
+	if m.APIKeySecret != "" && len(validation.IsDNS1123Subdomain(m.APIKeySecret)) > 0 {
+		return fmt.Errorf("invalid apiKeySecret %q", m.APIKeySecret)
+	}
+
+	if m.GuidedDecodingBackend != "" && !slices.Contains(GuidedDecodingBackends, m.GuidedDecodingBackend) {
+		return fmt.Errorf("invalid guidedDecodingBackend %q", m.GuidedDecodingBackend)
+	}
+
+	speculative := m.SpeculativeModel != "" || m.SpeculativeMethod != ""
+	switch {
+	case speculative && m.NumSpeculativeTokens == "":
+		return fmt.Errorf("numSpeculativeTokens is required with speculative decoding")
+	case !speculative && m.NumSpeculativeTokens != "":
+		return fmt.Errorf("numSpeculativeTokens requires a speculativeModel or speculativeMethod")
+	}
+	if m.NumSpeculativeTokens != "" {
+		if n, err := strconv.Atoi(m.NumSpeculativeTokens); err != nil || n < 1 {
+			return fmt.Errorf("invalid numSpeculativeTokens %q", m.NumSpeculativeTokens)
+		}
+	}
+
 	return nil
 }
@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"testing"
+	"time"
 )
 
 func TestModelConfig_ToConfigMapData(t *testing.T) {
@@ -192,6 +193,70 @@ func TestModelConfig_Validate(t *testing.T) {
 			config:  &ModelConfig{},
 			wantErr: true,
 		},
+		{
+			name: "speculative decoding",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.SpeculativeModel = "test/draft-model"
+				c.NumSpeculativeTokens = "4"
+				return &c
+			}(),
+			wantErr: false,
+		},
+		{
+			name: "speculative decoding without numSpeculativeTokens",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.SpeculativeMethod = "ngram"
+				return &c
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "numSpeculativeTokens without draft model",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.NumSpeculativeTokens = "4"
+				return &c
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid requestTimeout",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.RequestTimeout = "10 minutes"
+				return &c
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid maxStreamDuration",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.MaxStreamDuration = "-5m"
+				return &c
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid guidedDecodingBackend",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.GuidedDecodingBackend = "regex"
+				return &c
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "invalid apiKeySecret",
+			config: func() *ModelConfig {
+				c := *validConfig
+				c.APIKeySecret = "Qwen_API_Key"
+				return &c
+			}(),
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -204,6 +269,71 @@ func TestModelConfig_Validate(t *testing.T) {
 	}
 }
 
+func TestModelConfig_SharesBaseWith(t *testing.T) {
+	base := ModelConfig{
+		ModelName:       "Qwen/Qwen3-8B",
+		ServedModelName: "qwen-sql",
+		MaxModelLen:     "32768",
+		LoRAAdapter:     "acme/qwen-sql-lora",
+	}
+
+	sibling := base
+	sibling.ServedModelName = "qwen-legal"
+	sibling.LoRAAdapter = "acme/qwen-legal-lora"
+	sibling.MaxReplicas = "2"
+	if !base.SharesBaseWith(&sibling) {
+		t.Error("adapters on the same base and runtime parameters should share the base")
+	}
+
+	otherLength := sibling
+	otherLength.MaxModelLen = "8192"
+	if base.SharesBaseWith(&otherLength) {
+		t.Error("different runtime parameters need a pod restart")
+	}
+
+	plain := base
+	plain.LoRAAdapter = ""
+	if base.SharesBaseWith(&plain) || plain.SharesBaseWith(&base) {
+		t.Error("models without an adapter are never swapped")
+	}
+
+	base.PodTemplate = &PodTemplate{Image: "vllm/vllm-openai:v0.11.0"}
+	sibling.PodTemplate = &PodTemplate{Image: "vllm/vllm-openai:v0.11.0"}
+	if !base.SharesBaseWith(&sibling) {
+		t.Error("equal pod templates should share the base")
+	}
+	sibling.PodTemplate = &PodTemplate{Image: "vllm/vllm-openai:nightly"}
+	if base.SharesBaseWith(&sibling) {
+		t.Error("different pod templates need a pod restart")
+	}
+	sibling.PodTemplate = base.PodTemplate
+	sibling.GPUType = "NVIDIA-A100-SXM4-80GB"
+	if base.SharesBaseWith(&sibling) {
+		t.Error("models on different node pools need a pod restart")
+	}
+}
+
+func TestModelConfig_RequestLimits(t *testing.T) {
+	tokens, timeout := (&ModelConfig{}).RequestLimits()
+	if tokens != 0 || timeout != 0 {
+		t.Errorf("RequestLimits() = %v, %v, want no limits", tokens, timeout)
+	}
+
+	tokens, timeout = (&ModelConfig{MaxOutputTokens: "4096", RequestTimeout: "90s"}).RequestLimits()
+	if tokens != 4096 || timeout != 90*time.Second {
+		t.Errorf("RequestLimits() = %v, %v, want 4096, 1m30s", tokens, timeout)
+	}
+}
+
+func TestModelConfig_StreamLimit(t *testing.T) {
+	if limit := (&ModelConfig{}).StreamLimit(); limit != 0 {
+		t.Errorf("StreamLimit() = %v, want no limit", limit)
+	}
+	if limit := (&ModelConfig{MaxStreamDuration: "30m"}).StreamLimit(); limit != 30*time.Minute {
+		t.Errorf("StreamLimit() = %v, want 30m", limit)
+	}
+}
+
 func TestBoolToString(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,140 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// hfCacheHostPath is the node directory holding the Hugging Face cache of the vLLM pods
+	hfCacheHostPath = "/home/manu/.cache/huggingface"
+
+	pageCacheImage = "busybox:1.36"
+
+	// pageCacheMountPath is where the page cache warmer mounts the Hugging Face cache
+	pageCacheMountPath = "/hf-cache"
+
+	// pageCacheRefresh is how often, in seconds, the warmer reads the files again so the kernel
+	// keeps them among the recently used pages
+	pageCacheRefresh = 300
+)
+
+// pageCacheScript reads the files of each model directory given as argument, in order, until the
+// byte budget (0 = unlimited) is spent, then reads them again every refresh interval. Files that
+// don't fit are skipped, so a large model doesn't keep a smaller one out of the budget.
+const pageCacheScript = `
+while true; do
+  left=$BUDGET
+  for dir in "$@"; do
+    [ -d "$dir" ] || continue
+    for f in $(find -L "$dir/snapshots" -type f 2>/dev/null); do
+      size=$(stat -Lc %s "$f")
+      if [ "$BUDGET" -gt 0 ]; then
+        [ "$size" -le "$left" ] || continue
+        left=$((left - size))
+      fi
+      cat "$f" > /dev/null
+    done
+  done
+  sleep $REFRESH
+done
+`
+
+// PageCacheWarmerName returns the name of the page cache warmer pod
+func (m *K8sManager) PageCacheWarmerName() string {
+	return m.config.Deployment + "-page-cache"
+}
+
+// HFCacheDir returns the directory of a Hugging Face model in the cache, relative to the cache
+// root, and false for models loaded from a local path
+func HFCacheDir(modelName string) (string, bool) {
+	if modelName == "" || strings.HasPrefix(modelName, "/") || strings.HasPrefix(modelName, ".") {
+		return "", false
+	}
+	return "hub/models--" + strings.ReplaceAll(modelName, "/", "--"), true
+}
+
+// StartPageCacheWarmer replaces the page cache warmer with one reading the files of the models,
+// most recently used first, on the node. Reading the weights keeps them in the node's page cache,
+// so the next cold start loads them from memory instead of disk.
+func (m *K8sManager) StartPageCacheWarmer(ctx context.Context, node string, models []string, budget int64) error {
+	var dirs []string
+	for _, model := range models {
+		if dir, ok := HFCacheDir(model); ok {
+			dirs = append(dirs, pageCacheMountPath+"/"+dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+	if err := m.StopPageCacheWarmer(ctx); err != nil {
+		return err
+	}
+
+	pod := m.buildPageCacheWarmerPod(node, dirs, budget)
+	if _, err := m.clientset.CoreV1().Pods(m.config.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create page cache warmer: %w", err)
+	}
+	log.Printf("Created page cache warmer %s/%s on node %s for %s", m.config.Namespace, pod.Name, node, strings.Join(models, ", "))
+	return nil
+}
+
+// StopPageCacheWarmer deletes the page cache warmer, if any. The pages it read stay cached until
+// the kernel needs the memory.
+func (m *K8sManager) StopPageCacheWarmer(ctx context.Context) error {
+	return m.deletePodNamed(ctx, m.PageCacheWarmerName(), 0)
+}
+
+// buildPageCacheWarmerPod builds the warmer pod, pinned to the node whose page cache it fills
+func (m *K8sManager) buildPageCacheWarmerPod(node string, dirs []string, budget int64) *corev1.Pod {
+	hostPathType := corev1.HostPathDirectory
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.PageCacheWarmerName(),
+			Namespace: m.config.Namespace,
+			Labels: map[string]string{
+				"app":        m.config.appLabel() + "-page-cache",
+				"managed-by": "vllm-chill",
+			},
+		},
+		Spec: corev1.PodSpec{
+			NodeName:      node,
+			RestartPolicy: corev1.RestartPolicyAlways,
+			Volumes: []corev1.Volume{{
+				Name: "hf-cache",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: hfCacheHostPath, Type: &hostPathType},
+				},
+			}},
+			Containers: []corev1.Container{{
+				Name:            "page-cache",
+				Image:           pageCacheImage,
+				ImagePullPolicy: corev1.PullIfNotPresent,
+				Command:         append([]string{"sh", "-c", pageCacheScript, "page-cache"}, dirs...),
+				Env: []corev1.EnvVar{
+					{Name: "BUDGET", Value: strconv.FormatInt(budget, 10)},
+					{Name: "REFRESH", Value: strconv.Itoa(pageCacheRefresh)},
+				},
+				// No memory limit: the page cache would be charged to it and reclaimed at the limit
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    resource.MustParse("10m"),
+						corev1.ResourceMemory: resource.MustParse("16Mi"),
+					},
+				},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "hf-cache",
+					MountPath: pageCacheMountPath,
+					ReadOnly:  true,
+				}},
+			}},
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package kubernetes
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestHFCacheDir(t *testing.T) {
+	tests := []struct {
+		model string
+		want  string
+		ok    bool
+	}{
+		{"Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8", "hub/models--Qwen--Qwen3-Coder-30B-A3B-Instruct-FP8", true},
+		{"gpt2", "hub/models--gpt2", true},
+		{"/models/llama", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := HFCacheDir(tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("HFCacheDir(%q) = %q, %v, want %q, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestK8sManager_StartPageCacheWarmer(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm"})
+	ctx := context.Background()
+
+	models := []string{"Qwen/Qwen3-8B", "/models/local", "mistralai/Devstral-Small-2505"}
+	if err := manager.StartPageCacheWarmer(ctx, "gpu-node", models, 1<<30); err != nil {
+		t.Fatalf("StartPageCacheWarmer() error = %v", err)
+	}
+
+	pod, err := clientset.CoreV1().Pods("test-ns").Get(ctx, "vllm-page-cache", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("page cache warmer not created: %v", err)
+	}
+	if pod.Spec.NodeName != "gpu-node" {
+		t.Errorf("NodeName = %q, want gpu-node", pod.Spec.NodeName)
+	}
+	if pod.Labels["app"] != "vllm-page-cache" {
+		t.Errorf("app label = %q, must not select the warmer as a vLLM pod", pod.Labels["app"])
+	}
+	container := pod.Spec.Containers[0]
+	dirs := container.Command[4:]
+	want := []string{"/hf-cache/hub/models--Qwen--Qwen3-8B", "/hf-cache/hub/models--mistralai--Devstral-Small-2505"}
+	if !slices.Equal(dirs, want) {
+		t.Errorf("model directories = %v, want %v in order of use", dirs, want)
+	}
+	if !slices.Contains(container.Env, corev1.EnvVar{Name: "BUDGET", Value: "1073741824"}) {
+		t.Errorf("Env = %v, want the byte budget", container.Env)
+	}
+	if _, ok := container.Resources.Limits[corev1.ResourceMemory]; ok {
+		t.Error("a memory limit would reclaim the page cache the warmer fills")
+	}
+	if !container.VolumeMounts[0].ReadOnly {
+		t.Error("the Hugging Face cache must be mounted read-only")
+	}
+
+	// Starting again replaces the warmer
+	if err := manager.StartPageCacheWarmer(ctx, "gpu-node", models[:1], 0); err != nil {
+		t.Fatalf("StartPageCacheWarmer() error = %v", err)
+	}
+	if err := manager.StopPageCacheWarmer(ctx); err != nil {
+		t.Fatalf("StopPageCacheWarmer() error = %v", err)
+	}
+	if _, err := clientset.CoreV1().Pods("test-ns").Get(ctx, "vllm-page-cache", metav1.GetOptions{}); err == nil {
+		t.Error("page cache warmer not deleted")
+	}
+	if err := manager.StopPageCacheWarmer(ctx); err != nil {
+		t.Errorf("StopPageCacheWarmer() without warmer error = %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package kubernetes
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ImageAnnotation holds the vLLM image a pod was created with, restored when a paused pod resumes
+const ImageAnnotation = "vllm.sir-alfred.io/image"
+
+// GPUTypeLabel is the node label gpuType selects, set by NVIDIA GPU feature discovery
+const GPUTypeLabel = "nvidia.com/gpu.product"
+
+// PodTemplate overrides parts of the vLLM pod spec from the VLLMModel podTemplate field, so
+// clusters with other paths, GPUs or scheduling constraints don't need to fork buildPodSpec
+type PodTemplate struct {
+	Image        string                      `json:"image,omitempty"`        // Replaces the vLLM image
+	Resources    corev1.ResourceRequirements `json:"resources,omitempty"`    // Replaces the default limits and requests it lists
+	Env          []corev1.EnvVar             `json:"env,omitempty"`          // Added to the vLLM env, replacing the variables of the same name
+	Volumes      []corev1.Volume             `json:"volumes,omitempty"`      // Added to the pod, replacing the volumes of the same name (hf-cache, vllm-compile-cache, shm)
+	VolumeMounts []corev1.VolumeMount        `json:"volumeMounts,omitempty"` // Added to the vLLM container, replacing the mounts of the same volume
+	NodeSelector map[string]string           `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration         `json:"tolerations,omitempty"`
+	Affinity     *corev1.Affinity            `json:"affinity,omitempty"`
+}
+
+// vllmImage returns the image of the vLLM container, the template's when set
+func (t *PodTemplate) vllmImage() string {
+	if t == nil || t.Image == "" {
+		return vllmImage
+	}
+	return t.Image
+}
+
+// VLLMImage returns the image vLLM runs the model with
+func (c *ModelConfig) VLLMImage() string {
+	return c.PodTemplate.vllmImage()
+}
+
+// apply merges the template into a vLLM pod spec built with the defaults
+func (t *PodTemplate) apply(spec *corev1.PodSpec) {
+	if t == nil {
+		return
+	}
+	container := &spec.Containers[0]
+	mergeResources(container.Resources.Limits, t.Resources.Limits)
+	mergeResources(container.Resources.Requests, t.Resources.Requests)
+	container.Env = mergeByName(container.Env, t.Env, func(e corev1.EnvVar) string { return e.Name })
+	container.VolumeMounts = mergeByName(container.VolumeMounts, t.VolumeMounts, func(m corev1.VolumeMount) string { return m.Name })
+	spec.Volumes = mergeByName(spec.Volumes, t.Volumes, func(v corev1.Volume) string { return v.Name })
+
+	spec.NodeSelector = t.NodeSelector
+	spec.Tolerations = t.Tolerations
+	spec.Affinity = t.Affinity
+}
+
+// mergeResources sets the resources listed in overrides
+func mergeResources(resources, overrides corev1.ResourceList) {
+	for name, quantity := range overrides {
+		resources[name] = quantity
+	}
+}
+
+// mergeByName replaces the items of defaults named like an override and appends the other overrides
+func mergeByName[T any](defaults, overrides []T, name func(T) string) []T {
+	merged := append([]T(nil), defaults...)
+	for _, override := range overrides {
+		replaced := false
+		for i := range merged {
+			if name(merged[i]) == name(override) {
+				merged[i] = override
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, override)
+		}
+	}
+	return merged
+}
+
+// applyScheduling places the pod on the node pool of the model, on top of the template's constraints
+func applyScheduling(spec *corev1.PodSpec, modelConfig *ModelConfig) {
+	if len(modelConfig.NodeSelector) > 0 || modelConfig.GPUType != "" {
+		nodeSelector := make(map[string]string, len(spec.NodeSelector)+len(modelConfig.NodeSelector)+1)
+		for label, value := range spec.NodeSelector {
+			nodeSelector[label] = value
+		}
+		for label, value := range modelConfig.NodeSelector {
+			nodeSelector[label] = value
+		}
+		if modelConfig.GPUType != "" {
+			nodeSelector[GPUTypeLabel] = modelConfig.GPUType
+		}
+		spec.NodeSelector = nodeSelector
+	}
+	spec.Tolerations = append(append([]corev1.Toleration(nil), spec.Tolerations...), modelConfig.Tolerations...)
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodTemplate_BuildPodSpec(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 2})
+	modelConfig := &ModelConfig{
+		ModelName:       "test/model",
+		ServedModelName: "test-model",
+		PodTemplate: &PodTemplate{
+			Image: "vllm/vllm-openai:v0.11.0",
+			Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("96Gi")},
+			},
+			Env: []corev1.EnvVar{
+				{Name: "OMP_NUM_THREADS", Value: "32"},
+				{Name: "VLLM_LOGGING_LEVEL", Value: "DEBUG"},
+			},
+			Volumes: []corev1.Volume{{
+				Name: "hf-cache",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "models"},
+				},
+			}},
+			NodeSelector: map[string]string{"gpu": "rtx3090"},
+			Tolerations:  []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}},
+		},
+	}
+
+	defaults := manager.buildPodSpec(&ModelConfig{ModelName: "test/model", ServedModelName: "test-model"})
+	spec := manager.buildPodSpec(modelConfig)
+	container := spec.Containers[0]
+
+	if container.Image != "vllm/vllm-openai:v0.11.0" {
+		t.Errorf("Container image = %v, want the template image", container.Image)
+	}
+	if memory := container.Resources.Limits[corev1.ResourceMemory]; memory.String() != "96Gi" {
+		t.Errorf("Memory limit = %v, want 96Gi", memory.String())
+	}
+	if gpus := container.Resources.Limits["nvidia.com/gpu"]; gpus.Value() != 2 {
+		t.Errorf("GPU limit = %v, want the default 2", gpus.Value())
+	}
+
+	env := map[string]string{}
+	for _, e := range container.Env {
+		if _, ok := env[e.Name]; ok {
+			t.Errorf("Env %s is set twice", e.Name)
+		}
+		env[e.Name] = e.Value
+	}
+	if env["OMP_NUM_THREADS"] != "32" || env["VLLM_LOGGING_LEVEL"] != "DEBUG" {
+		t.Errorf("Env = %v, want the template variables", env)
+	}
+	if len(container.Env) != len(defaults.Containers[0].Env)+1 {
+		t.Errorf("Env has %d variables, want the defaults plus one", len(container.Env))
+	}
+
+	if len(spec.Volumes) != len(defaults.Volumes) {
+		t.Errorf("Volumes = %d, want the template to replace hf-cache", len(spec.Volumes))
+	}
+	for _, volume := range spec.Volumes {
+		if volume.Name == "hf-cache" && (volume.PersistentVolumeClaim == nil || volume.HostPath != nil) {
+			t.Errorf("hf-cache volume = %+v, want the template PVC", volume.VolumeSource)
+		}
+	}
+
+	if spec.NodeSelector["gpu"] != "rtx3090" || len(spec.Tolerations) != 1 {
+		t.Errorf("Scheduling = %v/%v, want the template node selector and tolerations", spec.NodeSelector, spec.Tolerations)
+	}
+	if manager.SpecHash(modelConfig) == manager.SpecHash(&ModelConfig{ModelName: "test/model", ServedModelName: "test-model"}) {
+		t.Error("SpecHash should change with the pod template")
+	}
+}
+
+func TestPodTemplate_ResumeRestoresImage(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{
+		Namespace:  "test-ns",
+		Deployment: "vllm",
+		PauseImage: DefaultPauseImage,
+	})
+	ctx := context.Background()
+
+	modelConfig := &ModelConfig{
+		ModelName:       "test/model",
+		ServedModelName: "test-model",
+		PodTemplate:     &PodTemplate{Image: "vllm/vllm-openai:v0.11.0"},
+	}
+	if err := manager.CreatePod(ctx, modelConfig); err != nil {
+		t.Fatalf("CreatePod() error = %v", err)
+	}
+	if err := manager.PauseVLLMContainer(ctx); err != nil {
+		t.Fatalf("PauseVLLMContainer() error = %v", err)
+	}
+	if err := manager.ResumeVLLMContainer(ctx); err != nil {
+		t.Fatalf("ResumeVLLMContainer() error = %v", err)
+	}
+
+	pod, err := manager.GetPod(ctx)
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	if image := pod.Spec.Containers[0].Image; image != "vllm/vllm-openai:v0.11.0" {
+		t.Errorf("Resumed image = %v, want the template image", image)
+	}
+}
+
+func TestPodTemplate_Scheduling(t *testing.T) {
+	manager := NewK8sManager(nil, &Config{GPUCount: 1})
+	modelConfig := &ModelConfig{
+		ModelName:       "test/model",
+		ServedModelName: "test-model",
+		PodTemplate: &PodTemplate{
+			NodeSelector: map[string]string{"pool": "gpu", "zone": "a"},
+			Tolerations:  []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		},
+		GPUType:      "NVIDIA-A100-SXM4-80GB",
+		NodeSelector: map[string]string{"zone": "b"},
+		Tolerations:  []corev1.Toleration{{Key: "nvidia.com/gpu", Operator: corev1.TolerationOpExists}},
+	}
+
+	spec := manager.buildPodSpec(modelConfig)
+
+	want := map[string]string{"pool": "gpu", "zone": "b", GPUTypeLabel: "NVIDIA-A100-SXM4-80GB"}
+	if len(spec.NodeSelector) != len(want) {
+		t.Errorf("NodeSelector = %v, want %v", spec.NodeSelector, want)
+	}
+	for label, value := range want {
+		if spec.NodeSelector[label] != value {
+			t.Errorf("NodeSelector[%s] = %q, want %q", label, spec.NodeSelector[label], value)
+		}
+	}
+	if len(spec.Tolerations) != 2 {
+		t.Errorf("Tolerations = %v, want the template's and the model's", spec.Tolerations)
+	}
+	if len(modelConfig.PodTemplate.Tolerations) != 1 || modelConfig.PodTemplate.NodeSelector["zone"] != "a" {
+		t.Error("buildPodSpec should not modify the pod template")
+	}
+
+	if defaults := manager.buildPodSpec(&ModelConfig{ModelName: "test/model"}); defaults.NodeSelector != nil || defaults.Tolerations != nil {
+		t.Errorf("Scheduling = %v/%v, want none by default", defaults.NodeSelector, defaults.Tolerations)
+	}
+}
@@ -0,0 +1,178 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// PrefetchModelAnnotation holds the repositories a prefetch Job downloads
+	PrefetchModelAnnotation = "vllm.sir-alfred.io/prefetch"
+
+	// prefetchBackoffLimit is how many times a failed download is retried
+	prefetchBackoffLimit = 2
+
+	// prefetchJobTTL is how long, in seconds, a finished prefetch Job is kept for inspection
+	prefetchJobTTL = 600
+)
+
+// prefetchScript downloads each repository given as argument into the Hugging Face cache. Files
+// already in the cache are not downloaded again.
+const prefetchScript = `
+for repo in "$@"; do
+  echo "Downloading $repo"
+  huggingface-cli download "$repo" || exit 1
+done
+`
+
+// PrefetchStatus is the state of a prefetch Job
+type PrefetchStatus int
+
+const (
+	// PrefetchRunning is a download in progress
+	PrefetchRunning PrefetchStatus = iota
+	// PrefetchSucceeded is a completed download, the model is in the cache
+	PrefetchSucceeded
+	// PrefetchFailed is a download that failed after its retries
+	PrefetchFailed
+)
+
+// PrefetchJobName returns the name of the prefetch Job
+func (m *K8sManager) PrefetchJobName() string {
+	return m.config.Deployment + "-prefetch"
+}
+
+// prefetchRepos returns the Hugging Face repositories a model loads, the base model, its adapter
+// and its draft model, leaving out local paths
+func prefetchRepos(modelConfig *ModelConfig) []string {
+	var repos []string
+	for _, repo := range []string{modelConfig.ModelName, modelConfig.LoRAAdapter, modelConfig.SpeculativeModel} {
+		if _, ok := HFCacheDir(repo); ok {
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+// StartPrefetchJob starts a Job downloading the model into the Hugging Face cache of the vLLM pod,
+// unless one already downloads it, and reports whether there is anything to download. A Job
+// downloading another model is replaced.
+func (m *K8sManager) StartPrefetchJob(ctx context.Context, modelConfig *ModelConfig) (bool, error) {
+	repos := prefetchRepos(modelConfig)
+	if len(repos) == 0 {
+		return false, nil
+	}
+
+	jobs := m.clientset.BatchV1().Jobs(m.config.Namespace)
+	existing, err := jobs.Get(ctx, m.PrefetchJobName(), metav1.GetOptions{})
+	switch {
+	case err == nil && existing.Annotations[PrefetchModelAnnotation] == strings.Join(repos, ","):
+		return true, nil
+	case err == nil:
+		if err := m.DeletePrefetchJob(ctx); err != nil {
+			return false, err
+		}
+	case !errors.IsNotFound(err):
+		return false, fmt.Errorf("failed to get prefetch job: %w", err)
+	}
+
+	if _, err := jobs.Create(ctx, m.buildPrefetchJob(modelConfig, repos), metav1.CreateOptions{}); err != nil {
+		return false, fmt.Errorf("failed to create prefetch job: %w", err)
+	}
+	log.Printf("Created prefetch job %s/%s for %s", m.config.Namespace, m.PrefetchJobName(), strings.Join(repos, ", "))
+	return true, nil
+}
+
+// PrefetchJobStatus returns the state of the prefetch Job and when it was created
+func (m *K8sManager) PrefetchJobStatus(ctx context.Context) (PrefetchStatus, time.Time, error) {
+	job, err := m.clientset.BatchV1().Jobs(m.config.Namespace).Get(ctx, m.PrefetchJobName(), metav1.GetOptions{})
+	if err != nil {
+		return PrefetchFailed, time.Time{}, fmt.Errorf("failed to get prefetch job: %w", err)
+	}
+	created := job.CreationTimestamp.Time
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			return PrefetchSucceeded, created, nil
+		case batchv1.JobFailed:
+			return PrefetchFailed, created, nil
+		}
+	}
+	if job.Status.Succeeded > 0 {
+		return PrefetchSucceeded, created, nil
+	}
+	return PrefetchRunning, created, nil
+}
+
+// DeletePrefetchJob deletes the prefetch Job and its pods, if any
+func (m *K8sManager) DeletePrefetchJob(ctx context.Context) error {
+	propagation := metav1.DeletePropagationBackground
+	err := m.clientset.BatchV1().Jobs(m.config.Namespace).Delete(ctx, m.PrefetchJobName(), metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+	})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete prefetch job: %w", err)
+	}
+	return nil
+}
+
+// buildPrefetchJob builds the download Job. It runs the vLLM image, which ships huggingface-cli
+// and is already pulled on the GPU nodes, with the volumes, env and scheduling of the vLLM pod so
+// the weights land in the cache the pod reads, but without GPUs.
+func (m *K8sManager) buildPrefetchJob(modelConfig *ModelConfig, repos []string) *batchv1.Job {
+	spec := m.buildPodSpec(modelConfig)
+	vllm := spec.Containers[0]
+	spec.Containers = []corev1.Container{{
+		Name:            "prefetch",
+		Image:           vllm.Image,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Command:         append([]string{"sh", "-c", prefetchScript, "prefetch"}, repos...),
+		Env:             vllm.Env,
+		VolumeMounts:    vllm.VolumeMounts,
+	}}
+	spec.RestartPolicy = corev1.RestartPolicyNever
+	spec.TerminationGracePeriodSeconds = nil
+
+	backoffLimit := int32(prefetchBackoffLimit)
+	ttl := int32(prefetchJobTTL)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      m.PrefetchJobName(),
+			Namespace: m.config.Namespace,
+			Labels: map[string]string{
+				"app":        m.config.appLabel() + "-prefetch",
+				"managed-by": "vllm-chill",
+			},
+			Annotations: map[string]string{PrefetchModelAnnotation: strings.Join(repos, ",")},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app":        m.config.appLabel() + "-prefetch",
+						"managed-by": "vllm-chill",
+					},
+				},
+				Spec: spec,
+			},
+		},
+	}
+	if m.config.PrefetchTimeout > 0 {
+		deadline := int64(m.config.PrefetchTimeout.Seconds())
+		job.Spec.ActiveDeadlineSeconds = &deadline
+	}
+	return job
+}
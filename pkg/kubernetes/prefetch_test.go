@@ -0,0 +1,66 @@
+package kubernetes
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sManager_StartPrefetchJob(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm", PrefetchTimeout: time.Hour})
+	ctx := context.Background()
+
+	if started, err := manager.StartPrefetchJob(ctx, &ModelConfig{ModelName: "/models/local"}); err != nil || started {
+		t.Errorf("StartPrefetchJob() = %v, %v, want nothing to download for a local path", started, err)
+	}
+
+	modelConfig := &ModelConfig{
+		ModelName:    "Qwen/Qwen3-8B",
+		LoRAAdapter:  "org/qwen3-sql-lora",
+		GPUType:      "NVIDIA-GeForce-RTX-3090",
+		NodeSelector: map[string]string{"pool": "gpu"},
+	}
+	if started, err := manager.StartPrefetchJob(ctx, modelConfig); err != nil || !started {
+		t.Fatalf("StartPrefetchJob() = %v, %v, want a download", started, err)
+	}
+
+	job, err := clientset.BatchV1().Jobs("test-ns").Get(ctx, "vllm-prefetch", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("prefetch job not created: %v", err)
+	}
+	spec := job.Spec.Template.Spec
+	container := spec.Containers[0]
+	if repos := container.Command[4:]; !slices.Equal(repos, []string{"Qwen/Qwen3-8B", "org/qwen3-sql-lora"}) {
+		t.Errorf("repositories = %v, want the model and its adapter", repos)
+	}
+	if container.Image != vllmImage {
+		t.Errorf("Image = %q, want the vLLM image", container.Image)
+	}
+	if !slices.ContainsFunc(container.VolumeMounts, func(m corev1.VolumeMount) bool { return m.Name == "hf-cache" }) {
+		t.Errorf("VolumeMounts = %v, want the Hugging Face cache", container.VolumeMounts)
+	}
+	if spec.NodeSelector[GPUTypeLabel] != "NVIDIA-GeForce-RTX-3090" || spec.NodeSelector["pool"] != "gpu" {
+		t.Errorf("NodeSelector = %v, want the model's node pool", spec.NodeSelector)
+	}
+	if job.Spec.ActiveDeadlineSeconds == nil || *job.Spec.ActiveDeadlineSeconds != 3600 {
+		t.Errorf("ActiveDeadlineSeconds = %v, want the prefetch timeout", job.Spec.ActiveDeadlineSeconds)
+	}
+	if status, _, err := manager.PrefetchJobStatus(ctx); err != nil || status != PrefetchRunning {
+		t.Errorf("PrefetchJobStatus() = %v, %v, want running", status, err)
+	}
+
+	// A Job downloading another model is replaced
+	if _, err := manager.StartPrefetchJob(ctx, &ModelConfig{ModelName: "mistralai/Devstral-Small-2505"}); err != nil {
+		t.Fatalf("StartPrefetchJob() error = %v", err)
+	}
+	job, err = clientset.BatchV1().Jobs("test-ns").Get(ctx, "vllm-prefetch", metav1.GetOptions{})
+	if err != nil || job.Annotations[PrefetchModelAnnotation] != "mistralai/Devstral-Small-2505" {
+		t.Errorf("prefetch job = %v, %v, want the new model", job.Annotations, err)
+	}
+}
@@ -0,0 +1,157 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// replicaLabel holds the index of additional vLLM replicas (the primary pod has none)
+const replicaLabel = "vllm-chill/replica"
+
+// vllmPort is the port vLLM serves the OpenAI API on
+const vllmPort = 8000
+
+// replicaPodName returns the pod name of an additional replica
+func (m *K8sManager) replicaPodName(index int) string {
+	return fmt.Sprintf("%s-%d", m.config.Deployment, index)
+}
+
+// podSelector selects every vLLM pod managed for this deployment, primary and replicas
+func (m *K8sManager) podSelector() string {
+	return fmt.Sprintf("app=%s,managed-by=vllm-chill", m.config.appLabel())
+}
+
+// CreateReplica creates an additional vLLM pod serving the same model as the primary pod.
+// Replicas share the service selector, so they receive traffic once ready.
+func (m *K8sManager) CreateReplica(ctx context.Context, modelConfig *ModelConfig, index int) error {
+	name := m.replicaPodName(index)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: m.config.Namespace,
+			Labels: map[string]string{
+				"app":        m.config.appLabel(),
+				"managed-by": "vllm-chill",
+				replicaLabel: strconv.Itoa(index),
+			},
+			Annotations: m.podAnnotations(modelConfig),
+		},
+		Spec: m.buildPodSpec(modelConfig),
+	}
+
+	if _, err := m.clientset.CoreV1().Pods(m.config.Namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create replica pod: %w", err)
+	}
+
+	log.Printf("Created replica Pod %s/%s", m.config.Namespace, name)
+	return nil
+}
+
+// DeleteReplica deletes an additional replica with the configured grace period, without waiting
+func (m *K8sManager) DeleteReplica(ctx context.Context, index int) error {
+	name := m.replicaPodName(index)
+	if err := m.deletePodNamed(ctx, name, m.config.gracePeriodSeconds()); err != nil {
+		return err
+	}
+	log.Printf("Deleted replica Pod %s/%s", m.config.Namespace, name)
+	return nil
+}
+
+// ListReplicas returns the indexes of the existing additional replicas, in ascending order
+func (m *K8sManager) ListReplicas(ctx context.Context) ([]int, error) {
+	pods, err := m.clientset.CoreV1().Pods(m.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: m.podSelector() + "," + replicaLabel,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list replica pods: %w", err)
+	}
+
+	indexes := make([]int, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if index, err := strconv.Atoi(pod.Labels[replicaLabel]); err == nil {
+			indexes = append(indexes, index)
+		}
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// ReadyEndpoints returns the base URLs of the ready vLLM pods, primary and replicas, sorted
+func (m *K8sManager) ReadyEndpoints(ctx context.Context) ([]string, error) {
+	pods, err := m.clientset.CoreV1().Pods(m.config.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: m.podSelector(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vLLM pods: %w", err)
+	}
+
+	endpoints := make([]string, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" || !isReady(&pod) {
+			continue
+		}
+		// Only pods of this deployment, not other deployments sharing the app label
+		if pod.Name != m.config.Deployment && !strings.HasPrefix(pod.Name, m.config.Deployment+"-") {
+			continue
+		}
+		endpoints = append(endpoints, fmt.Sprintf("%s://%s:%d", m.vllmScheme(), pod.Status.PodIP, vllmPort))
+	}
+	sort.Strings(endpoints)
+	return endpoints, nil
+}
+
+// WatchReadyEndpoints calls onChange with the ready endpoints whenever a vLLM pod changes.
+// The watch is restarted when the API server closes it, until ctx is done.
+func (m *K8sManager) WatchReadyEndpoints(ctx context.Context, onChange func(endpoints []string)) error {
+	watcher, err := m.clientset.CoreV1().Pods(m.config.Namespace).Watch(ctx, metav1.ListOptions{
+		LabelSelector: m.podSelector(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch vLLM pods: %w", err)
+	}
+
+	go func() {
+		defer watcher.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-watcher.ResultChan():
+				if !ok {
+					log.Printf("Pod watch closed for %s/%s, restarting watch", m.config.Namespace, m.config.Deployment)
+					if err := m.WatchReadyEndpoints(ctx, onChange); err != nil {
+						log.Printf("Failed to restart pod watch: %v", err)
+					}
+					return
+				}
+				endpoints, err := m.ReadyEndpoints(ctx)
+				if err != nil {
+					log.Printf("Failed to list ready endpoints: %v", err)
+					continue
+				}
+				onChange(endpoints)
+			}
+		}
+	}()
+	return nil
+}
+
+// isReady reports whether the pod has the Ready condition
+func isReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
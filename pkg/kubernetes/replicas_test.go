@@ -0,0 +1,124 @@
+package kubernetes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func readyPod(name, ip string, labels map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-ns", Labels: labels},
+		Status: corev1.PodStatus{
+			PodIP:      ip,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func TestK8sManager_Replicas(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm"})
+	ctx := context.Background()
+
+	modelConfig := &ModelConfig{
+		ModelName:       "test/model",
+		ServedModelName: "test-model",
+	}
+
+	for _, index := range []int{2, 1} {
+		if err := manager.CreateReplica(ctx, modelConfig, index); err != nil {
+			t.Fatalf("CreateReplica(%d) error = %v", index, err)
+		}
+	}
+
+	pod, err := clientset.CoreV1().Pods("test-ns").Get(ctx, "vllm-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("replica pod not created: %v", err)
+	}
+	if pod.Labels["app"] != "vllm" || pod.Labels[replicaLabel] != "1" {
+		t.Errorf("unexpected replica labels: %v", pod.Labels)
+	}
+
+	indexes, err := manager.ListReplicas(ctx)
+	if err != nil {
+		t.Fatalf("ListReplicas() error = %v", err)
+	}
+	if !reflect.DeepEqual(indexes, []int{1, 2}) {
+		t.Errorf("ListReplicas() = %v, want [1 2]", indexes)
+	}
+
+	if err := manager.DeleteReplica(ctx, 2); err != nil {
+		t.Fatalf("DeleteReplica() error = %v", err)
+	}
+	indexes, err = manager.ListReplicas(ctx)
+	if err != nil {
+		t.Fatalf("ListReplicas() error = %v", err)
+	}
+	if !reflect.DeepEqual(indexes, []int{1}) {
+		t.Errorf("ListReplicas() after delete = %v, want [1]", indexes)
+	}
+}
+
+func TestK8sManager_ReadyEndpoints(t *testing.T) {
+	labels := map[string]string{"app": "vllm", "managed-by": "vllm-chill"}
+	starting := readyPod("vllm-2", "10.0.0.3", labels)
+	starting.Status.Conditions = nil
+
+	clientset := fake.NewSimpleClientset(
+		readyPod("vllm", "10.0.0.1", labels),
+		readyPod("vllm-1", "10.0.0.2", labels),
+		starting,
+		readyPod("other", "10.0.0.4", labels),
+		readyPod("unmanaged", "10.0.0.5", map[string]string{"app": "vllm"}),
+	)
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm"})
+
+	endpoints, err := manager.ReadyEndpoints(context.Background())
+	if err != nil {
+		t.Fatalf("ReadyEndpoints() error = %v", err)
+	}
+
+	want := []string{"http://10.0.0.1:8000", "http://10.0.0.2:8000"}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("ReadyEndpoints() = %v, want %v", endpoints, want)
+	}
+
+	manager.config.TLSSecret = "vllm-tls"
+	endpoints, err = manager.ReadyEndpoints(context.Background())
+	if err != nil {
+		t.Fatalf("ReadyEndpoints() error = %v", err)
+	}
+	want = []string{"https://10.0.0.1:8000", "https://10.0.0.2:8000"}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("ReadyEndpoints() with TLS = %v, want %v", endpoints, want)
+	}
+}
+
+func TestModelConfig_ReplicaBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max string
+		wantMin  int
+		wantMax  int
+	}{
+		{name: "defaults", wantMin: 0, wantMax: 1},
+		{name: "range", min: "1", max: "3", wantMin: 1, wantMax: 3},
+		{name: "min above max", min: "4", max: "2", wantMin: 2, wantMax: 2},
+		{name: "invalid max", min: "0", max: "0", wantMin: 0, wantMax: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &ModelConfig{MinReplicas: tt.min, MaxReplicas: tt.max}
+			gotMin, gotMax := m.ReplicaBounds()
+			if gotMin != tt.wantMin || gotMax != tt.wantMax {
+				t.Errorf("ReplicaBounds() = %d, %d, want %d, %d", gotMin, gotMax, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package kubernetes
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RESTConfig returns the config to reach the API server. With a kubeconfig path or context, the
+// kubeconfig is used; otherwise the in-cluster config, falling back to $KUBECONFIG and
+// ~/.kube/config when the process runs outside a cluster (e.g. on a GPU box that isn't a node).
+// The namespace is the one of the kubeconfig context, empty in-cluster or when the context sets none.
+func RESTConfig(kubeconfig, context string) (*rest.Config, string, error) {
+	if kubeconfig == "" && context == "" {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return config, "", nil
+		}
+		if !errors.Is(err, rest.ErrNotInCluster) {
+			return nil, "", fmt.Errorf("failed to get in-cluster config: %w", err)
+		}
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: context})
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	raw, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if context == "" {
+		context = raw.CurrentContext
+	}
+	var namespace string
+	if kubeContext := raw.Contexts[context]; kubeContext != nil {
+		namespace = kubeContext.Namespace
+	}
+	return config, namespace, nil
+}
@@ -0,0 +1,87 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: homelab
+clusters:
+- name: homelab
+  cluster:
+    server: https://192.168.1.10:6443
+- name: lab
+  cluster:
+    server: https://10.0.0.5:6443
+users:
+- name: admin
+  user:
+    token: secret
+contexts:
+- name: homelab
+  context:
+    cluster: homelab
+    user: admin
+    namespace: inference
+- name: lab
+  context:
+    cluster: lab
+    user: admin
+`
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0o600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestRESTConfig(t *testing.T) {
+	path := writeTestKubeconfig(t)
+
+	tests := []struct {
+		name      string
+		context   string
+		host      string
+		namespace string
+	}{
+		{name: "current context", host: "https://192.168.1.10:6443", namespace: "inference"},
+		{name: "selected context", context: "lab", host: "https://10.0.0.5:6443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, namespace, err := RESTConfig(path, tt.context)
+			if err != nil {
+				t.Fatalf("RESTConfig() error = %v", err)
+			}
+			if config.Host != tt.host {
+				t.Errorf("host = %q, want %q", config.Host, tt.host)
+			}
+			if namespace != tt.namespace {
+				t.Errorf("namespace = %q, want %q", namespace, tt.namespace)
+			}
+		})
+	}
+
+	if _, _, err := RESTConfig(path, "missing"); err == nil {
+		t.Error("RESTConfig() with an unknown context succeeded")
+	}
+}
+
+func TestRESTConfig_OutOfClusterFallback(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	t.Setenv("KUBECONFIG", writeTestKubeconfig(t))
+
+	config, namespace, err := RESTConfig("", "")
+	if err != nil {
+		t.Fatalf("RESTConfig() error = %v", err)
+	}
+	if config.Host != "https://192.168.1.10:6443" || namespace != "inference" {
+		t.Errorf("RESTConfig() = %q, %q, want the $KUBECONFIG current context", config.Host, namespace)
+	}
+}
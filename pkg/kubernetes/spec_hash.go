@@ -0,0 +1,31 @@
+package kubernetes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// SpecHashAnnotation holds the hash of the desired spec a vLLM pod was created from, so drift is
+// detected by comparing it with the hash of the current desired spec
+const SpecHashAnnotation = "vllm.sir-alfred.io/spec-hash"
+
+// SpecHash returns the hash of the pod spec built for the model: any change to the model config or
+// to the infrastructure settings the spec depends on changes it
+func (m *K8sManager) SpecHash(modelConfig *ModelConfig) string {
+	spec := m.buildPodSpec(modelConfig)
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// podAnnotations returns the annotations of a vLLM pod created for the model
+func (m *K8sManager) podAnnotations(modelConfig *ModelConfig) map[string]string {
+	return map[string]string{
+		SpecHashAnnotation: m.SpecHash(modelConfig),
+		ImageAnnotation:    modelConfig.PodTemplate.vllmImage(),
+	}
+}
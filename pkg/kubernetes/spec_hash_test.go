@@ -0,0 +1,53 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestK8sManager_SpecHash(t *testing.T) {
+	modelConfig := &ModelConfig{
+		ModelName:           "test/model",
+		ServedModelName:     "test-model",
+		MaxModelLen:         "8192",
+		EnablePrefixCaching: "true",
+		ToolCallParser:      "hermes",
+	}
+	clientset := fake.NewSimpleClientset()
+	manager := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm", GPUCount: 2})
+	ctx := context.Background()
+
+	if err := manager.CreatePod(ctx, modelConfig); err != nil {
+		t.Fatalf("CreatePod() error = %v", err)
+	}
+	pod, err := manager.GetPod(ctx)
+	if err != nil {
+		t.Fatalf("GetPod() error = %v", err)
+	}
+	hash := pod.Annotations[SpecHashAnnotation]
+	if hash == "" || hash != manager.SpecHash(modelConfig) {
+		t.Fatalf("pod annotated with spec hash %q, want %q", hash, manager.SpecHash(modelConfig))
+	}
+
+	// The pod is checked while it is still pending
+	matches, err := manager.VerifyPodConfig(ctx, modelConfig)
+	if err != nil || !matches {
+		t.Errorf("VerifyPodConfig() = %v, %v, want a match", matches, err)
+	}
+
+	// A field outside the critical args drifts as well
+	changed := *modelConfig
+	changed.EnablePrefixCaching = "false"
+	matches, err = manager.VerifyPodConfig(ctx, &changed)
+	if err != nil || matches {
+		t.Errorf("VerifyPodConfig() with prefix caching disabled = %v, %v, want a drift", matches, err)
+	}
+
+	// Infrastructure settings are part of the spec
+	other := NewK8sManager(clientset, &Config{Namespace: "test-ns", Deployment: "vllm", GPUCount: 1})
+	if other.SpecHash(modelConfig) == hash {
+		t.Error("SpecHash() ignores the GPU count")
+	}
+}
@@ -0,0 +1,147 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Dtypes are the values of vLLM's --dtype
+var Dtypes = []string{"auto", "half", "float16", "bfloat16", "float", "float32"}
+
+// float32ContextLimit is the context length above which a float32 model is unlikely to fit: its
+// weights and KV cache take twice the memory of a 16-bit dtype
+const float32ContextLimit = 16384
+
+// ModelValidation is the dry run of a VLLMModel: the vLLM command its pods would run, the problems
+// that would keep them from starting (errors) and the ones that would waste GPUs (warnings)
+type ModelValidation struct {
+	Name     string   `json:"name"`
+	Model    string   `json:"model,omitempty"`
+	Image    string   `json:"image,omitempty"`
+	Command  []string `json:"command,omitempty"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Valid reports whether the model's pods would start
+func (v *ModelValidation) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// ValidateModels dry-runs the VLLMModels with the infrastructure settings of config, or only the
+// one whose resource or served model name is model when not empty. Nothing is created.
+func (c *CRDClient) ValidateModels(ctx context.Context, config *Config, model string) ([]*ModelValidation, error) {
+	list, err := c.dynamicClient.Resource(vllmModelGVR).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list VLLMModels: %w", err)
+	}
+
+	validations := []*ModelValidation{}
+	for i := range list.Items {
+		item := &list.Items[i]
+		served, _, _ := unstructured.NestedString(item.Object, "spec", "servedModelName")
+		if model != "" && item.GetName() != model && served != model {
+			continue
+		}
+		modelConfig, err := c.convertToModelConfig(item)
+		if err != nil {
+			validations = append(validations, &ModelValidation{Name: item.GetName(), Model: served, Errors: []string{err.Error()}})
+			continue
+		}
+		validation := ValidateModel(config, modelConfig)
+		validation.Name = item.GetName()
+		validations = append(validations, validation)
+	}
+	if model != "" && len(validations) == 0 {
+		return nil, &ModelNotFoundError{ModelID: model}
+	}
+	return validations, nil
+}
+
+// ValidateModel builds the pod of a valid model configuration as the proxy would and checks it
+// against what vLLM accepts at startup
+func ValidateModel(config *Config, modelConfig *ModelConfig) *ModelValidation {
+	manager := NewK8sManager(nil, config)
+	spec := manager.buildPodSpec(modelConfig)
+	container := spec.Containers[0]
+
+	validation := &ModelValidation{
+		Name:    modelConfig.ServedModelName,
+		Model:   modelConfig.ServedModelName,
+		Image:   container.Image,
+		Command: append(slices.Clone(container.Command), container.Args...),
+	}
+	errorf := func(format string, args ...any) {
+		validation.Errors = append(validation.Errors, fmt.Sprintf(format, args...))
+	}
+	warnf := func(format string, args ...any) {
+		validation.Warnings = append(validation.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	// Tensor parallelism spreads the model over every GPU of the pod
+	tensorParallelSize := config.gpuCount()
+	gpuLimit := container.Resources.Limits[GPUResource]
+	gpuRequest, requested := container.Resources.Requests[GPUResource]
+	gpus := int(gpuLimit.Value())
+	switch {
+	case gpus < tensorParallelSize:
+		errorf("--tensor-parallel-size %d needs %d GPUs but the pod is limited to %d %s: set the GPU count to %d or raise podTemplate.resources.limits",
+			tensorParallelSize, tensorParallelSize, gpus, GPUResource, max(gpus, 1))
+	case gpus > tensorParallelSize:
+		warnf("the pod gets %d GPUs but --tensor-parallel-size %d only uses %d of them: set the GPU count to %d or lower podTemplate.resources.limits",
+			gpus, tensorParallelSize, tensorParallelSize, gpus)
+	}
+	if requested && gpuRequest.Cmp(gpuLimit) != 0 {
+		errorf("%s requests (%s) must equal its limits (%s): fix podTemplate.resources", GPUResource, gpuRequest.String(), gpuLimit.String())
+	}
+	if tensorParallelSize&(tensorParallelSize-1) != 0 {
+		warnf("--tensor-parallel-size %d is not a power of two, which the attention heads of most models can't be split by", tensorParallelSize)
+	}
+
+	maxModelLen, ok := positiveInt(modelConfig.MaxModelLen)
+	if !ok {
+		errorf("maxModelLen %q is not a positive integer", modelConfig.MaxModelLen)
+	}
+	maxNumBatchedTokens, ok := positiveInt(modelConfig.MaxNumBatchedTokens)
+	if !ok {
+		errorf("maxNumBatchedTokens %q is not a positive integer", modelConfig.MaxNumBatchedTokens)
+	}
+	maxNumSeqs, ok := positiveInt(modelConfig.MaxNumSeqs)
+	if !ok {
+		errorf("maxNumSeqs %q is not a positive integer", modelConfig.MaxNumSeqs)
+	}
+	if utilization, err := strconv.ParseFloat(modelConfig.GPUMemoryUtilization, 64); err != nil || utilization <= 0 || utilization > 1 {
+		errorf("gpuMemoryUtilization %q must be a fraction between 0 and 1 (e.g., 0.9)", modelConfig.GPUMemoryUtilization)
+	}
+
+	// Without chunked prefill, a whole prompt is prefilled in a single batch
+	if modelConfig.EnableChunkedPrefill != "true" && maxNumBatchedTokens > 0 && maxNumBatchedTokens < maxModelLen {
+		errorf("maxNumBatchedTokens %d is smaller than maxModelLen %d, which vLLM rejects without chunked prefill: raise maxNumBatchedTokens to %d or set enableChunkedPrefill",
+			maxNumBatchedTokens, maxModelLen, maxModelLen)
+	}
+	if maxNumBatchedTokens > 0 && maxNumBatchedTokens < maxNumSeqs {
+		errorf("maxNumBatchedTokens %d is smaller than maxNumSeqs %d, which vLLM rejects: raise maxNumBatchedTokens or lower maxNumSeqs",
+			maxNumBatchedTokens, maxNumSeqs)
+	}
+
+	switch {
+	case !slices.Contains(Dtypes, modelConfig.Dtype):
+		errorf("invalid dtype %q, expected one of %v", modelConfig.Dtype, Dtypes)
+	case (modelConfig.Dtype == "float" || modelConfig.Dtype == "float32") && maxModelLen > float32ContextLimit:
+		warnf("dtype %s takes twice the memory of a 16-bit dtype, so a %d-token context is unlikely to fit: use bfloat16 or half, or lower maxModelLen to %d",
+			modelConfig.Dtype, maxModelLen, float32ContextLimit)
+	}
+
+	return validation
+}
+
+// positiveInt parses a strictly positive integer
+func positiveInt(s string) (int, bool) {
+	n, err := strconv.Atoi(s)
+	return n, err == nil && n > 0
+}
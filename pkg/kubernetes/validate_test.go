@@ -0,0 +1,196 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func validModelConfig() *ModelConfig {
+	return &ModelConfig{
+		ModelName:              "Qwen/Qwen3-Coder",
+		ServedModelName:        "qwen",
+		ToolCallParser:         "hermes",
+		MaxModelLen:            "32768",
+		GPUMemoryUtilization:   "0.9",
+		EnableChunkedPrefill:   "true",
+		MaxNumBatchedTokens:    "8192",
+		MaxNumSeqs:             "16",
+		Dtype:                  "bfloat16",
+		DisableCustomAllReduce: "false",
+		EnablePrefixCaching:    "true",
+		EnableAutoToolChoice:   "true",
+	}
+}
+
+func TestValidateModel(t *testing.T) {
+	tests := []struct {
+		name        string
+		gpuCount    int
+		modify      func(*ModelConfig)
+		wantErrors  []string
+		wantWarning string
+	}{
+		{name: "valid", gpuCount: 2},
+		{
+			name:     "fewer GPUs than tensor-parallel-size",
+			gpuCount: 2,
+			modify: func(c *ModelConfig) {
+				c.PodTemplate = &PodTemplate{Resources: corev1.ResourceRequirements{
+					Limits:   corev1.ResourceList{GPUResource: resource.MustParse("1")},
+					Requests: corev1.ResourceList{GPUResource: resource.MustParse("1")},
+				}}
+			},
+			wantErrors: []string{"--tensor-parallel-size 2 needs 2 GPUs"},
+		},
+		{
+			name:     "more GPUs than tensor-parallel-size",
+			gpuCount: 2,
+			modify: func(c *ModelConfig) {
+				c.PodTemplate = &PodTemplate{Resources: corev1.ResourceRequirements{
+					Limits:   corev1.ResourceList{GPUResource: resource.MustParse("4")},
+					Requests: corev1.ResourceList{GPUResource: resource.MustParse("4")},
+				}}
+			},
+			wantWarning: "only uses 2 of them",
+		},
+		{
+			name:     "GPU requests differ from limits",
+			gpuCount: 2,
+			modify: func(c *ModelConfig) {
+				c.PodTemplate = &PodTemplate{Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{GPUResource: resource.MustParse("1")},
+				}}
+			},
+			wantErrors: []string{"requests (1) must equal its limits (2)"},
+		},
+		{name: "tensor-parallel-size not a power of two", gpuCount: 3, wantWarning: "not a power of two"},
+		{
+			name:     "invalid numbers",
+			gpuCount: 2,
+			modify: func(c *ModelConfig) {
+				c.MaxModelLen = "32k"
+				c.MaxNumSeqs = "0"
+				c.GPUMemoryUtilization = "90"
+			},
+			wantErrors: []string{`maxModelLen "32k"`, `maxNumSeqs "0"`, `gpuMemoryUtilization "90"`},
+		},
+		{
+			name:     "batched tokens below context without chunked prefill",
+			gpuCount: 2,
+			modify:   func(c *ModelConfig) { c.EnableChunkedPrefill = "false" },
+			wantErrors: []string{
+				"maxNumBatchedTokens 8192 is smaller than maxModelLen 32768",
+			},
+		},
+		{
+			name:       "batched tokens below sequences",
+			gpuCount:   2,
+			modify:     func(c *ModelConfig) { c.MaxNumBatchedTokens = "8" },
+			wantErrors: []string{"maxNumBatchedTokens 8 is smaller than maxNumSeqs 16"},
+		},
+		{
+			name:       "invalid dtype",
+			gpuCount:   2,
+			modify:     func(c *ModelConfig) { c.Dtype = "fp8" },
+			wantErrors: []string{`invalid dtype "fp8"`},
+		},
+		{
+			name:        "float32 with a long context",
+			gpuCount:    2,
+			modify:      func(c *ModelConfig) { c.Dtype = "float32" },
+			wantWarning: "a 32768-token context is unlikely to fit",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			modelConfig := validModelConfig()
+			if tt.modify != nil {
+				tt.modify(modelConfig)
+			}
+			validation := ValidateModel(&Config{GPUCount: tt.gpuCount}, modelConfig)
+
+			if len(validation.Errors) != len(tt.wantErrors) {
+				t.Fatalf("Errors = %v, want %d errors", validation.Errors, len(tt.wantErrors))
+			}
+			for i, want := range tt.wantErrors {
+				if !strings.Contains(validation.Errors[i], want) {
+					t.Errorf("Errors[%d] = %q, want it to contain %q", i, validation.Errors[i], want)
+				}
+			}
+			if validation.Valid() != (len(tt.wantErrors) == 0) {
+				t.Errorf("Valid() = %v with errors %v", validation.Valid(), validation.Errors)
+			}
+			if tt.wantWarning == "" && len(validation.Warnings) > 0 {
+				t.Errorf("Warnings = %v, want none", validation.Warnings)
+			}
+			if tt.wantWarning != "" && !slices.ContainsFunc(validation.Warnings, func(w string) bool { return strings.Contains(w, tt.wantWarning) }) {
+				t.Errorf("Warnings = %v, want one containing %q", validation.Warnings, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestValidateModel_Command(t *testing.T) {
+	validation := ValidateModel(&Config{GPUCount: 2}, validModelConfig())
+
+	command := strings.Join(validation.Command, " ")
+	for _, want := range []string{
+		"python3 -m vllm.entrypoints.openai.api_server",
+		"--model Qwen/Qwen3-Coder",
+		"--tensor-parallel-size 2",
+		"--max-model-len 32768",
+		"--dtype bfloat16",
+	} {
+		if !strings.Contains(command, want) {
+			t.Errorf("Command = %q, want it to contain %q", command, want)
+		}
+	}
+	if validation.Image != vllmImage {
+		t.Errorf("Image = %q, want %q", validation.Image, vllmImage)
+	}
+}
+
+func TestCRDClient_ValidateModels(t *testing.T) {
+	client := newFakeCRDClient(t, map[string]map[string]interface{}{
+		"qwen3-coder": {"modelName": "Qwen/Qwen3-Coder", "servedModelName": "qwen", "toolCallParser": "hermes"},
+		"broken":      {"servedModelName": "broken"},
+	})
+	ctx := context.Background()
+	config := &Config{GPUCount: 2}
+
+	validations, err := client.ValidateModels(ctx, config, "")
+	if err != nil {
+		t.Fatalf("ValidateModels() error = %v", err)
+	}
+	if len(validations) != 2 {
+		t.Fatalf("ValidateModels() returned %d models, want 2", len(validations))
+	}
+
+	validations, err = client.ValidateModels(ctx, config, "qwen")
+	if err != nil {
+		t.Fatalf("ValidateModels(qwen) error = %v", err)
+	}
+	if len(validations) != 1 || validations[0].Name != "qwen3-coder" || !validations[0].Valid() {
+		t.Errorf("ValidateModels(qwen) = %+v, want the valid qwen3-coder", validations[0])
+	}
+
+	validations, err = client.ValidateModels(ctx, config, "broken")
+	if err != nil {
+		t.Fatalf("ValidateModels(broken) error = %v", err)
+	}
+	if len(validations) != 1 || validations[0].Valid() || !strings.Contains(validations[0].Errors[0], "modelName cannot be empty") {
+		t.Errorf("ValidateModels(broken) = %+v, want the missing modelName reported", validations[0])
+	}
+
+	var notFound *ModelNotFoundError
+	if _, err := client.ValidateModels(ctx, config, "unknown"); !errors.As(err, &notFound) {
+		t.Errorf("ValidateModels(unknown) error = %v, want a ModelNotFoundError", err)
+	}
+}
@@ -0,0 +1,275 @@
+// Package notify posts scale events and failures to Slack, Discord or generic JSON webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Event types
+const (
+	ScaleUp            = "scale_up"
+	ScaleDown          = "scale_down"
+	ModelSwitch        = "model_switch"
+	StartupFailure     = "startup_failure"
+	ConfigDriftRestart = "config_drift_restart"
+)
+
+// eventTypes lists the event types in the order they are documented
+var eventTypes = []string{ScaleUp, ScaleDown, ModelSwitch, StartupFailure, ConfigDriftRestart}
+
+// Webhook formats
+const (
+	FormatGeneric = "generic" // The event as JSON, with the rendered text
+	FormatSlack   = "slack"   // Slack incoming webhook, {"text": ...}
+	FormatDiscord = "discord" // Discord webhook, {"content": ...}
+)
+
+// DefaultTemplate renders the text of the events
+const DefaultTemplate = "[vllm-chill] {{.Model}}: {{.Message}}"
+
+const (
+	defaultRetries = 3
+	defaultBackoff = time.Second
+	defaultTimeout = 10 * time.Second
+	queueSize      = 100
+
+	webhookSpecSeparator      = ","
+	webhookFormatURLSeparator = "="
+)
+
+// Event is a scale event or failure ops should know about
+type Event struct {
+	Type    string    `json:"event"`
+	Model   string    `json:"model"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// Webhook is an endpoint events are posted to
+type Webhook struct {
+	Format string
+	URL    string
+}
+
+// ParseWebhooks parses a webhook list of the form "slack=https://hooks.slack.com/...,https://ops/hook".
+// Entries without a format are generic.
+func ParseWebhooks(spec string) ([]Webhook, error) {
+	var webhooks []Webhook
+	for _, entry := range strings.Split(spec, webhookSpecSeparator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		format, rawURL := FormatGeneric, entry
+		if idx := strings.Index(entry, webhookFormatURLSeparator); idx > 0 && !strings.Contains(entry[:idx], "://") {
+			format, rawURL = entry[:idx], entry[idx+1:]
+		}
+		switch format {
+		case FormatGeneric, FormatSlack, FormatDiscord:
+		default:
+			return nil, fmt.Errorf("invalid webhook format %q (expected generic, slack or discord)", format)
+		}
+
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL for %s", format) // The URL may hold a token
+		}
+		webhooks = append(webhooks, Webhook{Format: format, URL: rawURL})
+	}
+	return webhooks, nil
+}
+
+// ParseEvents parses a comma-separated list of event types, empty for all of them
+func ParseEvents(spec string) (map[string]bool, error) {
+	events := make(map[string]bool)
+	for _, event := range strings.Split(spec, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if !slices.Contains(eventTypes, event) {
+			return nil, fmt.Errorf("invalid event %q (expected %s)", event, strings.Join(eventTypes, ", "))
+		}
+		events[event] = true
+	}
+	return events, nil
+}
+
+// ParseTemplate parses the text/template rendering the text of the events, DefaultTemplate when empty
+func ParseTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Notifier posts events to the webhooks in the background, retrying failed deliveries. A nil
+// Notifier drops the events.
+type Notifier struct {
+	webhooks []Webhook
+	events   map[string]bool // Event types posted, all when empty
+	template *template.Template
+	client   *http.Client
+	retries  int
+	backoff  time.Duration
+	mu       sync.Mutex // Guards queue and closed
+	queue    chan Event
+	closed   bool
+	done     chan struct{}
+}
+
+// New creates a notifier posting the events of the given types, all when empty, with their text
+// rendered by messageTemplate
+func New(webhooks []Webhook, events map[string]bool, messageTemplate string) (*Notifier, error) {
+	tmpl, err := ParseTemplate(messageTemplate)
+	if err != nil {
+		return nil, err
+	}
+	n := &Notifier{
+		webhooks: webhooks,
+		events:   events,
+		template: tmpl,
+		client:   &http.Client{Timeout: defaultTimeout},
+		retries:  defaultRetries,
+		backoff:  defaultBackoff,
+		queue:    make(chan Event, queueSize),
+		done:     make(chan struct{}),
+	}
+	go n.run()
+	return n, nil
+}
+
+// Notify queues the event for delivery without waiting. Events are dropped when the queue is full.
+func (n *Notifier) Notify(event Event) {
+	if n == nil || (len(n.events) > 0 && !n.events[event.Type]) {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.queue <- event:
+	default:
+		log.Printf("Notification queue full, dropped %s event for %s", event.Type, event.Model)
+	}
+}
+
+// Close delivers the queued events, giving up when ctx is done
+func (n *Notifier) Close(ctx context.Context) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.queue)
+	}
+	n.mu.Unlock()
+
+	select {
+	case <-n.done:
+	case <-ctx.Done():
+		log.Printf("Gave up delivering the queued notifications: %v", ctx.Err())
+	}
+}
+
+// run delivers the queued events in order
+func (n *Notifier) run() {
+	defer close(n.done)
+	for event := range n.queue {
+		text := n.render(event)
+		for _, webhook := range n.webhooks {
+			if err := n.post(webhook, payload(webhook.Format, event, text)); err != nil {
+				log.Printf("Failed to notify %s webhook of %s event: %v", webhook.Format, event.Type, err)
+			}
+		}
+	}
+}
+
+// render returns the text of the event, its message when the template fails
+func (n *Notifier) render(event Event) string {
+	var text bytes.Buffer
+	if err := n.template.Execute(&text, event); err != nil {
+		log.Printf("Failed to render the notification template: %v", err)
+		return event.Message
+	}
+	return text.String()
+}
+
+// payload returns the body posted to a webhook of the format
+func payload(format string, event Event, text string) interface{} {
+	switch format {
+	case FormatSlack:
+		return map[string]string{"text": text}
+	case FormatDiscord:
+		return map[string]string{"content": text}
+	default:
+		return struct {
+			Event
+			Text string `json:"text"`
+		}{event, text}
+	}
+}
+
+// post sends the payload, retrying connection errors, throttling and server errors with
+// exponential backoff
+func (n *Notifier) post(webhook Webhook, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	backoff := n.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := n.postOnce(webhook.URL, data)
+		if err == nil || !retry || attempt >= n.retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postOnce sends the payload once and reports whether a failure is worth retrying. Errors don't
+// include the URL, which may hold a token.
+func (n *Notifier) postOnce(webhookURL string, data []byte) (bool, error) {
+	resp, err := n.client.Post(webhookURL, "application/json", bytes.NewReader(data))
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return false, nil
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhooks(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", spec: "", want: nil},
+		{name: "formats", spec: "slack=https://hooks.slack.com/services/T0/B0/x, discord=https://discord.com/api/webhooks/1/x", want: []string{"slack", "discord"}},
+		{name: "generic by default", spec: "https://ops.example.com/hook?a=b", want: []string{"generic"}},
+		{name: "unknown format", spec: "teams=https://example.com/hook", wantErr: true},
+		{name: "invalid URL", spec: "slack=not-a-url", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhooks, err := ParseWebhooks(tt.spec)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var formats []string
+			for _, w := range webhooks {
+				formats = append(formats, w.Format)
+			}
+			assert.Equal(t, tt.want, formats)
+		})
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("scale_up, startup_failure")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{ScaleUp: true, StartupFailure: true}, events)
+
+	_, err = ParseEvents("scale_sideways")
+	assert.ErrorContains(t, err, `invalid event "scale_sideways"`)
+}
+
+// recordingServer records the bodies posted to it, failing the first failures requests
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   []map[string]interface{}
+	attempts int
+}
+
+func newRecordingServer(t *testing.T, failures int, status int) *recordingServer {
+	t.Helper()
+	s := &recordingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.attempts++
+		if s.attempts <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		s.bodies = append(s.bodies, body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestNotifier_Deliver(t *testing.T) {
+	slack := newRecordingServer(t, 1, http.StatusServiceUnavailable)
+	discord := newRecordingServer(t, 0, 0)
+	generic := newRecordingServer(t, 1, http.StatusBadRequest)
+
+	webhooks := []Webhook{
+		{Format: FormatSlack, URL: slack.URL},
+		{Format: FormatDiscord, URL: discord.URL},
+		{Format: FormatGeneric, URL: generic.URL},
+	}
+	n, err := New(webhooks, map[string]bool{ScaleUp: true}, "{{.Type}} {{.Model}}: {{.Message}}")
+	require.NoError(t, err)
+	n.backoff = time.Millisecond
+
+	n.Notify(Event{Type: ScaleDown, Model: "qwen", Message: "Released"}) // Filtered out
+	n.Notify(Event{Type: ScaleUp, Model: "qwen", Message: "Ready after 45s"})
+	n.Close(context.Background())
+	n.Notify(Event{Type: ScaleUp, Model: "qwen", Message: "Dropped once closed"})
+
+	// Server errors are retried
+	require.Len(t, slack.bodies, 1)
+	assert.Equal(t, "scale_up qwen: Ready after 45s", slack.bodies[0]["text"])
+	assert.Equal(t, 2, slack.attempts)
+
+	require.Len(t, discord.bodies, 1)
+	assert.Equal(t, "scale_up qwen: Ready after 45s", discord.bodies[0]["content"])
+
+	// Client errors are not
+	assert.Empty(t, generic.bodies)
+	assert.Equal(t, 1, generic.attempts)
+}
+
+func TestNotifier_GenericPayload(t *testing.T) {
+	generic := newRecordingServer(t, 0, 0)
+	n, err := New([]Webhook{{Format: FormatGeneric, URL: generic.URL}}, nil, "")
+	require.NoError(t, err)
+
+	at := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	n.Notify(Event{Type: StartupFailure, Model: "qwen", Message: "StartTimeout: vLLM wasn't ready after 2m0s", Time: at})
+	n.Close(context.Background())
+
+	require.Len(t, generic.bodies, 1)
+	assert.Equal(t, map[string]interface{}{
+		"event":   "startup_failure",
+		"model":   "qwen",
+		"message": "StartTimeout: vLLM wasn't ready after 2m0s",
+		"time":    "2026-10-16T08:00:00Z",
+		"text":    "[vllm-chill] qwen: StartTimeout: vLLM wasn't ready after 2m0s",
+	}, generic.bodies[0])
+
+	// A nil notifier drops the events
+	var none *Notifier
+	none.Notify(Event{Type: ScaleUp})
+	none.Close(context.Background())
+}
+
+func TestParseTemplate(t *testing.T) {
+	_, err := ParseTemplate("{{.Model")
+	assert.ErrorContains(t, err, "invalid notification template")
+}
@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonFrame is an object or array open at a point of the repaired JSON
+type jsonFrame struct {
+	closer    byte // } or ]
+	expectKey bool // An object's next string or bare word is a key
+}
+
+// RepairJSON fixes the syntax errors models make in tool call arguments: trailing commas, single
+// quoted strings, unquoted keys and string values, raw newlines and tabs in strings, invalid
+// escapes, Python literals (True, False, None), and output truncated in the middle of a value,
+// whose strings, arrays and objects are closed. Content after the top-level value is dropped.
+// It reports false when the result is still not valid JSON.
+func RepairJSON(input string) (string, bool) {
+	var out []byte
+	var stack []jsonFrame
+	objects := 0   // Objects in the stack
+	var quote byte // Quote of the string being read, 0 outside strings
+	pendingKey := false
+
+	top := func() *jsonFrame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return &stack[len(stack)-1]
+	}
+	// endValue records a complete string or bare word: a key when its object expects one
+	endValue := func() {
+		if frame := top(); frame != nil && frame.closer == '}' && frame.expectKey {
+			frame.expectKey = false
+			pendingKey = true
+		}
+	}
+	closeFrame := func() {
+		out = trimTrailingComma(out)
+		if pendingKey {
+			out = append(out, ":null"...)
+			pendingKey = false
+		}
+		closer := stack[len(stack)-1].closer
+		if closer == '}' {
+			objects--
+		}
+		out = append(out, closer)
+		stack = stack[:len(stack)-1]
+	}
+
+	i := 0
+	for ; i < len(input); i++ {
+		c := input[i]
+		if quote != 0 {
+			switch {
+			case c == quote:
+				out = append(out, '"')
+				quote = 0
+				endValue()
+			case c == '\\':
+				if i+1 == len(input) {
+					continue
+				}
+				i++
+				switch next := input[i]; next {
+				case '"', '\\', '/', 'b', 'f', 'n', 'r', 't', 'u':
+					out = append(out, '\\')
+					out = append(out, next)
+				case '\'':
+					out = append(out, '\'')
+				default:
+					out = append(out, `\\`...)
+					out = append(out, next)
+				}
+			case c == '"':
+				out = append(out, `\"`...)
+			case c == '\n':
+				out = append(out, `\n`...)
+			case c == '\r':
+				out = append(out, `\r`...)
+			case c == '\t':
+				out = append(out, `\t`...)
+			case c < 0x20:
+				out = fmt.Appendf(out, `\u%04x`, c)
+			default:
+				out = append(out, c)
+			}
+			continue
+		}
+
+		switch {
+		case c == '"' || c == '\'':
+			quote = c
+			out = append(out, '"')
+		case c == '{' || c == '[':
+			if frame := top(); frame != nil && frame.closer == '}' && frame.expectKey {
+				// A value where a key was expected
+				frame.expectKey = false
+			}
+			closer := byte('}')
+			if c == '[' {
+				closer = ']'
+			}
+			if c == '{' {
+				objects++
+			}
+			stack = append(stack, jsonFrame{closer: closer, expectKey: c == '{'})
+			out = append(out, c)
+		case c == '}' || c == ']':
+			if len(stack) == 0 {
+				i = len(input)
+				break
+			}
+			// A mismatched closer also closes the frames it skips
+			for len(stack) > 0 && top().closer != c && (c == ']' || objects > 0) {
+				closeFrame()
+			}
+			if len(stack) > 0 && top().closer == c {
+				closeFrame()
+			}
+			if len(stack) == 0 {
+				i = len(input)
+			}
+		case c == ':':
+			pendingKey = false
+			out = append(out, c)
+		case c == ',':
+			if frame := top(); frame != nil && frame.closer == '}' {
+				if pendingKey {
+					out = append(out, ":null"...)
+					pendingKey = false
+				}
+				frame.expectKey = true
+			}
+			out = append(out, c)
+		case isBareWordByte(c):
+			end := i
+			for end < len(input) && isBareWordByte(input[end]) {
+				end++
+			}
+			word := input[i:end]
+			i = end - 1
+			if frame := top(); frame != nil && frame.closer == '}' && frame.expectKey {
+				out = append(out, quoteJSON(word)...)
+			} else {
+				out = append(out, bareValue(word)...)
+			}
+			endValue()
+		default:
+			out = append(out, c)
+		}
+	}
+
+	// Close what the truncated output left open
+	if quote != 0 {
+		out = append(out, '"')
+		endValue()
+	}
+	if trimmed := bytes.TrimRight(out, " \t\r\n"); bytes.HasSuffix(trimmed, []byte(":")) {
+		out = append(out, "null"...)
+	}
+	for len(stack) > 0 {
+		closeFrame()
+	}
+
+	return string(out), json.Valid(out)
+}
+
+// trimTrailingComma removes a comma ending the output, before a closer
+func trimTrailingComma(out []byte) []byte {
+	trimmed := bytes.TrimRight(out, " \t\r\n")
+	if bytes.HasSuffix(trimmed, []byte(",")) {
+		return trimmed[:len(trimmed)-1]
+	}
+	return out
+}
+
+// isBareWordByte reports whether c belongs to an unquoted key, literal or number
+func isBareWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '+' || c == '.' || c == '$' || c >= 0x80
+}
+
+// bareValue returns the JSON of an unquoted value: a literal, a number, or else a string
+func bareValue(word string) string {
+	switch word {
+	case "true", "false", "null":
+		return word
+	case "True":
+		return "true"
+	case "False":
+		return "false"
+	case "None", "undefined", "NaN":
+		return "null"
+	}
+	if json.Valid([]byte(word)) {
+		return word
+	}
+	return quoteJSON(word)
+}
+
+// quoteJSON returns word as a JSON string
+func quoteJSON(word string) string {
+	quoted, _ := json.Marshal(word)
+	return string(quoted)
+}
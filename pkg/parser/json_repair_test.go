@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "valid",
+			input:    `{"path": "/tmp", "recursive": true, "depth": 2, "tags": ["a", null]}`,
+			expected: `{"path": "/tmp", "recursive": true, "depth": 2, "tags": ["a", null]}`,
+		},
+		{
+			name:     "trailing_commas",
+			input:    `{"paths": ["a", "b",], "all": false,}`,
+			expected: `{"paths":["a","b"],"all":false}`,
+		},
+		{
+			name:     "single_quotes",
+			input:    `{'path': 'it\'s "here"'}`,
+			expected: `{"path":"it's \"here\""}`,
+		},
+		{
+			name:     "unquoted_keys",
+			input:    `{path: "/tmp", max_depth: 3, nested: {dry_run: true}}`,
+			expected: `{"path":"/tmp","max_depth":3,"nested":{"dry_run":true}}`,
+		},
+		{
+			name:     "unescaped_newlines",
+			input:    "{\"content\": \"line 1\nline 2\tend\"}",
+			expected: `{"content":"line 1\nline 2\tend"}`,
+		},
+		{
+			name:     "invalid_escape",
+			input:    `{"pattern": "\d+\.go"}`,
+			expected: `{"pattern":"\\d+\\.go"}`,
+		},
+		{
+			name:     "python_literals",
+			input:    `{"force": True, "verbose": False, "limit": None}`,
+			expected: `{"force":true,"verbose":false,"limit":null}`,
+		},
+		{
+			name:     "unquoted_value",
+			input:    `{"mode": fast}`,
+			expected: `{"mode":"fast"}`,
+		},
+		{
+			name:     "truncated_string",
+			input:    `{"command": "ls -la`,
+			expected: `{"command":"ls -la"}`,
+		},
+		{
+			name:     "truncated_nested",
+			input:    `{"edits": [{"old": "a", "new": "b"}, {"old": "c"`,
+			expected: `{"edits":[{"old":"a","new":"b"},{"old":"c"}]}`,
+		},
+		{
+			name:     "truncated_after_colon",
+			input:    `{"path": "/tmp", "content":`,
+			expected: `{"path":"/tmp","content":null}`,
+		},
+		{
+			name:     "truncated_after_key",
+			input:    `{"path": "/tmp", "content"`,
+			expected: `{"path":"/tmp","content":null}`,
+		},
+		{
+			name:     "truncated_after_comma",
+			input:    `{"path": "/tmp",`,
+			expected: `{"path":"/tmp"}`,
+		},
+		{
+			name:     "text_after_value",
+			input:    `{"path": "/tmp"}} extra`,
+			expected: `{"path":"/tmp"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, ok := RepairJSON(tt.input)
+			require.True(t, ok, "repaired: %s", repaired)
+			assert.JSONEq(t, tt.expected, repaired)
+		})
+	}
+}
+
+func TestRepairJSON_Unrepairable(t *testing.T) {
+	for _, input := range []string{``, `{"a" "b"}`, `{"a": 1 2}`, `<xml/>`} {
+		repaired, ok := RepairJSON(input)
+		assert.False(t, ok, "input %q repaired as %q", input, repaired)
+	}
+}
+
+func TestParsers_RepairArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(string) []ToolCall
+		content string
+	}{
+		{
+			name:    "xml",
+			parse:   NewXMLToolParser(false).ParseXMLToolCalls,
+			content: "<tool_call><tool_name>write</tool_name><tool_arguments>{path: 'a.txt', content: \"x\ny\"}</tool_arguments></tool_call>",
+		},
+		{
+			name:    "json_string_arguments",
+			parse:   NewJSONToolParser(false).ParseJSONToolCalls,
+			content: `{"name": "write", "arguments": "{path: 'a.txt', content: \"x\ny\"}"}`,
+		},
+		{
+			name:    "mistral",
+			parse:   NewMistralToolParser(false).Parse,
+			content: "[TOOL_CALLS]write[ARGS]{path: 'a.txt', content: \"x\ny\"}",
+		},
+		{
+			name:    "llama3",
+			parse:   NewLlama3ToolParser(false).Parse,
+			content: "<function=write>{path: 'a.txt', content: \"x\ny\",}</function>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolCalls := tt.parse(tt.content)
+			require.Len(t, toolCalls, 1)
+			assert.Equal(t, "write", toolCalls[0].Function.Name)
+			assert.JSONEq(t, `{"path":"a.txt","content":"x\ny"}`, toolCalls[0].Function.Arguments)
+			assert.True(t, toolCalls[0].Repaired)
+
+			// The flag stays out of the API response
+			data, err := json.Marshal(toolCalls[0])
+			require.NoError(t, err)
+			assert.NotContains(t, string(data), "Repaired")
+		})
+	}
+
+	valid := NewJSONToolParser(false).ParseJSONToolCalls(`{"name": "ls", "arguments": {"path": "."}}`)
+	require.Len(t, valid, 1)
+	assert.False(t, valid[0].Repaired)
+}
+
+func TestXMLStreamParser_Repairs(t *testing.T) {
+	p := NewXMLStreamParser()
+	events := p.Feed("<tool_call><tool_name>ls</tool_name><tool_arguments>{path: '.'}</tool_arguments></tool_call>")
+	events = append(events, p.Finish()...)
+
+	require.NotEmpty(t, events)
+	assert.Equal(t, 1, p.ToolCalls())
+	assert.Equal(t, 1, p.Repairs())
+}
+
+func FuzzRepairJSON(f *testing.F) {
+	for _, seed := range []string{`{"a": [1, 'b',], c: True}`, `{"a": "b`, `[{"a": {`, `}]`, "{\"a\": \"\n\\\"} x"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		repaired, ok := RepairJSON(input)
+		if ok && !json.Valid([]byte(repaired)) {
+			t.Fatalf("invalid JSON %q reported as repaired from %q", repaired, input)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// jsonToolCallStartRegex matches the start of a JSON tool call: a fenced json block or an object
+// whose first key is a tool call key
+var jsonToolCallStartRegex = regexp.MustCompile("```json|\\{\\s*\"(name|function|type)\"\\s*:")
+
+// JSONToolParser handles parsing of tool calls emitted as bare JSON in the content,
+// e.g. {"name":"ls","arguments":{"path":"/tmp"}}, optionally inside a fenced ```json block
+type JSONToolParser struct {
+	debug bool
+}
+
+// jsonToolCall is the JSON shape models emit for a tool call
+type jsonToolCall struct {
+	Name       string          `json:"name"`
+	Arguments  json.RawMessage `json:"arguments"`
+	Parameters json.RawMessage `json:"parameters"`
+	Function   *jsonToolCall   `json:"function"` // OpenAI shape: {"type":"function","function":{...}}
+}
+
+// NewJSONToolParser creates a new JSON tool parser
+func NewJSONToolParser(debug bool) *JSONToolParser {
+	return &JSONToolParser{debug: debug}
+}
+
+// Name returns the parser's fallbackToolParser name
+func (p *JSONToolParser) Name() string {
+	return "json"
+}
+
+// Detect reports whether content likely contains a JSON tool call
+func (p *JSONToolParser) Detect(content string) bool {
+	return ContainsJSONToolCallPattern(content)
+}
+
+// Parse extracts the JSON tool calls of content
+func (p *JSONToolParser) Parse(content string) []ToolCall {
+	return p.ParseJSONToolCalls(content)
+}
+
+// ContainsJSONToolCallPattern reports whether content likely contains a JSON tool call
+func ContainsJSONToolCallPattern(content string) bool {
+	return jsonToolCallStartRegex.MatchString(content)
+}
+
+// ParseJSONToolCalls extracts every JSON object (or array of objects) in content that
+// describes a tool call. Surrounding text and markdown fences are ignored.
+func (p *JSONToolParser) ParseJSONToolCalls(content string) []ToolCall {
+	if p.debug {
+		log.Printf("[JSON-PARSER] Parsing JSON tool calls from content (length: %d)", len(content))
+	}
+
+	toolCalls := []ToolCall{}
+	for i := 0; i < len(content); i++ {
+		if content[i] != '{' && content[i] != '[' {
+			continue
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(content[i:]))
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			continue
+		}
+
+		calls := p.toToolCalls(value, len(toolCalls))
+		if len(calls) == 0 {
+			continue
+		}
+		toolCalls = append(toolCalls, calls...)
+		// Skip the decoded value, nested objects are part of the arguments
+		i += int(decoder.InputOffset()) - 1
+	}
+
+	if p.debug {
+		log.Printf("[JSON-PARSER] Total tool calls parsed: %d", len(toolCalls))
+	}
+	return toolCalls
+}
+
+// toToolCalls converts a decoded object or array into tool calls, or nil when it is not a tool call
+func (p *JSONToolParser) toToolCalls(value json.RawMessage, startIndex int) []ToolCall {
+	var raw []json.RawMessage
+	if value[0] == '[' {
+		if err := json.Unmarshal(value, &raw); err != nil {
+			return nil
+		}
+	} else {
+		raw = []json.RawMessage{value}
+	}
+
+	toolCalls := make([]ToolCall, 0, len(raw))
+	for _, item := range raw {
+		var call jsonToolCall
+		if err := json.Unmarshal(item, &call); err != nil {
+			return nil
+		}
+		if call.Function != nil {
+			call = *call.Function
+		}
+
+		arguments, repaired, ok := p.normalizeArguments(call)
+		if call.Name == "" || !ok {
+			return nil
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:   toolCallID(startIndex + len(toolCalls)),
+			Type: "function",
+			Function: ToolCallFunction{
+				Name:      call.Name,
+				Arguments: arguments,
+			},
+			Repaired: repaired,
+		})
+	}
+	return toolCalls
+}
+
+// normalizeArguments returns the arguments as a JSON object string, and whether they were
+// malformed JSON repaired by RepairJSON.
+// Arguments may be an object or a JSON-encoded string, "parameters" is accepted as an alias.
+func (p *JSONToolParser) normalizeArguments(call jsonToolCall) (string, bool, bool) {
+	args := call.Arguments
+	if len(args) == 0 {
+		args = call.Parameters
+	}
+	if len(args) == 0 {
+		return "", false, false
+	}
+
+	var encoded string
+	if err := json.Unmarshal(args, &encoded); err == nil {
+		args = json.RawMessage(encoded)
+	}
+
+	repaired := false
+	var object map[string]interface{}
+	if err := json.Unmarshal(args, &object); err != nil {
+		fixed, ok := RepairJSON(string(args))
+		if !ok || json.Unmarshal([]byte(fixed), &object) != nil {
+			return "", false, false
+		}
+		if p.debug {
+			log.Printf("[JSON-PARSER] Repaired malformed JSON arguments")
+		}
+		repaired = true
+	}
+	normalized, err := json.Marshal(object)
+	if err != nil {
+		return "", false, false
+	}
+	return string(normalized), repaired, true
+}
+
+// ParseJSONToolCalls parses JSON tool calls from content.
+//
+// This is a convenience function mirroring ParseXMLToolCalls.
+func ParseJSONToolCalls(content string) []ToolCall {
+	parser := NewJSONToolParser(true)
+	return parser.ParseJSONToolCalls(content)
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONToolParser_Formats(t *testing.T) {
+	parser := NewJSONToolParser(false)
+
+	tests := []struct {
+		name         string
+		content      string
+		expectedName string
+		expectedArgs map[string]interface{}
+	}{
+		{
+			name:         "bare_object",
+			content:      `{"name":"ls","arguments":{"path":"/tmp"}}`,
+			expectedName: "ls",
+			expectedArgs: map[string]interface{}{"path": "/tmp"},
+		},
+		{
+			name:         "surrounded_by_text",
+			content:      "I'll list the directory.\n{\"name\": \"ls\", \"arguments\": {\"path\": \"/tmp\"}}\nDone.",
+			expectedName: "ls",
+			expectedArgs: map[string]interface{}{"path": "/tmp"},
+		},
+		{
+			name:         "fenced_block",
+			content:      "```json\n{\"name\": \"read_file\", \"arguments\": {\"path\": \"main.go\", \"lines\": 20}}\n```",
+			expectedName: "read_file",
+			expectedArgs: map[string]interface{}{"path": "main.go", "lines": float64(20)},
+		},
+		{
+			name:         "parameters_alias",
+			content:      `{"name":"search","parameters":{"query":"vllm"}}`,
+			expectedName: "search",
+			expectedArgs: map[string]interface{}{"query": "vllm"},
+		},
+		{
+			name:         "string_arguments",
+			content:      `{"name":"search","arguments":"{\"query\":\"vllm\"}"}`,
+			expectedName: "search",
+			expectedArgs: map[string]interface{}{"query": "vllm"},
+		},
+		{
+			name:         "openai_shape",
+			content:      `{"type":"function","function":{"name":"ls","arguments":{"path":"/"}}}`,
+			expectedName: "ls",
+			expectedArgs: map[string]interface{}{"path": "/"},
+		},
+		{
+			name:         "nested_braces_in_arguments",
+			content:      `{"name":"write","arguments":{"content":"func main() { fmt.Println(\"{}\") }"}}`,
+			expectedName: "write",
+			expectedArgs: map[string]interface{}{"content": "func main() { fmt.Println(\"{}\") }"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toolCalls := parser.ParseJSONToolCalls(tt.content)
+			require.Len(t, toolCalls, 1)
+			assert.Equal(t, tt.expectedName, toolCalls[0].Function.Name)
+			assert.Equal(t, "function", toolCalls[0].Type)
+			assert.Equal(t, "call_a", toolCalls[0].ID)
+
+			var args map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(toolCalls[0].Function.Arguments), &args))
+			assert.Equal(t, tt.expectedArgs, args)
+		})
+	}
+}
+
+func TestJSONToolParser_MultipleCalls(t *testing.T) {
+	parser := NewJSONToolParser(false)
+
+	t.Run("array", func(t *testing.T) {
+		content := `[{"name":"ls","arguments":{"path":"/a"}},{"name":"ls","arguments":{"path":"/b"}}]`
+		toolCalls := parser.ParseJSONToolCalls(content)
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "call_a", toolCalls[0].ID)
+		assert.Equal(t, "call_b", toolCalls[1].ID)
+		assert.JSONEq(t, `{"path":"/b"}`, toolCalls[1].Function.Arguments)
+	})
+
+	t.Run("separate_blocks", func(t *testing.T) {
+		content := "```json\n{\"name\":\"ls\",\"arguments\":{}}\n```\nthen\n```json\n{\"name\":\"pwd\",\"arguments\":{}}\n```"
+		toolCalls := parser.ParseJSONToolCalls(content)
+		require.Len(t, toolCalls, 2)
+		assert.Equal(t, "ls", toolCalls[0].Function.Name)
+		assert.Equal(t, "pwd", toolCalls[1].Function.Name)
+	})
+}
+
+func TestJSONToolParser_IgnoresNonToolJSON(t *testing.T) {
+	parser := NewJSONToolParser(false)
+
+	contents := []string{
+		`Here is a user: {"name":"John","age":42}`,
+		`{"arguments":{"path":"/tmp"}}`,
+		`{"name":"ls","arguments":"not json"}`,
+		`{"name":"ls","arguments":{"path":`,
+		"Plain text without any JSON",
+	}
+	for _, content := range contents {
+		assert.Empty(t, parser.ParseJSONToolCalls(content), content)
+	}
+}
+
+func TestContainsJSONToolCallPattern(t *testing.T) {
+	assert.True(t, ContainsJSONToolCallPattern(`{"name": "ls"`))
+	assert.True(t, ContainsJSONToolCallPattern("```json\n"))
+	assert.True(t, ContainsJSONToolCallPattern(`{ "type":"function"`))
+	assert.False(t, ContainsJSONToolCallPattern(`{"answer": 42}`))
+	assert.False(t, ContainsJSONToolCallPattern("no json here"))
+}
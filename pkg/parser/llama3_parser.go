@@ -0,0 +1,66 @@
+package parser
+
+import (
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// llamaPythonTag precedes the JSON tool calls of Llama 3 models with built-in tool calling
+const llamaPythonTag = "<|python_tag|>"
+
+// llamaFunctionRegex matches the <function=name>{...}</function> calls of Llama 3 custom tools
+var llamaFunctionRegex = regexp.MustCompile(`<function=([^>]+)>([\s\S]*?)</function>`)
+
+// Llama3ToolParser handles the tool calls of Llama 3 and 4 models: JSON objects with a "parameters"
+// field, optionally after <|python_tag|> and separated by semicolons, and <function=name>{...}</function>
+type Llama3ToolParser struct {
+	debug bool
+	json  *JSONToolParser
+}
+
+// NewLlama3ToolParser creates a new Llama 3 tool parser
+func NewLlama3ToolParser(debug bool) *Llama3ToolParser {
+	return &Llama3ToolParser{debug: debug, json: NewJSONToolParser(false)}
+}
+
+// Name returns the parser's fallbackToolParser name
+func (p *Llama3ToolParser) Name() string {
+	return "llama3"
+}
+
+// Detect reports whether content holds a python tag, a function tag or a JSON tool call
+func (p *Llama3ToolParser) Detect(content string) bool {
+	return strings.Contains(content, llamaPythonTag) || strings.Contains(content, "<function=") ||
+		ContainsJSONToolCallPattern(content)
+}
+
+// Parse extracts the function tag calls, or the JSON calls when there are none
+func (p *Llama3ToolParser) Parse(content string) []ToolCall {
+	if p.debug {
+		log.Printf("[LLAMA3-PARSER] Parsing Llama 3 tool calls from content (length: %d)", len(content))
+	}
+
+	toolCalls := []ToolCall{}
+	for _, match := range llamaFunctionRegex.FindAllStringSubmatch(content, -1) {
+		arguments, repaired, ok := p.json.normalizeArguments(jsonToolCall{Arguments: json.RawMessage(strings.TrimSpace(match[2]))})
+		if !ok {
+			continue
+		}
+		toolCalls = append(toolCalls, ToolCall{
+			ID:       toolCallID(len(toolCalls)),
+			Type:     "function",
+			Function: ToolCallFunction{Name: strings.TrimSpace(match[1]), Arguments: arguments},
+			Repaired: repaired,
+		})
+	}
+	if len(toolCalls) == 0 {
+		toolCalls = p.json.ParseJSONToolCalls(strings.ReplaceAll(content, llamaPythonTag, ""))
+	}
+
+	if p.debug {
+		log.Printf("[LLAMA3-PARSER] Total tool calls parsed: %d", len(toolCalls))
+	}
+	return toolCalls
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+)
+
+// Mistral tool call markers: [TOOL_CALLS] followed by a JSON array of calls, or by name[ARGS]{...}
+// calls with the v11+ tokenizers, optionally with a [CALL_ID]
+const (
+	mistralToolCalls = "[TOOL_CALLS]"
+	mistralArgs      = "[ARGS]"
+	mistralCallID    = "[CALL_ID]"
+)
+
+// MistralToolParser handles the [TOOL_CALLS] markup of Mistral models, for the calls vLLM's mistral
+// parser leaves in the content
+type MistralToolParser struct {
+	debug bool
+	json  *JSONToolParser
+}
+
+// NewMistralToolParser creates a new Mistral tool parser
+func NewMistralToolParser(debug bool) *MistralToolParser {
+	return &MistralToolParser{debug: debug, json: NewJSONToolParser(false)}
+}
+
+// Name returns the parser's fallbackToolParser name
+func (p *MistralToolParser) Name() string {
+	return "mistral"
+}
+
+// Detect reports whether content holds a [TOOL_CALLS] marker
+func (p *MistralToolParser) Detect(content string) bool {
+	return strings.Contains(content, mistralToolCalls)
+}
+
+// Parse extracts the calls following each [TOOL_CALLS] marker
+func (p *MistralToolParser) Parse(content string) []ToolCall {
+	if p.debug {
+		log.Printf("[MISTRAL-PARSER] Parsing Mistral tool calls from content (length: %d)", len(content))
+	}
+
+	toolCalls := []ToolCall{}
+	segments := strings.Split(content, mistralToolCalls)
+	for _, segment := range segments[1:] {
+		segment = strings.TrimSpace(segment)
+		if strings.HasPrefix(segment, "[") || strings.HasPrefix(segment, "{") {
+			for _, call := range p.json.ParseJSONToolCalls(segment) {
+				call.ID = toolCallID(len(toolCalls))
+				toolCalls = append(toolCalls, call)
+			}
+			continue
+		}
+		if call, ok := p.parseNamedCall(segment, len(toolCalls)); ok {
+			toolCalls = append(toolCalls, call)
+		}
+	}
+
+	if p.debug {
+		log.Printf("[MISTRAL-PARSER] Total tool calls parsed: %d", len(toolCalls))
+	}
+	return toolCalls
+}
+
+// parseNamedCall parses a name[ARGS]{...} call, or name[CALL_ID]id[ARGS]{...}
+func (p *MistralToolParser) parseNamedCall(segment string, index int) (ToolCall, bool) {
+	name, args, found := strings.Cut(segment, mistralArgs)
+	if !found {
+		return ToolCall{}, false
+	}
+	name, _, _ = strings.Cut(name, mistralCallID)
+	name = strings.TrimSpace(name)
+
+	if name == "" {
+		return ToolCall{}, false
+	}
+	var arguments json.RawMessage
+	if err := json.NewDecoder(strings.NewReader(args)).Decode(&arguments); err != nil {
+		// Malformed arguments are left to the repair of normalizeArguments
+		arguments = json.RawMessage(strings.TrimSpace(args))
+	}
+	normalized, repaired, ok := p.json.normalizeArguments(jsonToolCall{Arguments: arguments})
+	if !ok {
+		return ToolCall{}, false
+	}
+	return ToolCall{
+		ID:       toolCallID(index),
+		Type:     "function",
+		Function: ToolCallFunction{Name: name, Arguments: normalized},
+		Repaired: repaired,
+	}, true
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"sort"
+	"sync"
+)
+
+// XMLParserName is the parser used when a model selects none
+const XMLParserName = "xml"
+
+// ToolCallParser extracts the tool calls a model writes as text in its response content, for the
+// calls vLLM's own parser misses
+type ToolCallParser interface {
+	// Name is the parser's name in the VLLMModel fallbackToolParser field
+	Name() string
+
+	// Detect reports whether content, possibly incomplete, holds the start of a tool call
+	Detect(content string) bool
+
+	// Parse returns the tool calls of the complete content, none when it holds no valid call
+	Parse(content string) []ToolCall
+}
+
+var (
+	registryMu sync.RWMutex
+	parsers    = map[string]ToolCallParser{}
+	vllmFormat = map[string]string{} // vLLM --tool-call-parser value to the parser reading its format
+)
+
+func init() {
+	Register(NewXMLToolParser(true))
+	Register(NewJSONToolParser(true))
+	Register(NewMistralToolParser(true), "mistral")
+	Register(NewLlama3ToolParser(true), "llama3_json", "llama4_json")
+}
+
+// Register makes a parser selectable by its name, and by default for the models whose vLLM
+// toolCallParser is one of vllmParsers. A parser registered under an existing name replaces it.
+func Register(p ToolCallParser, vllmParsers ...string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	parsers[p.Name()] = p
+	for _, vllmParser := range vllmParsers {
+		vllmFormat[vllmParser] = p.Name()
+	}
+}
+
+// Get returns the parser registered under name
+func Get(name string) (ToolCallParser, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	p, ok := parsers[name]
+	return p, ok
+}
+
+// Names returns the names of the registered parsers, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(parsers))
+	for name := range parsers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForModel selects the parser of a model: its VLLMModel fallbackToolParser when set, else the parser
+// reading the format of its vLLM toolCallParser, else the XML parser. Models configured with another
+// vLLM parser (e.g., hermes) keep the XML parser, which converts the XML calls a mismatched parser misses.
+func ForModel(fallbackToolParser, toolCallParser string) ToolCallParser {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	if p, ok := parsers[fallbackToolParser]; ok {
+		return p
+	}
+	if p, ok := parsers[vllmFormat[toolCallParser]]; ok {
+		return p
+	}
+	return parsers[XMLParserName]
+}
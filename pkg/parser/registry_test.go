@@ -0,0 +1,146 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		name               string
+		fallbackToolParser string
+		toolCallParser     string
+		expected           string
+	}{
+		{name: "default", expected: "xml"},
+		{name: "explicit fallback", fallbackToolParser: "json", toolCallParser: "mistral", expected: "json"},
+		{name: "mistral format", toolCallParser: "mistral", expected: "mistral"},
+		{name: "llama3 format", toolCallParser: "llama3_json", expected: "llama3"},
+		{name: "hermes keeps the xml fallback", toolCallParser: "hermes", expected: "xml"},
+		{name: "unknown fallback", fallbackToolParser: "yaml", toolCallParser: "qwen3_coder", expected: "xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ForModel(tt.fallbackToolParser, tt.toolCallParser).Name())
+		})
+	}
+}
+
+// upperParser is a custom parser reading CALL name calls
+type upperParser struct{}
+
+func (upperParser) Name() string               { return "upper" }
+func (upperParser) Detect(content string) bool { return len(content) > 5 && content[:5] == "CALL " }
+func (upperParser) Parse(content string) []ToolCall {
+	return []ToolCall{{ID: toolCallID(0), Type: "function", Function: ToolCallFunction{Name: content[5:], Arguments: "{}"}}}
+}
+
+func TestRegister(t *testing.T) {
+	Register(upperParser{}, "upper_vllm")
+	defer func() {
+		registryMu.Lock()
+		delete(parsers, "upper")
+		delete(vllmFormat, "upper_vllm")
+		registryMu.Unlock()
+	}()
+
+	assert.Equal(t, []string{"json", "llama3", "mistral", "upper", "xml"}, Names())
+	p, ok := Get("upper")
+	require.True(t, ok)
+	assert.True(t, p.Detect("CALL ls"))
+	assert.Equal(t, "upper", ForModel("", "upper_vllm").Name())
+
+	_, ok = Get("yaml")
+	assert.False(t, ok)
+}
+
+func TestMistralToolParser(t *testing.T) {
+	p := NewMistralToolParser(false)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []ToolCallFunction
+	}{
+		{
+			name:     "json array",
+			content:  `[TOOL_CALLS] [{"name": "get_weather", "arguments": {"city": "Paris"}}, {"name": "get_time", "arguments": {}}]`,
+			expected: []ToolCallFunction{{Name: "get_weather", Arguments: `{"city":"Paris"}`}, {Name: "get_time", Arguments: `{}`}},
+		},
+		{
+			name:     "named calls",
+			content:  `[TOOL_CALLS]get_weather[ARGS]{"city": "Paris"}[TOOL_CALLS]get_time[CALL_ID]a1b2c3d4e[ARGS]{}`,
+			expected: []ToolCallFunction{{Name: "get_weather", Arguments: `{"city":"Paris"}`}, {Name: "get_time", Arguments: `{}`}},
+		},
+		{
+			name:     "no marker",
+			content:  `{"name": "get_weather", "arguments": {"city": "Paris"}}`,
+			expected: []ToolCallFunction{},
+		},
+		{
+			name:     "truncated arguments are closed",
+			content:  `[TOOL_CALLS]get_weather[ARGS]{"city": "Par`,
+			expected: []ToolCallFunction{{Name: "get_weather", Arguments: `{"city":"Par"}`}},
+		},
+		{
+			name:     "named call without arguments",
+			content:  `[TOOL_CALLS]get_weather[ARGS]`,
+			expected: []ToolCallFunction{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := p.Parse(tt.content)
+			functions := []ToolCallFunction{}
+			for i, call := range calls {
+				assert.Equal(t, toolCallID(i), call.ID)
+				functions = append(functions, call.Function)
+			}
+			assert.Equal(t, tt.expected, functions)
+		})
+	}
+	assert.True(t, p.Detect("Sure. [TOOL_CALLS"+"]"))
+	assert.False(t, p.Detect("<tool_call>"))
+}
+
+func TestLlama3ToolParser(t *testing.T) {
+	p := NewLlama3ToolParser(false)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected []ToolCallFunction
+	}{
+		{
+			name:     "python tag",
+			content:  `<|python_tag|>{"name": "get_weather", "parameters": {"city": "Paris"}}; {"name": "get_time", "parameters": {}}`,
+			expected: []ToolCallFunction{{Name: "get_weather", Arguments: `{"city":"Paris"}`}, {Name: "get_time", Arguments: `{}`}},
+		},
+		{
+			name:     "function tags",
+			content:  `<function=get_weather>{"city": "Paris"}</function><function=get_time>{}</function>`,
+			expected: []ToolCallFunction{{Name: "get_weather", Arguments: `{"city":"Paris"}`}, {Name: "get_time", Arguments: `{}`}},
+		},
+		{
+			name:     "plain text",
+			content:  "The weather in Paris is sunny.",
+			expected: []ToolCallFunction{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			functions := []ToolCallFunction{}
+			for _, call := range p.Parse(tt.content) {
+				functions = append(functions, call.Function)
+			}
+			assert.Equal(t, tt.expected, functions)
+		})
+	}
+	assert.True(t, p.Detect("<|python_tag|>"))
+	assert.False(t, p.Detect("The weather in Paris is sunny."))
+}
@@ -0,0 +1,244 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ToolSchemas maps the tools a request defines to the JSON schema of their arguments: the
+// parameters of OpenAI function tools, or the input_schema of Anthropic tools
+type ToolSchemas map[string]json.RawMessage
+
+// ArgumentsError reports a tool call whose arguments don't match the schema of its tool
+type ArgumentsError struct {
+	Tool     string
+	Problems []string // One per mismatching value, prefixed with its path in the arguments
+}
+
+func (e *ArgumentsError) Error() string {
+	return fmt.Sprintf("arguments of tool %q don't match its schema: %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// ToolCallErrorText is the content replacing a rejected tool call, for the client and the model
+// to see what was wrong instead of a call their tool can't run
+func ToolCallErrorText(err error) string {
+	return "\n[tool call error] " + err.Error() + "\n"
+}
+
+// jsonSchema is the subset of JSON Schema tool arguments are checked against
+type jsonSchema struct {
+	Type       json.RawMessage        `json:"type"` // A type or an array of types
+	Properties map[string]*jsonSchema `json:"properties"`
+	Required   []string               `json:"required"`
+	Items      *jsonSchema            `json:"items"`
+	Enum       []interface{}          `json:"enum"`
+}
+
+// schemaCheck collects the outcome of checking arguments against a schema
+type schemaCheck struct {
+	problems []string
+	coerced  bool
+}
+
+func (c *schemaCheck) fail(path, format string, args ...interface{}) {
+	if path == "" {
+		path = "arguments"
+	}
+	c.problems = append(c.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// Check validates the JSON arguments of a call to the tool against its schema. Values of the
+// wrong simple type are coerced when they convert as is, e.g. "5" for an integer or a JSON
+// encoded array for an array, and the arguments are then returned re-encoded with coerced true,
+// along with an *ArgumentsError when other values still don't match. The type, properties,
+// required, items and enum keywords are checked, others are ignored, as are schemas that don't
+// parse. A call to a tool the request doesn't define is an error.
+func (s ToolSchemas) Check(tool, arguments string) (string, bool, error) {
+	raw, ok := s[tool]
+	if !ok {
+		return arguments, false, &ArgumentsError{Tool: tool, Problems: []string{"the request defines no such tool"}}
+	}
+	var schema jsonSchema
+	if len(raw) == 0 || json.Unmarshal(raw, &schema) != nil {
+		return arguments, false, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(arguments))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return arguments, false, &ArgumentsError{Tool: tool, Problems: []string{"arguments: not valid JSON"}}
+	}
+
+	var check schemaCheck
+	value = schema.check(value, "", &check)
+	var mismatch error
+	if len(check.problems) > 0 {
+		mismatch = &ArgumentsError{Tool: tool, Problems: check.problems}
+	}
+	if !check.coerced {
+		return arguments, false, mismatch
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return arguments, false, err
+	}
+	return string(encoded), true, mismatch
+}
+
+// check returns the value at path, coerced to the schema's type where needed
+func (s *jsonSchema) check(value interface{}, path string, c *schemaCheck) interface{} {
+	types := s.types()
+	if len(types) > 0 && !slices.ContainsFunc(types, func(t string) bool { return hasJSONType(value, t) }) {
+		coerced, ok := coerceJSONType(value, types)
+		if !ok {
+			c.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeOf(value))
+			return value
+		}
+		value, c.coerced = coerced, true
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(allowed interface{}) bool { return jsonEqual(value, allowed) }) {
+		allowed, _ := json.Marshal(s.Enum)
+		c.fail(path, "expected one of %s", allowed)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				c.fail(propertyPath(path, name), "required property missing")
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if property, ok := v[name]; ok && s.Properties[name] != nil {
+				v[name] = s.Properties[name].check(property, propertyPath(path, name), c)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i := range v {
+				v[i] = s.Items.check(v[i], fmt.Sprintf("%s[%d]", path, i), c)
+			}
+		}
+	}
+	return value
+}
+
+// types returns the types the schema allows, none when it doesn't restrict them
+func (s *jsonSchema) types() []string {
+	var single string
+	if json.Unmarshal(s.Type, &single) == nil {
+		return []string{single}
+	}
+	var types []string
+	_ = json.Unmarshal(s.Type, &types)
+	return types
+}
+
+func propertyPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// hasJSONType reports whether a decoded value is of the JSON Schema type
+func hasJSONType(value interface{}, schemaType string) bool {
+	switch v := value.(type) {
+	case json.Number:
+		if schemaType == "integer" {
+			f, err := v.Float64()
+			return err == nil && f == math.Trunc(f)
+		}
+		return schemaType == "number"
+	case nil:
+		return schemaType == "null"
+	}
+	return jsonTypeOf(value) == schemaType
+}
+
+// jsonTypeOf returns the JSON Schema type of a decoded value
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return "null"
+}
+
+// coerceJSONType converts a value to the first of the types it converts to as is
+func coerceJSONType(value interface{}, types []string) (interface{}, bool) {
+	for _, schemaType := range types {
+		switch v := value.(type) {
+		case string:
+			s := strings.TrimSpace(v)
+			switch schemaType {
+			case "integer":
+				if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && json.Valid([]byte(s)) {
+					return json.Number(strconv.FormatFloat(f, 'f', -1, 64)), true
+				}
+			case "number":
+				if _, err := strconv.ParseFloat(s, 64); err == nil && json.Valid([]byte(s)) {
+					return json.Number(s), true
+				}
+			case "boolean":
+				switch strings.ToLower(s) {
+				case "true":
+					return true, true
+				case "false":
+					return false, true
+				}
+			case "null":
+				if s == "null" {
+					return nil, true
+				}
+			case "array", "object":
+				decoder := json.NewDecoder(strings.NewReader(s))
+				decoder.UseNumber()
+				var decoded interface{}
+				if decoder.Decode(&decoded) == nil && !decoder.More() && jsonTypeOf(decoded) == schemaType {
+					return decoded, true
+				}
+			}
+		case json.Number:
+			if schemaType == "string" {
+				return v.String(), true
+			}
+		case bool:
+			if schemaType == "string" {
+				return strconv.FormatBool(v), true
+			}
+		}
+	}
+	return value, false
+}
+
+// jsonEqual reports whether two decoded values are the same JSON value, numbers compared by value
+func jsonEqual(a, b interface{}) bool {
+	if number, ok := a.(json.Number); ok {
+		f, err := number.Float64()
+		other, isFloat := b.(float64)
+		return err == nil && isFloat && f == other
+	}
+	encodedA, _ := json.Marshal(a)
+	encodedB, _ := json.Marshal(b)
+	return bytes.Equal(encodedA, encodedB)
+}
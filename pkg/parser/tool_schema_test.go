@@ -0,0 +1,173 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testToolSchemas = ToolSchemas{
+	"read_file": json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {"type": "string"},
+			"offset": {"type": "integer"},
+			"limit": {"type": ["integer", "null"]},
+			"follow": {"type": "boolean"},
+			"mode": {"type": "string", "enum": ["text", "binary"]},
+			"ranges": {"type": "array", "items": {"type": "object", "properties": {"start": {"type": "number"}}, "required": ["start"]}}
+		},
+		"required": ["path"]
+	}`),
+	"ls":     json.RawMessage(`{"type": "object", "properties": {}}`),
+	"legacy": nil,
+}
+
+func TestToolSchemas_Check(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      string
+		arguments string
+		expected  string
+		coerced   bool
+		problems  []string
+	}{
+		{
+			name:      "valid",
+			tool:      "read_file",
+			arguments: `{"path": "main.go", "offset": 10, "limit": null, "mode": "text"}`,
+			expected:  `{"path": "main.go", "offset": 10, "limit": null, "mode": "text"}`,
+		},
+		{
+			name:      "coerced_strings",
+			tool:      "read_file",
+			arguments: `{"path": "main.go", "offset": "10", "follow": "True", "ranges": "[{\"start\": \"1.5\"}]"}`,
+			expected:  `{"path": "main.go", "offset": 10, "follow": true, "ranges": [{"start": 1.5}]}`,
+			coerced:   true,
+		},
+		{
+			name:      "coerced_to_string",
+			tool:      "read_file",
+			arguments: `{"path": 42}`,
+			expected:  `{"path": "42"}`,
+			coerced:   true,
+		},
+		{
+			name:      "mismatches",
+			tool:      "read_file",
+			arguments: `{"offset": "ten", "mode": "hex", "ranges": [{"end": 3}]}`,
+			problems: []string{
+				"path: required property missing",
+				`mode: expected one of ["text","binary"]`,
+				`offset: expected integer, got string`,
+				"ranges[0].start: required property missing",
+			},
+		},
+		{
+			name:      "not_an_integer",
+			tool:      "read_file",
+			arguments: `{"path": "a", "offset": 1.5}`,
+			problems:  []string{"offset: expected integer, got number"},
+		},
+		{
+			name:      "not_an_object",
+			tool:      "ls",
+			arguments: `["."]`,
+			problems:  []string{"arguments: expected object, got array"},
+		},
+		{
+			name:      "unknown_tool",
+			tool:      "rm",
+			arguments: `{}`,
+			problems:  []string{"the request defines no such tool"},
+		},
+		{
+			name:      "tool_without_schema",
+			tool:      "legacy",
+			arguments: `{"anything": 1}`,
+			expected:  `{"anything": 1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arguments, coerced, err := testToolSchemas.Check(tt.tool, tt.arguments)
+			if tt.problems != nil {
+				var argumentsErr *ArgumentsError
+				require.ErrorAs(t, err, &argumentsErr)
+				assert.Equal(t, tt.tool, argumentsErr.Tool)
+				assert.Equal(t, tt.problems, argumentsErr.Problems)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.coerced, coerced)
+			assert.JSONEq(t, tt.expected, arguments)
+			if !tt.coerced {
+				assert.Equal(t, tt.arguments, arguments, "arguments matching their schema are kept as sent")
+			}
+		})
+	}
+}
+
+func TestToolSchemas_CheckCoercesAroundMismatches(t *testing.T) {
+	arguments, coerced, err := testToolSchemas.Check("read_file", `{"path": "a", "offset": "2", "follow": "maybe"}`)
+	require.Error(t, err)
+	assert.True(t, coerced)
+	assert.JSONEq(t, `{"path": "a", "offset": 2, "follow": "maybe"}`, arguments)
+}
+
+func TestXMLStreamParser_ToolSchemas(t *testing.T) {
+	content := "<tool_call>\n<function=read_file>\n<parameter=path>main.go</parameter>\n<parameter=offset>10</parameter>\n</function>\n</tool_call>" +
+		"<tool_call>\n<function=read_file>\n<parameter=offset>ten</parameter>\n</function>\n</tool_call>"
+
+	t.Run("reject", func(t *testing.T) {
+		p := NewXMLStreamParser()
+		p.SetToolSchemas(testToolSchemas, true)
+		var events []StreamEvent
+		for _, delta := range strings.SplitAfter(content, ">") {
+			events = append(events, p.Feed(delta)...)
+		}
+		events = append(events, p.Finish()...)
+
+		require.Len(t, events, 2)
+		call := events[0].ToolCall
+		require.NotNil(t, call)
+		assert.Equal(t, "read_file", call.Name)
+		assert.NotEmpty(t, call.ID)
+		assert.JSONEq(t, `{"path":"main.go","offset":10}`, call.Arguments, "a checked call is emitted whole, coerced")
+		assert.Contains(t, events[1].Text, "[tool call error]")
+		assert.Contains(t, events[1].Text, "path: required property missing")
+		assert.Equal(t, 1, p.ToolCalls())
+
+		checked, coerced, invalid := p.SchemaChecks()
+		assert.Equal(t, []int{2, 1, 1}, []int{checked, coerced, invalid})
+	})
+
+	t.Run("coerce_only", func(t *testing.T) {
+		p := NewXMLStreamParser()
+		p.SetToolSchemas(testToolSchemas, false)
+		events := append(p.Feed(content), p.Finish()...)
+
+		var arguments []string
+		for _, event := range events {
+			require.NotNil(t, event.ToolCall, "mismatching calls are passed on")
+			arguments = append(arguments, event.ToolCall.Arguments)
+		}
+		require.Len(t, arguments, 2)
+		assert.JSONEq(t, `{"offset":"ten"}`, arguments[1])
+		assert.Equal(t, 2, p.ToolCalls())
+	})
+
+	t.Run("standard_format", func(t *testing.T) {
+		p := NewXMLStreamParser()
+		p.SetToolSchemas(testToolSchemas, true)
+		events := append(p.Feed(`<tool_call><tool_name>read_file</tool_name><tool_arguments>{"path": "a", "offset": "3"}</tool_arguments></tool_call>`), p.Finish()...)
+
+		require.Len(t, events, 1)
+		require.NotNil(t, events[0].ToolCall)
+		assert.JSONEq(t, `{"path":"a","offset":3}`, events[0].ToolCall.Arguments)
+	})
+}
@@ -0,0 +1,494 @@
+package parser
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	functionTag      = "<function="
+	parameterTag     = "<parameter="
+	parameterEndTag  = "</parameter"
+	functionEndTag   = "</function>"
+	toolCallEndTag   = "</tool_call>"
+	maxPendingTagLen = 64  // Longest tag start held back while deciding whether it opens a tool call
+	maxTagLen        = 256 // Longest tag awaited until its >, beyond which its < is content
+)
+
+var (
+	// blockStart matches the start of a tool call in the standard formats, namespaced or not
+	blockStart = regexp.MustCompile(`^<(?:[a-zA-Z0-9_-]+:)?(tool_call|function_call)\b`)
+
+	// pendingTag matches what may still become a tool call tag once more content arrives
+	pendingTag = regexp.MustCompile(`^</?[a-zA-Z0-9_:-]*$`)
+)
+
+// StreamEvent is an output of the XMLStreamParser: either content to pass through or a tool call delta
+type StreamEvent struct {
+	Text     string
+	ToolCall *ToolCallDelta
+}
+
+// ToolCallDelta is a fragment of an OpenAI streamed tool call
+type ToolCallDelta struct {
+	Index     int
+	ID        string // Set with Name on the first delta of the call
+	Name      string
+	Arguments string // Fragment of the JSON arguments
+}
+
+// streamState is where the XMLStreamParser stands in the content
+type streamState int
+
+const (
+	stateText     streamState = iota // Outside tool calls
+	stateFunction                    // Inside <function=name>, between parameters
+	stateValue                       // Inside a parameter value
+	stateAfter                       // After </function>, before an optional </tool_call>
+	stateBlock                       // Inside a standard format tool call, buffered until it closes
+)
+
+// XMLStreamParser converts XML tool calls as the content deltas of a response arrive, instead of
+// parsing the whole response once complete. Content outside tool calls is passed through as soon
+// as it cannot start a tool call tag. The <function=name><parameter=key> format (Qwen3 Coder) is
+// converted parameter by parameter; the other formats are held back until their tool call closes
+// and converted by the XMLToolParser.
+type XMLStreamParser struct {
+	buf       string          // Content not consumed yet
+	held      strings.Builder // Start of the value or block being read, moved out of buf once searched
+	state     streamState
+	blockEnd  *regexp.Regexp
+	calls     int             // Tool calls started
+	blocks    int             // Standard format tool calls read, converted or not
+	repairs   int             // Tool calls whose malformed JSON arguments were repaired
+	schemas   ToolSchemas     // Tools of the request, calls are checked against when set
+	reject    bool            // Calls not matching their schema are replaced by an error text
+	checking  bool            // The current call is held back until complete, to be checked
+	name      string          // Tool of the current checked call
+	args      strings.Builder // Arguments of the current checked call
+	checked   int             // Tool calls checked against their schema
+	coerced   int             // Checked tool calls matching their schema once their arguments were coerced
+	invalid   int             // Checked tool calls not matching their schema, rejected or passed on
+	params    int             // Parameters emitted in the current call
+	key       string          // Parameter whose value is being read
+	space     strings.Builder // Whitespace after a tool call, dropped unless more content follows
+	text      strings.Builder // Content passed through since the last event
+	failed    bool
+	events    []StreamEvent
+	xmlParser *XMLToolParser
+	load      func() ToolSchemas // Loads the schemas on the first tool call, when set
+}
+
+// NewXMLStreamParser creates a parser for one streamed response
+func NewXMLStreamParser() *XMLStreamParser {
+	return &XMLStreamParser{xmlParser: NewXMLToolParser(false)}
+}
+
+// ToolCalls returns the number of tool calls converted so far
+func (p *XMLStreamParser) ToolCalls() int {
+	return p.calls
+}
+
+// Repairs returns the number of tool calls converted so far whose arguments were repaired JSON
+func (p *XMLStreamParser) Repairs() int {
+	return p.repairs
+}
+
+// SetToolSchemas makes the parser check each tool call against the schemas of the request's tools
+// before emitting it. A checked call is held back until complete, then emitted with its arguments
+// coerced to the schema. With reject, a call whose arguments still don't match is replaced by an
+// error text, otherwise it is emitted anyway.
+func (p *XMLStreamParser) SetToolSchemas(schemas ToolSchemas, reject bool) {
+	p.schemas, p.reject = schemas, reject
+}
+
+// LoadToolSchemas is SetToolSchemas with the schemas loaded on the first tool call, so responses
+// without one don't pay for them
+func (p *XMLStreamParser) LoadToolSchemas(load func() ToolSchemas, reject bool) {
+	p.load, p.reject = load, reject
+}
+
+// toolSchemas returns the schemas calls are checked against, loading them if needed
+func (p *XMLStreamParser) toolSchemas() ToolSchemas {
+	if p.load != nil {
+		p.schemas, p.load = p.load(), nil
+	}
+	return p.schemas
+}
+
+// SchemaChecks returns the number of tool calls checked against their schema so far, of them
+// those matching once coerced, and those not matching
+func (p *XMLStreamParser) SchemaChecks() (checked, coerced, invalid int) {
+	return p.checked, p.coerced, p.invalid
+}
+
+// Failed reports whether a tool call could not be converted and was passed through as content
+func (p *XMLStreamParser) Failed() bool {
+	return p.failed
+}
+
+// Feed consumes a content delta and returns the events it completes, in order
+func (p *XMLStreamParser) Feed(delta string) []StreamEvent {
+	p.buf += delta
+	for p.step(false) {
+	}
+	return p.flushEvents()
+}
+
+// Finish consumes the rest of the content at the end of the response, closing a truncated tool
+// call and passing held back content through
+func (p *XMLStreamParser) Finish() []StreamEvent {
+	for p.step(true) {
+	}
+	switch p.state {
+	case stateValue:
+		p.emitParameter(p.take(len(p.buf)))
+		p.endCall()
+	case stateFunction:
+		p.endCall()
+	case stateBlock:
+		p.convertBlock(p.take(len(p.buf)))
+	default:
+		p.emitText(p.buf)
+	}
+	p.buf, p.state = "", stateText
+	return p.flushEvents()
+}
+
+// step consumes the start of buf, reporting false when it needs more content to go on
+func (p *XMLStreamParser) step(final bool) bool {
+	switch p.state {
+	case stateFunction:
+		return p.stepFunction(final)
+	case stateValue:
+		return p.stepValue(final)
+	case stateAfter:
+		return p.stepAfter(final)
+	case stateBlock:
+		return p.stepBlock()
+	default:
+		return p.stepText(final)
+	}
+}
+
+func (p *XMLStreamParser) stepText(final bool) bool {
+	i := strings.IndexByte(p.buf, '<')
+	if i < 0 {
+		p.emitText(p.buf)
+		p.buf = ""
+		return false
+	}
+	p.emitText(p.buf[:i])
+	p.buf = p.buf[i:]
+
+	switch {
+	case strings.HasPrefix(p.buf, functionTag):
+		end := strings.IndexByte(p.buf, '>')
+		if end < 0 {
+			if len(p.buf) <= maxTagLen {
+				return false
+			}
+			break
+		}
+		p.startCall(strings.TrimSpace(p.buf[len(functionTag):end]))
+		p.buf = p.buf[end+1:]
+		p.state = stateFunction
+		return true
+
+	case p.blocks < maxXMLToolCalls && blockStart.MatchString(p.buf):
+		end := strings.IndexByte(p.buf, '>')
+		if end < 0 {
+			if len(p.buf) <= maxTagLen {
+				return false
+			}
+			break
+		}
+		// Qwen3 Coder wraps <function=name> in <tool_call>, which is then dropped
+		rest := strings.TrimLeft(p.buf[end+1:], " \t\r\n")
+		if strings.HasPrefix(rest, functionTag) {
+			p.buf = rest
+			return true
+		}
+		if strings.HasPrefix(functionTag, rest) && !final {
+			return false
+		}
+		tag := blockStart.FindStringSubmatch(p.buf)
+		p.blockEnd = regexp.MustCompile(`</(?:[a-zA-Z0-9_-]+:)?` + tag[1] + `\s*>`)
+		p.state = stateBlock
+		return true
+
+	case p.calls+p.invalid > 0 && strings.HasPrefix(p.buf, toolCallEndTag):
+		// Closes a <function=name> call without opening <tool_call>
+		p.buf = p.buf[len(toolCallEndTag):]
+		return true
+	}
+
+	if !final && len(p.buf) < maxPendingTagLen && pendingTag.MatchString(p.buf) {
+		return false
+	}
+	p.emitText("<")
+	p.buf = p.buf[1:]
+	return true
+}
+
+func (p *XMLStreamParser) stepFunction(final bool) bool {
+	p.buf = strings.TrimLeft(p.buf, " \t\r\n")
+	switch {
+	case p.buf == "":
+		return false
+	case strings.HasPrefix(p.buf, parameterTag):
+		end := strings.IndexByte(p.buf, '>')
+		if end < 0 {
+			if len(p.buf) <= maxTagLen {
+				return false
+			}
+			break
+		}
+		p.key = strings.TrimSpace(p.buf[len(parameterTag):end])
+		p.buf = p.buf[end+1:]
+		p.state = stateValue
+		return true
+	case strings.HasPrefix(p.buf, functionEndTag):
+		p.buf = p.buf[len(functionEndTag):]
+		p.endCall()
+		p.state = stateAfter
+		return true
+	case strings.HasPrefix(p.buf, toolCallEndTag):
+		p.buf = p.buf[len(toolCallEndTag):]
+		p.endCall()
+		p.state = stateText
+		return true
+	case !final && isTagPrefix(p.buf, parameterTag, functionEndTag, toolCallEndTag):
+		return false
+	}
+
+	// Skip stray content between parameters
+	next := strings.IndexByte(p.buf[1:], '<')
+	if next < 0 {
+		p.buf = ""
+		return false
+	}
+	p.buf = p.buf[next+1:]
+	return true
+}
+
+func (p *XMLStreamParser) stepValue(final bool) bool {
+	end, endLen := -1, 0
+	for _, tag := range []string{parameterEndTag, parameterTag, functionEndTag, toolCallEndTag} {
+		if i := strings.Index(p.buf, tag); i >= 0 && (end < 0 || i < end) {
+			end = i
+			endLen = 0
+			if tag == parameterEndTag {
+				endLen = len(tag)
+			}
+		}
+	}
+	if end < 0 {
+		// The searched value moves out of buf, tags are searched again from where they may have started
+		p.hold(len(p.buf) - len(toolCallEndTag))
+		return false
+	}
+	if endLen > 0 {
+		// The XMLToolParser also accepts a closing tag cut off before its >
+		if end+endLen == len(p.buf) && !final {
+			return false
+		}
+		if end+endLen < len(p.buf) && p.buf[end+endLen] == '>' {
+			endLen++
+		}
+	}
+
+	p.emitParameter(p.take(end))
+	p.buf = p.buf[end+endLen:]
+	p.state = stateFunction
+	return true
+}
+
+func (p *XMLStreamParser) stepAfter(final bool) bool {
+	p.buf = strings.TrimLeft(p.buf, " \t\r\n")
+	switch {
+	case p.buf == "":
+		return false
+	case strings.HasPrefix(p.buf, toolCallEndTag):
+		p.buf = p.buf[len(toolCallEndTag):]
+	case !final && isTagPrefix(p.buf, toolCallEndTag):
+		return false
+	}
+	p.state = stateText
+	return true
+}
+
+func (p *XMLStreamParser) stepBlock() bool {
+	loc := p.blockEnd.FindStringIndex(p.buf)
+	if loc == nil {
+		p.hold(len(p.buf) - maxPendingTagLen)
+		if p.held.Len() > MaxXMLContentSize {
+			// A block this large is not converted, nor held back any longer
+			p.failed = true
+			p.emitText(p.take(len(p.buf)))
+			p.buf, p.state = "", stateText
+		}
+		return false
+	}
+	end := loc[1]
+	p.convertBlock(p.take(end))
+	p.buf = p.buf[end:]
+	p.state = stateText
+	return true
+}
+
+// convertBlock converts a standard format tool call, passing it through when it holds none
+func (p *XMLStreamParser) convertBlock(block string) {
+	p.blocks++
+	toolCalls := p.xmlParser.ParseXMLToolCalls(block)
+	if len(toolCalls) == 0 {
+		p.failed = true
+		p.emitText(block)
+		return
+	}
+	for _, toolCall := range toolCalls {
+		if toolCall.Repaired {
+			p.repairs++
+		}
+		p.completeCall(toolCall.Function.Name, toolCall.Function.Arguments)
+	}
+}
+
+// hold moves the first n bytes of buf, searched already, to the held value or block, so that only
+// its end is searched again with the next delta
+func (p *XMLStreamParser) hold(n int) {
+	if n > 0 {
+		p.held.WriteString(p.buf[:n])
+		p.buf = p.buf[n:]
+	}
+}
+
+// take returns the held value or block followed by the first end bytes of buf, which it keeps
+func (p *XMLStreamParser) take(end int) string {
+	taken := p.held.String() + p.buf[:end]
+	p.held.Reset()
+	return taken
+}
+
+// startCall starts a call of the <function=name> format, whose arguments stream parameter by
+// parameter unless the call is checked
+func (p *XMLStreamParser) startCall(name string) {
+	p.params = 0
+	if len(p.toolSchemas()) > 0 {
+		p.space.Reset()
+		p.checking, p.name = true, name
+		p.args.Reset()
+		return
+	}
+	p.openCall(name)
+}
+
+// openCall emits the first delta of a call, with its ID and name
+func (p *XMLStreamParser) openCall(name string) {
+	p.space.Reset()
+	p.addEvent(&ToolCallDelta{
+		Index: p.calls,
+		ID:    toolCallID(p.calls),
+		Name:  name,
+	})
+	p.calls++
+}
+
+// completeCall emits a call whose arguments are complete, checked against its schema when set
+func (p *XMLStreamParser) completeCall(name, arguments string) {
+	if len(p.toolSchemas()) > 0 {
+		checked, coerced, err := p.schemas.Check(name, arguments)
+		p.checked++
+		switch {
+		case err != nil:
+			p.invalid++
+			if p.reject {
+				p.emitText(ToolCallErrorText(err))
+				return
+			}
+		case coerced:
+			p.coerced++
+		}
+		arguments = checked
+	}
+	p.openCall(name)
+	p.events[len(p.events)-1].ToolCall.Arguments = arguments
+}
+
+// emitParameter emits a parameter of the current call as a fragment of its JSON arguments
+func (p *XMLStreamParser) emitParameter(value string) {
+	fragment := ","
+	if p.params == 0 {
+		fragment = "{"
+	}
+	key, _ := json.Marshal(p.key)
+	encoded, _ := json.Marshal(strings.TrimSpace(value))
+	p.params++
+	p.emitArguments(fragment + string(key) + ":" + string(encoded))
+}
+
+func (p *XMLStreamParser) endCall() {
+	if p.params == 0 {
+		p.emitArguments("{}")
+	} else {
+		p.emitArguments("}")
+	}
+	if p.checking {
+		p.checking = false
+		p.completeCall(p.name, p.args.String())
+	}
+}
+
+func (p *XMLStreamParser) emitArguments(fragment string) {
+	if p.checking {
+		p.args.WriteString(fragment)
+		return
+	}
+	p.addEvent(&ToolCallDelta{Index: p.calls - 1, Arguments: fragment})
+}
+
+// addEvent appends a tool call event, after the content passed through before it
+func (p *XMLStreamParser) addEvent(toolCall *ToolCallDelta) {
+	p.flushText()
+	p.events = append(p.events, StreamEvent{ToolCall: toolCall})
+}
+
+// emitText passes content through, merged with the content before it until the next tool call event
+func (p *XMLStreamParser) emitText(text string) {
+	if text == "" {
+		return
+	}
+	if p.calls > 0 && strings.TrimSpace(text) == "" {
+		p.space.WriteString(text)
+		return
+	}
+	p.text.WriteString(p.space.String())
+	p.text.WriteString(text)
+	p.space.Reset()
+}
+
+// flushText turns the content passed through into a text event
+func (p *XMLStreamParser) flushText() {
+	if p.text.Len() > 0 {
+		p.events = append(p.events, StreamEvent{Text: p.text.String()})
+		p.text.Reset()
+	}
+}
+
+func (p *XMLStreamParser) flushEvents() []StreamEvent {
+	p.flushText()
+	events := p.events
+	p.events = nil
+	return events
+}
+
+// isTagPrefix reports whether s is the start of one of the tags
+func isTagPrefix(s string, tags ...string) bool {
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, s) {
+			return true
+		}
+	}
+	return false
+}
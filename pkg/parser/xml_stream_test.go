@@ -0,0 +1,204 @@
+package parser
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamResult gathers the events of a streamed response
+type streamResult struct {
+	text  string
+	calls []ToolCall
+}
+
+func (r *streamResult) add(events []StreamEvent) {
+	for _, event := range events {
+		if event.ToolCall == nil {
+			r.text += event.Text
+			continue
+		}
+		if event.ToolCall.Index == len(r.calls) {
+			r.calls = append(r.calls, ToolCall{ID: event.ToolCall.ID, Type: "function", Function: ToolCallFunction{Name: event.ToolCall.Name}})
+		}
+		r.calls[event.ToolCall.Index].Function.Arguments += event.ToolCall.Arguments
+	}
+}
+
+// streamDeltas feeds the deltas to a new parser, then finishes it
+func streamDeltas(deltas ...string) streamResult {
+	p := NewXMLStreamParser()
+	var result streamResult
+	for _, delta := range deltas {
+		result.add(p.Feed(delta))
+	}
+	result.add(p.Finish())
+	return result
+}
+
+func TestXMLStreamParser_FunctionFormat(t *testing.T) {
+	p := NewXMLStreamParser()
+
+	events := p.Feed("Let me look.\n<tool_call>\n<function=read_file>\n<para")
+	require.Len(t, events, 2, "the call starts as soon as its name is complete")
+	assert.Equal(t, "Let me look.\n", events[0].Text)
+	assert.Equal(t, &ToolCallDelta{Index: 0, ID: "call_a", Name: "read_file"}, events[1].ToolCall)
+
+	events = p.Feed("meter=path>\n/tmp/a.go\n</parameter>\n<parameter=limit>10")
+	require.Len(t, events, 1, "a value is emitted once its closing tag arrives")
+	assert.Equal(t, `{"path":"/tmp/a.go"`, events[0].ToolCall.Arguments)
+
+	events = p.Feed("</parameter>\n</function>\n</tool_call>")
+	require.Len(t, events, 2)
+	assert.Equal(t, `,"limit":"10"`, events[0].ToolCall.Arguments)
+	assert.Equal(t, "}", events[1].ToolCall.Arguments)
+
+	assert.Empty(t, p.Finish())
+	assert.Equal(t, 1, p.ToolCalls())
+	assert.False(t, p.Failed())
+}
+
+func TestXMLStreamParser_MatchesXMLToolParser(t *testing.T) {
+	contents := []string{
+		"<tool_call>\n<function=ls>\n<parameter=path>\n.\n</parameter>\n</function>\n</tool_call>",
+		"<function=ls> <parameter=path> internal/agent </tool_call>",
+		"<function=ls>\n<parameter=path>\n.\n</parameter>\n</function>\n</tool_call>",
+		`<tool_call><tool_name>get_weather</tool_name><tool_arguments>{"city": "Paris"}</tool_arguments></tool_call>`,
+		`<function_call><name>search</name><arguments>{"query": "vllm"}</arguments></function_call>`,
+		"<function=read>\n<parameter=path>/tmp",
+	}
+
+	for _, content := range contents {
+		want := NewXMLToolParser(false).ParseXMLToolCalls(content)
+		require.NotEmpty(t, want, content)
+
+		// Deltas of every size, including ones splitting tags
+		for _, size := range []int{1, 2, 3, 7, len(content)} {
+			var deltas []string
+			for i := 0; i < len(content); i += size {
+				deltas = append(deltas, content[i:min(i+size, len(content))])
+			}
+			result := streamDeltas(deltas...)
+
+			assert.Empty(t, result.text, "%q in deltas of %d", content, size)
+			require.Len(t, result.calls, len(want), "%q in deltas of %d", content, size)
+			for i, call := range result.calls {
+				assert.Equal(t, want[i].ID, call.ID)
+				assert.Equal(t, want[i].Function.Name, call.Function.Name)
+				assert.JSONEq(t, want[i].Function.Arguments, call.Function.Arguments, "%q in deltas of %d", content, size)
+			}
+		}
+	}
+}
+
+func TestXMLStreamParser_MultipleCalls(t *testing.T) {
+	result := streamDeltas(
+		"<tool_call>\n<function=a>\n<parameter=x>1</parameter>\n</function>\n</tool_call>\n",
+		"<tool_call>\n<function=b>\n</function>\n</tool_call>\n",
+	)
+
+	require.Len(t, result.calls, 2)
+	assert.Equal(t, "call_b", result.calls[1].ID)
+	assert.Equal(t, `{"x":"1"}`, result.calls[0].Function.Arguments)
+	assert.Equal(t, "{}", result.calls[1].Function.Arguments)
+	assert.Empty(t, result.text, "whitespace after the calls is dropped")
+}
+
+func TestXMLStreamParser_PassesTextThrough(t *testing.T) {
+	p := NewXMLStreamParser()
+
+	assert.Equal(t, []StreamEvent{{Text: "if a "}}, p.Feed("if a <"), "a < that may start a tag is held back")
+	assert.Equal(t, []StreamEvent{{Text: "< b then <div>"}}, p.Feed(" b then <div>"))
+	assert.Empty(t, p.Feed("<func"))
+	assert.Equal(t, []StreamEvent{{Text: "<function> is a tag"}}, p.Feed("tion> is a tag"))
+	assert.Equal(t, []StreamEvent{{Text: "<tool"}}, append(p.Feed("<tool"), p.Finish()...))
+	assert.Zero(t, p.ToolCalls())
+}
+
+func TestXMLStreamParser_ValueWithMarkup(t *testing.T) {
+	result := streamDeltas("<function=write>\n<parameter=content>\n<div>a < b</div>\n</parameter>\n</function>")
+
+	require.Len(t, result.calls, 1)
+	var args map[string]string
+	require.NoError(t, json.Unmarshal([]byte(result.calls[0].Function.Arguments), &args))
+	assert.Equal(t, "<div>a < b</div>", args["content"])
+}
+
+func TestXMLStreamParser_UnparsableBlock(t *testing.T) {
+	content := "<tool_call>not a tool call</tool_call> done"
+	p := NewXMLStreamParser()
+
+	events := append(p.Feed(content), p.Finish()...)
+
+	assert.Equal(t, []StreamEvent{{Text: content}}, events)
+	assert.True(t, p.Failed())
+	assert.Zero(t, p.ToolCalls())
+}
+
+func TestXMLStreamParser_UnclosedTag(t *testing.T) {
+	tag := "<function=" + strings.Repeat("a", maxTagLen)
+	p := NewXMLStreamParser()
+
+	assert.Equal(t, []StreamEvent{{Text: tag}}, p.Feed(tag), "a tag not closed within maxTagLen is content")
+	assert.Equal(t, []StreamEvent{{Text: "> and more"}}, p.Feed("> and more"))
+	assert.Zero(t, p.ToolCalls())
+}
+
+func TestXMLStreamParser_BlockOverSizeLimit(t *testing.T) {
+	p := NewXMLStreamParser()
+	var result streamResult
+
+	result.add(p.Feed("<tool_call><tool_name>write</tool_name><tool_arguments>"))
+	chunk := strings.Repeat("a", 64<<10)
+	for i := 0; i <= MaxXMLContentSize/len(chunk); i++ {
+		result.add(p.Feed(chunk))
+	}
+	assert.True(t, p.Failed(), "the block is given up once too large")
+	assert.True(t, strings.HasPrefix(result.text, "<tool_call>"))
+
+	result.add(p.Feed("</tool_arguments></tool_call>"))
+	result.add(p.Finish())
+	assert.Empty(t, result.calls)
+	assert.True(t, strings.HasSuffix(result.text, "</tool_arguments></tool_call>"))
+}
+
+func TestXMLStreamParser_LongValue(t *testing.T) {
+	value := strings.Repeat("line of a large file\n", 50000)
+	deltas := []string{"<function=write>\n<parameter=content>\n"}
+	for i := 0; i < len(value); i += 4 {
+		deltas = append(deltas, value[i:min(i+4, len(value))])
+	}
+	deltas = append(deltas, "</parameter>\n</function>")
+
+	start := time.Now()
+	result := streamDeltas(deltas...)
+	assert.Less(t, time.Since(start), 5*time.Second, "a value is not copied again with each delta")
+
+	require.Len(t, result.calls, 1)
+	var args map[string]string
+	require.NoError(t, json.Unmarshal([]byte(result.calls[0].Function.Arguments), &args))
+	assert.Equal(t, strings.TrimSpace(value), args["content"])
+}
+
+func FuzzXMLStreamParser(f *testing.F) {
+	f.Add("Let me look.\n<tool_call>\n<function=read_file>\n<parameter=path>\n/tmp/a.go\n</parameter>\n</function>\n</tool_call>", 3)
+	f.Add(`<tool_call><tool_name>get_weather</tool_name><tool_arguments>{"city": "Paris"}</tool_arguments></tool_call>`, 5)
+	f.Add("if a < b <function=x <parameter=y></tool_call>", 1)
+
+	f.Fuzz(func(t *testing.T, content string, size int) {
+		size = max(1, size%64)
+		var deltas []string
+		for i := 0; i < len(content); i += size {
+			deltas = append(deltas, content[i:min(i+size, len(content))])
+		}
+
+		result := streamDeltas(deltas...)
+		for _, call := range result.calls {
+			assert.True(t, json.Valid([]byte(call.Function.Arguments)), "arguments %q of %q", call.Function.Arguments, content)
+		}
+	})
+}
@@ -1,4 +1,5 @@
-// Package parser provides functionality for parsing XML tool calls.
+// Package parser converts the tool calls models write as text (XML, JSON, Mistral and Llama 3
+// markup) into OpenAI tool calls, with a parser selected per model.
 package parser
 
 import (
@@ -7,14 +8,56 @@ import (
 	"html"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 )
 
+const (
+	// MaxXMLContentSize is the largest content the XML parsers convert, in bytes. Longer content is
+	// passed through as text, so that pathological model output can't pin a CPU core.
+	MaxXMLContentSize = 4 << 20
+
+	// maxXMLToolCalls bounds the tool calls parsed from one response
+	maxXMLToolCalls = 128
+)
+
+var (
+	toolCallPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`<[a-zA-Z0-9_-]*:?tool_call`),
+		regexp.MustCompile(`<[a-zA-Z0-9_-]*:?function_call`),
+	}
+	codeBlockRegex       = regexp.MustCompile("```[a-z]*\n")
+	cdataRegex           = regexp.MustCompile(`<!\[CDATA\[(.*?)\]\]>`)
+	toolCallTagRegex     = regexp.MustCompile(`<(?:[a-zA-Z0-9_-]+:)?(tool_call|function_call)[^>]*>`)
+	partRegex            = regexp.MustCompile(`<tool_call[^>]*part="[^"]*"[^>]*>([\s\S]*?)</tool_call>`)
+	fragmentNameRegex    = regexp.MustCompile(`<tool_name>([\s\S]*?)</tool_name>`)
+	fragmentArgsRegex    = regexp.MustCompile(`<tool_arguments>([\s\S]*?)</tool_arguments>`)
+	fragmentOpenRegex    = regexp.MustCompile(`<tool_arguments>([\s\S]*?)$`)
+	nameAttrRegex        = regexp.MustCompile(`<(?:tool_call|function_call)[^>]*\s+name\s*=\s*["']([^"']+)["']`)
+	namespaceDeclRegex   = regexp.MustCompile(`\s+xmlns[^=]*="[^"]*"`)
+	namespacePrefixRegex = regexp.MustCompile(`<(/?)([a-zA-Z0-9_-]+):`)
+	nestedTagRegex       = regexp.MustCompile(`<([a-zA-Z_][a-zA-Z0-9_-]*)(?:\s+type="([^"]+)")?>([^<]*)</[a-zA-Z_][a-zA-Z0-9_-]*>`)
+
+	// tagRegexps holds the patterns built from tag names, compiled once
+	tagRegexps sync.Map
+)
+
+// tagRegexp returns the compiled pattern, built from a tag name
+func tagRegexp(pattern string) *regexp.Regexp {
+	if re, ok := tagRegexps.Load(pattern); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, _ := tagRegexps.LoadOrStore(pattern, regexp.MustCompile(pattern))
+	return re.(*regexp.Regexp)
+}
+
 // ToolCall represents a parsed tool call from XML or JSON format
 type ToolCall struct {
 	ID       string           `json:"id"`
 	Type     string           `json:"type"`
 	Function ToolCallFunction `json:"function"`
+	Repaired bool             `json:"-"` // The arguments were malformed JSON fixed by RepairJSON
 }
 
 // ToolCallFunction represents the function part of a tool call
@@ -38,6 +81,10 @@ func (p *XMLToolParser) ParseXMLToolCalls(content string) []ToolCall {
 	if p.debug {
 		log.Printf("[XML-PARSER] Parsing XML tool calls from content (length: %d)", len(content))
 	}
+	if len(content) > MaxXMLContentSize {
+		log.Printf("[XML-PARSER] Content of %d bytes exceeds the %d bytes limit, not parsed", len(content), MaxXMLContentSize)
+		return []ToolCall{}
+	}
 
 	// Preprocess content
 	content = p.preprocessContent(content)
@@ -66,17 +113,41 @@ func (p *XMLToolParser) ParseXMLToolCalls(content string) []ToolCall {
 	return toolCalls
 }
 
+// Name returns the parser's fallbackToolParser name
+func (p *XMLToolParser) Name() string {
+	return XMLParserName
+}
+
+// Detect reports whether content holds complete or incomplete XML tool call patterns
+func (p *XMLToolParser) Detect(content string) bool {
+	return ContainsXMLToolCallPattern(content)
+}
+
+// Parse extracts the XML tool calls of content
+func (p *XMLToolParser) Parse(content string) []ToolCall {
+	return p.ParseXMLToolCalls(content)
+}
+
+// ContainsXMLToolCallPattern reports whether content holds complete or incomplete XML tool call patterns
+func ContainsXMLToolCallPattern(content string) bool {
+	return strings.Contains(content, "<function=") ||
+		strings.Contains(content, "<tool_call") ||
+		strings.Contains(content, "<function_call") ||
+		// Also detect incomplete fragments that might be XML
+		(strings.Contains(content, "<function") && !strings.Contains(content, "function>"))
+}
+
 // containsToolCallPattern checks if content likely contains tool call XML
 func (p *XMLToolParser) containsToolCallPattern(content string) bool {
-	// Use regex to match tool call patterns with or without namespace
-	patterns := []string{
-		`<[a-zA-Z0-9_-]*:?tool_call`,
-		`<[a-zA-Z0-9_-]*:?function_call`,
-		`<function=`,
+	if strings.Contains(content, "<function=") {
+		return true
+	}
+	if !containsToolCallTagName(content) {
+		return false
 	}
 
-	for _, pattern := range patterns {
-		regex := regexp.MustCompile(pattern)
+	// Use regex to match tool call patterns with or without namespace
+	for _, regex := range toolCallPatterns {
 		if regex.MatchString(content) {
 			return true
 		}
@@ -84,18 +155,22 @@ func (p *XMLToolParser) containsToolCallPattern(content string) bool {
 	return false
 }
 
+// containsToolCallTagName reports whether content names a tool_call or function_call tag, before
+// the slower search of the tags themselves
+func containsToolCallTagName(content string) bool {
+	return strings.Contains(content, "tool_call") || strings.Contains(content, "function_call")
+}
+
 // preprocessContent cleans and normalizes the content
 func (p *XMLToolParser) preprocessContent(content string) string {
 	// Remove BOM
 	content = strings.TrimPrefix(content, "\uFEFF")
 
 	// Remove markdown code blocks
-	codeBlockRegex := regexp.MustCompile("```[a-z]*\n")
 	content = codeBlockRegex.ReplaceAllString(content, "")
 	content = strings.ReplaceAll(content, "```", "")
 
 	// Unwrap CDATA sections that wrap entire tool calls
-	cdataRegex := regexp.MustCompile(`<!\[CDATA\[(.*?)\]\]>`)
 	content = cdataRegex.ReplaceAllString(content, "$1")
 
 	// Handle cases where "<" appears in non-XML contexts
@@ -117,32 +192,42 @@ func (p *XMLToolParser) parseStandardXMLFormat(content string) []ToolCall {
 	var toolCalls []ToolCall
 	callIndex := 0
 
+	if !containsToolCallTagName(content) {
+		return nil
+	}
+
 	// Handle streaming fragments (merge parts)
 	content = p.mergeStreamingFragments(content)
 
 	// Use a single comprehensive pattern for both tool_call and function_call
 	// This pattern matches tags with optional namespace prefix
-	pattern := `<(?:[a-zA-Z0-9_-]+:)?(tool_call|function_call)[^>]*>`
-	regex := regexp.MustCompile(pattern)
-	matches := regex.FindAllStringSubmatchIndex(content, -1)
+	// Past maxXMLToolCalls tags, the rest of the content is not parsed
+	matches := toolCallTagRegex.FindAllStringSubmatchIndex(content, maxXMLToolCalls+1)
+	if len(matches) > maxXMLToolCalls {
+		log.Printf("[XML-PARSER] Parsing the first %d of more tool call tags", maxXMLToolCalls)
+	}
 
-	// Track processed positions to avoid duplicates
-	processedPositions := make(map[int]bool)
+	// Closing tags are searched once per tag type, not from every opening tag
+	closings := make(map[string][][]int)
 
-	for _, match := range matches {
-		startIdx := match[0]
-
-		// Skip if we've already processed this position
-		if processedPositions[startIdx] {
-			continue
+	for i, match := range matches {
+		if i == maxXMLToolCalls {
+			break
 		}
-		processedPositions[startIdx] = true
+		startIdx := match[0]
 
 		// Get the tag type (tool_call or function_call)
 		tagName := content[match[2]:match[3]]
+		if _, ok := closings[tagName]; !ok {
+			closings[tagName] = closingTagRegexp(tagName).FindAllStringIndex(content, -1)
+		}
 
 		// Find the closing tag (may not exist for truncated output)
-		endIdx := p.findClosingTag(content, startIdx, tagName)
+		nextIdx := len(content)
+		if i+1 < len(matches) {
+			nextIdx = matches[i+1][0]
+		}
+		endIdx := p.findClosingTag(closings[tagName], startIdx, nextIdx)
 
 		// Extract the tool call XML
 		toolCallXML := content[startIdx:endIdx]
@@ -158,38 +243,30 @@ func (p *XMLToolParser) parseStandardXMLFormat(content string) []ToolCall {
 	return toolCalls
 }
 
-// findClosingTag finds the closing tag for an XML element
-func (p *XMLToolParser) findClosingTag(content string, startIdx int, tagName string) int {
-	// Look for closing tag patterns (with or without namespace)
+// closingTagRegexp returns the pattern of the closing tags of a tool call tag, with or without namespace
+func closingTagRegexp(tagName string) *regexp.Regexp {
 	closingPatterns := []string{
 		fmt.Sprintf(`</%s>`, tagName),
 		fmt.Sprintf(`</[a-zA-Z0-9_-]*:%s>`, tagName),
 		fmt.Sprintf(`<[a-zA-Z0-9_-]*:/%s>`, tagName),
 	}
+	return tagRegexp("(" + strings.Join(closingPatterns, "|") + ")")
+}
 
-	closingPattern := "(" + strings.Join(closingPatterns, "|") + ")"
-	closingRegex := regexp.MustCompile(closingPattern)
-	closingMatches := closingRegex.FindStringIndex(content[startIdx:])
-
-	if closingMatches != nil {
-		return startIdx + closingMatches[1]
-	}
-
-	// No closing tag found, try to find next tool call or end of content
-	nextToolCall := regexp.MustCompile(`<[^:>]*:?(tool_call|function_call)[^>]*>`)
-	nextMatches := nextToolCall.FindStringIndex(content[startIdx+1:])
-
-	if nextMatches != nil {
-		return startIdx + 1 + nextMatches[0]
+// findClosingTag returns the end of the element starting at startIdx: its first closing tag, or
+// the next tool call, at nextIdx, when that comes first (tool calls don't nest, an unclosed one
+// ends where the next starts)
+func (p *XMLToolParser) findClosingTag(closings [][]int, startIdx, nextIdx int) int {
+	i := sort.Search(len(closings), func(i int) bool { return closings[i][0] >= startIdx })
+	if i < len(closings) && closings[i][0] < nextIdx {
+		return closings[i][1]
 	}
-
-	return len(content)
+	return nextIdx
 }
 
 // mergeStreamingFragments merges tool calls with part attributes
 func (p *XMLToolParser) mergeStreamingFragments(content string) string {
 	// Find all tool_call tags with part attribute
-	partRegex := regexp.MustCompile(`<tool_call[^>]*part="[^"]*"[^>]*>([\s\S]*?)</tool_call>`)
 	matches := partRegex.FindAllStringSubmatchIndex(content, -1)
 
 	if len(matches) <= 1 {
@@ -204,19 +281,16 @@ func (p *XMLToolParser) mergeStreamingFragments(content string) string {
 		fragment := content[matchIdx[2]:matchIdx[3]]
 
 		// Extract tool_name if present
-		nameRegex := regexp.MustCompile(`<tool_name>([\s\S]*?)</tool_name>`)
-		if nameMatch := nameRegex.FindStringSubmatch(fragment); nameMatch != nil {
+		if nameMatch := fragmentNameRegex.FindStringSubmatch(fragment); nameMatch != nil {
 			toolName = strings.TrimSpace(nameMatch[1])
 		}
 
 		// Extract tool_arguments content
-		argsRegex := regexp.MustCompile(`<tool_arguments>([\s\S]*?)</tool_arguments>`)
-		if argsMatch := argsRegex.FindStringSubmatch(fragment); argsMatch != nil {
+		if argsMatch := fragmentArgsRegex.FindStringSubmatch(fragment); argsMatch != nil {
 			arguments.WriteString(strings.TrimSpace(argsMatch[1]))
 		} else {
 			// Handle case where only arguments are in the fragment (no closing tag)
-			argsRegex2 := regexp.MustCompile(`<tool_arguments>([\s\S]*?)$`)
-			if argsMatch2 := argsRegex2.FindStringSubmatch(fragment); argsMatch2 != nil {
+			if argsMatch2 := fragmentOpenRegex.FindStringSubmatch(fragment); argsMatch2 != nil {
 				arguments.WriteString(strings.TrimSpace(argsMatch2[1]))
 			}
 		}
@@ -255,7 +329,6 @@ func (p *XMLToolParser) parseToolCallXML(xmlContent string, tagType string, call
 	var toolName string
 
 	// Try to extract name from attribute: <tool_call name="...">
-	nameAttrRegex := regexp.MustCompile(`<(?:tool_call|function_call)[^>]*\s+name\s*=\s*["']([^"']+)["']`)
 	if match := nameAttrRegex.FindStringSubmatch(xmlContent); match != nil {
 		toolName = match[1]
 	} else {
@@ -275,6 +348,7 @@ func (p *XMLToolParser) parseToolCallXML(xmlContent string, tagType string, call
 
 	// Extract arguments
 	var argsJSON string
+	var repaired bool
 	if tagType == "tool_call" {
 		// Try multiple tag names for arguments
 		argsContent := p.extractTagContent(xmlContent, "tool_arguments")
@@ -284,13 +358,13 @@ func (p *XMLToolParser) parseToolCallXML(xmlContent string, tagType string, call
 		if argsContent == "" {
 			argsContent = p.extractTagContent(xmlContent, "args")
 		}
-		argsJSON = p.parseArguments(argsContent)
+		argsJSON, repaired = p.parseArguments(argsContent)
 	} else {
 		argsContent := p.extractTagContent(xmlContent, "arguments")
 		if argsContent == "" {
 			argsContent = p.extractTagContent(xmlContent, "args")
 		}
-		argsJSON = p.parseArguments(argsContent)
+		argsJSON, repaired = p.parseArguments(argsContent)
 	}
 
 	return &ToolCall{
@@ -300,19 +374,18 @@ func (p *XMLToolParser) parseToolCallXML(xmlContent string, tagType string, call
 			Name:      toolName,
 			Arguments: argsJSON,
 		},
+		Repaired: repaired,
 	}
 }
 
 // stripNamespaces removes XML namespace prefixes
 func (p *XMLToolParser) stripNamespaces(content string) string {
 	// Remove namespace declarations
-	nsRegex := regexp.MustCompile(`\s+xmlns[^=]*="[^"]*"`)
-	content = nsRegex.ReplaceAllString(content, "")
+	content = namespaceDeclRegex.ReplaceAllString(content, "")
 
 	// Remove namespace prefixes from opening and closing tags
 	// Match patterns like <qwen:tool_call> or </qwen:tool_call>
-	prefixRegex := regexp.MustCompile(`<(/?)([a-zA-Z0-9_-]+):`)
-	content = prefixRegex.ReplaceAllString(content, "<$1")
+	content = namespacePrefixRegex.ReplaceAllString(content, "<$1")
 
 	return content
 }
@@ -325,8 +398,7 @@ func (p *XMLToolParser) extractTagContent(xmlContent, tagName string) string {
 		fmt.Sprintf(`<[a-zA-Z0-9_-]*:%s[^>]*><!\[CDATA\[(.*?)\]\]></[a-zA-Z0-9_-]*:%s>`, tagName, tagName),
 	}
 	for _, pattern := range cdataPatterns {
-		cdataRegex := regexp.MustCompile(pattern)
-		if match := cdataRegex.FindStringSubmatch(xmlContent); match != nil {
+		if match := tagRegexp(pattern).FindStringSubmatch(xmlContent); match != nil {
 			if len(match) > 1 && match[1] != "" {
 				return match[1]
 			}
@@ -344,8 +416,7 @@ func (p *XMLToolParser) extractTagContent(xmlContent, tagName string) string {
 	}
 
 	for _, pattern := range patterns {
-		regex := regexp.MustCompile(pattern)
-		if match := regex.FindStringSubmatch(xmlContent); match != nil {
+		if match := tagRegexp(pattern).FindStringSubmatch(xmlContent); match != nil {
 			content := strings.TrimSpace(match[1])
 			// Unescape any remaining entities
 			content = html.UnescapeString(content)
@@ -362,8 +433,7 @@ func (p *XMLToolParser) extractTagContent(xmlContent, tagName string) string {
 	}
 
 	for _, pattern := range truncatedPatterns {
-		regex := regexp.MustCompile(pattern)
-		if match := regex.FindStringSubmatch(xmlContent); match != nil {
+		if match := tagRegexp(pattern).FindStringSubmatch(xmlContent); match != nil {
 			content := match[1]
 			// Remove any trailing comment or incomplete tags
 			if idx := strings.Index(content, "<!--"); idx != -1 {
@@ -387,52 +457,37 @@ func (p *XMLToolParser) extractTagContent(xmlContent, tagName string) string {
 	return ""
 }
 
-// parseArguments converts argument content to JSON string
-func (p *XMLToolParser) parseArguments(argsContent string) string {
+// parseArguments converts argument content to JSON string, reporting whether it repaired malformed JSON
+func (p *XMLToolParser) parseArguments(argsContent string) (string, bool) {
 	argsContent = strings.TrimSpace(argsContent)
 
 	// Handle empty arguments
 	if argsContent == "" {
-		return "{}"
+		return "{}", false
 	}
 
 	// Check if it's already JSON
 	if strings.HasPrefix(argsContent, "{") || strings.HasPrefix(argsContent, "[") {
-		// Validate and clean the JSON
-		var test interface{}
-		if err := json.Unmarshal([]byte(argsContent), &test); err == nil {
-			return argsContent
+		if json.Valid([]byte(argsContent)) {
+			return argsContent, false
 		}
 
-		// Try to fix common JSON issues
-		fixedJSON := p.fixCommonJSONIssues(argsContent)
-		if err := json.Unmarshal([]byte(fixedJSON), &test); err == nil {
-			return fixedJSON
+		// Try to repair it: trailing commas, single quotes, unquoted keys, truncation...
+		if repaired, ok := RepairJSON(argsContent); ok {
+			if p.debug {
+				log.Printf("[XML-PARSER] Repaired malformed JSON arguments")
+			}
+			return repaired, true
 		}
 	}
 
 	// Try to parse as nested XML
 	if strings.HasPrefix(argsContent, "<") {
-		return p.parseNestedXMLArguments(argsContent)
+		return p.parseNestedXMLArguments(argsContent), false
 	}
 
 	// Default to empty object
-	return "{}"
-}
-
-// fixCommonJSONIssues attempts to fix common JSON formatting issues
-func (p *XMLToolParser) fixCommonJSONIssues(jsonStr string) string {
-	// Handle trailing commas
-	trailingCommaRegex := regexp.MustCompile(`,(\s*[}\]])`)
-	jsonStr = trailingCommaRegex.ReplaceAllString(jsonStr, "$1")
-
-	// Handle single quotes (convert to double quotes)
-	// This is a simplified approach - a full implementation would need proper parsing
-	if !strings.Contains(jsonStr, `"`) && strings.Contains(jsonStr, `'`) {
-		jsonStr = strings.ReplaceAll(jsonStr, `'`, `"`)
-	}
-
-	return jsonStr
+	return "{}", false
 }
 
 // parseNestedXMLArguments converts nested XML to JSON
@@ -442,8 +497,7 @@ func (p *XMLToolParser) parseNestedXMLArguments(xmlContent string) string {
 
 	// Find all simple tags - match opening tag, content, closing tag
 	// Pattern: <tagname [type="..."]>content</tagname>
-	tagRegex := regexp.MustCompile(`<([a-zA-Z_][a-zA-Z0-9_-]*)(?:\s+type="([^"]+)")?>([^<]*)</[a-zA-Z_][a-zA-Z0-9_-]*>`)
-	matches := tagRegex.FindAllStringSubmatch(xmlContent, -1)
+	matches := nestedTagRegex.FindAllStringSubmatch(xmlContent, -1)
 
 	for _, match := range matches {
 		tagName := match[1]
@@ -491,7 +545,7 @@ func (p *XMLToolParser) parseLegacyFormat(content string) []ToolCall {
 	idx := 0
 	callIndex := 0
 
-	for {
+	for len(toolCalls) < maxXMLToolCalls {
 		// Find start of function tag
 		funcStart := strings.Index(content[idx:], "<function=")
 		if funcStart == -1 {
@@ -511,7 +565,8 @@ func (p *XMLToolParser) parseLegacyFormat(content string) []ToolCall {
 
 		// Find end of tool call
 		toolCallEnd := strings.Index(content[funcEnd:], "</tool_call>")
-		if toolCallEnd == -1 {
+		closed := toolCallEnd != -1
+		if !closed {
 			// Try without closing tag for truncated output
 			toolCallEnd = len(content[funcEnd:])
 		}
@@ -545,7 +600,7 @@ func (p *XMLToolParser) parseLegacyFormat(content string) []ToolCall {
 
 		callIndex++
 		// Move to next potential tool call
-		if strings.Contains(content[funcEnd:], "</tool_call>") {
+		if closed {
 			idx = toolCallEnd + 12 // len("</tool_call>")
 		} else {
 			idx = toolCallEnd
@@ -648,6 +703,11 @@ func (p *XMLToolParser) parseParameters(content string) map[string]string {
 
 // generateToolCallID generates a simple tool call ID
 func (p *XMLToolParser) generateToolCallID(index int) string {
+	return toolCallID(index)
+}
+
+// toolCallID generates the tool call ID for the call at index (call_a, call_b, ..., call_a1)
+func toolCallID(index int) string {
 	letter := 'a' + rune(index%26)
 	num := index / 26
 	if num == 0 {
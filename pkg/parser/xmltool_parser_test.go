@@ -3,7 +3,9 @@ package parser
 import (
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -239,3 +241,57 @@ Result: a < b < c < d is true`
 		assert.Empty(t, toolCalls)
 	})
 }
+
+func TestXMLToolParser_PathologicalInputs(t *testing.T) {
+	parser := NewXMLToolParser(false)
+
+	inputs := map[string]string{
+		"unclosed_tags":         strings.Repeat("<tool_call>", 100000) + "</tool_call>",
+		"namespaced_tags":       strings.Repeat("<a:tool_call x=1>", 100000),
+		"fragments":             strings.Repeat(`<tool_call part="1"><tool_arguments>x</tool_call>`, 20000),
+		"nested_arguments":      "<tool_call><tool_name>a</tool_name><tool_arguments>" + strings.Repeat("<a>", 300000) + "</tool_arguments></tool_call>",
+		"functions":             strings.Repeat("<function=a>", 100000),
+		"parameters":            "<function=a>" + strings.Repeat("<parameter=x>", 100000) + "</parameter>",
+		"less_than_then_a_call": strings.Repeat("<", 1000000) + "<tool_call>",
+	}
+	for name, content := range inputs {
+		t.Run(name, func(t *testing.T) {
+			start := time.Now()
+			toolCalls := parser.ParseXMLToolCalls(content)
+			assert.Less(t, time.Since(start), 5*time.Second, "%d bytes", len(content))
+			assert.LessOrEqual(t, len(toolCalls), maxXMLToolCalls)
+		})
+	}
+
+	t.Run("many_calls", func(t *testing.T) {
+		call := "<tool_call><tool_name>a</tool_name><tool_arguments>{}</tool_arguments></tool_call>"
+		assert.Len(t, parser.ParseXMLToolCalls(strings.Repeat(call, 1000)), maxXMLToolCalls)
+	})
+
+	t.Run("over_size_limit", func(t *testing.T) {
+		call := "<tool_call><tool_name>write</tool_name><tool_arguments>{\"content\":\"" +
+			strings.Repeat("a", MaxXMLContentSize) + "\"}</tool_arguments></tool_call>"
+		assert.Empty(t, parser.ParseXMLToolCalls(call))
+	})
+}
+
+func FuzzXMLToolParser(f *testing.F) {
+	data, err := os.ReadFile("../../test/data/tool-calls.json")
+	require.NoError(f, err)
+	var testCases []TestCase
+	require.NoError(f, json.Unmarshal(data, &testCases))
+	for _, tc := range testCases {
+		f.Add(tc.ModelOutputXML)
+	}
+	f.Add("<tool_call><tool_call><a:tool_call></tool_call")
+	f.Add("<function=a><parameter=x><parameter=y></parameter")
+
+	parser := NewXMLToolParser(false)
+	f.Fuzz(func(t *testing.T, content string) {
+		toolCalls := parser.ParseXMLToolCalls(content)
+		assert.LessOrEqual(t, len(toolCalls), maxXMLToolCalls)
+		for _, toolCall := range toolCalls {
+			assert.True(t, json.Valid([]byte(toolCall.Function.Arguments)), "arguments %q of %q", toolCall.Function.Arguments, content)
+		}
+	})
+}
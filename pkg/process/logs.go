@@ -0,0 +1,57 @@
+package process
+
+import (
+	"strings"
+	"sync"
+)
+
+// logTailLines is the number of output lines kept for VLLMLogTail
+const logTailLines = 1000
+
+// logTail keeps the last lines written by the vLLM process, the way the container runtime keeps
+// its logs
+type logTail struct {
+	mu      sync.Mutex
+	lines   []string
+	max     int
+	partial string // Last line, until its newline is written
+}
+
+func newLogTail(max int) *logTail {
+	return &logTail{max: max}
+}
+
+// Write stores the complete lines of p, shared by stdout and stderr
+func (l *logTail) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	text := l.partial + string(p)
+	lines := strings.Split(text, "\n")
+	l.partial = lines[len(lines)-1]
+	l.lines = append(l.lines, lines[:len(lines)-1]...)
+	if len(l.lines) > l.max {
+		l.lines = append(l.lines[:0], l.lines[len(l.lines)-l.max:]...)
+	}
+	return len(p), nil
+}
+
+// tail returns the last n lines, including an unterminated one
+func (l *logTail) tail(n int) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lines := l.lines
+	if l.partial != "" {
+		lines = append(lines[:len(lines):len(lines)], l.partial)
+	}
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reset drops the output of a previous process
+func (l *logTail) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines, l.partial = nil, ""
+}
@@ -0,0 +1,336 @@
+// Package process runs the vLLM server as a local process instead of a Kubernetes pod, for
+// bare-metal hosts with vLLM installed in a Python environment: a child process of the proxy, or
+// a transient systemd user unit that outlives proxy restarts.
+package process
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+const (
+	// SpecHashEnv holds the hash of the command line a vLLM process was started with, so drift is
+	// detected like for pods
+	SpecHashEnv = "VLLM_CHILL_SPEC_HASH"
+
+	// DefaultPython is the interpreter vLLM is started with when none is configured
+	DefaultPython = "python3"
+
+	vllmModule    = "vllm.entrypoints.openai.api_server"
+	healthTimeout = 5 * time.Second
+)
+
+// Config holds the settings of the vLLM process
+type Config struct {
+	Python       string        // Interpreter vLLM is installed for, e.g. a virtualenv's bin/python (defaults to python3)
+	Port         int           // Port vLLM listens on, on 127.0.0.1
+	GPUCount     int           // Tensor parallel size
+	CPUOffloadGB int           // CPU offload in GB
+	SleepMode    bool          // Start vLLM with sleep mode
+	StopTimeout  time.Duration // Time vLLM gets to drain on SIGTERM before being killed
+	Env          []string      // Variables added to the proxy's environment, as NAME=value
+	SystemdUnit  string        // Run vLLM as this transient systemd user unit instead of a child process
+}
+
+// commandRunner runs a command and returns its standard output, replaced in tests
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// Runner drives the vLLM process of the active model, the way K8sManager drives its pod
+type Runner struct {
+	config *Config
+	health *http.Client
+	run    commandRunner // Runs systemd-run, systemctl and journalctl in systemd mode
+
+	mu    sync.Mutex
+	child *exec.Cmd     // Child process, nil when none was started
+	done  chan struct{} // Closed once the child process exited
+	hash  string        // Spec hash of the child process
+	args  []string      // Arguments of the child process
+	logs  *logTail      // Output of the child process
+}
+
+// NewRunner creates a runner for the process described by config
+func NewRunner(config *Config) *Runner {
+	if config.Python == "" {
+		config.Python = DefaultPython
+	}
+	return &Runner{
+		config: config,
+		health: &http.Client{Timeout: healthTimeout},
+		run:    runCommand,
+		logs:   newLogTail(logTailLines),
+	}
+}
+
+// runCommand runs a command, its standard error in the error when it fails
+func runCommand(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return out, fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// vllmArgs returns the vLLM arguments of the model's pod, listening on 127.0.0.1 of the host
+// without an API key
+func (r *Runner) vllmArgs(modelConfig *kubernetes.ModelConfig) []string {
+	podArgs := kubernetes.VLLMArgs(&kubernetes.Config{
+		GPUCount:     r.config.GPUCount,
+		CPUOffloadGB: r.config.CPUOffloadGB,
+		SleepMode:    r.config.SleepMode,
+	}, modelConfig)
+
+	args := make([]string, 0, len(podArgs))
+	for i := 0; i < len(podArgs); i++ {
+		switch podArgs[i] {
+		case "--api-key":
+			i++
+		case "--host":
+			args = append(args, "--host", "127.0.0.1")
+			i++
+		case "--port":
+			args = append(args, "--port", strconv.Itoa(r.config.Port))
+			i++
+		default:
+			args = append(args, podArgs[i])
+		}
+	}
+	return args
+}
+
+// env returns the variables vLLM is started with for the model, on top of the proxy's environment
+func (r *Runner) env(modelConfig *kubernetes.ModelConfig) []string {
+	var env []string
+	if modelConfig.LoRAAdapter != "" {
+		env = append(env, "VLLM_ALLOW_RUNTIME_LORA_UPDATING=True")
+	}
+	if r.config.SleepMode {
+		env = append(env, "VLLM_SERVER_DEV_MODE=1")
+	}
+	if modelConfig.PodTemplate != nil {
+		// Variables read from Secrets or fields only exist in pods
+		for _, e := range modelConfig.PodTemplate.Env {
+			if e.ValueFrom == nil {
+				env = append(env, e.Name+"="+e.Value)
+			}
+		}
+	}
+	return append(env, r.config.Env...)
+}
+
+// SpecHash returns the hash of the command line and environment built for the model
+func (r *Runner) SpecHash(modelConfig *kubernetes.ModelConfig) string {
+	spec := append([]string{r.config.Python}, r.vllmArgs(modelConfig)...)
+	spec = append(spec, r.env(modelConfig)...)
+	sum := sha256.Sum256([]byte(strings.Join(spec, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// CreatePod starts vLLM for the model
+func (r *Runner) CreatePod(ctx context.Context, modelConfig *kubernetes.ModelConfig) error {
+	args := r.vllmArgs(modelConfig)
+	env := append(r.env(modelConfig), SpecHashEnv+"="+r.SpecHash(modelConfig))
+	if r.config.SystemdUnit != "" {
+		return r.startUnit(ctx, args, env)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.running() {
+		return fmt.Errorf("vLLM process %d is already running", r.child.Process.Pid)
+	}
+
+	// Not bound to ctx, vLLM outlives the request that started it
+	child := exec.Command(r.config.Python, append([]string{"-m", vllmModule}, args...)...)
+	child.Env = append(os.Environ(), env...)
+	r.logs.reset()
+	child.Stdout, child.Stderr = r.logs, r.logs
+	if err := child.Start(); err != nil {
+		return fmt.Errorf("failed to start vLLM: %w", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		err := child.Wait()
+		log.Printf("vLLM process %d exited: %v", child.Process.Pid, err)
+		close(done)
+	}()
+	r.child, r.done, r.args = child, done, args
+	r.hash = r.SpecHash(modelConfig)
+	log.Printf("Started vLLM process %d: %s -m %s %s", child.Process.Pid, r.config.Python, vllmModule, strings.Join(args, " "))
+	return nil
+}
+
+// startUnit starts vLLM as a transient systemd user unit, removed once it stops
+func (r *Runner) startUnit(ctx context.Context, args, env []string) error {
+	command := []string{"--user", "--unit=" + r.config.SystemdUnit, "--collect",
+		"--property=TimeoutStopSec=" + strconv.Itoa(int(r.config.StopTimeout/time.Second))}
+	for _, e := range env {
+		command = append(command, "--setenv="+e)
+	}
+	command = append(command, "--", r.config.Python, "-m", vllmModule)
+	if _, err := r.run(ctx, "systemd-run", append(command, args...)...); err != nil {
+		return fmt.Errorf("failed to start unit %s: %w", r.config.SystemdUnit, err)
+	}
+	log.Printf("Started vLLM unit %s", r.config.SystemdUnit)
+	return nil
+}
+
+// running reports whether the child process was started and hasn't exited, with mu held
+func (r *Runner) running() bool {
+	if r.child == nil {
+		return false
+	}
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// DeletePod stops vLLM with SIGTERM, killing it once the stop timeout has elapsed
+func (r *Runner) DeletePod(ctx context.Context) error {
+	if r.config.SystemdUnit != "" {
+		state, err := r.unitState(ctx)
+		if err != nil || state == "inactive" {
+			return err
+		}
+		// systemd sends SIGKILL after TimeoutStopSec
+		if _, err := r.run(ctx, "systemctl", "--user", "stop", r.config.SystemdUnit); err != nil {
+			return fmt.Errorf("failed to stop unit %s: %w", r.config.SystemdUnit, err)
+		}
+		log.Printf("Stopped vLLM unit %s", r.config.SystemdUnit)
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.running() {
+		return nil
+	}
+	pid := r.child.Process.Pid
+	if err := r.child.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("failed to stop vLLM process %d: %w", pid, err)
+	}
+	select {
+	case <-r.done:
+	case <-time.After(r.config.StopTimeout):
+		log.Printf("vLLM process %d still running after %v, killing it", pid, r.config.StopTimeout)
+		_ = r.child.Process.Kill()
+		<-r.done
+	}
+	log.Printf("Stopped vLLM process %d", pid)
+	return nil
+}
+
+// unitState returns the ActiveState of the systemd unit: active, activating, deactivating,
+// failed, or inactive when it isn't loaded
+func (r *Runner) unitState(ctx context.Context) (string, error) {
+	out, err := r.run(ctx, "systemctl", "--user", "show", r.config.SystemdUnit, "--property=ActiveState", "--value")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the state of unit %s: %w", r.config.SystemdUnit, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// PodExists checks if vLLM is running or starting
+func (r *Runner) PodExists(ctx context.Context) (bool, error) {
+	if r.config.SystemdUnit != "" {
+		state, err := r.unitState(ctx)
+		if err != nil {
+			return false, err
+		}
+		return state == "active" || state == "activating" || state == "reloading", nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running(), nil
+}
+
+// IsPodReady checks if vLLM is running and answers on /health
+func (r *Runner) IsPodReady(ctx context.Context) (bool, error) {
+	exists, err := r.PodExists(ctx)
+	if err != nil || !exists {
+		return false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://127.0.0.1:%d/health", r.config.Port), nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := r.health.Do(req)
+	if err != nil {
+		return false, nil // Not listening yet
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode == http.StatusOK, nil
+}
+
+// VLLMLogTail returns the last lines of the vLLM output
+func (r *Runner) VLLMLogTail(ctx context.Context, lines int64) (string, error) {
+	if r.config.SystemdUnit != "" {
+		out, err := r.run(ctx, "journalctl", "--user", "--unit="+r.config.SystemdUnit, "--lines="+strconv.FormatInt(lines, 10), "--no-pager", "--output=cat")
+		if err != nil {
+			return "", fmt.Errorf("failed to get vLLM logs: %w", err)
+		}
+		return string(out), nil
+	}
+	return r.logs.tail(int(lines)), nil
+}
+
+// ConfigDrift lists the differences between the running vLLM process and the command line built
+// for the model, when their spec hashes differ
+func (r *Runner) ConfigDrift(ctx context.Context, modelConfig *kubernetes.ModelConfig) ([]kubernetes.Drift, error) {
+	expectedHash := r.SpecHash(modelConfig)
+	if r.config.SystemdUnit != "" {
+		state, err := r.unitState(ctx)
+		if err != nil || (state != "active" && state != "activating") {
+			return nil, err // No unit means no drift
+		}
+		out, err := r.run(ctx, "systemctl", "--user", "show", r.config.SystemdUnit, "--property=Environment", "--value")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the environment of unit %s: %w", r.config.SystemdUnit, err)
+		}
+		var actualHash string
+		for _, e := range strings.Fields(string(out)) {
+			if value, ok := strings.CutPrefix(e, SpecHashEnv+"="); ok {
+				actualHash = value
+			}
+		}
+		if actualHash == expectedHash {
+			return nil, nil
+		}
+		return []kubernetes.Drift{{Field: "spec", Change: fmt.Sprintf("%q -> %q", actualHash, expectedHash)}}, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.running() || r.hash == expectedHash {
+		return nil, nil
+	}
+	actualArgs, expectedArgs := strings.Join(r.args, " "), strings.Join(r.vllmArgs(modelConfig), " ")
+	if actualArgs != expectedArgs {
+		return []kubernetes.Drift{{Field: "args", Change: fmt.Sprintf("%q -> %q", actualArgs, expectedArgs)}}, nil
+	}
+	return []kubernetes.Drift{{Field: "spec", Change: fmt.Sprintf("%q -> %q", r.hash, expectedHash)}}, nil
+}
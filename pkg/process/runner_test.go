@@ -0,0 +1,205 @@
+package process
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testModel() *kubernetes.ModelConfig {
+	return &kubernetes.ModelConfig{
+		ModelName:            "Qwen/Qwen3-8B",
+		ServedModelName:      "qwen3-8b",
+		ToolCallParser:       "hermes",
+		MaxModelLen:          "32768",
+		GPUMemoryUtilization: "0.9",
+		MaxNumBatchedTokens:  "8192",
+		MaxNumSeqs:           "16",
+		Dtype:                "auto",
+	}
+}
+
+// fakePython writes an interpreter printing its arguments and environment then running script
+func fakePython(t *testing.T, script string) string {
+	path := filepath.Join(t.TempDir(), "python")
+	content := "#!/bin/sh\necho \"args: $*\"\necho \"hash: $" + SpecHashEnv + "\"\n" + script + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o755))
+	return path
+}
+
+// healthServer answers /health with status on a local port
+func healthServer(t *testing.T, status *int) int {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(*status)
+	}))
+	t.Cleanup(server.Close)
+	return server.Listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestRunner_VLLMArgs(t *testing.T) {
+	runner := NewRunner(&Config{Port: 8123, GPUCount: 2})
+	args := strings.Join(runner.vllmArgs(testModel()), " ")
+	assert.Contains(t, args, "--model Qwen/Qwen3-8B --served-model-name qwen3-8b --tensor-parallel-size 2")
+	assert.Contains(t, args, "--host 127.0.0.1 --port 8123")
+	assert.NotContains(t, args, "--api-key", "vLLM only listens on localhost")
+	assert.Equal(t, DefaultPython, runner.config.Python)
+}
+
+func TestRunner_ChildProcess(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	runner := NewRunner(&Config{
+		Python:      fakePython(t, "exec sleep 30"),
+		Port:        healthServer(t, &status),
+		GPUCount:    1,
+		StopTimeout: 5 * time.Second,
+	})
+	ctx := context.Background()
+
+	exists, err := runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	assert.Error(t, runner.CreatePod(ctx, testModel()), "a single vLLM process runs at a time")
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Ready once vLLM answers on /health
+	ready, err := runner.IsPodReady(ctx)
+	require.NoError(t, err)
+	assert.False(t, ready)
+	status = http.StatusOK
+	ready, err = runner.IsPodReady(ctx)
+	require.NoError(t, err)
+	assert.True(t, ready)
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(runner.logs.tail(10), "hash: "+runner.SpecHash(testModel()))
+	}, 5*time.Second, 10*time.Millisecond)
+	logs, err := runner.VLLMLogTail(ctx, 10)
+	require.NoError(t, err)
+	assert.Contains(t, logs, "args: -m vllm.entrypoints.openai.api_server --model Qwen/Qwen3-8B")
+
+	// Drift only while running
+	drifts, err := runner.ConfigDrift(ctx, testModel())
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+	changed := testModel()
+	changed.MaxModelLen = "65536"
+	drifts, err = runner.ConfigDrift(ctx, changed)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, "args", drifts[0].Field)
+
+	require.NoError(t, runner.DeletePod(ctx))
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, runner.DeletePod(ctx), "stopping a stopped process succeeds")
+	drifts, err = runner.ConfigDrift(ctx, changed)
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+}
+
+func TestRunner_ChildProcessKilled(t *testing.T) {
+	runner := NewRunner(&Config{
+		Python:      fakePython(t, "trap '' TERM\nwhile :; do sleep 0.1; done"),
+		StopTimeout: 200 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	require.Eventually(t, func() bool {
+		return strings.Contains(runner.logs.tail(10), "hash:")
+	}, 5*time.Second, 10*time.Millisecond, "the trap is set")
+	require.NoError(t, runner.DeletePod(ctx))
+	exists, err := runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestRunner_SystemdUnit(t *testing.T) {
+	state, environment := "inactive", ""
+	var commands []string
+	runner := NewRunner(&Config{Port: 8000, StopTimeout: 30 * time.Second, SystemdUnit: "vllm"})
+	runner.run = func(_ context.Context, name string, args ...string) ([]byte, error) {
+		command := name + " " + strings.Join(args, " ")
+		commands = append(commands, command)
+		switch {
+		case name == "systemd-run":
+			state = "activating"
+			for _, arg := range args {
+				if env, ok := strings.CutPrefix(arg, "--setenv="); ok {
+					environment += env + " "
+				}
+			}
+		case strings.HasPrefix(command, "systemctl --user stop"):
+			state, environment = "inactive", ""
+		case strings.Contains(command, "--property=ActiveState"):
+			return []byte(state + "\n"), nil
+		case strings.Contains(command, "--property=Environment"):
+			return []byte(environment + "\n"), nil
+		case name == "journalctl":
+			return []byte("INFO Loading weights\n"), nil
+		}
+		return nil, nil
+	}
+	ctx := context.Background()
+
+	exists, err := runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	require.NoError(t, runner.DeletePod(ctx), "stopping an inactive unit succeeds")
+	assert.NotContains(t, strings.Join(commands, "\n"), "systemctl --user stop")
+
+	require.NoError(t, runner.CreatePod(ctx, testModel()))
+	start := commands[len(commands)-1]
+	assert.Contains(t, start, "systemd-run --user --unit=vllm --collect --property=TimeoutStopSec=30")
+	assert.Contains(t, start, "-- python3 -m vllm.entrypoints.openai.api_server --model Qwen/Qwen3-8B")
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	drifts, err := runner.ConfigDrift(ctx, testModel())
+	require.NoError(t, err)
+	assert.Empty(t, drifts)
+	changed := testModel()
+	changed.MaxModelLen = "65536"
+	drifts, err = runner.ConfigDrift(ctx, changed)
+	require.NoError(t, err)
+	require.Len(t, drifts, 1)
+	assert.Equal(t, "spec", drifts[0].Field)
+
+	logs, err := runner.VLLMLogTail(ctx, 20)
+	require.NoError(t, err)
+	assert.Equal(t, "INFO Loading weights\n", logs)
+	assert.Contains(t, commands[len(commands)-1], "journalctl --user --unit=vllm --lines=20")
+
+	require.NoError(t, runner.DeletePod(ctx))
+	assert.Contains(t, commands[len(commands)-1], "systemctl --user stop vllm")
+	exists, err = runner.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLogTail(t *testing.T) {
+	logs := newLogTail(2)
+	_, _ = logs.Write([]byte("one\ntwo\nthr"))
+	_, _ = logs.Write([]byte("ee\nfour"))
+	assert.Equal(t, "two\nthree\nfour", logs.tail(0), "the last two complete lines and the current one")
+	assert.Equal(t, "three\nfour", logs.tail(2))
+	assert.Equal(t, "four", logs.tail(1))
+	logs.reset()
+	assert.Empty(t, logs.tail(10))
+}
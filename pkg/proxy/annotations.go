@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// annotationField is the extension key added to non-streaming OpenAI responses
+const annotationField = "vllm_chill"
+
+// requestAnnotation records the autoscaler's impact on a single request
+type requestAnnotation struct {
+	ColdStart     bool  `json:"cold_start"`
+	StartupMs     int64 `json:"startup_ms"`
+	ModelSwitched bool  `json:"model_switched"`
+
+	Debug *requestDebug `json:"debug,omitempty"` // Report of requests sent with X-Chill-Debug
+}
+
+// annotatingWriter buffers non-streaming JSON responses so the request annotation can be
+// added to the body. Streaming and non-JSON responses are passed through untouched.
+type annotatingWriter struct {
+	http.ResponseWriter
+	annotation  requestAnnotation
+	wroteHeader bool
+	buffering   bool
+	statusCode  int
+	body        bytes.Buffer
+}
+
+// newAnnotatingWriter wraps w to add the annotation to JSON responses
+func newAnnotatingWriter(w http.ResponseWriter, annotation requestAnnotation) *annotatingWriter {
+	return &annotatingWriter{
+		ResponseWriter: w,
+		annotation:     annotation,
+		statusCode:     http.StatusOK,
+	}
+}
+
+// WriteHeader decides whether the response is buffered for annotation
+func (aw *annotatingWriter) WriteHeader(code int) {
+	if aw.wroteHeader {
+		return
+	}
+	aw.wroteHeader = true
+
+	header := aw.Header()
+	aw.buffering = code == http.StatusOK &&
+		strings.HasPrefix(header.Get("Content-Type"), "application/json") &&
+		header.Get("Content-Encoding") == ""
+	if aw.buffering {
+		aw.statusCode = code
+		// Body size changes once annotated
+		header.Del("Content-Length")
+		return
+	}
+	aw.ResponseWriter.WriteHeader(code)
+}
+
+// Write buffers JSON responses and passes everything else through
+func (aw *annotatingWriter) Write(b []byte) (int, error) {
+	if !aw.wroteHeader {
+		aw.WriteHeader(http.StatusOK)
+	}
+	if aw.buffering {
+		return aw.body.Write(b)
+	}
+	return aw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher, buffered responses are only flushed by finish
+func (aw *annotatingWriter) Flush() {
+	if aw.buffering {
+		return
+	}
+	if flusher, ok := aw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the buffered response with the annotation added.
+// Bodies that are not JSON objects are written unchanged.
+func (aw *annotatingWriter) finish() error {
+	if !aw.buffering {
+		return nil
+	}
+
+	out := aw.body.Bytes()
+	var payload map[string]interface{}
+	if err := json.Unmarshal(out, &payload); err == nil {
+		payload[annotationField] = aw.annotation
+		if annotated, err := json.Marshal(payload); err == nil {
+			out = annotated
+		}
+	}
+
+	aw.ResponseWriter.WriteHeader(aw.statusCode)
+	_, err := aw.ResponseWriter.Write(out)
+	return err
+}
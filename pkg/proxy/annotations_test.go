@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotatingWriter_AnnotatesJSON(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	aw := newAnnotatingWriter(recorder, requestAnnotation{ColdStart: true, StartupMs: 83000, ModelSwitched: true})
+
+	body := `{"id":"chatcmpl-1","object":"chat.completion","choices":[]}`
+	aw.Header().Set("Content-Type", "application/json")
+	aw.Header().Set("Content-Length", "59")
+	aw.WriteHeader(http.StatusOK)
+	_, err := aw.Write([]byte(body))
+	require.NoError(t, err)
+
+	// Nothing is sent until finish
+	assert.Empty(t, recorder.Body.String())
+	require.NoError(t, aw.finish())
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Content-Length"))
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "chatcmpl-1", response["id"])
+	assert.Equal(t, map[string]interface{}{
+		"cold_start":     true,
+		"startup_ms":     float64(83000),
+		"model_switched": true,
+	}, response[annotationField])
+}
+
+func TestAnnotatingWriter_PassesThroughStreaming(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	aw := newAnnotatingWriter(recorder, requestAnnotation{ColdStart: true})
+
+	chunk := "data: {\"choices\":[]}\n\n"
+	aw.Header().Set("Content-Type", "text/event-stream")
+	_, err := aw.Write([]byte(chunk))
+	require.NoError(t, err)
+	aw.Flush()
+
+	assert.Equal(t, chunk, recorder.Body.String())
+	require.NoError(t, aw.finish())
+	assert.Equal(t, chunk, recorder.Body.String())
+}
+
+func TestAnnotatingWriter_PassesThroughErrors(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	aw := newAnnotatingWriter(recorder, requestAnnotation{ColdStart: true})
+
+	body := `{"error":{"message":"bad request"}}`
+	aw.Header().Set("Content-Type", "application/json")
+	aw.WriteHeader(http.StatusBadRequest)
+	_, err := aw.Write([]byte(body))
+	require.NoError(t, err)
+	require.NoError(t, aw.finish())
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, body, recorder.Body.String())
+}
+
+func TestAnnotatingWriter_KeepsNonObjectJSON(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	aw := newAnnotatingWriter(recorder, requestAnnotation{})
+
+	aw.Header().Set("Content-Type", "application/json")
+	_, err := aw.Write([]byte(`[1,2,3]`))
+	require.NoError(t, err)
+	require.NoError(t, aw.finish())
+
+	assert.Equal(t, `[1,2,3]`, recorder.Body.String())
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// apiRequest reports whether the API middlewares (authentication, rate limits, session budgets,
+// usage accounting and the audit log) apply to a request: those to /v1 and to the passthrough
+// paths, probes aside
+func (as *AutoScaler) apiRequest(r *http.Request) bool {
+	if r.Method == http.MethodHead || r.Method == http.MethodOptions {
+		return false
+	}
+	return strings.HasPrefix(r.URL.Path, "/v1/") || as.config.passthroughPath(r.URL.Path)
+}
+
+// authMiddleware rejects /v1 and passthrough requests whose API key is not in the Secret, before
+// anything can wake the model, and attaches the key's tenant to the request
+func (as *AutoScaler) authMiddleware(c *gin.Context) {
+	r := c.Request
+	if !as.apiRequest(r) {
+		c.Next()
+		return
+	}
+
+	tenant, ok := as.apiKeys.Lookup(auth.KeyFromRequest(r))
+	if !ok {
+		log.Printf("Rejected %s %s: unknown API key", r.Method, r.URL.Path)
+		writeAPIError(c.Writer, http.StatusUnauthorized, "Incorrect API key provided.", "invalid_request_error", "invalid_api_key")
+		as.metrics.RecordRequest(r.Method, r.URL.Path, http.StatusUnauthorized, 0, 0, int64(c.Writer.Size()))
+		c.Abort()
+		return
+	}
+
+	c.Request = r.WithContext(auth.WithTenant(r.Context(), tenant))
+	c.Next()
+}
+
+// writeAPIError sends an OpenAI-compatible error rejecting a client request
+func writeAPIError(w http.ResponseWriter, status int, message, errorType, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": message,
+			"type":    errorType,
+			"code":    code,
+		},
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// allowModel rejects the request with a 403 when its tenant may not use the requested model,
+// checked under every name the client could have used
+func allowModel(w http.ResponseWriter, r *http.Request, names ...string) bool {
+	tenant := auth.TenantFrom(r.Context())
+	if tenant == nil || tenant.AllowsModel(names...) {
+		return true
+	}
+	log.Printf("Rejected %s %s: tenant %s may not use model %s", r.Method, r.URL.Path, tenant.Name, names[0])
+	writeAPIError(w, http.StatusForbidden,
+		fmt.Sprintf("The model %s is not available to this API key.", names[0]), "permission_error", "model_not_allowed")
+	return false
+}
+
+// loadAPIKeys reads the API key Secret and applies the tenants' quotas to the rate limiter
+func (as *AutoScaler) loadAPIKeys(ctx context.Context) error {
+	data, err := as.k8sManager.GetSecretData(ctx, as.config.APIKeysSecret)
+	if err != nil {
+		return fmt.Errorf("failed to read API key Secret %s: %w", as.config.APIKeysSecret, err)
+	}
+	if err := as.apiKeys.Load(data); err != nil {
+		return fmt.Errorf("invalid API key Secret %s: %w", as.config.APIKeysSecret, err)
+	}
+
+	quotas := make(map[string]rateLimits)
+	for digest, tenant := range as.apiKeys.Quotas() {
+		quotas[digest] = rateLimits{RPM: tenant.RPM, TPM: tenant.TPM}
+	}
+	as.rateLimiter.setQuotas(quotas)
+	return nil
+}
+
+// startAPIKeyReload periodically reloads the API key Secret, keeping the current keys on errors
+func (as *AutoScaler) startAPIKeyReload(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := as.loadAPIKeys(ctx); err != nil {
+				log.Printf("Keeping the current API keys: %v", err)
+			}
+		}
+	}
+}
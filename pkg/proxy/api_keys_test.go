@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// newAPIKeyTestAutoScaler loads the tenants from a fake Secret
+func newAPIKeyTestAutoScaler(t *testing.T, tenants map[string][]byte) *AutoScaler {
+	t.Helper()
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-api-keys", Namespace: "vllm"},
+		Data:       tenants,
+	})
+	as := &AutoScaler{
+		config:       &Config{Namespace: "vllm", APIKeysSecret: "vllm-api-keys"},
+		k8sManager:   kubernetes.NewK8sManager(clientset, &kubernetes.Config{Namespace: "vllm"}),
+		metrics:      stats.NewMetricsRecorder(),
+		apiKeys:      auth.NewKeyStore(),
+		rateLimiter:  newRateLimiter(rateLimits{}),
+		lastActivity: time.Now().Add(-time.Hour),
+	}
+	require.NoError(t, as.loadAPIKeys(context.Background()))
+	return as
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	as := newAPIKeyTestAutoScaler(t, map[string][]byte{
+		"team-a": []byte(`{"key":"sk-a","models":["qwen"]}`),
+	})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.authMiddleware)
+	var tenant *auth.Tenant
+	router.NoRoute(func(c *gin.Context) {
+		tenant = auth.TenantFrom(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/v1/chat/completions", nil)
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send(http.MethodPost, "sk-unknown")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_api_key"`)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "").Code)
+	assert.Nil(t, tenant)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodOptions, "").Code, "CORS preflights carry no key")
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "sk-a").Code)
+	require.NotNil(t, tenant)
+	assert.Equal(t, "team-a", tenant.Name)
+}
+
+func TestProxyHandler_RejectsModelsOutsideTheTenant(t *testing.T) {
+	as := newAPIKeyTestAutoScaler(t, map[string][]byte{
+		"team-a": []byte(`{"key":"sk-a","models":["llama"]}`),
+	})
+	as.config.MaxRequestBodySize = "1Mi"
+	as.activeModel = "qwen"
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(0, 1))
+	lastActivity := as.lastActivity
+
+	tenant, ok := as.apiKeys.Lookup("sk-a")
+	require.True(t, ok)
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8"}`))
+	r = r.WithContext(auth.WithTenant(r.Context(), tenant))
+	w := httptest.NewRecorder()
+
+	as.proxyHandler(w, r)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"model_not_allowed"`)
+	assert.Equal(t, lastActivity, as.lastActivity, "rejected requests do not wake the model")
+}
+
+func TestLoadAPIKeys_AppliesQuotas(t *testing.T) {
+	as := newAPIKeyTestAutoScaler(t, map[string][]byte{
+		"team-a": []byte(`{"key":"sk-a","rpm":1}`),
+		"team-b": []byte(`{"key":"sk-b"}`),
+	})
+
+	assert.Empty(t, as.rateLimiter.allow(auth.Digest("sk-a")).exceeded)
+	assert.Equal(t, "requests", as.rateLimiter.allow(auth.Digest("sk-a")).exceeded)
+	assert.Empty(t, as.rateLimiter.allow(auth.Digest("sk-b")).exceeded)
+	assert.Empty(t, as.rateLimiter.allow(auth.Digest("sk-b")).exceeded, "tenants without quota keep the global limits")
+}
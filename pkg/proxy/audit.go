@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"log"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/audit"
+	"github.com/gin-gonic/gin"
+)
+
+// maxAuditCapture bounds the bytes of a request or response body kept for the audit log, so
+// whole bodies of usual size are redacted without holding long streams in memory
+const maxAuditCapture = 1 << 20
+
+// newAuditLogger creates the audit logger of the configured sink, nil when none is configured
+func newAuditLogger(config *Config) (*audit.Logger, error) {
+	if config.AuditLog == "" {
+		return nil, nil
+	}
+	return audit.New(config.AuditLog, int(config.GetAuditBodySize()), audit.ParseFields(config.AuditRedactFields))
+}
+
+// auditWriter keeps the start of the response body for the audit log
+type auditWriter struct {
+	gin.ResponseWriter
+	body []byte
+}
+
+func (aw *auditWriter) Write(b []byte) (int, error) {
+	aw.capture(b)
+	return aw.ResponseWriter.Write(b)
+}
+
+func (aw *auditWriter) WriteString(s string) (int, error) {
+	aw.capture([]byte(s))
+	return aw.ResponseWriter.WriteString(s)
+}
+
+// capture keeps the written bytes up to maxAuditCapture
+func (aw *auditWriter) capture(b []byte) {
+	if room := maxAuditCapture - len(aw.body); room > 0 {
+		aw.body = append(aw.body, b[:min(len(b), room)]...)
+	}
+}
+
+// auditMiddleware records each /v1 and passthrough request in the audit log once its response is
+// complete, rejected requests included: the client, the model that served it, its tokens and
+// latency, and its bodies when they are recorded
+func (as *AutoScaler) auditMiddleware(c *gin.Context) {
+	r := c.Request
+	if !as.apiRequest(r) {
+		c.Next()
+		return
+	}
+	started := time.Now()
+
+	var request []byte
+	var aw *auditWriter
+	if as.audit.Bodies() {
+		if r.Body != nil {
+			var err error
+			request, err = io.ReadAll(io.LimitReader(r.Body, maxAuditCapture))
+			if err != nil {
+				log.Printf("Failed to read the body of %s %s for the audit log: %v", r.Method, r.URL.Path, err)
+			}
+			r.Body = replayBody(request, r.Body)
+		}
+		aw = &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = aw
+	}
+
+	// The usage middleware fills the same account
+	account := &requestAccount{}
+	c.Request = r.WithContext(context.WithValue(r.Context(), requestAccountKey{}, account))
+	uw, done := trackUsage(c)
+	defer done()
+	c.Next()
+
+	// The later middlewares put the tenant, user and session in the request they pass on
+	r = c.Request
+	record := audit.Record{
+		Time:      started,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Status:    c.Writer.Status(),
+		Client:    usageKey(r),
+		User:      userFrom(r.Context()),
+		Session:   sessionID(r),
+		Model:     account.model,
+		LatencyMs: time.Since(started).Milliseconds(),
+	}
+	if account.model != "" {
+		record.PromptTokens, record.CompletionTokens = uw.split(r.ContentLength)
+	}
+	if aw != nil {
+		record.Request = as.audit.Body(redactBlobs(request))
+		record.Response = as.audit.Body(redactBlobs(aw.body))
+	}
+	as.audit.Log(record)
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/audit"
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	config := &Config{AuditLog: path, AuditBodySize: "1Ki", AuditRedactFields: "content"}
+	logger, err := newAuditLogger(config)
+	require.NoError(t, err)
+	as := &AutoScaler{config: config, audit: logger, usage: usage.NewTracker()}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.auditMiddleware)
+	router.Use(as.usageMiddleware)
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.NoRoute(func(c *gin.Context) {
+		if c.Request.URL.Path == "/v1/rejected" {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "rate limited"})
+			return
+		}
+		accountFrom(c.Request.Context()).serve("qwen", 2)
+		body, _ := io.ReadAll(c.Request.Body)
+		assert.Contains(t, string(body), "secret prompt", "the handler reads the whole body")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString(`{"content":[{"type":"text","text":"secret answer"}],"usage":{"input_tokens":10,"output_tokens":5}}`)
+	})
+
+	send := func(path string) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"model":"qwen","messages":[{"role":"user","content":"secret prompt"}]}`))
+		r.Header.Set("Authorization", "Bearer sk-a")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	send("/v1/messages")
+	send("/v1/rejected")
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	as.audit.Close(context.Background())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret", "redacted fields are never recorded")
+
+	var records []audit.Record
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		var record audit.Record
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2, "only /v1 requests are recorded")
+
+	assert.Equal(t, "/v1/messages", records[0].Path)
+	assert.Equal(t, http.StatusOK, records[0].Status)
+	assert.Equal(t, auth.Digest("sk-a"), records[0].Client)
+	assert.Equal(t, "qwen", records[0].Model)
+	assert.Equal(t, 10, records[0].PromptTokens)
+	assert.Equal(t, 5, records[0].CompletionTokens)
+	assert.Contains(t, records[0].Request, `"content":"[REDACTED]"`)
+	assert.Contains(t, records[0].Response, `"content":"[REDACTED]"`)
+
+	assert.Equal(t, http.StatusTooManyRequests, records[1].Status, "rejected requests are recorded")
+	assert.Empty(t, records[1].Model)
+	assert.Zero(t, records[1].PromptTokens)
+
+	assert.Equal(t, int64(1), as.usage.Summary().Total.Requests, "usage is accounted once")
+}
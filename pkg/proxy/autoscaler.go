@@ -3,25 +3,43 @@ package proxy
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"net/http/httputil"
 	"net/url"
-	"os"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/efortin/vllm-chill/pkg/admin"
+	"github.com/efortin/vllm-chill/pkg/apis/vllm/v1alpha1"
+	"github.com/efortin/vllm-chill/pkg/audit"
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/benchmark"
+	"github.com/efortin/vllm-chill/pkg/compare"
+	"github.com/efortin/vllm-chill/pkg/federation"
 	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/notify"
 	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/efortin/vllm-chill/pkg/tracing"
+	"github.com/efortin/vllm-chill/pkg/usage"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/dynamic"
 	k8sclient "k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 )
 
 const (
@@ -32,92 +50,305 @@ const (
 
 // AutoScaler manages automatic scaling of vLLM deployments
 type AutoScaler struct {
-	clientset    *k8sclient.Clientset
-	crdClient    *kubernetes.CRDClient
-	k8sManager   *kubernetes.K8sManager
-	config       *Config
-	targetURL    *url.URL
-	lastActivity time.Time
-	activeModel  string // Currently active model ID
-	mu           sync.RWMutex
-	isScalingUp  bool
-	scaleUpCond  *sync.Cond
-	metrics      *stats.MetricsRecorder
-	version      string
-	commit       string
-	buildDate    string
+	clientset      *k8sclient.Clientset
+	crdClient      *kubernetes.CRDClient
+	k8sManager     *kubernetes.K8sManager // nil with the docker and process backends
+	runner         vllmRunner             // Runs vLLM: k8sManager, or the local container or process
+	config         *Config
+	targetURL      *url.URL
+	lastActivity   time.Time
+	activeModel    string // Currently active model ID
+	mu             sync.RWMutex
+	isScalingUp    bool
+	scaleUpCond    *sync.Cond
+	metrics        *stats.MetricsRecorder
+	strategy       scaleStrategy        // How the model is released when idle and brought back
+	parserCheck    *toolParserDetector  // Flags models whose tool call output does not match the configured parser
+	modelOptions   sync.Map             // *modelOptions per served model name, read from the VLLMModel CRD
+	replicas       replicaPool          // In-flight requests and ready pods, for load-aware scaling
+	switches       modelSwitches        // Model switch in progress, draining the current model's requests
+	coldStarts     *coldStartGate       // Caps the models starting at once, nil when unlimited
+	embeddings     *embeddingBackend    // Embedding pod, nil when no embedding model is configured
+	shadow         *shadowBackend       // Shadow pod mirroring the completion traffic, nil when no shadow model is configured
+	namespaces     *namespaceBackends   // Pods of the tenant namespaces, nil when only the proxy's namespace is served
+	federation     *federation.Registry // Remote peers, nil when federation is disabled
+	leader         *leaderElector       // Lease election between proxy replicas, nil when disabled
+	modelStatus    *modelStatusWriter   // VLLMModel status updates, nil when disabled
+	notifier       *notify.Notifier     // Webhook notifications, nil when no webhook is configured
+	audit          *audit.Logger        // Audit trail of the /v1 requests, nil when no audit log is configured
+	vllmAPIKeys    sync.Map             // [namespace/]Secret name -> cachedAPIKey, the API keys sent to vLLM
+	batches        *messageBatches      // Anthropic Message Batches, nil when disabled
+	responses      *responseCache       // Responses of deterministic requests, nil when disabled
+	rateLimiter    *rateLimiter         // Per API key budgets, nil when rate limiting is disabled
+	userLabels     *userLabels          // Per-user metrics labels, nil when users are not tracked
+	apiKeys        *auth.KeyStore       // Allowed API keys, nil when key validation is disabled
+	sessions       *sessionBudgets      // Token usage per client session, nil when no session budget is set
+	affinity       *sessionAffinity     // Sessions keeping the active model, nil when session affinity is disabled
+	usage          *usage.Tracker       // Tokens and GPU time per model and per API key
+	draining       atomic.Bool          // Shutting down, /readyz reports not ready
+	gpuNotReady    atomic.Bool          // The vLLM pod waits for the GPU device plugin to register the GPUs
+	prewarming     atomic.Bool          // A warm-up window is starting the model
+	readySince     atomic.Int64         // Unix nanoseconds of the vLLM pod's last Ready transition, 0 until seen
+	vllmState      atomic.Int32         // vllm_chill_vllm_state value, reported by name in /proxy/status
+	startingAt     atomic.Int64         // Unix nanoseconds the pod creation in progress started, 0 when not starting
+	lastStartup    atomic.Int64         // Duration of the last vLLM startup, 0 until one is seen
+	startup        startupTracker       // Startup phase read from the vLLM log
+	recentModels   *recentModels        // Models kept in the page cache after scale-down, nil when disabled
+	prefetched     sync.Map             // Models whose download Job completed, or failed and left the download to vLLM
+	gpu            gpuSampler           // GPUs sampled by the idle checker, nil unless GPUBusyUtilization is set
+	gpuUnavailable bool                 // The last GPU sample failed, only used by the idle checker
+	vllmMetrics    vllmMetricsCache     // Last vLLM metrics scraped, served by /proxy/metrics while vLLM is down
+	version        string
+	commit         string
+	buildDate      string
+
+	// TLS with vLLM and on the proxy's port
+	vllmTransport http.RoundTripper               // Requests to vLLM, over TLS when the vLLM pods serve it (nil = http.DefaultTransport)
+	tlsCert       *certificateFiles               // Certificate served on the proxy's port, nil unless read from files
+	tlsSecretCert atomic.Pointer[tls.Certificate] // Certificate served on the proxy's port, nil unless read from a Secret
 }
 
 // NewAutoScaler creates a new AutoScaler instance
 func NewAutoScaler(config *Config) (*AutoScaler, error) {
+	config.ApplyDefaults()
 	if err := config.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
 
-	// In-cluster config
-	restConfig, err := rest.InClusterConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get in-cluster config: %w", err)
+	as := &AutoScaler{
+		config:       config,
+		lastActivity: time.Now(),
+		activeModel:  config.ModelID,
+		metrics:      stats.NewMetricsRecorder(),
+		usage:        usage.NewTracker(),
+		version:      "dev",
+		commit:       "none",
+		buildDate:    "unknown",
 	}
+	as.scaleUpCond = sync.NewCond(&as.mu)
 
-	clientset, err := k8sclient.NewForConfig(restConfig)
+	if config.localBackend() {
+		crdClient, runner, err := newLocalBackend(config)
+		if err != nil {
+			return nil, err
+		}
+		as.crdClient, as.runner = crdClient, runner
+		as.targetURL, err = config.localTargetURL()
+		if err != nil {
+			return nil, fmt.Errorf("invalid target URL: %w", err)
+		}
+		log.Printf("%s backend: vLLM listens on 127.0.0.1:%d, models read from %s", config.Backend, config.LocalPort, config.ModelsDir)
+	} else {
+		// In-cluster config, or the kubeconfig when running outside the cluster
+		restConfig, _, err := kubernetes.RESTConfig(config.Kubeconfig, config.KubeContext)
+		if err != nil {
+			return nil, err
+		}
+
+		as.clientset, err = k8sclient.NewForConfig(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create clientset: %w", err)
+		}
+
+		// Create dynamic client for CRD operations
+		dynamicClient, err := dynamic.NewForConfig(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+		}
+		as.crdClient = kubernetes.NewCRDClient(dynamicClient)
+		as.k8sManager = kubernetes.NewK8sManager(as.clientset, config.kubernetesConfig())
+		as.runner = as.k8sManager
+
+		// Chat model service
+		as.targetURL, err = config.targetURL(config.TargetHost)
+		if err != nil {
+			return nil, fmt.Errorf("invalid target URL: %w", err)
+		}
+	}
+
+	var err error
+	as.vllmTransport, err = as.newVLLMTransport(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create clientset: %w", err)
+		return nil, err
+	}
+	if config.VLLMTLSSecret != "" {
+		log.Printf("vLLM serves TLS with the certificate of Secret %s", config.VLLMTLSSecret)
 	}
 
-	// Create dynamic client for CRD operations
-	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	as.parserCheck = newToolParserDetector(as.metrics)
+	if config.GPUBusyUtilization > 0 {
+		as.gpu = stats.NewGPUStatsHandler()
+	}
+	if config.MaxConcurrentColdStarts > 0 {
+		as.coldStarts = newColdStartGate(config.MaxConcurrentColdStarts, as.metrics)
+	}
+	as.strategy, err = newScaleStrategy(as, config.ScaleStrategy)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+		return nil, err
 	}
+	log.Printf("Scale strategy: %s", as.strategy.name())
 
-	// Construct target URL from environment variables
-	targetHost := os.Getenv("VLLM_TARGET")
-	targetPort := os.Getenv("VLLM_PORT")
-	if targetHost == "" {
-		targetHost = "vllm"
+	// Register federation peers if configured
+	if config.FederationPeers != "" {
+		peers, err := federation.ParsePeers(config.FederationPeers)
+		if err != nil {
+			return nil, fmt.Errorf("invalid federation peers: %w", err)
+		}
+		as.federation, err = federation.NewRegistry(peers, config.federationTLS())
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure federation: %w", err)
+		}
+		log.Printf("Federation enabled with %d peer(s)", len(peers))
 	}
-	if targetPort == "" {
-		targetPort = "80"
+
+	// Only the elected replica manages the pods, the lease is named after the deployment
+	if config.LeaderElection {
+		as.leader = newLeaderElector(as.clientset, config.Namespace, config.Deployment+"-proxy-leader", config.AdvertiseURL, config.GetLeaderToken())
 	}
-	targetURL, err := url.Parse(fmt.Sprintf("http://%s:%s", targetHost, targetPort))
+
+	if config.ModelStatus {
+		as.modelStatus = newModelStatusWriter()
+	}
+
+	as.notifier, err = newNotifier(config)
 	if err != nil {
-		return nil, fmt.Errorf("invalid target URL: %w", err)
+		return nil, fmt.Errorf("failed to configure notifications: %w", err)
 	}
 
-	k8sManagerConfig := &kubernetes.Config{
-		Namespace:     config.Namespace,
-		Deployment:    config.Deployment,
-		ConfigMapName: config.ConfigMapName,
-		GPUCount:      config.GPUCount,
-		CPUOffloadGB:  config.CPUOffloadGB,
+	as.audit, err = newAuditLogger(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the audit log: %w", err)
 	}
 
-	as := &AutoScaler{
-		clientset:    clientset,
-		crdClient:    kubernetes.NewCRDClient(dynamicClient),
-		k8sManager:   kubernetes.NewK8sManager(clientset, k8sManagerConfig),
-		config:       config,
-		targetURL:    targetURL,
-		lastActivity: time.Now(),
-		activeModel:  config.ModelID,
-		metrics:      stats.NewMetricsRecorder(),
-		version:      "dev",
-		commit:       "none",
-		buildDate:    "unknown",
+	if config.MessageBatches {
+		as.batches = newMessageBatches(config.BatchConcurrency)
 	}
-	as.scaleUpCond = sync.NewCond(&as.mu)
 
-	// Ensure K8s resources exist with the configured model
+	if size := config.GetResponseCacheSize(); size > 0 {
+		as.responses = newResponseCache(size, config.GetResponseCacheTTL(), as.metrics)
+	}
+
+	if config.UserTracking {
+		as.userLabels = newUserLabels(config.MaxUserLabels)
+	}
+	if config.rateLimited() {
+		as.rateLimiter = newRateLimiter(rateLimits{RPM: config.RateLimitRPM, TPM: config.RateLimitTPM})
+	}
+
+	if config.SessionTokenBudget > 0 {
+		as.sessions = newSessionBudgets(config.SessionTokenBudget, config.GetSessionTTL())
+	}
+	if config.SessionAffinity {
+		as.affinity = newSessionAffinity(config.GetSessionAffinityIdle())
+	}
+
+	if config.PageCacheModels > 0 {
+		as.recentModels = newRecentModels(config.PageCacheModels)
+	}
+
+	// Load the allowed API keys, failing closed when the Secret cannot be read
 	ctx := context.Background()
+	if config.APIKeysSecret != "" {
+		as.apiKeys = auth.NewKeyStore()
+		if err := as.loadAPIKeys(ctx); err != nil {
+			return nil, err
+		}
+		log.Printf("Loaded %d API key(s) from Secret %s", as.apiKeys.Len(), config.APIKeysSecret)
+	}
+
+	// Load the certificate served on the proxy's port
+	switch {
+	case config.TLSCert != "":
+		as.tlsCert, err = newCertificateFiles(config.TLSCert, config.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Serving TLS with the certificate %s", config.TLSCert)
+	case config.TLSSecret != "":
+		if err := as.loadTLSSecret(ctx); err != nil {
+			return nil, err
+		}
+		log.Printf("Serving TLS with the certificate of Secret %s", config.TLSSecret)
+	}
+
+	// Resume the usage accounting, failing rather than overwriting it when it cannot be read
+	if config.UsageConfigMap != "" {
+		if err := as.restoreUsage(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Ensure K8s resources exist with the configured model
 	modelConfig, err := as.crdClient.GetModel(ctx, config.ModelID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get model '%s' from CRD: %w", config.ModelID, err)
 	}
-	if err := as.k8sManager.EnsureVLLMResources(ctx, modelConfig); err != nil {
-		return nil, fmt.Errorf("failed to ensure vLLM resources: %w", err)
+	if as.k8sManager != nil {
+		if err := as.k8sManager.EnsureVLLMResources(ctx, modelConfig); err != nil {
+			return nil, fmt.Errorf("failed to ensure vLLM resources: %w", err)
+		}
 	}
 	log.Printf("Loaded model configuration: %s", config.ModelID)
+	if as.recentModels != nil {
+		as.recentModels.touch(modelConfig.ModelName)
+	}
+
+	// Set up the embedding pod alongside the chat pod if configured
+	if config.EmbeddingModelID != "" {
+		embeddingModel, err := as.crdClient.GetModel(ctx, config.EmbeddingModelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get embedding model '%s' from CRD: %w", config.EmbeddingModelID, err)
+		}
+		embeddingManager := kubernetes.NewK8sManager(as.clientset, config.embeddingKubernetesConfig())
+		if err := embeddingManager.EnsureVLLMResources(ctx, embeddingModel); err != nil {
+			return nil, fmt.Errorf("failed to ensure embedding resources: %w", err)
+		}
+
+		embeddingURL, err := config.targetURL(config.EmbeddingTargetHost)
+		if err != nil {
+			return nil, fmt.Errorf("invalid embedding target URL: %w", err)
+		}
+		as.embeddings = newEmbeddingBackend(embeddingManager, as.crdClient, config.EmbeddingModelID, embeddingURL)
+		as.embeddings.coldStarts = as.coldStarts
+		as.embeddings.leader = as.leader
+		as.embeddings.transport = as.vllmTransport
+		as.embeddings.apiKey = func(ctx context.Context) string {
+			return as.vllmAPIKey(ctx, config.EmbeddingModelID)
+		}
+		log.Printf("Loaded embedding model configuration: %s", config.EmbeddingModelID)
+	}
+
+	// Set up the shadow pod mirroring the completion traffic if configured
+	if config.ShadowModelID != "" {
+		shadowModel, err := as.crdClient.GetModel(ctx, config.ShadowModelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get shadow model '%s' from CRD: %w", config.ShadowModelID, err)
+		}
+		shadowManager := kubernetes.NewK8sManager(as.clientset, config.shadowKubernetesConfig())
+		if err := shadowManager.EnsureVLLMResources(ctx, shadowModel); err != nil {
+			return nil, fmt.Errorf("failed to ensure shadow resources: %w", err)
+		}
+
+		shadowURL, err := config.targetURL(config.ShadowTargetHost)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shadow target URL: %w", err)
+		}
+		as.shadow = newShadowBackend(shadowManager, as.crdClient, config.ShadowModelID, shadowURL,
+			config.ShadowSamplePercent, config.ShadowLogResponses)
+		as.shadow.coldStarts = as.coldStarts
+		as.shadow.leader = as.leader
+		as.shadow.metrics = as.metrics
+		as.shadow.client.Transport = as.vllmTransport
+		as.shadow.apiKey = func(ctx context.Context) string {
+			return as.vllmAPIKey(ctx, config.ShadowModelID)
+		}
+		log.Printf("Loaded shadow model configuration: %s", config.ShadowModelID)
+	}
+
+	// Set up the pods of the tenant namespaces if configured
+	if config.Namespaces != "" || config.NamespaceSelector != "" {
+		as.newNamespaceBackends(ctx)
+	}
 
 	// Start watching the active model for changes
 	as.startModelWatch(ctx)
@@ -158,7 +389,7 @@ func (as *AutoScaler) GetMetrics() *stats.MetricsRecorder {
 
 // podExists checks if the vLLM pod exists
 func (as *AutoScaler) podExists(ctx context.Context) (bool, error) {
-	return as.k8sManager.PodExists(ctx)
+	return as.runner.PodExists(ctx)
 }
 
 // managePod creates or deletes the pod based on the desired state
@@ -167,9 +398,9 @@ func (as *AutoScaler) managePod(ctx context.Context, create bool) error {
 	direction := "up"
 	if !create {
 		direction = "down"
-		as.metrics.SetVLLMState(3) // stopping
+		as.setVLLMState(3) // stopping
 	} else {
-		as.metrics.SetVLLMState(1) // starting
+		as.setVLLMState(1) // starting
 	}
 
 	var err error
@@ -180,22 +411,29 @@ func (as *AutoScaler) managePod(ctx context.Context, create bool) error {
 		modelConfig, err = as.crdClient.GetModel(ctx, activeModelID)
 		if err != nil {
 			as.metrics.RecordScaleOp(direction, false, time.Since(start))
-			as.metrics.SetVLLMState(0) // failed to start, mark as stopped
+			as.startFailed("InvalidModelConfig", err.Error())
+			as.setVLLMState(0) // failed to start, mark as stopped
 			return fmt.Errorf("failed to get model config for '%s': %w", activeModelID, err)
 		}
 
+		as.modelOptions.Store(activeModelID, newModelOptions(modelConfig))
+		if as.recentModels != nil {
+			as.recentModels.touch(modelConfig.ModelName)
+		}
 		log.Printf("Creating pod with model: %s (%s)", activeModelID, modelConfig.ModelName)
-		err = as.k8sManager.CreatePod(ctx, modelConfig)
+		err = as.runner.CreatePod(ctx, modelConfig)
 	} else {
-		err = as.k8sManager.DeletePod(ctx)
+		as.removeReplicas(ctx)
+		err = as.runner.DeletePod(ctx)
 	}
 
 	if err != nil {
 		as.metrics.RecordScaleOp(direction, false, time.Since(start))
 		if !create {
-			as.metrics.SetVLLMState(2) // failed to stop, keep as running
+			as.setVLLMState(2) // failed to stop, keep as running
 		} else {
-			as.metrics.SetVLLMState(0) // failed to start, mark as stopped
+			as.startFailed("PodCreationFailed", err.Error())
+			as.setVLLMState(0) // failed to start, mark as stopped
 		}
 		return err
 	}
@@ -208,41 +446,78 @@ func (as *AutoScaler) managePod(ctx context.Context, create bool) error {
 		as.metrics.UpdateReplicas(0)
 		shutdownDuration := time.Since(start)
 		as.metrics.RecordVLLMShutdown(shutdownDuration)
-		as.metrics.SetVLLMState(0) // stopped
+		as.setVLLMState(0) // stopped
+		as.gpuNotReady.Store(false)
 		log.Printf("Deleted pod %s/%s (shutdown took %v)", as.config.Namespace, as.config.Deployment, shutdownDuration)
 	}
 
 	return nil
 }
 
-// waitForReady waits for the pod to be ready
-func (as *AutoScaler) waitForReady(ctx context.Context, timeout time.Duration) error {
+// waitForReady waits for the pod to be ready. A pod already ready is not waited for, and its
+// startup is not recorded again.
+func (as *AutoScaler) waitForReady(ctx context.Context, timeout time.Duration) (err error) {
 	startupStart := time.Now()
+	ctx, span := tracing.Tracer.Start(ctx, "wait_for_ready")
+	polls := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("readiness.polls", polls))
+		tracing.End(span, err)
+	}()
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	var gpus gpuWait
+	ready := func() bool {
+		polls++
+		if as.k8sManager == nil {
+			// The container passes its health check, with no pod condition or GPU registration to wait for
+			ok, err := as.runner.IsPodReady(ctx)
+			return err == nil && ok
+		}
+		pod, err := as.k8sManager.GetPod(ctx)
+		if err != nil || as.waitingForGPUs(ctx, pod, &gpus) {
+			return false
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == "Ready" && cond.Status == "True" {
+				as.readySince.Store(cond.LastTransitionTime.UnixNano())
+				return true
+			}
+		}
+		return false
+	}
+	if ready() {
+		return nil
+	}
+	coldStartWaitFrom(ctx).block()
+
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			as.metrics.SetVLLMState(0) // failed to start, mark as stopped
+			if gpus.reason != "" {
+				return &GPUNotReadyError{Reason: gpus.reason}
+			}
+			as.startFailed("StartTimeout", fmt.Sprintf("vLLM wasn't ready after %s", timeout))
+			as.setVLLMState(0) // failed to start, mark as stopped
 			return fmt.Errorf("timeout waiting for pod to be ready")
 		case <-ticker.C:
-			pod, err := as.k8sManager.GetPod(ctx)
-			if err != nil {
-				continue
-			}
-			// Check if pod is ready
-			for _, cond := range pod.Status.Conditions {
-				if cond.Type == "Ready" && cond.Status == "True" {
-					startupDuration := time.Since(startupStart)
-					as.metrics.RecordVLLMStartup(startupDuration)
-					as.metrics.SetVLLMState(2) // running
-					log.Printf("Pod %s/%s is ready (startup took %v)", as.config.Namespace, as.config.Deployment, startupDuration)
-					return nil
+			as.refreshStartupPhase(ctx)
+			if ready() {
+				if as.k8sManager == nil {
+					as.readySince.Store(time.Now().UnixNano()) // Containers report no Ready transition time
 				}
+				as.resetStartupPhase()
+				startupDuration := time.Since(startupStart)
+				as.metrics.RecordVLLMStartup(startupDuration)
+				as.lastStartup.Store(int64(startupDuration))
+				as.setVLLMState(2) // running
+				log.Printf("Pod %s/%s is ready (startup took %v)", as.config.Namespace, as.config.Deployment, startupDuration)
+				as.notify(notify.ScaleUp, as.GetActiveModel(), fmt.Sprintf("Ready after %s", startupDuration.Round(time.Second)))
+				return nil
 			}
 		}
 	}
@@ -250,20 +525,25 @@ func (as *AutoScaler) waitForReady(ctx context.Context, timeout time.Duration) e
 
 // ensureScaledUp ensures the pod is created and ready
 func (as *AutoScaler) ensureScaledUp(ctx context.Context) error {
+	// Followers leave the pods to the leader
+	if !as.leading() {
+		return as.scaleUpThroughLeader(ctx)
+	}
+
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
-	// Check if pod already exists
-	exists, err := as.podExists(ctx)
+	// Check if the model is already running or starting
+	up, err := as.strategy.isUp(ctx)
 	if err != nil {
 		return err
 	}
 
-	if exists {
-		// Pod already exists, just wait for ready
+	if up {
+		// Pod already up, just wait for ready
 		// Use background context so request timeout doesn't cancel pod startup
 		as.mu.Unlock()
-		bgCtx, cancel := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+		bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultScaleUpTimeout)
 		defer cancel()
 		err := as.waitForReady(bgCtx, defaultScaleUpTimeout)
 		as.mu.Lock()
@@ -273,28 +553,59 @@ func (as *AutoScaler) ensureScaledUp(ctx context.Context) error {
 	// If another goroutine is already scaling up, wait
 	if as.isScalingUp {
 		log.Printf("Waiting for ongoing pod creation...")
+		coldStartWaitFrom(ctx).block()
 		as.scaleUpCond.Wait()
 		return nil
 	}
 
 	// We're the one creating the pod
+	coldStartWaitFrom(ctx).block()
 	as.isScalingUp = true
 	defer func() {
 		as.isScalingUp = false
 		as.scaleUpCond.Broadcast()
 	}()
 
-	log.Printf("Creating pod %s/%s...", as.config.Namespace, as.config.Deployment)
-
 	as.mu.Unlock()
-	err = as.managePod(ctx, true)
+
+	// Wait for the other models' cold starts, the request deadline doesn't cancel the start either
+	queueCtx, cancelQueue := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+	defer cancelQueue()
+	_, queueSpan := tracing.Tracer.Start(ctx, "cold_start_queue")
+	release, err := as.coldStarts.acquire(queueCtx, as.GetActiveModel())
+	tracing.End(queueSpan, err)
+	if err != nil {
+		as.mu.Lock()
+		return fmt.Errorf("timeout waiting for other models to start: %w", err)
+	}
+	defer release()
+
+	as.startingAt.Store(time.Now().UnixNano())
+	defer as.startingAt.Store(0)
+	as.resetStartupPhase()
+
+	// Download a model missing from the cache first, the request deadline doesn't cancel the wait either
+	prefetchCtx, prefetchSpan := tracing.Tracer.Start(context.WithoutCancel(ctx), "prefetch_model")
+	err = as.prefetchModel(prefetchCtx, defaultScaleUpTimeout)
+	tracing.End(prefetchSpan, err)
+	if err != nil {
+		as.mu.Lock()
+		return err
+	}
+
+	as.stopPageCacheWarmer(ctx)
+	log.Printf("Creating pod %s/%s...", as.config.Namespace, as.config.Deployment)
+	createCtx, createSpan := tracing.Tracer.Start(ctx, "create_pod", trace.WithAttributes(attribute.String("scale.strategy", as.strategy.name())))
+	err = as.strategy.scaleUp(createCtx)
+	tracing.End(createSpan, err)
 	if err != nil {
 		as.mu.Lock()
 		return err
 	}
+	as.metrics.RecordColdStart(as.GetActiveModel())
 
 	// Use background context so request timeout doesn't cancel pod startup
-	bgCtx, cancel := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+	bgCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultScaleUpTimeout)
 	defer cancel()
 	err = as.waitForReady(bgCtx, defaultScaleUpTimeout)
 	as.mu.Lock()
@@ -311,50 +622,417 @@ func (as *AutoScaler) updateActivity() {
 
 // proxyHandler handles incoming HTTP requests
 func (as *AutoScaler) proxyHandler(w http.ResponseWriter, r *http.Request) {
+	// Gateway and SDK probes are answered without waking the model
+	if handleProbe(w, r) {
+		return
+	}
+
+	// The model list is read from the CRDs, listing models never wakes one
+	if isModelsRequest(r) {
+		as.serveModels(w, r)
+		return
+	}
+
+	// Outside the OpenAI and Anthropic APIs, only the passthrough paths reach vLLM
+	if !strings.HasPrefix(r.URL.Path, "/v1/") && !as.config.passthroughPath(r.URL.Path) {
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("Unknown path %s.", r.URL.Path), "invalid_request_error", "unknown_path")
+		return
+	}
+
 	start := time.Now()
-	ctx := r.Context()
+
+	// The request span joins the client's trace, if any
+	ctx, span := tracing.Tracer.Start(otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header)),
+		r.Method+" "+r.URL.Path, trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attribute.String("http.request.method", r.Method), attribute.String("url.path", r.URL.Path)))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	// Admin keys may trace a single request with X-Chill-Debug
+	dbg := as.debugRequest(r)
+
+	// Reject bodies over the limit before reading them
+	maxBodySize := as.config.GetMaxRequestBodySize()
+	if maxBodySize > 0 && r.ContentLength > maxBodySize {
+		writeRequestTooLarge(w, maxBodySize)
+		return
+	}
 
 	// Wrap request body to capture size and check for model parameter
-	var requestSize int64
+	var body *bodyReader
 	var requestedModel string
 	if r.Body != nil {
-		bodyReader := newBodyReader(r.Body)
-		r.Body = bodyReader
-		defer func() {
-			requestSize = bodyReader.BytesRead()
-		}()
+		if maxBodySize > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		}
+		body = newBodyReader(r.Body)
+		r.Body = body
 
 		// Extract model from request body if this is a /v1/* endpoint
 		if len(r.URL.Path) >= 3 && r.URL.Path[:3] == "/v1" {
+			_, extractSpan := tracing.Tracer.Start(ctx, "extract_model")
 			requestedModel = as.extractModelFromRequest(r)
+			extractSpan.SetAttributes(attribute.String("model.requested", requestedModel))
+			extractSpan.End()
+			if body.tooLarge() {
+				writeRequestTooLarge(w, maxBodySize)
+				return
+			}
+		}
+
+		// The body is read and parsed once for every step inspecting or rewriting it. Raw
+		// passthrough streams it to vLLM as sent.
+		if r.Method == http.MethodPost && !as.passesThrough(r) {
+			var err error
+			r, err = withRequestBody(r)
+			var maxBytesErr *http.MaxBytesError
+			switch {
+			case errors.As(err, &maxBytesErr):
+				writeRequestTooLarge(w, maxBytesErr.Limit)
+				return
+			case err != nil:
+				log.Printf("Failed to read the body of %s %s: %v", r.Method, r.URL.Path, err)
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			ctx = r.Context()
+		}
+	}
+
+	// Clients may use the Hugging Face or VLLMModel name, vLLM only knows the served name
+	servedModel := as.resolveServedModel(ctx, requestedModel)
+	if dbg != nil {
+		dbg.RequestedModel, dbg.ServedModel = requestedModel, servedModel
+		dbg.logf("%s %s for model %q, served as %q", r.Method, r.URL.Path, requestedModel, servedModel)
+	}
+	if requestedModel != "" && !allowModel(w, r, requestedModel, servedModel) {
+		return
+	}
+
+	// Requests vLLM would reject are answered without waking the model
+	if as.config.RequestValidation && body != nil && r.Method == http.MethodPost {
+		err := validateRequest(r)
+		var maxBytesErr *http.MaxBytesError
+		var validationErr *requestValidationError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case errors.As(err, &validationErr):
+			log.Printf("Rejected invalid %s %s: %v", r.Method, r.URL.Path, err)
+			validationErr.write(w)
+			return
+		case err != nil:
+			log.Printf("Failed to validate %s %s: %v", r.Method, r.URL.Path, err)
+		}
+	}
+
+	// Prompts are sized without waking the model
+	if r.Method == http.MethodPost && r.URL.Path == countTokensPath {
+		as.serveCountTokens(w, r, servedModel)
+		return
+	}
+
+	// Tenants of other namespaces are served by their namespace's pod
+	if as.namespaces != nil {
+		backend, ok := as.namespaceBackendFor(w, r)
+		if !ok {
+			return
+		}
+		if backend != nil {
+			accountFrom(ctx).serve(servedModel, as.config.GPUCount)
+			backend.serveHTTP(w, r, servedModel)
+			return
+		}
+	}
+
+	// Raw passthrough trades the response transformations for throughput. The passthrough paths
+	// outside the OpenAI and Anthropic APIs, e.g., /tokenize, /pooling or gRPC methods, are always
+	// passed through
+	if as.passesThrough(r) {
+		as.serveRaw(w, r, requestedModel, servedModel)
+		return
+	}
+	if servedModel != requestedModel {
+		if err := rewriteRequestModel(r, servedModel); err != nil {
+			log.Printf("Failed to rewrite model %s to served name %s: %v", requestedModel, servedModel, err)
+		} else {
+			log.Printf("Rewrote model %s to served name %s", requestedModel, servedModel)
+			mw := newModelNameWriter(w, servedModel, requestedModel)
+			defer func() {
+				if err := mw.finish(); err != nil {
+					log.Printf("Failed to write rewritten response: %v", err)
+				}
+			}()
+			w = mw
+			requestedModel = servedModel
+		}
+	}
+
+	// Requests forbidding tool use don't need the tool schemas in the model's context
+	if as.config.DropToolsOnNone && body != nil && r.Method == http.MethodPost && requestedModel != "" {
+		dropped, err := dropForbiddenTools(r)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to check the tool_choice of %s %s: %v", r.Method, r.URL.Path, err)
+		case dropped:
+			log.Printf("Dropped the tools of %s %s, its tool_choice is none", r.Method, r.URL.Path)
+		}
+	}
+
+	// The model's max_tokens cap keeps a single request from generating up to the context length
+	if body != nil && r.Method == http.MethodPost && requestedModel != "" {
+		capped, err := capMaxTokens(r, as.modelOptionsFor(ctx, requestedModel).maxOutputTokens)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to cap the max_tokens of %s %s: %v", r.Method, r.URL.Path, err)
+		case capped:
+			log.Printf("Capped the max_tokens of %s %s for model %s", r.Method, r.URL.Path, requestedModel)
+		}
+	}
+
+	// Guided decoding parameters sent as a header are moved into the body vLLM reads
+	if body != nil && r.Method == http.MethodPost {
+		moved, err := applyGuidedDecodingHeader(r)
+		var maxBytesErr *http.MaxBytesError
+		var validationErr *requestValidationError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case errors.As(err, &validationErr):
+			log.Printf("Rejected invalid %s %s: %v", r.Method, r.URL.Path, err)
+			validationErr.write(w)
+			return
+		case err != nil:
+			log.Printf("Failed to apply the %s header of %s %s: %v", guidedDecodingHeader, r.Method, r.URL.Path, err)
+		case moved:
+			log.Printf("Moved the %s header of %s %s into its body", guidedDecodingHeader, r.Method, r.URL.Path)
+		}
+	}
+
+	// Forced tool calls are decoded against the tool's schema on models supporting guided decoding
+	if body != nil && r.Method == http.MethodPost && requestedModel != "" && as.modelOptionsFor(ctx, requestedModel).guidedDecoding {
+		guided, err := applyGuidedDecoding(r)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to apply guided decoding to %s %s: %v", r.Method, r.URL.Path, err)
+		case guided:
+			log.Printf("Guided the tool call of %s %s by its input_schema", r.Method, r.URL.Path)
+		}
+	}
+
+	if body != nil && r.Method == http.MethodPost && r.URL.Path == anthropicMessagesPath {
+		// Images are checked before vLLM decodes them, so clients get the invalid block
+		err := checkImageBlocks(r, as.config.GetMaxImageSize())
+		var maxBytesErr *http.MaxBytesError
+		var imageErr *imageBlockError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case errors.As(err, &imageErr):
+			log.Printf("Rejected an image block of %s %s: %v", r.Method, r.URL.Path, err)
+			writeAPIError(w, http.StatusBadRequest, imageErr.Error(), "invalid_request_error", "invalid_image")
+			return
+		case err != nil:
+			log.Printf("Failed to check the image blocks of %s %s: %v", r.Method, r.URL.Path, err)
+		}
+
+		// Prompt caching breakpoints are left to vLLM's automatic prefix caching
+		removed, err := stripCacheControl(r)
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to strip the cache_control fields of %s %s: %v", r.Method, r.URL.Path, err)
+		case removed > 0:
+			log.Printf("Stripped %d cache_control fields from %s %s", removed, r.Method, r.URL.Path)
+		}
+
+		cw := newCacheUsageWriter(w)
+		defer func() {
+			if err := cw.finish(); err != nil {
+				log.Printf("Failed to write the response with cache usage: %v", err)
+			}
+		}()
+		w = cw
+	}
+
+	dbg.logBody(r)
+
+	// Clients asking for a buffered stream get a single JSON response aggregated from the events
+	bufferStream := wantsBufferedStream(r)
+	if bufferStream {
+		aggregator := newStreamAggregator(w, r.URL.Path)
+		defer func() {
+			if err := aggregator.finish(); err != nil {
+				log.Printf("Failed to write the aggregated stream: %v", err)
+			}
+		}()
+		w = aggregator
+	}
+
+	// Streaming clients get heartbeats while vLLM starts, so they don't give up on a silent connection
+	var heartbeats *heartbeatWriter
+	if as.config.GetSSEHeartbeatInterval() > 0 && body != nil && !bufferStream {
+		stream, err := streamingCompletion(r)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to read the body of %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, "Bad Request", http.StatusBadRequest)
+			return
+		case stream:
+			heartbeats = newHeartbeatWriter(w, r.URL.Path)
+			heartbeats.progress = as.startupPhase
+			defer func() {
+				if err := heartbeats.finish(); err != nil {
+					log.Printf("Failed to send the error event: %v", err)
+				}
+			}()
+			w = heartbeats
 		}
 	}
 
 	// Wrap response writer to capture status and size
 	rw := newResponseWriter(w, as.config.LogOutput, as.metrics)
+	sampledModel := requestedModel
+	if sampledModel == "" {
+		sampledModel = as.GetActiveModel()
+	}
+	rw.xmlFallbackOff = !as.xmlFallbackEnabled(sampledModel)
+	if body != nil && as.config.ToolArgumentValidation != ToolArgsOff {
+		// Converted tool calls are checked against the tools the request defines
+		tools, err := requestTools(r)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(rw, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to read the tools of %s %s: %v", r.Method, r.URL.Path, err)
+		}
+		rw.requestTools = tools
+		rw.rejectInvalidCalls = as.config.ToolArgumentValidation == ToolArgsStrict
+	}
 	defer func() {
 		duration := time.Since(start)
+		var requestSize int64
+		if body != nil {
+			requestSize = body.BytesRead()
+		}
+		as.parserCheck.observe(sampledModel, rw.xmlPatternSeen, rw.toolCallsDetected)
 		as.metrics.RecordRequest(r.Method, r.URL.Path, rw.Status(), duration, requestSize, rw.Size())
+		rw.stream.record(as.metrics, sampledModel)
+		span.SetAttributes(attribute.String("model.served", sampledModel), attribute.Int("http.response.status_code", rw.Status()))
+		if rw.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.Status()))
+		}
+		if dbg != nil {
+			dbg.logf("Completed with %d in %s: %s", rw.Status(), duration, dbg.report())
+		}
 
 		// Log output if enabled
 		if as.config.LogOutput && len(rw.Body()) > 0 {
-			log.Printf("Response body for %s %s: %s", r.Method, r.URL.Path, string(rw.Body()))
+			log.Printf("Response body for %s %s: %s", r.Method, r.URL.Path, redactBlobs(rw.Body()))
 		}
 	}()
 
+	// Embeddings are served by their own pod, independently of the chat model lifecycle
+	if as.embeddings != nil && r.URL.Path == embeddingsPath {
+		accountFrom(ctx).serve(as.embeddings.modelID, as.config.EmbeddingGPUCount)
+		as.embeddings.serveHTTP(rw, r)
+		return
+	}
+
+	// Route to a warm peer when the model is cold locally
+	if as.federation != nil && requestedModel != "" && r.Header.Get(federation.ForwardedHeader) == "" {
+		if as.forwardToWarmPeer(ctx, rw, r, requestedModel) {
+			return
+		}
+	}
+
+	// Requests for another model switch it as far as the client and the switch policy allow it
+	previousModel := as.GetActiveModel()
+	pinned := pinnedToActiveModel(r)
+	if requestedModel != "" && requestedModel != previousModel && (pinned || !as.config.switchAllowed(requestedModel)) {
+		as.refuseModelSwitch(ctx, rw, requestedModel, pinned)
+		return
+	}
+	var sessionErr *SessionActiveError
+	if err := as.checkSessionAffinity(ctx, requestedModel); errors.As(err, &sessionErr) {
+		log.Printf("Refused model %s: %v", requestedModel, err)
+		writeSessionActive(rw, sessionErr)
+		return
+	}
+
+	// Deterministic requests answered before are served from the cache, without waking the model.
+	// They are looked up once the request may use the model, so the cache doesn't bypass the checks.
+	var cacheKey string
+	if as.responses != nil && body != nil && dbg == nil {
+		key, cacheable, err := responseCacheKey(r, requestedModel)
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			writeRequestTooLarge(rw, maxBytesErr.Limit)
+			return
+		case err != nil:
+			log.Printf("Failed to read the body of %s %s: %v", r.Method, r.URL.Path, err)
+		case cacheable:
+			entry := as.responses.get(key)
+			as.metrics.RecordResponseCacheRequest(entry != nil)
+			if entry != nil {
+				if err := as.serveCachedResponse(rw, entry); err != nil {
+					log.Printf("Failed to write the cached response: %v", err)
+				}
+				return
+			}
+			cacheKey = key
+		}
+	}
+
 	// Update activity
 	as.updateActivity()
 
 	// Handle automatic model switching for /v1/* endpoints
 	var modelSwitched bool
 	if requestedModel != "" {
-		if err := as.handleModelSwitch(ctx, requestedModel); err != nil {
+		switchStart := time.Now()
+		switchCtx, switchSpan := tracing.Tracer.Start(ctx, "model_switch",
+			trace.WithAttributes(attribute.String("model.previous", previousModel), attribute.String("model.requested", requestedModel)))
+		err := as.handleModelSwitch(switchCtx, requestedModel)
+		tracing.End(switchSpan, err)
+		dbg.time("switch", time.Since(switchStart))
+		if err != nil {
 			// Check if this is a model not found error
 			if modelNotFoundErr, ok := err.(*ModelNotFoundError); ok {
 				log.Printf("Model not found: %s, returning available models", modelNotFoundErr.RequestedModel)
 				as.returnAvailableModels(ctx, rw, modelNotFoundErr.RequestedModel)
 				return
 			}
+			var inProgress *ModelSwitchInProgressError
+			if errors.As(err, &inProgress) {
+				log.Printf("Refused model %s: %v", requestedModel, err)
+				writeSwitchInProgress(rw, inProgress)
+				return
+			}
 
 			// Other errors
 			log.Printf("Failed to switch model to %s: %v", requestedModel, err)
@@ -374,19 +1052,70 @@ func (as *AutoScaler) proxyHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Check if we actually switched models
-		as.mu.RLock()
-		currentModel := as.activeModel
-		as.mu.RUnlock()
-		modelSwitched = (requestedModel == currentModel)
+		modelSwitched = requestedModel != previousModel
 	}
+	// The session is active from its request until its response, however long it takes
+	as.touchSession(ctx)
+	defer as.touchSession(ctx)
 
 	// Ensure deployment is scaled up
-	if err := as.ensureScaledUp(ctx); err != nil {
+	var annotation requestAnnotation
+	if as.config.ResponseAnnotations || dbg != nil {
+		annotation.ModelSwitched = modelSwitched
+		annotation.ColdStart = !as.isPodReady(ctx)
+	}
+	scaleStart := time.Now()
+	if heartbeats != nil {
+		heartbeats.start(as.config.GetSSEHeartbeatInterval())
+	}
+	scaleCtx, scaleSpan := tracing.Tracer.Start(withSwitchRequest(ctx, pinned), "ensure_scaled_up")
+	scaleCtx, coldStart := withColdStartWait(scaleCtx, as.metrics, as.GetActiveModel())
+	err := as.ensureScaledUp(scaleCtx)
+	coldStart.done()
+	scaleSpan.SetAttributes(attribute.Bool("model.switched", modelSwitched))
+	tracing.End(scaleSpan, err)
+	heartbeats.stop()
+	dbg.time("scale", time.Since(scaleStart))
+	if err != nil {
 		log.Printf("Failed to scale up: %v", err)
 
+		// The leader checks the switch of a follower's request again
+		var switchErr *SwitchNotAllowedError
+		if errors.As(err, &switchErr) {
+			writeSwitchNotAllowed(rw, switchErr)
+			return
+		}
+
+		// The pod can't start before the node's GPU device plugin registers the GPUs
+		var gpuErr *GPUNotReadyError
+		if errors.As(err, &gpuErr) {
+			rw.Header().Set("Retry-After", "30")
+			writeAPIError(rw, http.StatusServiceUnavailable,
+				"The GPU driver is not ready yet, the node is probably starting up. Please retry in a few moments.",
+				"service_unavailable", gpuDriverNotReady)
+			return
+		}
+
 		// Determine if this is a model loading scenario
 		isChat := r.URL.Path == "/v1/chat/completions"
-		if isChat && (modelSwitched || requestedModel != "") {
+
+		// The model is missing from the cache, its download takes longer than a cold start
+		var downloadErr *ModelDownloadingError
+		if errors.As(err, &downloadErr) {
+			if isChat {
+				as.writeLoadingMessage(rw, r, requestedModel, downloadErr.message(time.Now()))
+				return
+			}
+			rw.Header().Set("Retry-After", "60")
+			writeAPIError(rw, http.StatusServiceUnavailable,
+				"The model is downloading into the cache. Please retry in a few minutes.",
+				"service_unavailable", modelDownloading)
+			return
+		}
+
+		// A leader that doesn't answer gets the standard error rather than a loading message
+		var leaderErr *LeaderTimeoutError
+		if isChat && (modelSwitched || requestedModel != "") && !errors.As(err, &leaderErr) {
 			// Send loading message for chat completions
 			as.sendLoadingMessage(rw, r, requestedModel)
 			return
@@ -409,14 +1138,228 @@ func (as *AutoScaler) proxyHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Proxy the request via HTTP
-	proxy := httputil.NewSingleHostReverseProxy(as.targetURL)
+	options := as.modelOptionsFor(ctx, sampledModel)
+	rw.toolParser = options.toolParser
+
+	// A sample of the traffic is mirrored to the shadow model, its responses never reach the client
+	if as.shadow != nil {
+		as.shadow.mirror(r, sampledModel)
+	}
+
+	// The model's request timeout starts once vLLM is ready, cold starts don't count against it
+	if options.requestTimeout > 0 {
+		timeoutCtx, cancel := context.WithTimeout(ctx, options.requestTimeout)
+		defer cancel()
+		r = r.WithContext(timeoutCtx)
+	}
+
+	// Proxy the request via HTTP, to the least loaded pod when several replicas are ready, or to
+	// the session's pod with sticky sessions so its prefix cache is reused
+	var sticky string
+	if as.config.StickySessions {
+		sticky = sessionID(r)
+	}
+	target, release := as.acquireReplica(sticky)
+	defer release()
+	if target == nil {
+		target = as.targetURL
+	}
+	if dbg != nil {
+		dbg.Target = target.String()
+		dbg.logf("Proxying to %s", dbg.Target)
+	}
+	// A stalled or overlong stream cancels its upstream request, like a client disconnect does
+	streamCtx, cancelStream := context.WithCancel(r.Context())
+	defer cancelStream()
+	r = r.WithContext(streamCtx)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = as.vllmTransport
+	authorizeUpstream(proxy, as.vllmAPIKey(ctx, sampledModel))
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		limitStream(resp, cancelStream, as.config.GetStreamIdleTimeout(), options.maxStreamDuration)
+		return recoverInterruptedStreams(resp)
+	}
 	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Request to %s timed out after %s", sampledModel, options.requestTimeout)
+			writeAPIError(w, http.StatusGatewayTimeout,
+				fmt.Sprintf("The model did not answer within its request timeout of %s", options.requestTimeout),
+				"timeout_error", "request_timeout")
+			return
+		}
 		log.Printf("Proxy error: %v", err)
 		http.Error(w, "Bad Gateway", http.StatusBadGateway)
 	}
 
-	proxy.ServeHTTP(rw, r)
+	// The first requests after a cold start may reach a vLLM that isn't fully warm yet
+	retry, err := as.coldStartRetry(r)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeRequestTooLarge(rw, maxBytesErr.Limit)
+		return
+	case err != nil:
+		log.Printf("Failed to read the body of %s %s: %v", r.Method, r.URL.Path, err)
+		http.Error(rw, "Bad Request", http.StatusBadRequest)
+		return
+	case retry != nil:
+		proxy.Transport = retry
+	}
+
+	// vLLM joins the trace when its own tracing is enabled
+	upstreamCtx, upstreamSpan := tracing.Tracer.Start(r.Context(), "upstream", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.address", target.Host)))
+	defer upstreamSpan.End()
+	r = r.WithContext(upstreamCtx)
+	otel.GetTextMapPropagator().Inject(upstreamCtx, propagation.HeaderCarrier(r.Header))
+
+	accountFrom(ctx).serve(sampledModel, as.config.GPUCount)
+	rw.stream.forward()
+	if !as.config.ResponseAnnotations && dbg == nil {
+		as.serveUpstream(proxy, rw, r, cacheKey)
+		return
+	}
+
+	if annotation.ColdStart {
+		annotation.StartupMs = time.Since(scaleStart).Milliseconds()
+	}
+	annotation.Debug = dbg
+	aw := newAnnotatingWriter(rw, annotation)
+	proxyStart := time.Now()
+	as.serveUpstream(proxy, aw, r, cacheKey)
+	dbg.time("proxy", time.Since(proxyStart))
+	dbg.collect(rw)
+	if !aw.buffering {
+		// Streamed responses are already sent, the report follows them
+		dbg.setTrailer(aw.Header())
+	}
+	if err := aw.finish(); err != nil {
+		log.Printf("Failed to write annotated response: %v", err)
+	}
+}
+
+// xmlFallbackEnabled reports whether XML tool calls should be converted for the given model
+func (as *AutoScaler) xmlFallbackEnabled(model string) bool {
+	switch as.config.XMLFallback {
+	case XMLFallbackOff:
+		return false
+	case XMLFallbackAuto:
+		return as.parserCheck.needsXMLFallback(model)
+	default:
+		return true
+	}
+}
+
+// statusHandler reports the proxy state and detected misconfigurations
+func (as *AutoScaler) statusHandler(c *gin.Context) {
+	xmlFallback := as.config.XMLFallback
+	if xmlFallback == "" {
+		xmlFallback = XMLFallbackOn
+	}
+	as.mu.RLock()
+	lastActivity := as.lastActivity
+	as.mu.RUnlock()
+
+	now := time.Now()
+	status := gin.H{
+		"active_model":         as.GetActiveModel(),
+		"ready":                as.isPodReady(c.Request.Context()),
+		"vllm_state":           as.vllmStateName(),
+		"last_activity":        lastActivity.UTC(),
+		"idle_seconds":         now.Sub(lastActivity).Seconds(),
+		"cold_start_queue":     as.coldStarts.queueLength(),
+		"scale_strategy":       as.strategy.name(),
+		"xml_fallback":         xmlFallback,
+		"tool_parser_warnings": as.parserCheck.activeWarnings(),
+		"load":                 as.replicas.snapshot(),
+		"gpu_driver_not_ready": as.gpuNotReady.Load(),
+	}
+	if modelSwitch := as.switches.snapshot(); modelSwitch != nil {
+		status["model_switch"] = modelSwitch
+	}
+	if as.affinity != nil {
+		status["active_sessions"] = as.affinity.count()
+	}
+	if leader := as.leaderStatus(); leader != nil {
+		status["leader_election"] = leader
+	}
+	if startup := as.startupProgress(now); startup != nil {
+		status["startup"] = startup
+	}
+	if as.sessions != nil {
+		status["tracked_sessions"] = as.sessions.count()
+	}
+	if as.responses != nil {
+		entries, size := as.responses.stats()
+		status["response_cache"] = gin.H{"entries": entries, "bytes": size}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// configHandler returns the effective configuration: flags and env vars with defaults applied,
+// the resolved backend targets and the CRD-derived configuration of the active model
+func (as *AutoScaler) configHandler(c *gin.Context) {
+	activeModel := as.GetActiveModel()
+	response := gin.H{
+		"config":       as.config.Effective(),
+		"target_url":   as.targetURL.String(),
+		"active_model": activeModel,
+	}
+	if as.embeddings != nil {
+		response["embedding_target_url"] = as.embeddings.targetURL.String()
+	}
+	if namespaces := as.namespaceStatus(); namespaces != nil {
+		response["namespaces"] = namespaces
+	}
+	if as.shadow != nil {
+		response["shadow_target_url"] = as.shadow.targetURL.String()
+	}
+
+	modelConfig, err := as.crdClient.GetModel(c.Request.Context(), activeModel)
+	if err != nil {
+		response["model_error"] = err.Error()
+	} else {
+		response["model"] = modelConfig
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// isPodReady reports whether the vLLM pod exists and passes its readiness probe
+func (as *AutoScaler) isPodReady(ctx context.Context) bool {
+	ready, err := as.runner.IsPodReady(ctx)
+	return err == nil && ready
+}
+
+// forwardToWarmPeer proxies the request to a federation peer when the requested model
+// is not warm locally but is warm on a peer. Returns true if the request was handled.
+func (as *AutoScaler) forwardToWarmPeer(ctx context.Context, w http.ResponseWriter, r *http.Request, requestedModel string) bool {
+	// Prefer locality: a local warm model always wins
+	if requestedModel == as.GetActiveModel() && as.isPodReady(ctx) {
+		return false
+	}
+
+	peer, ok := as.federation.FindWarmPeer(ctx, requestedModel)
+	if !ok {
+		return false
+	}
+
+	log.Printf("[FEDERATION] Model %s is cold locally, routing to warm peer %s", requestedModel, peer.Name)
+	as.federation.Forward(w, r, peer)
+	return true
+}
+
+// federationStatusHandler advertises the local warm state to federation peers
+func (as *AutoScaler) federationStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, federation.Status{
+		ActiveModel: as.GetActiveModel(),
+		Ready:       as.isPodReady(c.Request.Context()),
+	})
 }
 
 // healthHandler handles health check requests
@@ -435,32 +1378,8 @@ func (as *AutoScaler) versionHandler(c *gin.Context) {
 
 // MetricsHandler combines vLLM metrics with proxy metrics
 func (as *AutoScaler) MetricsHandler(c *gin.Context) {
-	// Fetch vLLM metrics via HTTP
-	client := &http.Client{Timeout: 30 * time.Second}
-	vllmMetricsURL := fmt.Sprintf("%s/metrics", as.targetURL.String())
-	resp, err := client.Get(vllmMetricsURL)
-
-	var vllmMetrics string
-	if err != nil {
-		log.Printf("Warning: Failed to fetch vLLM metrics: %v", err)
-		vllmMetrics = fmt.Sprintf("# vLLM metrics unavailable: %v\n", err)
-	} else {
-		defer func() {
-			if err := resp.Body.Close(); err != nil {
-				log.Printf("Warning: Failed to close response body: %v", err)
-			}
-		}()
-		if resp.StatusCode == http.StatusOK {
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				vllmMetrics = fmt.Sprintf("# Failed to read vLLM metrics: %v\n", err)
-			} else {
-				vllmMetrics = string(body)
-			}
-		} else {
-			vllmMetrics = fmt.Sprintf("# vLLM metrics returned status %d\n", resp.StatusCode)
-		}
-	}
+	vllmMetrics, stale := as.vllmMetricsText(c.Request.Context())
+	as.metrics.SetVLLMMetricsStale(stale)
 
 	// Get proxy metrics from Prometheus handler
 	w := httptest.NewRecorder()
@@ -487,7 +1406,73 @@ func (as *AutoScaler) Start(ctx context.Context) error {
 
 // Stop implements operation.Manager interface for manual stop
 func (as *AutoScaler) Stop(ctx context.Context) error {
-	return as.managePod(ctx, false)
+	return as.strategy.scaleDown(ctx)
+}
+
+// Restart deletes the vLLM pod, regardless of the scale strategy, and starts a fresh one
+func (as *AutoScaler) Restart(ctx context.Context) error {
+	exists, err := as.podExists(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check pod existence: %w", err)
+	}
+	if exists {
+		if err := as.managePod(ctx, false); err != nil {
+			return fmt.Errorf("failed to stop current pod: %w", err)
+		}
+	}
+	return as.Start(ctx)
+}
+
+// Benchmark runs the standardized benchmark against the ready model, directly on the vLLM service
+// under its served name so the proxy's own metrics are not skewed, and records the result in the
+// VLLMModel status
+func (as *AutoScaler) Benchmark(ctx context.Context, modelID string) (*benchmark.Result, error) {
+	modelConfig, err := as.crdClient.GetModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Benchmarking model %s", modelID)
+	runner := benchmark.NewRunner(as.targetURL.String(), defaultScaleUpTimeout)
+	runner.SetTransport(as.vllmTransport)
+	runner.SetAPIKey(as.vllmAPIKey(ctx, modelID))
+	result, err := runner.Run(ctx, modelConfig.ServedModelName)
+	as.updateActivity()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Benchmarked %s: %.1f tokens/s, TTFT %dms", modelID, result.TokensPerSecond, result.TimeToFirstTokenMs)
+
+	status := &v1alpha1.BenchmarkStatus{
+		CompletedAt:        metav1.NewTime(result.CompletedAt),
+		Prompts:            int32(result.Prompts),
+		TokensPerSecond:    result.TokensPerSecond,
+		TimeToFirstTokenMs: result.TimeToFirstTokenMs,
+	}
+	if result.KVCacheMeasured {
+		status.KVCacheHeadroomPercent = result.KVCacheHeadroom
+	}
+	if err := as.crdClient.UpdateBenchmarkStatus(ctx, modelID, status); err != nil {
+		return nil, fmt.Errorf("failed to record benchmark result: %w", err)
+	}
+	return result, nil
+}
+
+// SendToModel sends a chat completion request directly to the ready model on the vLLM service,
+// under its served name, so model comparisons don't go through client accounting
+func (as *AutoScaler) SendToModel(ctx context.Context, modelID string, request map[string]interface{}) (*compare.Response, error) {
+	modelConfig, err := as.crdClient.GetModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	client := compare.NewClient(as.targetURL.String(), as.vllmAPIKey(ctx, modelID), defaultScaleUpTimeout)
+	client.SetTransport(as.vllmTransport)
+	response, err := client.Send(ctx, modelConfig.ServedModelName, request)
+	as.updateActivity()
+	if err != nil {
+		return nil, fmt.Errorf("failed to send the request to %s: %w", modelID, err)
+	}
+	log.Printf("Model %s answered the comparison with %d in %dms", modelID, response.StatusCode, response.DurationMs)
+	return response, nil
 }
 
 // UpdateActivity implements operation.Manager interface
@@ -502,8 +1487,26 @@ func (as *AutoScaler) GetActiveModel() string {
 	return as.activeModel
 }
 
-// SwitchModel switches to a different model
+// SwitchModel switches to a different model once the in-flight requests of the current one have
+// completed. Concurrent switches to the same model wait for the first, switches to another model
+// fail with a ModelSwitchInProgressError until it completes.
 func (as *AutoScaler) SwitchModel(ctx context.Context, modelID string) error {
+	start := time.Now()
+	previous := as.GetActiveModel()
+	err := as.switches.run(ctx, modelID, as.metrics, func() error {
+		return as.switchModel(ctx, modelID)
+	})
+	var inProgress *ModelSwitchInProgressError
+	if previous != modelID && !errors.As(err, &inProgress) {
+		as.metrics.RecordManagedOperation(previous, modelID, err == nil, time.Since(start))
+	}
+	return err
+}
+
+// switchModel drains the current model, releases its pod and makes the model the active one
+func (as *AutoScaler) switchModel(ctx context.Context, modelID string) error {
+	as.drainInFlight(ctx)
+
 	as.mu.Lock()
 	defer as.mu.Unlock()
 
@@ -524,8 +1527,13 @@ func (as *AutoScaler) SwitchModel(ctx context.Context, modelID string) error {
 	}
 
 	// Update active model
+	previous := as.activeModel
 	as.activeModel = modelID
 	log.Printf("Switched active model to: %s", modelID)
+	as.modelStatus.notify()
+	if previous != modelID {
+		as.notify(notify.ModelSwitch, modelID, fmt.Sprintf("Switched from %s", previous))
+	}
 
 	return nil
 }
@@ -555,14 +1563,14 @@ func (as *AutoScaler) ListModels(ctx context.Context) ([]ModelInfo, error) {
 	return result, nil
 }
 
-// IsRunning returns whether the vLLM pod is currently running
+// IsRunning returns whether the vLLM model is currently running
 func (as *AutoScaler) IsRunning() bool {
 	ctx := context.Background()
-	exists, err := as.podExists(ctx)
+	up, err := as.strategy.isUp(ctx)
 	if err != nil {
 		return false
 	}
-	return exists
+	return up
 }
 
 // ModelInfo represents basic model information
@@ -579,41 +1587,154 @@ func (as *AutoScaler) startIdleChecker() {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		as.mu.RLock()
-		idleTime := time.Since(as.lastActivity)
-		as.mu.RUnlock()
-
-		if idleTime > as.config.GetIdleTimeout() {
-			ctx := context.Background()
-			exists, err := as.podExists(ctx)
-			if err != nil {
-				log.Printf("Failed to check pod existence: %v", err)
-				continue
-			}
+		as.checkIdle(context.Background(), time.Now())
+	}
+}
 
-			if exists {
-				log.Printf("Idle for %v, deleting pod...", idleTime.Round(time.Second))
-				if err := as.managePod(ctx, false); err != nil {
-					log.Printf("Failed to delete pod: %v", err)
-				}
-			}
+// checkIdle releases the model once idle for the idle timeout in effect, unless requests are in
+// flight or the GPU is still busy. During a warm-up window the model is started instead, and kept
+// running regardless of traffic.
+func (as *AutoScaler) checkIdle(ctx context.Context, now time.Time) {
+	gpu, gpuSampled := as.sampleGPU()
+	if !as.leading() {
+		return
+	}
+	if as.embeddings != nil {
+		as.embeddings.checkIdle(ctx, as.config.GetIdleTimeout())
+	}
+	if as.shadow != nil {
+		as.shadow.checkIdle(ctx, as.config.GetIdleTimeout())
+	}
+	if as.namespaces != nil {
+		for _, backend := range as.namespaces.all() {
+			backend.checkIdle(ctx, as.config.GetIdleTimeout())
+		}
+	}
+
+	if window, ok := as.warmWindow(now); ok {
+		as.prewarm(ctx, window)
+		return
+	}
+
+	as.mu.RLock()
+	idleTime := now.Sub(as.lastActivity)
+	as.mu.RUnlock()
+
+	if idleTime <= as.idleTimeout(now) {
+		return
+	}
+	// A request still streaming is activity, however long ago it arrived
+	if inFlight := as.replicas.inFlightCount(); inFlight > 0 {
+		log.Printf("Idle for %v but %d requests in flight, deferring scale-down", idleTime.Round(time.Second), inFlight)
+		return
+	}
+	// So does one streaming through another replica
+	if inFlight := as.leader.followersInFlight(now); inFlight > 0 {
+		log.Printf("Idle for %v but the other replicas have %d requests in flight, deferring scale-down", idleTime.Round(time.Second), inFlight)
+		return
+	}
+	up, err := as.strategy.isUp(ctx)
+	if err != nil {
+		log.Printf("Failed to check pod existence: %v", err)
+		return
+	}
+
+	if up && as.keepsWarm(ctx) {
+		return
+	}
+	// The HTTP activity can be stale while vLLM generates, e.g. a long streamed response
+	if up && gpuSampled && gpu == gpuStateBusy {
+		log.Printf("Idle for %v but the GPU is busy, deferring scale-down", idleTime.Round(time.Second))
+		as.metrics.RecordScaleDownDeferred()
+		return
+	}
+	if up {
+		log.Printf("Idle for %v, scaling down (%s)...", idleTime.Round(time.Second), as.strategy.name())
+		node := as.podNode(ctx)
+		as.removeReplicas(ctx)
+		if err := as.strategy.scaleDown(ctx); err != nil {
+			log.Printf("Failed to scale down: %v", err)
+		} else {
+			as.notify(notify.ScaleDown, as.GetActiveModel(), fmt.Sprintf("Released after %s idle (%s)", idleTime.Round(time.Second), as.strategy.name()))
+			as.warmPageCache(ctx, node)
 		}
+	} else if deep, ok := as.strategy.(deepIdler); ok {
+		deep.checkDeepIdle(ctx, idleTime)
 	}
 }
 
 // Run starts the HTTP server and idle checker
 func (as *AutoScaler) Run() error {
+	// Run for the lease before starting the loops only the leader acts in
+	if as.leader != nil {
+		if err := as.leader.start(context.Background()); err != nil {
+			return err
+		}
+	}
+
 	// Start idle checker
 	go as.startIdleChecker()
 
+	// Followers report their traffic so the leader doesn't release a model they use
+	if as.leader != nil {
+		go as.startActivityReports(context.Background())
+	}
+
+	// Start load-aware replica scaling
+	go as.startReplicaScaler(context.Background())
+
+	// Report the active model's state in its VLLMModel status
+	if as.modelStatus != nil {
+		go as.startModelStatus(context.Background())
+	}
+
+	// Remove the pods before the active model's VLLMModel is deleted
+	if as.config.ModelFinalizers {
+		go as.startModelFinalizers(context.Background())
+	}
+
+	// Serve the namespaces newly matching the selector
+	if as.config.NamespaceSelector != "" {
+		go as.startNamespaceRefresh(context.Background())
+	}
+
+	// Serve the renewed certificate of the TLS Secret
+	if as.config.TLSSecret != "" {
+		go as.startTLSSecretReload(context.Background())
+	}
+
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(gin.Recovery())
 
+	// Audit log - every /v1 and passthrough request, rejected ones included, recorded once its response is complete
+	if as.audit != nil {
+		router.Use(as.auditMiddleware)
+	}
+
+	// API key validation - unknown keys are rejected before they can wake the model
+	if as.apiKeys != nil {
+		router.Use(as.authMiddleware)
+		go as.startAPIKeyReload(context.Background())
+	}
+
+	// User tracking - the user declared in /v1 request bodies, read before it is rate limited
+	if as.userLabels != nil {
+		router.Use(as.userMiddleware)
+	}
+
+	// Rate limiting - per API key budgets on /v1 and passthrough requests
+	if as.rateLimiter != nil {
+		router.Use(as.rateLimitMiddleware)
+		if as.config.RateLimitConfigMap != "" {
+			go as.startRateLimitReload(context.Background())
+		}
+	}
+
 	// Health endpoints
 	router.GET("/health", as.healthHandler)
-	router.GET("/readyz", as.healthHandler)
+	router.GET("/readyz", as.readyzHandler)
 
 	// Proxy group
 	proxyGroup := router.Group("/proxy")
@@ -621,10 +1742,48 @@ func (as *AutoScaler) Run() error {
 		// Metrics endpoint - combines vLLM metrics + proxy metrics
 		proxyGroup.GET("/metrics", as.MetricsHandler)
 		proxyGroup.GET("/version", as.versionHandler)
+		proxyGroup.GET("/status", as.statusHandler)
+		proxyGroup.GET("/config", as.configHandler)
+		proxyGroup.GET("/usage", as.usageHandler)
 
 		// GPU stats endpoint
 		gpuStatsHandler := stats.NewGinGPUStatsHandler()
 		proxyGroup.GET("/stats", gpuStatsHandler.Handler)
+
+		// Federation endpoint - lets peers discover our warm model
+		proxyGroup.GET("/federation/status", as.federationStatusHandler)
+
+		// Leader endpoints - the other replicas ask the leader to start models and report their traffic
+		if as.leader != nil {
+			proxyGroup.POST("/leader/scale-up", as.leaderScaleUpHandler)
+			proxyGroup.POST("/leader/activity", as.leaderActivityHandler)
+		}
+
+		// Admin API - only exposed when a token is configured
+		if as.config.AdminToken != "" {
+			admin.NewHandler(as, as.config.AdminToken).Register(proxyGroup.Group("/admin"))
+		}
+	}
+
+	// Session budgets - cumulative tokens per client session on /v1 and passthrough requests
+	if as.sessions != nil {
+		router.Use(as.sessionBudgetMiddleware)
+	}
+
+	// Session affinity - the session of /v1 requests, keeping the active model while it is active
+	if as.affinity != nil {
+		router.Use(as.sessionAffinityMiddleware)
+	}
+
+	// Usage accounting - tokens and GPU time per model and per API key on /v1 and passthrough requests
+	router.Use(as.usageMiddleware)
+	if as.config.UsageConfigMap != "" {
+		go as.startUsagePersistence(context.Background())
+	}
+
+	// Anthropic Message Batches - run through the proxy like single requests, after the /v1 middlewares
+	if as.batches != nil {
+		as.registerMessageBatches(router)
 	}
 
 	// Default proxy handler for all other routes
@@ -633,7 +1792,7 @@ func (as *AutoScaler) Run() error {
 	// Log all registered endpoints dynamically
 	as.logRegisteredRoutes(router)
 
-	return router.Run(":" + as.config.Port)
+	return as.listenAndServe(router)
 }
 
 // logRegisteredRoutes dynamically logs all registered routes from the Gin router
@@ -667,10 +1826,11 @@ func (as *AutoScaler) startModelWatch(ctx context.Context) {
 	activeModel := as.activeModel
 	as.mu.RUnlock()
 
-	// Watch the active model CRD
+	// Watch the active model CRD, the pod is restarted when the change alters its spec
 	err := as.crdClient.WatchModel(ctx, activeModel, func() {
-		log.Printf("Model %s configuration changed, restarting vLLM pod...", activeModel)
-		as.restartVLLMPod()
+		log.Printf("Model %s configuration changed, checking the vLLM pod for drift", activeModel)
+		as.modelOptions.Delete(activeModel)
+		as.checkConfigDrift(ctx)
 	})
 	if err != nil {
 		log.Printf("Warning: Failed to start watching model %s: %v", activeModel, err)
@@ -682,7 +1842,7 @@ func (as *AutoScaler) restartVLLMPod() {
 	ctx := context.Background()
 
 	// Check if pod exists
-	exists, err := as.k8sManager.PodExists(ctx)
+	exists, err := as.runner.PodExists(ctx)
 	if err != nil {
 		log.Printf("Error checking if pod exists: %v", err)
 		return
@@ -694,7 +1854,7 @@ func (as *AutoScaler) restartVLLMPod() {
 	}
 
 	// Delete the pod - it will be recreated on next request with new config
-	if err := as.k8sManager.DeletePod(ctx); err != nil {
+	if err := as.runner.DeletePod(ctx); err != nil {
 		log.Printf("Error restarting vLLM pod: %v", err)
 		return
 	}
@@ -722,6 +1882,9 @@ func (as *AutoScaler) startConfigDriftCheck(ctx context.Context) {
 
 // checkConfigDrift checks if the running pod config matches the CRD and restarts if needed
 func (as *AutoScaler) checkConfigDrift(ctx context.Context) {
+	if !as.leading() {
+		return
+	}
 	as.mu.RLock()
 	activeModel := as.activeModel
 	as.mu.RUnlock()
@@ -734,41 +1897,31 @@ func (as *AutoScaler) checkConfigDrift(ctx context.Context) {
 	}
 
 	// Verify pod config matches
-	matches, err := as.k8sManager.VerifyPodConfig(ctx, modelConfig)
+	drifts, err := as.runner.ConfigDrift(ctx, modelConfig)
 	if err != nil {
 		log.Printf("Warning: Failed to verify pod config: %v", err)
 		return
 	}
+	as.reportConfigDrift(drifts)
 
-	if !matches {
-		log.Printf("Config drift detected! vLLM pod config doesn't match CRD. Restarting pod...")
-		as.restartVLLMPod()
+	if len(drifts) == 0 {
+		return
+	}
+	if as.config.ConfigDriftAction == ConfigDriftWarn {
+		log.Printf("Config drift detected! vLLM pod config doesn't match CRD, leaving the pod running (CONFIG_DRIFT_ACTION=warn)")
+		return
 	}
+	log.Printf("Config drift detected! vLLM pod config doesn't match CRD. Restarting pod...")
+	as.notify(notify.ConfigDriftRestart, activeModel, fmt.Sprintf("Restarting, the pod drifted from its VLLMModel (%s)", driftSummary(drifts)))
+	as.restartVLLMPod()
 }
 
-// extractModelFromRequest extracts the model parameter from the request body
+// extractModelFromRequest extracts the model parameter from the request body. Only the body up
+// to the top-level model field is read, at most modelPeekLimit bytes, then replayed for the proxy.
 func (as *AutoScaler) extractModelFromRequest(r *http.Request) string {
-	// Read the body
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		return ""
-	}
-
-	// Restore the body for subsequent reads
-	r.Body = newBodyReaderFromBytes(bodyBytes)
-
-	// Parse JSON to extract model field
-	var reqBody map[string]interface{}
-	if err := json.Unmarshal(bodyBytes, &reqBody); err != nil {
-		return ""
-	}
-
-	// Extract model field
-	if model, ok := reqBody["model"].(string); ok {
-		return model
-	}
-
-	return ""
+	model, consumed := peekModel(r.Body, modelPeekLimit)
+	r.Body = replayBody(consumed, r.Body)
+	return model
 }
 
 // handleModelSwitch checks if the requested model differs from the active model and switches if needed
@@ -777,6 +1930,12 @@ func (as *AutoScaler) handleModelSwitch(ctx context.Context, requestedModel stri
 	currentModel := as.activeModel
 	as.mu.RUnlock()
 
+	// During a switch only the requests for the new model wait for it, the others are refused
+	if err := as.switches.conflicts(requestedModel); err != nil {
+		as.metrics.RecordModelSwitchRequest("rejected")
+		return err
+	}
+
 	// If the requested model is the same as the active model, no action needed
 	if requestedModel == currentModel {
 		return nil
@@ -792,6 +1951,19 @@ func (as *AutoScaler) handleModelSwitch(ctx context.Context, requestedModel stri
 		}
 	}
 
+	// Followers only track the model, the leader switches when asked to scale it up
+	if !as.leading() {
+		as.mu.Lock()
+		as.activeModel = requestedModel
+		as.mu.Unlock()
+		return nil
+	}
+
+	// LoRA models sharing the running base model only need their adapter swapped
+	if as.swapLoRAAdapter(ctx, currentModel, requestedModel) {
+		return nil
+	}
+
 	// Perform the model switch
 	if err := as.SwitchModel(ctx, requestedModel); err != nil {
 		return fmt.Errorf("failed to switch model: %w", err)
@@ -812,6 +1984,12 @@ func (e *ModelNotFoundError) Error() string {
 
 // returnAvailableModels returns available models in OpenAI /v1/models format
 func (as *AutoScaler) returnAvailableModels(ctx context.Context, w http.ResponseWriter, requestedModel string) {
+	as.writeModelList(ctx, w, http.StatusNotFound, "model_not_found", fmt.Sprintf("Model '%s' not found", requestedModel), nil)
+}
+
+// writeModelList writes an error listing the models of the CRDs kept by the filter (all of them
+// when nil) in OpenAI /v1/models format
+func (as *AutoScaler) writeModelList(ctx context.Context, w http.ResponseWriter, status int, code, message string, keep func(ModelInfo) bool) {
 	// Get all available models from CRDs
 	models, err := as.ListModels(ctx)
 	if err != nil {
@@ -829,6 +2007,10 @@ func (as *AutoScaler) returnAvailableModels(ctx context.Context, w http.Response
 		return
 	}
 
+	if keep != nil {
+		models = slices.DeleteFunc(models, func(model ModelInfo) bool { return !keep(model) })
+	}
+
 	// Build OpenAI-compatible model list response
 	modelList := make([]map[string]interface{}, 0, len(models))
 	for _, model := range models {
@@ -842,12 +2024,12 @@ func (as *AutoScaler) returnAvailableModels(ctx context.Context, w http.Response
 
 	// Build error response with available models
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(status)
 	response := map[string]interface{}{
 		"error": map[string]interface{}{
-			"message": fmt.Sprintf("Model '%s' not found. Available models: %v", requestedModel, getModelNames(models)),
+			"message": fmt.Sprintf("%s. Available models: %v", message, getModelNames(models)),
 			"type":    "invalid_request_error",
-			"code":    "model_not_found",
+			"code":    code,
 			"param":   "model",
 		},
 		"available_models": map[string]interface{}{
@@ -872,17 +2054,23 @@ func getModelNames(models []ModelInfo) []string {
 
 // sendLoadingMessage sends a streaming chat completion message indicating model is loading
 func (as *AutoScaler) sendLoadingMessage(w http.ResponseWriter, r *http.Request, modelName string) {
+	message := fmt.Sprintf("Model '%s' is loading, please wait...", modelName)
+	if phase := as.startupPhase(); phase != "" {
+		message = fmt.Sprintf("Model '%s' is loading (%s), please wait...", modelName, phase)
+	}
+	as.writeLoadingMessage(w, r, modelName, message)
+}
+
+// writeLoadingMessage answers a chat completion with a message about the model's startup
+func (as *AutoScaler) writeLoadingMessage(w http.ResponseWriter, r *http.Request, modelName, message string) {
 	// Check if request expects streaming response
-	var reqBody map[string]interface{}
+	var stream bool
 	if r.Body != nil {
-		bodyBytes, err := io.ReadAll(r.Body)
-		if err == nil {
-			_ = json.Unmarshal(bodyBytes, &reqBody)
+		if body, err := parsedBody(r); err == nil {
+			stream = body.streamed()
 		}
 	}
 
-	stream, _ := reqBody["stream"].(bool)
-
 	if stream {
 		// Send SSE streaming response
 		w.Header().Set("Content-Type", "text/event-stream")
@@ -893,7 +2081,6 @@ func (as *AutoScaler) sendLoadingMessage(w http.ResponseWriter, r *http.Request,
 		flusher, _ := w.(http.Flusher)
 
 		// Send loading message chunk
-		message := fmt.Sprintf("Model '%s' is loading, please wait...", modelName)
 		chunk := map[string]interface{}{
 			"id":      "chatcmpl-loading",
 			"object":  "chat.completion.chunk",
@@ -943,7 +2130,6 @@ func (as *AutoScaler) sendLoadingMessage(w http.ResponseWriter, r *http.Request,
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 
-		message := fmt.Sprintf("Model '%s' is loading, please wait...", modelName)
 		response := map[string]interface{}{
 			"id":      "chatcmpl-loading",
 			"object":  "chat.completion",
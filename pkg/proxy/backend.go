@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/efortin/vllm-chill/pkg/docker"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/process"
+)
+
+// Backends running the vLLM server
+const (
+	BackendKubernetes = "kubernetes" // A pod created from the VLLMModel CRD (default)
+	BackendDocker     = "docker"     // A local Docker or Podman container, the models read from manifest files
+	BackendProcess    = "process"    // A local process or systemd unit, the models read from manifest files
+)
+
+// vllmRunner starts, stops and checks the vLLM server of the active model, implemented by
+// K8sManager for its pod, docker.Runner for a local container and process.Runner for a local
+// process
+type vllmRunner interface {
+	podManager
+	ConfigDrift(ctx context.Context, modelConfig *kubernetes.ModelConfig) ([]kubernetes.Drift, error)
+	VLLMLogTail(ctx context.Context, lines int64) (string, error)
+}
+
+// newLocalBackend returns the models of the manifest directory and the runner of the local vLLM
+// container or process
+func newLocalBackend(config *Config) (*kubernetes.CRDClient, vllmRunner, error) {
+	crdClient, err := kubernetes.NewManifestCRDClient(config.ModelsDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the models from %s: %w", config.ModelsDir, err)
+	}
+	if config.Backend == BackendProcess {
+		return crdClient, newProcessRunner(config), nil
+	}
+	runner, err := newDockerRunner(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return crdClient, runner, nil
+}
+
+// newDockerRunner returns the runner of the local vLLM container
+func newDockerRunner(config *Config) (*docker.Runner, error) {
+	// The Hugging Face token of the proxy lets vLLM download gated models
+	var env []string
+	if token := os.Getenv("HF_TOKEN"); token != "" {
+		env = append(env, "HF_TOKEN="+token)
+	}
+	runner, err := docker.NewRunner(&docker.Config{
+		Host:          config.DockerHost,
+		ContainerName: config.Deployment,
+		Port:          config.LocalPort,
+		GPUCount:      config.GPUCount,
+		CPUOffloadGB:  config.CPUOffloadGB,
+		SleepMode:     config.ScaleStrategy == ScaleStrategyVLLMSleep,
+		HFCacheDir:    config.HFCacheDir,
+		StopTimeout:   config.GetShutdownGrace(),
+		Env:           env,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the docker backend: %w", err)
+	}
+	return runner, nil
+}
+
+// newProcessRunner returns the runner of the local vLLM process, which inherits the proxy's
+// environment and so its Hugging Face token and cache
+func newProcessRunner(config *Config) *process.Runner {
+	return process.NewRunner(&process.Config{
+		Python:       config.VLLMPython,
+		Port:         config.LocalPort,
+		GPUCount:     config.GPUCount,
+		CPUOffloadGB: config.CPUOffloadGB,
+		SleepMode:    config.ScaleStrategy == ScaleStrategyVLLMSleep,
+		StopTimeout:  config.GetShutdownGrace(),
+		SystemdUnit:  config.SystemdUnit,
+	})
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRunner is a vLLM container ready as soon as it is created
+type fakeRunner struct {
+	mu      sync.Mutex
+	model   string // Served name of the running model, empty when stopped
+	drifts  []kubernetes.Drift
+	deletes int
+}
+
+func (f *fakeRunner) CreatePod(_ context.Context, modelConfig *kubernetes.ModelConfig) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.model = modelConfig.ServedModelName
+	return nil
+}
+
+func (f *fakeRunner) DeletePod(context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.model = ""
+	f.deletes++
+	return nil
+}
+
+func (f *fakeRunner) PodExists(context.Context) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.model != "", nil
+}
+
+func (f *fakeRunner) IsPodReady(ctx context.Context) (bool, error) {
+	return f.PodExists(ctx)
+}
+
+func (f *fakeRunner) ConfigDrift(context.Context, *kubernetes.ModelConfig) ([]kubernetes.Drift, error) {
+	return f.drifts, nil
+}
+
+func (f *fakeRunner) VLLMLogTail(context.Context, int64) (string, error) {
+	return "", nil
+}
+
+func TestDockerBackend_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	runner := &fakeRunner{}
+	as := &AutoScaler{
+		config:      &Config{Namespace: "vllm", Deployment: "vllm", Backend: BackendDocker, ConfigDriftAction: ConfigDriftRestart},
+		crdClient:   newFakeCRDClient(t, replicaModelSpec(0, 1)),
+		runner:      runner,
+		activeModel: "qwen",
+		metrics:     stats.NewMetricsRecorder(),
+	}
+
+	// No pod, replica or GPU to look up without Kubernetes
+	require.NoError(t, as.managePod(ctx, true))
+	require.NoError(t, as.waitForReady(ctx, time.Second))
+	assert.Equal(t, "qwen", runner.model)
+	assert.True(t, as.isPodReady(ctx))
+	assert.Empty(t, as.vllmAPIKey(ctx, "qwen"))
+
+	// A drifted container is restarted
+	runner.drifts = []kubernetes.Drift{{Field: "image", Change: `"vllm/vllm-openai:v0.10.0" -> "vllm/vllm-openai:v0.11.0"`}}
+	as.checkConfigDrift(ctx)
+	assert.Equal(t, 1, runner.deletes)
+
+	require.NoError(t, as.managePod(ctx, true))
+	require.NoError(t, as.managePod(ctx, false))
+	exists, err := as.podExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestProcessBackend_ExitStopsChildProcess(t *testing.T) {
+	ctx := context.Background()
+	for _, unit := range []string{"", "vllm"} {
+		runner := &fakeRunner{}
+		as := &AutoScaler{
+			config:  &Config{Backend: BackendProcess, SystemdUnit: unit},
+			runner:  runner,
+			metrics: stats.NewMetricsRecorder(),
+		}
+		require.NoError(t, runner.CreatePod(ctx, &kubernetes.ModelConfig{ServedModelName: "qwen"}))
+		as.exit()
+
+		// A systemd unit outlives the proxy like a pod
+		exists, err := runner.PodExists(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, unit != "", exists, "systemd unit %q", unit)
+	}
+}
@@ -0,0 +1,48 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoScalerBenchmark(t *testing.T) {
+	var model, authorization string
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			_, _ = fmt.Fprint(w, "vllm:gpu_cache_usage_perc{model_name=\"qwen\"} 0.4\n")
+			return
+		}
+		var request struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		model, authorization = request.Model, r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprint(w, `data: {"choices":[{"delta":{"content":"ok"}}]}`+"\n\n")
+		_, _ = fmt.Fprint(w, `data: {"choices":[],"usage":{"completion_tokens":1}}`+"\n\ndata: [DONE]\n\n")
+	}))
+	defer vllm.Close()
+
+	as := newReadyAutoScaler(t, vllm.URL, false)
+	as.vllmAPIKeys.Store(kubernetes.VLLMAPIKeySecret, cachedAPIKey{key: "sk-vllm", read: time.Now()})
+
+	result, err := as.Benchmark(context.Background(), "qwen")
+	require.NoError(t, err)
+	assert.Equal(t, "qwen", result.Model)
+	assert.InDelta(t, 60.0, result.KVCacheHeadroom, 0.001)
+	assert.Equal(t, "qwen", model, "the model is benchmarked under its served name")
+	assert.Equal(t, "Bearer sk-vllm", authorization, "vLLM gets its API key")
+
+	as.crdClient = newFakeCRDClient(t)
+	_, err = as.Benchmark(context.Background(), "qwen")
+	assert.ErrorContains(t, err, "VLLMModel with servedModelName 'qwen' not found")
+}
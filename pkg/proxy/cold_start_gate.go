@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+// coldStartGate caps the number of models starting at once, so concurrent cold starts don't
+// overcommit the GPUs. Queued models get free slots in turn, whatever their number of waiters, so
+// a busy model can't starve the others.
+type coldStartGate struct {
+	mu      sync.Mutex
+	limit   int                        // Concurrent cold starts, 0 = unlimited
+	running int                        // Cold starts holding a slot
+	models  []string                   // Models with waiters, in turn order
+	waiters map[string][]chan struct{} // Waiters per model, in arrival order
+	queued  int
+	metrics *stats.MetricsRecorder
+}
+
+// newColdStartGate creates a gate letting limit models start at once (0 = unlimited)
+func newColdStartGate(limit int, metrics *stats.MetricsRecorder) *coldStartGate {
+	return &coldStartGate{limit: limit, waiters: make(map[string][]chan struct{}), metrics: metrics}
+}
+
+// acquire waits for a cold start slot for the model and returns the function releasing it. A nil
+// gate never waits.
+func (g *coldStartGate) acquire(ctx context.Context, model string) (func(), error) {
+	if g == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	g.mu.Lock()
+	if g.limit <= 0 || (g.running < g.limit && g.queued == 0) {
+		g.running++
+		g.mu.Unlock()
+		g.metrics.RecordColdStartQueueWait(model, 0)
+		return g.release, nil
+	}
+
+	granted := make(chan struct{})
+	if len(g.waiters[model]) == 0 {
+		g.models = append(g.models, model)
+	}
+	g.waiters[model] = append(g.waiters[model], granted)
+	g.queued++
+	g.metrics.SetColdStartQueueLength(g.queued)
+	running := g.running
+	g.mu.Unlock()
+	log.Printf("Cold start of %s queued behind %d starting model(s)", model, running)
+
+	select {
+	case <-granted:
+		wait := time.Since(start)
+		g.metrics.RecordColdStartQueueWait(model, wait)
+		log.Printf("Cold start of %s proceeds after %v in queue", model, wait.Round(time.Second))
+		return g.release, nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		select {
+		case <-granted:
+			// Granted while giving up, hand the slot over
+			g.running--
+			g.grant()
+		default:
+			g.dequeue(model, granted)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// queueLength returns the number of cold starts waiting for a slot, 0 for a nil gate
+func (g *coldStartGate) queueLength() int {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.queued
+}
+
+// release frees a slot for the next queued model
+func (g *coldStartGate) release() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.running--
+	g.grant()
+}
+
+// grant hands the free slots to the queued models in turn, g.mu must be held
+func (g *coldStartGate) grant() {
+	for g.running < g.limit && len(g.models) > 0 {
+		model := g.models[0]
+		g.models = g.models[1:]
+		queue := g.waiters[model]
+		close(queue[0])
+		g.running++
+		g.queued--
+		if len(queue) > 1 {
+			// The model's other waiters go back at the end of the turn
+			g.waiters[model] = queue[1:]
+			g.models = append(g.models, model)
+		} else {
+			delete(g.waiters, model)
+		}
+	}
+	g.metrics.SetColdStartQueueLength(g.queued)
+}
+
+// dequeue removes a waiter that gave up, g.mu must be held
+func (g *coldStartGate) dequeue(model string, granted chan struct{}) {
+	queue := g.waiters[model]
+	for i, waiter := range queue {
+		if waiter == granted {
+			queue = append(queue[:i], queue[i+1:]...)
+			g.queued--
+			break
+		}
+	}
+	if len(queue) > 0 {
+		g.waiters[model] = queue
+	} else {
+		delete(g.waiters, model)
+		for i, m := range g.models {
+			if m == model {
+				g.models = append(g.models[:i], g.models[i+1:]...)
+				break
+			}
+		}
+	}
+	g.metrics.SetColdStartQueueLength(g.queued)
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queueColdStart acquires a slot in the background, once the gate has queued it the model is
+// sent on started when its slot is granted
+func queueColdStart(t *testing.T, g *coldStartGate, model string, started chan<- string) {
+	t.Helper()
+	g.mu.Lock()
+	queued := g.queued
+	g.mu.Unlock()
+
+	go func() {
+		release, err := g.acquire(context.Background(), model)
+		if err != nil {
+			return
+		}
+		started <- model
+		release()
+	}()
+	require.Eventually(t, func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return g.queued == queued+1
+	}, time.Second, time.Millisecond)
+}
+
+func TestColdStartGate_TakesTurnsAcrossModels(t *testing.T) {
+	g := newColdStartGate(1, nil)
+	release, err := g.acquire(context.Background(), "qwen")
+	require.NoError(t, err)
+
+	// Each started model releases its slot at once, letting the next one start
+	started := make(chan string, 3)
+	queueColdStart(t, g, "qwen", started)
+	queueColdStart(t, g, "qwen", started)
+	queueColdStart(t, g, "bge-m3", started)
+	release()
+
+	var order []string
+	for i := 0; i < 3; i++ {
+		order = append(order, <-started)
+	}
+	assert.Equal(t, []string{"qwen", "bge-m3", "qwen"}, order, "a model with several waiters doesn't starve the others")
+}
+
+func TestColdStartGate_Limit(t *testing.T) {
+	g := newColdStartGate(2, nil)
+	ctx := context.Background()
+
+	releaseFirst, err := g.acquire(ctx, "qwen")
+	require.NoError(t, err)
+	_, err = g.acquire(ctx, "bge-m3")
+	require.NoError(t, err, "two models may start at once")
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = g.acquire(timeout, "deepseek")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, g.queued, "a waiter giving up leaves the queue")
+	assert.Empty(t, g.models)
+
+	releaseFirst()
+	_, err = g.acquire(ctx, "deepseek")
+	assert.NoError(t, err)
+}
+
+func TestColdStartGate_Unlimited(t *testing.T) {
+	var g *coldStartGate
+	release, err := g.acquire(context.Background(), "qwen")
+	require.NoError(t, err)
+	release()
+}
@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// coldStartRetryBackoff is the delay before the first retry, doubled on each following one
+const coldStartRetryBackoff = 500 * time.Millisecond
+
+// coldStartRetryPaths are the completion endpoints retried after a cold start. A completion has
+// no side effect on vLLM, sending it again is safe.
+var coldStartRetryPaths = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	anthropicMessagesPath:  true,
+}
+
+// coldStartRetryTransport retries a request whose backend connection fails, or which gets a
+// 502 or 503 while vLLM finishes warming up, replaying its buffered body. Retries happen before
+// anything is sent to the client.
+type coldStartRetryTransport struct {
+	base    http.RoundTripper
+	body    []byte
+	retries int
+	backoff time.Duration
+}
+
+func (t *coldStartRetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	backoff := t.backoff
+	for attempt := 0; ; attempt++ {
+		req.Body = io.NopCloser(bytes.NewReader(t.body))
+		resp, err := t.base.RoundTrip(req)
+		if attempt == t.retries || !retryableAfterColdStart(resp, err) {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Printf("vLLM not reachable after startup (%v), retrying %s in %v", err, req.URL.Path, backoff)
+		} else {
+			log.Printf("vLLM returned %d after startup, retrying %s in %v", resp.StatusCode, req.URL.Path, backoff)
+			_ = resp.Body.Close()
+		}
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// retryableAfterColdStart reports whether the backend failed the way a vLLM still warming up does
+func retryableAfterColdStart(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// coldStartRetry returns a transport retrying the request when it is a completion sent within the
+// retry window after vLLM became ready, or nil. The request body is buffered for the replays.
+func (as *AutoScaler) coldStartRetry(r *http.Request) (http.RoundTripper, error) {
+	window := as.config.GetColdStartRetryWindow()
+	readySince := as.readySince.Load()
+	if window <= 0 || as.config.ColdStartRetries <= 0 || readySince == 0 || r.Body == nil ||
+		r.Method != http.MethodPost || !coldStartRetryPaths[r.URL.Path] ||
+		time.Since(time.Unix(0, readySince)) > window {
+		return nil, nil
+	}
+
+	body, err := parsedBody(r)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body.data))
+	base := as.vllmTransport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &coldStartRetryTransport{
+		base:    base,
+		body:    body.data,
+		retries: as.config.ColdStartRetries,
+		backoff: coldStartRetryBackoff,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestColdStartRetryTransport(t *testing.T) {
+	const body = `{"model":"qwen","messages":[{"role":"user","content":"Hi"}]}`
+
+	var attempts int
+	var bodies []string
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		data, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		bodies = append(bodies, string(data))
+		switch attempts {
+		case 1:
+			return nil, errors.New("connect: connection refused")
+		case 2:
+			return &http.Response{StatusCode: http.StatusBadGateway, Body: io.NopCloser(strings.NewReader("warming up"))}, nil
+		default:
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+		}
+	})
+
+	transport := &coldStartRetryTransport{base: base, body: []byte(body), retries: 3, backoff: time.Millisecond}
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{body, body, body}, bodies, "the body is replayed on each attempt")
+
+	attempts = 0
+	transport.retries = 1
+	resp, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode, "the last response is returned")
+	assert.Equal(t, 2, attempts, "gives up after the retries")
+}
+
+func TestColdStartRetryTransportKeepsErrors(t *testing.T) {
+	var attempts int
+	base := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{StatusCode: http.StatusBadRequest, Body: io.NopCloser(strings.NewReader("bad"))}, nil
+	})
+
+	transport := &coldStartRetryTransport{base: base, retries: 3, backoff: time.Millisecond}
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, 1, attempts, "client errors are not retried")
+}
+
+func TestColdStartRetry(t *testing.T) {
+	newRequest := func(method, path string) *http.Request {
+		return httptest.NewRequest(method, path, strings.NewReader(`{"model":"qwen"}`))
+	}
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		readySince time.Duration
+		retried    bool
+	}{
+		{name: "completion after startup", req: newRequest(http.MethodPost, "/v1/chat/completions"), readySince: 5 * time.Second, retried: true},
+		{name: "anthropic message after startup", req: newRequest(http.MethodPost, anthropicMessagesPath), readySince: 5 * time.Second, retried: true},
+		{name: "window elapsed", req: newRequest(http.MethodPost, "/v1/chat/completions"), readySince: time.Minute},
+		{name: "not a completion", req: newRequest(http.MethodPost, "/v1/embeddings"), readySince: 5 * time.Second},
+		{name: "not a POST", req: newRequest(http.MethodGet, "/v1/chat/completions"), readySince: 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			as := &AutoScaler{config: &Config{ColdStartRetryWindow: "30s", ColdStartRetries: 3}}
+			as.readySince.Store(time.Now().Add(-tt.readySince).UnixNano())
+
+			retry, err := as.coldStartRetry(tt.req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.retried, retry != nil)
+
+			data, err := io.ReadAll(tt.req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, `{"model":"qwen"}`, string(data), "the body is still readable")
+		})
+	}
+
+	as := &AutoScaler{config: &Config{ColdStartRetryWindow: "30s", ColdStartRetries: 3}}
+	retry, err := as.coldStartRetry(newRequest(http.MethodPost, "/v1/chat/completions"))
+	require.NoError(t, err)
+	assert.Nil(t, retry, "not retried before the pod was seen ready")
+}
@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"context"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+// coldStartWaitKey holds the *coldStartWait of a request in its context
+type coldStartWaitKey struct{}
+
+// coldStartWait follows a request through the scale-up: it counts as waiting from the first step
+// that blocks on the model starting, and the time it waited is its cold start penalty
+type coldStartWait struct {
+	metrics *stats.MetricsRecorder
+	model   string
+	since   time.Time // Zero until the request blocks
+}
+
+// withColdStartWait returns a context whose scale-up records the request's wait for the model
+func withColdStartWait(ctx context.Context, metrics *stats.MetricsRecorder, model string) (context.Context, *coldStartWait) {
+	wait := &coldStartWait{metrics: metrics, model: model}
+	return context.WithValue(ctx, coldStartWaitKey{}, wait), wait
+}
+
+// coldStartWaitFrom returns the wait recorded for the request of ctx, nil outside a request
+func coldStartWaitFrom(ctx context.Context) *coldStartWait {
+	wait, _ := ctx.Value(coldStartWaitKey{}).(*coldStartWait)
+	return wait
+}
+
+// block marks the request as waiting for the model to start, from its first blocking step
+func (w *coldStartWait) block() {
+	if w == nil || !w.since.IsZero() {
+		return
+	}
+	w.since = time.Now()
+	w.metrics.AddScaleUpWaitingRequests(1)
+}
+
+// done records the time the request waited, when it did
+func (w *coldStartWait) done() {
+	if w == nil || w.since.IsZero() {
+		return
+	}
+	w.metrics.AddScaleUpWaitingRequests(-1)
+	w.metrics.RecordColdStartWait(w.model, time.Since(w.since))
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColdStartWait(t *testing.T) {
+	as := &AutoScaler{
+		config:      &Config{Namespace: "vllm", Deployment: "vllm", Backend: BackendDocker},
+		crdClient:   newFakeCRDClient(t, replicaModelSpec(0, 1)),
+		runner:      &fakeRunner{},
+		activeModel: "qwen",
+		metrics:     stats.NewMetricsRecorder(),
+	}
+	as.scaleUpCond = sync.NewCond(&as.mu)
+	as.strategy = &deleteStrategy{as: as}
+
+	// The first request starts the model and pays the cold start
+	ctx, cold := withColdStartWait(context.Background(), as.metrics, "qwen")
+	require.NoError(t, as.ensureScaledUp(ctx))
+	cold.done()
+	assert.False(t, cold.since.IsZero())
+
+	// The next one finds it ready
+	ctx, warm := withColdStartWait(context.Background(), as.metrics, "qwen")
+	require.NoError(t, as.ensureScaledUp(ctx))
+	warm.done()
+	assert.True(t, warm.since.IsZero())
+
+	// Scale-ups outside a request are not counted
+	assert.Nil(t, coldStartWaitFrom(context.Background()))
+	coldStartWaitFrom(context.Background()).block()
+}
@@ -2,21 +2,366 @@ package proxy
 
 import (
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/efortin/vllm-chill/pkg/audit"
+	"github.com/efortin/vllm-chill/pkg/federation"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/notify"
+	"github.com/efortin/vllm-chill/pkg/process"
+	"github.com/efortin/vllm-chill/pkg/schedule"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Defaults applied by ApplyDefaults to unset fields
+const (
+	defaultTargetHost          = "vllm-api"
+	defaultTargetPort          = "80"
+	defaultEmbeddingTargetHost = "vllm-embed-api"
+	defaultShadowTargetHost    = "vllm-shadow-api"
+	defaultGPUCount            = 2
+	defaultEmbeddingGPUCount   = 1
+	defaultShadowGPUCount      = 1
+	defaultLocalPort           = 8000
+	defaultShadowSamplePercent = 100
+	defaultSleepLevel          = 1
+	defaultSessionTTL          = "1h"
+	defaultSessionAffinityIdle = "5m"
+	defaultPrefetchTimeout     = "1h"
+	defaultAggressiveIdle      = "1m"
+	defaultScheduleTimezone    = "UTC"
+	defaultBatchConcurrency    = 1
+	defaultResponseCacheTTL    = "10m"
+	defaultPassthroughPaths    = "/tokenize,/detokenize,/pooling,/classify,/score,/rerank"
 )
 
-// Config holds the configuration for the AutoScaler
+// Config holds the configuration for the AutoScaler. It is the single source of settings for the
+// proxy and the Kubernetes managers: the serve command fills it from flags, which default to
+// environment variables, and ApplyDefaults fills what is still unset.
 type Config struct {
 	Namespace      string
 	Deployment     string
 	ConfigMapName  string
 	IdleTimeout    string
+	ShutdownGrace  string // Time vLLM gets to drain on pod deletion before being force-killed (e.g., 30s, 0 = immediate)
 	Port           string
 	LogOutput      bool
 	ModelID        string // Static model ID to load from CRD
 	GPUCount       int    // Number of GPUs to allocate (infrastructure-level)
 	CPUOffloadGB   int    // CPU offload in GB (infrastructure-level)
 	PublicEndpoint string // Public-facing endpoint URL (e.g., https://vllm.sir-alfred.io)
+	XMLFallback    string // XML tool call conversion: on, off or auto (enabled once a parser mismatch is detected)
+
+	// Checking of the tool calls converted from content against the schemas of the request's
+	// tools: coerce (default) arguments of the wrong simple type, strict to also replace calls
+	// still not matching by an error text, or off
+	ToolArgumentValidation string
+
+	// Kubeconfig the proxy reaches the API server with when it runs outside the cluster (default:
+	// the in-cluster config, then $KUBECONFIG and ~/.kube/config)
+	Kubeconfig  string
+	KubeContext string // kubeconfig context (defaults to the current context)
+
+	// Backend running vLLM: kubernetes (default), docker for a local Docker or Podman container on
+	// a workstation without a cluster, or process for vLLM installed on a bare-metal host
+	Backend     string
+	LocalPort   int    // docker and process backends: port vLLM listens on, on 127.0.0.1 (defaults to 8000)
+	ModelsDir   string // docker and process backends: directory of the VLLMModel manifests, read instead of the CRDs
+	DockerHost  string // docker backend: Engine API endpoint (defaults to unix:///var/run/docker.sock)
+	HFCacheDir  string // docker backend: Hugging Face cache mounted in the container (defaults to ~/.cache/huggingface)
+	VLLMPython  string // process backend: interpreter vLLM is installed for (defaults to python3)
+	SystemdUnit string // process backend: run vLLM as this transient systemd user unit instead of a child process
+
+	// vLLM services the proxy forwards to
+	TargetHost          string // Chat model service host (defaults to vllm-api)
+	TargetPort          string // Service port, shared by the chat and embedding services (defaults to 80)
+	EmbeddingTargetHost string // Embedding model service host (defaults to vllm-embed-api)
+	ShadowTargetHost    string // Shadow model service host (defaults to vllm-shadow-api)
+
+	// Idle scale strategy
+	ScaleStrategy   string // How the model is released when idle: delete (default), pause-image or vllm-sleep
+	SleepLevel      int    // vLLM sleep level for the vllm-sleep strategy (1 offloads weights to CPU, 2 discards them)
+	DeepIdleTimeout string // vllm-sleep only: idle time after which the sleeping pod is deleted (empty disables)
+
+	// Models allowed to cold start at once, the others queue in turn (0 = unlimited)
+	MaxConcurrentColdStarts int
+
+	// Whether requests for another model switch the active model: auto (default), manual or
+	// allowlist. The admin and model APIs always switch.
+	SwitchPolicy    string
+	SwitchAllowlist string // Comma-separated served model names requests may switch to with the allowlist policy
+
+	// Time a model switch waits for the in-flight requests of the current model, SSE streams
+	// included, before deleting its pod (e.g., 60s, empty or 0 switches at once)
+	SwitchDrainTimeout string
+
+	// GPU utilization percent at or above which an idle scale-down is deferred, the GPUs being read
+	// through NVML on the proxy's node (0 = scale-down on HTTP activity alone)
+	GPUBusyUtilization int
+
+	// Scheduled windows, each a cron expression opening it and a duration (e.g., "0 8 * * 1-5 10h"), separated by semicolons
+	WarmSchedule          string // Model started when a window opens and kept running regardless of traffic
+	AggressiveSchedule    string // Idle timeout shortened to AggressiveIdleTimeout
+	AggressiveIdleTimeout string // Idle timeout during aggressive windows (defaults to 1m)
+	ScheduleTimezone      string // Time zone the schedules are evaluated in (defaults to UTC)
+
+	// Retry completions whose backend connection fails (or which get a 502/503) shortly after vLLM became ready
+	ColdStartRetryWindow string // Time after the pod became ready during which requests are retried (e.g., 30s, empty or 0 disables)
+	ColdStartRetries     int    // Retries per request, with exponential backoff
+
+	// Interval of the SSE heartbeats sent to streaming requests while vLLM starts (e.g., 10s, empty or 0 disables)
+	SSEHeartbeatInterval string
+
+	// Time a stream from vLLM may go without data before it is cut (e.g., 5m, empty or 0 disables).
+	// The stream's max duration is set per model with maxStreamDuration.
+	StreamIdleTimeout string
+
+	// Add a "vllm_chill" object (cold start, startup time, model switch) to non-streaming responses
+	ResponseAnnotations bool
+
+	// Remove tools from requests whose tool_choice is none, saving the prompt tokens of their schemas
+	DropToolsOnNone bool
+
+	// Reject completion requests vLLM would reject (missing model, messages or prompt, sampling
+	// parameters out of bounds) without waking the model
+	RequestValidation bool
+
+	// Prefix the vLLM metrics bundled in /proxy/metrics with upstream_ and label them with the model
+	RelabelVLLMMetrics bool
+
+	// Proxy requests and responses untouched: no XML tool call conversion, response rewrites,
+	// heartbeats, debug traces or response logging, only scale-to-zero and model switching
+	RawPassthrough bool
+
+	// Comma-separated paths outside /v1 passed through to vLLM, e.g., /tokenize; a path ending in /
+	// passes every path under it, e.g., the methods of a gRPC service. Other paths get a 404.
+	PassthroughPaths string
+
+	// Bearer token for the /proxy/admin API (empty disables the admin API)
+	AdminToken string
+
+	// Largest request body accepted, as a quantity (e.g., 32Mi, empty or 0 = unlimited)
+	MaxRequestBodySize string
+
+	// Largest decoded image of an Anthropic image block, as a quantity (e.g., 5Mi, empty or 0 = unlimited)
+	MaxImageSize string
+
+	// Cache of the responses of deterministic requests (non-streamed, temperature 0), answered
+	// without waking the model
+	ResponseCacheSize string // Total size of the cached responses, as a quantity (e.g., 64Mi, empty or 0 = disabled)
+	ResponseCacheTTL  string // Time a response is served from the cache (defaults to 10m)
+
+	// Page cache warm pool: after scale-down, the weights of the most recently used models are read
+	// on the node so the next cold start loads them from memory
+	PageCacheModels int    // Models kept in the page cache (0 = disabled)
+	PageCacheBudget string // Largest amount of model files read, as a quantity (e.g., 64Gi, empty or 0 = unlimited)
+
+	// Download models into the Hugging Face cache with a Job before creating their pod, so a first
+	// cold start isn't cut by the scale-up timeout while vLLM downloads the weights
+	ModelPrefetch        bool
+	ModelPrefetchTimeout string // Time the download Job gets (defaults to 1h)
+
+	// Per API key rate limits (0 = unlimited), per-key overrides are read from the ConfigMap if set
+	RateLimitRPM       int    // Requests per minute
+	RateLimitTPM       int    // Tokens per minute
+	RateLimitConfigMap string // ConfigMap with a "default" entry and per-key entries keyed by SHA-256 digest
+
+	// Per-user visibility from the user clients declare: the OpenAI user field, or metadata.user_id
+	// on /v1/messages. Users are not authenticated, they share the API key's trust.
+	UserTracking     bool // Read the user of /v1 requests for the per-user metrics and usage accounting
+	MaxUserLabels    int  // Users with their own metrics label, the following ones share "other"
+	RateLimitPerUser bool // Rate limit each user instead of each API key, requires UserTracking
+
+	// Cumulative token budget per client session (X-Session-ID or X-Conversation-ID header)
+	SessionTokenBudget int    // Tokens a session may use (0 = unlimited)
+	SessionTTL         string // Idle time after which a session's usage is forgotten (defaults to 1h)
+
+	// Keep the active model while other sessions (X-Session-ID or X-Conversation-ID header, or
+	// metadata.user_id on /v1/messages) use it, refusing the requests that would switch it
+	SessionAffinity     bool
+	SessionAffinityIdle string // Time without requests after which a session no longer holds the model (defaults to 5m)
+	SessionAffinityWait string // Time a switch waits for the sessions to go idle before being refused (empty or 0 refuses at once)
+
+	// Route each session (X-Session-ID or X-Conversation-ID header) to the same replica, preserving its prefix cache
+	StickySessions bool
+
+	// Secret listing the API keys allowed on /v1 endpoints, with their models and quotas (empty disables key validation)
+	APIKeysSecret string
+
+	// Report /readyz not ready while vLLM isn't, for load balancers that should only route to a
+	// warm backend. Such a load balancer never wakes a scaled-down model.
+	ReadyzRequiresVLLM bool
+
+	// Time /readyz reports not ready after SIGTERM before the listener closes, so the Service stops
+	// routing to this pod and a replacement takes over without refusing connections
+	DrainDelay string
+
+	// Shutdown after SIGTERM, once the listener is closed
+	DrainTimeout    string // Time in-flight requests, SSE streams included, get to complete before being cut (0 = wait indefinitely)
+	ScaleDownOnExit bool   // Release vLLM with the scale strategy on exit instead of leaving it running for the next proxy
+
+	// Set a finalizer on the VLLMModels, so deleting the active model's VLLMModel removes its pods first
+	ModelFinalizers bool
+
+	// What a drift of the vLLM pod from its VLLMModel triggers: restart (default) or warn, which
+	// only logs the drifted fields and reports them in vllm_chill_config_drift
+	ConfigDriftAction string
+
+	// Write the active model's phase, conditions, pod and last activity to its VLLMModel status
+	ModelStatus bool
+
+	// Leader election between proxy replicas: only the leader creates and deletes pods, the others
+	// ask it to scale up through the URL it advertises
+	LeaderElection bool
+	AdvertiseURL   string // URL the other replicas reach this one at, e.g. http://$(POD_IP):8080
+	LeaderToken    string // Bearer token the replicas send the leader their scale-ups with (default: the admin token)
+
+	// ConfigMap persisting the usage accounting served on /proxy/usage (empty keeps it in memory only)
+	UsageConfigMap string
+
+	// Embedding model served by a dedicated pod on /v1/embeddings
+	EmbeddingModelID  string // Served model name of the embedding model (empty disables)
+	EmbeddingGPUCount int    // Number of GPUs for the embedding pod
+
+	// Shadow model mirroring a sample of the completion traffic from a dedicated pod, its responses
+	// never reaching the clients
+	ShadowModelID       string // Served model name of the shadow model (empty disables)
+	ShadowGPUCount      int    // Number of GPUs for the shadow pod
+	ShadowSamplePercent int    // Percentage of the completion requests mirrored (default: 100)
+	ShadowLogResponses  bool   // Log the shadow responses instead of discarding them
+
+	// Tenant namespaces, each running its own vLLM pod, picked by the API key's namespace or the
+	// X-Chill-Namespace header
+	Namespaces        string // Comma-separated list of namespaces served besides the proxy's own
+	NamespaceSelector string // Label selector of the namespaces served, read again periodically
+
+	// Webhook notifications of scale events and failures
+	NotifyWebhooks string // Comma-separated list of format=url webhooks, format being slack, discord or generic (default)
+	NotifyEvents   string // Comma-separated event types posted (empty posts all of them)
+	NotifyTemplate string // text/template of the message, with .Type, .Model, .Message and .Time
+
+	// Audit log of the /v1 requests, for regulated environments keeping a trail of the LLM usage
+	AuditLog          string // File the records are appended to as JSON lines, - for stdout, or http(s) URL they are posted to (empty disables)
+	AuditBodySize     string // Bytes of the request and response bodies kept in each record, as a quantity (e.g., 4Ki, empty or 0 = bodies not recorded)
+	AuditRedactFields string // Comma-separated JSON fields whose values are redacted in the recorded bodies, at any depth (e.g., content,text)
+
+	// OpenTelemetry tracing
+	TracingEndpoint string // OTLP/HTTP endpoint traces are exported to, e.g. http://otel-collector:4318 (empty disables tracing)
+
+	// Anthropic Message Batches
+	MessageBatches   bool // Serve /v1/messages/batches, running the batched requests through the proxy
+	BatchConcurrency int  // Batched requests running at once across batches (default: 1)
+
+	// Federation with remote vllm-chill peers
+	FederationPeers   string // Comma-separated list of name=url peers
+	FederationTLSCert string // Client certificate for mTLS to peers
+	FederationTLSKey  string // Client key for mTLS to peers
+	FederationTLSCA   string // CA bundle used to verify peers
+
+	// TLS termination on the proxy's port, with the certificate and key of files, reloaded when
+	// they change (e.g., a mounted Secret renewed by cert-manager), or of a kubernetes.io/tls Secret
+	TLSCert   string // Certificate file (empty serves plain HTTP)
+	TLSKey    string // Key file
+	TLSSecret string // Secret holding tls.crt and tls.key, read through the API and periodically reloaded
+
+	// TLS between the proxy and vLLM, for clusters where plaintext pod-to-pod traffic isn't acceptable
+	VLLMTLSSecret     string // kubernetes.io/tls Secret mounted in the vLLM pods, which then serve HTTPS with its tls.crt and tls.key (empty disables)
+	VLLMTLSCA         string // CA bundle verifying vLLM (defaults to the ca.crt of VLLMTLSSecret, then the system roots)
+	VLLMTLSCert       string // Client certificate presented to vLLM, which then requires one signed by the ca.crt of VLLMTLSSecret
+	VLLMTLSKey        string // Client key presented to vLLM
+	VLLMTLSServerName string // Name sent as SNI and verified in vLLM's certificate (defaults to the host dialed)
+
+	// API key the vLLM pods require, sent by the proxy in place of the client's key
+	VLLMAPIKeySecret string // Secret holding the key under api-key, unless the model names its own (defaults to vllm-api-key)
+
+	// HTTP/2 cleartext between the proxy and vLLM, for vLLM gRPC servers; over TLS, HTTP/2 is negotiated
+	VLLMH2C bool // Reach vLLM over HTTP/2 with prior knowledge instead of HTTP/1.1
+}
+
+// ApplyDefaults fills unset optional fields with their defaults
+func (c *Config) ApplyDefaults() {
+	if c.Backend == "" {
+		c.Backend = BackendKubernetes
+	}
+	if c.localBackend() && c.LocalPort == 0 {
+		c.LocalPort = defaultLocalPort
+	}
+	if c.Backend == BackendProcess && c.VLLMPython == "" {
+		c.VLLMPython = process.DefaultPython
+	}
+	if c.TargetHost == "" {
+		c.TargetHost = defaultTargetHost
+	}
+	if c.TargetPort == "" {
+		c.TargetPort = defaultTargetPort
+	}
+	if c.EmbeddingTargetHost == "" {
+		c.EmbeddingTargetHost = defaultEmbeddingTargetHost
+	}
+	if c.ShadowTargetHost == "" {
+		c.ShadowTargetHost = defaultShadowTargetHost
+	}
+	if c.VLLMAPIKeySecret == "" {
+		c.VLLMAPIKeySecret = kubernetes.VLLMAPIKeySecret
+	}
+	if c.ScaleStrategy == "" {
+		c.ScaleStrategy = ScaleStrategyDelete
+	}
+	if c.ScaleStrategy == ScaleStrategyVLLMSleep && c.SleepLevel == 0 {
+		c.SleepLevel = defaultSleepLevel
+	}
+	if c.XMLFallback == "" {
+		c.XMLFallback = XMLFallbackOn
+	}
+	if c.ToolArgumentValidation == "" {
+		c.ToolArgumentValidation = ToolArgsCoerce
+	}
+	if c.PassthroughPaths == "" {
+		c.PassthroughPaths = defaultPassthroughPaths
+	}
+	if c.ConfigDriftAction == "" {
+		c.ConfigDriftAction = ConfigDriftRestart
+	}
+	if c.SwitchPolicy == "" {
+		c.SwitchPolicy = SwitchPolicyAuto
+	}
+	if c.GPUCount == 0 {
+		c.GPUCount = defaultGPUCount
+	}
+	if c.ModelPrefetch && c.ModelPrefetchTimeout == "" {
+		c.ModelPrefetchTimeout = defaultPrefetchTimeout
+	}
+	if c.SessionTokenBudget > 0 && c.SessionTTL == "" {
+		c.SessionTTL = defaultSessionTTL
+	}
+	if c.SessionAffinity && c.SessionAffinityIdle == "" {
+		c.SessionAffinityIdle = defaultSessionAffinityIdle
+	}
+	if c.AggressiveSchedule != "" && c.AggressiveIdleTimeout == "" {
+		c.AggressiveIdleTimeout = defaultAggressiveIdle
+	}
+	if (c.WarmSchedule != "" || c.AggressiveSchedule != "") && c.ScheduleTimezone == "" {
+		c.ScheduleTimezone = defaultScheduleTimezone
+	}
+	if c.EmbeddingModelID != "" && c.EmbeddingGPUCount == 0 {
+		c.EmbeddingGPUCount = defaultEmbeddingGPUCount
+	}
+	if c.ShadowModelID != "" && c.ShadowGPUCount == 0 {
+		c.ShadowGPUCount = defaultShadowGPUCount
+	}
+	if c.ShadowModelID != "" && c.ShadowSamplePercent == 0 {
+		c.ShadowSamplePercent = defaultShadowSamplePercent
+	}
+	if c.GetResponseCacheSize() > 0 && c.ResponseCacheTTL == "" {
+		c.ResponseCacheTTL = defaultResponseCacheTTL
+	}
+	if c.MessageBatches && c.BatchConcurrency == 0 {
+		c.BatchConcurrency = defaultBatchConcurrency
+	}
 }
 
 // Validate checks if the configuration is valid
@@ -33,9 +378,257 @@ func (c *Config) Validate() error {
 	if _, err := time.ParseDuration(c.IdleTimeout); err != nil {
 		return fmt.Errorf("invalid idle timeout: %w", err)
 	}
+	if c.ShutdownGrace != "" {
+		if d, err := time.ParseDuration(c.ShutdownGrace); err != nil || d < 0 {
+			return fmt.Errorf("invalid shutdown grace period %q", c.ShutdownGrace)
+		}
+	}
+	if c.DrainDelay != "" {
+		if d, err := time.ParseDuration(c.DrainDelay); err != nil || d < 0 {
+			return fmt.Errorf("invalid drain delay %q", c.DrainDelay)
+		}
+	}
+	if c.DrainTimeout != "" {
+		if d, err := time.ParseDuration(c.DrainTimeout); err != nil || d < 0 {
+			return fmt.Errorf("invalid drain timeout %q", c.DrainTimeout)
+		}
+	}
+	if c.SwitchDrainTimeout != "" {
+		if d, err := time.ParseDuration(c.SwitchDrainTimeout); err != nil || d < 0 {
+			return fmt.Errorf("invalid switch drain timeout %q", c.SwitchDrainTimeout)
+		}
+	}
 	if c.ModelID == "" {
 		return fmt.Errorf("model ID cannot be empty")
 	}
+	if c.TargetPort != "" {
+		if port, err := strconv.Atoi(c.TargetPort); err != nil || port < 1 || port > 65535 {
+			return fmt.Errorf("invalid target port %q", c.TargetPort)
+		}
+	}
+	if c.GPUCount < 0 || c.EmbeddingGPUCount < 0 || c.ShadowGPUCount < 0 {
+		return fmt.Errorf("GPU counts cannot be negative")
+	}
+	if c.MaxRequestBodySize != "" {
+		if q, err := resource.ParseQuantity(c.MaxRequestBodySize); err != nil || q.Sign() < 0 {
+			return fmt.Errorf("invalid max request body size %q", c.MaxRequestBodySize)
+		}
+	}
+	if c.MaxImageSize != "" {
+		if q, err := resource.ParseQuantity(c.MaxImageSize); err != nil || q.Sign() < 0 {
+			return fmt.Errorf("invalid max image size %q", c.MaxImageSize)
+		}
+	}
+	if err := audit.ParseSink(c.AuditLog); err != nil {
+		return err
+	}
+	if c.AuditBodySize != "" {
+		if q, err := resource.ParseQuantity(c.AuditBodySize); err != nil || q.Sign() < 0 {
+			return fmt.Errorf("invalid audit body size %q", c.AuditBodySize)
+		}
+	}
+	if c.ResponseCacheSize != "" {
+		if q, err := resource.ParseQuantity(c.ResponseCacheSize); err != nil || q.Sign() < 0 {
+			return fmt.Errorf("invalid response cache size %q", c.ResponseCacheSize)
+		}
+	}
+	if c.ResponseCacheTTL != "" {
+		if d, err := time.ParseDuration(c.ResponseCacheTTL); err != nil || d <= 0 {
+			return fmt.Errorf("invalid response cache TTL %q", c.ResponseCacheTTL)
+		}
+	}
+	if c.PageCacheModels < 0 {
+		return fmt.Errorf("page cache models cannot be negative")
+	}
+	if c.PageCacheBudget != "" {
+		if q, err := resource.ParseQuantity(c.PageCacheBudget); err != nil || q.Sign() < 0 {
+			return fmt.Errorf("invalid page cache budget %q", c.PageCacheBudget)
+		}
+	}
+	if c.ModelPrefetchTimeout != "" {
+		if d, err := time.ParseDuration(c.ModelPrefetchTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid model prefetch timeout %q", c.ModelPrefetchTimeout)
+		}
+	}
+	if c.RateLimitRPM < 0 || c.RateLimitTPM < 0 {
+		return fmt.Errorf("rate limits cannot be negative")
+	}
+	if c.MaxUserLabels < 0 {
+		return fmt.Errorf("max user labels cannot be negative")
+	}
+	if c.RateLimitPerUser && !c.UserTracking {
+		return fmt.Errorf("per-user rate limits require user tracking")
+	}
+	if c.SessionTokenBudget < 0 {
+		return fmt.Errorf("session token budget cannot be negative")
+	}
+	if c.SessionTTL != "" {
+		if d, err := time.ParseDuration(c.SessionTTL); err != nil || d <= 0 {
+			return fmt.Errorf("invalid session TTL %q", c.SessionTTL)
+		}
+	}
+	if c.SessionAffinityIdle != "" {
+		if d, err := time.ParseDuration(c.SessionAffinityIdle); err != nil || d <= 0 {
+			return fmt.Errorf("invalid session affinity idle time %q", c.SessionAffinityIdle)
+		}
+	}
+	if c.SessionAffinityWait != "" {
+		if d, err := time.ParseDuration(c.SessionAffinityWait); err != nil || d < 0 {
+			return fmt.Errorf("invalid session affinity wait %q", c.SessionAffinityWait)
+		}
+	}
+	switch c.ConfigDriftAction {
+	case "", ConfigDriftRestart, ConfigDriftWarn:
+	default:
+		return fmt.Errorf("invalid config drift action %q (expected restart or warn)", c.ConfigDriftAction)
+	}
+	switch c.SwitchPolicy {
+	case "", SwitchPolicyAuto, SwitchPolicyManual:
+	case SwitchPolicyAllowlist:
+		if len(c.switchAllowlist()) == 0 {
+			return fmt.Errorf("the allowlist switch policy needs a switch allowlist")
+		}
+	default:
+		return fmt.Errorf("invalid switch policy %q (expected auto, manual or allowlist)", c.SwitchPolicy)
+	}
+	if c.LeaderElection {
+		if u, err := url.Parse(c.AdvertiseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid advertise URL %q, leader election needs the URL the other replicas reach this one at", c.AdvertiseURL)
+		}
+		if c.GetLeaderToken() == "" {
+			return fmt.Errorf("leader election needs a leader token or an admin token to authenticate the replicas")
+		}
+	}
+	switch c.ScaleStrategy {
+	case "", ScaleStrategyDelete, ScaleStrategyPauseImage, ScaleStrategyVLLMSleep:
+	default:
+		return fmt.Errorf("invalid scale strategy %q (expected delete, pause-image or vllm-sleep)", c.ScaleStrategy)
+	}
+	switch c.Backend {
+	case "", BackendKubernetes:
+	case BackendDocker, BackendProcess:
+		if err := c.validateLocalBackend(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid backend %q (expected kubernetes, docker or process)", c.Backend)
+	}
+	if c.ScaleStrategy == ScaleStrategyVLLMSleep {
+		if c.SleepLevel != 1 && c.SleepLevel != 2 {
+			return fmt.Errorf("invalid sleep level %d (expected 1 or 2)", c.SleepLevel)
+		}
+		if c.DeepIdleTimeout != "" {
+			d, err := time.ParseDuration(c.DeepIdleTimeout)
+			if err != nil {
+				return fmt.Errorf("invalid deep idle timeout: %w", err)
+			}
+			if d <= c.GetIdleTimeout() {
+				return fmt.Errorf("deep idle timeout (%s) must be longer than the idle timeout (%s)", c.DeepIdleTimeout, c.IdleTimeout)
+			}
+		}
+	}
+	if c.ColdStartRetryWindow != "" {
+		if d, err := time.ParseDuration(c.ColdStartRetryWindow); err != nil || d < 0 {
+			return fmt.Errorf("invalid cold start retry window %q", c.ColdStartRetryWindow)
+		}
+	}
+	if c.SSEHeartbeatInterval != "" {
+		if d, err := time.ParseDuration(c.SSEHeartbeatInterval); err != nil || d < 0 {
+			return fmt.Errorf("invalid SSE heartbeat interval %q", c.SSEHeartbeatInterval)
+		}
+	}
+	if c.StreamIdleTimeout != "" {
+		if d, err := time.ParseDuration(c.StreamIdleTimeout); err != nil || d < 0 {
+			return fmt.Errorf("invalid stream idle timeout %q", c.StreamIdleTimeout)
+		}
+	}
+	if c.MaxConcurrentColdStarts < 0 {
+		return fmt.Errorf("max concurrent cold starts cannot be negative")
+	}
+	if c.ColdStartRetries < 0 {
+		return fmt.Errorf("cold start retries cannot be negative")
+	}
+	if _, err := schedule.Parse(c.WarmSchedule); err != nil {
+		return fmt.Errorf("invalid warm schedule: %w", err)
+	}
+	if _, err := schedule.Parse(c.AggressiveSchedule); err != nil {
+		return fmt.Errorf("invalid aggressive schedule: %w", err)
+	}
+	if c.AggressiveIdleTimeout != "" {
+		if d, err := time.ParseDuration(c.AggressiveIdleTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("invalid aggressive idle timeout %q", c.AggressiveIdleTimeout)
+		}
+	}
+	if c.ScheduleTimezone != "" {
+		if _, err := time.LoadLocation(c.ScheduleTimezone); err != nil {
+			return fmt.Errorf("invalid schedule time zone %q", c.ScheduleTimezone)
+		}
+	}
+	switch c.XMLFallback {
+	case "", XMLFallbackOn, XMLFallbackOff, XMLFallbackAuto:
+	default:
+		return fmt.Errorf("invalid XML fallback mode %q (expected on, off or auto)", c.XMLFallback)
+	}
+	switch c.ToolArgumentValidation {
+	case "", ToolArgsCoerce, ToolArgsStrict, ToolArgsOff:
+	default:
+		return fmt.Errorf("invalid tool argument validation mode %q (expected coerce, strict or off)", c.ToolArgumentValidation)
+	}
+	if _, err := notify.ParseWebhooks(c.NotifyWebhooks); err != nil {
+		return fmt.Errorf("invalid notification webhooks: %w", err)
+	}
+	if _, err := notify.ParseEvents(c.NotifyEvents); err != nil {
+		return fmt.Errorf("invalid notification events: %w", err)
+	}
+	if _, err := notify.ParseTemplate(c.NotifyTemplate); err != nil {
+		return err
+	}
+	if c.TracingEndpoint != "" {
+		if u, err := url.Parse(c.TracingEndpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid tracing endpoint %q, expected the URL of an OTLP/HTTP collector", c.TracingEndpoint)
+		}
+	}
+	for _, path := range c.passthroughPathList() {
+		if path == "/" || !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "/v1/") || strings.HasPrefix(path, "/proxy/") {
+			return fmt.Errorf("invalid passthrough path %q, expected an absolute path outside /v1 and /proxy", path)
+		}
+	}
+	if c.BatchConcurrency < 0 {
+		return fmt.Errorf("batch concurrency cannot be negative")
+	}
+	if c.GPUBusyUtilization < 0 || c.GPUBusyUtilization > 100 {
+		return fmt.Errorf("GPU busy utilization must be between 0 and 100")
+	}
+	if c.ShadowSamplePercent < 0 || c.ShadowSamplePercent > 100 {
+		return fmt.Errorf("shadow sample percent must be between 0 and 100")
+	}
+	if c.ShadowModelID != "" && c.ShadowModelID == c.ModelID {
+		return fmt.Errorf("the shadow model must differ from the primary model %q", c.ModelID)
+	}
+	if _, err := labels.Parse(c.NamespaceSelector); err != nil {
+		return fmt.Errorf("invalid namespace selector: %w", err)
+	}
+	if _, err := federation.ParsePeers(c.FederationPeers); err != nil {
+		return fmt.Errorf("invalid federation peers: %w", err)
+	}
+	if (c.FederationTLSCert == "") != (c.FederationTLSKey == "") {
+		return fmt.Errorf("federation TLS cert and key must be set together")
+	}
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return fmt.Errorf("TLS cert and key must be set together")
+	}
+	if c.TLSCert != "" && c.TLSSecret != "" {
+		return fmt.Errorf("TLS cert files and a TLS Secret cannot be used together")
+	}
+	if (c.VLLMTLSCert == "") != (c.VLLMTLSKey == "") {
+		return fmt.Errorf("vLLM TLS cert and key must be set together")
+	}
+	if c.VLLMTLSSecret == "" && (c.VLLMTLSCA != "" || c.VLLMTLSCert != "" || c.VLLMTLSServerName != "") {
+		return fmt.Errorf("vLLM TLS settings need a vLLM TLS Secret")
+	}
+	if c.VLLMH2C && c.VLLMTLSSecret != "" {
+		return fmt.Errorf("vLLM h2c cannot be used with a vLLM TLS Secret, HTTP/2 is negotiated over TLS")
+	}
 	return nil
 }
 
@@ -44,3 +637,505 @@ func (c *Config) GetIdleTimeout() time.Duration {
 	d, _ := time.ParseDuration(c.IdleTimeout)
 	return d
 }
+
+// GetShutdownGrace parses and returns the pod shutdown grace period (0 when unset)
+func (c *Config) GetShutdownGrace() time.Duration {
+	d, _ := time.ParseDuration(c.ShutdownGrace)
+	return d
+}
+
+// GetDrainDelay parses and returns the readiness drain delay (0 when unset)
+func (c *Config) GetDrainDelay() time.Duration {
+	d, _ := time.ParseDuration(c.DrainDelay)
+	return d
+}
+
+// GetDrainTimeout parses and returns the in-flight request drain timeout (0 when unset)
+func (c *Config) GetDrainTimeout() time.Duration {
+	d, _ := time.ParseDuration(c.DrainTimeout)
+	return d
+}
+
+// GetSwitchDrainTimeout parses and returns the time a model switch waits for in-flight requests (0 when unset)
+func (c *Config) GetSwitchDrainTimeout() time.Duration {
+	d, _ := time.ParseDuration(c.SwitchDrainTimeout)
+	return d
+}
+
+// GetDeepIdleTimeout parses and returns the deep idle timeout (0 when unset)
+func (c *Config) GetDeepIdleTimeout() time.Duration {
+	d, _ := time.ParseDuration(c.DeepIdleTimeout)
+	return d
+}
+
+// GetSessionTTL parses and returns the session idle TTL (0 when unset)
+func (c *Config) GetSessionTTL() time.Duration {
+	d, _ := time.ParseDuration(c.SessionTTL)
+	return d
+}
+
+// GetSessionAffinityIdle parses and returns the time after which a session no longer holds the model (0 when unset)
+func (c *Config) GetSessionAffinityIdle() time.Duration {
+	d, _ := time.ParseDuration(c.SessionAffinityIdle)
+	return d
+}
+
+// GetSessionAffinityWait parses and returns the time a switch waits for the sessions to go idle (0 when unset)
+func (c *Config) GetSessionAffinityWait() time.Duration {
+	d, _ := time.ParseDuration(c.SessionAffinityWait)
+	return d
+}
+
+// GetColdStartRetryWindow parses and returns the cold start retry window (0 when unset)
+func (c *Config) GetColdStartRetryWindow() time.Duration {
+	d, _ := time.ParseDuration(c.ColdStartRetryWindow)
+	return d
+}
+
+// GetModelPrefetchTimeout parses and returns the time a model download Job gets (0 when unset)
+func (c *Config) GetModelPrefetchTimeout() time.Duration {
+	d, _ := time.ParseDuration(c.ModelPrefetchTimeout)
+	return d
+}
+
+// GetStreamIdleTimeout parses and returns the time a stream may go without data (0 when unset)
+func (c *Config) GetStreamIdleTimeout() time.Duration {
+	d, _ := time.ParseDuration(c.StreamIdleTimeout)
+	return d
+}
+
+// GetLeaderToken returns the token authenticating the replicas to the leader, the admin token
+// when no leader token is set
+func (c *Config) GetLeaderToken() string {
+	if c.LeaderToken != "" {
+		return c.LeaderToken
+	}
+	return c.AdminToken
+}
+
+// GetSSEHeartbeatInterval parses and returns the SSE heartbeat interval (0 when unset)
+func (c *Config) GetSSEHeartbeatInterval() time.Duration {
+	d, _ := time.ParseDuration(c.SSEHeartbeatInterval)
+	return d
+}
+
+// GetWarmSchedule parses and returns the warm-up windows (empty when unset)
+func (c *Config) GetWarmSchedule() schedule.Schedule {
+	s, _ := schedule.Parse(c.WarmSchedule)
+	return s
+}
+
+// GetAggressiveSchedule parses and returns the aggressive idle windows (empty when unset)
+func (c *Config) GetAggressiveSchedule() schedule.Schedule {
+	s, _ := schedule.Parse(c.AggressiveSchedule)
+	return s
+}
+
+// GetAggressiveIdleTimeout parses and returns the idle timeout of aggressive windows (0 when unset)
+func (c *Config) GetAggressiveIdleTimeout() time.Duration {
+	d, _ := time.ParseDuration(c.AggressiveIdleTimeout)
+	return d
+}
+
+// GetScheduleLocation returns the time zone of the schedules (UTC when unset)
+func (c *Config) GetScheduleLocation() *time.Location {
+	location, err := time.LoadLocation(c.ScheduleTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// GetMaxRequestBodySize returns the request body limit in bytes (0 when unlimited)
+func (c *Config) GetMaxRequestBodySize() int64 {
+	if c.MaxRequestBodySize == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(c.MaxRequestBodySize)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// GetResponseCacheSize returns the size of the response cache in bytes (0 when disabled)
+func (c *Config) GetResponseCacheSize() int64 {
+	if c.ResponseCacheSize == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(c.ResponseCacheSize)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// GetResponseCacheTTL parses and returns the time a response is cached (0 when unset)
+func (c *Config) GetResponseCacheTTL() time.Duration {
+	d, _ := time.ParseDuration(c.ResponseCacheTTL)
+	return d
+}
+
+// GetMaxImageSize returns the decoded image size limit in bytes (0 when unlimited)
+func (c *Config) GetMaxImageSize() int64 {
+	if c.MaxImageSize == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(c.MaxImageSize)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// GetAuditBodySize returns the bytes of the bodies kept in the audit records (0 when not recorded)
+func (c *Config) GetAuditBodySize() int64 {
+	if c.AuditBodySize == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(c.AuditBodySize)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// GetPageCacheBudget returns the page cache budget in bytes (0 when unlimited)
+func (c *Config) GetPageCacheBudget() int64 {
+	if c.PageCacheBudget == "" {
+		return 0
+	}
+	q, err := resource.ParseQuantity(c.PageCacheBudget)
+	if err != nil {
+		return 0
+	}
+	return q.Value()
+}
+
+// rateLimited reports whether rate limiting is enabled, globally or through per-key limits and tenant quotas
+func (c *Config) rateLimited() bool {
+	return c.RateLimitRPM > 0 || c.RateLimitTPM > 0 || c.RateLimitConfigMap != "" || c.APIKeysSecret != ""
+}
+
+// redacted replaces a secret value, keeping whether it is set visible
+func redacted(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
+// Effective returns the configuration with defaults applied and secrets redacted
+func (c *Config) Effective() map[string]interface{} {
+	d := *c
+	d.ApplyDefaults()
+
+	effective := map[string]interface{}{
+		"namespace":             d.Namespace,
+		"deployment":            d.Deployment,
+		"configmap":             d.ConfigMapName,
+		"port":                  d.Port,
+		"model_id":              d.ModelID,
+		"kubeconfig":            d.Kubeconfig,
+		"kube_context":          d.KubeContext,
+		"backend":               d.Backend,
+		"public_endpoint":       d.PublicEndpoint,
+		"idle_timeout":          d.GetIdleTimeout().String(),
+		"shutdown_grace_period": d.GetShutdownGrace().String(),
+		"drain_delay":           d.GetDrainDelay().String(),
+		"drain_timeout":         d.GetDrainTimeout().String(),
+		"scale_down_on_exit":    d.ScaleDownOnExit,
+		"model_finalizers":      d.ModelFinalizers,
+		"config_drift_action":   d.ConfigDriftAction,
+		"switch_policy":         d.SwitchPolicy,
+		"switch_drain_timeout":  d.GetSwitchDrainTimeout().String(),
+		"gpu_busy_utilization":  d.GPUBusyUtilization,
+		"model_status":          d.ModelStatus,
+		"leader_election":       d.LeaderElection,
+		"cold_start_retry":      d.GetColdStartRetryWindow().String(),
+		"max_cold_starts":       d.MaxConcurrentColdStarts,
+		"cold_start_retries":    d.ColdStartRetries,
+		"sse_heartbeat":         d.GetSSEHeartbeatInterval().String(),
+		"stream_idle_timeout":   d.GetStreamIdleTimeout().String(),
+		"gpu_count":             d.GPUCount,
+		"cpu_offload_gb":        d.CPUOffloadGB,
+		"log_output":            d.LogOutput,
+		"xml_fallback":          d.XMLFallback,
+		"tool_arg_validation":   d.ToolArgumentValidation,
+		"response_annotations":  d.ResponseAnnotations,
+		"drop_tools_on_none":    d.DropToolsOnNone,
+		"request_validation":    d.RequestValidation,
+		"relabel_vllm_metrics":  d.RelabelVLLMMetrics,
+		"raw_passthrough":       d.RawPassthrough,
+		"passthrough_paths":     d.PassthroughPaths,
+		"scale_strategy":        d.ScaleStrategy,
+		"admin_token":           redacted(d.AdminToken),
+		"max_request_body_size": d.GetMaxRequestBodySize(),
+		"max_image_size":        d.GetMaxImageSize(),
+		"api_keys_secret":       d.APIKeysSecret,
+		"sticky_sessions":       d.StickySessions,
+		"readyz_requires_vllm":  d.ReadyzRequiresVLLM,
+		"usage_configmap":       d.UsageConfigMap,
+	}
+	if d.localBackend() {
+		effective["local_port"] = d.LocalPort
+		effective["models_dir"] = d.ModelsDir
+	}
+	switch d.Backend {
+	case BackendDocker:
+		effective["docker_host"] = d.DockerHost
+		effective["hf_cache_dir"] = d.HFCacheDir
+	case BackendProcess:
+		effective["vllm_python"] = d.VLLMPython
+		effective["systemd_unit"] = d.SystemdUnit
+	}
+	if d.PageCacheModels > 0 {
+		effective["page_cache_models"] = d.PageCacheModels
+		effective["page_cache_budget"] = d.GetPageCacheBudget()
+	}
+	if d.ModelPrefetch {
+		effective["model_prefetch"] = d.ModelPrefetch
+		effective["model_prefetch_timeout"] = d.GetModelPrefetchTimeout().String()
+	}
+	if d.ScaleStrategy == ScaleStrategyVLLMSleep {
+		effective["sleep_level"] = d.SleepLevel
+		effective["deep_idle_timeout"] = d.GetDeepIdleTimeout().String()
+	}
+	if d.rateLimited() {
+		effective["rate_limit_rpm"] = d.RateLimitRPM
+		effective["rate_limit_tpm"] = d.RateLimitTPM
+		effective["rate_limit_configmap"] = d.RateLimitConfigMap
+	}
+	if d.UserTracking {
+		effective["user_tracking"] = d.UserTracking
+		effective["max_user_labels"] = d.MaxUserLabels
+		effective["rate_limit_per_user"] = d.RateLimitPerUser
+	}
+	if d.WarmSchedule != "" || d.AggressiveSchedule != "" {
+		effective["warm_schedule"] = d.WarmSchedule
+		effective["aggressive_schedule"] = d.AggressiveSchedule
+		effective["aggressive_idle_timeout"] = d.GetAggressiveIdleTimeout().String()
+		effective["schedule_timezone"] = d.ScheduleTimezone
+	}
+	if d.SessionTokenBudget > 0 {
+		effective["session_token_budget"] = d.SessionTokenBudget
+		effective["session_ttl"] = d.GetSessionTTL().String()
+	}
+	if d.SessionAffinity {
+		effective["session_affinity_idle"] = d.GetSessionAffinityIdle().String()
+		effective["session_affinity_wait"] = d.GetSessionAffinityWait().String()
+	}
+	if d.SwitchPolicy == SwitchPolicyAllowlist {
+		effective["switch_allowlist"] = d.SwitchAllowlist
+	}
+	if d.LeaderElection {
+		effective["advertise_url"] = d.AdvertiseURL
+		effective["leader_token"] = redacted(d.LeaderToken)
+	}
+	if d.EmbeddingModelID != "" {
+		effective["embedding_model_id"] = d.EmbeddingModelID
+		effective["embedding_gpu_count"] = d.EmbeddingGPUCount
+	}
+	if d.ShadowModelID != "" {
+		effective["shadow_model_id"] = d.ShadowModelID
+		effective["shadow_gpu_count"] = d.ShadowGPUCount
+		effective["shadow_sample_percent"] = d.ShadowSamplePercent
+		effective["shadow_log_responses"] = d.ShadowLogResponses
+	}
+	if d.Namespaces != "" || d.NamespaceSelector != "" {
+		effective["namespaces"] = d.Namespaces
+		effective["namespace_selector"] = d.NamespaceSelector
+	}
+	if d.NotifyWebhooks != "" {
+		effective["notify_webhooks"] = redacted(d.NotifyWebhooks)
+		effective["notify_events"] = d.NotifyEvents
+		effective["notify_template"] = d.NotifyTemplate
+	}
+	if d.TracingEndpoint != "" {
+		effective["tracing_endpoint"] = d.TracingEndpoint
+	}
+	if d.AuditLog != "" {
+		effective["audit_log"] = d.AuditLog
+		if strings.Contains(d.AuditLog, "://") {
+			effective["audit_log"] = redacted(d.AuditLog) // The URL may hold a token
+		}
+		effective["audit_body_size"] = d.GetAuditBodySize()
+		effective["audit_redact_fields"] = d.AuditRedactFields
+	}
+	if d.GetResponseCacheSize() > 0 {
+		effective["response_cache_size"] = d.GetResponseCacheSize()
+		effective["response_cache_ttl"] = d.GetResponseCacheTTL().String()
+	}
+	if d.MessageBatches {
+		effective["message_batches"] = d.MessageBatches
+		effective["batch_concurrency"] = d.BatchConcurrency
+	}
+	if d.FederationPeers != "" {
+		effective["federation_peers"] = d.FederationPeers
+		effective["federation_tls_cert"] = d.FederationTLSCert
+		effective["federation_tls_key"] = redacted(d.FederationTLSKey)
+		effective["federation_tls_ca"] = d.FederationTLSCA
+	}
+	if d.TLSCert != "" || d.TLSSecret != "" {
+		effective["tls_cert"] = d.TLSCert
+		effective["tls_key"] = redacted(d.TLSKey)
+		effective["tls_secret"] = d.TLSSecret
+	}
+	if d.VLLMTLSSecret != "" {
+		effective["vllm_tls_secret"] = d.VLLMTLSSecret
+		effective["vllm_tls_ca"] = d.VLLMTLSCA
+		effective["vllm_tls_cert"] = d.VLLMTLSCert
+		effective["vllm_tls_key"] = redacted(d.VLLMTLSKey)
+		effective["vllm_tls_server_name"] = d.VLLMTLSServerName
+	}
+	if d.Backend == BackendKubernetes {
+		effective["vllm_api_key_secret"] = d.VLLMAPIKeySecret
+	}
+	if d.VLLMH2C {
+		effective["vllm_h2c"] = true
+	}
+	return effective
+}
+
+// federationTLS returns the mTLS settings for peers, or nil when none are configured
+func (c *Config) federationTLS() *federation.TLSConfig {
+	if c.FederationTLSCert == "" && c.FederationTLSCA == "" {
+		return nil
+	}
+	return &federation.TLSConfig{
+		CertFile: c.FederationTLSCert,
+		KeyFile:  c.FederationTLSKey,
+		CAFile:   c.FederationTLSCA,
+	}
+}
+
+// localBackend reports whether vLLM runs on the proxy's host rather than in the cluster
+func (c *Config) localBackend() bool {
+	return c.Backend == BackendDocker || c.Backend == BackendProcess
+}
+
+// validateLocalBackend checks the docker and process backend settings, and that no feature
+// relying on the cluster is enabled
+func (c *Config) validateLocalBackend() error {
+	if c.ModelsDir == "" {
+		return fmt.Errorf("the %s backend needs a models directory", c.Backend)
+	}
+	if c.LocalPort < 0 || c.LocalPort > 65535 {
+		return fmt.Errorf("invalid local port %d", c.LocalPort)
+	}
+	for _, feature := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"the pause-image scale strategy", c.ScaleStrategy == ScaleStrategyPauseImage},
+		{"leader election", c.LeaderElection},
+		{"model prefetch", c.ModelPrefetch},
+		{"the page cache warm pool", c.PageCacheModels > 0},
+		{"an API keys Secret", c.APIKeysSecret != ""},
+		{"a rate limit ConfigMap", c.RateLimitConfigMap != ""},
+		{"a usage ConfigMap", c.UsageConfigMap != ""},
+		{"an embedding model", c.EmbeddingModelID != ""},
+		{"a shadow model", c.ShadowModelID != ""},
+		{"tenant namespaces", c.Namespaces != "" || c.NamespaceSelector != ""},
+		{"a TLS Secret", c.TLSSecret != ""},
+		{"a vLLM TLS Secret", c.VLLMTLSSecret != ""},
+	} {
+		if feature.enabled {
+			return fmt.Errorf("%s needs the kubernetes backend", feature.name)
+		}
+	}
+	return nil
+}
+
+// targetURL returns the URL of a vLLM service on the configured target port, over HTTPS when the
+// vLLM pods serve TLS
+func (c *Config) targetURL(host string) (*url.URL, error) {
+	scheme := "http"
+	if c.VLLMTLSSecret != "" {
+		scheme = "https"
+	}
+	return url.Parse(fmt.Sprintf("%s://%s:%s", scheme, host, c.TargetPort))
+}
+
+// localTargetURL returns the URL the local vLLM container or process listens on
+func (c *Config) localTargetURL() (*url.URL, error) {
+	return url.Parse(fmt.Sprintf("http://127.0.0.1:%d", c.LocalPort))
+}
+
+// kubernetesConfig returns the settings of the chat model pod and service
+func (c *Config) kubernetesConfig() *kubernetes.Config {
+	k8sConfig := &kubernetes.Config{
+		Namespace:     c.Namespace,
+		Deployment:    c.Deployment,
+		ConfigMapName: c.ConfigMapName,
+		GPUCount:      c.GPUCount,
+		CPUOffloadGB:  c.CPUOffloadGB,
+
+		ShutdownGracePeriod: c.GetShutdownGrace(),
+		PrefetchTimeout:     c.GetModelPrefetchTimeout(),
+		TLSSecret:           c.VLLMTLSSecret,
+		TLSClientAuth:       c.VLLMTLSCert != "",
+		APIKeySecret:        c.VLLMAPIKeySecret,
+	}
+	switch c.ScaleStrategy {
+	case ScaleStrategyPauseImage:
+		k8sConfig.PauseImage = kubernetes.DefaultPauseImage
+	case ScaleStrategyVLLMSleep:
+		k8sConfig.SleepMode = true
+	}
+	return k8sConfig
+}
+
+// embeddingKubernetesConfig returns the settings of the embedding model pod and service
+func (c *Config) embeddingKubernetesConfig() *kubernetes.Config {
+	return &kubernetes.Config{
+		Namespace:   c.Namespace,
+		Deployment:  c.Deployment + "-embed",
+		GPUCount:    c.EmbeddingGPUCount,
+		ServiceName: defaultEmbeddingTargetHost,
+		AppLabel:    "vllm-embed",
+
+		ShutdownGracePeriod: c.GetShutdownGrace(),
+		TLSSecret:           c.VLLMTLSSecret,
+		TLSClientAuth:       c.VLLMTLSCert != "",
+		APIKeySecret:        c.VLLMAPIKeySecret,
+	}
+}
+
+// shadowKubernetesConfig returns the settings of the shadow model pod and service
+func (c *Config) shadowKubernetesConfig() *kubernetes.Config {
+	return &kubernetes.Config{
+		Namespace:   c.Namespace,
+		Deployment:  c.Deployment + "-shadow",
+		GPUCount:    c.ShadowGPUCount,
+		ServiceName: defaultShadowTargetHost,
+		AppLabel:    "vllm-shadow",
+
+		ShutdownGracePeriod: c.GetShutdownGrace(),
+		TLSSecret:           c.VLLMTLSSecret,
+		TLSClientAuth:       c.VLLMTLSCert != "",
+		APIKeySecret:        c.VLLMAPIKeySecret,
+	}
+}
+
+// namespaceKubernetesConfig returns the settings of the chat model pod and service of a tenant
+// namespace, named as in the proxy's namespace
+func (c *Config) namespaceKubernetesConfig(namespace string) *kubernetes.Config {
+	k8sConfig := c.kubernetesConfig()
+	k8sConfig.Namespace = namespace
+	// The pod is deleted when idle, never paused or put to sleep
+	k8sConfig.PauseImage = ""
+	k8sConfig.SleepMode = false
+	return k8sConfig
+}
+
+// namespaceList returns the tenant namespaces listed in Namespaces, without the proxy's own
+func (c *Config) namespaceList() []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(c.Namespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" && namespace != c.Namespace {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,25 @@
+package proxy
+
+import (
+	"log"
+	"slices"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+// Config drift actions
+const (
+	ConfigDriftRestart = "restart" // Recreate the vLLM pod with its current config (default)
+	ConfigDriftWarn    = "warn"    // Log the drifted fields and report them in vllm_chill_config_drift only
+)
+
+// reportConfigDrift logs the drifted fields of the vLLM pod and sets vllm_chill_config_drift
+func (as *AutoScaler) reportConfigDrift(drifts []kubernetes.Drift) {
+	for _, drift := range drifts {
+		log.Printf("Config drift detected: %s", drift)
+	}
+	for _, field := range kubernetes.DriftFields {
+		drifted := slices.ContainsFunc(drifts, func(d kubernetes.Drift) bool { return d.Field == field })
+		as.metrics.SetConfigDrift(field, drifted)
+	}
+}
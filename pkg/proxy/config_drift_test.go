@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCheckConfigDrift(t *testing.T) {
+	tests := []struct {
+		action      string
+		podRestarts bool
+	}{
+		{action: ConfigDriftRestart, podRestarts: true},
+		{action: ConfigDriftWarn, podRestarts: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			ctx := context.Background()
+			as := &AutoScaler{
+				config:      &Config{Namespace: "vllm", Deployment: "vllm", ConfigDriftAction: tt.action},
+				crdClient:   newFakeCRDClient(t, replicaModelSpec(0, 1)),
+				k8sManager:  kubernetes.NewK8sManager(fake.NewSimpleClientset(), &kubernetes.Config{Namespace: "vllm", Deployment: "vllm", GPUCount: 1}),
+				activeModel: "qwen",
+				metrics:     stats.NewMetricsRecorder(),
+			}
+			as.runner = as.k8sManager
+
+			modelConfig, err := as.crdClient.GetModel(ctx, "qwen")
+			require.NoError(t, err)
+
+			// The pod's own config doesn't drift
+			require.NoError(t, as.k8sManager.CreatePod(ctx, modelConfig))
+			as.checkConfigDrift(ctx)
+			exists, err := as.k8sManager.PodExists(ctx)
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			// A pod created before the model changed does
+			outdated := *modelConfig
+			outdated.MaxModelLen = "32768"
+			require.NoError(t, as.k8sManager.DeletePod(ctx))
+			require.NoError(t, as.k8sManager.CreatePod(ctx, &outdated))
+			as.checkConfigDrift(ctx)
+			exists, err = as.k8sManager.PodExists(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, !tt.podRestarts, exists)
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// newFakeCRDClient returns a CRD client serving the given VLLMModel specs
+func newFakeCRDClient(t testing.TB, specs ...map[string]interface{}) *kubernetes.CRDClient {
+	t.Helper()
+	gvr := schema.GroupVersionResource{Group: "vllm.sir-alfred.io", Version: "v1alpha1", Resource: "models"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "VLLMModelList"})
+
+	for _, spec := range specs {
+		model := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vllm.sir-alfred.io/v1alpha1",
+			"kind":       "VLLMModel",
+			"metadata":   map[string]interface{}{"name": spec["servedModelName"]},
+			"spec":       spec,
+		}}
+		_, err := client.Resource(gvr).Create(context.Background(), model, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+	return kubernetes.NewCRDClient(client)
+}
+
+func TestConfigEffective(t *testing.T) {
+	config := &Config{
+		Namespace:         "vllm",
+		IdleTimeout:       "10m",
+		ModelID:           "qwen",
+		FederationPeers:   "big=https://big-box:8443",
+		FederationTLSCert: "/certs/tls.crt",
+		FederationTLSKey:  "/certs/tls.key",
+		AdminToken:        "s3cret",
+	}
+
+	effective := config.Effective()
+
+	assert.Equal(t, "10m0s", effective["idle_timeout"])
+	assert.Equal(t, XMLFallbackOn, effective["xml_fallback"])
+	assert.Equal(t, ScaleStrategyDelete, effective["scale_strategy"])
+	assert.Equal(t, 2, effective["gpu_count"])
+	assert.Equal(t, "/certs/tls.crt", effective["federation_tls_cert"])
+	assert.Equal(t, "[REDACTED]", effective["federation_tls_key"])
+	assert.Equal(t, "[REDACTED]", effective["admin_token"])
+	assert.NotContains(t, effective, "sleep_level")
+	assert.NotContains(t, effective, "embedding_model_id")
+}
+
+func TestConfigHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	as := &AutoScaler{
+		config:      &Config{IdleTimeout: "5m", ModelID: "qwen"},
+		activeModel: "qwen",
+		targetURL:   &url.URL{Scheme: "http", Host: "vllm-api:80"},
+		crdClient: newFakeCRDClient(t, map[string]interface{}{
+			"modelName":              "Qwen/Qwen3-Coder",
+			"servedModelName":        "qwen",
+			"toolCallParser":         "qwen3_coder",
+			"maxModelLen":            int64(65536),
+			"maxNumBatchedTokens":    int64(8192),
+			"maxNumSeqs":             int64(16),
+			"gpuMemoryUtilization":   0.9,
+			"dtype":                  "auto",
+			"enableChunkedPrefill":   true,
+			"disableCustomAllReduce": false,
+			"enablePrefixCaching":    true,
+			"enableAutoToolChoice":   true,
+		}),
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/proxy/config", nil)
+
+	as.configHandler(c)
+
+	assert.Equal(t, 200, w.Code)
+	var response struct {
+		Config      map[string]interface{} `json:"config"`
+		TargetURL   string                 `json:"target_url"`
+		ActiveModel string                 `json:"active_model"`
+		Model       map[string]interface{} `json:"model"`
+		ModelError  string                 `json:"model_error"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "5m0s", response.Config["idle_timeout"])
+	assert.Equal(t, "http://vllm-api:80", response.TargetURL)
+	assert.Equal(t, "qwen", response.ActiveModel)
+	assert.Empty(t, response.ModelError)
+	assert.Equal(t, "Qwen/Qwen3-Coder", response.Model["modelName"])
+	assert.Equal(t, "qwen3_coder", response.Model["toolCallParser"])
+	assert.Equal(t, "65536", response.Model["maxModelLen"])
+}
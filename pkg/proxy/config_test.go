@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfigValidate(t *testing.T) {
@@ -85,6 +87,101 @@ func TestConfigValidate(t *testing.T) {
 			},
 			expectError: true,
 		},
+		{
+			name: "invalid shutdown grace period",
+			config: Config{
+				Namespace:     "test-ns",
+				Deployment:    "test-deployment",
+				ConfigMapName: "test-configmap",
+				IdleTimeout:   "5m",
+				ShutdownGrace: "soon",
+				Port:          "8080",
+				ModelID:       "test-model",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid sleep level",
+			config: Config{
+				Namespace:     "test-ns",
+				Deployment:    "test-deployment",
+				ConfigMapName: "test-configmap",
+				IdleTimeout:   "5m",
+				Port:          "8080",
+				ModelID:       "test-model",
+				ScaleStrategy: ScaleStrategyVLLMSleep,
+				SleepLevel:    3,
+			},
+			expectError: true,
+		},
+		{
+			name: "deep idle timeout shorter than idle timeout",
+			config: Config{
+				Namespace:       "test-ns",
+				Deployment:      "test-deployment",
+				ConfigMapName:   "test-configmap",
+				IdleTimeout:     "5m",
+				Port:            "8080",
+				ModelID:         "test-model",
+				ScaleStrategy:   ScaleStrategyVLLMSleep,
+				SleepLevel:      1,
+				DeepIdleTimeout: "1m",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid XML fallback mode",
+			config: Config{
+				Namespace:     "test-ns",
+				Deployment:    "test-deployment",
+				ConfigMapName: "test-configmap",
+				IdleTimeout:   "5m",
+				Port:          "8080",
+				ModelID:       "test-model",
+				XMLFallback:   "sometimes",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid federation peer",
+			config: Config{
+				Namespace:       "test-ns",
+				Deployment:      "test-deployment",
+				ConfigMapName:   "test-configmap",
+				IdleTimeout:     "5m",
+				Port:            "8080",
+				ModelID:         "test-model",
+				FederationPeers: "big=not-a-url",
+			},
+			expectError: true,
+		},
+		{
+			name: "federation cert without key",
+			config: Config{
+				Namespace:         "test-ns",
+				Deployment:        "test-deployment",
+				ConfigMapName:     "test-configmap",
+				IdleTimeout:       "5m",
+				Port:              "8080",
+				ModelID:           "test-model",
+				FederationPeers:   "big=https://big-box:8443",
+				FederationTLSCert: "/certs/tls.crt",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalid max request body size",
+			config: Config{
+				Namespace:          "test-ns",
+				Deployment:         "test-deployment",
+				ConfigMapName:      "test-configmap",
+				IdleTimeout:        "5m",
+				Port:               "8080",
+				ModelID:            "test-model",
+				MaxRequestBodySize: "lots",
+			},
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -99,6 +196,176 @@ func TestConfigValidate(t *testing.T) {
 	}
 }
 
+func TestConfigValidateErrors(t *testing.T) {
+	valid := func() Config {
+		return Config{
+			Namespace:     "test-ns",
+			Deployment:    "test-deployment",
+			ConfigMapName: "test-configmap",
+			IdleTimeout:   "5m",
+			ModelID:       "test-model",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		err    string
+	}{
+		{name: "target port", modify: func(c *Config) { c.TargetPort = "http" }, err: `invalid target port "http"`},
+		{name: "target port range", modify: func(c *Config) { c.TargetPort = "70000" }, err: `invalid target port "70000"`},
+		{name: "gpu count", modify: func(c *Config) { c.GPUCount = -1 }, err: "GPU counts cannot be negative"},
+		{name: "scale strategy", modify: func(c *Config) { c.ScaleStrategy = "scale" }, err: `invalid scale strategy "scale"`},
+		{name: "sleep level", modify: func(c *Config) { c.ScaleStrategy = ScaleStrategyVLLMSleep; c.SleepLevel = 3 }, err: "invalid sleep level 3"},
+		{name: "xml fallback", modify: func(c *Config) { c.XMLFallback = "maybe" }, err: `invalid XML fallback mode "maybe"`},
+		{name: "tool argument validation", modify: func(c *Config) { c.ToolArgumentValidation = "lenient" }, err: `invalid tool argument validation mode "lenient"`},
+		{name: "body size", modify: func(c *Config) { c.MaxRequestBodySize = "-1Mi" }, err: `invalid max request body size "-1Mi"`},
+		{name: "image size", modify: func(c *Config) { c.MaxImageSize = "big" }, err: `invalid max image size "big"`},
+		{name: "page cache models", modify: func(c *Config) { c.PageCacheModels = -1 }, err: "page cache models cannot be negative"},
+		{name: "page cache budget", modify: func(c *Config) { c.PageCacheBudget = "-1Gi" }, err: `invalid page cache budget "-1Gi"`},
+		{name: "config drift action", modify: func(c *Config) { c.ConfigDriftAction = "ignore" }, err: `invalid config drift action "ignore"`},
+		{name: "advertise URL", modify: func(c *Config) { c.LeaderElection = true }, err: `invalid advertise URL ""`},
+		{name: "leader token", modify: func(c *Config) { c.LeaderElection, c.AdvertiseURL = true, "http://10.0.0.1:8080" }, err: "leader election needs a leader token or an admin token"},
+		{name: "notification webhooks", modify: func(c *Config) { c.NotifyWebhooks = "teams=https://example.com/hook" }, err: `invalid webhook format "teams"`},
+		{name: "notification events", modify: func(c *Config) { c.NotifyEvents = "scale_up,oom" }, err: `invalid event "oom"`},
+		{name: "notification template", modify: func(c *Config) { c.NotifyTemplate = "{{.Model" }, err: "invalid notification template"},
+		{name: "audit log", modify: func(c *Config) { c.AuditLog = "ftp://audit.example.com/log" }, err: "invalid audit log URL"},
+		{name: "audit body size", modify: func(c *Config) { c.AuditBodySize = "-4Ki" }, err: `invalid audit body size "-4Ki"`},
+		{name: "passthrough path", modify: func(c *Config) { c.PassthroughPaths = "/tokenize,metrics" }, err: `invalid passthrough path "metrics"`},
+		{name: "passthrough path prefix", modify: func(c *Config) { c.PassthroughPaths = "/proxy/admin/" }, err: `invalid passthrough path "/proxy/admin/"`},
+		{name: "vLLM h2c", modify: func(c *Config) { c.VLLMH2C, c.VLLMTLSSecret = true, "vllm-tls" }, err: "vLLM h2c cannot be used with a vLLM TLS Secret"},
+		{name: "tracing endpoint", modify: func(c *Config) { c.TracingEndpoint = "otel-collector:4318" }, err: `invalid tracing endpoint "otel-collector:4318"`},
+		{name: "batch concurrency", modify: func(c *Config) { c.BatchConcurrency = -1 }, err: "batch concurrency cannot be negative"},
+		{name: "response cache size", modify: func(c *Config) { c.ResponseCacheSize = "lots" }, err: `invalid response cache size "lots"`},
+		{name: "response cache ttl", modify: func(c *Config) { c.ResponseCacheTTL = "0s" }, err: `invalid response cache TTL "0s"`},
+		{name: "switch policy", modify: func(c *Config) { c.SwitchPolicy = "never" }, err: `invalid switch policy "never" (expected auto, manual or allowlist)`},
+		{name: "switch allowlist", modify: func(c *Config) { c.SwitchPolicy = SwitchPolicyAllowlist; c.SwitchAllowlist = " , " }, err: "the allowlist switch policy needs a switch allowlist"},
+		{name: "switch drain timeout", modify: func(c *Config) { c.SwitchDrainTimeout = "-1s" }, err: `invalid switch drain timeout "-1s"`},
+		{name: "backend", modify: func(c *Config) { c.Backend = "nomad" }, err: `invalid backend "nomad"`},
+		{name: "docker models dir", modify: func(c *Config) { c.Backend = BackendDocker }, err: "the docker backend needs a models directory"},
+		{name: "local port", modify: func(c *Config) { c.Backend, c.ModelsDir, c.LocalPort = BackendDocker, "models", 70000 }, err: "invalid local port 70000"},
+		{name: "docker prefetch", modify: func(c *Config) { c.Backend, c.ModelsDir, c.ModelPrefetch = BackendDocker, "models", true }, err: "model prefetch needs the kubernetes backend"},
+		{name: "process models dir", modify: func(c *Config) { c.Backend = BackendProcess }, err: "the process backend needs a models directory"},
+		{name: "process usage", modify: func(c *Config) { c.Backend, c.ModelsDir, c.UsageConfigMap = BackendProcess, "models", "usage" }, err: "a usage ConfigMap needs the kubernetes backend"},
+		{name: "docker shadow model", modify: func(c *Config) { c.Backend, c.ModelsDir, c.ShadowModelID = BackendDocker, "models", "llama" }, err: "a shadow model needs the kubernetes backend"},
+		{name: "GPU busy utilization", modify: func(c *Config) { c.GPUBusyUtilization = 120 }, err: "GPU busy utilization must be between 0 and 100"},
+		{name: "shadow sample percent", modify: func(c *Config) { c.ShadowSamplePercent = 101 }, err: "shadow sample percent must be between 0 and 100"},
+		{name: "shadow model", modify: func(c *Config) { c.ModelID = "qwen"; c.ShadowModelID = "qwen" }, err: `the shadow model must differ from the primary model "qwen"`},
+		{name: "namespace selector", modify: func(c *Config) { c.NamespaceSelector = "tenant in (a" }, err: "invalid namespace selector"},
+		{name: "session affinity idle", modify: func(c *Config) { c.SessionAffinityIdle = "0s" }, err: `invalid session affinity idle time "0s"`},
+		{name: "model prefetch timeout", modify: func(c *Config) { c.ModelPrefetchTimeout = "0s" }, err: `invalid model prefetch timeout "0s"`},
+		{name: "rate limit", modify: func(c *Config) { c.RateLimitTPM = -1 }, err: "rate limits cannot be negative"},
+		{name: "user labels", modify: func(c *Config) { c.UserTracking = true; c.MaxUserLabels = -1 }, err: "max user labels cannot be negative"},
+		{name: "per-user rate limits", modify: func(c *Config) { c.RateLimitPerUser = true }, err: "per-user rate limits require user tracking"},
+		{name: "drain delay", modify: func(c *Config) { c.DrainDelay = "-5s" }, err: `invalid drain delay "-5s"`},
+		{name: "drain timeout", modify: func(c *Config) { c.DrainTimeout = "soon" }, err: `invalid drain timeout "soon"`},
+		{name: "max cold starts", modify: func(c *Config) { c.MaxConcurrentColdStarts = -1 }, err: "max concurrent cold starts cannot be negative"},
+		{name: "cold start retry window", modify: func(c *Config) { c.ColdStartRetryWindow = "-1s" }, err: `invalid cold start retry window "-1s"`},
+		{name: "cold start retries", modify: func(c *Config) { c.ColdStartRetries = -1 }, err: "cold start retries cannot be negative"},
+		{name: "sse heartbeat interval", modify: func(c *Config) { c.SSEHeartbeatInterval = "often" }, err: `invalid SSE heartbeat interval "often"`},
+		{name: "stream idle timeout", modify: func(c *Config) { c.StreamIdleTimeout = "-1m" }, err: `invalid stream idle timeout "-1m"`},
+		{name: "warm schedule", modify: func(c *Config) { c.WarmSchedule = "0 8 * * 1-5" }, err: "invalid warm schedule"},
+		{name: "aggressive schedule", modify: func(c *Config) { c.AggressiveSchedule = "0 25 * * * 2h" }, err: "invalid aggressive schedule"},
+		{name: "aggressive idle timeout", modify: func(c *Config) { c.AggressiveIdleTimeout = "0s" }, err: `invalid aggressive idle timeout "0s"`},
+		{name: "schedule time zone", modify: func(c *Config) { c.ScheduleTimezone = "Mars/Olympus" }, err: `invalid schedule time zone "Mars/Olympus"`},
+		{name: "session budget", modify: func(c *Config) { c.SessionTokenBudget = 1000; c.SessionTTL = "soon" }, err: `invalid session TTL "soon"`},
+		{name: "TLS key", modify: func(c *Config) { c.TLSCert = "/certs/tls.crt" }, err: "TLS cert and key must be set together"},
+		{name: "TLS files and Secret", modify: func(c *Config) { c.TLSCert, c.TLSKey, c.TLSSecret = "/certs/tls.crt", "/certs/tls.key", "proxy-tls" }, err: "TLS cert files and a TLS Secret cannot be used together"},
+		{name: "vLLM TLS key", modify: func(c *Config) { c.VLLMTLSSecret, c.VLLMTLSCert = "vllm-tls", "/certs/client.crt" }, err: "vLLM TLS cert and key must be set together"},
+		{name: "vLLM TLS Secret", modify: func(c *Config) { c.VLLMTLSServerName = "vllm-api" }, err: "vLLM TLS settings need a vLLM TLS Secret"},
+		{name: "docker vLLM TLS", modify: func(c *Config) { c.Backend, c.ModelsDir, c.VLLMTLSSecret = BackendDocker, "models", "vllm-tls" }, err: "a vLLM TLS Secret needs the kubernetes backend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(&config)
+			assert.ErrorContains(t, config.Validate(), tt.err)
+		})
+	}
+}
+
+func TestConfigApplyDefaults(t *testing.T) {
+	config := &Config{ScaleStrategy: ScaleStrategyVLLMSleep, EmbeddingModelID: "bge-m3", ShadowModelID: "qwen-awq", GPUCount: 4}
+	config.ApplyDefaults()
+
+	assert.Equal(t, "vllm-api", config.TargetHost)
+	assert.Equal(t, "80", config.TargetPort)
+	assert.Equal(t, "vllm-embed-api", config.EmbeddingTargetHost)
+	assert.Equal(t, XMLFallbackOn, config.XMLFallback)
+	assert.Equal(t, ToolArgsCoerce, config.ToolArgumentValidation)
+	assert.Equal(t, 1, config.SleepLevel)
+	assert.Equal(t, 4, config.GPUCount, "explicit values are kept")
+	assert.Equal(t, 1, config.EmbeddingGPUCount)
+	assert.Equal(t, "vllm-shadow-api", config.ShadowTargetHost)
+	assert.Equal(t, 1, config.ShadowGPUCount)
+	assert.Equal(t, 100, config.ShadowSamplePercent)
+
+	config = &Config{}
+	config.ApplyDefaults()
+	assert.Equal(t, ScaleStrategyDelete, config.ScaleStrategy)
+	assert.Equal(t, 0, config.SleepLevel)
+	assert.Equal(t, 0, config.EmbeddingGPUCount)
+	assert.Equal(t, 0, config.ShadowSamplePercent)
+}
+
+func TestConfigKubernetesConfig(t *testing.T) {
+	config := &Config{
+		Namespace:         "vllm",
+		Deployment:        "vllm",
+		ConfigMapName:     "vllm-config",
+		GPUCount:          2,
+		ShutdownGrace:     "30s",
+		ScaleStrategy:     ScaleStrategyPauseImage,
+		EmbeddingGPUCount: 1,
+	}
+
+	k8sConfig := config.kubernetesConfig()
+	assert.Equal(t, "vllm", k8sConfig.Deployment)
+	assert.Equal(t, 2, k8sConfig.GPUCount)
+	assert.Equal(t, 30*time.Second, k8sConfig.ShutdownGracePeriod)
+	assert.NotEmpty(t, k8sConfig.PauseImage)
+	assert.False(t, k8sConfig.SleepMode)
+
+	embeddingConfig := config.embeddingKubernetesConfig()
+	assert.Equal(t, "vllm-embed", embeddingConfig.Deployment)
+	assert.Equal(t, "vllm-embed-api", embeddingConfig.ServiceName)
+	assert.Equal(t, 1, embeddingConfig.GPUCount)
+	assert.Empty(t, embeddingConfig.PauseImage, "the embedding pod is always deleted when idle")
+
+	shadowConfig := config.shadowKubernetesConfig()
+	assert.Equal(t, "vllm-shadow", shadowConfig.Deployment)
+	assert.Equal(t, "vllm-shadow-api", shadowConfig.ServiceName)
+	assert.Equal(t, "vllm-shadow", shadowConfig.AppLabel)
+
+	config.TargetPort = "8000"
+	target, err := config.targetURL("vllm-api")
+	require.NoError(t, err)
+	assert.Equal(t, "http://vllm-api:8000", target.String())
+
+	config.VLLMTLSSecret = "vllm-tls"
+	config.VLLMTLSCert, config.VLLMTLSKey = "/certs/client.crt", "/certs/client.key"
+	target, err = config.targetURL("vllm-api")
+	require.NoError(t, err)
+	assert.Equal(t, "https://vllm-api:8000", target.String())
+	for _, k8sConfig := range []*kubernetes.Config{config.kubernetesConfig(), config.embeddingKubernetesConfig(), config.shadowKubernetesConfig()} {
+		assert.Equal(t, "vllm-tls", k8sConfig.TLSSecret)
+		assert.True(t, k8sConfig.TLSClientAuth, "vLLM requires the client certificate presented")
+	}
+}
+
+func TestConfigGetShutdownGrace(t *testing.T) {
+	assert.Equal(t, time.Duration(0), (&Config{}).GetShutdownGrace())
+	assert.Equal(t, 30*time.Second, (&Config{ShutdownGrace: "30s"}).GetShutdownGrace())
+}
+
+func TestConfigGetMaxRequestBodySize(t *testing.T) {
+	assert.Equal(t, int64(0), (&Config{}).GetMaxRequestBodySize())
+	assert.Equal(t, int64(0), (&Config{MaxRequestBodySize: "0"}).GetMaxRequestBodySize())
+	assert.Equal(t, int64(32<<20), (&Config{MaxRequestBodySize: "32Mi"}).GetMaxRequestBodySize())
+	assert.Equal(t, int64(1000000), (&Config{MaxRequestBodySize: "1M"}).GetMaxRequestBodySize())
+}
+
 func TestConfigGetIdleTimeout(t *testing.T) {
 	tests := []struct {
 		name     string
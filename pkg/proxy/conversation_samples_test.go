@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
@@ -340,3 +341,60 @@ func TestQwenNativeStreamingToolCalls(t *testing.T) {
 	assert.Equal(t, "Analyze commit message", args["title"], "Title should match")
 	assert.Contains(t, args["notes"].(string), "aa62adf0", "Notes should contain commit hash")
 }
+
+func TestConversationSamples_ParallelToolCalls(t *testing.T) {
+	samples := loadConversationSamples(t)
+
+	// convert streams the chunks through the response writer and returns the aggregated message
+	convert := func(t *testing.T, chunks []StreamChunk) Message {
+		recorder := httptest.NewRecorder()
+		sa := newStreamAggregator(recorder, "/v1/chat/completions")
+		rw := newResponseWriter(sa, false, nil)
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.WriteHeader(http.StatusOK)
+		for _, chunk := range chunks {
+			_, err := rw.Write([]byte("data: " + chunk.Data + "\n\n"))
+			require.NoError(t, err)
+		}
+		_, err := rw.Write([]byte("data: [DONE]\n\n"))
+		require.NoError(t, err)
+		require.NoError(t, sa.finish())
+
+		var completion ChatCompletion
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &completion))
+		require.Len(t, completion.Choices, 1)
+		return completion.Choices[0].Message
+	}
+
+	// Samples converted on their own are chained into one response, the first one's finish chunk dropped
+	var convertible []ConversationSample
+	for _, sample := range samples {
+		if len(convert(t, sample.GenerateStream).ToolCalls) == 1 {
+			convertible = append(convertible, sample)
+		}
+	}
+	require.GreaterOrEqual(t, len(convertible), 2)
+
+	for i := 0; i+1 < len(convertible); i++ {
+		first, second := convertible[i], convertible[i+1]
+		t.Run(first.Title+"+"+second.Title, func(t *testing.T) {
+			chunks := append([]StreamChunk{}, first.GenerateStream[:len(first.GenerateStream)-1]...)
+			chunks = append(chunks, second.GenerateStream...)
+
+			message := convert(t, chunks)
+
+			require.Len(t, message.ToolCalls, 2, "each XML block is a tool call")
+			assert.Equal(t, first.Expected.Choices[0].Message.ToolCalls[0].Function.Name, message.ToolCalls[0].Function.Name)
+			assert.Equal(t, second.Expected.Choices[0].Message.ToolCalls[0].Function.Name, message.ToolCalls[1].Function.Name)
+			assert.NotEqual(t, message.ToolCalls[0].ID, message.ToolCalls[1].ID)
+			for _, toolCall := range message.ToolCalls {
+				var args map[string]interface{}
+				assert.NoError(t, json.Unmarshal([]byte(toolCall.Function.Arguments), &args))
+			}
+			if message.Content != nil {
+				assert.NotContains(t, *message.Content, "<tool_call")
+				assert.NotContains(t, *message.Content, "<args")
+			}
+		})
+	}
+}
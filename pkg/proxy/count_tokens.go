@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/usage"
+)
+
+// countTokensPath is the Anthropic endpoint clients call to size a prompt before sending it
+const countTokensPath = "/v1/messages/count_tokens"
+
+const tokenizeTimeout = 10 * time.Second
+
+// countTokensRequest holds the parts of a Messages request that take up the model's context
+type countTokensRequest struct {
+	System   json.RawMessage `json:"system"`
+	Messages []struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	Tools json.RawMessage `json:"tools"`
+}
+
+// contentBlock is an Anthropic content block, only the fields carrying prompt text
+type contentBlock struct {
+	Type    string          `json:"type"`
+	Text    string          `json:"text"`
+	Name    string          `json:"name"`
+	Input   json.RawMessage `json:"input"`
+	Content json.RawMessage `json:"content"`
+}
+
+// promptText flattens the system prompt, messages and tool schemas into the text the model reads
+func (req *countTokensRequest) promptText() string {
+	var text strings.Builder
+	appendContent(&text, req.System)
+	for _, message := range req.Messages {
+		appendContent(&text, message.Content)
+	}
+	if len(req.Tools) > 0 && string(req.Tools) != "null" {
+		text.Write(req.Tools)
+	}
+	return text.String()
+}
+
+// appendContent appends a string content or the text of content blocks, tool results included
+func appendContent(text *strings.Builder, content json.RawMessage) {
+	var s string
+	if json.Unmarshal(content, &s) == nil {
+		text.WriteString(s)
+		text.WriteByte('\n')
+		return
+	}
+	var blocks []contentBlock
+	if json.Unmarshal(content, &blocks) != nil {
+		return
+	}
+	for _, block := range blocks {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+			text.WriteByte('\n')
+		case "tool_use":
+			text.WriteString(block.Name)
+			text.Write(block.Input)
+			text.WriteByte('\n')
+		case "tool_result":
+			appendContent(text, block.Content)
+		}
+	}
+}
+
+// serveCountTokens answers POST /v1/messages/count_tokens without waking vLLM: the prompt is
+// tokenized by vLLM when the model is already running, and estimated from its size otherwise
+func (as *AutoScaler) serveCountTokens(w http.ResponseWriter, r *http.Request, model string) {
+	var req countTokensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err), "invalid_request_error", "invalid_request")
+		return
+	}
+	text := req.promptText()
+
+	ctx := r.Context()
+	tokens := (len(text) + usage.BytesPerToken - 1) / usage.BytesPerToken
+	if model != "" && model == as.GetActiveModel() && as.isPodReady(ctx) {
+		count, err := as.tokenize(ctx, model, text)
+		if err != nil {
+			log.Printf("Failed to tokenize with vLLM, estimating the token count: %v", err)
+		} else {
+			tokens = count
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]int{"input_tokens": tokens}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// tokenize counts the tokens of a text with the model's tokenizer, through vLLM's /tokenize
+func (as *AutoScaler) tokenize(ctx context.Context, model, text string) (int, error) {
+	body, err := json.Marshal(map[string]string{"model": model, "prompt": text})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, tokenizeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, as.targetURL.JoinPath("/tokenize").String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey := as.vllmAPIKey(ctx, model); apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := (&http.Client{Transport: as.vllmTransport}).Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("vLLM /tokenize returned %d: %s", resp.StatusCode, string(message))
+	}
+
+	var result struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("invalid vLLM /tokenize response: %w", err)
+	}
+	return result.Count, nil
+}
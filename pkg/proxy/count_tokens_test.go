@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const countTokensBody = `{
+	"model": "qwen",
+	"system": [{"type": "text", "text": "You are a coding agent."}],
+	"messages": [
+		{"role": "user", "content": "List the files"},
+		{"role": "assistant", "content": [{"type": "tool_use", "id": "t1", "name": "ls", "input": {"path": "."}}]},
+		{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "t1", "content": [{"type": "text", "text": "main.go"}]}]}
+	],
+	"tools": [{"name": "ls", "input_schema": {"type": "object"}}]
+}`
+
+func TestCountTokensPromptText(t *testing.T) {
+	var req countTokensRequest
+	require.NoError(t, json.Unmarshal([]byte(countTokensBody), &req))
+
+	text := req.promptText()
+	for _, part := range []string{"You are a coding agent.", "List the files", `ls{"path": "."}`, "main.go", `"input_schema"`} {
+		assert.Contains(t, text, part)
+	}
+}
+
+func TestServeCountTokens_Cold(t *testing.T) {
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyDelete)
+	as.activeModel = "qwen"
+
+	w := httptest.NewRecorder()
+	as.serveCountTokens(w, httptest.NewRequest(http.MethodPost, countTokensPath, strings.NewReader(countTokensBody)), "qwen")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Positive(t, response.InputTokens, "a cold model gets an estimate")
+
+	w = httptest.NewRecorder()
+	as.serveCountTokens(w, httptest.NewRequest(http.MethodPost, countTokensPath, strings.NewReader(`not json`)), "qwen")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestServeCountTokens_Warm(t *testing.T) {
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tokenize", r.URL.Path)
+		var req map[string]string
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "qwen", req["model"])
+		_, _ = w.Write([]byte(`{"count":42,"max_model_len":65536,"tokens":[]}`))
+	}))
+	defer vllm.Close()
+
+	pod := vllmPod()
+	pod.Status = corev1.PodStatus{
+		Phase:      corev1.PodRunning,
+		Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+	}
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyDelete, pod)
+	as.activeModel = "qwen"
+	var err error
+	as.targetURL, err = url.Parse(vllm.URL)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	as.serveCountTokens(w, httptest.NewRequest(http.MethodPost, countTokensPath, strings.NewReader(countTokensBody)), "qwen")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"input_tokens":42}`, w.Body.String(), "a running model counts with its tokenizer")
+}
@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+)
+
+// debugHeader enables verbose logging and a debug report for a single request, for admin keys only.
+// The report is added to JSON responses under vllm_chill.debug, and sent as a trailer of streams.
+const debugHeader = "X-Chill-Debug"
+
+// requestDebug traces a single request: every method is a no-op on a nil receiver, so requests
+// without the header are not traced
+type requestDebug struct {
+	ID             string             `json:"id"`
+	RequestedModel string             `json:"requested_model,omitempty"`
+	ServedModel    string             `json:"served_model,omitempty"`
+	Target         string             `json:"target,omitempty"`
+	TimingsMs      map[string]float64 `json:"timings_ms"`
+	Dedup          debugDedup         `json:"dedup"`
+	Parser         debugParser        `json:"parser"`
+}
+
+// debugDedup reports the chunks vLLM duplicated with tensor parallelism
+type debugDedup struct {
+	DuplicateBytes int64 `json:"duplicate_bytes"`
+}
+
+// debugParser reports the tool call parsing decisions
+type debugParser struct {
+	Fallback         string `json:"fallback"` // Fallback parser applied to the content: xml, json or off
+	NativeToolCalls  bool   `json:"native_tool_calls"`
+	XMLPatternSeen   bool   `json:"xml_pattern_seen"`
+	ParsedToolCalls  int    `json:"parsed_tool_calls"`
+	RepairedCalls    int    `json:"repaired_tool_calls"` // Parsed tool calls whose malformed JSON arguments were repaired
+	CoercedCalls     int    `json:"coerced_tool_calls"`  // Parsed tool calls whose arguments were coerced to their tool's schema
+	InvalidCalls     int    `json:"invalid_tool_calls"`  // Parsed tool calls whose arguments don't match their tool's schema
+	ConversionFailed bool   `json:"conversion_failed"`
+}
+
+// debugRequest starts tracing the request when it asks for it with an admin key. The header is
+// removed so it does not reach vLLM.
+func (as *AutoScaler) debugRequest(r *http.Request) *requestDebug {
+	value := r.Header.Get(debugHeader)
+	if value == "" {
+		return nil
+	}
+	r.Header.Del(debugHeader)
+	if enabled, _ := strconv.ParseBool(value); !enabled {
+		return nil
+	}
+	if !as.debugAllowed(r) {
+		log.Printf("Ignored %s on %s %s: not an admin key", debugHeader, r.Method, r.URL.Path)
+		return nil
+	}
+
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+	return &requestDebug{ID: hex.EncodeToString(id), TimingsMs: make(map[string]float64)}
+}
+
+// debugAllowed reports whether the request is made with an admin tenant key or the admin token
+func (as *AutoScaler) debugAllowed(r *http.Request) bool {
+	if tenant := auth.TenantFrom(r.Context()); tenant != nil {
+		return tenant.Admin
+	}
+	key := auth.KeyFromRequest(r)
+	return as.config.AdminToken != "" && subtle.ConstantTimeCompare([]byte(key), []byte(as.config.AdminToken)) == 1
+}
+
+// logf logs a line tagged with the request's debug ID
+func (d *requestDebug) logf(format string, args ...interface{}) {
+	if d == nil {
+		return
+	}
+	log.Printf("[DEBUG %s] %s", d.ID, fmt.Sprintf(format, args...))
+}
+
+// logBody logs the request body, its base64 blobs replaced by their size, and keeps it readable
+func (d *requestDebug) logBody(r *http.Request) {
+	if d == nil || r.Body == nil {
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	r.Body = replayBody(data, r.Body)
+	if err != nil {
+		d.logf("Failed to read the request body: %v", err)
+		return
+	}
+	d.logf("Request body (%d bytes): %s", len(data), redactBlobs(data))
+}
+
+// time records how long a phase of the request took
+func (d *requestDebug) time(phase string, duration time.Duration) {
+	if d == nil {
+		return
+	}
+	d.TimingsMs[phase] = float64(duration.Microseconds()) / 1000
+	d.logf("%s took %s", phase, duration)
+}
+
+// collect records the parser decisions and costs of the response
+func (d *requestDebug) collect(rw *responseWriter) {
+	if d == nil {
+		return
+	}
+	d.Parser.Fallback = rw.toolParser.Name()
+	if rw.xmlToolCalls() && rw.xmlFallbackOff {
+		d.Parser.Fallback = XMLFallbackOff
+	}
+	d.Parser.NativeToolCalls = rw.toolCallsDetected
+	d.Parser.XMLPatternSeen = rw.xmlPatternSeen
+	d.Parser.ParsedToolCalls = rw.parsedToolCalls
+	d.Parser.RepairedCalls = rw.repairedCalls
+	d.Parser.CoercedCalls = rw.coercedCalls
+	d.Parser.InvalidCalls = rw.invalidCalls
+	d.Parser.ConversionFailed = rw.conversionFailed
+	d.Dedup.DuplicateBytes = rw.duplicateBytes
+	d.time("transform", rw.transformTime)
+}
+
+// report returns the debug report as JSON
+func (d *requestDebug) report() string {
+	report, err := json.Marshal(d)
+	if err != nil {
+		return ""
+	}
+	return string(report)
+}
+
+// setTrailer sends the debug report as a trailer, for responses streamed before it was complete
+func (d *requestDebug) setTrailer(header http.Header) {
+	if d == nil {
+		return
+	}
+	header.Set(http.TrailerPrefix+debugHeader, d.report())
+}
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugRequest(t *testing.T) {
+	as := &AutoScaler{config: &Config{AdminToken: "admin-secret"}}
+	newRequest := func(key, value string, tenant *auth.Tenant) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		r.Header.Set("Authorization", "Bearer "+key)
+		r.Header.Set(debugHeader, value)
+		if tenant != nil {
+			r = r.WithContext(auth.WithTenant(r.Context(), tenant))
+		}
+		return r
+	}
+
+	r := newRequest("admin-secret", "1", nil)
+	dbg := as.debugRequest(r)
+	require.NotNil(t, dbg, "the admin token enables debugging")
+	assert.Len(t, dbg.ID, 8)
+	assert.Empty(t, r.Header.Get(debugHeader), "the header does not reach vLLM")
+
+	assert.NotNil(t, as.debugRequest(newRequest("sk-ops", "true", &auth.Tenant{Name: "ops", Admin: true})), "admin tenants may debug")
+	assert.Nil(t, as.debugRequest(newRequest("sk-a", "true", &auth.Tenant{Name: "team-a"})), "other tenants may not")
+	assert.Nil(t, as.debugRequest(newRequest("sk-a", "true", nil)))
+	assert.Nil(t, as.debugRequest(newRequest("admin-secret", "0", nil)))
+	assert.Nil(t, as.debugRequest(newRequest("admin-secret", "", nil)))
+}
+
+func TestRequestDebugCollect(t *testing.T) {
+	rw := newResponseWriter(httptest.NewRecorder(), false, nil)
+	rw.toolParser = parser.ForModel("json", "")
+	rw.toolCallsDetected = true
+	rw.parsedToolCalls = 2
+	rw.repairedCalls = 1
+	rw.duplicateBytes = 128
+	rw.transformTime = 1500 * time.Microsecond
+
+	dbg := &requestDebug{ID: "abcd1234", TimingsMs: make(map[string]float64)}
+	dbg.time("scale", 2*time.Second)
+	dbg.collect(rw)
+
+	var report map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(dbg.report()), &report))
+	assert.Equal(t, map[string]interface{}{"scale": 2000.0, "transform": 1.5}, report["timings_ms"])
+	assert.Equal(t, map[string]interface{}{"duplicate_bytes": 128.0}, report["dedup"])
+	assert.Equal(t, map[string]interface{}{
+		"fallback":            "json",
+		"native_tool_calls":   true,
+		"xml_pattern_seen":    false,
+		"parsed_tool_calls":   2.0,
+		"repaired_tool_calls": 1.0,
+		"coerced_tool_calls":  0.0,
+		"invalid_tool_calls":  0.0,
+		"conversion_failed":   false,
+	}, report["parser"])
+
+	var untraced *requestDebug
+	assert.NotPanics(t, func() {
+		untraced.time("proxy", time.Second)
+		untraced.collect(rw)
+		untraced.setTrailer(http.Header{})
+		untraced.logf("ignored")
+	}, "requests without the header are not traced")
+}
+
+func TestRequestDebugTrailer(t *testing.T) {
+	dbg := &requestDebug{ID: "abcd1234", TimingsMs: map[string]float64{"proxy": 12}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+		w.(http.Flusher).Flush()
+		dbg.setTrailer(w.Header())
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, dbg.report(), resp.Trailer.Get(debugHeader), "streams get the report as a trailer")
+}
+
+func TestRequestDebugLogBody(t *testing.T) {
+	const body = `{"model":"qwen","messages":[]}`
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+
+	var dbg *requestDebug
+	dbg.logBody(r)
+	dbg = &requestDebug{ID: "abcd1234", TimingsMs: map[string]float64{}}
+	dbg.logBody(r)
+
+	data, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(data), "the body is still readable")
+}
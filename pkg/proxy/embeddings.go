@@ -0,0 +1,180 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+const embeddingsPath = "/v1/embeddings"
+
+// podManager is the subset of K8sManager used to drive a single vLLM pod
+type podManager interface {
+	CreatePod(ctx context.Context, modelConfig *kubernetes.ModelConfig) error
+	DeletePod(ctx context.Context) error
+	PodExists(ctx context.Context) (bool, error)
+	IsPodReady(ctx context.Context) (bool, error)
+}
+
+// modelGetter resolves a model ID to its CRD configuration
+type modelGetter interface {
+	GetModel(ctx context.Context, servedModelName string) (*kubernetes.ModelConfig, error)
+}
+
+// embeddingBackend runs the embedding model pod alongside the chat pod.
+// It has its own lifecycle: embeddings traffic never wakes or switches the chat model.
+type embeddingBackend struct {
+	k8sManager   podManager
+	crdClient    modelGetter
+	modelID      string
+	targetURL    *url.URL
+	lastActivity time.Time
+	mu           sync.Mutex        // Guards lastActivity
+	scaleMu      sync.Mutex        // Serializes pod creation
+	coldStarts   *coldStartGate    // Shared with the chat model, nil when unlimited
+	leader       *leaderElector    // Starts the pod when this replica follows, nil without leader election
+	transport    http.RoundTripper // Requests to the embedding pod (nil = http.DefaultTransport)
+	// API key of the embedding pod sent in place of the client's, nil forwards the client's
+	apiKey func(ctx context.Context) string
+}
+
+// newEmbeddingBackend creates the embedding backend for the given model
+func newEmbeddingBackend(k8sManager podManager, crdClient modelGetter, modelID string, targetURL *url.URL) *embeddingBackend {
+	return &embeddingBackend{
+		k8sManager:   k8sManager,
+		crdClient:    crdClient,
+		modelID:      modelID,
+		targetURL:    targetURL,
+		lastActivity: time.Now(),
+	}
+}
+
+// ensureScaledUp creates the embedding pod if needed and waits until it is ready
+func (e *embeddingBackend) ensureScaledUp(ctx context.Context) error {
+	// Followers leave the pod to the leader
+	if e.leader.follows() {
+		req := scaleUpRequest{Embeddings: true}
+		resp, err := e.leader.requestScaleUp(req)
+		if err != nil {
+			return err
+		}
+		return resp.err(req)
+	}
+
+	e.scaleMu.Lock()
+	defer e.scaleMu.Unlock()
+
+	exists, err := e.k8sManager.PodExists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		queueCtx, cancelQueue := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+		defer cancelQueue()
+		release, err := e.coldStarts.acquire(queueCtx, e.modelID)
+		if err != nil {
+			return fmt.Errorf("timeout waiting for other models to start: %w", err)
+		}
+		defer release()
+
+		modelConfig, err := e.crdClient.GetModel(ctx, e.modelID)
+		if err != nil {
+			return fmt.Errorf("failed to get embedding model config for '%s': %w", e.modelID, err)
+		}
+		log.Printf("[EMBEDDINGS] Creating embedding pod with model: %s (%s)", e.modelID, modelConfig.ModelName)
+		if err := e.k8sManager.CreatePod(ctx, modelConfig); err != nil {
+			return err
+		}
+	}
+
+	// Use background context so request timeout doesn't cancel pod startup
+	waitCtx, cancel := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if ready, err := e.k8sManager.IsPodReady(waitCtx); err == nil && ready {
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("timeout waiting for embedding pod to be ready")
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateActivity records embeddings traffic
+func (e *embeddingBackend) updateActivity() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastActivity = time.Now()
+}
+
+// serveHTTP scales up the embedding pod if needed and proxies the request to it
+func (e *embeddingBackend) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	e.updateActivity()
+
+	if err := e.ensureScaledUp(r.Context()); err != nil {
+		log.Printf("[EMBEDDINGS] Failed to scale up: %v", err)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", "10")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		response := map[string]interface{}{
+			"error": map[string]interface{}{
+				"message": "Embedding service is starting up. Please wait and retry in a few moments.",
+				"type":    "service_unavailable",
+				"code":    "scaling_up",
+			},
+		}
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Printf("Failed to encode response: %v", err)
+		}
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(e.targetURL)
+	proxy.Transport = e.transport
+	if e.apiKey != nil {
+		authorizeUpstream(proxy, e.apiKey(r.Context()))
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		log.Printf("[EMBEDDINGS] Proxy error: %v", err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// checkIdle deletes the embedding pod once it has been idle longer than the timeout
+func (e *embeddingBackend) checkIdle(ctx context.Context, idleTimeout time.Duration) {
+	e.mu.Lock()
+	idleTime := time.Since(e.lastActivity)
+	e.mu.Unlock()
+
+	if idleTime <= idleTimeout {
+		return
+	}
+
+	exists, err := e.k8sManager.PodExists(ctx)
+	if err != nil {
+		log.Printf("[EMBEDDINGS] Failed to check pod existence: %v", err)
+		return
+	}
+	if exists {
+		log.Printf("[EMBEDDINGS] Idle for %v, deleting embedding pod...", idleTime.Round(time.Second))
+		if err := e.k8sManager.DeletePod(ctx); err != nil {
+			log.Printf("[EMBEDDINGS] Failed to delete pod: %v", err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// stubModelGetter returns a fixed model config
+type stubModelGetter struct {
+	models map[string]*kubernetes.ModelConfig
+}
+
+func (s *stubModelGetter) GetModel(_ context.Context, id string) (*kubernetes.ModelConfig, error) {
+	if m, ok := s.models[id]; ok {
+		return m, nil
+	}
+	return nil, &kubernetes.ModelNotFoundError{ModelID: id}
+}
+
+func readyEmbeddingPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-embed", Namespace: "test-ns"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+}
+
+func newTestEmbeddingBackend(t *testing.T, clientset *fake.Clientset, target string) *embeddingBackend {
+	t.Helper()
+	manager := kubernetes.NewK8sManager(clientset, &kubernetes.Config{
+		Namespace:   "test-ns",
+		Deployment:  "vllm-embed",
+		ServiceName: "vllm-embed-api",
+		AppLabel:    "vllm-embed",
+	})
+	targetURL, err := url.Parse(target)
+	require.NoError(t, err)
+	getter := &stubModelGetter{models: map[string]*kubernetes.ModelConfig{
+		"bge-m3": {ModelName: "BAAI/bge-m3", ServedModelName: "bge-m3", Embedding: "true"},
+	}}
+	return newEmbeddingBackend(manager, getter, "bge-m3", targetURL)
+}
+
+func TestEmbeddingBackend_ServeHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, embeddingsPath, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"list","data":[{"object":"embedding","embedding":[0.1],"index":0}]}`))
+	}))
+	defer upstream.Close()
+
+	clientset := fake.NewSimpleClientset(readyEmbeddingPod())
+	backend := newTestEmbeddingBackend(t, clientset, upstream.URL)
+
+	req := httptest.NewRequest(http.MethodPost, embeddingsPath, strings.NewReader(`{"model":"bge-m3","input":"hello"}`))
+	w := httptest.NewRecorder()
+	backend.serveHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"embedding"`)
+}
+
+func TestEmbeddingBackend_CreatesPodWhenMissing(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	// Mark pods as ready as soon as they are created
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		return false, nil, nil
+	})
+	backend := newTestEmbeddingBackend(t, clientset, "http://127.0.0.1:1")
+
+	err := backend.ensureScaledUp(context.Background())
+	require.NoError(t, err)
+
+	pod, err := clientset.CoreV1().Pods("test-ns").Get(context.Background(), "vllm-embed", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "vllm-embed", pod.Labels["app"])
+	assert.Contains(t, pod.Spec.Containers[0].Args, "embed")
+}
+
+func TestEmbeddingBackend_CheckIdle(t *testing.T) {
+	t.Run("deletes pod when idle", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(readyEmbeddingPod())
+		backend := newTestEmbeddingBackend(t, clientset, "http://127.0.0.1:1")
+		backend.lastActivity = time.Now().Add(-10 * time.Minute)
+
+		backend.checkIdle(context.Background(), 5*time.Minute)
+
+		exists, err := backend.k8sManager.PodExists(context.Background())
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("keeps pod when active", func(t *testing.T) {
+		clientset := fake.NewSimpleClientset(readyEmbeddingPod())
+		backend := newTestEmbeddingBackend(t, clientset, "http://127.0.0.1:1")
+
+		backend.checkIdle(context.Background(), 5*time.Minute)
+
+		exists, err := backend.k8sManager.PodExists(context.Background())
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+}
@@ -0,0 +1,62 @@
+package proxy
+
+import (
+	"log"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+// GPU states reported by vllm_chill_gpu_state
+const (
+	gpuStateIdle = iota // No model in GPU memory
+	gpuStateWarm        // A model and its KV cache in GPU memory, but no kernel running
+	gpuStateBusy        // Generating
+)
+
+// gpuWarmMemoryPercent is the GPU memory use from which a model is considered loaded. vLLM
+// reserves its memory utilization share up front, so a loaded model is well above it.
+const gpuWarmMemoryPercent = 10
+
+// gpuSampler reads the load of the GPUs, implemented by stats.GPUStatsHandler through NVML
+type gpuSampler interface {
+	Activity() ([]stats.GPUActivity, error)
+}
+
+// gpuState classifies the GPUs: busy when any of them is at or above busyUtilization percent,
+// warm when any holds a model, idle otherwise
+func gpuState(activity []stats.GPUActivity, busyUtilization int) int {
+	state := gpuStateIdle
+	for _, gpu := range activity {
+		if gpu.Utilization >= float64(busyUtilization) {
+			return gpuStateBusy
+		}
+		if gpu.MemoryUtil() >= gpuWarmMemoryPercent {
+			state = gpuStateWarm
+		}
+	}
+	return state
+}
+
+// sampleGPU reads and exports the load of the GPUs, and returns their state. It reports false
+// when GPU sampling is disabled or NVML can't be queried, e.g. the proxy doesn't run on the GPU
+// node, the decisions then resting on HTTP activity alone.
+func (as *AutoScaler) sampleGPU() (int, bool) {
+	if as.gpu == nil {
+		return gpuStateIdle, false
+	}
+	activity, err := as.gpu.Activity()
+	if err != nil {
+		// Logged once, not on every check
+		if !as.gpuUnavailable {
+			log.Printf("GPU stats unavailable, scale-down ignores GPU activity: %v", err)
+			as.gpuUnavailable = true
+		}
+		return gpuStateIdle, false
+	}
+	as.gpuUnavailable = false
+
+	state := gpuState(activity, as.config.GPUBusyUtilization)
+	as.metrics.RecordGPUActivity(activity)
+	as.metrics.SetGPUState(state)
+	return state, true
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGPU returns a fixed sample of the GPUs
+type fakeGPU struct {
+	activity []stats.GPUActivity
+	err      error
+}
+
+func (f *fakeGPU) Activity() ([]stats.GPUActivity, error) {
+	return f.activity, f.err
+}
+
+func TestGPUState(t *testing.T) {
+	loaded := stats.GPUActivity{Utilization: 3, MemoryUsed: 21504, MemoryTotal: 24576}
+	generating := stats.GPUActivity{Utilization: 95, MemoryUsed: 21504, MemoryTotal: 24576}
+	empty := stats.GPUActivity{MemoryUsed: 300, MemoryTotal: 24576}
+
+	assert.Equal(t, gpuStateIdle, gpuState(nil, 50))
+	assert.Equal(t, gpuStateIdle, gpuState([]stats.GPUActivity{empty}, 50))
+	assert.Equal(t, gpuStateWarm, gpuState([]stats.GPUActivity{empty, loaded}, 50), "KV cache warm but nothing running")
+	assert.Equal(t, gpuStateBusy, gpuState([]stats.GPUActivity{loaded, generating}, 50))
+}
+
+func TestCheckIdleDefersWhileGPUBusy(t *testing.T) {
+	now := time.Now()
+	as, clientset := scheduleTestAutoScaler(t, now, time.Hour, vllmPod())
+	as.config.GPUBusyUtilization = 50
+	gpu := &fakeGPU{activity: []stats.GPUActivity{{Utilization: 90, MemoryUsed: 21504, MemoryTotal: 24576}}}
+	as.gpu = gpu
+
+	// A long streamed response keeps the GPU busy without HTTP activity
+	as.checkIdle(context.Background(), now)
+	assert.True(t, podExists(t, clientset))
+
+	// Warm but idle GPUs don't hold the model
+	gpu.activity[0].Utilization = 2
+	as.checkIdle(context.Background(), now)
+	assert.False(t, podExists(t, clientset))
+}
+
+func TestCheckIdleWithoutGPUStats(t *testing.T) {
+	now := time.Now()
+	as, clientset := scheduleTestAutoScaler(t, now, time.Hour, vllmPod())
+	as.config.GPUBusyUtilization = 50
+	as.gpu = &fakeGPU{err: errors.New("NVML not initialized")}
+
+	as.checkIdle(context.Background(), now)
+	assert.False(t, podExists(t, clientset), "scale-down falls back on HTTP activity")
+	assert.True(t, as.gpuUnavailable)
+}
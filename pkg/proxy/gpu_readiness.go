@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gpuDriverNotReady is the error code of requests that can't be served until the GPU device plugin is ready
+const gpuDriverNotReady = "gpu_driver_not_ready"
+
+// gpuRecreateLimit bounds the recreations of a pod rejected at admission during a single wait
+const gpuRecreateLimit = 3
+
+// vllmStateGPUDriverNotReady is the vllm_chill_vllm_state value of a pod waiting for the device plugin
+const vllmStateGPUDriverNotReady = 4
+
+// GPUNotReadyError reports that vLLM could not start because no node advertises allocatable GPUs,
+// typically while the NVIDIA device plugin starts after a node reboot
+type GPUNotReadyError struct {
+	Reason string
+}
+
+func (e *GPUNotReadyError) Error() string {
+	return fmt.Sprintf("GPU driver not ready: %s", e.Reason)
+}
+
+// gpuWait tracks a pod start blocked on the GPU device plugin
+type gpuWait struct {
+	reason    string // Last scheduling failure, empty once the pod got its GPUs
+	recreated int    // Pods recreated after an admission rejection
+}
+
+// waitingForGPUs reports whether the pod is blocked on the GPU device plugin. The scheduler retries
+// unschedulable pods by itself, but the kubelet never retries a pod it rejected at admission, so
+// that one is recreated once a node advertises enough GPUs again.
+func (as *AutoScaler) waitingForGPUs(ctx context.Context, pod *corev1.Pod, wait *gpuWait) bool {
+	reason, blocked := kubernetes.GPUSchedulingFailure(pod)
+	if !blocked {
+		if as.gpuNotReady.Swap(false) {
+			log.Printf("GPU device plugin ready, vLLM pod scheduled")
+		}
+		wait.reason = ""
+		return false
+	}
+
+	wait.reason = reason
+	if !as.gpuNotReady.Swap(true) {
+		log.Printf("vLLM pod waiting for the GPU device plugin: %s", reason)
+		as.setVLLMState(vllmStateGPUDriverNotReady)
+	}
+	if pod.Status.Phase != corev1.PodFailed || wait.recreated >= gpuRecreateLimit {
+		return true
+	}
+
+	available, err := as.k8sManager.GPUsAvailable(ctx)
+	if err != nil {
+		log.Printf("Failed to check the nodes' GPUs: %v", err)
+		return true
+	}
+	if !available {
+		return true
+	}
+	wait.recreated++
+	log.Printf("GPUs allocatable, recreating the rejected vLLM pod (attempt %d/%d)", wait.recreated, gpuRecreateLimit)
+	if err := as.managePod(ctx, false); err != nil {
+		log.Printf("Failed to delete the rejected pod: %v", err)
+		return true
+	}
+	if err := as.managePod(ctx, true); err != nil {
+		log.Printf("Failed to recreate the pod: %v", err)
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWaitingForGPUs(t *testing.T) {
+	ctx := context.Background()
+	pod := vllmPod()
+	pod.Status = corev1.PodStatus{Phase: corev1.PodFailed, Reason: "UnexpectedAdmissionError"}
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, pod)
+	as.activeModel = "qwen"
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(0, 1))
+
+	var wait gpuWait
+	assert.True(t, as.waitingForGPUs(ctx, pod, &wait), "a pod rejected at admission waits for the device plugin")
+	assert.True(t, as.gpuNotReady.Load())
+	assert.Equal(t, 0, wait.recreated, "the pod is kept until a node advertises GPUs")
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu-node"},
+		Status:     corev1.NodeStatus{Allocatable: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")}},
+	}
+	_, err := clientset.CoreV1().Nodes().Create(ctx, node, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.True(t, as.waitingForGPUs(ctx, pod, &wait))
+	assert.Equal(t, 1, wait.recreated)
+
+	recreated, err := clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, recreated.Status.Phase, "the rejected pod is replaced")
+	assert.False(t, as.waitingForGPUs(ctx, recreated, &wait))
+	assert.Empty(t, wait.reason)
+	assert.False(t, as.gpuNotReady.Load())
+}
+
+func TestWaitingForGPUs_RecreateLimit(t *testing.T) {
+	pod := vllmPod()
+	pod.Status = corev1.PodStatus{Phase: corev1.PodFailed, Reason: "UnexpectedAdmissionError"}
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyDelete, pod)
+
+	wait := gpuWait{recreated: gpuRecreateLimit}
+	assert.True(t, as.waitingForGPUs(context.Background(), pod, &wait))
+	assert.Equal(t, gpuRecreateLimit, wait.recreated, "recreations are bounded")
+	assert.Equal(t, `GPU driver not ready: UnexpectedAdmissionError`, (&GPUNotReadyError{Reason: wait.reason}).Error())
+}
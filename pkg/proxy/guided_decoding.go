@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// guidedDecodingHeader carries vLLM's guided decoding parameters as a JSON object, for clients of
+// the Anthropic API whose SDKs don't send fields outside the API in the body
+const guidedDecodingHeader = "X-Chill-Guided-Decoding"
+
+// guidedDecodingPaths are the endpoints vLLM guides the generation of
+var guidedDecodingPaths = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	anthropicMessagesPath:  true,
+}
+
+// guidedParams are vLLM's guided decoding request parameters, with the type they take
+var guidedParams = map[string]string{
+	"guided_json":    "an object or a string",
+	"guided_regex":   "a string",
+	"guided_choice":  "an array of strings",
+	"guided_grammar": "a string",
+}
+
+// hasGuidedParam reports whether a request sets one of the guided decoding parameters, vLLM
+// taking a single one per request
+func hasGuidedParam(fields map[string]json.RawMessage) bool {
+	for name := range guidedParams {
+		if hasAnyField(fields, []string{name}) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkGuidedParams checks the guided decoding parameters of a request, when set, are of the
+// types vLLM takes, naming them with prefix
+func checkGuidedParams(fields map[string]json.RawMessage, prefix string) error {
+	for _, name := range slices.Sorted(maps.Keys(guidedParams)) {
+		raw, ok := fields[name]
+		if !ok || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		var valid bool
+		switch name {
+		case "guided_json":
+			valid = jsonType(raw) == "object" || jsonType(raw) == "string"
+		case "guided_choice":
+			var choices []string
+			valid = json.Unmarshal(raw, &choices) == nil && len(choices) > 0
+		default:
+			valid = jsonType(raw) == "string"
+		}
+		if !valid {
+			return invalidType(prefix+name, guidedParams[name], raw)
+		}
+	}
+	return nil
+}
+
+// applyGuidedDecodingHeader moves the guided decoding parameters of the X-Chill-Guided-Decoding
+// header into the request body, removing the header. Parameters set in the body are kept. It
+// reports whether the body was rewritten; an invalid header is a *requestValidationError.
+func applyGuidedDecodingHeader(r *http.Request) (bool, error) {
+	value := r.Header.Get(guidedDecodingHeader)
+	if value == "" || !guidedDecodingPaths[r.URL.Path] {
+		return false, nil
+	}
+	r.Header.Del(guidedDecodingHeader)
+
+	var params map[string]json.RawMessage
+	if json.Unmarshal([]byte(value), &params) != nil || params == nil {
+		return false, &requestValidationError{param: guidedDecodingHeader, code: "invalid_header",
+			message: fmt.Sprintf("Invalid %s header: expected a JSON object of guided decoding parameters.", guidedDecodingHeader)}
+	}
+	for name := range params {
+		if _, ok := guidedParams[name]; !ok {
+			return false, &requestValidationError{param: guidedDecodingHeader, code: "invalid_header",
+				message: fmt.Sprintf("Invalid %s header: unknown guided decoding parameter '%s'.", guidedDecodingHeader, name)}
+		}
+	}
+	if err := checkGuidedParams(params, guidedDecodingHeader+"."); err != nil {
+		return false, err
+	}
+
+	body, err := parsedBody(r)
+	if err != nil {
+		return false, err
+	}
+	fields, err := body.object()
+	if err != nil {
+		return false, err
+	}
+	if hasGuidedParam(fields) {
+		return false, nil
+	}
+	maps.Copy(fields, params)
+	return true, body.rewrite(r)
+}
+
+// anthropicTool is the part of an Anthropic tool definition guided decoding needs
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	InputSchema json.RawMessage `json:"input_schema"`
+}
+
+// forcedTool returns the tool an Anthropic tool_choice makes the model call: the named tool of
+// {"type":"tool"}, or the only tool with {"type":"any"}. It is nil when the model may answer in
+// text or pick among several tools.
+func forcedTool(rawChoice, rawTools json.RawMessage) *anthropicTool {
+	var choice struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}
+	var tools []anthropicTool
+	if json.Unmarshal(rawChoice, &choice) != nil || json.Unmarshal(rawTools, &tools) != nil {
+		return nil
+	}
+	switch {
+	case choice.Type == "any" && len(tools) == 1:
+		return &tools[0]
+	case choice.Type == "tool":
+		for i := range tools {
+			if tools[i].Name == choice.Name {
+				return &tools[i]
+			}
+		}
+	}
+	return nil
+}
+
+// applyGuidedDecoding constrains a /v1/messages request forcing a tool call to the tool's
+// input_schema, through vLLM's guided_json, so the arguments always parse. OpenAI requests are
+// left as sent: vLLM already guides response_format and named tool_choice. It reports whether
+// the body was rewritten; requests setting a guided decoding parameter themselves are left as sent.
+func applyGuidedDecoding(r *http.Request) (bool, error) {
+	if r.URL.Path != anthropicMessagesPath {
+		return false, nil
+	}
+	body, err := parsedBody(r)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Contains(body.data, []byte(`"input_schema"`)) {
+		return false, nil
+	}
+	fields, err := body.object()
+	if err != nil {
+		return false, err
+	}
+	if hasGuidedParam(fields) {
+		return false, nil
+	}
+	tool := forcedTool(fields["tool_choice"], fields["tools"])
+	if tool == nil || jsonType(tool.InputSchema) != "object" {
+		return false, nil
+	}
+
+	fields["guided_json"] = tool.InputSchema
+	return true, body.rewrite(r)
+}
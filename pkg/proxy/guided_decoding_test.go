@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyGuidedDecoding(t *testing.T) {
+	weather := `{"name":"get_weather","input_schema":{"type":"object","required":["city"]}}`
+	search := `{"name":"search","input_schema":{"type":"object"}}`
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		guided string
+	}{
+		{name: "named tool", path: anthropicMessagesPath, body: `{"tools":[` + search + `,` + weather + `],"tool_choice":{"type":"tool","name":"get_weather"}}`, guided: `{"type":"object","required":["city"]}`},
+		{name: "single tool", path: anthropicMessagesPath, body: `{"tools":[` + weather + `],"tool_choice":{"type":"any"}}`, guided: `{"type":"object","required":["city"]}`},
+		{name: "any of several", path: anthropicMessagesPath, body: `{"tools":[` + search + `,` + weather + `],"tool_choice":{"type":"any"}}`},
+		{name: "auto", path: anthropicMessagesPath, body: `{"tools":[` + weather + `],"tool_choice":{"type":"auto"}}`},
+		{name: "unknown tool", path: anthropicMessagesPath, body: `{"tools":[` + weather + `],"tool_choice":{"type":"tool","name":"ls"}}`},
+		{name: "guided by the client", path: anthropicMessagesPath, body: `{"tools":[` + weather + `],"tool_choice":{"type":"any"},"guided_regex":"a"}`},
+		{name: "openai", path: "/v1/chat/completions", body: `{"tools":[` + weather + `],"tool_choice":{"type":"any"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			guided, err := applyGuidedDecoding(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.guided != "", guided)
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			if tt.guided == "" {
+				assert.Equal(t, tt.body, string(body), "the body is forwarded as sent")
+				return
+			}
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(body, &fields))
+			assert.JSONEq(t, tt.guided, string(fields["guided_json"]))
+			assert.Equal(t, int64(len(body)), r.ContentLength)
+		})
+	}
+}
+
+func TestProxyHandler_GuidedDecodingPerModel(t *testing.T) {
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwarded = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","content":[]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	request := `{"model":"qwen","max_tokens":64,"messages":[],"tools":[{"name":"ls","input_schema":{"type":"object"}}],"tool_choice":{"type":"any"}}`
+
+	as.modelOptions.Store("qwen", &modelOptions{toolParser: parser.ForModel("", "")})
+	as.proxyHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(request)))
+	assert.NotContains(t, forwarded, "guided_json")
+
+	as.modelOptions.Store("qwen", &modelOptions{toolParser: parser.ForModel("", ""), guidedDecoding: true})
+	as.proxyHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(request)))
+	assert.Contains(t, forwarded, `"guided_json":{"type":"object"}`)
+
+	// Structured output requested through the header takes precedence
+	r := httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(request))
+	r.Header.Set(guidedDecodingHeader, `{"guided_regex":"ls"}`)
+	as.proxyHandler(httptest.NewRecorder(), r)
+	assert.Contains(t, forwarded, `"guided_regex":"ls"`)
+	assert.NotContains(t, forwarded, "guided_json")
+
+	w := httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(request))
+	r.Header.Set(guidedDecodingHeader, `{"guided_regex":1}`)
+	as.proxyHandler(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestApplyGuidedDecodingHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		body   string
+		want   string
+		code   string
+	}{
+		{name: "regex", path: anthropicMessagesPath, header: `{"guided_regex":"\\d+"}`, body: `{"model":"qwen"}`, want: `{"model":"qwen","guided_regex":"\\d+"}`},
+		{name: "choice", path: "/v1/chat/completions", header: `{"guided_choice":["yes","no"]}`, body: `{"model":"qwen"}`, want: `{"model":"qwen","guided_choice":["yes","no"]}`},
+		{name: "body wins", path: anthropicMessagesPath, header: `{"guided_regex":"a"}`, body: `{"guided_json":{}}`},
+		{name: "no header", path: anthropicMessagesPath, body: `{"model":"qwen"}`},
+		{name: "other endpoint", path: "/v1/embeddings", header: `{"guided_regex":"a"}`, body: `{"model":"qwen"}`},
+		{name: "not json", path: anthropicMessagesPath, header: `regex`, body: `{}`, code: "invalid_header"},
+		{name: "unknown parameter", path: anthropicMessagesPath, header: `{"guided_xml":"a"}`, body: `{}`, code: "invalid_header"},
+		{name: "invalid type", path: anthropicMessagesPath, header: `{"guided_choice":"yes"}`, body: `{}`, code: "invalid_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set(guidedDecodingHeader, tt.header)
+			}
+			moved, err := applyGuidedDecodingHeader(r)
+			if tt.code != "" {
+				var validationErr *requestValidationError
+				require.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, tt.code, validationErr.code)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want != "", moved)
+			if tt.path != "/v1/embeddings" {
+				assert.Empty(t, r.Header.Get(guidedDecodingHeader), "the header is not forwarded")
+			}
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Equal(t, tt.body, string(body), "the body is forwarded as sent")
+				return
+			}
+			assert.JSONEq(t, tt.want, string(body))
+			assert.Equal(t, int64(len(body)), r.ContentLength)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// heartbeatWriter keeps a streaming request's connection alive while vLLM starts: until the backend
+// answers, it sends the SSE headers and a heartbeat event every interval, Anthropic ping events on
+// /v1/messages and SSE comment lines on the OpenAI endpoints, with the startup phase when known.
+// Once heartbeats were sent, the status is committed, so the response's WriteHeader is dropped and
+// error responses are sent as SSE error events instead.
+type heartbeatWriter struct {
+	http.ResponseWriter
+	anthropic bool
+	progress  func() string // Startup phase added to the OpenAI heartbeats, nil for none
+
+	mu      sync.Mutex
+	started bool          // Headers sent by a heartbeat
+	failed  int           // Error status of the response written after the heartbeats
+	errBody bytes.Buffer  // Error response, sent as an SSE error event by finish
+	stopCh  chan struct{} // Closed by stop
+	done    chan struct{} // Closed when the heartbeat loop exits
+}
+
+// newHeartbeatWriter wraps w for a streaming request to path
+func newHeartbeatWriter(w http.ResponseWriter, path string) *heartbeatWriter {
+	return &heartbeatWriter{ResponseWriter: w, anthropic: path == anthropicMessagesPath}
+}
+
+// streamingCompletion reports whether r is a streamed completion request, from the body the proxy
+// parsed, keeping it readable
+func streamingCompletion(r *http.Request) (bool, error) {
+	if r.Method != http.MethodPost || !coldStartRetryPaths[r.URL.Path] || r.Body == nil {
+		return false, nil
+	}
+	body, err := parsedBody(r)
+	if err != nil {
+		return false, err
+	}
+	return body.streamed(), nil
+}
+
+// start sends a heartbeat every interval until stop
+func (hw *heartbeatWriter) start(interval time.Duration) {
+	hw.stopCh, hw.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(hw.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hw.stopCh:
+				return
+			case <-ticker.C:
+				if err := hw.heartbeat(); err != nil {
+					log.Printf("Failed to send a heartbeat, the client is probably gone: %v", err)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// stop ends the heartbeats before the response is written
+func (hw *heartbeatWriter) stop() {
+	if hw == nil || hw.stopCh == nil {
+		return
+	}
+	close(hw.stopCh)
+	<-hw.done
+	hw.stopCh = nil
+}
+
+func (hw *heartbeatWriter) heartbeat() error {
+	hw.mu.Lock()
+	defer hw.mu.Unlock()
+	if !hw.started {
+		hw.started = true
+		hw.Header().Set("Content-Type", "text/event-stream")
+		hw.Header().Set("Cache-Control", "no-cache")
+		hw.ResponseWriter.WriteHeader(http.StatusOK)
+	}
+
+	event := ": processing\n\n"
+	if hw.progress != nil {
+		if phase := hw.progress(); phase != "" {
+			event = ": processing (" + phase + ")\n\n"
+		}
+	}
+	if hw.anthropic {
+		event = "event: ping\ndata: {\"type\": \"ping\"}\n\n"
+	}
+	if _, err := io.WriteString(hw.ResponseWriter, event); err != nil {
+		return err
+	}
+	if flusher, ok := hw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (hw *heartbeatWriter) WriteHeader(code int) {
+	if !hw.started {
+		hw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code >= http.StatusBadRequest {
+		hw.failed = code
+	}
+}
+
+func (hw *heartbeatWriter) Write(b []byte) (int, error) {
+	if hw.failed != 0 {
+		return hw.errBody.Write(b)
+	}
+	return hw.ResponseWriter.Write(b)
+}
+
+func (hw *heartbeatWriter) Flush() {
+	if flusher, ok := hw.ResponseWriter.(http.Flusher); ok && hw.failed == 0 {
+		flusher.Flush()
+	}
+}
+
+// finish sends the error response written after the heartbeats as an SSE error event
+func (hw *heartbeatWriter) finish() error {
+	hw.stop()
+	if hw.failed == 0 {
+		return nil
+	}
+
+	// Error responses are OpenAI-style JSON, or plain text from the reverse proxy
+	body := bytes.TrimSpace(hw.errBody.Bytes())
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error.Message == "" {
+		message := string(body)
+		if message == "" {
+			message = http.StatusText(hw.failed)
+		}
+		apiErr.Error.Message = message
+		body, _ = json.Marshal(map[string]interface{}{
+			"error": map[string]interface{}{"message": message, "type": "api_error", "code": hw.failed},
+		})
+	}
+
+	event := fmt.Sprintf("data: %s\n\ndata: [DONE]\n\n", body)
+	if hw.anthropic {
+		errorType := "api_error"
+		if hw.failed == http.StatusServiceUnavailable {
+			errorType = "overloaded_error"
+		}
+		data, _ := json.Marshal(map[string]interface{}{
+			"type":  "error",
+			"error": map[string]interface{}{"type": errorType, "message": apiErr.Error.Message},
+		})
+		event = fmt.Sprintf("event: error\ndata: %s\n\n", data)
+	}
+	if _, err := io.WriteString(hw.ResponseWriter, event); err != nil {
+		return err
+	}
+	if flusher, ok := hw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitForHeartbeats lets the heartbeat loop run a few intervals, then stops it
+func waitForHeartbeats(hw *heartbeatWriter) {
+	hw.start(5 * time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	hw.stop()
+}
+
+func TestHeartbeatWriterOpenAI(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	hw := newHeartbeatWriter(recorder, "/v1/chat/completions")
+	waitForHeartbeats(hw)
+
+	// The backend's stream follows the heartbeats, its status was already sent
+	hw.Header().Set("Content-Type", "text/event-stream")
+	hw.WriteHeader(http.StatusOK)
+	_, err := hw.Write([]byte("data: {\"choices\":[]}\n\ndata: [DONE]\n\n"))
+	require.NoError(t, err)
+	require.NoError(t, hw.finish())
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.True(t, strings.HasPrefix(body, ": processing\n\n"), body)
+	assert.True(t, strings.HasSuffix(body, ": processing\n\ndata: {\"choices\":[]}\n\ndata: [DONE]\n\n"), body)
+}
+
+func TestHeartbeatWriterAnthropicError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	hw := newHeartbeatWriter(recorder, anthropicMessagesPath)
+	waitForHeartbeats(hw)
+
+	writeAPIError(hw, http.StatusServiceUnavailable, "The GPU driver is not ready yet", "service_unavailable", gpuDriverNotReady)
+	require.NoError(t, hw.finish())
+
+	assert.Equal(t, http.StatusOK, recorder.Code, "the status was sent with the first heartbeat")
+	body := recorder.Body.String()
+	assert.True(t, strings.HasPrefix(body, "event: ping\ndata: {\"type\": \"ping\"}\n\n"), body)
+	assert.True(t, strings.HasSuffix(body, "event: error\ndata: {\"error\":{\"message\":\"The GPU driver is not ready yet\",\"type\":\"overloaded_error\"},\"type\":\"error\"}\n\n"), body)
+}
+
+func TestHeartbeatWriterPlainTextError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	hw := newHeartbeatWriter(recorder, "/v1/completions")
+	waitForHeartbeats(hw)
+
+	http.Error(hw, "Bad Gateway", http.StatusBadGateway)
+	require.NoError(t, hw.finish())
+	assert.True(t, strings.HasSuffix(recorder.Body.String(),
+		"data: {\"error\":{\"code\":502,\"message\":\"Bad Gateway\",\"type\":\"api_error\"}}\n\ndata: [DONE]\n\n"), recorder.Body.String())
+}
+
+func TestHeartbeatWriterWarmModel(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	hw := newHeartbeatWriter(recorder, "/v1/chat/completions")
+
+	// The backend answers before the first heartbeat is due
+	hw.start(time.Hour)
+	hw.stop()
+	writeAPIError(hw, http.StatusBadRequest, "invalid request", "invalid_request_error", "bad_request")
+	require.NoError(t, hw.finish())
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "the response is untouched")
+	assert.Contains(t, recorder.Body.String(), `"invalid request"`)
+	assert.NotContains(t, recorder.Body.String(), "processing")
+}
+
+func TestStreamingCompletion(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		stream bool
+	}{
+		{name: "streamed chat completion", method: http.MethodPost, path: "/v1/chat/completions", body: `{"model":"qwen","stream":true}`, stream: true},
+		{name: "streamed anthropic message", method: http.MethodPost, path: anthropicMessagesPath, body: `{"stream":true,"model":"qwen"}`, stream: true},
+		{name: "not streamed", method: http.MethodPost, path: "/v1/chat/completions", body: `{"model":"qwen"}`},
+		{name: "not a completion", method: http.MethodPost, path: "/v1/embeddings", body: `{"stream":true}`},
+		{name: "invalid JSON", method: http.MethodPost, path: "/v1/completions", body: `stream`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			stream, err := streamingCompletion(req)
+			require.NoError(t, err)
+			assert.Equal(t, tt.stream, stream)
+
+			data, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(data), "the body is still readable")
+		})
+	}
+	// The proxy's parsed body is used, the body isn't read again
+	req, err := withRequestBody(httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","stream":true}`)))
+	require.NoError(t, err)
+	req.Body = io.NopCloser(iotest.ErrReader(errors.New("read again")))
+	stream, err := streamingCompletion(req)
+	require.NoError(t, err)
+	assert.True(t, stream)
+}
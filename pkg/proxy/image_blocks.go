@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// anthropicImageTypes are the media types the Anthropic API accepts in image blocks
+var anthropicImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// imageBlock is an Anthropic content block, only the fields of images and of the tool results
+// that may hold them
+type imageBlock struct {
+	Type   string `json:"type"`
+	Source struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+		URL       string `json:"url"`
+	} `json:"source"`
+	Content json.RawMessage `json:"content"`
+}
+
+// imageBlockError reports an image block that can't be sent to the model
+type imageBlockError struct {
+	message string
+}
+
+func (e *imageBlockError) Error() string {
+	return e.message
+}
+
+// checkImageBlocks validates the image blocks of an Anthropic request before vLLM decodes them.
+// Base64 sources must hold an image of a supported type, matching their media_type, and no larger
+// than maxSize once decoded (0 = unlimited). URL sources must be http or https URLs. Invalid blocks
+// are reported as an *imageBlockError; requests that are not valid JSON are left to vLLM.
+func checkImageBlocks(r *http.Request, maxSize int64) error {
+	body, err := parsedBody(r)
+	if err != nil {
+		return err
+	}
+	if body.fields == nil || !bytes.Contains(body.data, []byte(`"image"`)) {
+		return nil
+	}
+
+	var messages []struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if json.Unmarshal(body.fields["messages"], &messages) != nil {
+		return nil
+	}
+	for i, message := range messages {
+		if err := checkImageContent(message.Content, fmt.Sprintf("messages.%d.content", i), maxSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkImageContent checks the image blocks of a content, those of tool results included
+func checkImageContent(content json.RawMessage, path string, maxSize int64) error {
+	var blocks []imageBlock
+	if json.Unmarshal(content, &blocks) != nil {
+		return nil // String content
+	}
+	for i, block := range blocks {
+		blockPath := fmt.Sprintf("%s.%d", path, i)
+		switch block.Type {
+		case "image":
+			if err := checkImageSource(block, maxSize); err != nil {
+				return &imageBlockError{message: blockPath + ": " + err.Error()}
+			}
+		case "tool_result":
+			if err := checkImageContent(block.Content, blockPath+".content", maxSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkImageSource checks the source of an image block
+func checkImageSource(block imageBlock, maxSize int64) error {
+	source := block.Source
+	switch source.Type {
+	case "base64":
+		if !anthropicImageTypes[source.MediaType] {
+			return fmt.Errorf("unsupported image media type %q, expected image/jpeg, image/png, image/gif or image/webp", source.MediaType)
+		}
+		if maxSize > 0 && int64(base64.StdEncoding.DecodedLen(len(source.Data))) > maxSize+2 {
+			// Padding aside, the decoded size is known without decoding
+			return fmt.Errorf("image exceeds the maximum size of %d bytes", maxSize)
+		}
+		data, err := base64.StdEncoding.DecodeString(source.Data)
+		if err != nil {
+			return fmt.Errorf("image data is not valid base64")
+		}
+		if maxSize > 0 && int64(len(data)) > maxSize {
+			return fmt.Errorf("image exceeds the maximum size of %d bytes", maxSize)
+		}
+		if detected := http.DetectContentType(data); detected != source.MediaType {
+			return fmt.Errorf("image data is %s, not the declared %s", detected, source.MediaType)
+		}
+	case "url":
+		u, err := url.Parse(source.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid image URL %q, expected an http or https URL", source.URL)
+		}
+	default:
+		return fmt.Errorf("unsupported image source type %q, expected base64 or url", source.Type)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngImage is the start of a PNG file, enough to be recognized
+var pngImage = base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01"))
+
+func imageRequest(content string) *http.Request {
+	body := `{"model":"qwen-vl","max_tokens":64,"messages":[{"role":"user","content":"Hi"},{"role":"user","content":[{"type":"text","text":"What is this?"},` + content + `]}]}`
+	return httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(body))
+}
+
+func TestCheckImageBlocks_AcceptsValidImages(t *testing.T) {
+	for _, content := range []string{
+		`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + pngImage + `"}}`,
+		`{"type":"image","source":{"type":"url","url":"https://example.com/cat.jpg"}}`,
+		`{"type":"tool_result","tool_use_id":"call_a","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + pngImage + `"}}]}`,
+	} {
+		r := imageRequest(content)
+
+		require.NoError(t, checkImageBlocks(r, 1024), content)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), content, "the body is forwarded as sent")
+	}
+}
+
+func TestCheckImageBlocks_RejectsInvalidImages(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		maxSize int64
+		err     string
+	}{
+		{
+			name:    "media type",
+			content: `{"type":"image","source":{"type":"base64","media_type":"image/bmp","data":"` + pngImage + `"}}`,
+			err:     `messages.1.content.1: unsupported image media type "image/bmp"`,
+		},
+		{
+			name:    "mismatched data",
+			content: `{"type":"image","source":{"type":"base64","media_type":"image/jpeg","data":"` + pngImage + `"}}`,
+			err:     "image data is image/png, not the declared image/jpeg",
+		},
+		{
+			name:    "base64",
+			content: `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"not base64!"}}`,
+			err:     "not valid base64",
+		},
+		{
+			name:    "size",
+			content: `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + pngImage + `"}}`,
+			maxSize: 8,
+			err:     "image exceeds the maximum size of 8 bytes",
+		},
+		{
+			name:    "url",
+			content: `{"type":"image","source":{"type":"url","url":"file:///etc/passwd"}}`,
+			err:     "invalid image URL",
+		},
+		{
+			name:    "source type",
+			content: `{"type":"image","source":{"type":"file","file_id":"file_1"}}`,
+			err:     `unsupported image source type "file"`,
+		},
+		{
+			name:    "tool result",
+			content: `{"type":"tool_result","tool_use_id":"call_a","content":[{"type":"image","source":{"type":"url","url":"ftp://host/a.png"}}]}`,
+			err:     "messages.1.content.1.content.0: invalid image URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImageBlocks(imageRequest(tt.content), tt.maxSize)
+
+			var imageErr *imageBlockError
+			require.True(t, errors.As(err, &imageErr), "got %v", err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
+
+func TestProxyHandler_RejectsInvalidImageBlocks(t *testing.T) {
+	as := &AutoScaler{config: &Config{MaxImageSize: "1Ki"}, crdClient: newFakeCRDClient(t)}
+	w := httptest.NewRecorder()
+
+	as.proxyHandler(w, imageRequest(`{"type":"image","source":{"type":"base64","media_type":"image/gif","data":"`+pngImage+`"}}`))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"invalid_image"`)
+	assert.Contains(t, w.Body.String(), "not the declared image/gif")
+}
@@ -0,0 +1,548 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Lease timings, the client-go defaults of controllers
+const (
+	leaseDuration = 15 * time.Second
+	leaseRenewal  = 10 * time.Second
+	leaseRetry    = 2 * time.Second
+)
+
+const (
+	// leaderScaleUpPath is where the leader starts models for the other replicas
+	leaderScaleUpPath = "/proxy/leader/scale-up"
+
+	// leaderActivityPath is where the other replicas report their in-flight requests to the leader
+	leaderActivityPath = "/proxy/leader/activity"
+
+	// activityReportInterval is how often the other replicas report to the leader, well within the
+	// idle check interval
+	activityReportInterval = 5 * time.Second
+
+	// activityReportTTL is how long a report holds the model: a replica that stopped reporting,
+	// e.g. deleted mid-stream, doesn't keep it forever
+	activityReportTTL = 3 * activityReportInterval
+
+	// leaderScaleUpTimeout bounds a scale-up asked of the leader: the cold start queue, the model
+	// download and the startup each wait up to the scale-up timeout
+	leaderScaleUpTimeout = 3 * defaultScaleUpTimeout
+
+	// notLeader is the error code of scale-ups sent to a replica that lost the lease
+	notLeader = "not_leader"
+
+	// invalidLeaderToken is the error code of scale-ups without the replicas' shared token
+	invalidLeaderToken = "invalid_leader_token"
+)
+
+// LeaderTimeoutError is returned when the leader doesn't answer a scale-up in time
+type LeaderTimeoutError struct {
+	Leader  string
+	Timeout time.Duration
+}
+
+func (e *LeaderTimeoutError) Error() string {
+	return fmt.Sprintf("the proxy leader %s didn't answer the scale-up within %v", e.Leader, e.Timeout)
+}
+
+// leaderElector runs the Lease election between the proxy replicas. Every replica serves the
+// traffic, but only the leader creates and deletes pods: the others ask it to scale up, and report
+// their in-flight requests so it doesn't release a model they are streaming from.
+type leaderElector struct {
+	lock      *resourcelock.LeaseLock
+	client    *http.Client
+	identity  string // URL this replica advertises
+	token     string // Shared by the replicas, authenticates the requests they send the leader
+	leading   atomic.Bool
+	mu        sync.RWMutex
+	leaderURL string                    // Identity of the current leader, the URL it advertises
+	reports   map[string]activityReport // Latest report of each other replica, by identity, on the leader
+	cancel    context.CancelFunc        // Stops running for the lease and releases it
+	done      chan struct{}             // Closed once the lease is released
+}
+
+// newLeaderElector creates the elector of the replica reachable at identity, on the Lease name,
+// authenticating the scale-ups between replicas with the token
+func newLeaderElector(clientset k8sclient.Interface, namespace, name, identity, token string) *leaderElector {
+	return &leaderElector{
+		lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		client:   &http.Client{Timeout: leaderScaleUpTimeout},
+		identity: identity,
+		token:    token,
+	}
+}
+
+// start runs for the lease until release, running again each time it is lost
+func (l *leaderElector) start(ctx context.Context) error {
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            l.lock,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   leaseRenewal,
+		RetryPeriod:     leaseRetry,
+		ReleaseOnCancel: true,
+		Name:            l.lock.LeaseMeta.Name,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				l.leading.Store(true)
+				log.Printf("Leading the proxy replicas, this replica now manages the vLLM pods")
+			},
+			OnStoppedLeading: func() {
+				if l.leading.Swap(false) {
+					log.Printf("Lost the leader lease, leaving the vLLM pods to the next leader")
+				}
+			},
+			OnNewLeader: func(identity string) {
+				l.mu.Lock()
+				l.leaderURL = identity
+				l.mu.Unlock()
+				log.Printf("Proxy leader: %s", identity)
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to configure leader election: %w", err)
+	}
+
+	ctx, l.cancel = context.WithCancel(ctx)
+	l.done = make(chan struct{})
+	go func() {
+		defer close(l.done)
+		for ctx.Err() == nil {
+			elector.Run(ctx)
+		}
+	}()
+	return nil
+}
+
+// release stops running for the lease and releases it if held, so another replica takes over
+// without waiting for it to expire
+func (l *leaderElector) release() {
+	if l == nil || l.cancel == nil {
+		return
+	}
+	l.cancel()
+	<-l.done
+}
+
+// follows reports whether another replica manages the pods, false without leader election
+func (l *leaderElector) follows() bool {
+	return l != nil && !l.leading.Load()
+}
+
+// leader returns the URL of the current leader, empty until one is elected
+func (l *leaderElector) leader() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.leaderURL
+}
+
+// leading reports whether this replica manages the pods, always true without leader election
+func (as *AutoScaler) leading() bool {
+	return !as.leader.follows()
+}
+
+// scaleUpRequest asks the leader to start a model
+type scaleUpRequest struct {
+	Model      string `json:"model,omitempty"`      // Served model name to switch to, the active model when empty
+	Embeddings bool   `json:"embeddings,omitempty"` // Start the embedding pod instead
+	Shadow     bool   `json:"shadow,omitempty"`     // Start the shadow pod instead
+	Namespace  string `json:"namespace,omitempty"`  // Start the model in the pod of this tenant namespace instead
+
+	// Client request the scale-up is for, the leader checks its switch against the switch policy
+	// again. Nil for the admin and operations APIs, which switch whatever the policy.
+	Client *scaleUpClient `json:"client,omitempty"`
+}
+
+// scaleUpClient is what a follower decided a client request's switch on
+type scaleUpClient struct {
+	Pinned  bool   `json:"pinned,omitempty"`  // Sent with the no-switch header
+	Tenant  string `json:"tenant,omitempty"`  // Tenant of its API key, empty without key validation
+	Session string `json:"session,omitempty"` // Session keeping the active model with session affinity
+}
+
+// newScaleUpClient describes the client request of the context, nil outside of one
+func newScaleUpClient(ctx context.Context) *scaleUpClient {
+	pinned, ok := switchRequestFrom(ctx)
+	if !ok {
+		return nil
+	}
+	client := &scaleUpClient{Pinned: pinned, Session: affinitySessionFrom(ctx)}
+	if tenant := auth.TenantFrom(ctx); tenant != nil {
+		client.Tenant = tenant.Name
+	}
+	return client
+}
+
+// scaleUpResponse is the outcome of a scale-up by the leader, with the details the followers need
+// to answer their requests as the leader would
+type scaleUpResponse struct {
+	ActiveModel string    `json:"active_model"`
+	Error       string    `json:"error,omitempty"`
+	Code        string    `json:"code,omitempty"`
+	Reason      string    `json:"reason,omitempty"` // Why the GPUs aren't ready, or the switch is not allowed
+	StartedAt   time.Time `json:"started_at"`       // Start of the model download
+}
+
+// newScaleUpResponse describes the scale-up error, if any, and the status it is served with
+func newScaleUpResponse(err error) (int, scaleUpResponse) {
+	var (
+		gpuErr      *GPUNotReadyError
+		downloadErr *ModelDownloadingError
+		notFoundErr *ModelNotFoundError
+		switchErr   *SwitchNotAllowedError
+	)
+	switch {
+	case err == nil:
+		return http.StatusOK, scaleUpResponse{}
+	case errors.As(err, &gpuErr):
+		return http.StatusServiceUnavailable, scaleUpResponse{Error: err.Error(), Code: gpuDriverNotReady, Reason: gpuErr.Reason}
+	case errors.As(err, &downloadErr):
+		return http.StatusServiceUnavailable, scaleUpResponse{Error: err.Error(), Code: modelDownloading, StartedAt: downloadErr.StartedAt}
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound, scaleUpResponse{Error: err.Error(), Code: "model_not_found"}
+	case errors.As(err, &switchErr):
+		return http.StatusConflict, scaleUpResponse{Error: err.Error(), Code: modelSwitchNotAllowed, Reason: switchErr.Reason}
+	default:
+		return http.StatusServiceUnavailable, scaleUpResponse{Error: err.Error()}
+	}
+}
+
+// err turns the response back into the error the leader's scale-up returned
+func (r *scaleUpResponse) err(req scaleUpRequest) error {
+	switch {
+	case r.Error == "":
+		return nil
+	case r.Code == gpuDriverNotReady:
+		return &GPUNotReadyError{Reason: r.Reason}
+	case r.Code == modelDownloading:
+		return &ModelDownloadingError{Model: req.Model, StartedAt: r.StartedAt}
+	case r.Code == "model_not_found":
+		return &ModelNotFoundError{RequestedModel: req.Model}
+	case r.Code == modelSwitchNotAllowed:
+		return &SwitchNotAllowedError{RequestedModel: req.Model, Reason: r.Reason}
+	default:
+		return fmt.Errorf("leader failed to scale up: %s", r.Error)
+	}
+}
+
+// requestScaleUp asks the leader to start the model and waits until it is ready. The request's
+// deadline doesn't cancel it, like a scale-up by this replica.
+func (l *leaderElector) requestScaleUp(req scaleUpRequest) (*scaleUpResponse, error) {
+	leaderURL := l.leader()
+	if leaderURL == "" {
+		return nil, fmt.Errorf("no proxy leader elected yet")
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(leaderURL, "/")+leaderScaleUpPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid leader URL %q: %w", leaderURL, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+l.token)
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil, &LeaderTimeoutError{Leader: leaderURL, Timeout: l.client.Timeout}
+		}
+		return nil, fmt.Errorf("failed to reach the proxy leader %s: %w", leaderURL, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var result scaleUpResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("proxy leader %s answered %s", leaderURL, resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK && result.Error == "" {
+		result.Error = resp.Status
+	}
+	return &result, nil
+}
+
+// scaleUpThroughLeader has the leader switch to the active model and start it, adopting the model
+// the leader ends up running
+func (as *AutoScaler) scaleUpThroughLeader(ctx context.Context) error {
+	req := scaleUpRequest{Model: as.GetActiveModel(), Client: newScaleUpClient(ctx)}
+	resp, err := as.leader.requestScaleUp(req)
+	if err != nil {
+		return err
+	}
+	if resp.ActiveModel != "" {
+		as.mu.Lock()
+		as.activeModel = resp.ActiveModel
+		as.mu.Unlock()
+	}
+	return resp.err(req)
+}
+
+// authorized reports whether a request to the leader carries the replicas' token
+func (l *leaderElector) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && l.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(l.token)) == 1
+}
+
+// leaderScaleUpHandler starts a model for another replica, counting the request as activity
+func (as *AutoScaler) leaderScaleUpHandler(c *gin.Context) {
+	if !as.leader.authorized(c.Request) {
+		c.JSON(http.StatusUnauthorized, scaleUpResponse{Error: "missing or invalid leader token", Code: invalidLeaderToken})
+		return
+	}
+	if !as.leading() {
+		c.JSON(http.StatusConflict, scaleUpResponse{Error: "this replica is not the leader", Code: notLeader})
+		return
+	}
+	var req scaleUpRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, scaleUpResponse{Error: err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var err error
+	switch {
+	case req.Embeddings && as.embeddings == nil:
+		err = fmt.Errorf("no embedding model is configured")
+	case req.Embeddings:
+		as.embeddings.updateActivity()
+		err = as.embeddings.ensureScaledUp(ctx)
+	case req.Shadow && as.shadow == nil:
+		err = fmt.Errorf("no shadow model is configured")
+	case req.Shadow:
+		as.shadow.updateActivity()
+		err = as.shadow.ensureScaledUp(ctx)
+	case req.Namespace != "" && (as.namespaces == nil || as.namespaces.get(req.Namespace) == nil):
+		err = fmt.Errorf("namespace %s is not served", req.Namespace)
+	case req.Namespace != "":
+		backend := as.namespaces.get(req.Namespace)
+		backend.updateActivity()
+		err = backend.ensureScaledUp(ctx, req.Model)
+	default:
+		if err = as.checkLeaderSwitch(ctx, req); err != nil {
+			break
+		}
+		as.updateActivity()
+		if req.Model != "" {
+			err = as.handleModelSwitch(ctx, req.Model)
+		}
+		if err == nil {
+			err = as.ensureScaledUp(ctx)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to scale up for another replica: %v", err)
+	}
+
+	status, resp := newScaleUpResponse(err)
+	resp.ActiveModel = as.GetActiveModel()
+	c.JSON(status, resp)
+}
+
+// checkLeaderSwitch checks the switch a follower asks for a client request as the proxy handler
+// does: the no-switch header, the switch policy, the tenant's models and session affinity
+func (as *AutoScaler) checkLeaderSwitch(ctx context.Context, req scaleUpRequest) error {
+	if req.Client == nil || req.Model == "" || req.Model == as.GetActiveModel() {
+		return nil
+	}
+	switch {
+	case req.Client.Pinned:
+		return &SwitchNotAllowedError{RequestedModel: req.Model, Reason: fmt.Sprintf("the request was sent with %s", noSwitchHeader)}
+	case !as.config.switchAllowed(req.Model):
+		return &SwitchNotAllowedError{RequestedModel: req.Model, Reason: "the switch policy doesn't allow it"}
+	}
+	if req.Client.Tenant != "" && as.apiKeys != nil {
+		tenant, ok := as.apiKeys.Tenant(req.Client.Tenant)
+		if !ok || !tenant.AllowsModel(req.Model, as.resolveServedModel(ctx, req.Model)) {
+			return &SwitchNotAllowedError{RequestedModel: req.Model, Reason: fmt.Sprintf("tenant %s may not use it", req.Client.Tenant)}
+		}
+	}
+	var sessionErr *SessionActiveError
+	if err := as.checkSessionAffinity(context.WithValue(ctx, affinitySessionKey{}, req.Client.Session), req.Model); errors.As(err, &sessionErr) {
+		return &SwitchNotAllowedError{RequestedModel: req.Model, Reason: err.Error()}
+	}
+	return nil
+}
+
+// activityReport is what a replica following the leader reports of its traffic
+type activityReport struct {
+	Replica      string    `json:"replica"`       // URL the replica advertises
+	InFlight     int       `json:"in_flight"`     // Requests it is proxying to vLLM
+	LastActivity time.Time `json:"last_activity"` // Its last request
+	received     time.Time // When the leader got it
+}
+
+// reportActivity sends this replica's in-flight requests and last activity to the leader
+func (l *leaderElector) reportActivity(ctx context.Context, report activityReport) error {
+	leaderURL := l.leader()
+	if leaderURL == "" {
+		return fmt.Errorf("no proxy leader elected yet")
+	}
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, activityReportInterval)
+	defer cancel()
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(leaderURL, "/")+leaderActivityPath, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid leader URL %q: %w", leaderURL, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+l.token)
+	resp, err := l.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to reach the proxy leader %s: %w", leaderURL, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusNoContent {
+		var result scaleUpResponse
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.Error == "" {
+			return fmt.Errorf("proxy leader %s answered %s", leaderURL, resp.Status)
+		}
+		return fmt.Errorf("proxy leader %s refused the report: %s", leaderURL, result.Error)
+	}
+	return nil
+}
+
+// recordReport keeps the latest report of a replica
+func (l *leaderElector) recordReport(report activityReport, now time.Time) {
+	report.received = now
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.reports == nil {
+		l.reports = make(map[string]activityReport)
+	}
+	l.reports[report.Replica] = report
+}
+
+// followersInFlight returns the in-flight requests the other replicas reported within the report
+// TTL, forgetting the older reports. Always 0 without leader election.
+func (l *leaderElector) followersInFlight(now time.Time) int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	inFlight := 0
+	for replica, report := range l.reports {
+		if now.Sub(report.received) > activityReportTTL {
+			delete(l.reports, replica)
+			continue
+		}
+		inFlight += report.InFlight
+	}
+	return inFlight
+}
+
+// startActivityReports reports this replica's traffic to the leader while following it
+func (as *AutoScaler) startActivityReports(ctx context.Context) {
+	ticker := time.NewTicker(activityReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !as.leader.follows() || as.leader.leader() == "" {
+				continue
+			}
+			if err := as.reportActivity(ctx); err != nil {
+				log.Printf("Failed to report activity to the proxy leader: %v", err)
+			}
+		}
+	}
+}
+
+// reportActivity sends the leader this replica's in-flight requests and last activity
+func (as *AutoScaler) reportActivity(ctx context.Context) error {
+	as.mu.RLock()
+	lastActivity := as.lastActivity
+	as.mu.RUnlock()
+	return as.leader.reportActivity(ctx, activityReport{
+		Replica:      as.leader.identity,
+		InFlight:     as.replicas.inFlightCount(),
+		LastActivity: lastActivity,
+	})
+}
+
+// leaderActivityHandler records the traffic another replica reports: its in-flight requests hold
+// the model until they complete, and its last activity counts as the leader's
+func (as *AutoScaler) leaderActivityHandler(c *gin.Context) {
+	if !as.leader.authorized(c.Request) {
+		c.JSON(http.StatusUnauthorized, scaleUpResponse{Error: "missing or invalid leader token", Code: invalidLeaderToken})
+		return
+	}
+	if !as.leading() {
+		c.JSON(http.StatusConflict, scaleUpResponse{Error: "this replica is not the leader", Code: notLeader})
+		return
+	}
+	var report activityReport
+	if err := c.ShouldBindJSON(&report); err != nil {
+		c.JSON(http.StatusBadRequest, scaleUpResponse{Error: err.Error()})
+		return
+	}
+	if report.Replica == "" || report.InFlight < 0 {
+		c.JSON(http.StatusBadRequest, scaleUpResponse{Error: "the report needs the replica and a non-negative in-flight count"})
+		return
+	}
+
+	now := time.Now()
+	as.leader.recordReport(report, now)
+	// The replicas' clocks may be ahead of the leader's
+	lastActivity := report.LastActivity
+	if lastActivity.After(now) {
+		lastActivity = now
+	}
+	as.mu.Lock()
+	if lastActivity.After(as.lastActivity) {
+		as.lastActivity = lastActivity
+	}
+	as.mu.Unlock()
+	c.Status(http.StatusNoContent)
+}
+
+// leaderStatus reports the election in /proxy/status, nil without leader election
+func (as *AutoScaler) leaderStatus() gin.H {
+	if as.leader == nil {
+		return nil
+	}
+	return gin.H{
+		"leading": as.leading(),
+		"leader":  as.leader.leader(),
+	}
+}
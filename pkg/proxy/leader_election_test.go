@@ -0,0 +1,296 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLeaderElector_AcquireAndRelease(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	leader := newLeaderElector(clientset, "vllm", "vllm-proxy-leader", "http://10.0.0.1:8080", "s3cret")
+	assert.True(t, leader.follows())
+	assert.Equal(t, leaderScaleUpTimeout, leader.client.Timeout, "a leader that hangs doesn't hold the requests forever")
+
+	require.NoError(t, leader.start(context.Background()))
+	assert.Eventually(t, func() bool { return !leader.follows() }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "http://10.0.0.1:8080", leader.leader())
+
+	// The lease is released on exit so another replica takes over at once
+	leader.release()
+	assert.True(t, leader.follows())
+	lease, err := clientset.CoordinationV1().Leases("vllm").Get(context.Background(), "vllm-proxy-leader", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, *lease.Spec.HolderIdentity)
+
+	// Without leader election every replica manages the pods
+	var none *leaderElector
+	assert.False(t, none.follows())
+	none.release()
+}
+
+func TestScaleUpThroughLeader(t *testing.T) {
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer vllm.Close()
+
+	// The leader manages the pods, the follower asks it to scale up
+	leading := newReadyAutoScaler(t, vllm.URL, false)
+	leading.leader = &leaderElector{token: "s3cret"}
+	leading.leader.leading.Store(true)
+	leading.lastActivity = time.Now().Add(-time.Hour)
+	router := gin.New()
+	router.POST(leaderScaleUpPath, leading.leaderScaleUpHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	follower := newReadyAutoScaler(t, vllm.URL, false)
+	follower.leader = &leaderElector{client: server.Client(), leaderURL: server.URL, token: "s3cret"}
+	follower.lastActivity = time.Now().Add(-time.Hour)
+
+	require.NoError(t, follower.ensureScaledUp(context.Background()))
+	assert.WithinDuration(t, time.Now(), leading.lastActivity, time.Minute, "scale-ups count as activity on the leader")
+
+	// Only the leader releases idle models
+	follower.checkIdle(context.Background(), time.Now())
+	exists, err := follower.k8sManager.PodExists(context.Background())
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// Scale-ups without the replicas' token are refused
+	follower.leader.token = "wrong"
+	assert.ErrorContains(t, follower.ensureScaledUp(context.Background()), "missing or invalid leader token")
+	follower.leader.token = "s3cret"
+
+	// A replica that lost the lease refuses to scale up
+	leading.leader.leading.Store(false)
+	assert.ErrorContains(t, follower.ensureScaledUp(context.Background()), "not the leader")
+
+	// Without a leader, there is nobody to ask
+	follower.leader.leaderURL = ""
+	assert.ErrorContains(t, follower.ensureScaledUp(context.Background()), "no proxy leader")
+}
+
+func TestScaleUpThroughLeader_Timeout(t *testing.T) {
+	// The leader accepts the scale-up but never answers
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hung:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(hung)
+
+	follower := newReadyAutoScaler(t, "http://127.0.0.1:1", false)
+	follower.leader = &leaderElector{client: &http.Client{Timeout: 50 * time.Millisecond}, leaderURL: server.URL, token: "s3cret"}
+
+	var timeoutErr *LeaderTimeoutError
+	require.ErrorAs(t, follower.ensureScaledUp(context.Background()), &timeoutErr)
+	assert.Equal(t, server.URL, timeoutErr.Leader)
+
+	// Chat requests get the standard error too, not a loading message
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","messages":[]}`))
+	w := httptest.NewRecorder()
+	follower.proxyHandler(w, r)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), `"code":"scaling_up"`)
+}
+
+func TestCheckIdle_FollowerStreamHoldsModel(t *testing.T) {
+	// vLLM streams the follower's response until released
+	streaming := make(chan struct{})
+	vllm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"hi\"}}]}\n\n"))
+		w.(http.Flusher).Flush()
+		<-streaming
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer vllm.Close()
+
+	leading := newReadyAutoScaler(t, vllm.URL, false)
+	leading.config.IdleTimeout = "5m"
+	leading.leader = &leaderElector{token: "s3cret"}
+	leading.leader.leading.Store(true)
+	router := gin.New()
+	router.POST(leaderScaleUpPath, leading.leaderScaleUpHandler)
+	router.POST(leaderActivityPath, leading.leaderActivityHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	follower := newReadyAutoScaler(t, vllm.URL, false)
+	follower.leader = &leaderElector{client: server.Client(), identity: "http://10.0.0.2:8080", leaderURL: server.URL, token: "s3cret"}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","stream":true,"messages":[]}`))
+		follower.proxyHandler(httptest.NewRecorder(), r)
+	}()
+	require.Eventually(t, func() bool { return follower.replicas.inFlightCount() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	podExists := func() bool {
+		exists, err := leading.k8sManager.PodExists(context.Background())
+		require.NoError(t, err)
+		return exists
+	}
+	idleForAnHour := func() {
+		leading.mu.Lock()
+		leading.lastActivity = time.Now().Add(-time.Hour)
+		leading.mu.Unlock()
+	}
+
+	// The report counts as activity on the leader
+	idleForAnHour()
+	require.NoError(t, follower.reportActivity(context.Background()))
+	assert.WithinDuration(t, time.Now(), leading.lastActivity, time.Minute)
+
+	// The leader has nothing in flight, the follower's stream holds the model
+	idleForAnHour()
+	leading.checkIdle(context.Background(), time.Now())
+	assert.True(t, podExists(), "a stream on another replica defers the scale-down")
+
+	// A replica that stopped reporting doesn't hold it forever
+	assert.Zero(t, leading.leader.followersInFlight(time.Now().Add(activityReportTTL+time.Second)))
+
+	close(streaming)
+	<-done
+	require.NoError(t, follower.reportActivity(context.Background()))
+	idleForAnHour()
+	leading.checkIdle(context.Background(), time.Now())
+	assert.False(t, podExists(), "released once the follower's stream completes")
+
+	// Reports without the replicas' token are refused
+	follower.leader.token = "wrong"
+	assert.ErrorContains(t, follower.reportActivity(context.Background()), "missing or invalid leader token")
+}
+
+func TestLeaderScaleUpHandler_Unauthorized(t *testing.T) {
+	leading := newReadyAutoScaler(t, "http://127.0.0.1:1", false)
+	leading.leader = &leaderElector{token: "s3cret"}
+	leading.leader.leading.Store(true)
+	router := gin.New()
+	router.POST(leaderScaleUpPath, leading.leaderScaleUpHandler)
+
+	for name, header := range map[string]string{"missing": "", "wrong": "Bearer guess", "not bearer": "s3cret"} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, leaderScaleUpPath, strings.NewReader(`{"model":"deepseek"}`))
+			if header != "" {
+				r.Header.Set("Authorization", header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Body.String(), invalidLeaderToken)
+			assert.Equal(t, "qwen", leading.GetActiveModel())
+		})
+	}
+
+	// Without a token every scale-up is refused
+	leading.leader.token = ""
+	r := httptest.NewRequest(http.MethodPost, leaderScaleUpPath, strings.NewReader(`{}`))
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestScaleUpThroughLeader_SwitchPolicy(t *testing.T) {
+	leading := newReadyAutoScaler(t, "http://127.0.0.1:1", false)
+	leading.leader = &leaderElector{token: "s3cret"}
+	leading.leader.leading.Store(true)
+	router := gin.New()
+	router.POST(leaderScaleUpPath, leading.leaderScaleUpHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	follower := newReadyAutoScaler(t, "http://127.0.0.1:1", false)
+	follower.leader = &leaderElector{client: server.Client(), leaderURL: server.URL, token: "s3cret"}
+	follower.activeModel = "deepseek"
+	clientRequest := withSwitchRequest(context.Background(), false)
+
+	t.Run("policy", func(t *testing.T) {
+		leading.config.SwitchPolicy = SwitchPolicyManual
+		defer func() { leading.config.SwitchPolicy = "" }()
+
+		var switchErr *SwitchNotAllowedError
+		require.ErrorAs(t, follower.scaleUpThroughLeader(clientRequest), &switchErr)
+		assert.Equal(t, "deepseek", switchErr.RequestedModel)
+		assert.Equal(t, "the switch policy doesn't allow it", switchErr.Reason)
+		assert.Equal(t, "qwen", leading.GetActiveModel())
+
+		assert.Equal(t, "qwen", follower.GetActiveModel(), "the follower adopts the leader's model")
+
+		// The admin API switches whatever the policy, here to a model the leader doesn't know
+		follower.activeModel = "deepseek"
+		var notFound *ModelNotFoundError
+		assert.ErrorAs(t, follower.scaleUpThroughLeader(context.Background()), &notFound)
+	})
+
+	t.Run("pinned", func(t *testing.T) {
+		follower.activeModel = "deepseek"
+		var switchErr *SwitchNotAllowedError
+		require.ErrorAs(t, follower.scaleUpThroughLeader(withSwitchRequest(context.Background(), true)), &switchErr)
+		assert.Contains(t, switchErr.Reason, noSwitchHeader)
+	})
+
+	t.Run("tenant", func(t *testing.T) {
+		leading.apiKeys = auth.NewKeyStore()
+		require.NoError(t, leading.apiKeys.Load(map[string][]byte{"team-a": []byte(`{"key":"sk-a","models":["qwen"]}`)}))
+		defer func() { leading.apiKeys = nil }()
+
+		follower.activeModel = "deepseek"
+		ctx := auth.WithTenant(clientRequest, &auth.Tenant{Name: "team-a"})
+		var switchErr *SwitchNotAllowedError
+		require.ErrorAs(t, follower.scaleUpThroughLeader(ctx), &switchErr)
+		assert.Equal(t, "tenant team-a may not use it", switchErr.Reason)
+
+		// The model the tenant may use is started
+		follower.activeModel = "qwen"
+		assert.NoError(t, follower.scaleUpThroughLeader(ctx))
+	})
+}
+
+func TestScaleUpResponse_Errors(t *testing.T) {
+	startedAt := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	req := scaleUpRequest{Model: "qwen"}
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{name: "ready", err: nil, status: http.StatusOK},
+		{name: "gpu not ready", err: &GPUNotReadyError{Reason: "Insufficient nvidia.com/gpu"}, status: http.StatusServiceUnavailable},
+		{name: "downloading", err: &ModelDownloadingError{Model: "qwen", StartedAt: startedAt}, status: http.StatusServiceUnavailable},
+		{name: "not found", err: &ModelNotFoundError{RequestedModel: "qwen"}, status: http.StatusNotFound},
+		{name: "switch not allowed", err: &SwitchNotAllowedError{RequestedModel: "qwen", Reason: "the switch policy doesn't allow it"}, status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := newScaleUpResponse(tt.err)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.err, resp.err(req))
+		})
+	}
+
+	// Other errors keep their message
+	_, resp := newScaleUpResponse(assert.AnError)
+	assert.ErrorContains(t, resp.err(req), assert.AnError.Error())
+}
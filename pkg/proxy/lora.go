@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const loraRequestTimeout = 2 * time.Minute
+
+// loraClient calls the vLLM runtime adapter endpoints (requires VLLM_ALLOW_RUNTIME_LORA_UPDATING)
+type loraClient struct {
+	client *http.Client
+	apiKey string // vLLM API key, the adapter endpoints are under /v1
+}
+
+// newLoRAClient creates a client authenticating with vLLM's API key, if any, through the
+// transport (nil = http.DefaultTransport)
+func newLoRAClient(apiKey string, transport http.RoundTripper) *loraClient {
+	return &loraClient{
+		client: &http.Client{Timeout: loraRequestTimeout, Transport: transport},
+		apiKey: apiKey,
+	}
+}
+
+// load loads an adapter under the given name
+func (c *loraClient) load(ctx context.Context, target *url.URL, name, path string) error {
+	return c.post(ctx, target, "/v1/load_lora_adapter", map[string]string{"lora_name": name, "lora_path": path})
+}
+
+// unload removes an adapter, freeing its slot for the next one
+func (c *loraClient) unload(ctx context.Context, target *url.URL, name string) error {
+	return c.post(ctx, target, "/v1/unload_lora_adapter", map[string]string{"lora_name": name})
+}
+
+// post sends a JSON request to the given vLLM endpoint
+func (c *loraClient) post(ctx context.Context, target *url.URL, path string, payload map[string]string) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.JoinPath(path).String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vLLM %s request failed: %w", path, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("vLLM %s returned %d: %s", path, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// swapLoRAAdapter switches between two LoRA models sharing a base model by swapping adapters on
+// the running pods instead of restarting them. It returns false when the models can't be swapped,
+// no pod is running or the swap failed, leaving the full switch to the caller.
+func (as *AutoScaler) swapLoRAAdapter(ctx context.Context, current, requested string) bool {
+	currentConfig, err := as.crdClient.GetModel(ctx, current)
+	if err != nil {
+		return false
+	}
+	requestedConfig, err := as.crdClient.GetModel(ctx, requested)
+	if err != nil || !currentConfig.SharesBaseWith(requestedConfig) {
+		return false
+	}
+	if !as.isPodReady(ctx) {
+		return false
+	}
+
+	start := time.Now()
+	client := newLoRAClient(as.vllmAPIKey(ctx, current), as.vllmTransport)
+	for _, target := range as.replicas.targets(as.targetURL) {
+		// A missing adapter is not an error for the swap, the new one is loaded regardless
+		if err := client.unload(ctx, target, current); err != nil {
+			log.Printf("Failed to unload LoRA adapter %s from %s: %v", current, target.Host, err)
+		}
+		if err := client.load(ctx, target, requested, requestedConfig.LoRAAdapter); err != nil {
+			log.Printf("Failed to load LoRA adapter %s on %s, restarting the pod instead: %v", requested, target.Host, err)
+			return false
+		}
+	}
+
+	as.mu.Lock()
+	as.activeModel = requested
+	as.mu.Unlock()
+	as.metrics.RecordManagedOperation(current, requested, true, time.Since(start))
+	log.Printf("Swapped LoRA adapter %s for %s on base model %s in %v", current, requested, requestedConfig.ModelName, time.Since(start))
+	return true
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func loraModelSpec(served, adapter string) map[string]interface{} {
+	spec := replicaModelSpec(0, 1)
+	spec["servedModelName"] = served
+	spec["loraAdapter"] = adapter
+	return spec
+}
+
+// fakeLoRAServer records the adapter calls vLLM receives, failing loads when failLoad is set
+func fakeLoRAServer(t *testing.T, failLoad bool) (*httptest.Server, func() []string) {
+	var mu sync.Mutex
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer vllm-secret", r.Header.Get("Authorization"))
+		var payload map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+
+		mu.Lock()
+		calls = append(calls, r.URL.Path+" "+payload["lora_name"]+" "+payload["lora_path"])
+		mu.Unlock()
+		if failLoad && r.URL.Path == "/v1/load_lora_adapter" {
+			http.Error(w, "adapter not found", http.StatusNotFound)
+		}
+	}))
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), calls...)
+	}
+}
+
+func newLoRATestAutoScaler(t *testing.T, target string) *AutoScaler {
+	t.Helper()
+	pod := vllmPod()
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, pod)
+	_, err := clientset.CoreV1().Secrets("vllm").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-api-key", Namespace: "vllm"},
+		Data:       map[string][]byte{"api-key": []byte("vllm-secret")},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+
+	as.targetURL, err = url.Parse(target)
+	require.NoError(t, err)
+	as.activeModel = "qwen-sql"
+	as.crdClient = newFakeCRDClient(t,
+		loraModelSpec("qwen-sql", "acme/qwen-sql-lora"),
+		loraModelSpec("qwen-legal", "acme/qwen-legal-lora"),
+		replicaModelSpec(0, 1),
+	)
+	return as
+}
+
+func TestHandleModelSwitch_SwapsLoRAAdapter(t *testing.T) {
+	server, calls := fakeLoRAServer(t, false)
+	defer server.Close()
+	as := newLoRATestAutoScaler(t, server.URL)
+
+	require.NoError(t, as.handleModelSwitch(context.Background(), "qwen-legal"))
+
+	assert.Equal(t, "qwen-legal", as.GetActiveModel())
+	assert.Equal(t, []string{
+		"/v1/unload_lora_adapter qwen-sql ",
+		"/v1/load_lora_adapter qwen-legal acme/qwen-legal-lora",
+	}, calls())
+	exists, err := as.k8sManager.PodExists(context.Background())
+	require.NoError(t, err)
+	assert.True(t, exists, "the pod is kept")
+}
+
+func TestSwapLoRAAdapter_FallsBack(t *testing.T) {
+	server, calls := fakeLoRAServer(t, true)
+	defer server.Close()
+	as := newLoRATestAutoScaler(t, server.URL)
+	ctx := context.Background()
+
+	assert.False(t, as.swapLoRAAdapter(ctx, "qwen-sql", "qwen-legal"), "a failed load restarts the pod")
+	assert.Equal(t, "qwen-sql", as.GetActiveModel())
+	assert.Len(t, calls(), 2)
+
+	assert.False(t, as.swapLoRAAdapter(ctx, "qwen-sql", "qwen"), "models without an adapter are not swapped")
+	assert.Len(t, calls(), 2)
+}
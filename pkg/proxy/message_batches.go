@@ -0,0 +1,563 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+)
+
+// messageBatchesPath is the Anthropic Message Batches API, served by the proxy which replays each
+// request of a batch on /v1/messages
+const messageBatchesPath = "/v1/messages/batches"
+
+const (
+	maxBatchRequests      = 10000          // Requests per batch
+	batchExpiry           = 24 * time.Hour // Requests not started within it expire
+	batchRetention        = 24 * time.Hour // Ended batches and their results are kept this long
+	defaultBatchListLimit = 20
+	maxBatchListLimit     = 1000
+)
+
+// Processing statuses of a batch
+const (
+	batchInProgress = "in_progress"
+	batchCanceling  = "canceling"
+	batchEnded      = "ended"
+)
+
+// customIDPattern is the format of the custom_id of the requests of a batch
+var customIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Results of the requests not run
+var (
+	canceledResult = json.RawMessage(`{"type":"canceled"}`)
+	expiredResult  = json.RawMessage(`{"type":"expired"}`)
+)
+
+// batchRequest is a Messages request of a batch
+type batchRequest struct {
+	CustomID string          `json:"custom_id"`
+	Params   json.RawMessage `json:"params"`
+}
+
+// batchRequestCounts counts the requests of a batch by state
+type batchRequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// messageBatch is the Message Batch object of the API
+type messageBatch struct {
+	ID                string             `json:"id"`
+	Type              string             `json:"type"`
+	ProcessingStatus  string             `json:"processing_status"`
+	RequestCounts     batchRequestCounts `json:"request_counts"`
+	CreatedAt         time.Time          `json:"created_at"`
+	ExpiresAt         time.Time          `json:"expires_at"`
+	EndedAt           *time.Time         `json:"ended_at"`
+	CancelInitiatedAt *time.Time         `json:"cancel_initiated_at"`
+	ArchivedAt        *time.Time         `json:"archived_at"`
+	ResultsURL        *string            `json:"results_url"`
+}
+
+// batchJob is a batch with its requests and their results
+type batchJob struct {
+	batch    messageBatch
+	owner    string // Usage key of the client that created it, the only one that sees it
+	requests []batchRequest
+	results  []json.RawMessage // Result of each request, nil until it ends
+}
+
+// messageBatches holds the batches of the clients and bounds the requests running at once,
+// across batches
+type messageBatches struct {
+	mu    sync.Mutex
+	jobs  map[string]*batchJob
+	order []string // Batch IDs, oldest first
+	slots chan struct{}
+}
+
+func newMessageBatches(concurrency int) *messageBatches {
+	return &messageBatches{
+		jobs:  make(map[string]*batchJob),
+		slots: make(chan struct{}, max(concurrency, 1)),
+	}
+}
+
+// create adds a batch of the owner's requests, in progress
+func (b *messageBatches) create(owner string, requests []batchRequest, now time.Time) *batchJob {
+	id := make([]byte, 12)
+	_, _ = rand.Read(id)
+	job := &batchJob{
+		batch: messageBatch{
+			ID:               "msgbatch_" + hex.EncodeToString(id),
+			Type:             "message_batch",
+			ProcessingStatus: batchInProgress,
+			RequestCounts:    batchRequestCounts{Processing: len(requests)},
+			CreatedAt:        now,
+			ExpiresAt:        now.Add(batchExpiry),
+		},
+		owner:    owner,
+		requests: requests,
+		results:  make([]json.RawMessage, len(requests)),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	b.jobs[job.batch.ID] = job
+	b.order = append(b.order, job.batch.ID)
+	return job
+}
+
+// prune forgets the batches ended for longer than the retention, b.mu must be held
+func (b *messageBatches) prune(now time.Time) {
+	b.order = slices.DeleteFunc(b.order, func(id string) bool {
+		ended := b.jobs[id].batch.EndedAt
+		if ended == nil || now.Sub(*ended) < batchRetention {
+			return false
+		}
+		delete(b.jobs, id)
+		return true
+	})
+}
+
+// get returns the owner's batch, nil when it doesn't exist or belongs to another client
+func (b *messageBatches) get(owner, id string) *batchJob {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	job := b.jobs[id]
+	if job == nil || job.owner != owner {
+		return nil
+	}
+	return job
+}
+
+// snapshot returns the batch object of a job
+func (b *messageBatches) snapshot(job *batchJob) messageBatch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return job.batch
+}
+
+// skip ends the request without running it when its batch is canceling or expired, and reports
+// whether it did
+func (b *messageBatches) skip(job *batchJob, i int, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case job.batch.ProcessingStatus == batchCanceling:
+		job.results[i] = canceledResult
+		job.batch.RequestCounts.Canceled++
+	case now.After(job.batch.ExpiresAt):
+		job.results[i] = expiredResult
+		job.batch.RequestCounts.Expired++
+	default:
+		return false
+	}
+	job.batch.RequestCounts.Processing--
+	return true
+}
+
+// finish records the result of a request
+func (b *messageBatches) finish(job *batchJob, i int, result json.RawMessage, succeeded bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	job.results[i] = result
+	job.batch.RequestCounts.Processing--
+	if succeeded {
+		job.batch.RequestCounts.Succeeded++
+	} else {
+		job.batch.RequestCounts.Errored++
+	}
+}
+
+// end marks the batch ended once all its requests have a result
+func (b *messageBatches) end(job *batchJob, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	job.batch.ProcessingStatus = batchEnded
+	job.batch.EndedAt = &now
+}
+
+// cancel stops running the requests of the batch not started yet
+func (b *messageBatches) cancel(job *batchJob, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if job.batch.ProcessingStatus == batchInProgress {
+		job.batch.ProcessingStatus = batchCanceling
+		job.batch.CancelInitiatedAt = &now
+	}
+}
+
+// remove deletes an ended batch and reports whether it did
+func (b *messageBatches) remove(job *batchJob) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if job.batch.ProcessingStatus != batchEnded {
+		return false
+	}
+	delete(b.jobs, job.batch.ID)
+	b.order = slices.DeleteFunc(b.order, func(id string) bool { return id == job.batch.ID })
+	return true
+}
+
+// list returns the owner's batches, most recent first
+func (b *messageBatches) list(owner string, now time.Time) []messageBatch {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(now)
+	var batches []messageBatch
+	for i := len(b.order) - 1; i >= 0; i-- {
+		if job := b.jobs[b.order[i]]; job.owner == owner {
+			batches = append(batches, job.batch)
+		}
+	}
+	return batches
+}
+
+// validateBatchRequests checks the requests of a new batch: unique custom IDs and non-streamed
+// Messages requests naming their model
+func validateBatchRequests(requests []batchRequest) error {
+	if len(requests) == 0 || len(requests) > maxBatchRequests {
+		return fmt.Errorf("a batch holds between 1 and %d requests, got %d", maxBatchRequests, len(requests))
+	}
+	seen := make(map[string]bool, len(requests))
+	for i, req := range requests {
+		if !customIDPattern.MatchString(req.CustomID) {
+			return fmt.Errorf("requests.%d.custom_id: expected 1 to 64 letters, digits, hyphens or underscores", i)
+		}
+		if seen[req.CustomID] {
+			return fmt.Errorf("requests.%d.custom_id: %s is not unique in the batch", i, req.CustomID)
+		}
+		seen[req.CustomID] = true
+
+		var params struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return fmt.Errorf("requests.%d.params: %v", i, err)
+		}
+		if params.Model == "" {
+			return fmt.Errorf("requests.%d.params.model: field required", i)
+		}
+		if params.Stream {
+			return fmt.Errorf("requests.%d.params.stream: batch requests cannot be streamed", i)
+		}
+	}
+	return nil
+}
+
+// registerMessageBatches serves the Message Batches API, after the middlewares of /v1
+func (as *AutoScaler) registerMessageBatches(router gin.IRoutes) {
+	router.POST(messageBatchesPath, as.createMessageBatch)
+	router.GET(messageBatchesPath, as.listMessageBatches)
+	router.GET(messageBatchesPath+"/:id", as.getMessageBatch)
+	router.DELETE(messageBatchesPath+"/:id", as.deleteMessageBatch)
+	router.POST(messageBatchesPath+"/:id/cancel", as.cancelMessageBatch)
+	router.GET(messageBatchesPath+"/:id/results", as.messageBatchResults)
+}
+
+// createMessageBatch queues a batch of Messages requests, run in the background
+func (as *AutoScaler) createMessageBatch(c *gin.Context) {
+	r := c.Request
+	if limit := as.config.GetMaxRequestBodySize(); limit > 0 {
+		r.Body = http.MaxBytesReader(c.Writer, r.Body, limit)
+	}
+	var body struct {
+		Requests []batchRequest `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeRequestTooLarge(c.Writer, maxBytesErr.Limit)
+			return
+		}
+		writeAPIError(c.Writer, http.StatusBadRequest, "Invalid batch: "+err.Error(), "invalid_request_error", "invalid_batch")
+		return
+	}
+	if err := validateBatchRequests(body.Requests); err != nil {
+		writeAPIError(c.Writer, http.StatusBadRequest, "Invalid batch: "+err.Error(), "invalid_request_error", "invalid_batch")
+		return
+	}
+
+	job := as.batches.create(usageKey(r), body.Requests, time.Now())
+	log.Printf("Created message batch %s with %d requests", job.batch.ID, len(body.Requests))
+	// The requests outlive the request creating the batch, with its tenant and user
+	go as.runBatch(context.WithoutCancel(r.Context()), job)
+	as.writeMessageBatch(c, job)
+}
+
+// getMessageBatch returns a batch of the client
+func (as *AutoScaler) getMessageBatch(c *gin.Context) {
+	if job := as.messageBatch(c); job != nil {
+		as.writeMessageBatch(c, job)
+	}
+}
+
+// listMessageBatches returns the batches of the client, most recent first, paginated with
+// after_id and before_id
+func (as *AutoScaler) listMessageBatches(c *gin.Context) {
+	limit := defaultBatchListLimit
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > maxBatchListLimit {
+			writeAPIError(c.Writer, http.StatusBadRequest,
+				fmt.Sprintf("limit: expected a number between 1 and %d", maxBatchListLimit), "invalid_request_error", "invalid_limit")
+			return
+		}
+		limit = n
+	}
+
+	batches := as.batches.list(usageKey(c.Request), time.Now())
+	index := func(id string) int {
+		return slices.IndexFunc(batches, func(batch messageBatch) bool { return batch.ID == id })
+	}
+	start, end := 0, len(batches)
+	if afterID := c.Query("after_id"); afterID != "" {
+		start = index(afterID) + 1
+	}
+	if beforeID := c.Query("before_id"); beforeID != "" {
+		if i := index(beforeID); i >= 0 {
+			end = i
+		}
+	}
+	if start > end {
+		start = end
+	}
+
+	var page []messageBatch
+	var hasMore bool
+	if c.Query("before_id") != "" {
+		page, hasMore = batches[max(start, end-limit):end], end-limit > start
+	} else {
+		page, hasMore = batches[start:min(end, start+limit)], start+limit < end
+	}
+	response := gin.H{"data": []messageBatch{}, "has_more": hasMore, "first_id": nil, "last_id": nil}
+	if len(page) > 0 {
+		for i := range page {
+			as.setResultsURL(c.Request, &page[i])
+		}
+		response["data"], response["first_id"], response["last_id"] = page, page[0].ID, page[len(page)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// cancelMessageBatch stops running the requests of a batch not started yet, the running ones
+// complete
+func (as *AutoScaler) cancelMessageBatch(c *gin.Context) {
+	if job := as.messageBatch(c); job != nil {
+		as.batches.cancel(job, time.Now())
+		as.writeMessageBatch(c, job)
+	}
+}
+
+// deleteMessageBatch forgets an ended batch and its results
+func (as *AutoScaler) deleteMessageBatch(c *gin.Context) {
+	job := as.messageBatch(c)
+	if job == nil {
+		return
+	}
+	if !as.batches.remove(job) {
+		writeAPIError(c.Writer, http.StatusBadRequest,
+			fmt.Sprintf("Batch %s is still processing, cancel it before deleting it.", job.batch.ID), "invalid_request_error", "batch_in_progress")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": job.batch.ID, "type": "message_batch_deleted"})
+}
+
+// messageBatchResults streams the results of an ended batch as JSON lines, in request order
+func (as *AutoScaler) messageBatchResults(c *gin.Context) {
+	job := as.messageBatch(c)
+	if job == nil {
+		return
+	}
+	if as.batches.snapshot(job).ProcessingStatus != batchEnded {
+		writeAPIError(c.Writer, http.StatusBadRequest,
+			fmt.Sprintf("Batch %s is still processing, its results are available once it ended.", job.batch.ID), "invalid_request_error", "batch_in_progress")
+		return
+	}
+
+	c.Header("Content-Type", "application/x-jsonl")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+	for i, req := range job.requests {
+		line := struct {
+			CustomID string          `json:"custom_id"`
+			Result   json.RawMessage `json:"result"`
+		}{req.CustomID, job.results[i]}
+		if err := encoder.Encode(line); err != nil {
+			log.Printf("Failed to write the results of batch %s: %v", job.batch.ID, err)
+			return
+		}
+	}
+}
+
+// messageBatch returns the batch named in the path, answering 404 when the client has none by
+// that ID
+func (as *AutoScaler) messageBatch(c *gin.Context) *batchJob {
+	id := c.Param("id")
+	job := as.batches.get(usageKey(c.Request), id)
+	if job == nil {
+		writeAPIError(c.Writer, http.StatusNotFound, fmt.Sprintf("Batch %s not found.", id), "not_found_error", "batch_not_found")
+	}
+	return job
+}
+
+// writeMessageBatch answers with the batch object
+func (as *AutoScaler) writeMessageBatch(c *gin.Context, job *batchJob) {
+	batch := as.batches.snapshot(job)
+	as.setResultsURL(c.Request, &batch)
+	c.JSON(http.StatusOK, batch)
+}
+
+// setResultsURL sets the URL of the results of an ended batch, on the public endpoint when
+// configured and else on the host the client reached
+func (as *AutoScaler) setResultsURL(r *http.Request, batch *messageBatch) {
+	if batch.ProcessingStatus != batchEnded {
+		return
+	}
+	base := strings.TrimSuffix(as.config.PublicEndpoint, "/")
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	resultsURL := base + messageBatchesPath + "/" + batch.ID + "/results"
+	batch.ResultsURL = &resultsURL
+}
+
+// runBatch runs the requests of a batch in order, as many at once as the batch concurrency
+// allows across batches, then ends it
+func (as *AutoScaler) runBatch(ctx context.Context, job *batchJob) {
+	var wg sync.WaitGroup
+	for i := range job.requests {
+		if as.batches.skip(job, i, time.Now()) {
+			continue
+		}
+		as.batches.slots <- struct{}{}
+		// The batch may have been canceled while waiting for a slot
+		if as.batches.skip(job, i, time.Now()) {
+			<-as.batches.slots
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-as.batches.slots }()
+			result, succeeded := as.runBatchRequest(ctx, job.owner, job.requests[i])
+			as.batches.finish(job, i, result, succeeded)
+		}(i)
+	}
+	wg.Wait()
+
+	as.batches.end(job, time.Now())
+	counts := as.batches.snapshot(job).RequestCounts
+	log.Printf("Message batch %s ended: %d succeeded, %d errored, %d canceled, %d expired",
+		job.batch.ID, counts.Succeeded, counts.Errored, counts.Canceled, counts.Expired)
+}
+
+// runBatchRequest sends a request of a batch through the proxy, switching and starting its model
+// like any request, and returns its result. The tokens are accounted to the owner of the batch.
+func (as *AutoScaler) runBatchRequest(ctx context.Context, owner string, req batchRequest) (json.RawMessage, bool) {
+	account := &requestAccount{}
+	ctx = context.WithValue(ctx, requestAccountKey{}, account)
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, anthropicMessagesPath, bytes.NewReader(req.Params))
+	if err != nil {
+		return erroredBatchResult(http.StatusInternalServerError, []byte(err.Error())), false
+	}
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, r)
+
+	body := w.Body.Bytes()
+	if account.model != "" {
+		counter := usage.NewTokenCounter()
+		counter.Scan(body)
+		prompt, completion := counter.Split(int64(len(req.Params)), int64(len(body)))
+		counter.Release()
+		as.recordUsage(account, owner, userFrom(ctx), prompt, completion)
+	}
+
+	if w.Code != http.StatusOK || !json.Valid(body) {
+		return erroredBatchResult(w.Code, body), false
+	}
+	result, err := json.Marshal(struct {
+		Type    string          `json:"type"`
+		Message json.RawMessage `json:"message"`
+	}{"succeeded", body})
+	if err != nil {
+		return erroredBatchResult(http.StatusInternalServerError, []byte(err.Error())), false
+	}
+	return result, true
+}
+
+// erroredBatchResult returns the result of a failed request, keeping the error type and message of
+// Anthropic and OpenAI error responses
+func erroredBatchResult(status int, body []byte) json.RawMessage {
+	var response struct {
+		Error struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	errType, message := response.Error.Type, strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &response) == nil && response.Error.Message != "" {
+		errType, message = response.Error.Type, response.Error.Message
+	}
+	if errType == "" {
+		switch status {
+		case http.StatusBadRequest:
+			errType = "invalid_request_error"
+		case http.StatusNotFound:
+			errType = "not_found_error"
+		case http.StatusTooManyRequests:
+			errType = "rate_limit_error"
+		case http.StatusServiceUnavailable:
+			errType = "overloaded_error"
+		default:
+			errType = "api_error"
+		}
+	}
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	var result struct {
+		Type  string `json:"type"`
+		Error struct {
+			Type  string `json:"type"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"error"`
+	}
+	result.Type, result.Error.Type = "errored", "error"
+	result.Error.Error.Type, result.Error.Error.Message = errType, message
+	data, _ := json.Marshal(result)
+	return data
+}
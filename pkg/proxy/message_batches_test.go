@@ -0,0 +1,308 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBatchRouter serves the Message Batches API of as, the other requests going to the proxy handler
+func newBatchRouter(as *AutoScaler, concurrency int) *gin.Engine {
+	as.config.MessageBatches = true
+	as.batches = newMessageBatches(concurrency)
+	as.usage = usage.NewTracker()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.usageMiddleware)
+	as.registerMessageBatches(router)
+	router.NoRoute(as.ginProxyHandler)
+	return router
+}
+
+// sendBatchRequest sends a request to the router as the client holding key
+func sendBatchRequest(router http.Handler, method, path, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// batchResultLine is a line of the results of a batch
+type batchResultLine struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string `json:"type"`
+		Message struct {
+			ID string `json:"id"`
+		} `json:"message"`
+		Error struct {
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"error"`
+	} `json:"result"`
+}
+
+// waitBatchEnded polls the batch until it ended
+func waitBatchEnded(t *testing.T, router http.Handler, key, id string) messageBatch {
+	t.Helper()
+	var batch messageBatch
+	require.Eventually(t, func() bool {
+		w := sendBatchRequest(router, http.MethodGet, messageBatchesPath+"/"+id, key, "")
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+		return batch.ProcessingStatus == batchEnded
+	}, 5*time.Second, 10*time.Millisecond)
+	return batch
+}
+
+func TestMessageBatches(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if bytes.Contains(body, []byte("fail")) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: field required"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":12,"output_tokens":3}}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	router := newBatchRouter(as, 2)
+
+	w := sendBatchRequest(router, http.MethodPost, messageBatchesPath, "sk-a", `{"requests":[
+		{"custom_id":"first","params":{"model":"qwen","max_tokens":16,"messages":[{"role":"user","content":"Hello"}]}},
+		{"custom_id":"second","params":{"model":"qwen","messages":[{"role":"user","content":"fail"}]}},
+		{"custom_id":"third","params":{"model":"qwen","max_tokens":16,"messages":[{"role":"user","content":"Hello"}]}}
+	]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var created messageBatch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, strings.HasPrefix(created.ID, "msgbatch_"))
+	assert.Equal(t, "message_batch", created.Type)
+	assert.Nil(t, created.ResultsURL, "no results before the batch ended")
+
+	batch := waitBatchEnded(t, router, "sk-a", created.ID)
+	assert.Equal(t, batchRequestCounts{Succeeded: 2, Errored: 1}, batch.RequestCounts)
+	require.NotNil(t, batch.ResultsURL)
+	assert.Equal(t, "http://example.com"+messageBatchesPath+"/"+created.ID+"/results", *batch.ResultsURL)
+
+	// The results are in request order
+	w = sendBatchRequest(router, http.MethodGet, messageBatchesPath+"/"+created.ID+"/results", "sk-a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-jsonl", w.Header().Get("Content-Type"))
+	var results []batchResultLine
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var line batchResultLine
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		results = append(results, line)
+	}
+	require.Len(t, results, 3)
+	assert.Equal(t, "first", results[0].CustomID)
+	assert.Equal(t, "succeeded", results[0].Result.Type)
+	assert.Equal(t, "msg_1", results[0].Result.Message.ID)
+	assert.Equal(t, "second", results[1].CustomID)
+	assert.Equal(t, "errored", results[1].Result.Type)
+	assert.Equal(t, "invalid_request_error", results[1].Result.Error.Error.Type)
+	assert.Equal(t, "max_tokens: field required", results[1].Result.Error.Error.Message)
+	assert.Equal(t, "third", results[2].CustomID)
+
+	// The batched requests are billed to the client that created the batch
+	summary := as.usage.Summary()
+	assert.Equal(t, int64(3), summary.Keys[auth.Digest("sk-a")].Requests)
+	assert.Equal(t, int64(3), summary.Models["qwen"].Requests)
+
+	// Other clients don't see the batch
+	assert.Equal(t, http.StatusNotFound, sendBatchRequest(router, http.MethodGet, messageBatchesPath+"/"+created.ID, "sk-b", "").Code)
+	w = sendBatchRequest(router, http.MethodGet, messageBatchesPath, "sk-b", "")
+	assert.JSONEq(t, `{"data":[],"has_more":false,"first_id":null,"last_id":null}`, w.Body.String())
+
+	w = sendBatchRequest(router, http.MethodDelete, messageBatchesPath+"/"+created.ID, "sk-a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"id":%q,"type":"message_batch_deleted"}`, created.ID), w.Body.String())
+	assert.Equal(t, http.StatusNotFound, sendBatchRequest(router, http.MethodGet, messageBatchesPath+"/"+created.ID, "sk-a", "").Code)
+
+	// Single messages still go to the proxy
+	w = sendBatchRequest(router, http.MethodPost, anthropicMessagesPath, "sk-a", `{"model":"qwen","max_tokens":16,"messages":[]}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "msg_1")
+}
+
+func TestMessageBatches_Cancel(t *testing.T) {
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","content":[]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	router := newBatchRouter(as, 1)
+
+	w := sendBatchRequest(router, http.MethodPost, messageBatchesPath, "sk-a", `{"requests":[
+		{"custom_id":"a","params":{"model":"qwen","max_tokens":16,"messages":[]}},
+		{"custom_id":"b","params":{"model":"qwen","max_tokens":16,"messages":[]}},
+		{"custom_id":"c","params":{"model":"qwen","max_tokens":16,"messages":[]}}
+	]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var batch messageBatch
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	<-started
+
+	// Results are only available once the batch ended, and only ended batches are deleted
+	assert.Equal(t, http.StatusBadRequest, sendBatchRequest(router, http.MethodGet, messageBatchesPath+"/"+batch.ID+"/results", "sk-a", "").Code)
+	assert.Equal(t, http.StatusBadRequest, sendBatchRequest(router, http.MethodDelete, messageBatchesPath+"/"+batch.ID, "sk-a", "").Code)
+
+	w = sendBatchRequest(router, http.MethodPost, messageBatchesPath+"/"+batch.ID+"/cancel", "sk-a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &batch))
+	assert.Equal(t, batchCanceling, batch.ProcessingStatus)
+	assert.NotNil(t, batch.CancelInitiatedAt)
+
+	// The running request completes, the others are canceled
+	close(release)
+	batch = waitBatchEnded(t, router, "sk-a", batch.ID)
+	assert.Equal(t, batchRequestCounts{Succeeded: 1, Canceled: 2}, batch.RequestCounts)
+	w = sendBatchRequest(router, http.MethodGet, messageBatchesPath+"/"+batch.ID+"/results", "sk-a", "")
+	assert.Contains(t, w.Body.String(), `{"custom_id":"c","result":{"type":"canceled"}}`)
+}
+
+func TestListMessageBatches(t *testing.T) {
+	as := newReadyAutoScaler(t, "http://vllm-api", false)
+	as.config.PublicEndpoint = "https://vllm.example.com"
+	router := newBatchRouter(as, 1)
+
+	now := time.Now()
+	var ids []string
+	for i := 0; i < 5; i++ {
+		job := as.batches.create(auth.Digest("sk-a"), []batchRequest{{CustomID: "a"}}, now)
+		as.batches.end(job, now)
+		ids = append(ids, job.batch.ID)
+	}
+	// Batches ended for longer than the retention are forgotten
+	old := as.batches.create(auth.Digest("sk-a"), nil, now.Add(-2*batchRetention))
+	as.batches.end(old, now.Add(-2*batchRetention))
+
+	list := func(query string) (page struct {
+		Data    []messageBatch `json:"data"`
+		HasMore bool           `json:"has_more"`
+		FirstID string         `json:"first_id"`
+		LastID  string         `json:"last_id"`
+	}) {
+		w := sendBatchRequest(router, http.MethodGet, messageBatchesPath+query, "sk-a", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	page := list("?limit=2")
+	assert.True(t, page.HasMore)
+	assert.Equal(t, ids[4], page.FirstID, "most recent first")
+	assert.Equal(t, ids[3], page.LastID)
+	require.NotNil(t, page.Data[0].ResultsURL)
+	assert.Equal(t, "https://vllm.example.com"+messageBatchesPath+"/"+ids[4]+"/results", *page.Data[0].ResultsURL)
+
+	page = list("?limit=2&after_id=" + ids[3])
+	assert.Equal(t, []string{ids[2], ids[1]}, []string{page.FirstID, page.LastID})
+	assert.True(t, page.HasMore)
+
+	page = list("?limit=2&after_id=" + ids[1])
+	assert.Len(t, page.Data, 1)
+	assert.False(t, page.HasMore)
+
+	page = list("?limit=2&before_id=" + ids[1])
+	assert.Equal(t, []string{ids[3], ids[2]}, []string{page.FirstID, page.LastID})
+	assert.True(t, page.HasMore)
+
+	assert.Len(t, list("").Data, 5)
+	assert.Equal(t, http.StatusBadRequest, sendBatchRequest(router, http.MethodGet, messageBatchesPath+"?limit=0", "sk-a", "").Code)
+}
+
+func TestValidateBatchRequests(t *testing.T) {
+	params := json.RawMessage(`{"model":"qwen","max_tokens":16,"messages":[]}`)
+	tests := []struct {
+		name     string
+		requests []batchRequest
+		err      string
+	}{
+		{name: "valid", requests: []batchRequest{{CustomID: "req-1", Params: params}, {CustomID: "req_2", Params: params}}},
+		{name: "empty", requests: nil, err: "between 1 and 10000 requests"},
+		{name: "invalid custom id", requests: []batchRequest{{CustomID: "req 1", Params: params}}, err: "requests.0.custom_id"},
+		{name: "duplicate custom id", requests: []batchRequest{{CustomID: "req", Params: params}, {CustomID: "req", Params: params}}, err: "req is not unique"},
+		{name: "params not an object", requests: []batchRequest{{CustomID: "req", Params: json.RawMessage(`[]`)}}, err: "requests.0.params"},
+		{name: "no model", requests: []batchRequest{{CustomID: "req", Params: json.RawMessage(`{"messages":[]}`)}}, err: "requests.0.params.model"},
+		{name: "streamed", requests: []batchRequest{{CustomID: "req", Params: json.RawMessage(`{"model":"qwen","stream":true}`)}}, err: "cannot be streamed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBatchRequests(tt.requests)
+			if tt.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestErroredBatchResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		result string
+	}{
+		{
+			name:   "anthropic error",
+			status: http.StatusBadRequest,
+			body:   `{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: field required"}}`,
+			result: `{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"max_tokens: field required"}}}`,
+		},
+		{
+			name:   "openai error",
+			status: http.StatusNotFound,
+			body:   `{"error":{"message":"Model llama not found","type":"model_not_found","code":"model_not_found"}}`,
+			result: `{"type":"errored","error":{"type":"error","error":{"type":"model_not_found","message":"Model llama not found"}}}`,
+		},
+		{
+			name:   "plain text",
+			status: http.StatusServiceUnavailable,
+			body:   "upstream connect error\n",
+			result: `{"type":"errored","error":{"type":"error","error":{"type":"overloaded_error","message":"upstream connect error"}}}`,
+		},
+		{
+			name:   "empty",
+			status: http.StatusBadGateway,
+			result: `{"type":"errored","error":{"type":"error","error":{"type":"api_error","message":"Bad Gateway"}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.result, string(erroredBatchResult(tt.status, []byte(tt.body))))
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// resolveServedModel maps the model a client asked for (served name, Hugging Face name or
+// VLLMModel name) to the served model name vLLM knows. Unknown models are returned unchanged.
+func (as *AutoScaler) resolveServedModel(ctx context.Context, requested string) string {
+	if requested == "" || requested == as.GetActiveModel() {
+		return requested
+	}
+	modelConfig, err := as.crdClient.ResolveModel(ctx, requested)
+	if err != nil {
+		return requested
+	}
+	return modelConfig.ServedModelName
+}
+
+// rewriteRequestModel replaces the model field of the JSON request body, keeping the other fields as sent
+func rewriteRequestModel(r *http.Request, model string) error {
+	body, err := parsedBody(r)
+	if err != nil {
+		return err
+	}
+	fields, err := body.object()
+	if err != nil {
+		return err
+	}
+	fields["model"], _ = json.Marshal(model)
+	return body.rewrite(r)
+}
+
+// modelNameWriter rewrites the served model name back to the name the client sent, so
+// VLLMModel naming choices don't leak to clients. Writes ending in a partial match are held
+// back until the next write so a name split across writes is still rewritten.
+type modelNameWriter struct {
+	http.ResponseWriter
+	served   []byte
+	client   []byte
+	pending  []byte
+	disabled bool // Compressed responses are passed through untouched
+}
+
+// newModelNameWriter wraps w to replace the served model name with the client's name
+func newModelNameWriter(w http.ResponseWriter, served, client string) *modelNameWriter {
+	return &modelNameWriter{
+		ResponseWriter: w,
+		served:         modelField(served),
+		client:         modelField(client),
+	}
+}
+
+// modelField returns the compact JSON encoding of a model field, as emitted by vLLM
+func modelField(model string) []byte {
+	encoded, _ := json.Marshal(model)
+	return append([]byte(`"model":`), encoded...)
+}
+
+// WriteHeader drops Content-Length since the body size changes once rewritten
+func (mw *modelNameWriter) WriteHeader(code int) {
+	mw.disabled = mw.Header().Get("Content-Encoding") != ""
+	if !mw.disabled && len(mw.served) != len(mw.client) {
+		mw.Header().Del("Content-Length")
+	}
+	mw.ResponseWriter.WriteHeader(code)
+}
+
+// Write rewrites complete occurrences of the served model name and holds back a trailing partial match
+func (mw *modelNameWriter) Write(b []byte) (int, error) {
+	if mw.disabled {
+		return mw.ResponseWriter.Write(b)
+	}
+
+	data := bytes.ReplaceAll(append(mw.pending, b...), mw.served, mw.client)
+	mw.pending = nil
+	if keep := partialSuffix(data, mw.served); keep > 0 {
+		mw.pending = append([]byte(nil), data[len(data)-keep:]...)
+		data = data[:len(data)-keep]
+	}
+
+	if _, err := mw.ResponseWriter.Write(data); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher
+func (mw *modelNameWriter) Flush() {
+	if flusher, ok := mw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes any held back bytes
+func (mw *modelNameWriter) finish() error {
+	if len(mw.pending) == 0 {
+		return nil
+	}
+	_, err := mw.ResponseWriter.Write(mw.pending)
+	mw.pending = nil
+	return err
+}
+
+// partialSuffix returns the length of the longest suffix of data that is a proper prefix of pattern
+func partialSuffix(data, pattern []byte) int {
+	for n := min(len(pattern)-1, len(data)); n > 0; n-- {
+		if bytes.HasSuffix(data, pattern[:n]) {
+			return n
+		}
+	}
+	return 0
+}
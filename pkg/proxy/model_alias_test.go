@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveServedModel(t *testing.T) {
+	as := &AutoScaler{
+		activeModel: "qwen",
+		crdClient: newFakeCRDClient(t, map[string]interface{}{
+			"modelName":              "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8",
+			"servedModelName":        "qwen",
+			"maxModelLen":            int64(65536),
+			"maxNumBatchedTokens":    int64(8192),
+			"maxNumSeqs":             int64(16),
+			"gpuMemoryUtilization":   0.9,
+			"dtype":                  "auto",
+			"enableChunkedPrefill":   true,
+			"disableCustomAllReduce": false,
+			"enablePrefixCaching":    true,
+			"enableAutoToolChoice":   true,
+		}),
+	}
+
+	assert.Equal(t, "qwen", as.resolveServedModel(t.Context(), "qwen"))
+	assert.Equal(t, "qwen", as.resolveServedModel(t.Context(), "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8"))
+	assert.Equal(t, "unknown", as.resolveServedModel(t.Context(), "unknown"))
+	assert.Empty(t, as.resolveServedModel(t.Context(), ""))
+}
+
+func TestRewriteRequestModel(t *testing.T) {
+	body := `{"model":"Qwen/Qwen3-Coder","stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+
+	require.NoError(t, rewriteRequestModel(r, "qwen"))
+
+	rewritten, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"model":"qwen","stream":true,"messages":[{"role":"user","content":"hi"}]}`, string(rewritten))
+	assert.Equal(t, int64(len(rewritten)), r.ContentLength)
+
+	r = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`not json`))
+	assert.Error(t, rewriteRequestModel(r, "qwen"))
+}
+
+func TestModelNameWriter_RewritesResponses(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	mw := newModelNameWriter(recorder, "qwen", "Qwen/Qwen3-Coder")
+
+	mw.Header().Set("Content-Length", "42")
+	mw.WriteHeader(http.StatusOK)
+	_, err := mw.Write([]byte(`{"id":"chatcmpl-1","model":"qwen","choices":[]}`))
+	require.NoError(t, err)
+	require.NoError(t, mw.finish())
+
+	assert.Equal(t, `{"id":"chatcmpl-1","model":"Qwen/Qwen3-Coder","choices":[]}`, recorder.Body.String())
+	assert.Empty(t, recorder.Header().Get("Content-Length"))
+}
+
+func TestModelNameWriter_RewritesNameSplitAcrossWrites(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	mw := newModelNameWriter(recorder, "qwen", "Qwen/Qwen3-Coder")
+
+	for _, part := range []string{`data: {"id":"1","mod`, `el":"qw`, `en","choices":[]}` + "\n\n", "data: [DONE]\n\n"} {
+		_, err := mw.Write([]byte(part))
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.finish())
+
+	assert.Equal(t, `data: {"id":"1","model":"Qwen/Qwen3-Coder","choices":[]}`+"\n\ndata: [DONE]\n\n", recorder.Body.String())
+}
+
+func TestModelNameWriter_KeepsOtherModels(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	mw := newModelNameWriter(recorder, "qwen", "Qwen/Qwen3-Coder")
+
+	_, err := mw.Write([]byte(`{"model":"qwen-large"}`))
+	require.NoError(t, err)
+	require.NoError(t, mw.finish())
+
+	// Only the exact served name is rewritten
+	assert.Equal(t, `{"model":"qwen-large"}`, recorder.Body.String())
+}
+
+func TestModelNameWriter_SkipsCompressedResponses(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	mw := newModelNameWriter(recorder, "qwen", "Qwen/Qwen3-Coder")
+
+	mw.Header().Set("Content-Encoding", "gzip")
+	mw.WriteHeader(http.StatusOK)
+	_, err := mw.Write([]byte(`"model":"qwen"`))
+	require.NoError(t, err)
+
+	assert.Equal(t, `"model":"qwen"`, recorder.Body.String())
+}
@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+// startModelFinalizers periodically sets the cleanup finalizer on the VLLMModels and completes the
+// deletion of those being deleted
+func (as *AutoScaler) startModelFinalizers(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	as.syncModelFinalizers(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			as.syncModelFinalizers(ctx)
+		}
+	}
+}
+
+// syncModelFinalizers sets the cleanup finalizer on the VLLMModels. When the active model's VLLMModel
+// is deleted, its pods are removed before the finalizer is released, so they don't keep the GPUs
+// while no proxy can stop them anymore.
+func (as *AutoScaler) syncModelFinalizers(ctx context.Context) {
+	if !as.leading() {
+		return
+	}
+	models, err := as.crdClient.ListModels(ctx)
+	if err != nil {
+		log.Printf("Failed to list VLLMModels for finalizers: %v", err)
+		return
+	}
+
+	finalizer := kubernetes.CleanupFinalizer(as.config.Namespace)
+	for _, model := range models {
+		finalized := slices.Contains(model.Finalizers, finalizer)
+		if model.DeletionTimestamp == nil {
+			if !finalized {
+				if err := as.crdClient.AddFinalizer(ctx, model.Name, finalizer); err != nil {
+					log.Printf("Failed to set finalizer on VLLMModel %s: %v", model.Name, err)
+				}
+			}
+			continue
+		}
+		if !finalized {
+			continue
+		}
+
+		if model.Spec.ServedModelName == as.GetActiveModel() {
+			log.Printf("VLLMModel %s of the active model is being deleted, removing its pods", model.Name)
+			if err := as.managePod(ctx, false); err != nil {
+				log.Printf("Failed to remove the pods of VLLMModel %s: %v", model.Name, err)
+				continue
+			}
+		}
+		if err := as.crdClient.RemoveFinalizer(ctx, model.Name, finalizer); err != nil {
+			log.Printf("Failed to release finalizer of VLLMModel %s: %v", model.Name, err)
+		}
+	}
+}
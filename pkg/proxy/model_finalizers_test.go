@@ -0,0 +1,78 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestSyncModelFinalizers(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "vllm.sir-alfred.io", Version: "v1alpha1", Resource: "models"}
+	finalizer := kubernetes.CleanupFinalizer("vllm")
+	deleting := metav1.NewTime(time.Now())
+
+	model := func(name, served string, deletionTimestamp *metav1.Time) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "vllm.sir-alfred.io/v1alpha1",
+			"kind":       "VLLMModel",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec":       map[string]interface{}{"modelName": "org/" + name, "servedModelName": served},
+		}}
+		if deletionTimestamp != nil {
+			u.SetDeletionTimestamp(deletionTimestamp)
+			u.SetFinalizers([]string{finalizer})
+		}
+		return u
+	}
+
+	tests := []struct {
+		name        string
+		active      string
+		podsRemoved bool
+	}{
+		{name: "active model deleted", active: "qwen", podsRemoved: true},
+		{name: "inactive model deleted", active: "deepseek", podsRemoved: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{gvr: "VLLMModelList"})
+			ctx := context.Background()
+			for _, m := range []*unstructured.Unstructured{
+				model("qwen3-coder", "qwen", &deleting),
+				model("deepseek-r1", "deepseek", nil),
+			} {
+				_, err := client.Resource(gvr).Create(ctx, m, metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+
+			as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, vllmPod())
+			as.crdClient = kubernetes.NewCRDClient(client)
+			as.activeModel = tt.active
+
+			as.syncModelFinalizers(ctx)
+
+			models, err := as.crdClient.ListModels(ctx)
+			require.NoError(t, err)
+			finalizers := map[string][]string{}
+			for _, m := range models {
+				finalizers[m.Name] = m.Finalizers
+			}
+			assert.Empty(t, finalizers["qwen3-coder"], "the deleted model's finalizer is released")
+			assert.Equal(t, []string{finalizer}, finalizers["deepseek-r1"])
+
+			_, err = clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+			assert.Equal(t, tt.podsRemoved, err != nil, "pod removed")
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/parser"
+)
+
+// modelOptions are the proxy-side settings of a model, read from its VLLMModel
+type modelOptions struct {
+	toolParser        parser.ToolCallParser
+	maxOutputTokens   int           // Cap of max_tokens, 0 when uncapped
+	requestTimeout    time.Duration // Time vLLM gets to answer, 0 when unbounded
+	maxStreamDuration time.Duration // Time a streamed response may last, 0 when unbounded
+	guidedDecoding    bool          // Forced tool calls are decoded against the tool's input_schema
+	apiKeySecret      string        // Secret holding the API key of the model's pods, the proxy's when empty
+}
+
+// newModelOptions reads the proxy-side settings of a model config
+func newModelOptions(modelConfig *kubernetes.ModelConfig) *modelOptions {
+	options := &modelOptions{toolParser: parser.ForModel(modelConfig.FallbackToolParser, modelConfig.ToolCallParser)}
+	options.maxOutputTokens, options.requestTimeout = modelConfig.RequestLimits()
+	options.maxStreamDuration = modelConfig.StreamLimit()
+	options.guidedDecoding = modelConfig.GuidedDecoding == "true"
+	options.apiKeySecret = modelConfig.APIKeySecret
+	return options
+}
+
+// modelOptionsFor returns the proxy-side settings of a model, looking them up in the CRD on first
+// use. Unknown models get the defaults.
+func (as *AutoScaler) modelOptionsFor(ctx context.Context, model string) *modelOptions {
+	if cached, ok := as.modelOptions.Load(model); ok {
+		return cached.(*modelOptions)
+	}
+	modelConfig, err := as.crdClient.GetModel(ctx, model)
+	if err != nil {
+		return &modelOptions{toolParser: parser.ForModel("", "")}
+	}
+	options := newModelOptions(modelConfig)
+	as.modelOptions.Store(model, options)
+	return options
+}
+
+// maxTokensFields are the request fields bounding the tokens generated, per endpoint
+var maxTokensFields = map[string][]string{
+	"/v1/chat/completions": {"max_tokens", "max_completion_tokens"},
+	"/v1/completions":      {"max_tokens"},
+	anthropicMessagesPath:  {"max_tokens"},
+}
+
+// capMaxTokens lowers the max_tokens of a request to the model's cap. Chat completions without
+// max_tokens would otherwise generate up to the context length, so they get the cap; completions
+// default to 16 tokens in vLLM and Anthropic requires max_tokens. It reports whether the body was
+// rewritten; other requests are left as sent.
+func capMaxTokens(r *http.Request, limit int) (bool, error) {
+	fieldNames, ok := maxTokensFields[r.URL.Path]
+	if !ok || limit <= 0 {
+		return false, nil
+	}
+	body, err := parsedBody(r)
+	if err != nil {
+		return false, err
+	}
+	fields, err := body.object()
+	if err != nil {
+		return false, err
+	}
+	capped := []byte(strconv.Itoa(limit))
+	rewrite, present := false, false
+	for _, name := range fieldNames {
+		raw, ok := fields[name]
+		if !ok || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+		present = true
+		var value float64
+		if json.Unmarshal(raw, &value) == nil && value > float64(limit) {
+			fields[name] = capped
+			rewrite = true
+		}
+	}
+	if !present && r.URL.Path == "/v1/chat/completions" {
+		fields["max_tokens"] = capped
+		rewrite = true
+	}
+	if !rewrite {
+		return false, nil
+	}
+	return true, body.rewrite(r)
+}
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/efortin/vllm-chill/pkg/parser"
+)
+
+func TestCapMaxTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		limit    int
+		capped   bool
+		expected string
+	}{
+		{
+			name:     "above the cap",
+			path:     "/v1/chat/completions",
+			body:     `{"model":"qwen","max_tokens":100000,"messages":[]}`,
+			limit:    4096,
+			capped:   true,
+			expected: `"max_tokens":4096`,
+		},
+		{
+			name:     "max_completion_tokens",
+			path:     "/v1/chat/completions",
+			body:     `{"model":"qwen","max_completion_tokens":100000,"messages":[]}`,
+			limit:    4096,
+			capped:   true,
+			expected: `"max_completion_tokens":4096`,
+		},
+		{
+			name:     "missing on chat completions",
+			path:     "/v1/chat/completions",
+			body:     `{"model":"qwen","messages":[]}`,
+			limit:    4096,
+			capped:   true,
+			expected: `"max_tokens":4096`,
+		},
+		{
+			name:     "anthropic messages",
+			path:     anthropicMessagesPath,
+			body:     `{"model":"qwen","max_tokens":32000,"messages":[]}`,
+			limit:    8192,
+			capped:   true,
+			expected: `"max_tokens":8192`,
+		},
+		{
+			name:     "below the cap",
+			path:     anthropicMessagesPath,
+			body:     `{"model":"qwen","max_tokens":1024,"messages":[]}`,
+			limit:    8192,
+			expected: `"max_tokens":1024`,
+		},
+		{
+			name:     "missing on completions",
+			path:     "/v1/completions",
+			body:     `{"model":"qwen","prompt":"Hi"}`,
+			limit:    4096,
+			expected: `{"model":"qwen","prompt":"Hi"}`,
+		},
+		{
+			name:     "uncapped model",
+			path:     "/v1/chat/completions",
+			body:     `{"model":"qwen","max_tokens":100000,"messages":[]}`,
+			expected: `"max_tokens":100000`,
+		},
+		{
+			name:     "other endpoint",
+			path:     embeddingsPath,
+			body:     `{"model":"qwen","input":"Hi"}`,
+			limit:    4096,
+			expected: `{"model":"qwen","input":"Hi"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+
+			capped, err := capMaxTokens(r, tt.limit)
+			require.NoError(t, err)
+			assert.Equal(t, tt.capped, capped)
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), tt.expected)
+			if capped {
+				assert.Equal(t, int64(len(body)), r.ContentLength)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_EnforcesModelLimits(t *testing.T) {
+	forwarded := make(chan string, 2)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwarded <- string(body)
+		if strings.Contains(string(body), `"slow":true`) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","content":[]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.modelOptions.Store("qwen", &modelOptions{
+		toolParser:      parser.ForModel("", ""),
+		maxOutputTokens: 8192,
+		requestTimeout:  100 * time.Millisecond,
+	})
+
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodPost, anthropicMessagesPath,
+		strings.NewReader(`{"model":"qwen","max_tokens":32000,"messages":[]}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, <-forwarded, `"max_tokens":8192`)
+
+	w = httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"qwen","slow":true,"messages":[]}`)))
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"request_timeout"`)
+	assert.Contains(t, <-forwarded, `"max_tokens":8192`)
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/efortin/vllm-chill/pkg/apis/vllm/v1alpha1"
+	"github.com/efortin/vllm-chill/pkg/auth"
+)
+
+// modelsPath lists the models, modelsPath/{id} retrieves one
+const modelsPath = "/v1/models"
+
+// modelObject is an OpenAI model object, extended with the model's state in the autoscaler
+type modelObject struct {
+	ID          string `json:"id"`
+	Object      string `json:"object"`
+	Created     int64  `json:"created"`
+	OwnedBy     string `json:"owned_by"`
+	Root        string `json:"root,omitempty"`
+	MaxModelLen int    `json:"max_model_len,omitempty"`
+	Active      bool   `json:"active"` // The model requests without a model field go to
+	Loaded      bool   `json:"loaded"` // Active and its pod is ready, requests are served without a cold start
+	name        string // VLLMModel name
+}
+
+// isModelsRequest reports whether the request lists or retrieves models
+func isModelsRequest(r *http.Request) bool {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	return r.Method == http.MethodGet && (path == modelsPath || strings.HasPrefix(path, modelsPath+"/"))
+}
+
+// serveModels answers GET /v1/models and /v1/models/{id} from the VLLMModel CRDs, so clients can
+// enumerate models without waking vLLM. Models a tenant may not use are not listed.
+func (as *AutoScaler) serveModels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	models, err := as.modelObjects(ctx, auth.TenantFrom(ctx))
+	if err != nil {
+		log.Printf("Failed to list models: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "Failed to retrieve available models", "internal_error", "model_list_error")
+		return
+	}
+
+	var response interface{} = map[string]interface{}{"object": "list", "data": models}
+	if id := strings.TrimPrefix(strings.TrimSuffix(r.URL.Path, "/"), modelsPath+"/"); id != modelsPath {
+		model := findModelObject(models, id)
+		if model == nil {
+			writeAPIError(w, http.StatusNotFound, fmt.Sprintf("The model '%s' does not exist", id), "invalid_request_error", "model_not_found")
+			return
+		}
+		response = model
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// modelObjects returns the models the tenant may use, by served name
+func (as *AutoScaler) modelObjects(ctx context.Context, tenant *auth.Tenant) ([]modelObject, error) {
+	models, err := as.crdClient.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := as.GetActiveModel()
+	objects := make([]modelObject, 0, len(models))
+	for _, model := range models {
+		if tenant != nil && !tenant.AllowsModel(model.Name, model.Spec.ServedModelName, model.Spec.ModelName) {
+			continue
+		}
+		object := newModelObject(model)
+		if object.Active = active == model.Name || active == model.Spec.ServedModelName; object.Active {
+			object.Loaded = as.isPodReady(ctx)
+		}
+		objects = append(objects, object)
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].ID < objects[j].ID
+	})
+	return objects, nil
+}
+
+// newModelObject describes a VLLMModel under the name vLLM serves it as
+func newModelObject(model *v1alpha1.VLLMModel) modelObject {
+	id := model.Spec.ServedModelName
+	if id == "" {
+		id = model.Name
+	}
+	return modelObject{
+		ID:          id,
+		Object:      "model",
+		Created:     model.CreationTimestamp.Unix(),
+		OwnedBy:     "vllm-chill",
+		Root:        model.Spec.ModelName,
+		MaxModelLen: model.Spec.MaxModelLen,
+		name:        model.Name,
+	}
+}
+
+// findModelObject finds a model by any name clients may use: served, Hugging Face or VLLMModel name
+func findModelObject(models []modelObject, id string) *modelObject {
+	for i := range models {
+		if models[i].ID == id || models[i].Root == id || models[i].name == id {
+			return &models[i]
+		}
+	}
+	return nil
+}
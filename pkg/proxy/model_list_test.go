@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newModelListTestAutoScaler(t *testing.T) *AutoScaler {
+	t.Helper()
+	pod := vllmPod()
+	pod.Status = corev1.PodStatus{
+		Phase:      corev1.PodRunning,
+		Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+	}
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyDelete, pod)
+	as.activeModel = "qwen"
+
+	llama := replicaModelSpec(1, 1)
+	llama["modelName"] = "meta-llama/Llama-3.1-8B-Instruct"
+	llama["servedModelName"] = "llama"
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(1, 1), llama)
+	return as
+}
+
+func TestServeModels(t *testing.T) {
+	as := newModelListTestAutoScaler(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	require.True(t, isModelsRequest(r))
+	w := httptest.NewRecorder()
+	as.serveModels(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var list struct {
+		Object string        `json:"object"`
+		Data   []modelObject `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Equal(t, "list", list.Object)
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "llama", list.Data[0].ID)
+	assert.False(t, list.Data[0].Active)
+	assert.Equal(t, "qwen", list.Data[1].ID)
+	assert.Equal(t, "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8", list.Data[1].Root)
+	assert.Equal(t, 65536, list.Data[1].MaxModelLen)
+	assert.True(t, list.Data[1].Active)
+	assert.True(t, list.Data[1].Loaded, "the active model's pod is ready")
+}
+
+func TestServeModels_Retrieve(t *testing.T) {
+	as := newModelListTestAutoScaler(t)
+
+	w := httptest.NewRecorder()
+	as.serveModels(w, httptest.NewRequest(http.MethodGet, "/v1/models/meta-llama/Llama-3.1-8B-Instruct", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var model modelObject
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &model))
+	assert.Equal(t, "llama", model.ID, "models are found under their Hugging Face name too")
+
+	w = httptest.NewRecorder()
+	as.serveModels(w, httptest.NewRequest(http.MethodGet, "/v1/models/mistral", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"model_not_found"`)
+}
+
+func TestServeModels_TenantModels(t *testing.T) {
+	as := newModelListTestAutoScaler(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	r = r.WithContext(auth.WithTenant(r.Context(), &auth.Tenant{Name: "team-a", Models: []string{"llama"}}))
+	w := httptest.NewRecorder()
+	as.serveModels(w, r)
+
+	assert.Contains(t, w.Body.String(), `"id":"llama"`)
+	assert.NotContains(t, w.Body.String(), `"id":"qwen"`, "models the tenant may not use are not listed")
+}
+
+func TestIsModelsRequest(t *testing.T) {
+	assert.True(t, isModelsRequest(httptest.NewRequest(http.MethodGet, "/v1/models/", nil)))
+	assert.False(t, isModelsRequest(httptest.NewRequest(http.MethodPost, "/v1/models", nil)))
+	assert.False(t, isModelsRequest(httptest.NewRequest(http.MethodGet, "/v1/modelsx", nil)))
+}
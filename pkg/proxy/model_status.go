@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/apis/vllm/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// modelStatusWriter writes the state of the active model to the status of its VLLMModel, on each
+// vLLM state change and every 30s for the last activity
+type modelStatusWriter struct {
+	updates  chan struct{} // Signals a state change, buffered so state changes never wait for the API
+	mu       sync.Mutex
+	failure  *modelState // Last failed start, cleared once vLLM runs
+	reported modelState  // Last state written, unchanged states aren't written again
+}
+
+// modelState is the state of a model as reported in its VLLMModel status
+type modelState struct {
+	model        string
+	phase        string
+	reason       string
+	message      string
+	podName      string
+	lastActivity time.Time
+}
+
+func newModelStatusWriter() *modelStatusWriter {
+	return &modelStatusWriter{updates: make(chan struct{}, 1)}
+}
+
+// notify schedules a status update, nil-safe
+func (w *modelStatusWriter) notify() {
+	if w == nil {
+		return
+	}
+	select {
+	case w.updates <- struct{}{}:
+	default: // An update is already scheduled
+	}
+}
+
+// failStart records why the model failed to start, reported until it runs, nil-safe
+func (w *modelStatusWriter) failStart(reason, message string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failure = &modelState{phase: v1alpha1.PhaseFailed, reason: reason, message: message}
+}
+
+// started forgets the last failed start, nil-safe
+func (w *modelStatusWriter) started() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.failure = nil
+}
+
+// startModelStatus writes the VLLMModel status of the active model until ctx is done
+func (as *AutoScaler) startModelStatus(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	as.writeModelStatus(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-as.modelStatus.updates:
+			as.writeModelStatus(ctx)
+		case <-ticker.C:
+			as.writeModelStatus(ctx)
+		}
+	}
+}
+
+// writeModelStatus writes the state of the active model to its VLLMModel status if it changed. The
+// model switched away from is reported idle.
+func (as *AutoScaler) writeModelStatus(ctx context.Context) {
+	if !as.leading() {
+		return
+	}
+	w := as.modelStatus
+	state := as.modelState()
+	if state == w.reported {
+		return
+	}
+
+	if previous := w.reported.model; previous != "" && previous != state.model {
+		switched := modelState{
+			phase:   v1alpha1.PhaseIdle,
+			reason:  "Switched",
+			message: fmt.Sprintf("Released for model %s", state.model),
+		}
+		if err := as.crdClient.UpdateModelStatus(ctx, previous, switched.apply); err != nil {
+			log.Printf("Failed to update the status of model %s: %v", previous, err)
+		}
+	}
+
+	if err := as.crdClient.UpdateModelStatus(ctx, state.model, state.apply); err != nil {
+		log.Printf("Failed to update the status of model %s: %v", state.model, err)
+		return
+	}
+	w.reported = state
+}
+
+// modelState returns the state of the active model from the vLLM state
+func (as *AutoScaler) modelState() modelState {
+	as.mu.RLock()
+	state := modelState{model: as.activeModel, lastActivity: as.lastActivity.Truncate(time.Second)}
+	as.mu.RUnlock()
+
+	pod := as.config.Deployment
+	switch as.vllmState.Load() {
+	case 2: // running
+		state.phase, state.reason, state.message, state.podName = v1alpha1.PhaseReady, "Running", "Serving requests", pod
+	case 1: // starting
+		state.phase, state.reason, state.message, state.podName = v1alpha1.PhaseLoading, "Starting", "vLLM is starting", pod
+	case vllmStateDownloading:
+		state.phase, state.reason, state.message = v1alpha1.PhaseLoading, "Downloading", "Downloading the model into the cache"
+	case 4: // gpu_driver_not_ready
+		state.phase, state.reason, state.message, state.podName = v1alpha1.PhaseLoading, "GPUDriverNotReady", "Waiting for the GPU device plugin", pod
+	case 3: // stopping
+		state.phase, state.reason, state.message, state.podName = v1alpha1.PhaseIdle, "Stopping", "Releasing the model", pod
+	default:
+		state.phase, state.reason, state.message = v1alpha1.PhaseIdle, "ScaledDown", "Released, the next request starts it"
+		as.modelStatus.mu.Lock()
+		if failure := as.modelStatus.failure; failure != nil {
+			state.phase, state.reason, state.message = failure.phase, failure.reason, failure.message
+		}
+		as.modelStatus.mu.Unlock()
+	}
+	return state
+}
+
+// apply sets the state in a VLLMModel status, the conditions keep their transition time while
+// their status doesn't change. The last activity is kept when unknown.
+func (s modelState) apply(status *v1alpha1.VLLMModelStatus) {
+	status.Phase = s.phase
+	status.Message = s.message
+	status.CurrentPodName = s.podName
+	if !s.lastActivity.IsZero() {
+		status.LastActivity = &metav1.Time{Time: s.lastActivity}
+	}
+
+	for _, c := range []struct{ conditionType, phase string }{
+		{v1alpha1.ConditionLoaded, v1alpha1.PhaseReady},
+		{v1alpha1.ConditionLoading, v1alpha1.PhaseLoading},
+		{v1alpha1.ConditionFailed, v1alpha1.PhaseFailed},
+	} {
+		condition := metav1.Condition{Type: c.conditionType, Status: metav1.ConditionFalse, Reason: s.reason, Message: s.message}
+		if s.phase == c.phase {
+			condition.Status = metav1.ConditionTrue
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/apis/vllm/v1alpha1"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// modelStatus returns the VLLMModel status of the served model name
+func modelStatus(t *testing.T, as *AutoScaler, servedModelName string) v1alpha1.VLLMModelStatus {
+	t.Helper()
+	models, err := as.crdClient.ListModels(context.Background())
+	require.NoError(t, err)
+	for _, model := range models {
+		if model.Spec.ServedModelName == servedModelName {
+			return model.Status
+		}
+	}
+	t.Fatalf("model %s not found", servedModelName)
+	return v1alpha1.VLLMModelStatus{}
+}
+
+func TestWriteModelStatus(t *testing.T) {
+	devstral := replicaModelSpec(0, 1)
+	devstral["servedModelName"] = "devstral"
+	as := &AutoScaler{
+		config:       &Config{Namespace: "vllm", Deployment: "vllm"},
+		crdClient:    newFakeCRDClient(t, replicaModelSpec(0, 1), devstral),
+		activeModel:  "qwen",
+		lastActivity: time.Now(),
+		metrics:      stats.NewMetricsRecorder(),
+		modelStatus:  newModelStatusWriter(),
+	}
+	ctx := context.Background()
+
+	as.setVLLMState(2) // running
+	as.writeModelStatus(ctx)
+	status := modelStatus(t, as, "qwen")
+	assert.Equal(t, v1alpha1.PhaseReady, status.Phase)
+	assert.Equal(t, "vllm", status.CurrentPodName)
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, v1alpha1.ConditionLoaded))
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, v1alpha1.ConditionLoading))
+	require.NotNil(t, status.LastActivity)
+	loadedAt := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionLoaded).LastTransitionTime
+
+	// A start timing out is reported until the model runs again
+	as.modelStatus.failStart("StartTimeout", "vLLM wasn't ready after 2m0s")
+	as.setVLLMState(0) // stopped
+	as.writeModelStatus(ctx)
+	status = modelStatus(t, as, "qwen")
+	assert.Equal(t, v1alpha1.PhaseFailed, status.Phase)
+	assert.Empty(t, status.CurrentPodName)
+	failed := meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionFailed)
+	require.NotNil(t, failed)
+	assert.Equal(t, metav1.ConditionTrue, failed.Status)
+	assert.Equal(t, "StartTimeout", failed.Reason)
+
+	as.setVLLMState(2) // running
+	as.writeModelStatus(ctx)
+	status = modelStatus(t, as, "qwen")
+	assert.Equal(t, v1alpha1.PhaseReady, status.Phase)
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, v1alpha1.ConditionFailed))
+	assert.False(t, meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionLoaded).LastTransitionTime.Before(&loadedAt))
+
+	// The model switched away from is released
+	as.mu.Lock()
+	as.activeModel = "devstral"
+	as.mu.Unlock()
+	as.setVLLMState(1) // starting
+	as.writeModelStatus(ctx)
+	assert.Equal(t, v1alpha1.PhaseLoading, modelStatus(t, as, "devstral").Phase)
+	status = modelStatus(t, as, "qwen")
+	assert.Equal(t, v1alpha1.PhaseIdle, status.Phase)
+	assert.Equal(t, "Switched", meta.FindStatusCondition(status.Conditions, v1alpha1.ConditionLoaded).Reason)
+	assert.NotNil(t, status.LastActivity, "the last activity of the released model is kept")
+}
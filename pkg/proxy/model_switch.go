@@ -0,0 +1,156 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+// Model switch states, reported by vllm_chill_model_switch_state
+const (
+	switchIdle      = 0
+	switchDraining  = 1 // Waiting for the in-flight requests of the current model
+	switchSwitching = 2 // Releasing the current model and activating the new one
+)
+
+// switchDrainPollInterval is how often a draining switch checks the in-flight requests
+const switchDrainPollInterval = 250 * time.Millisecond
+
+// ModelSwitchInProgressError is returned for a request for another model than the one a switch
+// in progress activates
+type ModelSwitchInProgressError struct {
+	RequestedModel string
+	TargetModel    string
+}
+
+func (e *ModelSwitchInProgressError) Error() string {
+	return fmt.Sprintf("model '%s' can't be activated while switching to '%s'", e.RequestedModel, e.TargetModel)
+}
+
+// modelSwitch is a switch in progress, done is closed once err is set
+type modelSwitch struct {
+	target string
+	state  int
+	done   chan struct{}
+	err    error
+}
+
+// modelSwitches serializes the model switches. Requests for the model being switched to wait for
+// the switch in progress instead of starting another, those for any other model are refused until
+// it completes. The zero value has no switch in progress.
+type modelSwitches struct {
+	mu      sync.Mutex
+	current *modelSwitch // Switch in progress, nil when idle
+}
+
+// run performs the switch to the target model, or waits for the one in progress when it
+// activates the same model
+func (s *modelSwitches) run(ctx context.Context, target string, metrics *stats.MetricsRecorder, switchModel func() error) error {
+	s.mu.Lock()
+	if current := s.current; current != nil {
+		s.mu.Unlock()
+		if current.target != target {
+			metrics.RecordModelSwitchRequest("rejected")
+			return &ModelSwitchInProgressError{RequestedModel: target, TargetModel: current.target}
+		}
+		metrics.RecordModelSwitchRequest("queued")
+		select {
+		case <-current.done:
+			return current.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	current := &modelSwitch{target: target, state: switchSwitching, done: make(chan struct{})}
+	s.current = current
+	s.mu.Unlock()
+	metrics.SetModelSwitchState(switchSwitching)
+
+	err := switchModel()
+
+	s.mu.Lock()
+	current.err = err
+	s.current = nil
+	s.mu.Unlock()
+	close(current.done)
+	metrics.SetModelSwitchState(switchIdle)
+	return err
+}
+
+// conflicts returns the error refusing a request for the model while another switch is in progress
+func (s *modelSwitches) conflicts(model string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil && s.current.target != model {
+		return &ModelSwitchInProgressError{RequestedModel: model, TargetModel: s.current.target}
+	}
+	return nil
+}
+
+// setState records the stage of the switch in progress
+func (s *modelSwitches) setState(state int, metrics *stats.MetricsRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current != nil {
+		s.current.state = state
+		metrics.SetModelSwitchState(state)
+	}
+}
+
+// snapshot returns the switch in progress for /proxy/status, nil when idle
+func (s *modelSwitches) snapshot() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil {
+		return nil
+	}
+	state := "switching"
+	if s.current.state == switchDraining {
+		state = "draining"
+	}
+	return map[string]string{"target": s.current.target, "state": state}
+}
+
+// drainInFlight waits for the requests proxied to the current model, SSE streams included, to
+// complete before its pod is released, for at most the switch drain timeout. Requests still in
+// flight when it expires are cut with the pod.
+func (as *AutoScaler) drainInFlight(ctx context.Context) {
+	timeout := as.config.GetSwitchDrainTimeout()
+	if timeout <= 0 || as.replicas.inFlightCount() == 0 {
+		return
+	}
+
+	as.switches.setState(switchDraining, as.metrics)
+	defer as.switches.setState(switchSwitching, as.metrics)
+	start := time.Now()
+	log.Printf("Waiting up to %s for %d in-flight requests to complete before switching models", timeout, as.replicas.inFlightCount())
+
+	// The switch goes on for the queued requests even if the request that started it gives up
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	ticker := time.NewTicker(switchDrainPollInterval)
+	defer ticker.Stop()
+	defer func() { as.metrics.RecordModelSwitchDrain(time.Since(start)) }()
+	for as.replicas.inFlightCount() > 0 {
+		select {
+		case <-ctx.Done():
+			log.Printf("Switch drain timeout expired, cutting %d in-flight requests", as.replicas.inFlightCount())
+			return
+		case <-ticker.C:
+		}
+	}
+	log.Printf("In-flight requests completed after %v", time.Since(start).Round(time.Millisecond))
+}
+
+// writeSwitchInProgress answers a request for another model than the one being switched to
+func writeSwitchInProgress(w http.ResponseWriter, err *ModelSwitchInProgressError) {
+	w.Header().Set("Retry-After", "10")
+	writeAPIError(w, http.StatusConflict,
+		fmt.Sprintf("The proxy is switching to model '%s', model '%s' can't be served until the switch completes. Please retry in a few moments.", err.TargetModel, err.RequestedModel),
+		"invalid_request_error", "model_switch_in_progress")
+}
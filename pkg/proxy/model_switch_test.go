@@ -2,13 +2,18 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/efortin/vllm-chill/pkg/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExtractModelFromRequest(t *testing.T) {
@@ -159,3 +164,111 @@ func TestGetActiveModel(t *testing.T) {
 	model := as.GetActiveModel()
 	assert.Equal(t, "test-model", model)
 }
+
+func TestModelSwitches_Run(t *testing.T) {
+	var switches modelSwitches
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	failed := errors.New("pod deletion failed")
+
+	owner := make(chan error, 1)
+	go func() {
+		owner <- switches.run(context.Background(), "llama", nil, func() error {
+			close(started)
+			<-unblock
+			return failed
+		})
+	}()
+	<-started
+	assert.Equal(t, map[string]string{"target": "llama", "state": "switching"}, switches.snapshot())
+
+	// A request for the same model waits for the switch in progress and gets its outcome
+	queued := make(chan error, 1)
+	go func() {
+		queued <- switches.run(context.Background(), "llama", nil, func() error {
+			t.Error("a second switch to the same model was started")
+			return nil
+		})
+	}()
+
+	// A request for another model is refused
+	var inProgress *ModelSwitchInProgressError
+	require.ErrorAs(t, switches.run(context.Background(), "mistral", nil, func() error { return nil }), &inProgress)
+	assert.Equal(t, "llama", inProgress.TargetModel)
+	require.ErrorAs(t, switches.conflicts("qwen"), &inProgress)
+	assert.NoError(t, switches.conflicts("llama"))
+
+	select {
+	case <-queued:
+		t.Fatal("the queued request did not wait for the switch")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(unblock)
+	assert.ErrorIs(t, <-owner, failed)
+	assert.ErrorIs(t, <-queued, failed)
+	assert.Nil(t, switches.snapshot())
+	assert.NoError(t, switches.conflicts("mistral"))
+}
+
+func TestDrainInFlight(t *testing.T) {
+	as := &AutoScaler{config: &Config{SwitchDrainTimeout: "5s"}}
+	_, release := as.replicas.acquire("")
+
+	drained := make(chan struct{})
+	go func() {
+		as.drainInFlight(context.Background())
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		t.Fatal("the switch did not wait for the in-flight request")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	release()
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the switch kept waiting once the request completed")
+	}
+}
+
+func TestDrainInFlight_Timeout(t *testing.T) {
+	as := &AutoScaler{config: &Config{SwitchDrainTimeout: "300ms"}}
+	_, release := as.replicas.acquire("")
+	defer release()
+
+	start := time.Now()
+	as.drainInFlight(context.Background())
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	as.config.SwitchDrainTimeout = "0"
+	start = time.Now()
+	as.drainInFlight(context.Background())
+	assert.Less(t, time.Since(start), 100*time.Millisecond, "a zero timeout switches at once")
+}
+
+func TestProxyHandler_SwitchInProgress(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a request for another model reached vLLM during the switch")
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.switches.current = &modelSwitch{target: "llama", state: switchDraining, done: make(chan struct{})}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"qwen","messages":[{"role":"user","content":"Hi"}]}`))
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, r)
+
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	var response struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "model_switch_in_progress", response.Error.Code)
+}
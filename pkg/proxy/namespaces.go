@@ -0,0 +1,402 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sclient "k8s.io/client-go/kubernetes"
+)
+
+// namespaceHeader picks the namespace serving a request whose API key isn't bound to one
+const namespaceHeader = "X-Chill-Namespace"
+
+// namespacePodManager is the subset of K8sManager used to drive the pod of a tenant namespace
+type namespacePodManager interface {
+	podManager
+	VerifyPodConfig(ctx context.Context, modelConfig *kubernetes.ModelConfig) (bool, error)
+}
+
+// namespaceBackend runs the vLLM pod of a tenant namespace. Like the embedding pod it has its own
+// lifecycle, and it switches to the model its requests ask for.
+type namespaceBackend struct {
+	namespace    string
+	k8sManager   namespacePodManager
+	crdClient    modelGetter
+	defaultModel string // Served model name started when a request names none
+	targetURL    *url.URL
+	model        string // Served model name of the pod, empty until started
+	lastActivity time.Time
+	mu           sync.Mutex        // Guards model and lastActivity
+	scaleMu      sync.Mutex        // Serializes pod creation and switches
+	coldStarts   *coldStartGate    // Shared with the chat model, nil when unlimited
+	leader       *leaderElector    // Starts the pod when this replica follows, nil without leader election
+	transport    http.RoundTripper // Requests to the pod (nil = http.DefaultTransport)
+	// API key of the pod's model sent in place of the client's, nil forwards the client's
+	apiKey func(ctx context.Context, model string) string
+}
+
+// newNamespaceBackend creates the backend of a tenant namespace, starting defaultModel when a
+// request names no model
+func newNamespaceBackend(namespace string, k8sManager namespacePodManager, crdClient modelGetter, defaultModel string, targetURL *url.URL) *namespaceBackend {
+	return &namespaceBackend{
+		namespace:    namespace,
+		k8sManager:   k8sManager,
+		crdClient:    crdClient,
+		defaultModel: defaultModel,
+		targetURL:    targetURL,
+		lastActivity: time.Now(),
+	}
+}
+
+// currentModel returns the served model name of the pod, empty until started
+func (n *namespaceBackend) currentModel() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.model
+}
+
+// ensureScaledUp starts the pod with the model, replacing a pod running another one, and waits
+// until it is ready. An empty model keeps the running one, or starts the default model.
+func (n *namespaceBackend) ensureScaledUp(ctx context.Context, model string) error {
+	// Followers leave the pod to the leader
+	if n.leader.follows() {
+		req := scaleUpRequest{Model: model, Namespace: n.namespace}
+		resp, err := n.leader.requestScaleUp(req)
+		if err != nil {
+			return err
+		}
+		if err := resp.err(req); err != nil {
+			return err
+		}
+		if model != "" {
+			n.mu.Lock()
+			n.model = model
+			n.mu.Unlock()
+		}
+		return nil
+	}
+
+	n.scaleMu.Lock()
+	defer n.scaleMu.Unlock()
+
+	if model == "" {
+		model = n.currentModel()
+	}
+	if model == "" {
+		model = n.defaultModel
+	}
+	modelConfig, err := n.crdClient.GetModel(ctx, model)
+	if err != nil {
+		var notFoundErr *kubernetes.ModelNotFoundError
+		if errors.As(err, &notFoundErr) {
+			return &ModelNotFoundError{RequestedModel: model}
+		}
+		return fmt.Errorf("failed to get model config for '%s': %w", model, err)
+	}
+
+	exists, err := n.k8sManager.PodExists(ctx)
+	if err != nil {
+		return err
+	}
+	// A pod of another model, or one the proxy didn't start, is replaced
+	if exists {
+		matches, err := n.k8sManager.VerifyPodConfig(ctx, modelConfig)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			log.Printf("[NAMESPACE %s] Switching pod to model: %s", n.namespace, model)
+			if err := n.k8sManager.DeletePod(context.Background()); err != nil {
+				return err
+			}
+			exists = false
+		}
+	}
+
+	if !exists {
+		queueCtx, cancelQueue := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+		defer cancelQueue()
+		release, err := n.coldStarts.acquire(queueCtx, model)
+		if err != nil {
+			return fmt.Errorf("timeout waiting for other models to start: %w", err)
+		}
+		defer release()
+
+		log.Printf("[NAMESPACE %s] Creating pod with model: %s (%s)", n.namespace, model, modelConfig.ModelName)
+		if err := n.k8sManager.CreatePod(ctx, modelConfig); err != nil {
+			return err
+		}
+	}
+	n.mu.Lock()
+	n.model = model
+	n.mu.Unlock()
+
+	// Use background context so request timeout doesn't cancel pod startup
+	waitCtx, cancel := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if ready, err := n.k8sManager.IsPodReady(waitCtx); err == nil && ready {
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("timeout waiting for the pod of namespace %s to be ready", n.namespace)
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateActivity records traffic of the namespace
+func (n *namespaceBackend) updateActivity() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastActivity = time.Now()
+}
+
+// serveHTTP starts the pod with the model if needed and proxies the request to it
+func (n *namespaceBackend) serveHTTP(w http.ResponseWriter, r *http.Request, model string) {
+	n.updateActivity()
+
+	if err := n.ensureScaledUp(r.Context(), model); err != nil {
+		var notFoundErr *ModelNotFoundError
+		if errors.As(err, &notFoundErr) {
+			writeAPIError(w, http.StatusNotFound, fmt.Sprintf("Model '%s' not found", notFoundErr.RequestedModel),
+				"invalid_request_error", "model_not_found")
+			return
+		}
+		log.Printf("[NAMESPACE %s] Failed to scale up: %v", n.namespace, err)
+		w.Header().Set("Retry-After", "10")
+		writeAPIError(w, http.StatusServiceUnavailable,
+			"Model is starting up. Please wait and retry in a few moments.", "service_unavailable", "scaling_up")
+		return
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(n.targetURL)
+	proxy.Transport = n.transport
+	if n.apiKey != nil {
+		authorizeUpstream(proxy, n.apiKey(r.Context(), n.currentModel()))
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		log.Printf("[NAMESPACE %s] Proxy error: %v", n.namespace, err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// checkIdle deletes the pod once it has been idle longer than the timeout
+func (n *namespaceBackend) checkIdle(ctx context.Context, idleTimeout time.Duration) {
+	n.mu.Lock()
+	idleTime := time.Since(n.lastActivity)
+	n.mu.Unlock()
+
+	if idleTime <= idleTimeout {
+		return
+	}
+
+	exists, err := n.k8sManager.PodExists(ctx)
+	if err != nil {
+		log.Printf("[NAMESPACE %s] Failed to check pod existence: %v", n.namespace, err)
+		return
+	}
+	if exists {
+		log.Printf("[NAMESPACE %s] Idle for %v, deleting pod...", n.namespace, idleTime.Round(time.Second))
+		if err := n.k8sManager.DeletePod(ctx); err != nil {
+			log.Printf("[NAMESPACE %s] Failed to delete pod: %v", n.namespace, err)
+		}
+	}
+}
+
+// namespaceBackends holds the backends of the tenant namespaces served
+type namespaceBackends struct {
+	mu       sync.RWMutex
+	backends map[string]*namespaceBackend
+	// Creates the backend of a namespace newly served, along with its service
+	newBackend func(ctx context.Context, namespace string) (*namespaceBackend, error)
+}
+
+// get returns the backend of a namespace, nil when it isn't served
+func (b *namespaceBackends) get(namespace string) *namespaceBackend {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.backends[namespace]
+}
+
+// all returns the backends of every namespace served
+func (b *namespaceBackends) all() []*namespaceBackend {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	backends := make([]*namespaceBackend, 0, len(b.backends))
+	for _, backend := range b.backends {
+		backends = append(backends, backend)
+	}
+	return backends
+}
+
+// set serves the namespaces, creating the backends of the new ones and deleting the pods of the
+// ones no longer served. A namespace whose backend can't be created is retried on the next call.
+func (b *namespaceBackends) set(ctx context.Context, namespaces []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for namespace, backend := range b.backends {
+		if slices.Contains(namespaces, namespace) {
+			continue
+		}
+		delete(b.backends, namespace)
+		// The leader manages the pods
+		if backend.leader.follows() {
+			continue
+		}
+		log.Printf("[NAMESPACE %s] No longer served, deleting its pod", namespace)
+		go func() {
+			if err := backend.k8sManager.DeletePod(context.Background()); err != nil {
+				log.Printf("[NAMESPACE %s] Failed to delete pod: %v", namespace, err)
+			}
+		}()
+	}
+	for _, namespace := range namespaces {
+		if _, ok := b.backends[namespace]; ok {
+			continue
+		}
+		backend, err := b.newBackend(ctx, namespace)
+		if err != nil {
+			log.Printf("[NAMESPACE %s] Not served: %v", namespace, err)
+			continue
+		}
+		b.backends[namespace] = backend
+		log.Printf("[NAMESPACE %s] Serving namespace", namespace)
+	}
+}
+
+// requestNamespace returns the namespace asked to serve the request: the one of its API key's
+// tenant, or else the X-Chill-Namespace header. The header is not forwarded to vLLM.
+func requestNamespace(r *http.Request) string {
+	namespace := r.Header.Get(namespaceHeader)
+	r.Header.Del(namespaceHeader)
+	if tenant := auth.TenantFrom(r.Context()); tenant != nil && tenant.Namespace != "" {
+		return tenant.Namespace
+	}
+	return namespace
+}
+
+// namespaceBackendFor returns the backend serving the request, nil when it is served in the
+// proxy's namespace. Requests for a namespace that isn't served are rejected with a 404.
+func (as *AutoScaler) namespaceBackendFor(w http.ResponseWriter, r *http.Request) (*namespaceBackend, bool) {
+	namespace := requestNamespace(r)
+	if namespace == "" || namespace == as.config.Namespace {
+		return nil, true
+	}
+	backend := as.namespaces.get(namespace)
+	if backend == nil {
+		log.Printf("Rejected %s %s: namespace %s is not served", r.Method, r.URL.Path, namespace)
+		writeAPIError(w, http.StatusNotFound, fmt.Sprintf("The namespace %s is not served by this proxy.", namespace),
+			"invalid_request_error", "namespace_not_found")
+		return nil, false
+	}
+	return backend, true
+}
+
+// newNamespaceBackends creates the backends of the tenant namespaces, with the pod and service
+// settings of the proxy's namespace
+func (as *AutoScaler) newNamespaceBackends(ctx context.Context) {
+	as.namespaces = &namespaceBackends{
+		backends: make(map[string]*namespaceBackend),
+		newBackend: func(ctx context.Context, namespace string) (*namespaceBackend, error) {
+			manager := kubernetes.NewK8sManager(as.clientset, as.config.namespaceKubernetesConfig(namespace))
+			defaultModel, err := as.crdClient.GetModel(ctx, as.config.ModelID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get model '%s' from CRD: %w", as.config.ModelID, err)
+			}
+			if err := manager.EnsureVLLMResources(ctx, defaultModel); err != nil {
+				return nil, fmt.Errorf("failed to ensure vLLM resources: %w", err)
+			}
+			targetURL, err := as.config.targetURL(fmt.Sprintf("%s.%s.svc", as.config.TargetHost, namespace))
+			if err != nil {
+				return nil, fmt.Errorf("invalid target URL: %w", err)
+			}
+			backend := newNamespaceBackend(namespace, manager, as.crdClient, as.config.ModelID, targetURL)
+			backend.coldStarts = as.coldStarts
+			backend.leader = as.leader
+			backend.transport = as.vllmTransport
+			backend.apiKey = func(ctx context.Context, model string) string {
+				return as.namespaceAPIKey(ctx, manager, namespace, model)
+			}
+			return backend, nil
+		},
+	}
+	as.refreshNamespaces(ctx)
+}
+
+// servedNamespaces returns the tenant namespaces listed in the config or matching its selector
+func servedNamespaces(ctx context.Context, clientset k8sclient.Interface, config *Config) ([]string, error) {
+	namespaces := config.namespaceList()
+	if config.NamespaceSelector == "" {
+		return namespaces, nil
+	}
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: config.NamespaceSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces matching %q: %w", config.NamespaceSelector, err)
+	}
+	for _, item := range list.Items {
+		if item.Name != config.Namespace && !slices.Contains(namespaces, item.Name) {
+			namespaces = append(namespaces, item.Name)
+		}
+	}
+	return namespaces, nil
+}
+
+// refreshNamespaces serves the namespaces listed in the config or matching the selector, keeping
+// the current ones when they can't be listed
+func (as *AutoScaler) refreshNamespaces(ctx context.Context) {
+	namespaces, err := servedNamespaces(ctx, as.clientset, as.config)
+	if err != nil {
+		log.Printf("Keeping the current namespaces: %v", err)
+		return
+	}
+	as.namespaces.set(ctx, namespaces)
+}
+
+// startNamespaceRefresh periodically reads the namespaces matching the selector again
+func (as *AutoScaler) startNamespaceRefresh(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			as.refreshNamespaces(ctx)
+		}
+	}
+}
+
+// namespaceStatus reports the pods of the tenant namespaces in /proxy/status, nil when none is served
+func (as *AutoScaler) namespaceStatus() map[string]interface{} {
+	if as.namespaces == nil {
+		return nil
+	}
+	status := make(map[string]interface{})
+	for _, backend := range as.namespaces.all() {
+		status[backend.namespace] = map[string]interface{}{
+			"model":      backend.currentModel(),
+			"target_url": backend.targetURL.String(),
+		}
+	}
+	return status
+}
@@ -0,0 +1,214 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newTestNamespaceBackend serves the team-a namespace from a fake clientset whose pods are ready
+// once created
+func newTestNamespaceBackend(t *testing.T, target string) (*namespaceBackend, *fake.Clientset) {
+	t.Helper()
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}
+		return false, nil, nil
+	})
+	config := &Config{Namespace: "vllm", Deployment: "vllm", GPUCount: 1}
+	manager := kubernetes.NewK8sManager(clientset, config.namespaceKubernetesConfig("team-a"))
+	targetURL, err := url.Parse(target)
+	require.NoError(t, err)
+	getter := &stubModelGetter{models: map[string]*kubernetes.ModelConfig{
+		"qwen":  {ModelName: "Qwen/Qwen3-8B", ServedModelName: "qwen"},
+		"llama": {ModelName: "meta-llama/Llama-3.1-8B", ServedModelName: "llama"},
+	}}
+	return newNamespaceBackend("team-a", manager, getter, "qwen", targetURL), clientset
+}
+
+func TestNamespaceBackend_SwitchesModel(t *testing.T) {
+	backend, clientset := newTestNamespaceBackend(t, "http://127.0.0.1:1")
+	ctx := context.Background()
+
+	// A request without a model starts the default one
+	require.NoError(t, backend.ensureScaledUp(ctx, ""))
+	pod, err := clientset.CoreV1().Pods("team-a").Get(ctx, "vllm", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, pod.Spec.Containers[0].Args, "Qwen/Qwen3-8B")
+	assert.Equal(t, "qwen", backend.currentModel())
+
+	require.NoError(t, backend.ensureScaledUp(ctx, "llama"))
+	pod, err = clientset.CoreV1().Pods("team-a").Get(ctx, "vllm", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Contains(t, pod.Spec.Containers[0].Args, "meta-llama/Llama-3.1-8B")
+	assert.Equal(t, "llama", backend.currentModel())
+
+	// Requests without a model keep the running one
+	require.NoError(t, backend.ensureScaledUp(ctx, ""))
+	assert.Equal(t, "llama", backend.currentModel())
+
+	err = backend.ensureScaledUp(ctx, "mistral")
+	var notFoundErr *ModelNotFoundError
+	assert.ErrorAs(t, err, &notFoundErr)
+}
+
+func TestNamespaceBackend_ServeHTTP(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk-team-a-qwen", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"object":"chat.completion"}`))
+	}))
+	defer upstream.Close()
+
+	backend, _ := newTestNamespaceBackend(t, upstream.URL)
+	backend.apiKey = func(_ context.Context, model string) string {
+		return "sk-team-a-" + model
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen"}`))
+	w := httptest.NewRecorder()
+	backend.serveHTTP(w, req, "qwen")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "chat.completion")
+
+	w = httptest.NewRecorder()
+	backend.serveHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`)), "mistral")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "model_not_found")
+}
+
+func TestNamespaceBackend_CheckIdle(t *testing.T) {
+	backend, _ := newTestNamespaceBackend(t, "http://127.0.0.1:1")
+	ctx := context.Background()
+	require.NoError(t, backend.ensureScaledUp(ctx, "qwen"))
+
+	backend.checkIdle(ctx, 5*time.Minute)
+	exists, err := backend.k8sManager.PodExists(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	backend.lastActivity = time.Now().Add(-10 * time.Minute)
+	backend.checkIdle(ctx, 5*time.Minute)
+	exists, err = backend.k8sManager.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestNamespaceBackends_Set(t *testing.T) {
+	backends := &namespaceBackends{
+		backends: make(map[string]*namespaceBackend),
+		newBackend: func(_ context.Context, namespace string) (*namespaceBackend, error) {
+			if namespace == "broken" {
+				return nil, fmt.Errorf("forbidden")
+			}
+			backend, _ := newTestNamespaceBackend(t, "http://127.0.0.1:1")
+			backend.namespace = namespace
+			return backend, nil
+		},
+	}
+	ctx := context.Background()
+
+	backends.set(ctx, []string{"team-a", "team-b", "broken"})
+	assert.NotNil(t, backends.get("team-a"))
+	assert.NotNil(t, backends.get("team-b"))
+	assert.Nil(t, backends.get("broken"))
+	assert.Len(t, backends.all(), 2)
+
+	teamA := backends.get("team-a")
+	require.NoError(t, teamA.ensureScaledUp(ctx, ""))
+
+	// A namespace no longer served loses its pod
+	backends.set(ctx, []string{"team-b"})
+	assert.Nil(t, backends.get("team-a"))
+	assert.Eventually(t, func() bool {
+		exists, err := teamA.k8sManager.PodExists(ctx)
+		return err == nil && !exists
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRequestNamespace(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set(namespaceHeader, "team-b")
+	assert.Equal(t, "team-b", requestNamespace(req))
+	assert.Empty(t, req.Header.Get(namespaceHeader), "the header is not forwarded to vLLM")
+
+	// The API key's namespace wins over the header
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req.Header.Set(namespaceHeader, "team-b")
+	req = req.WithContext(auth.WithTenant(req.Context(), &auth.Tenant{Name: "team-a", Namespace: "team-a"}))
+	assert.Equal(t, "team-a", requestNamespace(req))
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	req = req.WithContext(auth.WithTenant(req.Context(), &auth.Tenant{Name: "team-c"}))
+	assert.Empty(t, requestNamespace(req))
+}
+
+func TestAutoScaler_NamespaceBackendFor(t *testing.T) {
+	backend, _ := newTestNamespaceBackend(t, "http://127.0.0.1:1")
+	as := &AutoScaler{
+		config:     &Config{Namespace: "vllm"},
+		namespaces: &namespaceBackends{backends: map[string]*namespaceBackend{"team-a": backend}},
+	}
+
+	for _, tt := range []struct {
+		namespace string
+		want      *namespaceBackend
+		ok        bool
+	}{
+		{namespace: "", ok: true},
+		{namespace: "vllm", ok: true},
+		{namespace: "team-a", want: backend, ok: true},
+		{namespace: "team-b", ok: false},
+	} {
+		t.Run(tt.namespace, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			req.Header.Set(namespaceHeader, tt.namespace)
+			w := httptest.NewRecorder()
+			got, ok := as.namespaceBackendFor(w, req)
+			assert.Equal(t, tt.ok, ok)
+			assert.Same(t, tt.want, got)
+			if !ok {
+				assert.Equal(t, http.StatusNotFound, w.Code)
+				assert.Contains(t, w.Body.String(), "namespace_not_found")
+			}
+		})
+	}
+}
+
+func TestServedNamespaces(t *testing.T) {
+	tenant := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	clientset := fake.NewSimpleClientset(
+		tenant("vllm", map[string]string{"vllm-chill/tenant": "true"}),
+		tenant("team-b", map[string]string{"vllm-chill/tenant": "true"}),
+		tenant("team-c", map[string]string{"vllm-chill/tenant": "true"}),
+		tenant("other", nil),
+	)
+
+	config := &Config{Namespace: "vllm", Namespaces: "team-a, vllm,team-b"}
+	namespaces, err := servedNamespaces(context.Background(), clientset, config)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-a", "team-b"}, namespaces)
+
+	config.NamespaceSelector = "vllm-chill/tenant=true"
+	namespaces, err = servedNamespaces(context.Background(), clientset, config)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"team-a", "team-b", "team-c"}, namespaces)
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/notify"
+)
+
+// newNotifier creates the notifier of the configured webhooks, nil when none is configured
+func newNotifier(config *Config) (*notify.Notifier, error) {
+	webhooks, err := notify.ParseWebhooks(config.NotifyWebhooks)
+	if err != nil || len(webhooks) == 0 {
+		return nil, err
+	}
+	events, err := notify.ParseEvents(config.NotifyEvents)
+	if err != nil {
+		return nil, err
+	}
+	return notify.New(webhooks, events, config.NotifyTemplate)
+}
+
+// notify posts an event about the model to the webhooks, nil-safe. The caller must not hold as.mu
+// when model is read from it.
+func (as *AutoScaler) notify(eventType, model, message string) {
+	as.notifier.Notify(notify.Event{Type: eventType, Model: model, Message: message})
+}
+
+// startFailed reports a failed start of the active model in its VLLMModel status and to the
+// webhooks. The caller must not hold as.mu.
+func (as *AutoScaler) startFailed(reason, message string) {
+	as.modelStatus.failStart(reason, message)
+	as.notify(notify.StartupFailure, as.GetActiveModel(), fmt.Sprintf("Failed to start (%s): %s", reason, message))
+}
+
+// driftSummary lists the drifted fields of the pod, e.g. "image, env"
+func driftSummary(drifts []kubernetes.Drift) string {
+	fields := make([]string, 0, len(drifts))
+	for _, drift := range drifts {
+		fields = append(fields, drift.Field)
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifications(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []map[string]string
+	)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err == nil {
+			mu.Lock()
+			received = append(received, body)
+			mu.Unlock()
+		}
+	}))
+	defer webhook.Close()
+
+	now := time.Now()
+	as, _ := scheduleTestAutoScaler(t, now, time.Hour, vllmPod())
+	as.config.NotifyWebhooks = "slack=" + webhook.URL
+	as.config.NotifyEvents = "scale_down,startup_failure"
+	var err error
+	as.notifier, err = newNotifier(as.config)
+	require.NoError(t, err)
+
+	as.checkIdle(context.Background(), now)
+	as.startFailed("PodCreationFailed", "quota exceeded")
+	as.notify(notify.ModelSwitch, "qwen", "Switched from devstral") // Not subscribed to
+	as.notifier.Close(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, "[vllm-chill] qwen: Released after 1h0m0s idle (delete)", received[0]["text"])
+	assert.Equal(t, "[vllm-chill] qwen: Failed to start (PodCreationFailed): quota exceeded", received[1]["text"])
+
+	// Without webhooks nothing is posted
+	notifier, err := newNotifier(&Config{})
+	require.NoError(t, err)
+	assert.Nil(t, notifier)
+}
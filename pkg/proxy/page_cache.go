@@ -0,0 +1,70 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+)
+
+// recentModels tracks the most recently used models by Hugging Face name, for the page cache warm pool
+type recentModels struct {
+	mu     sync.Mutex
+	size   int
+	models []string // Most recent first
+}
+
+// newRecentModels creates a tracker keeping the size most recently used models
+func newRecentModels(size int) *recentModels {
+	return &recentModels{size: size}
+}
+
+// touch marks a model as the most recently used
+func (rm *recentModels) touch(model string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.models = slices.DeleteFunc(rm.models, func(m string) bool { return m == model })
+	rm.models = slices.Insert(rm.models, 0, model)
+	if len(rm.models) > rm.size {
+		rm.models = rm.models[:rm.size]
+	}
+}
+
+// list returns the tracked models, most recent first
+func (rm *recentModels) list() []string {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return slices.Clone(rm.models)
+}
+
+// podNode returns the node of the vLLM pod, empty when it isn't scheduled
+func (as *AutoScaler) podNode(ctx context.Context) string {
+	pod, err := as.k8sManager.GetPod(ctx)
+	if err != nil {
+		return ""
+	}
+	return pod.Spec.NodeName
+}
+
+// warmPageCache keeps the weights of the most recently used models in the page cache of the node
+// the model was released from, so the next cold start reads them from memory. vLLM sleep mode
+// keeps the weights in CPU memory already.
+func (as *AutoScaler) warmPageCache(ctx context.Context, node string) {
+	if as.recentModels == nil || node == "" || as.strategy.name() == ScaleStrategyVLLMSleep {
+		return
+	}
+	if err := as.k8sManager.StartPageCacheWarmer(ctx, node, as.recentModels.list(), as.config.GetPageCacheBudget()); err != nil {
+		log.Printf("Failed to start the page cache warmer: %v", err)
+	}
+}
+
+// stopPageCacheWarmer stops reading the model files once vLLM starts, the pages stay cached
+func (as *AutoScaler) stopPageCacheWarmer(ctx context.Context) {
+	if as.recentModels == nil {
+		return
+	}
+	if err := as.k8sManager.StopPageCacheWarmer(ctx); err != nil {
+		log.Printf("Failed to stop the page cache warmer: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecentModels(t *testing.T) {
+	recent := newRecentModels(2)
+
+	recent.touch("Qwen/Qwen3-8B")
+	recent.touch("mistralai/Devstral-Small-2505")
+	recent.touch("Qwen/Qwen3-8B")
+	assert.Equal(t, []string{"Qwen/Qwen3-8B", "mistralai/Devstral-Small-2505"}, recent.list())
+
+	recent.touch("google/gemma-3-27b-it")
+	assert.Equal(t, []string{"google/gemma-3-27b-it", "Qwen/Qwen3-8B"}, recent.list(), "the least recently used model leaves the pool")
+}
+
+func TestCheckIdleWarmsPageCache(t *testing.T) {
+	now := time.Now()
+	pod := vllmPod()
+	pod.Spec.NodeName = "gpu-node"
+	as, clientset := scheduleTestAutoScaler(t, now, time.Hour, pod)
+	as.recentModels = newRecentModels(2)
+	as.recentModels.touch("Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8")
+	as.config.PageCacheBudget = "40Gi"
+
+	as.checkIdle(context.Background(), now)
+
+	assert.False(t, podExists(t, clientset))
+	warmer, err := clientset.CoreV1().Pods("vllm").Get(context.Background(), "vllm-page-cache", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "gpu-node", warmer.Spec.NodeName, "the warmer fills the page cache of the released node")
+	assert.Contains(t, warmer.Spec.Containers[0].Command, "/hf-cache/hub/models--Qwen--Qwen3-Coder-30B-A3B-Instruct-FP8")
+
+	// The warmer stops once the model starts again
+	as.stopPageCacheWarmer(context.Background())
+	_, err = clientset.CoreV1().Pods("vllm").Get(context.Background(), "vllm-page-cache", metav1.GetOptions{})
+	assert.Error(t, err)
+}
+
+func TestCheckIdleWithoutPageCache(t *testing.T) {
+	now := time.Now()
+	as, clientset := scheduleTestAutoScaler(t, now, time.Hour, vllmPod())
+
+	as.checkIdle(context.Background(), now)
+
+	pods, err := clientset.CoreV1().Pods("vllm").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, pods.Items)
+}
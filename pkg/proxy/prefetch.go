@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+// modelDownloading is the error code of requests waiting for a model download
+const modelDownloading = "model_downloading"
+
+// vllmStateDownloading is the vllm_chill_vllm_state value while a Job downloads the model
+const vllmStateDownloading = 5
+
+// prefetchPollInterval is how often the download Job is checked
+const prefetchPollInterval = 2 * time.Second
+
+// ModelDownloadingError reports that the model is still being downloaded into the cache. The Job
+// keeps downloading, the next request waits for it again.
+type ModelDownloadingError struct {
+	Model     string
+	StartedAt time.Time // Creation of the download Job, zero when unknown
+}
+
+func (e *ModelDownloadingError) Error() string {
+	return fmt.Sprintf("model %s is still downloading", e.Model)
+}
+
+// message describes the download for the loading message of chat completions
+func (e *ModelDownloadingError) message(now time.Time) string {
+	if e.StartedAt.IsZero() {
+		return fmt.Sprintf("Model '%s' is downloading, please wait...", e.Model)
+	}
+	elapsed := now.Sub(e.StartedAt).Truncate(time.Second)
+	return fmt.Sprintf("Model '%s' is downloading (%s so far), please wait...", e.Model, elapsed)
+}
+
+// prefetchModel downloads the active model into the Hugging Face cache with a Job before its pod is
+// created, waiting at most timeout. Models are checked once per proxy: a completed download isn't
+// started again, and a failed one leaves the download to vLLM on startup.
+func (as *AutoScaler) prefetchModel(ctx context.Context, timeout time.Duration) error {
+	if !as.config.ModelPrefetch {
+		return nil
+	}
+	// Paused and sleeping pods have loaded the weights already
+	if exists, err := as.k8sManager.PodExists(ctx); err != nil || exists {
+		return nil
+	}
+	modelID := as.GetActiveModel()
+	modelConfig, err := as.crdClient.GetModel(ctx, modelID)
+	if err != nil {
+		return nil // Reported by the pod creation
+	}
+	key := modelConfig.ModelName + " " + modelConfig.LoRAAdapter
+	if _, done := as.prefetched.Load(key); done {
+		return nil
+	}
+
+	downloading, err := as.k8sManager.StartPrefetchJob(ctx, modelConfig)
+	if err != nil {
+		log.Printf("Failed to start the download of %s, vLLM downloads it on startup: %v", modelConfig.ModelName, err)
+		return nil
+	}
+	if !downloading {
+		as.prefetched.Store(key, true)
+		return nil
+	}
+	as.setVLLMState(vllmStateDownloading)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(prefetchPollInterval)
+	defer ticker.Stop()
+
+	for {
+		status, startedAt, err := as.k8sManager.PrefetchJobStatus(ctx)
+		switch {
+		case err != nil || status == kubernetes.PrefetchFailed:
+			log.Printf("Download of %s failed, vLLM downloads it on startup: %v", modelConfig.ModelName, err)
+			as.prefetched.Store(key, true)
+			return nil
+		case status == kubernetes.PrefetchSucceeded:
+			log.Printf("Downloaded %s into the cache", modelConfig.ModelName)
+			as.prefetched.Store(key, true)
+			if err := as.k8sManager.DeletePrefetchJob(ctx); err != nil {
+				log.Printf("Failed to delete the download job: %v", err)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			as.setVLLMState(0) // stopped, until the next request finds the download complete
+			return &ModelDownloadingError{Model: modelID, StartedAt: startedAt}
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPrefetchModel(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	as := &AutoScaler{
+		config:      &Config{Namespace: "vllm", Deployment: "vllm", ModelPrefetch: true},
+		crdClient:   newFakeCRDClient(t, replicaModelSpec(0, 1)),
+		k8sManager:  kubernetes.NewK8sManager(clientset, &kubernetes.Config{Namespace: "vllm", Deployment: "vllm"}),
+		activeModel: "qwen",
+		metrics:     stats.NewMetricsRecorder(),
+	}
+	ctx := context.Background()
+	jobs := clientset.BatchV1().Jobs("vllm")
+
+	err := as.prefetchModel(ctx, 10*time.Millisecond)
+	var downloadErr *ModelDownloadingError
+	require.ErrorAs(t, err, &downloadErr, "the download outlasts the wait")
+	assert.Equal(t, "qwen", downloadErr.Model)
+	assert.Equal(t, "stopped", as.vllmStateName())
+
+	job, err := jobs.Get(ctx, "vllm-prefetch", metav1.GetOptions{})
+	require.NoError(t, err)
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8", container.Command[len(container.Command)-1])
+	assert.Empty(t, container.Resources.Limits, "downloads don't take a GPU")
+	assert.Equal(t, corev1.RestartPolicyNever, job.Spec.Template.Spec.RestartPolicy)
+
+	// The next request waits for the same Job
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	_, err = jobs.UpdateStatus(ctx, job, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	require.NoError(t, as.prefetchModel(ctx, time.Minute))
+
+	_, err = jobs.Get(ctx, "vllm-prefetch", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err), "completed Jobs are deleted")
+
+	// Downloaded models are not checked again
+	require.NoError(t, as.prefetchModel(ctx, time.Minute))
+	_, err = jobs.Get(ctx, "vllm-prefetch", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestPrefetchModel_Disabled(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	as := &AutoScaler{
+		config:     &Config{Namespace: "vllm", Deployment: "vllm"},
+		k8sManager: kubernetes.NewK8sManager(clientset, &kubernetes.Config{Namespace: "vllm", Deployment: "vllm"}),
+	}
+
+	require.NoError(t, as.prefetchModel(context.Background(), time.Minute))
+	list, err := clientset.BatchV1().Jobs("vllm").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Items)
+}
+
+func TestModelDownloadingError_LoadingMessage(t *testing.T) {
+	now := time.Now()
+	err := &ModelDownloadingError{Model: "qwen", StartedAt: now.Add(-90 * time.Second)}
+	assert.Equal(t, "Model 'qwen' is downloading (1m30s so far), please wait...", err.message(now))
+	assert.Equal(t, "Model 'qwen' is downloading, please wait...", (&ModelDownloadingError{Model: "qwen"}).message(now))
+
+	as := &AutoScaler{}
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","stream":false}`))
+	w := httptest.NewRecorder()
+	as.writeLoadingMessage(w, r, "qwen", err.message(now))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "is downloading (1m30s so far)")
+}
@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// v1Methods lists the methods accepted by the known /v1 endpoints
+var v1Methods = map[string]string{
+	"/v1/chat/completions":      "POST, HEAD, OPTIONS",
+	"/v1/completions":           "POST, HEAD, OPTIONS",
+	"/v1/embeddings":            "POST, HEAD, OPTIONS",
+	"/v1/messages":              "POST, HEAD, OPTIONS",
+	"/v1/messages/count_tokens": "POST, HEAD, OPTIONS",
+	"/v1/models":                "GET, HEAD, OPTIONS",
+}
+
+// defaultCORSHeaders are allowed when a preflight request does not list the headers it needs
+const defaultCORSHeaders = "Authorization, Content-Type, X-API-Key, Anthropic-Version, OpenAI-Organization"
+
+// allowedMethods returns the methods accepted by a known /v1 path, or "" for other paths
+func allowedMethods(path string) string {
+	path = strings.TrimSuffix(path, "/")
+	if methods, ok := v1Methods[path]; ok {
+		return methods
+	}
+	if strings.HasPrefix(path, "/v1/models/") {
+		return v1Methods["/v1/models"]
+	}
+	return ""
+}
+
+// handleProbe answers HEAD and OPTIONS requests on known /v1 endpoints without reaching vLLM, so
+// API gateway and SDK probes neither count as activity nor trigger a scale-up. It returns false
+// for requests that must be proxied.
+func handleProbe(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodHead && r.Method != http.MethodOptions {
+		return false
+	}
+	methods := allowedMethods(r.URL.Path)
+	if methods == "" {
+		return false
+	}
+
+	w.Header().Set("Allow", methods)
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		return true
+	}
+
+	// CORS preflight
+	if origin := r.Header.Get("Origin"); origin != "" {
+		requested := r.Header.Get("Access-Control-Request-Headers")
+		if requested == "" {
+			requested = defaultCORSHeaders
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", methods)
+		w.Header().Set("Access-Control-Allow-Headers", requested)
+		w.Header().Set("Access-Control-Max-Age", "86400")
+		w.Header().Add("Vary", "Origin")
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
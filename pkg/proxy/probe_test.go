@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxyHandler_AnswersProbesWithoutActivity(t *testing.T) {
+	lastActivity := time.Now().Add(-time.Hour)
+	as := &AutoScaler{lastActivity: lastActivity}
+
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodHead, "/v1/chat/completions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "POST, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.Empty(t, w.Body.String())
+
+	r := httptest.NewRequest(http.MethodOptions, "/v1/models/qwen", nil)
+	r.Header.Set("Origin", "https://chat.example.com")
+	w = httptest.NewRecorder()
+	as.proxyHandler(w, r)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, HEAD, OPTIONS", w.Header().Get("Allow"))
+	assert.Equal(t, "https://chat.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, defaultCORSHeaders, w.Header().Get("Access-Control-Allow-Headers"))
+
+	assert.Equal(t, lastActivity, as.lastActivity, "probes must not count as activity")
+}
+
+func TestHandleProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		handled bool
+	}{
+		{name: "options on chat", method: http.MethodOptions, path: "/v1/chat/completions", handled: true},
+		{name: "head on messages", method: http.MethodHead, path: "/v1/messages", handled: true},
+		{name: "trailing slash", method: http.MethodHead, path: "/v1/models/", handled: true},
+		{name: "post is proxied", method: http.MethodPost, path: "/v1/chat/completions", handled: false},
+		{name: "get is proxied", method: http.MethodGet, path: "/v1/models", handled: false},
+		{name: "unknown path", method: http.MethodOptions, path: "/v1/audio/transcriptions", handled: false},
+		{name: "non v1 path", method: http.MethodHead, path: "/metrics", handled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			assert.Equal(t, tt.handled, handleProbe(w, httptest.NewRequest(tt.method, tt.path, nil)))
+		})
+	}
+}
+
+func TestHandleProbe_ReflectsRequestedHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+	r.Header.Set("Origin", "https://chat.example.com")
+	r.Header.Set("Access-Control-Request-Headers", "authorization, x-stainless-os")
+	w := httptest.NewRecorder()
+
+	handleProbe(w, r)
+
+	assert.Equal(t, "authorization, x-stainless-os", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "POST, HEAD, OPTIONS", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "86400", w.Header().Get("Access-Control-Max-Age"))
+}
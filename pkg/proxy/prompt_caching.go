@@ -0,0 +1,179 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// cacheControlField marks Anthropic prompt caching breakpoints. vLLM's automatic prefix caching
+// reuses cached prefixes without them, so they are removed rather than forwarded.
+const cacheControlField = "cache_control"
+
+// usageObject matches the start of a usage object, and an empty one whole
+var usageObject = regexp.MustCompile(`"usage":\s*\{(\s*\})?`)
+
+// stripCacheControl removes the cache_control fields of an Anthropic request: on the system
+// blocks, the tools and the content blocks of the messages, tool results included. Tool input
+// schemas are left untouched. It returns the number of fields removed; other requests are left
+// as sent.
+func stripCacheControl(r *http.Request) (int, error) {
+	body, err := parsedBody(r)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Contains(body.data, []byte(`"`+cacheControlField+`"`)) {
+		return 0, nil
+	}
+	fields, err := body.object()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, name := range []string{"system", "tools", "messages"} {
+		raw, ok := fields[name]
+		if !ok {
+			continue
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber() // Keep numbers as sent
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return 0, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		n := removeCacheControl(value, name == "messages")
+		if n == 0 {
+			continue
+		}
+		if fields[name], err = json.Marshal(value); err != nil {
+			return 0, err
+		}
+		removed += n
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	return removed, body.rewrite(r)
+}
+
+// removeCacheControl removes cache_control from each block of a list, and from the content
+// blocks of messages, returning the number of fields removed
+func removeCacheControl(value interface{}, messages bool) int {
+	blocks, ok := value.([]interface{})
+	if !ok {
+		return 0
+	}
+	removed := 0
+	for _, block := range blocks {
+		fields, ok := block.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, ok := fields[cacheControlField]; ok {
+			delete(fields, cacheControlField)
+			removed++
+		}
+		// Message and tool result content may be a list of blocks as well
+		if messages || fields["type"] == "tool_result" {
+			removed += removeCacheControl(fields["content"], false)
+		}
+	}
+	return removed
+}
+
+// cacheUsageWriter adds the prompt caching usage fields of the Anthropic API to the usage vLLM
+// reports, as zero, for clients that expect them. Streamed events are rewritten line by line,
+// JSON responses once complete; other responses are passed through untouched.
+type cacheUsageWriter struct {
+	http.ResponseWriter
+	streaming  bool
+	buffering  bool
+	statusCode int
+	pending    []byte // Incomplete line of a stream, or the whole JSON response
+}
+
+// newCacheUsageWriter wraps w to complete the usage of Anthropic responses
+func newCacheUsageWriter(w http.ResponseWriter) *cacheUsageWriter {
+	return &cacheUsageWriter{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+// WriteHeader decides how the response is rewritten
+func (cw *cacheUsageWriter) WriteHeader(code int) {
+	header := cw.Header()
+	contentType := header.Get("Content-Type")
+	if header.Get("Content-Encoding") == "" && code == http.StatusOK {
+		cw.streaming = strings.HasPrefix(contentType, "text/event-stream")
+		cw.buffering = strings.HasPrefix(contentType, "application/json")
+	}
+	if cw.buffering {
+		cw.statusCode = code
+		// Body size changes once rewritten
+		header.Del("Content-Length")
+		return
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+// Write rewrites the complete lines of streams and buffers JSON responses
+func (cw *cacheUsageWriter) Write(b []byte) (int, error) {
+	switch {
+	case cw.buffering:
+		cw.pending = append(cw.pending, b...)
+		return len(b), nil
+	case !cw.streaming:
+		return cw.ResponseWriter.Write(b)
+	}
+
+	cw.pending = append(cw.pending, b...)
+	end := bytes.LastIndexByte(cw.pending, '\n')
+	if end < 0 {
+		return len(b), nil
+	}
+	data := addCacheUsage(cw.pending[:end+1])
+	cw.pending = append([]byte(nil), cw.pending[end+1:]...)
+	if _, err := cw.ResponseWriter.Write(data); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher, buffered responses are only flushed by finish
+func (cw *cacheUsageWriter) Flush() {
+	if cw.buffering {
+		return
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// finish writes the buffered response, or the end of the stream
+func (cw *cacheUsageWriter) finish() error {
+	if cw.buffering {
+		cw.ResponseWriter.WriteHeader(cw.statusCode)
+	}
+	if len(cw.pending) == 0 {
+		return nil
+	}
+	_, err := cw.ResponseWriter.Write(addCacheUsage(cw.pending))
+	cw.pending = nil
+	return err
+}
+
+// addCacheUsage adds zero cache_creation_input_tokens and cache_read_input_tokens to the usage
+// objects of data, unless it already reports prompt caching usage
+func addCacheUsage(data []byte) []byte {
+	if bytes.Contains(data, []byte(`"cache_read_input_tokens"`)) {
+		return data
+	}
+	return usageObject.ReplaceAllFunc(data, func(match []byte) []byte {
+		fields := `"usage":{"cache_creation_input_tokens":0,"cache_read_input_tokens":0`
+		if bytes.HasSuffix(match, []byte("}")) {
+			return []byte(fields + "}")
+		}
+		return []byte(fields + ",")
+	})
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStripCacheControl(t *testing.T) {
+	body := `{"model":"qwen","max_tokens":1024,
+		"system":[{"type":"text","text":"You are a coding agent.","cache_control":{"type":"ephemeral"}}],
+		"tools":[{"name":"read","input_schema":{"type":"object","properties":{"cache_control":{"type":"string"}}},"cache_control":{"type":"ephemeral"}}],
+		"messages":[
+			{"role":"user","content":"Hello"},
+			{"role":"user","content":[
+				{"type":"text","text":"Read it","cache_control":{"type":"ephemeral"}},
+				{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"42","cache_control":{"type":"ephemeral"}}]}
+			]}
+		]}`
+	r := httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(body))
+
+	removed, err := stripCacheControl(r)
+	require.NoError(t, err)
+	assert.Equal(t, 4, removed)
+
+	rewritten, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(rewritten)), r.ContentLength)
+	assert.JSONEq(t, `{"model":"qwen","max_tokens":1024,
+		"system":[{"type":"text","text":"You are a coding agent."}],
+		"tools":[{"name":"read","input_schema":{"type":"object","properties":{"cache_control":{"type":"string"}}}}],
+		"messages":[
+			{"role":"user","content":"Hello"},
+			{"role":"user","content":[
+				{"type":"text","text":"Read it"},
+				{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"42"}]}
+			]}
+		]}`, string(rewritten), "tool input schemas are left untouched")
+}
+
+func TestStripCacheControlLeavesOtherRequests(t *testing.T) {
+	body := `{"model":"qwen","max_tokens":1024,"system":"Mention \"cache_control\" plainly"}`
+	r := httptest.NewRequest(http.MethodPost, anthropicMessagesPath, strings.NewReader(body))
+
+	removed, err := stripCacheControl(r)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	forwarded, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(forwarded))
+}
+
+func TestAddCacheUsage(t *testing.T) {
+	assert.Equal(t,
+		`{"usage":{"cache_creation_input_tokens":0,"cache_read_input_tokens":0,"input_tokens":12,"output_tokens":3}}`,
+		string(addCacheUsage([]byte(`{"usage":{"input_tokens":12,"output_tokens":3}}`))))
+	assert.Equal(t,
+		`{"usage":{"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}`,
+		string(addCacheUsage([]byte(`{"usage": {}}`))))
+
+	reported := `{"usage":{"input_tokens":12,"cache_read_input_tokens":8}}`
+	assert.Equal(t, reported, string(addCacheUsage([]byte(reported))), "usage reported by vLLM is kept")
+}
+
+func TestCacheUsageWriterStream(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	cw := newCacheUsageWriter(recorder)
+
+	cw.Header().Set("Content-Type", "text/event-stream")
+	cw.WriteHeader(http.StatusOK)
+	stream := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	// Split in the middle of the usage object
+	for _, part := range []string{stream[:70], stream[70:]} {
+		_, err := cw.Write([]byte(part))
+		require.NoError(t, err)
+	}
+	require.NoError(t, cw.finish())
+
+	assert.Equal(t, strings.Replace(stream, `"usage":{`, `"usage":{"cache_creation_input_tokens":0,"cache_read_input_tokens":0,`, 1), recorder.Body.String())
+}
+
+func TestCacheUsageWriterJSON(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	cw := newCacheUsageWriter(recorder)
+
+	body := `{"type":"message","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":12,"output_tokens":1}}`
+	cw.Header().Set("Content-Type", "application/json")
+	cw.Header().Set("Content-Length", "111")
+	cw.WriteHeader(http.StatusOK)
+	_, err := cw.Write([]byte(body))
+	require.NoError(t, err)
+	assert.Zero(t, recorder.Body.Len(), "JSON responses are held until complete")
+	require.NoError(t, cw.finish())
+
+	assert.Empty(t, recorder.Header().Get("Content-Length"))
+	assert.JSONEq(t, `{"type":"message","content":[{"type":"text","text":"Hi"}],
+		"usage":{"input_tokens":12,"output_tokens":1,"cache_creation_input_tokens":0,"cache_read_input_tokens":0}}`, recorder.Body.String())
+}
@@ -0,0 +1,403 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Rate limit settings
+const (
+	rateLimitDefaultKey = "default"   // ConfigMap entry overriding the global limits
+	anonymousClient     = "anonymous" // Client ID shared by requests without an API key
+)
+
+// rateLimits are the per minute budgets of a client, 0 means unlimited
+type rateLimits struct {
+	RPM int // Requests per minute
+	TPM int // Tokens per minute, prompt and completion
+}
+
+// unlimited reports whether no budget applies
+func (l rateLimits) unlimited() bool {
+	return l.RPM == 0 && l.TPM == 0
+}
+
+// parseRateLimits reads "rpm=60,tpm=100000" on top of base, fields left out keep their base value
+func parseRateLimits(value string, base rateLimits) (rateLimits, error) {
+	limits := base
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, raw, ok := strings.Cut(field, "=")
+		if !ok {
+			return limits, fmt.Errorf("invalid limit %q (expected rpm=N or tpm=N)", field)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || n < 0 {
+			return limits, fmt.Errorf("invalid limit %q", field)
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "rpm":
+			limits.RPM = n
+		case "tpm":
+			limits.TPM = n
+		default:
+			return limits, fmt.Errorf("unknown limit %q (expected rpm or tpm)", name)
+		}
+	}
+	return limits, nil
+}
+
+// tokenBucket refills continuously at its limit per minute and holds at most one minute of budget.
+// The level goes negative when a response used more tokens than were left.
+type tokenBucket struct {
+	level   float64
+	updated time.Time
+}
+
+// refill adds the budget accrued since the last update, a new bucket starts full
+func (b *tokenBucket) refill(limit int, now time.Time) {
+	if b.updated.IsZero() {
+		b.level = float64(limit)
+	} else {
+		b.level = min(b.level+now.Sub(b.updated).Minutes()*float64(limit), float64(limit))
+	}
+	b.updated = now
+}
+
+// wait returns how long until the bucket holds one unit
+func (b *tokenBucket) wait(limit int) time.Duration {
+	if b.level >= 1 || limit == 0 {
+		return 0
+	}
+	return time.Duration((1 - b.level) / float64(limit) * float64(time.Minute))
+}
+
+// clientBuckets holds the budgets of one client
+type clientBuckets struct {
+	requests tokenBucket
+	tokens   tokenBucket
+}
+
+// rateDecision is the outcome of a rate limit check
+type rateDecision struct {
+	limits            rateLimits
+	remainingRequests int
+	remainingTokens   int
+	exceeded          string        // "requests" or "tokens" when rejected
+	retryAfter        time.Duration // Time until the exceeded budget allows a request
+}
+
+// rateLimiter enforces requests and tokens per minute budgets per API key.
+// Keys are only kept as SHA-256 digests, which is also how per-key limits are configured.
+type rateLimiter struct {
+	mu       sync.Mutex
+	base     rateLimits            // Global limits from the configuration
+	defaults rateLimits            // Global limits with the ConfigMap default entry applied
+	keys     map[string]rateLimits // Per-key limits by key digest
+	quotas   map[string]rateLimits // Tenant quotas from the API key Secret by key digest, 0 inherits defaults
+	clients  map[string]*clientBuckets
+	now      func() time.Time
+}
+
+// newRateLimiter creates a limiter applying the global limits to every client
+func newRateLimiter(global rateLimits) *rateLimiter {
+	return &rateLimiter{
+		base:     global,
+		defaults: global,
+		keys:     make(map[string]rateLimits),
+		clients:  make(map[string]*clientBuckets),
+		now:      time.Now,
+	}
+}
+
+// limitsFor returns the limits of a client, callers hold mu. ConfigMap entries take precedence
+// over tenant quotas, which take precedence over the global limits.
+func (l *rateLimiter) limitsFor(client string) rateLimits {
+	if limits, ok := l.keys[client]; ok {
+		return limits
+	}
+	limits := l.defaults
+	if quota, ok := l.quotas[client]; ok {
+		if quota.RPM > 0 {
+			limits.RPM = quota.RPM
+		}
+		if quota.TPM > 0 {
+			limits.TPM = quota.TPM
+		}
+	}
+	return limits
+}
+
+// bucketsFor returns the budgets of a client, creating them full, callers hold mu
+func (l *rateLimiter) bucketsFor(client string) *clientBuckets {
+	buckets, ok := l.clients[client]
+	if !ok {
+		buckets = &clientBuckets{}
+		l.clients[client] = buckets
+	}
+	return buckets
+}
+
+// allow checks the client's budgets and counts the request when allowed
+func (l *rateLimiter) allow(client string) rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits := l.limitsFor(client)
+	decision := rateDecision{limits: limits}
+	if limits.unlimited() {
+		return decision
+	}
+
+	buckets := l.bucketsFor(client)
+	now := l.now()
+	buckets.requests.refill(limits.RPM, now)
+	buckets.tokens.refill(limits.TPM, now)
+
+	switch {
+	case limits.RPM > 0 && buckets.requests.level < 1:
+		decision.exceeded = "requests"
+		decision.retryAfter = buckets.requests.wait(limits.RPM)
+	case limits.TPM > 0 && buckets.tokens.level < 1:
+		decision.exceeded = "tokens"
+		decision.retryAfter = buckets.tokens.wait(limits.TPM)
+	default:
+		buckets.requests.level--
+	}
+	decision.remainingRequests = max(int(buckets.requests.level), 0)
+	decision.remainingTokens = max(int(buckets.tokens.level), 0)
+	return decision
+}
+
+// charge deducts the tokens a completed request used from the client's budget
+func (l *rateLimiter) charge(client string, tokens int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limits := l.limitsFor(client)
+	if limits.TPM == 0 {
+		return
+	}
+	buckets := l.bucketsFor(client)
+	buckets.tokens.refill(limits.TPM, l.now())
+	buckets.tokens.level -= float64(tokens)
+}
+
+// configure applies the ConfigMap entries: "default" overrides the global limits and every other
+// key is the SHA-256 hex digest of an API key, or "user.<name>" for a user with per-user rate
+// limits. Invalid entries are logged and skipped.
+func (l *rateLimiter) configure(data map[string]string) {
+	defaults := l.base
+	if value, ok := data[rateLimitDefaultKey]; ok {
+		parsed, err := parseRateLimits(value, l.base)
+		if err != nil {
+			log.Printf("Ignoring invalid default rate limits: %v", err)
+		} else {
+			defaults = parsed
+		}
+	}
+
+	keys := make(map[string]rateLimits, len(data))
+	for key, value := range data {
+		if key == rateLimitDefaultKey {
+			continue
+		}
+		limits, err := parseRateLimits(value, defaults)
+		if err != nil {
+			log.Printf("Ignoring invalid rate limits for key %s: %v", key, err)
+			continue
+		}
+		keys[strings.ToLower(key)] = limits
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.defaults = defaults
+	l.keys = keys
+}
+
+// setQuotas replaces the tenant quotas
+func (l *rateLimiter) setQuotas(quotas map[string]rateLimits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quotas = quotas
+}
+
+// prune drops the buckets of clients that are back to a full budget, they start full again anyway
+func (l *rateLimiter) prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	for client, buckets := range l.clients {
+		limits := l.limitsFor(client)
+		buckets.requests.refill(limits.RPM, now)
+		buckets.tokens.refill(limits.TPM, now)
+		if buckets.requests.level >= float64(limits.RPM) && buckets.tokens.level >= float64(limits.TPM) {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// clientID identifies the caller by the digest of its API key (Authorization bearer token or x-api-key)
+func clientID(r *http.Request) string {
+	key := auth.KeyFromRequest(r)
+	if key == "" {
+		return anonymousClient
+	}
+	return auth.Digest(key)
+}
+
+// rateLimitClient returns the client whose budget a request uses: its API key, or with per-user
+// rate limits the user it declares, so users sharing a key get a budget each
+func (as *AutoScaler) rateLimitClient(r *http.Request) string {
+	if user := userFrom(r.Context()); user != "" && as.config.RateLimitPerUser {
+		return userClientPrefix + strings.ToLower(user)
+	}
+	return clientID(r)
+}
+
+// setHeaders adds the OpenAI rate limit headers
+func (d rateDecision) setHeaders(header http.Header) {
+	if d.limits.RPM > 0 {
+		header.Set("x-ratelimit-limit-requests", strconv.Itoa(d.limits.RPM))
+		header.Set("x-ratelimit-remaining-requests", strconv.Itoa(d.remainingRequests))
+	}
+	if d.limits.TPM > 0 {
+		header.Set("x-ratelimit-limit-tokens", strconv.Itoa(d.limits.TPM))
+		header.Set("x-ratelimit-remaining-tokens", strconv.Itoa(d.remainingTokens))
+	}
+}
+
+// writeRateLimited sends an OpenAI-compatible 429 with the seconds to wait in Retry-After
+func writeRateLimited(w http.ResponseWriter, d rateDecision) {
+	limit := d.limits.RPM
+	if d.exceeded == "tokens" {
+		limit = d.limits.TPM
+	}
+	seconds := max(int(math.Ceil(d.retryAfter.Seconds())), 1)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	response := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": fmt.Sprintf("Rate limit reached for %s per minute (limit: %d). Please try again in %ds.", d.exceeded, limit, seconds),
+			"type":    d.exceeded,
+			"code":    "rate_limit_exceeded",
+		},
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// usageWriter reads the token usage reported in responses, streamed or not
+type usageWriter struct {
+	gin.ResponseWriter
+	counter *usage.TokenCounter
+}
+
+// trackUsage wraps the response writer to read the token usage, once however many middlewares need
+// it. The returned function releases the counter when this call created it, after the usage is read.
+func trackUsage(c *gin.Context) (*usageWriter, func()) {
+	if uw, ok := c.Writer.(*usageWriter); ok {
+		return uw, func() {}
+	}
+	uw := &usageWriter{ResponseWriter: c.Writer, counter: usage.NewTokenCounter()}
+	c.Writer = uw
+	return uw, uw.counter.Release
+}
+
+func (uw *usageWriter) Write(b []byte) (int, error) {
+	uw.counter.Scan(b)
+	return uw.ResponseWriter.Write(b)
+}
+
+func (uw *usageWriter) WriteString(s string) (int, error) {
+	uw.counter.Scan([]byte(s))
+	return uw.ResponseWriter.WriteString(s)
+}
+
+// tokens returns the usage the response reported, or an estimate from the request and response sizes
+func (uw *usageWriter) tokens(requestSize int64) int {
+	return uw.counter.Tokens(requestSize, int64(uw.Size()))
+}
+
+// split returns the prompt and completion tokens the response reported, or estimates from the
+// request and response sizes
+func (uw *usageWriter) split(requestSize int64) (prompt, completion int) {
+	return uw.counter.Split(requestSize, int64(uw.Size()))
+}
+
+// rateLimitMiddleware enforces the rate limits on /v1 and passthrough requests and charges the
+// tokens they used
+func (as *AutoScaler) rateLimitMiddleware(c *gin.Context) {
+	r := c.Request
+	if !as.apiRequest(r) {
+		c.Next()
+		return
+	}
+
+	client := as.rateLimitClient(r)
+	decision := as.rateLimiter.allow(client)
+	decision.setHeaders(c.Writer.Header())
+	if decision.exceeded != "" {
+		log.Printf("Rate limited %s %s: %s per minute exceeded", r.Method, r.URL.Path, decision.exceeded)
+		writeRateLimited(c.Writer, decision)
+		as.metrics.RecordRequest(r.Method, r.URL.Path, http.StatusTooManyRequests, 0, 0, int64(c.Writer.Size()))
+		c.Abort()
+		return
+	}
+	if decision.limits.TPM == 0 {
+		c.Next()
+		return
+	}
+
+	uw, done := trackUsage(c)
+	defer done()
+	c.Next()
+	as.rateLimiter.charge(client, uw.tokens(r.ContentLength))
+}
+
+// startRateLimitReload reads the per-key limits from the ConfigMap now and then periodically
+func (as *AutoScaler) startRateLimitReload(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		as.reloadRateLimits(ctx)
+		as.rateLimiter.prune()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reloadRateLimits applies the rate limit ConfigMap, a missing ConfigMap leaves only the global limits
+func (as *AutoScaler) reloadRateLimits(ctx context.Context) {
+	data, err := as.k8sManager.GetConfigMapData(ctx, as.config.RateLimitConfigMap)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		log.Printf("Failed to read rate limit ConfigMap %s: %v", as.config.RateLimitConfigMap, err)
+		return
+	}
+	as.rateLimiter.configure(data)
+}
@@ -0,0 +1,209 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable time source for the rate limiter
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestRateLimiter(limits rateLimits) (*rateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	limiter := newRateLimiter(limits)
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func TestParseRateLimits(t *testing.T) {
+	base := rateLimits{RPM: 10, TPM: 1000}
+
+	limits, err := parseRateLimits("rpm=60, tpm=100000", base)
+	require.NoError(t, err)
+	assert.Equal(t, rateLimits{RPM: 60, TPM: 100000}, limits)
+
+	limits, err = parseRateLimits("TPM=0", base)
+	require.NoError(t, err)
+	assert.Equal(t, rateLimits{RPM: 10, TPM: 0}, limits, "fields left out keep their base value")
+
+	for _, invalid := range []string{"rpm", "rpm=-1", "rpm=ten", "rps=5"} {
+		_, err := parseRateLimits(invalid, base)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestRateLimiter_RequestsPerMinute(t *testing.T) {
+	limiter, clock := newTestRateLimiter(rateLimits{RPM: 2})
+
+	assert.Empty(t, limiter.allow("a").exceeded)
+	decision := limiter.allow("a")
+	assert.Empty(t, decision.exceeded)
+	assert.Equal(t, 0, decision.remainingRequests)
+
+	decision = limiter.allow("a")
+	assert.Equal(t, "requests", decision.exceeded)
+	assert.Equal(t, 30*time.Second, decision.retryAfter)
+	assert.Empty(t, limiter.allow("b").exceeded, "each key has its own budget")
+
+	clock.now = clock.now.Add(30 * time.Second)
+	assert.Empty(t, limiter.allow("a").exceeded)
+	assert.Equal(t, "requests", limiter.allow("a").exceeded)
+}
+
+func TestRateLimiter_TokensPerMinute(t *testing.T) {
+	limiter, clock := newTestRateLimiter(rateLimits{TPM: 100})
+
+	assert.Empty(t, limiter.allow("a").exceeded)
+	limiter.charge("a", 150)
+
+	decision := limiter.allow("a")
+	assert.Equal(t, "tokens", decision.exceeded)
+	assert.Equal(t, 0, decision.remainingTokens)
+	assert.InDelta(t, (30600 * time.Millisecond).Seconds(), decision.retryAfter.Seconds(), 0.001)
+
+	clock.now = clock.now.Add(31 * time.Second)
+	assert.Empty(t, limiter.allow("a").exceeded)
+}
+
+func TestRateLimiter_Configure(t *testing.T) {
+	limiter, clock := newTestRateLimiter(rateLimits{RPM: 1})
+	limiter.configure(map[string]string{
+		"default":               "tpm=500",
+		strings.Repeat("A", 64): "rpm=3",
+		strings.Repeat("b", 64): "rpm=lots",
+		strings.Repeat("c", 64): "rpm=0,tpm=0",
+	})
+
+	assert.Equal(t, rateLimits{RPM: 1, TPM: 500}, limiter.limitsFor("other"))
+	assert.Equal(t, rateLimits{RPM: 3, TPM: 500}, limiter.limitsFor(strings.Repeat("a", 64)), "digests are matched case-insensitively")
+	assert.Equal(t, rateLimits{RPM: 1, TPM: 500}, limiter.limitsFor(strings.Repeat("b", 64)), "invalid entries are skipped")
+	assert.True(t, limiter.limitsFor(strings.Repeat("c", 64)).unlimited())
+
+	limiter.allow("other")
+	limiter.prune()
+	assert.Len(t, limiter.clients, 1, "clients with spent budget are kept")
+	clock.now = clock.now.Add(time.Minute)
+	limiter.prune()
+	assert.Empty(t, limiter.clients)
+
+	limiter.configure(nil)
+	assert.Equal(t, rateLimits{RPM: 1}, limiter.limitsFor(strings.Repeat("a", 64)), "a removed ConfigMap restores the global limits")
+}
+
+func TestClientID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	assert.Equal(t, anonymousClient, clientID(r))
+
+	r.Header.Set("x-api-key", "sk-test")
+	apiKey := clientID(r)
+	assert.Len(t, apiKey, 64)
+
+	r.Header.Del("x-api-key")
+	r.Header.Set("Authorization", "Bearer sk-test")
+	assert.Equal(t, apiKey, clientID(r), "both headers identify the same key")
+}
+
+func newRateLimitedRouter(as *AutoScaler, responses ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.rateLimitMiddleware)
+	router.GET("/proxy/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.NoRoute(func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		c.Status(http.StatusOK)
+		for _, response := range responses {
+			_, _ = c.Writer.WriteString(response)
+		}
+	})
+	return router
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter, _ := newTestRateLimiter(rateLimits{RPM: 5, TPM: 100})
+	as := &AutoScaler{config: &Config{}, rateLimiter: limiter, metrics: stats.NewMetricsRecorder()}
+	// Usage split across writes is still read
+	router := newRateLimitedRouter(as, `{"usage":{"prompt_tokens":50,"total_tok`, `ens":120}}`)
+
+	send := func(path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"model":"qwen"}`))
+		r.Header.Set("Authorization", "Bearer sk-test")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	w := send("/v1/chat/completions")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "5", w.Header().Get("x-ratelimit-limit-requests"))
+	assert.Equal(t, "4", w.Header().Get("x-ratelimit-remaining-requests"))
+	assert.Equal(t, "100", w.Header().Get("x-ratelimit-limit-tokens"))
+
+	w = send("/v1/chat/completions")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "13", w.Header().Get("Retry-After"))
+
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "tokens", response.Error.Type)
+	assert.Equal(t, "rate_limit_exceeded", response.Error.Code)
+	assert.Contains(t, response.Error.Message, "limit: 100")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/proxy/status", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "only /v1 requests are limited")
+}
+
+func TestUsageWriter_Tokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		responses  []string
+		tokens     int
+		prompt     int
+		completion int
+	}{
+		{name: "openai", responses: []string{`{"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`}, tokens: 15, prompt: 10, completion: 5},
+		{name: "anthropic stream", responses: []string{
+			`event: message_start` + "\n" + `data: {"message":{"usage":{"input_tokens":25,"output_tokens":1}}}` + "\n\n",
+			`event: message_delta` + "\n" + `data: {"usage":{"output_tokens":40}}` + "\n\n",
+		}, tokens: 65, prompt: 25, completion: 40},
+		{name: "estimated", responses: []string{strings.Repeat("x", 400)}, tokens: 104, prompt: 4, completion: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			uw, done := trackUsage(c)
+			defer done()
+			for _, response := range tt.responses {
+				_, err := uw.Write([]byte(response))
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.tokens, uw.tokens(16))
+			prompt, completion := uw.split(16)
+			assert.Equal(t, tt.prompt, prompt)
+			assert.Equal(t, tt.completion, completion)
+		})
+	}
+}
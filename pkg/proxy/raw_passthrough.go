@@ -0,0 +1,167 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+)
+
+// passthroughPathList returns the paths listed in PassthroughPaths
+func (c *Config) passthroughPathList() []string {
+	var paths []string
+	for _, path := range strings.Split(c.PassthroughPaths, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// passthroughPath reports whether a path outside /v1 may be passed through to vLLM
+func (c *Config) passthroughPath(path string) bool {
+	for _, allowed := range c.passthroughPathList() {
+		if path == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(path, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// passesThrough reports whether a request is served by serveRaw: every request in raw passthrough
+// mode but the embeddings, and the passthrough paths outside the OpenAI and Anthropic APIs
+func (as *AutoScaler) passesThrough(r *http.Request) bool {
+	return (as.config.RawPassthrough && r.URL.Path != embeddingsPath) || !strings.HasPrefix(r.URL.Path, "/v1/")
+}
+
+// serveRaw proxies a request in raw passthrough mode. The model is switched and scaled up as
+// usual, then the response is copied to the client as vLLM sends it, each write flushed and its
+// trailers forwarded: no XML tool call conversion, model name rewrite, heartbeats, annotations or
+// response logging.
+func (as *AutoScaler) serveRaw(w http.ResponseWriter, r *http.Request, requestedModel, servedModel string) {
+	ctx := r.Context()
+
+	// With API keys, only requests the auth middleware let through wake the model and get the vLLM
+	// API key, probes on passthrough paths included
+	if as.apiKeys != nil && auth.TenantFrom(ctx) == nil {
+		log.Printf("Rejected %s %s: no API key", r.Method, r.URL.Path)
+		writeAPIError(w, http.StatusUnauthorized, "Incorrect API key provided.", "invalid_request_error", "invalid_api_key")
+		return
+	}
+
+	// vLLM only knows the served name, responses keep it
+	if servedModel != requestedModel {
+		if err := rewriteRequestModel(r, servedModel); err != nil {
+			log.Printf("Failed to rewrite model %s to served name %s: %v", requestedModel, servedModel, err)
+		} else {
+			requestedModel = servedModel
+		}
+	}
+
+	pinned := pinnedToActiveModel(r)
+	if requestedModel != "" && requestedModel != as.GetActiveModel() && (pinned || !as.config.switchAllowed(requestedModel)) {
+		as.refuseModelSwitch(ctx, w, requestedModel, pinned)
+		return
+	}
+	var sessionErr *SessionActiveError
+	if err := as.checkSessionAffinity(ctx, requestedModel); errors.As(err, &sessionErr) {
+		log.Printf("Refused model %s: %v", requestedModel, err)
+		writeSessionActive(w, sessionErr)
+		return
+	}
+
+	as.updateActivity()
+	if requestedModel != "" {
+		if err := as.handleModelSwitch(ctx, requestedModel); err != nil {
+			var notFound *ModelNotFoundError
+			if errors.As(err, &notFound) {
+				log.Printf("Model not found: %s, returning available models", notFound.RequestedModel)
+				as.returnAvailableModels(ctx, w, notFound.RequestedModel)
+				return
+			}
+			var inProgress *ModelSwitchInProgressError
+			if errors.As(err, &inProgress) {
+				log.Printf("Refused model %s: %v", requestedModel, err)
+				writeSwitchInProgress(w, inProgress)
+				return
+			}
+			log.Printf("Failed to switch model to %s: %v", requestedModel, err)
+			writeAPIError(w, http.StatusServiceUnavailable,
+				fmt.Sprintf("Failed to switch to model %s: %v", requestedModel, err),
+				"model_switch_error", "model_unavailable")
+			return
+		}
+	}
+	as.touchSession(ctx)
+	defer as.touchSession(ctx)
+
+	scaleCtx, coldStart := withColdStartWait(withSwitchRequest(ctx, pinned), as.metrics, as.GetActiveModel())
+	err := as.ensureScaledUp(scaleCtx)
+	coldStart.done()
+	if err != nil {
+		log.Printf("Failed to scale up: %v", err)
+		var switchErr *SwitchNotAllowedError
+		if errors.As(err, &switchErr) {
+			writeSwitchNotAllowed(w, switchErr)
+			return
+		}
+		var gpuErr *GPUNotReadyError
+		if errors.As(err, &gpuErr) {
+			w.Header().Set("Retry-After", "30")
+			writeAPIError(w, http.StatusServiceUnavailable,
+				"The GPU driver is not ready yet, the node is probably starting up. Please retry in a few moments.",
+				"service_unavailable", gpuDriverNotReady)
+			return
+		}
+		w.Header().Set("Retry-After", "10")
+		writeAPIError(w, http.StatusServiceUnavailable,
+			"Service is starting up. Please wait and retry in a few moments.",
+			"service_unavailable", "scaling_up")
+		return
+	}
+
+	var sticky string
+	if as.config.StickySessions {
+		sticky = sessionID(r)
+	}
+	target, release := as.acquireReplica(sticky)
+	defer release()
+	if target == nil {
+		target = as.targetURL
+	}
+	model := requestedModel
+	if model == "" {
+		model = as.GetActiveModel()
+	}
+
+	// Stalled or overlong streams are cut, without an error event
+	streamCtx, cancelStream := context.WithCancel(ctx)
+	defer cancelStream()
+	r = r.WithContext(streamCtx)
+	maxStreamDuration := as.modelOptionsFor(ctx, model).maxStreamDuration
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.Transport = as.vllmTransport
+	authorizeUpstream(proxy, as.vllmAPIKey(ctx, model))
+	proxy.FlushInterval = -1 // Flush after each write, whatever the content type
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		limitStream(resp, cancelStream, as.config.GetStreamIdleTimeout(), maxStreamDuration)
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, _ *http.Request, err error) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeRequestTooLarge(w, maxBytesErr.Limit)
+			return
+		}
+		log.Printf("Proxy error: %v", err)
+		http.Error(w, "Bad Gateway", http.StatusBadGateway)
+	}
+
+	accountFrom(ctx).serve(model, as.config.GPUCount)
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,252 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// xmlToolCallStream is a streamed chat completion whose tool call vLLM left as XML content
+const xmlToolCallStream = `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"qwen","choices":[{"index":0,"delta":{"role":"assistant","content":"<tool_call>\n<function=ls>\n"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"qwen","choices":[{"index":0,"delta":{"content":"<parameter=path>.</parameter>\n</function>\n</tool_call>"},"finish_reason":null}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","model":"qwen","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+// serveXMLToolCallStream answers as a vLLM without a tool call parser
+func serveXMLToolCallStream(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	_, _ = w.Write([]byte(xmlToolCallStream))
+}
+
+// newReadyAutoScaler returns an autoscaler whose qwen pod is ready and served by target
+func newReadyAutoScaler(t testing.TB, target string, raw bool) *AutoScaler {
+	t.Helper()
+	targetURL, err := url.Parse(target)
+	require.NoError(t, err)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "vllm"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	as := &AutoScaler{
+		config:       &Config{Namespace: "vllm", Deployment: "vllm", RawPassthrough: raw, PassthroughPaths: defaultPassthroughPaths},
+		crdClient:    newFakeCRDClient(t, replicaModelSpec(0, 1)),
+		k8sManager:   kubernetes.NewK8sManager(fake.NewSimpleClientset(pod), &kubernetes.Config{Namespace: "vllm", Deployment: "vllm"}),
+		targetURL:    targetURL,
+		activeModel:  "qwen",
+		lastActivity: time.Now(),
+		metrics:      stats.NewMetricsRecorder(),
+		parserCheck:  newToolParserDetector(nil),
+	}
+	as.runner = as.k8sManager
+	as.scaleUpCond = sync.NewCond(&as.mu)
+	as.strategy = &deleteStrategy{as: as}
+	return as
+}
+
+func TestProxyHandler_RawPassthrough(t *testing.T) {
+	var forwarded string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		forwarded = string(body)
+		serveXMLToolCallStream(w)
+	}))
+	defer upstream.Close()
+
+	for _, raw := range []bool{false, true} {
+		as := newReadyAutoScaler(t, upstream.URL, raw)
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8","stream":true,"messages":[]}`))
+		w := httptest.NewRecorder()
+
+		as.proxyHandler(w, r)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, forwarded, `"model":"qwen"`, "the request names the served model")
+		if raw {
+			assert.Equal(t, xmlToolCallStream, w.Body.String(), "raw passthrough leaves the response untouched")
+		} else {
+			assert.Contains(t, w.Body.String(), `"tool_calls"`)
+			assert.Contains(t, w.Body.String(), `"model":"Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8"`)
+		}
+	}
+}
+
+func TestProxyHandler_RawPassthroughUnknownModel(t *testing.T) {
+	as := newReadyAutoScaler(t, "http://127.0.0.1:1", true)
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"llama"}`))
+	w := httptest.NewRecorder()
+
+	as.proxyHandler(w, r)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "qwen", "the available models are listed")
+}
+
+// BenchmarkProxyHandler compares the default pipeline to raw passthrough on a streamed chat
+// completion holding an XML tool call, the vLLM pod being ready
+func BenchmarkProxyHandler(b *testing.B) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		serveXMLToolCallStream(w)
+	}))
+	defer upstream.Close()
+	body := `{"model":"qwen","stream":true,"messages":[{"role":"user","content":"` + strings.Repeat("a", 4096) + `"}]}`
+
+	for _, mode := range []struct {
+		name string
+		raw  bool
+	}{{"default", false}, {"raw", true}} {
+		b.Run(mode.name, func(b *testing.B) {
+			as := newReadyAutoScaler(b, upstream.URL, mode.raw)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+				as.proxyHandler(httptest.NewRecorder(), r)
+			}
+		})
+	}
+}
+
+func TestProxyHandler_GRPCOverH2C(t *testing.T) {
+	h2c := new(http.Protocols)
+	h2c.SetUnencryptedHTTP2(true)
+
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor, "vLLM is reached over HTTP/2")
+		assert.Equal(t, "trailers", r.Header.Get("TE"))
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		_, _ = w.Write(body)
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	upstream.Config.Protocols = h2c
+	upstream.Start()
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.config.VLLMH2C = true
+	as.config.PassthroughPaths = "/grpc.health.v1.Health/"
+	var err error
+	as.vllmTransport, err = as.newVLLMTransport(context.Background())
+	require.NoError(t, err)
+
+	proxy := httptest.NewUnstartedServer(http.HandlerFunc(as.proxyHandler))
+	proxy.Config.Protocols = serverProtocols()
+	proxy.Start()
+	defer proxy.Close()
+
+	client := &http.Client{Transport: &http.Transport{Protocols: h2c}}
+	r, err := http.NewRequest(http.MethodPost, proxy.URL+"/grpc.health.v1.Health/Check", strings.NewReader("\x00\x00\x00\x00\x00"))
+	require.NoError(t, err)
+	r.Header.Set("Content-Type", "application/grpc")
+	r.Header.Set("TE", "trailers")
+	resp, err := client.Do(r)
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.ProtoMajor, "clients reach the proxy over h2c")
+	assert.Equal(t, "\x00\x00\x00\x00\x00", string(body))
+	assert.Equal(t, "0", resp.Trailer.Get("Grpc-Status"), "trailers are forwarded")
+}
+
+func TestProxyHandler_PassesThroughOtherPaths(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tokenize", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count":3,"tokens":[1,2,3]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.config.ResponseAnnotations = true
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodPost, "/tokenize", strings.NewReader(`{"model":"qwen","prompt":"hi"}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"count":3,"tokens":[1,2,3]}`, w.Body.String(), "responses outside /v1 are not annotated")
+}
+
+func TestProxyHandler_RejectsUnlistedPaths(t *testing.T) {
+	var hits int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.activeModel = "deepseek"
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodPost, "/start_profile", strings.NewReader(`{"model":"qwen"}`)))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"unknown_path"`)
+	assert.Zero(t, hits)
+	assert.Equal(t, "deepseek", as.GetActiveModel(), "unlisted paths don't switch the model")
+}
+
+func TestPassthroughPaths_RequireAPIKey(t *testing.T) {
+	var authorization []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"count":3,"tokens":[1,2,3]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.vllmAPIKeys.Store(kubernetes.VLLMAPIKeySecret, cachedAPIKey{key: "sk-vllm", read: time.Now()})
+	as.apiKeys = auth.NewKeyStore()
+	require.NoError(t, as.apiKeys.Load(map[string][]byte{"team-a": []byte(`{"key":"sk-a"}`)}))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.authMiddleware)
+	router.NoRoute(as.ginProxyHandler)
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/tokenize", strings.NewReader(`{"model":"qwen","prompt":"hi"}`))
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "sk-unknown").Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodHead, "").Code, "probes skip the middlewares but not the key check")
+	assert.Empty(t, authorization, "vLLM is not reached without a valid key")
+
+	w := send(http.MethodPost, "sk-a")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Bearer sk-vllm"}, authorization)
+}
@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// minRedactedBlob is the length from which base64 strings are replaced in logs, shorter ones being
+// more likely IDs or signatures than images
+const minRedactedBlob = 1024
+
+var (
+	// base64String matches a JSON string holding only base64, e.g. the data of an Anthropic image block
+	// or an embedding in base64 encoding
+	base64String = regexp.MustCompile(`"[A-Za-z0-9+/]+={0,2}"`)
+
+	// base64DataURL matches the payload of a data URL, e.g. an OpenAI image_url
+	base64DataURL = regexp.MustCompile(`(data:[\w.+-]+/[\w.+-]+;base64,)[A-Za-z0-9+/]+={0,2}`)
+)
+
+// redactBlobs replaces the base64 blobs of a JSON body with their size, so logging a multimodal
+// request or response doesn't write megabytes of image data. The body itself is never modified.
+func redactBlobs(body []byte) []byte {
+	body = base64DataURL.ReplaceAllFunc(body, func(match []byte) []byte {
+		prefix := base64DataURL.FindSubmatch(match)[1]
+		if len(match)-len(prefix) < minRedactedBlob {
+			return match
+		}
+		return fmt.Appendf(nil, "%s[%d bytes]", prefix, len(match)-len(prefix))
+	})
+	return base64String.ReplaceAllFunc(body, func(match []byte) []byte {
+		if len(match)-2 < minRedactedBlob {
+			return match
+		}
+		return fmt.Appendf(nil, `"[base64, %d bytes]"`, len(match)-2)
+	})
+}
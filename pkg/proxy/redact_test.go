@@ -0,0 +1,45 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactBlobs(t *testing.T) {
+	image := strings.Repeat("iVBORw0K", 512) + "=="
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			name: "anthropic image block",
+			body: `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"` + image + `"}}`,
+			want: `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"[base64, 4098 bytes]"}}`,
+		},
+		{
+			name: "openai data url",
+			body: `{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,` + image + `"}}`,
+			want: `{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,[4098 bytes]"}}`,
+		},
+		{
+			name: "short strings are kept",
+			body: `{"signature":"c2lnbmF0dXJl","text":"Describe this image"}`,
+			want: `{"signature":"c2lnbmF0dXJl","text":"Describe this image"}`,
+		},
+		{
+			name: "long text is kept",
+			body: `{"text":"` + strings.Repeat("word ", 300) + `"}`,
+			want: `{"text":"` + strings.Repeat("word ", 300) + `"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, string(redactBlobs([]byte(tt.body))))
+		})
+	}
+}
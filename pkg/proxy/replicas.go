@@ -0,0 +1,311 @@
+package proxy
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+// replicaPool tracks in-flight requests and balances them across the ready vLLM pods.
+// The zero value proxies everything through the service until endpoints are known.
+type replicaPool struct {
+	mu        sync.Mutex
+	inFlight  int            // Requests currently proxied to vLLM
+	peak      int            // Highest in-flight count since the last scaling decision
+	replicas  int            // Pods running the active model, primary included
+	capacity  int            // Concurrent sequences one pod serves (maxNumSeqs)
+	endpoints []*url.URL     // Ready vLLM pods
+	load      map[string]int // In-flight requests per endpoint
+	lowSince  time.Time      // When the load first fitted in fewer replicas
+}
+
+// acquire records a new in-flight request and returns the ready pod to send it to, or nil to
+// use the service when fewer than two pods are ready. Requests with a sticky key always go to
+// the same pod while the ready pods are unchanged, the others to the least loaded pod.
+// release must be called when done.
+func (p *replicaPool) acquire(sticky string) (target *url.URL, release func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.inFlight++
+	p.peak = max(p.peak, p.inFlight)
+
+	if len(p.endpoints) > 1 {
+		if sticky != "" {
+			target = rendezvous(sticky, p.endpoints)
+		} else {
+			target = p.endpoints[0]
+			for _, endpoint := range p.endpoints[1:] {
+				if p.load[endpoint.String()] < p.load[target.String()] {
+					target = endpoint
+				}
+			}
+		}
+		p.load[target.String()]++
+	}
+
+	return target, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.inFlight--
+		if target != nil && p.load[target.String()] > 0 {
+			p.load[target.String()]--
+		}
+	}
+}
+
+// rendezvous picks the endpoint with the highest hash for the key (highest random weight hashing):
+// when pods are added or removed, only the keys of the pods that changed move
+func rendezvous(key string, endpoints []*url.URL) *url.URL {
+	var best *url.URL
+	var bestScore uint64
+	for _, endpoint := range endpoints {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(endpoint.String()))
+		if score := h.Sum64(); best == nil || score > bestScore {
+			best, bestScore = endpoint, score
+		}
+	}
+	return best
+}
+
+// setEndpoints replaces the ready pods, keeping the load of pods still ready
+func (p *replicaPool) setEndpoints(endpoints []string) {
+	parsed := make([]*url.URL, 0, len(endpoints))
+	load := make(map[string]int, len(endpoints))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, endpoint := range endpoints {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			log.Printf("Ignoring invalid endpoint %s: %v", endpoint, err)
+			continue
+		}
+		parsed = append(parsed, u)
+		load[endpoint] = p.load[endpoint]
+	}
+	p.endpoints = parsed
+	p.load = load
+}
+
+// targets returns the ready pods, or the fallback (the service) when none are known
+func (p *replicaPool) targets(fallback *url.URL) []*url.URL {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.endpoints) == 0 {
+		return []*url.URL{fallback}
+	}
+	return append([]*url.URL(nil), p.endpoints...)
+}
+
+// takePeak returns the peak in-flight count since the last call and starts a new interval
+func (p *replicaPool) takePeak() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	peak := p.peak
+	p.peak = p.inFlight
+	return peak
+}
+
+// inFlightCount returns the requests currently proxied to vLLM
+func (p *replicaPool) inFlightCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inFlight
+}
+
+// queueDepth returns the in-flight requests beyond what the running replicas serve concurrently
+func (p *replicaPool) queueDepth() int {
+	if p.capacity == 0 {
+		return 0
+	}
+	return max(p.inFlight-p.replicas*p.capacity, 0)
+}
+
+// setReplicas records the pods running the active model
+func (p *replicaPool) setReplicas(replicas int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replicas = replicas
+}
+
+// snapshot returns the counters exposed in /proxy/status
+func (p *replicaPool) snapshot() map[string]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return map[string]int{
+		"replicas":        p.replicas,
+		"ready_endpoints": len(p.endpoints),
+		"in_flight":       p.inFlight,
+		"queue_depth":     p.queueDepth(),
+	}
+}
+
+// desiredReplicas returns the pods needed to serve peak concurrent requests, within the model bounds
+func desiredReplicas(peak, capacity, minReplicas, maxReplicas int) int {
+	desired := 1
+	if capacity > 0 {
+		desired = (peak + capacity - 1) / capacity
+	}
+	return min(max(desired, minReplicas, 1), maxReplicas)
+}
+
+// startReplicaScaler watches ready pods for load balancing and scales replicas with load
+func (as *AutoScaler) startReplicaScaler(ctx context.Context) {
+	if as.k8sManager == nil {
+		return // Replicas are pods, the docker and process backends run a single vLLM
+	}
+	if err := as.k8sManager.WatchReadyEndpoints(ctx, as.replicas.setEndpoints); err != nil {
+		log.Printf("Failed to watch vLLM endpoints, proxying through the service: %v", err)
+	}
+
+	ticker := time.NewTicker(defaultCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			as.scaleReplicas(ctx)
+		}
+	}
+}
+
+// scaleReplicas adds replicas as soon as the peak load needs them and removes them one at a
+// time once the load has fitted in fewer replicas for the idle timeout
+func (as *AutoScaler) scaleReplicas(ctx context.Context) {
+	peak := as.replicas.takePeak()
+	if !as.leading() {
+		return
+	}
+
+	up, err := as.strategy.isUp(ctx)
+	if err != nil {
+		return
+	}
+	if !up {
+		as.replicas.setReplicas(0)
+		return
+	}
+
+	modelConfig, err := as.crdClient.GetModel(ctx, as.GetActiveModel())
+	if err != nil {
+		log.Printf("Failed to get model config for replica scaling: %v", err)
+		return
+	}
+	minReplicas, maxReplicas := modelConfig.ReplicaBounds()
+
+	indexes, err := as.k8sManager.ListReplicas(ctx)
+	if err != nil {
+		log.Printf("Failed to list replicas: %v", err)
+		return
+	}
+	current := len(indexes) + 1
+	as.replicas.setReplicas(current)
+	if maxReplicas <= 1 && len(indexes) == 0 {
+		return
+	}
+
+	capacity, _ := strconv.Atoi(modelConfig.MaxNumSeqs)
+	desired := desiredReplicas(peak, capacity, minReplicas, maxReplicas)
+
+	as.replicas.mu.Lock()
+	as.replicas.capacity = capacity
+	switch {
+	case desired >= current:
+		as.replicas.lowSince = time.Time{}
+	case as.replicas.lowSince.IsZero():
+		as.replicas.lowSince = time.Now()
+	}
+	lowSince := as.replicas.lowSince
+	as.replicas.mu.Unlock()
+
+	if desired > current {
+		as.addReplicas(ctx, modelConfig, indexes, desired)
+	} else if desired < current && time.Since(lowSince) >= as.config.GetIdleTimeout() {
+		last := indexes[len(indexes)-1]
+		log.Printf("Load fits in %d replica(s) (peak %d in flight), removing replica %d", desired, peak, last)
+		if err := as.k8sManager.DeleteReplica(ctx, last); err != nil {
+			log.Printf("Failed to delete replica %d: %v", last, err)
+			return
+		}
+		as.replicas.mu.Lock()
+		as.replicas.lowSince = time.Now()
+		as.replicas.mu.Unlock()
+		as.replicas.setReplicas(current - 1)
+		as.metrics.UpdateReplicas(int32(current - 1))
+	}
+}
+
+// addReplicas creates the replicas missing to reach desired pods, reusing free indexes
+func (as *AutoScaler) addReplicas(ctx context.Context, modelConfig *kubernetes.ModelConfig, indexes []int, desired int) {
+	existing := make(map[int]bool, len(indexes))
+	for _, index := range indexes {
+		existing[index] = true
+	}
+
+	log.Printf("Scaling %s from %d to %d replica(s)", modelConfig.ServedModelName, len(indexes)+1, desired)
+	for index := 1; index < desired; index++ {
+		if existing[index] {
+			continue
+		}
+		if err := as.k8sManager.CreateReplica(ctx, modelConfig, index); err != nil {
+			log.Printf("Failed to create replica %d: %v", index, err)
+			continue
+		}
+		existing[index] = true
+	}
+	as.replicas.setReplicas(len(existing) + 1)
+	as.metrics.UpdateReplicas(int32(len(existing) + 1))
+}
+
+// removeReplicas deletes every additional replica when the model scales to zero or switches,
+// leaving the primary pod to the scale strategy
+func (as *AutoScaler) removeReplicas(ctx context.Context) {
+	if as.k8sManager == nil {
+		return
+	}
+	indexes, err := as.k8sManager.ListReplicas(ctx)
+	if err != nil {
+		log.Printf("Failed to list replicas: %v", err)
+		return
+	}
+	for _, index := range indexes {
+		if err := as.k8sManager.DeleteReplica(ctx, index); err != nil {
+			log.Printf("Failed to delete replica %d: %v", index, err)
+		}
+	}
+}
+
+// keepsWarm reports whether the active model sets minReplicas, so it is never scaled to zero
+func (as *AutoScaler) keepsWarm(ctx context.Context) bool {
+	modelConfig, err := as.crdClient.GetModel(ctx, as.GetActiveModel())
+	if err != nil {
+		return false
+	}
+	minReplicas, _ := modelConfig.ReplicaBounds()
+	return minReplicas >= 1
+}
+
+// acquireReplica records a proxied request like replicaPool.acquire, and counts its end as
+// activity: the idle timeout runs from the end of the response rather than its arrival, so a
+// long stream isn't released right after it completes
+func (as *AutoScaler) acquireReplica(sticky string) (target *url.URL, release func()) {
+	target, done := as.replicas.acquire(sticky)
+	as.metrics.AddInFlightRequests(1)
+	return target, func() {
+		done()
+		as.metrics.AddInFlightRequests(-1)
+		as.updateActivity()
+	}
+}
@@ -0,0 +1,186 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func replicaModelSpec(minReplicas, maxReplicas int64) map[string]interface{} {
+	return map[string]interface{}{
+		"modelName":              "Qwen/Qwen3-Coder-30B-A3B-Instruct-FP8",
+		"servedModelName":        "qwen",
+		"maxModelLen":            int64(65536),
+		"maxNumBatchedTokens":    int64(8192),
+		"maxNumSeqs":             int64(2),
+		"gpuMemoryUtilization":   0.9,
+		"dtype":                  "auto",
+		"enableChunkedPrefill":   true,
+		"disableCustomAllReduce": false,
+		"enablePrefixCaching":    true,
+		"enableAutoToolChoice":   true,
+		"minReplicas":            minReplicas,
+		"maxReplicas":            maxReplicas,
+	}
+}
+
+func replicaPodNames(t *testing.T, clientset *fake.Clientset) []string {
+	t.Helper()
+	pods, err := clientset.CoreV1().Pods("vllm").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	names := make([]string, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestReplicaPool_BalancesAcrossReadyPods(t *testing.T) {
+	var pool replicaPool
+
+	target, release := pool.acquire("")
+	assert.Nil(t, target, "the service is used until several pods are ready")
+	release()
+
+	pool.setEndpoints([]string{"http://10.0.0.1:8000", "http://10.0.0.2:8000"})
+	first, releaseFirst := pool.acquire("")
+	second, releaseSecond := pool.acquire("")
+	require.NotNil(t, first)
+	require.NotNil(t, second)
+	assert.NotEqual(t, first.String(), second.String())
+	assert.Equal(t, 2, pool.snapshot()["in_flight"])
+
+	releaseFirst()
+	third, releaseThird := pool.acquire("")
+	assert.Equal(t, first.String(), third.String(), "the least loaded pod is picked")
+
+	releaseSecond()
+	releaseThird()
+	assert.Equal(t, 0, pool.snapshot()["in_flight"])
+	assert.Equal(t, 2, pool.takePeak())
+	assert.Equal(t, 0, pool.takePeak())
+}
+
+func TestReplicaPool_StickySessions(t *testing.T) {
+	var pool replicaPool
+	pool.setEndpoints([]string{"http://10.0.0.1:8000", "http://10.0.0.2:8000", "http://10.0.0.3:8000"})
+
+	route := func(session string) string {
+		target, release := pool.acquire(session)
+		defer release()
+		require.NotNil(t, target)
+		return target.String()
+	}
+
+	before := make(map[string]string)
+	used := make(map[string]bool)
+	for i := range 30 {
+		session := fmt.Sprintf("conversation-%d", i)
+		before[session] = route(session)
+		used[before[session]] = true
+		assert.Equal(t, before[session], route(session), "a session keeps its pod")
+	}
+	assert.Len(t, used, 3, "sessions are spread across pods")
+
+	// Removing a pod only moves its own sessions
+	pool.setEndpoints([]string{"http://10.0.0.1:8000", "http://10.0.0.3:8000"})
+	for session, target := range before {
+		if target == "http://10.0.0.2:8000" {
+			assert.NotEqual(t, target, route(session))
+		} else {
+			assert.Equal(t, target, route(session), session)
+		}
+	}
+	assert.Equal(t, 0, pool.snapshot()["in_flight"])
+}
+
+func TestDesiredReplicas(t *testing.T) {
+	assert.Equal(t, 1, desiredReplicas(0, 4, 0, 3))
+	assert.Equal(t, 1, desiredReplicas(4, 4, 0, 3))
+	assert.Equal(t, 2, desiredReplicas(5, 4, 0, 3))
+	assert.Equal(t, 3, desiredReplicas(100, 4, 0, 3))
+	assert.Equal(t, 2, desiredReplicas(0, 4, 2, 3))
+	assert.Equal(t, 1, desiredReplicas(10, 0, 0, 3))
+}
+
+func TestScaleReplicas_FollowsLoad(t *testing.T) {
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, vllmPod())
+	as.activeModel = "qwen"
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(0, 3))
+	ctx := context.Background()
+
+	releases := make([]func(), 0, 5)
+	for range 5 {
+		_, release := as.replicas.acquire("")
+		releases = append(releases, release)
+	}
+
+	as.scaleReplicas(ctx)
+	assert.ElementsMatch(t, []string{"vllm", "vllm-1", "vllm-2"}, replicaPodNames(t, clientset))
+	assert.Equal(t, 3, as.replicas.snapshot()["replicas"])
+
+	for _, release := range releases {
+		release()
+	}
+
+	// The peak of the previous interval still counts, replicas are kept
+	as.scaleReplicas(ctx)
+	assert.Len(t, replicaPodNames(t, clientset), 3)
+
+	// Replicas are removed one at a time once idle
+	as.scaleReplicas(ctx)
+	assert.ElementsMatch(t, []string{"vllm", "vllm-1"}, replicaPodNames(t, clientset))
+	as.scaleReplicas(ctx)
+	assert.ElementsMatch(t, []string{"vllm"}, replicaPodNames(t, clientset))
+	assert.Equal(t, 1, as.replicas.snapshot()["replicas"])
+}
+
+func TestCheckIdleWithRequestsInFlight(t *testing.T) {
+	now := time.Now()
+	as, clientset := scheduleTestAutoScaler(t, now, time.Hour, vllmPod())
+
+	// A stream that started an hour ago is still generating
+	_, release := as.acquireReplica("")
+	as.checkIdle(context.Background(), now)
+	assert.True(t, podExists(t, clientset))
+
+	// Its end counts as activity, the idle timeout starts over
+	release()
+	as.checkIdle(context.Background(), time.Now())
+	assert.True(t, podExists(t, clientset))
+	as.checkIdle(context.Background(), time.Now().Add(10*time.Minute))
+	assert.False(t, podExists(t, clientset))
+}
+
+func TestScaleReplicas_RemovedWithPrimaryPod(t *testing.T) {
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, vllmPod())
+	as.activeModel = "qwen"
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(0, 2))
+	ctx := context.Background()
+
+	_, release := as.replicas.acquire("")
+	_, releaseOther := as.replicas.acquire("")
+	_, releaseLast := as.replicas.acquire("")
+	as.scaleReplicas(ctx)
+	release()
+	releaseOther()
+	releaseLast()
+	require.ElementsMatch(t, []string{"vllm", "vllm-1"}, replicaPodNames(t, clientset))
+
+	require.NoError(t, as.managePod(ctx, false))
+	assert.Empty(t, replicaPodNames(t, clientset))
+}
+
+func TestKeepsWarm(t *testing.T) {
+	as := &AutoScaler{activeModel: "qwen", crdClient: newFakeCRDClient(t, replicaModelSpec(1, 2))}
+	assert.True(t, as.keepsWarm(context.Background()))
+
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(0, 2))
+	assert.False(t, as.keepsWarm(context.Background()))
+}
@@ -0,0 +1,151 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// modelPeekLimit bounds how much of a request body is read to find the model field
+const modelPeekLimit = 1 << 20
+
+// peekModel reads body up to its top-level model field, at most limit bytes, and returns the
+// model with every byte consumed so the body can be replayed. Other fields are skipped as they
+// stream by instead of buffering the whole request.
+func peekModel(body io.Reader, limit int64) (model string, consumed []byte) {
+	var buf bytes.Buffer
+	decoder := json.NewDecoder(io.TeeReader(io.LimitReader(body, limit), &buf))
+	model, _ = findModelField(decoder)
+	return model, buf.Bytes()
+}
+
+// findModelField walks the keys of the top-level JSON object until the model field
+func findModelField(decoder *json.Decoder) (string, error) {
+	token, err := decoder.Token()
+	if err != nil || token != json.Delim('{') {
+		return "", err
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if key, _ := token.(string); key == "model" {
+			var model string
+			err := decoder.Decode(&model)
+			return model, err
+		}
+
+		var skipped json.RawMessage
+		if err := decoder.Decode(&skipped); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// replayBody returns a body that yields the consumed bytes, then the rest of body
+func replayBody(consumed []byte, body io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(consumed), body), body}
+}
+
+// requestBody is the body of a request read once, with its top-level fields parsed, for the steps
+// of the proxy inspecting or rewriting it
+type requestBody struct {
+	data   []byte
+	fields map[string]json.RawMessage // Top-level fields, nil when the body isn't a JSON object
+	err    error                      // Why the body isn't a JSON object
+}
+
+// requestBodyKey stores the *requestBody of a request in its context
+type requestBodyKey struct{}
+
+// newRequestBody parses the top-level fields of a body
+func newRequestBody(data []byte) *requestBody {
+	body := &requestBody{data: data}
+	if body.err = json.Unmarshal(data, &body.fields); body.err == nil && body.fields == nil {
+		body.err = errors.New("the body is null")
+	}
+	if body.err != nil {
+		body.fields = nil
+	}
+	return body
+}
+
+// withRequestBody reads and parses the body of r, stored on the returned request's context for the
+// steps inspecting it. The body stays readable for vLLM.
+func withRequestBody(r *http.Request) (*http.Request, error) {
+	body, err := parsedBody(r)
+	if err != nil {
+		return r, err
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestBodyKey{}, body)), nil
+}
+
+// parsedBody returns the body of r parsed by withRequestBody, or reads and parses it when the
+// request has none, keeping it readable
+func parsedBody(r *http.Request) (*requestBody, error) {
+	if body, ok := r.Context().Value(requestBodyKey{}).(*requestBody); ok {
+		return body, nil
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = newBodyReaderFromBytes(data)
+	return newRequestBody(data), nil
+}
+
+// object returns the top-level fields of the body, an error when it isn't a JSON object
+func (b *requestBody) object() (map[string]json.RawMessage, error) {
+	if b.err != nil {
+		return nil, fmt.Errorf("request body is not a JSON object: %w", b.err)
+	}
+	return b.fields, nil
+}
+
+// streamed reports whether the body asks for a streamed response
+func (b *requestBody) streamed() bool {
+	var stream bool
+	_ = json.Unmarshal(b.fields["stream"], &stream)
+	return stream
+}
+
+// rewrite encodes the fields again as the body of r, once a step changed them
+func (b *requestBody) rewrite(r *http.Request) error {
+	data, err := json.Marshal(b.fields)
+	if err != nil {
+		return err
+	}
+	b.data = data
+	r.Body = newBodyReaderFromBytes(data)
+	r.ContentLength = int64(len(data))
+	r.Header.Set("Content-Length", strconv.Itoa(len(data)))
+	return nil
+}
+
+// writeRequestTooLarge sends an OpenAI-style 413 error
+func writeRequestTooLarge(w http.ResponseWriter, limit int64) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusRequestEntityTooLarge)
+	response := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": fmt.Sprintf("Request body exceeds the maximum size of %d bytes", limit),
+			"type":    "invalid_request_error",
+			"code":    "request_too_large",
+		},
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeekModel(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		limit    int64
+		expected string
+	}{
+		{name: "model first", body: `{"model":"qwen","messages":[]}`, limit: 1024, expected: "qwen"},
+		{name: "model after messages", body: `{"messages":[{"role":"user","content":"{\"model\":\"nested\"}"}],"model":"qwen"}`, limit: 1024, expected: "qwen"},
+		{name: "model beyond the limit", body: `{"messages":[{"role":"user","content":"` + strings.Repeat("a", 100) + `"}],"model":"qwen"}`, limit: 64, expected: ""},
+		{name: "non-string model", body: `{"model":42}`, limit: 1024, expected: ""},
+		{name: "not an object", body: `["model","qwen"]`, limit: 1024, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := io.NopCloser(strings.NewReader(tt.body))
+			model, consumed := peekModel(body, tt.limit)
+			assert.Equal(t, tt.expected, model)
+
+			replayed, err := io.ReadAll(replayBody(consumed, body))
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(replayed))
+		})
+	}
+}
+
+func TestPeekModel_ReadsOnlyUpToModel(t *testing.T) {
+	rest := strings.Repeat(`{"role":"user","content":"hello"},`, 10000)
+	reader := strings.NewReader(`{"model":"qwen","messages":[` + rest + `{}]}`)
+
+	model, consumed := peekModel(reader, modelPeekLimit)
+
+	assert.Equal(t, "qwen", model)
+	assert.Less(t, len(consumed), 64*1024)
+	assert.Positive(t, reader.Len())
+}
+
+func TestRequestBody_SharedBySteps(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","max_tokens":100000,"tools":[],"tool_choice":"none"}`))
+	r, err := withRequestBody(r)
+	require.NoError(t, err)
+	body, err := parsedBody(r)
+	require.NoError(t, err)
+	again, err := parsedBody(r)
+	require.NoError(t, err)
+	assert.Same(t, body, again, "the steps share the body read once")
+
+	capped, err := capMaxTokens(r, 1024)
+	require.NoError(t, err)
+	require.True(t, capped)
+	dropped, err := dropForbiddenTools(r)
+	require.NoError(t, err)
+	require.True(t, dropped)
+
+	assert.JSONEq(t, `{"model":"qwen","max_tokens":1024}`, string(body.data), "each step sees the rewrites of the previous ones")
+	replayed, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, string(body.data), string(replayed))
+	assert.Equal(t, int64(len(replayed)), r.ContentLength)
+
+	for _, data := range []string{`null`, `[]`, `not json`} {
+		_, err := newRequestBody([]byte(data)).object()
+		assert.ErrorContains(t, err, "request body is not a JSON object", data)
+	}
+}
+
+func TestProxyHandler_RejectsLargeBodies(t *testing.T) {
+	as := &AutoScaler{config: &Config{MaxRequestBodySize: "64"}}
+	body := `{"messages":[{"role":"user","content":"` + strings.Repeat("a", 100) + `"}],"model":"qwen"}`
+
+	for name, contentLength := range map[string]int64{"declared": int64(len(body)), "chunked": -1} {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			r.ContentLength = contentLength
+			w := httptest.NewRecorder()
+
+			as.proxyHandler(w, r)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			var response map[string]map[string]string
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, "request_too_large", response["error"]["code"])
+			assert.Equal(t, "invalid_request_error", response["error"]["type"])
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxUserLength bounds the user names kept, clients may send long opaque identifiers
+	maxUserLength = 64
+
+	// otherUsersLabel is the metrics label shared by the users beyond the label limit
+	otherUsersLabel = "other"
+
+	// userClientPrefix marks the rate limit clients that are users, and their ConfigMap entries
+	userClientPrefix = "user."
+)
+
+type requestUserKey struct{}
+
+// requestUser returns the user a request body declares: the OpenAI user field, or metadata.user_id
+// on Anthropic requests. Users are what clients claim, they are not authenticated.
+func requestUser(body []byte) string {
+	var fields struct {
+		User     json.RawMessage `json:"user"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+	var user string
+	if json.Unmarshal(fields.User, &user) != nil || strings.TrimSpace(user) == "" {
+		var metadata struct {
+			UserID string `json:"user_id"`
+		}
+		if json.Unmarshal(fields.Metadata, &metadata) != nil {
+			return ""
+		}
+		user = metadata.UserID
+	}
+	user = strings.TrimSpace(user)
+	if len(user) > maxUserLength {
+		user = strings.ToValidUTF8(user[:maxUserLength], "")
+	}
+	return user
+}
+
+// userFrom returns the user of a request, empty when it declares none or users are not tracked
+func userFrom(ctx context.Context) string {
+	user, _ := ctx.Value(requestUserKey{}).(string)
+	return user
+}
+
+// userLabels bounds the cardinality of the per-user metrics: the first users seen get their own
+// label, the following ones share the "other" label
+type userLabels struct {
+	mu    sync.Mutex
+	limit int
+	users map[string]struct{}
+}
+
+// newUserLabels creates labels for at most limit users
+func newUserLabels(limit int) *userLabels {
+	return &userLabels{limit: limit, users: make(map[string]struct{})}
+}
+
+// label returns the metrics label of a user
+func (l *userLabels) label(user string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.users[user]; ok {
+		return user
+	}
+	if len(l.users) >= l.limit {
+		return otherUsersLabel
+	}
+	l.users[user] = struct{}{}
+	return user
+}
+
+// userMiddleware reads the user declared in /v1 request bodies, for the per-user metrics, usage
+// accounting and rate limits. The body is replayed for the proxy; bodies over the size limit are
+// left for the proxy handler to reject.
+func (as *AutoScaler) userMiddleware(c *gin.Context) {
+	r := c.Request
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/v1/") || r.Body == nil {
+		c.Next()
+		return
+	}
+	limit := as.config.GetMaxRequestBodySize()
+	if limit > 0 && r.ContentLength > limit {
+		c.Next()
+		return
+	}
+
+	reader := io.Reader(r.Body)
+	if limit > 0 {
+		reader = io.LimitReader(r.Body, limit+1)
+	}
+	body, err := io.ReadAll(reader)
+	r.Body = replayBody(body, r.Body)
+	if err != nil || (limit > 0 && int64(len(body)) > limit) {
+		c.Next()
+		return
+	}
+
+	if user := requestUser(body); user != "" {
+		c.Request = r.WithContext(context.WithValue(r.Context(), requestUserKey{}, user))
+	}
+	c.Next()
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "openai user", body: `{"model":"qwen","messages":[],"user":" alice "}`, expected: "alice"},
+		{name: "anthropic metadata", body: `{"model":"qwen","metadata":{"user_id":"bob"},"messages":[]}`, expected: "bob"},
+		{name: "user first", body: `{"user":"alice","metadata":{"user_id":"bob"}}`, expected: "alice"},
+		{name: "openai metadata", body: `{"user":"","metadata":{"session":"s1"}}`, expected: ""},
+		{name: "not a string", body: `{"user":42}`, expected: ""},
+		{name: "no user", body: `{"model":"qwen"}`, expected: ""},
+		{name: "not json", body: `user=alice`, expected: ""},
+		{name: "truncated", body: `{"user":"` + strings.Repeat("a", 100) + `"}`, expected: strings.Repeat("a", maxUserLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, requestUser([]byte(tt.body)))
+		})
+	}
+}
+
+func TestUserLabels(t *testing.T) {
+	labels := newUserLabels(2)
+
+	assert.Equal(t, "alice", labels.label("alice"))
+	assert.Equal(t, "bob", labels.label("bob"))
+	assert.Equal(t, otherUsersLabel, labels.label("carol"), "users beyond the limit share a label")
+	assert.Equal(t, "alice", labels.label("alice"), "known users keep their label")
+}
+
+func TestUserMiddleware(t *testing.T) {
+	limiter, _ := newTestRateLimiter(rateLimits{RPM: 1})
+	limiter.configure(map[string]string{"user.bob": "rpm=2"})
+	as := &AutoScaler{
+		config:      &Config{UserTracking: true, MaxUserLabels: 10, RateLimitPerUser: true, AdminToken: "s3cret"},
+		rateLimiter: limiter,
+		userLabels:  newUserLabels(10),
+		usage:       usage.NewTracker(),
+		metrics:     stats.NewMetricsRecorder(),
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.userMiddleware, as.rateLimitMiddleware, as.usageMiddleware)
+	router.GET("/proxy/usage", as.usageHandler)
+	var forwarded string
+	router.NoRoute(func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		forwarded = string(body)
+		accountFrom(c.Request.Context()).serve("qwen", 1)
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString(`{"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+	})
+
+	send := func(body string) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer sk-shared")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	alice := `{"model":"qwen","user":"Alice","messages":[]}`
+	assert.Equal(t, http.StatusOK, send(alice))
+	assert.Equal(t, alice, forwarded, "the body is replayed")
+	assert.Equal(t, http.StatusTooManyRequests, send(alice))
+	assert.Equal(t, http.StatusOK, send(`{"model":"qwen","metadata":{"user_id":"bob"},"messages":[]}`), "users sharing a key get a budget each")
+	assert.Equal(t, http.StatusOK, send(`{"model":"qwen","metadata":{"user_id":"bob"},"messages":[]}`), "user.bob has its own limits")
+	assert.Equal(t, http.StatusOK, send(`{"model":"qwen","messages":[]}`), "requests without a user use the key's budget")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/proxy/usage", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var summary usage.Summary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, int64(4), summary.Total.Requests)
+	require.Contains(t, summary.Users, "Alice")
+	assert.Equal(t, int64(1), summary.Users["Alice"].Requests)
+	assert.Equal(t, int64(5), summary.Users["Alice"].CompletionTokens)
+	assert.Equal(t, int64(2), summary.Users["bob"].Requests)
+}
@@ -0,0 +1,342 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+)
+
+// requiredFields are the fields a request must hold, per endpoint. Completions take either a
+// prompt or prompt embeddings.
+var requiredFields = map[string][][]string{
+	"/v1/chat/completions": {{"messages"}},
+	"/v1/completions":      {{"prompt", "prompt_embeds"}},
+}
+
+// numberRange bounds a numeric sampling parameter
+type numberRange struct {
+	integer  bool
+	min, max float64 // Inclusive bounds, NaN when unbounded
+	aboveMin bool    // min is exclusive
+}
+
+var unbounded = math.NaN()
+
+// samplingParams are the bounds vLLM enforces on the sampling parameters, rejecting requests out of
+// them with a 400
+var samplingParams = []struct {
+	name   string
+	bounds numberRange
+}{
+	{"temperature", numberRange{min: 0, max: unbounded}},
+	{"top_p", numberRange{min: 0, max: 1, aboveMin: true}},
+	{"top_k", numberRange{integer: true, min: -1, max: unbounded}},
+	{"min_p", numberRange{min: 0, max: 1}},
+	{"presence_penalty", numberRange{min: -2, max: 2}},
+	{"frequency_penalty", numberRange{min: -2, max: 2}},
+	{"repetition_penalty", numberRange{min: 0, max: unbounded, aboveMin: true}},
+	{"n", numberRange{integer: true, min: 1, max: unbounded}},
+	{"best_of", numberRange{integer: true, min: 1, max: unbounded}},
+	{"max_tokens", numberRange{integer: true, min: 1, max: unbounded}},
+	{"max_completion_tokens", numberRange{integer: true, min: 1, max: unbounded}},
+	{"min_tokens", numberRange{integer: true, min: 0, max: unbounded}},
+	{"top_logprobs", numberRange{integer: true, min: 0, max: unbounded}},
+	{"seed", numberRange{integer: true, min: unbounded, max: unbounded}},
+}
+
+// requestValidationError reports a request field vLLM would reject
+type requestValidationError struct {
+	param   string
+	code    string
+	message string
+}
+
+func (e *requestValidationError) Error() string {
+	return e.message
+}
+
+// write sends the OpenAI-style 400 error naming the offending parameter
+func (e *requestValidationError) write(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	response := map[string]interface{}{
+		"error": map[string]interface{}{
+			"message": e.message,
+			"type":    "invalid_request_error",
+			"param":   e.param,
+			"code":    e.code,
+		},
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// validateRequest checks an OpenAI completion request before it wakes the model: a JSON object
+// naming its model, holding its messages or prompt, and sampling parameters in the bounds vLLM
+// enforces. Invalid requests are reported as a *requestValidationError; other endpoints are left
+// to vLLM.
+func validateRequest(r *http.Request) error {
+	required, ok := requiredFields[r.URL.Path]
+	if !ok {
+		return nil
+	}
+	body, err := parsedBody(r)
+	if err != nil {
+		return err
+	}
+	fields, err := body.object()
+	if err != nil {
+		return &requestValidationError{code: "invalid_json",
+			message: "We could not parse the JSON body of your request. The body must be a JSON object."}
+	}
+
+	if err := checkFieldType(fields, "model", "string"); err != nil {
+		return err
+	}
+	if model, _ := stringField(fields, "model"); model == "" {
+		return missingParameter("model")
+	}
+	for _, alternatives := range required {
+		if !hasAnyField(fields, alternatives) {
+			return missingParameter(alternatives[0])
+		}
+	}
+	if err := checkMessages(fields["messages"]); err != nil {
+		return err
+	}
+
+	for _, param := range samplingParams {
+		if err := param.bounds.check(fields, param.name); err != nil {
+			return err
+		}
+	}
+	if err := checkFieldType(fields, "stream", "boolean"); err != nil {
+		return err
+	}
+	if err := checkStop(fields["stop"]); err != nil {
+		return err
+	}
+	if err := checkResponseFormat(fields["response_format"]); err != nil {
+		return err
+	}
+	if err := checkGuidedParams(fields, ""); err != nil {
+		return err
+	}
+	return checkTools(fields["tools"])
+}
+
+// missingParameter reports a required field absent from the request
+func missingParameter(name string) error {
+	return &requestValidationError{param: name, code: "missing_required_parameter",
+		message: fmt.Sprintf("Missing required parameter: '%s'.", name)}
+}
+
+// invalidType reports a field of the wrong JSON type
+func invalidType(name, expected string, raw json.RawMessage) error {
+	return &requestValidationError{param: name, code: "invalid_type",
+		message: fmt.Sprintf("Invalid type for '%s': expected %s, but got %s instead.", name, expected, jsonType(raw))}
+}
+
+// hasAnyField reports whether one of the fields is set, null counting as unset
+func hasAnyField(fields map[string]json.RawMessage, names []string) bool {
+	for _, name := range names {
+		if raw, ok := fields[name]; ok && !bytes.Equal(raw, []byte("null")) {
+			return true
+		}
+	}
+	return false
+}
+
+// stringField returns a string field, false when absent or of another type
+func stringField(fields map[string]json.RawMessage, name string) (string, bool) {
+	var value string
+	err := json.Unmarshal(fields[name], &value)
+	return value, err == nil
+}
+
+// checkFieldType checks the JSON type of a field, when set
+func checkFieldType(fields map[string]json.RawMessage, name, expected string) error {
+	raw, ok := fields[name]
+	if !ok || bytes.Equal(raw, []byte("null")) || jsonType(raw) == expected {
+		return nil
+	}
+	return invalidType(name, "a "+expected, raw)
+}
+
+// checkMessages checks the chat messages are an array of objects with a role
+func checkMessages(raw json.RawMessage) error {
+	if raw == nil {
+		return nil
+	}
+	var messages []json.RawMessage
+	if json.Unmarshal(raw, &messages) != nil {
+		return invalidType("messages", "an array", raw)
+	}
+	for i, message := range messages {
+		param := fmt.Sprintf("messages[%d]", i)
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(message, &fields) != nil || fields == nil {
+			return invalidType(param, "an object", message)
+		}
+		if role, ok := stringField(fields, "role"); !ok || role == "" {
+			return missingParameter(param + ".role")
+		}
+	}
+	return nil
+}
+
+// checkStop checks the stop sequences are a string or an array of strings
+func checkStop(raw json.RawMessage) error {
+	if raw == nil || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	var single string
+	var sequences []string
+	if json.Unmarshal(raw, &single) != nil && json.Unmarshal(raw, &sequences) != nil {
+		return invalidType("stop", "a string or an array of strings", raw)
+	}
+	return nil
+}
+
+// responseFormats are the response_format types vLLM accepts
+var responseFormats = map[string]bool{"text": true, "json_object": true, "json_schema": true}
+
+// checkResponseFormat checks the response_format is an object of a known type, a json_schema
+// format holding a named schema
+func checkResponseFormat(raw json.RawMessage) error {
+	if raw == nil || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil || fields == nil {
+		return invalidType("response_format", "an object", raw)
+	}
+	formatType, ok := stringField(fields, "type")
+	if !ok {
+		return missingParameter("response_format.type")
+	}
+	if !responseFormats[formatType] {
+		return &requestValidationError{param: "response_format.type", code: "invalid_value",
+			message: fmt.Sprintf("Invalid 'response_format.type': expected one of 'text', 'json_object' or 'json_schema', but got '%s' instead.", formatType)}
+	}
+	if formatType != "json_schema" {
+		return nil
+	}
+
+	var jsonSchema map[string]json.RawMessage
+	if json.Unmarshal(fields["json_schema"], &jsonSchema) != nil || jsonSchema == nil {
+		if !hasAnyField(fields, []string{"json_schema"}) {
+			return missingParameter("response_format.json_schema")
+		}
+		return invalidType("response_format.json_schema", "an object", fields["json_schema"])
+	}
+	if name, _ := stringField(jsonSchema, "name"); name == "" {
+		return missingParameter("response_format.json_schema.name")
+	}
+	if err := checkFieldType(jsonSchema, "schema", "object"); err != nil {
+		return invalidType("response_format.json_schema.schema", "an object", jsonSchema["schema"])
+	}
+	if err := checkFieldType(jsonSchema, "strict", "boolean"); err != nil {
+		return invalidType("response_format.json_schema.strict", "a boolean", jsonSchema["strict"])
+	}
+	return nil
+}
+
+// checkTools checks the tools are function definitions whose strict flag, when set, is a boolean
+// over an object schema
+func checkTools(raw json.RawMessage) error {
+	if raw == nil || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	var tools []json.RawMessage
+	if json.Unmarshal(raw, &tools) != nil {
+		return invalidType("tools", "an array", raw)
+	}
+	for i, tool := range tools {
+		param := fmt.Sprintf("tools[%d]", i)
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(tool, &fields) != nil || fields == nil {
+			return invalidType(param, "an object", tool)
+		}
+		var function map[string]json.RawMessage
+		if json.Unmarshal(fields["function"], &function) != nil || function == nil {
+			return missingParameter(param + ".function")
+		}
+		if name, _ := stringField(function, "name"); name == "" {
+			return missingParameter(param + ".function.name")
+		}
+		if err := checkFieldType(function, "strict", "boolean"); err != nil {
+			return invalidType(param+".function.strict", "a boolean", function["strict"])
+		}
+		if err := checkFieldType(function, "parameters", "object"); err != nil {
+			return invalidType(param+".function.parameters", "an object", function["parameters"])
+		}
+	}
+	return nil
+}
+
+// check checks a numeric sampling parameter, when set, is a number within the bounds
+func (b numberRange) check(fields map[string]json.RawMessage, name string) error {
+	raw, ok := fields[name]
+	if !ok || bytes.Equal(raw, []byte("null")) {
+		return nil
+	}
+	var value float64
+	if json.Unmarshal(raw, &value) != nil {
+		expected := "a number"
+		if b.integer {
+			expected = "an integer"
+		}
+		return invalidType(name, expected, raw)
+	}
+	if b.integer && value != math.Trunc(value) {
+		return invalidType(name, "an integer", raw)
+	}
+
+	tooLow := !math.IsNaN(b.min) && (value < b.min || (b.aboveMin && value == b.min))
+	tooHigh := !math.IsNaN(b.max) && value > b.max
+	if !tooLow && !tooHigh {
+		return nil
+	}
+	var expected string
+	switch {
+	case math.IsNaN(b.max) && b.aboveMin:
+		expected = fmt.Sprintf("a value > %g", b.min)
+	case math.IsNaN(b.max):
+		expected = fmt.Sprintf("a value >= %g", b.min)
+	case b.aboveMin:
+		expected = fmt.Sprintf("a value in (%g, %g]", b.min, b.max)
+	default:
+		expected = fmt.Sprintf("a value in [%g, %g]", b.min, b.max)
+	}
+	return &requestValidationError{param: name, code: "invalid_value",
+		message: fmt.Sprintf("Invalid '%s': expected %s, but got %s instead.", name, expected, raw)}
+}
+
+// jsonType names the JSON type of a value, integers apart from other numbers
+func jsonType(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "nothing"
+	}
+	switch raw[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	if bytes.ContainsAny(raw, ".eE") {
+		return "number"
+	}
+	return "integer"
+}
@@ -0,0 +1,116 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequest(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		body  string
+		param string
+		code  string
+	}{
+		{name: "valid chat", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[{"role":"user","content":"Hi"}],"temperature":0.7,"top_p":1,"max_tokens":64,"stop":["\n"]}`},
+		{name: "valid completion", path: "/v1/completions", body: `{"model":"qwen","prompt":"Hi","n":2,"seed":-1,"stop":"\n"}`},
+		{name: "prompt embeddings", path: "/v1/completions", body: `{"model":"qwen","prompt_embeds":"AAAA"}`},
+		{name: "null parameters", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"temperature":null,"stop":null}`},
+		{name: "other endpoint", path: "/v1/embeddings", body: `not json`},
+		{name: "not json", path: "/v1/chat/completions", body: `not json`, code: "invalid_json"},
+		{name: "not an object", path: "/v1/chat/completions", body: `[]`, code: "invalid_json"},
+		{name: "no model", path: "/v1/chat/completions", body: `{"messages":[]}`, param: "model", code: "missing_required_parameter"},
+		{name: "model not a string", path: "/v1/chat/completions", body: `{"model":1,"messages":[]}`, param: "model", code: "invalid_type"},
+		{name: "no messages", path: "/v1/chat/completions", body: `{"model":"qwen"}`, param: "messages", code: "missing_required_parameter"},
+		{name: "messages not an array", path: "/v1/chat/completions", body: `{"model":"qwen","messages":"Hi"}`, param: "messages", code: "invalid_type"},
+		{name: "message without role", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[{"content":"Hi"}]}`, param: "messages[0].role", code: "missing_required_parameter"},
+		{name: "no prompt", path: "/v1/completions", body: `{"model":"qwen"}`, param: "prompt", code: "missing_required_parameter"},
+		{name: "negative temperature", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"temperature":-1}`, param: "temperature", code: "invalid_value"},
+		{name: "top_p zero", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"top_p":0}`, param: "top_p", code: "invalid_value"},
+		{name: "presence penalty", path: "/v1/completions", body: `{"model":"qwen","prompt":"Hi","presence_penalty":3}`, param: "presence_penalty", code: "invalid_value"},
+		{name: "fractional n", path: "/v1/completions", body: `{"model":"qwen","prompt":"Hi","n":1.5}`, param: "n", code: "invalid_type"},
+		{name: "max_tokens string", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"max_tokens":"64"}`, param: "max_tokens", code: "invalid_type"},
+		{name: "zero max_tokens", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"max_tokens":0}`, param: "max_tokens", code: "invalid_value"},
+		{name: "stream not a boolean", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"stream":"true"}`, param: "stream", code: "invalid_type"},
+		{name: "stop numbers", path: "/v1/completions", body: `{"model":"qwen","prompt":"Hi","stop":[1]}`, param: "stop", code: "invalid_type"},
+		{name: "json object format", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":{"type":"json_object"}}`},
+		{name: "json schema format", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":{"type":"json_schema","json_schema":{"name":"w","schema":{},"strict":true}}}`},
+		{name: "format not an object", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":"json"}`, param: "response_format", code: "invalid_type"},
+		{name: "unknown format", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":{"type":"xml"}}`, param: "response_format.type", code: "invalid_value"},
+		{name: "format without schema", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":{"type":"json_schema"}}`, param: "response_format.json_schema", code: "missing_required_parameter"},
+		{name: "unnamed schema", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":{"type":"json_schema","json_schema":{"schema":{}}}}`, param: "response_format.json_schema.name", code: "missing_required_parameter"},
+		{name: "schema not an object", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"response_format":{"type":"json_schema","json_schema":{"name":"w","schema":"{}"}}}`, param: "response_format.json_schema.schema", code: "invalid_type"},
+		{name: "strict tool", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"tools":[{"type":"function","function":{"name":"ls","strict":true,"parameters":{}}}]}`},
+		{name: "strict not a boolean", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"tools":[{"type":"function","function":{"name":"ls","strict":"yes"}}]}`, param: "tools[0].function.strict", code: "invalid_type"},
+		{name: "tool parameters", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"tools":[{"type":"function","function":{"name":"ls","parameters":[]}}]}`, param: "tools[0].function.parameters", code: "invalid_type"},
+		{name: "guided choice", path: "/v1/completions", body: `{"model":"qwen","prompt":"Hi","guided_choice":["yes","no"]}`},
+		{name: "guided regex number", path: "/v1/completions", body: `{"model":"qwen","prompt":"Hi","guided_regex":1}`, param: "guided_regex", code: "invalid_type"},
+		{name: "guided choice string", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"guided_choice":"yes"}`, param: "guided_choice", code: "invalid_type"},
+		{name: "tool without function", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[],"tools":[{"type":"function"}]}`, param: "tools[0].function", code: "missing_required_parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			err := validateRequest(r)
+			body, readErr := io.ReadAll(r.Body)
+			require.NoError(t, readErr)
+			assert.Equal(t, tt.body, string(body), "the body is replayed")
+
+			if tt.code == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *requestValidationError
+			require.True(t, errors.As(err, &validationErr), "got %v", err)
+			assert.Equal(t, tt.param, validationErr.param)
+			assert.Equal(t, tt.code, validationErr.code)
+		})
+	}
+}
+
+func TestProxyHandler_RejectsInvalidRequests(t *testing.T) {
+	forwarded := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		forwarded++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.config.RequestValidation = true
+
+	w := httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"qwen","messages":[],"temperature":-0.5}`)))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response struct {
+		Error struct {
+			Message string `json:"message"`
+			Type    string `json:"type"`
+			Param   string `json:"param"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "invalid_request_error", response.Error.Type)
+	assert.Equal(t, "temperature", response.Error.Param)
+	assert.Equal(t, "Invalid 'temperature': expected a value >= 0, but got -0.5 instead.", response.Error.Message)
+	assert.Zero(t, forwarded, "vLLM never sees the invalid request")
+
+	w = httptest.NewRecorder()
+	as.proxyHandler(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+		strings.NewReader(`{"model":"qwen","messages":[{"role":"user","content":"Hi"}]}`)))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, forwarded)
+}
@@ -0,0 +1,220 @@
+package proxy
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+// responseCacheHeader tells clients whether a cacheable response came from the cache
+const responseCacheHeader = "X-Chill-Cache"
+
+// responseCachePaths are the completion endpoints whose deterministic responses are cached
+var responseCachePaths = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	anthropicMessagesPath:  true,
+}
+
+// cachedResponse is a vLLM response held in the cache
+type cachedResponse struct {
+	key         string
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// responseCache holds the vLLM responses of deterministic requests, so repeating them doesn't wake
+// the model. The least recently used responses are evicted once the size limit is reached, and
+// responses expire after the TTL.
+type responseCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	ttl     time.Duration
+	size    int64
+	entries map[string]*list.Element
+	lru     *list.List // *cachedResponse, most recently used first
+	metrics *stats.MetricsRecorder
+	now     func() time.Time
+}
+
+// newResponseCache creates a cache holding up to maxSize bytes of responses for ttl
+func newResponseCache(maxSize int64, ttl time.Duration, metrics *stats.MetricsRecorder) *responseCache {
+	return &responseCache{
+		maxSize: maxSize,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		metrics: metrics,
+		now:     time.Now,
+	}
+}
+
+// get returns the cached response of a request, nil when absent or expired
+func (c *responseCache) get(key string) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*cachedResponse)
+	if !c.now().Before(entry.expires) {
+		c.remove(element)
+		return nil
+	}
+	c.lru.MoveToFront(element)
+	return entry
+}
+
+// put stores a response, evicting the least recently used ones to make room. Responses larger than
+// the cache are not stored.
+func (c *responseCache) put(key, contentType string, body []byte) {
+	if int64(len(body)) > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &cachedResponse{key: key, contentType: contentType, body: body, expires: c.now().Add(c.ttl)}
+	c.entries[key] = c.lru.PushFront(entry)
+	c.size += int64(len(body))
+	for c.size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	c.metrics.SetResponseCacheBytes(c.size)
+}
+
+// remove evicts an entry, c.mu must be held
+func (c *responseCache) remove(element *list.Element) {
+	entry := c.lru.Remove(element).(*cachedResponse)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.body))
+	c.metrics.SetResponseCacheBytes(c.size)
+}
+
+// stats returns the number of cached responses and their size
+func (c *responseCache) stats() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len(), c.size
+}
+
+// responseCacheKey returns the cache key of a deterministic request: a non-streamed completion
+// with temperature 0, keyed on its tenant or API key, endpoint, model and body, the messages and
+// sampling parameters. Other requests, and those sent with Cache-Control no-cache or no-store, are not cacheable.
+func responseCacheKey(r *http.Request, model string) (string, bool, error) {
+	if r.Method != http.MethodPost || !responseCachePaths[r.URL.Path] {
+		return "", false, nil
+	}
+	if cacheControl := strings.ToLower(r.Header.Get("Cache-Control")); strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "no-store") {
+		return "", false, nil
+	}
+	body, err := parsedBody(r)
+	if err != nil {
+		return "", false, err
+	}
+	var stream bool
+	var temperature *float64
+	if body.fields == nil || json.Unmarshal(body.fields["temperature"], &temperature) != nil || temperature == nil || *temperature != 0 {
+		return "", false, nil
+	}
+	if raw, ok := body.fields["stream"]; ok && (json.Unmarshal(raw, &stream) != nil || stream) {
+		return "", false, nil
+	}
+	// Objects are re-encoded with sorted keys and numbers normalized, so the key doesn't depend on
+	// how the client wrote them. Only deterministic requests get this far.
+	var fields interface{}
+	if json.Unmarshal(body.data, &fields) != nil {
+		return "", false, nil
+	}
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return "", false, nil
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(usageKey(r) + "\x00" + r.URL.Path + "\x00" + model + "\x00"))
+	hash.Write(canonical)
+	return hex.EncodeToString(hash.Sum(nil)), true, nil
+}
+
+// serveCachedResponse answers with a cached vLLM response, through the same response writers as a
+// response of vLLM
+func (as *AutoScaler) serveCachedResponse(w http.ResponseWriter, entry *cachedResponse) error {
+	w.Header().Set("Content-Type", entry.contentType)
+	w.Header().Set(responseCacheHeader, "hit")
+	if as.config.ResponseAnnotations {
+		// Nothing was started or switched for a cached response
+		aw := newAnnotatingWriter(w, requestAnnotation{})
+		aw.WriteHeader(http.StatusOK)
+		if _, err := aw.Write(entry.body); err != nil {
+			return err
+		}
+		return aw.finish()
+	}
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(entry.body)
+	return err
+}
+
+// serveUpstream proxies the request to vLLM, storing the response of cacheable requests when vLLM
+// answers 200
+func (as *AutoScaler) serveUpstream(proxy *httputil.ReverseProxy, w http.ResponseWriter, r *http.Request, cacheKey string) {
+	if cacheKey == "" {
+		proxy.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set(responseCacheHeader, "miss")
+	recorder := &cacheRecorder{ResponseWriter: w, status: http.StatusOK, limit: as.responses.maxSize}
+	proxy.ServeHTTP(recorder, r)
+	if recorder.status == http.StatusOK && !recorder.overflow && w.Header().Get("Content-Encoding") == "" {
+		as.responses.put(cacheKey, w.Header().Get("Content-Type"), recorder.body.Bytes())
+	}
+}
+
+// cacheRecorder copies the response of vLLM as it is written, up to the size of the cache
+type cacheRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	overflow bool // The response is larger than the cache, it is not kept
+}
+
+// WriteHeader records the status code
+func (cr *cacheRecorder) WriteHeader(code int) {
+	cr.status = code
+	cr.ResponseWriter.WriteHeader(code)
+}
+
+// Write copies the response until it exceeds the limit
+func (cr *cacheRecorder) Write(b []byte) (int, error) {
+	if !cr.overflow {
+		if int64(cr.body.Len()+len(b)) > cr.limit {
+			cr.overflow = true
+			cr.body = bytes.Buffer{}
+		} else {
+			cr.body.Write(b)
+		}
+	}
+	return cr.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher
+func (cr *cacheRecorder) Flush() {
+	if flusher, ok := cr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCacheKey(t *testing.T) {
+	key := func(path, body string, headers ...string) (string, bool) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		k, ok, err := responseCacheKey(r, "qwen")
+		require.NoError(t, err)
+		return k, ok
+	}
+
+	deterministic, ok := key("/v1/chat/completions", `{"model":"qwen","temperature":0,"messages":[{"role":"user","content":"ping"}]}`)
+	require.True(t, ok)
+	reordered, ok := key("/v1/chat/completions", `{ "messages": [ {"role": "user", "content": "ping"} ], "temperature": 0.0, "model": "qwen" }`)
+	require.True(t, ok)
+	assert.Equal(t, deterministic, reordered, "the key doesn't depend on field order and spacing")
+
+	other, _ := key("/v1/chat/completions", `{"model":"qwen","temperature":0,"messages":[{"role":"user","content":"pong"}]}`)
+	assert.NotEqual(t, deterministic, other)
+	completion, ok := key("/v1/completions", `{"model":"qwen","temperature":0,"messages":[{"role":"user","content":"ping"}]}`)
+	assert.True(t, ok)
+	assert.NotEqual(t, deterministic, completion, "endpoints don't share entries")
+	tenant, ok := key("/v1/chat/completions", `{"model":"qwen","temperature":0,"messages":[{"role":"user","content":"ping"}]}`, "Authorization", "Bearer sk-a")
+	assert.True(t, ok)
+	assert.NotEqual(t, deterministic, tenant, "API keys don't share entries")
+
+	for _, tt := range []struct {
+		name    string
+		path    string
+		body    string
+		headers []string
+	}{
+		{name: "default temperature", path: "/v1/chat/completions", body: `{"model":"qwen","messages":[]}`},
+		{name: "sampled", path: "/v1/chat/completions", body: `{"model":"qwen","temperature":0.7,"messages":[]}`},
+		{name: "streamed", path: "/v1/chat/completions", body: `{"model":"qwen","temperature":0,"stream":true,"messages":[]}`},
+		{name: "no-cache", path: "/v1/chat/completions", body: `{"model":"qwen","temperature":0,"messages":[]}`, headers: []string{"Cache-Control", "no-cache"}},
+		{name: "embeddings", path: "/v1/embeddings", body: `{"model":"qwen","temperature":0,"input":"ping"}`},
+		{name: "not json", path: "/v1/completions", body: `prompt`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := key(tt.path, tt.body, tt.headers...)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestResponseCache_EvictionAndExpiry(t *testing.T) {
+	now := time.Now()
+	cache := newResponseCache(10, time.Minute, nil)
+	cache.now = func() time.Time { return now }
+
+	cache.put("a", "application/json", []byte("aaaa"))
+	cache.put("b", "application/json", []byte("bbbb"))
+	require.NotNil(t, cache.get("a"), "a is now the most recently used")
+	cache.put("c", "application/json", []byte("cccc"))
+	assert.Nil(t, cache.get("b"), "the least recently used response is evicted")
+	assert.NotNil(t, cache.get("a"))
+	assert.NotNil(t, cache.get("c"))
+	entries, size := cache.stats()
+	assert.Equal(t, 2, entries)
+	assert.Equal(t, int64(8), size)
+
+	cache.put("big", "application/json", []byte("larger than the cache"))
+	assert.Nil(t, cache.get("big"))
+
+	now = now.Add(time.Minute)
+	assert.Nil(t, cache.get("a"), "responses expire after the TTL")
+	entries, _ = cache.stats()
+	assert.Equal(t, 1, entries)
+}
+
+func TestProxyHandler_ResponseCache(t *testing.T) {
+	forwarded := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		forwarded++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cmpl-1","choices":[{"message":{"role":"assistant","content":"pong"}}]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.responses = newResponseCache(1<<20, time.Minute, as.metrics)
+	send := func(body string, headers ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			r.Header.Set(headers[i], headers[i+1])
+		}
+		as.proxyHandler(w, r)
+		return w
+	}
+	deterministic := `{"model":"qwen","temperature":0,"messages":[{"role":"user","content":"ping"}]}`
+
+	first := send(deterministic)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "miss", first.Header().Get(responseCacheHeader))
+
+	idleSince := time.Now().Add(-time.Hour)
+	as.lastActivity = idleSince
+	second := send(deterministic)
+	require.Equal(t, http.StatusOK, second.Code)
+	assert.Equal(t, "hit", second.Header().Get(responseCacheHeader))
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, 1, forwarded, "the repeated request doesn't reach vLLM")
+	assert.Equal(t, idleSince, as.lastActivity, "cached responses don't keep the model awake")
+
+	// Sampled requests always reach vLLM
+	third := send(`{"model":"qwen","temperature":1,"messages":[{"role":"user","content":"ping"}]}`)
+	assert.Empty(t, third.Header().Get(responseCacheHeader))
+	assert.Equal(t, 2, forwarded)
+
+	// The switch policy applies to cached responses too
+	as.activeModel = "deepseek"
+	as.config.SwitchPolicy = SwitchPolicyManual
+	refused := send(deterministic)
+	assert.Equal(t, http.StatusConflict, refused.Code)
+	assert.Equal(t, 2, forwarded)
+}
+
+func TestProxyHandler_ResponseCachePerAPIKey(t *testing.T) {
+	forwarded := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		forwarded++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"cmpl-1","choices":[{"message":{"role":"assistant","content":"pong"}}]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.responses = newResponseCache(1<<20, time.Minute, as.metrics)
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"qwen","temperature":0,"messages":[{"role":"user","content":"ping"}]}`))
+		r.Header.Set("Authorization", "Bearer "+apiKey)
+		as.proxyHandler(w, r)
+		return w
+	}
+
+	assert.Equal(t, "miss", send("sk-a").Header().Get(responseCacheHeader))
+	assert.Equal(t, "miss", send("sk-b").Header().Get(responseCacheHeader), "another key doesn't get the response of the first")
+	assert.Equal(t, "hit", send("sk-a").Header().Get(responseCacheHeader))
+	assert.Equal(t, "hit", send("sk-b").Header().Get(responseCacheHeader))
+	assert.Equal(t, 2, forwarded)
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/efortin/vllm-chill/pkg/parser"
 	"github.com/efortin/vllm-chill/pkg/stats"
 )
 
@@ -29,11 +31,31 @@ type responseWriter struct {
 	xmlDetectionStart  time.Time                // When XML detection was activated
 	chunkBuffer        []map[string]interface{} // Store parsed chunks for template
 	toolCallsDetected  bool                     // Whether native tool calls were detected
+	xmlPatternSeen     bool                     // Whether XML tool call markup appeared in content
+	xmlFallbackOff     bool                     // Disables XML to tool call conversion (detection still runs)
+	toolParser         parser.ToolCallParser    // Fallback parser of the model, converting the tool calls written in content
+	pendingRaw         bytes.Buffer             // Raw writes held back while buffering, flushed as-is if parsing fails
+	xmlStream          *parser.XMLStreamParser  // Converts XML tool calls as the content streams, created on the first chunk
+	xmlStreamDone      bool                     // The stream parser was finished at the end of the choice
+	transformer        streamTransformer        // Rewrites the events of the stream's API format, nil until SSE is seen
+	requestTools       json.RawMessage          // Tools of the request, their schemas are built on the first converted tool call
+	toolSchemas        parser.ToolSchemas       // Argument schemas of the request's tools, converted tool calls are checked against
+	rejectInvalidCalls bool                     // Converted tool calls not matching their schema are replaced by an error text
 	metrics            *stats.MetricsRecorder   // Metrics recorder for tracking operations
+	stream             streamTimer              // Time to first token and throughput of streamed responses
 	// Deduplication fields for native tool calls (vLLM tensor parallelism workaround)
 	seenChunks       map[string]bool // Track seen SSE chunks by hash
 	lastToolCallArgs map[int]string  // Track last arguments per tool call index
 	toolCallIDs      map[string]bool // Track which tool call IDs we've sent start events for
+	// Parser decisions and costs, reported to debugged requests
+	duplicateBytes   int64         // Bytes filtered by deduplication
+	parsedToolCalls  int           // Tool calls the fallback parser found in the content
+	conversionFailed bool          // The fallback parser buffered the stream but found no tool call
+	repairedCalls    int           // Parsed tool calls whose malformed JSON arguments were repaired
+	coercedCalls     int           // Parsed tool calls whose arguments were coerced to their tool's schema
+	invalidCalls     int           // Parsed tool calls whose arguments don't match their tool's schema
+	transformTime    time.Duration // Time spent deduplicating and parsing
+	xmlParseTime     time.Duration // Time spent in the XML stream parser
 }
 
 // newResponseWriter creates a new response writer wrapper
@@ -47,6 +69,7 @@ func newResponseWriter(w http.ResponseWriter, captureBody bool, metrics *stats.M
 		seenChunks:       make(map[string]bool),
 		lastToolCallArgs: make(map[int]string),
 		toolCallIDs:      make(map[string]bool),
+		toolParser:       parser.ForModel("", ""),
 	}
 	if captureBody {
 		rw.body = &bytes.Buffer{}
@@ -63,28 +86,31 @@ func (rw *responseWriter) WriteHeader(code int) {
 
 // Write captures the response size and converts XML tool calls
 func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.stream.observe(rw.Header().Get("Content-Type"), b)
+
 	// Accumulate all data in SSE buffer
 	rw.sseBuffer.Write(b)
 
-	// Try to process complete lines
-	content := rw.sseBuffer.String()
-
-	// Check if we have SSE data chunks
-	if !strings.HasPrefix(content, "data: ") {
+	// Pick the transformer of the stream's format from its first event
+	if rw.transformer == nil {
+		rw.transformer = streamTransformerFor(rw.sseBuffer.String())
+	}
+	if rw.transformer == nil {
 		// Not SSE format, pass through
-		n, err := rw.ResponseWriter.Write(b)
-		rw.bytesWritten += int64(n)
-		if rw.captureBody {
-			rw.body.Write(b)
-		}
-		return len(b), err
+		return len(b), rw.forward(b)
 	}
+	return len(b), rw.transformer.write(rw, b)
+}
 
+// writeOpenAI converts the tool calls of OpenAI chat completion chunks, and deduplicates native ones
+func (rw *responseWriter) writeOpenAI(b []byte) error {
 	// Parse only NEW SSE chunks (everything in current write)
 	lines := strings.Split(string(b), "\n")
 	hasDoneMarker := false
+	rewritten := false
+	dropped := make(map[int]bool)
 
-	for _, line := range lines {
+	for i, line := range lines {
 		if !strings.HasPrefix(line, "data: ") {
 			continue
 		}
@@ -92,6 +118,13 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 		jsonData := strings.TrimPrefix(line, "data: ")
 		if jsonData == "[DONE]" {
 			hasDoneMarker = true
+			if rw.streamsXML() {
+				// The choice ended without a finish reason, flush what the parser holds back
+				if events := rw.finishXMLStream(); len(events) > 0 {
+					lines[i] = rw.xmlStreamEvents(rw.chunkBuffer[0], events) + "\n\n" + line
+					rewritten = true
+				}
+			}
 			continue
 		}
 
@@ -106,43 +139,30 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 				if delta, ok := choice["delta"].(map[string]interface{}); ok {
 					// Check for native tool calls (pass through immediately)
 					if toolCalls, ok := delta["tool_calls"].([]interface{}); ok && len(toolCalls) > 0 {
-						if !rw.toolCallsDetected {
-							rw.toolCallsDetected = true
-							log.Printf("[TOOL-CALLS] Native tool calls detected - passing through")
-
-							// If we had XML mode active, cancel it since native tool calls are being used
-							if rw.xmlDetectionMode {
-								log.Printf("[TOOL-CALLS] Canceling XML mode - native tool calls detected")
-								rw.xmlDetectionMode = false
-								rw.accumulatedContent.Reset()
-								rw.chunkBuffer = nil
-							}
-						}
+						rw.nativeToolCalls()
 					}
 
 					// Extract text content
 					if deltaContent, ok := delta["content"].(string); ok && deltaContent != "" {
-						rw.accumulatedContent.WriteString(deltaContent)
-
-						accumulated := rw.accumulatedContent.String()
+						accumulated := rw.observeContent(deltaContent)
 						// Detect XML mode - check for various XML tool call patterns
-						if !rw.xmlDetectionMode && !rw.toolCallsDetected {
-							// Detect complete or incomplete XML tool call patterns
-							if strings.Contains(accumulated, "<function=") ||
-								strings.Contains(accumulated, "<tool_call") ||
-								strings.Contains(accumulated, "<function_call") ||
-								// Also detect incomplete fragments that might be XML
-								(strings.Contains(accumulated, "<function") && !strings.Contains(accumulated, "function>")) {
-								rw.xmlDetectionMode = true
-								rw.xmlDetectionStart = time.Now()
-								log.Printf("[XML-PARSER] XML detection mode activated - buffering until [DONE]")
-							}
+						if !rw.xmlDetectionMode && !rw.toolCallsDetected && rw.fallbackPatternSeen(accumulated) {
+							rw.xmlDetectionMode = true
+							rw.xmlDetectionStart = time.Now()
+							log.Printf("[%s] Detection mode activated - buffering until [DONE]", rw.parserLogPrefix())
 						}
 					}
 
 					// Note: reasoning_content (DeepSeek R1, etc.) is passed through unmodified
 					// It is separate from regular content and does not trigger XML parsing
 				}
+
+				if rw.streamsXML() {
+					if replacement, ok := rw.streamXMLToolCalls(chunk, choice); ok {
+						lines[i], rewritten = replacement, true
+						dropped[i] = replacement == ""
+					}
+				}
 			}
 		}
 
@@ -152,49 +172,64 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 		}
 	}
 
+	// Lines rewritten by the XML stream parser replace the written ones
+	out := b
+	if rewritten {
+		out = joinSSELines(lines, dropped)
+	}
+
 	// If we detected XML and stream is done, convert to single tool call response
 	if rw.xmlDetectionMode && hasDoneMarker {
 		accumulated := rw.accumulatedContent.String()
-		log.Printf("[XML-PARSER] Stream complete, parsing XML (length: %d)", len(accumulated))
+		log.Printf("[%s] Stream complete, parsing tool calls (length: %d)", rw.parserLogPrefix(), len(accumulated))
 
-		// Record proxy latency for XML parsing
+		// Record proxy latency for tool call parsing
 		parseStart := time.Now()
-		toolCalls := parseXMLToolCalls(accumulated)
+		toolCalls := rw.toolParser.Parse(accumulated)
 		parseDuration := time.Since(parseStart)
+		rw.transformTime += parseDuration
 
 		if rw.metrics != nil {
-			rw.metrics.RecordProxyLatency("xml_parsing", parseDuration)
+			rw.metrics.RecordProxyLatency(rw.toolParser.Name()+"_parsing", parseDuration)
 		}
 
 		if len(toolCalls) > 0 {
-			log.Printf("[XML-PARSER] Successfully parsed %d tool calls, sending as single SSE chunk", len(toolCalls))
-
-			// Record successful XML parsing
+			repaired := 0
+			for _, toolCall := range toolCalls {
+				if toolCall.Repaired {
+					repaired++
+				}
+			}
+			rw.repairedCalls += repaired
 			if rw.metrics != nil {
-				rw.metrics.RecordXMLParsing(true, len(toolCalls))
+				rw.metrics.RecordJSONRepairs(rw.toolParser.Name(), repaired)
 			}
+			var errorText string
+			toolCalls, errorText = rw.checkToolCalls(toolCalls)
+			rw.parsedToolCalls += len(toolCalls)
+			log.Printf("[%s] Successfully parsed %d tool calls, sending as single SSE chunk", rw.parserLogPrefix(), len(toolCalls))
 
-			// Build a single SSE chunk with the complete tool call
-			singleChunk := rw.buildSingleToolCallChunk(toolCalls[0])
+			// Build a single SSE chunk with the complete tool calls
+			singleChunk := rw.buildToolCallsChunk(toolCalls, errorText)
 
 			// Write the single chunk
 			_, err := rw.ResponseWriter.Write([]byte("data: "))
 			if err != nil {
-				return 0, err
+				return err
 			}
 			_, err = rw.ResponseWriter.Write(singleChunk)
 			if err != nil {
-				return 0, err
+				return err
 			}
 			_, err = rw.ResponseWriter.Write([]byte("\n\n"))
 			if err != nil {
-				return 0, err
+				return err
 			}
 
 			// Write [DONE] marker
 			_, err = rw.ResponseWriter.Write([]byte("data: [DONE]\n\n"))
 			if err != nil {
-				return 0, err
+				return err
 			}
 
 			rw.bytesWritten += int64(len(singleChunk) + 20)
@@ -204,69 +239,224 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 
 			// Reset state
 			rw.sseBuffer.Reset()
+			rw.pendingRaw.Reset()
 			rw.accumulatedContent.Reset()
 			rw.xmlDetectionMode = false
 			rw.chunkBuffer = nil
 
-			return len(b), nil
+			return nil
 		}
 
-		// XML parsing failed, flush buffered chunks as-is
-		log.Printf("[XML-PARSER] Failed to parse XML, flushing %d buffered chunks", len(rw.chunkBuffer))
+		// Parsing failed, flush the held back stream as-is
+		log.Printf("[%s] Failed to parse tool calls, flushing %d buffered bytes", rw.parserLogPrefix(), rw.pendingRaw.Len()+len(b))
 
-		// Record failed XML parsing
-		if rw.metrics != nil {
-			rw.metrics.RecordXMLParsing(false, 0)
-		}
+		rw.conversionFailed = true
 
-		for _, chunk := range rw.chunkBuffer {
-			chunkJSON, _ := json.Marshal(chunk)
-			_, _ = rw.ResponseWriter.Write([]byte("data: "))
-			_, _ = rw.ResponseWriter.Write(chunkJSON)
-			_, _ = rw.ResponseWriter.Write([]byte("\n\n"))
-		}
-		_, _ = rw.ResponseWriter.Write([]byte("data: [DONE]\n\n"))
+		rw.pendingRaw.Write(b)
+		err := rw.forward(rw.pendingRaw.Bytes())
 
 		// Reset state
 		rw.xmlDetectionMode = false
 		rw.chunkBuffer = nil
 		rw.sseBuffer.Reset()
+		rw.pendingRaw.Reset()
 		rw.accumulatedContent.Reset()
-		return len(b), nil
+		return err
 	}
 
 	// If NOT in XML mode, pass through (with deduplication if tool calls detected)
 	if !rw.xmlDetectionMode {
 		// If native tool calls detected, deduplicate chunks from vLLM tensor parallelism
 		if rw.toolCallsDetected {
-			dedupedData, bytesFiltered := rw.deduplicateToolCallChunks(b)
+			dedupStart := time.Now()
+			dedupedData, bytesFiltered := rw.deduplicateToolCallChunks(out)
+			rw.transformTime += time.Since(dedupStart)
+			rw.duplicateBytes += int64(bytesFiltered)
 			if bytesFiltered > 0 {
 				log.Printf("[DEDUP] Filtered %d duplicate bytes from vLLM tensor parallelism", bytesFiltered)
 			}
 			if len(dedupedData) == 0 {
 				// All chunks were duplicates
-				return len(b), nil
+				return nil
 			}
-			n, err := rw.ResponseWriter.Write(dedupedData)
-			rw.bytesWritten += int64(n)
-			if rw.captureBody {
-				rw.body.Write(dedupedData)
-			}
-			return len(b), err
+			return rw.forward(dedupedData)
 		}
 
 		// Normal pass-through (no tool calls)
-		n, err := rw.ResponseWriter.Write(b)
-		rw.bytesWritten += int64(n)
-		if rw.captureBody {
-			rw.body.Write(b)
-		}
-		return len(b), err
+		return rw.forward(out)
 	}
 
 	// XML mode active, buffering until [DONE]
-	log.Printf("[XML-PARSER] Buffering chunks... (elapsed: %v)", time.Since(rw.xmlDetectionStart))
-	return len(b), nil
+	rw.pendingRaw.Write(b)
+	log.Printf("[%s] Buffering chunks... (elapsed: %v)", rw.parserLogPrefix(), time.Since(rw.xmlDetectionStart))
+	return nil
+}
+
+// xmlToolCalls reports whether the model's fallback parser is the XML one, governed by XML_FALLBACK
+func (rw *responseWriter) xmlToolCalls() bool {
+	return rw.toolParser.Name() == parser.XMLParserName
+}
+
+// fallbackPatternSeen reports whether the accumulated content holds a tool call for the model's fallback
+// parser, to buffer the stream until it completes. XML tool calls are converted as they stream instead.
+func (rw *responseWriter) fallbackPatternSeen(accumulated string) bool {
+	return !rw.xmlToolCalls() && rw.toolParser.Detect(accumulated)
+}
+
+// streamsXML reports whether content chunks go through the XML stream parser
+func (rw *responseWriter) streamsXML() bool {
+	return rw.xmlToolCalls() && !rw.xmlFallbackOff && !rw.toolCallsDetected && !rw.xmlStreamDone
+}
+
+// streamXMLToolCalls feeds the content of the chunk to the XML stream parser. It returns the SSE events
+// replacing the chunk's line, empty to drop it while the parser holds its content back, or false when
+// the chunk passes through unchanged.
+func (rw *responseWriter) streamXMLToolCalls(chunk, choice map[string]interface{}) (string, bool) {
+	delta, _ := choice["delta"].(map[string]interface{})
+	content, _ := delta["content"].(string)
+	reason, finished := choice["finish_reason"].(string)
+	if content == "" && !finished {
+		return "", false
+	}
+
+	events := rw.feedXMLStream(content)
+	if !finished && passesThrough(events, content) {
+		return "", false
+	}
+	if !finished {
+		return rw.xmlStreamEvents(chunk, events), true
+	}
+
+	events = append(events, rw.finishXMLStream()...)
+	toolCalls := reason == "stop" && rw.xmlStream.ToolCalls() > 0
+	if len(events) == 0 && !toolCalls {
+		return "", false
+	}
+	// The finish chunk follows the converted content, which it no longer carries
+	if delta != nil {
+		delta["content"] = ""
+	}
+	if toolCalls {
+		choice["finish_reason"] = "tool_calls"
+	}
+	finishChunk, _ := json.Marshal(chunk)
+	if len(events) == 0 {
+		return "data: " + string(finishChunk), true
+	}
+	return rw.xmlStreamEvents(chunk, events) + "\n\ndata: " + string(finishChunk), true
+}
+
+// feedXMLStream feeds a content delta to the XML stream parser, created on the first one
+func (rw *responseWriter) feedXMLStream(content string) []parser.StreamEvent {
+	if rw.xmlStream == nil {
+		rw.xmlStream = parser.NewXMLStreamParser()
+		if rw.requestTools != nil || len(rw.toolSchemas) > 0 {
+			rw.xmlStream.LoadToolSchemas(rw.schemas, rw.rejectInvalidCalls)
+		}
+	}
+
+	parseStart := time.Now()
+	events := rw.xmlStream.Feed(content)
+	rw.xmlParseTime += time.Since(parseStart)
+	for _, event := range events {
+		if event.ToolCall != nil && event.ToolCall.ID != "" {
+			log.Printf("[XML-PARSER] Streaming tool call %s (%s)", event.ToolCall.Name, event.ToolCall.ID)
+		}
+	}
+	return events
+}
+
+// passesThrough reports whether the stream parser's events are the content delta they were fed
+func passesThrough(events []parser.StreamEvent, content string) bool {
+	return len(events) == 1 && events[0].ToolCall == nil && events[0].Text == content
+}
+
+// finishXMLStream flushes the XML stream parser at the end of the choice, and records its outcome
+func (rw *responseWriter) finishXMLStream() []parser.StreamEvent {
+	rw.xmlStreamDone = true
+	if rw.xmlStream == nil {
+		return nil
+	}
+
+	parseStart := time.Now()
+	events := rw.xmlStream.Finish()
+	rw.xmlParseTime += time.Since(parseStart)
+	rw.transformTime += rw.xmlParseTime
+	rw.parsedToolCalls = rw.xmlStream.ToolCalls()
+	rw.conversionFailed = rw.xmlStream.Failed()
+	rw.repairedCalls = rw.xmlStream.Repairs()
+	checked, coerced, invalid := rw.xmlStream.SchemaChecks()
+	rw.recordSchemaChecks(parser.XMLParserName, checked, coerced, invalid)
+
+	if rw.parsedToolCalls > 0 || rw.conversionFailed {
+		log.Printf("[XML-PARSER] Stream complete, converted %d tool calls", rw.parsedToolCalls)
+		if rw.metrics != nil {
+			rw.metrics.RecordXMLParsing(rw.parsedToolCalls > 0, rw.parsedToolCalls)
+			rw.metrics.RecordJSONRepairs(parser.XMLParserName, rw.repairedCalls)
+			rw.metrics.RecordProxyLatency(parser.XMLParserName+"_parsing", rw.xmlParseTime)
+		}
+	}
+	return events
+}
+
+// xmlStreamEvents builds the SSE lines of the stream parser's events, on the model of chunk
+func (rw *responseWriter) xmlStreamEvents(template map[string]interface{}, events []parser.StreamEvent) string {
+	lines := make([]string, 0, len(events))
+	for _, event := range events {
+		chunk := make(map[string]interface{}, len(template))
+		for k, v := range template {
+			if k != "choices" && k != "usage" {
+				chunk[k] = v
+			}
+		}
+
+		delta := map[string]interface{}{"content": event.Text}
+		if toolCall := event.ToolCall; toolCall != nil {
+			function := map[string]interface{}{"arguments": toolCall.Arguments}
+			call := map[string]interface{}{"index": toolCall.Index, "function": function}
+			if toolCall.ID != "" {
+				call["id"] = toolCall.ID
+				call["type"] = "function"
+				function["name"] = toolCall.Name
+			}
+			delta = map[string]interface{}{"tool_calls": []interface{}{call}}
+		}
+		chunk["choices"] = []interface{}{map[string]interface{}{"index": 0, "delta": delta, "finish_reason": nil}}
+
+		data, _ := json.Marshal(chunk)
+		lines = append(lines, "data: "+string(data))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// joinSSELines joins the lines of a write back, without the dropped ones and their event separator
+func joinSSELines(lines []string, dropped map[int]bool) []byte {
+	var out bytes.Buffer
+	first := true
+	for i := 0; i < len(lines); i++ {
+		if dropped[i] {
+			if i+1 < len(lines) && lines[i+1] == "" {
+				i++
+			}
+			continue
+		}
+		if !first {
+			out.WriteByte('\n')
+		}
+		out.WriteString(lines[i])
+		first = false
+	}
+	return out.Bytes()
+}
+
+// parserLogPrefix returns the log prefix of the active fallback parser
+func (rw *responseWriter) parserLogPrefix() string {
+	return strings.ToUpper(rw.toolParser.Name()) + "-PARSER"
+}
+
+// containsXMLToolCall reports whether content holds complete or incomplete XML tool call patterns
+func containsXMLToolCall(content string) bool {
+	return parser.ContainsXMLToolCallPattern(content)
 }
 
 // deduplicateToolCallChunks removes duplicate SSE chunks from vLLM tensor parallelism
@@ -327,10 +517,7 @@ func (rw *responseWriter) deduplicateToolCallChunks(b []byte) ([]byte, int) {
 								}
 
 								// Check for tool call ID (used for content_block_start dedup)
-								toolID := ""
-								if id, ok := toolCall["id"].(string); ok && id != "" {
-									toolID = id
-								}
+								toolID, _ := toolCall["id"].(string)
 
 								// Get function arguments if present
 								args := ""
@@ -340,24 +527,10 @@ func (rw *responseWriter) deduplicateToolCallChunks(b []byte) ([]byte, int) {
 									}
 								}
 
-								// Skip if we've seen this exact tool ID start event
-								if toolID != "" && args == "" {
-									// This is a tool_call start (has ID but no args yet)
-									if rw.toolCallIDs[toolID] {
-										shouldSkip = true
-										break
-									}
-									rw.toolCallIDs[toolID] = true
-								}
-
-								// Skip if same arguments as last time for this index
-								if args != "" && rw.lastToolCallArgs[idx] == args {
+								if rw.duplicateToolCall(idx, toolID, args) {
 									shouldSkip = true
 									break
 								}
-								if args != "" {
-									rw.lastToolCallArgs[idx] = args
-								}
 							}
 						}
 
@@ -380,16 +553,41 @@ func (rw *responseWriter) deduplicateToolCallChunks(b []byte) ([]byte, int) {
 	return deduped, bytesFiltered
 }
 
-// buildSingleToolCallChunk builds a single SSE chunk with the complete tool call
-func (rw *responseWriter) buildSingleToolCallChunk(toolCall ToolCall) []byte {
+// duplicateToolCall reports whether a native tool call delta repeats the start of a call already
+// sent, or the last arguments of its index, as vLLM tensor parallelism does
+func (rw *responseWriter) duplicateToolCall(index int, id, args string) bool {
+	// A tool call start has an ID but no arguments yet
+	if id != "" && args == "" {
+		if rw.toolCallIDs[id] {
+			return true
+		}
+		rw.toolCallIDs[id] = true
+	}
+
+	if args != "" {
+		if rw.lastToolCallArgs[index] == args {
+			return true
+		}
+		rw.lastToolCallArgs[index] = args
+	}
+	return false
+}
+
+// buildToolCallsChunk builds a single SSE chunk with the complete tool calls, indexed in order, and
+// the content replacing the rejected ones
+func (rw *responseWriter) buildToolCallsChunk(toolCalls []ToolCall, content string) []byte {
 	// Use the first chunk as template (to get id, model, created, etc.)
 	var templateChunk map[string]interface{}
 	if len(rw.chunkBuffer) > 0 {
 		templateChunk = rw.chunkBuffer[0]
 	} else {
 		// Fallback: create minimal chunk
+		id := "chatcmpl-vllm-chill"
+		if len(toolCalls) > 0 {
+			id = "chatcmpl-" + toolCalls[0].ID
+		}
 		templateChunk = map[string]interface{}{
-			"id":      "chatcmpl-" + toolCall.ID,
+			"id":      id,
 			"object":  "chat.completion.chunk",
 			"created": time.Now().Unix(),
 			"model":   "unknown",
@@ -402,24 +600,34 @@ func (rw *responseWriter) buildSingleToolCallChunk(toolCall ToolCall) []byte {
 		chunk[k] = v
 	}
 
-	// Set the delta with complete tool call
+	// Set the delta with the complete tool calls
+	deltas := make([]map[string]interface{}, 0, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		deltas = append(deltas, map[string]interface{}{
+			"index": i,
+			"id":    toolCall.ID,
+			"type":  toolCall.Type,
+			"function": map[string]interface{}{
+				"name":      toolCall.Function.Name,
+				"arguments": toolCall.Function.Arguments,
+			},
+		})
+	}
+	delta := map[string]interface{}{"tool_calls": deltas}
+	finishReason := "tool_calls"
+	if content != "" {
+		// Rejected tool calls are replaced by their error
+		delta["content"] = content
+	}
+	if len(deltas) == 0 {
+		delete(delta, "tool_calls")
+		finishReason = "stop"
+	}
 	chunk["choices"] = []map[string]interface{}{
 		{
-			"index": 0,
-			"delta": map[string]interface{}{
-				"tool_calls": []map[string]interface{}{
-					{
-						"index": 0,
-						"id":    toolCall.ID,
-						"type":  toolCall.Type,
-						"function": map[string]interface{}{
-							"name":      toolCall.Function.Name,
-							"arguments": toolCall.Function.Arguments,
-						},
-					},
-				},
-			},
-			"finish_reason": "tool_calls",
+			"index":         0,
+			"delta":         delta,
+			"finish_reason": finishReason,
 		},
 	}
 
@@ -460,10 +668,11 @@ func (rw *responseWriter) Body() []byte {
 	return nil
 }
 
-// bodyReader wraps the request body to capture its size
+// bodyReader wraps the request body to capture its size and read error
 type bodyReader struct {
 	io.ReadCloser
 	bytesRead int64
+	err       error // First read error other than io.EOF
 }
 
 // newBodyReader creates a new body reader wrapper
@@ -471,10 +680,13 @@ func newBodyReader(rc io.ReadCloser) *bodyReader {
 	return &bodyReader{ReadCloser: rc}
 }
 
-// Read captures the number of bytes read
+// Read captures the number of bytes read and the first read error
 func (br *bodyReader) Read(p []byte) (int, error) {
 	n, err := br.ReadCloser.Read(p)
 	br.bytesRead += int64(n)
+	if err != nil && err != io.EOF && br.err == nil {
+		br.err = err
+	}
 	return n, err
 }
 
@@ -483,6 +695,12 @@ func (br *bodyReader) BytesRead() int64 {
 	return br.bytesRead
 }
 
+// tooLarge reports whether reading stopped at the request body size limit
+func (br *bodyReader) tooLarge() bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(br.err, &maxBytesErr)
+}
+
 // newBodyReaderFromBytes creates a new body reader from a byte slice
 func newBodyReaderFromBytes(data []byte) *bodyReader {
 	return &bodyReader{
@@ -1,11 +1,15 @@
 package proxy
 
 import (
+	"encoding/json"
 	"io"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/efortin/vllm-chill/pkg/parser"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResponseWriter_Status(t *testing.T) {
@@ -60,3 +64,156 @@ func TestBodyReader_BytesRead(t *testing.T) {
 	assert.Equal(t, len(testData), n)
 	assert.Equal(t, int64(len(testData)), br.bytesRead)
 }
+
+// sseContentChunk builds an SSE chunk carrying a content delta
+func sseContentChunk(t *testing.T, content string) string {
+	t.Helper()
+	chunk, err := json.Marshal(map[string]interface{}{
+		"id":      "chatcmpl-1",
+		"object":  "chat.completion.chunk",
+		"choices": []map[string]interface{}{{"index": 0, "delta": map[string]interface{}{"content": content}}},
+	})
+	require.NoError(t, err)
+	return "data: " + string(chunk) + "\n\n"
+}
+
+func TestResponseWriter_JSONToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolParser = parser.ForModel("json", "")
+
+	for _, part := range []string{"```json\n", `{"name": "ls", `, `"arguments": {"path": "/tmp"}}`, "\n```"} {
+		_, err := rw.Write([]byte(sseContentChunk(t, part)))
+		require.NoError(t, err)
+	}
+	_, err := rw.Write([]byte("data: [DONE]\n\n"))
+	require.NoError(t, err)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"tool_calls"`)
+	assert.Contains(t, body, `"name":"ls"`)
+	assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+}
+
+func TestResponseWriter_JSONToolCallsIgnoresXML(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolParser = parser.ForModel("json", "")
+
+	stream := sseContentChunk(t, "<tool_call><function=read><parameter=path>/tmp</parameter></function></tool_call>") +
+		"data: [DONE]\n\n"
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	assert.True(t, rw.xmlPatternSeen)
+	assert.Equal(t, stream, recorder.Body.String())
+}
+
+func TestResponseWriter_FlushesStreamWhenParsingFails(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolParser = parser.ForModel("json", "")
+
+	// Looks like a tool call but has no arguments
+	chunks := []string{
+		sseContentChunk(t, "Example user: "),
+		sseContentChunk(t, `{"name": "John", `),
+		sseContentChunk(t, `"age": 42}`),
+		"data: [DONE]\n\n",
+	}
+	for _, chunk := range chunks {
+		_, err := rw.Write([]byte(chunk))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, strings.Join(chunks, ""), recorder.Body.String())
+	assert.False(t, rw.xmlDetectionMode)
+}
+
+func TestResponseWriter_MistralToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolParser = parser.ForModel("", "mistral")
+
+	for _, part := range []string{"[TOOL_CALLS]", "get_weather[ARGS]", `{"city": "Paris"}`} {
+		_, err := rw.Write([]byte(sseContentChunk(t, part)))
+		require.NoError(t, err)
+	}
+	_, err := rw.Write([]byte("data: [DONE]\n\n"))
+	require.NoError(t, err)
+
+	body := recorder.Body.String()
+	assert.NotContains(t, body, "TOOL_CALLS", "the markup is held back and replaced")
+	assert.Contains(t, body, `"name":"get_weather"`)
+	assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+}
+
+func TestResponseWriter_MistralParallelToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolParser = parser.ForModel("", "mistral")
+
+	stream := sseContentChunk(t, `[TOOL_CALLS][{"name": "get_weather", "arguments": {"city": "Paris"}}, {"name": "get_time", "arguments": {}}]`) +
+		"data: [DONE]\n\n"
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"index":0,"type":"function"`)
+	assert.Contains(t, body, `"name":"get_time"`, "every tool call is sent, not only the first")
+	assert.Contains(t, body, `"index":1`)
+}
+
+func TestResponseWriter_RepairsToolCallArguments(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolParser = parser.ForModel("", "mistral")
+
+	// Unquoted key, single quotes and arguments cut off by max_tokens
+	for _, part := range []string{"[TOOL_CALLS]", "get_weather[ARGS]", `{city: 'Par`} {
+		_, err := rw.Write([]byte(sseContentChunk(t, part)))
+		require.NoError(t, err)
+	}
+	_, err := rw.Write([]byte("data: [DONE]\n\n"))
+	require.NoError(t, err)
+
+	assert.Contains(t, recorder.Body.String(), `"arguments":"{\"city\":\"Par\"}"`)
+	assert.Equal(t, 1, rw.parsedToolCalls)
+	assert.Equal(t, 1, rw.repairedCalls)
+}
+
+func TestResponseWriter_StreamsXMLToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+
+	_, err := rw.Write([]byte(sseContentChunk(t, "Listing.\n<tool_call>\n<function=ls>\n<parameter=path>.")))
+	require.NoError(t, err)
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"content":"Listing.\n"`)
+	assert.Contains(t, body, `"name":"ls"`, "the tool call starts before the response completes")
+	assert.NotContains(t, body, "<tool_call>")
+
+	_, err = rw.Write([]byte(sseContentChunk(t, "</parameter>\n</function>\n</tool_call>")))
+	require.NoError(t, err)
+	assert.Contains(t, recorder.Body.String(), `"arguments":"{\"path\":\".\""`)
+
+	_, err = rw.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":""},"finish_reason":"stop"}]}` + "\n\ndata: [DONE]\n\n"))
+	require.NoError(t, err)
+	body = recorder.Body.String()
+	assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+	assert.True(t, strings.HasSuffix(body, "data: [DONE]\n\n"))
+	assert.Equal(t, 1, rw.parsedToolCalls)
+	assert.False(t, rw.xmlDetectionMode, "XML tool calls are not buffered")
+}
+
+func TestResponseWriter_XMLStreamPassesTextThrough(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+
+	stream := sseContentChunk(t, "if a < b {") + sseContentChunk(t, " return }") + "data: [DONE]\n\n"
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	assert.Equal(t, stream, recorder.Body.String(), "content that opens no tool call is written unchanged")
+}
@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Scale strategies
+const (
+	ScaleStrategyDelete     = "delete"      // Delete the pod when idle: releases the GPU, full cold start
+	ScaleStrategyPauseImage = "pause-image" // Swap vLLM for the pause image: keeps the node and GPU, skips scheduling
+	ScaleStrategyVLLMSleep  = "vllm-sleep"  // vLLM sleep mode: frees GPU memory, keeps the process for a fast wake up
+)
+
+// scaleStrategy controls how the vLLM model releases resources when idle and comes back on demand
+type scaleStrategy interface {
+	// name identifies the strategy in configuration and /proxy/status
+	name() string
+	// isUp reports whether the model is running or starting, so only readiness needs to be awaited
+	isUp(ctx context.Context) (bool, error)
+	// scaleUp starts bringing the model back, readiness is awaited by the caller
+	scaleUp(ctx context.Context) error
+	// scaleDown releases the model after the idle timeout
+	scaleDown(ctx context.Context) error
+}
+
+// deepIdler is implemented by strategies with a second, longer idle stage
+type deepIdler interface {
+	// checkDeepIdle is called on each idle check with the time since the last request
+	checkDeepIdle(ctx context.Context, idleTime time.Duration)
+}
+
+// newScaleStrategy returns the named strategy, an empty name selects the delete strategy
+func newScaleStrategy(as *AutoScaler, name string) (scaleStrategy, error) {
+	switch name {
+	case "", ScaleStrategyDelete:
+		return &deleteStrategy{as: as}, nil
+	case ScaleStrategyPauseImage:
+		return &pauseImageStrategy{as: as}, nil
+	case ScaleStrategyVLLMSleep:
+		return &vllmSleepStrategy{
+			as:              as,
+			client:          newVLLMSleepClient(as.targetURL, as.vllmTransport),
+			level:           as.config.SleepLevel,
+			deepIdleTimeout: as.config.GetDeepIdleTimeout(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown scale strategy %q", name)
+	}
+}
+
+// deleteStrategy creates the pod on demand and deletes it when idle
+type deleteStrategy struct {
+	as *AutoScaler
+}
+
+func (s *deleteStrategy) name() string {
+	return ScaleStrategyDelete
+}
+
+func (s *deleteStrategy) isUp(ctx context.Context) (bool, error) {
+	return s.as.podExists(ctx)
+}
+
+func (s *deleteStrategy) scaleUp(ctx context.Context) error {
+	return s.as.managePod(ctx, true)
+}
+
+func (s *deleteStrategy) scaleDown(ctx context.Context) error {
+	return s.as.managePod(ctx, false)
+}
+
+// pauseImageStrategy keeps the pod scheduled and swaps the vLLM container for the pause image when idle
+type pauseImageStrategy struct {
+	as *AutoScaler
+}
+
+func (s *pauseImageStrategy) name() string {
+	return ScaleStrategyPauseImage
+}
+
+func (s *pauseImageStrategy) isUp(ctx context.Context) (bool, error) {
+	exists, err := s.as.podExists(ctx)
+	if err != nil || !exists {
+		return false, err
+	}
+	paused, err := s.as.k8sManager.IsVLLMContainerPaused(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !paused, nil
+}
+
+func (s *pauseImageStrategy) scaleUp(ctx context.Context) error {
+	paused, err := s.as.k8sManager.IsVLLMContainerPaused(ctx)
+	if err != nil {
+		return err
+	}
+	if !paused {
+		// No pod to resume
+		return s.as.managePod(ctx, true)
+	}
+
+	return s.as.runInPlaceScaleOp(true, func() error {
+		return s.as.k8sManager.ResumeVLLMContainer(ctx)
+	})
+}
+
+func (s *pauseImageStrategy) scaleDown(ctx context.Context) error {
+	err := s.as.runInPlaceScaleOp(false, func() error {
+		return s.as.k8sManager.PauseVLLMContainer(ctx)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Paused vLLM for %s/%s, the pod keeps its GPU allocation", s.as.config.Namespace, s.as.config.Deployment)
+	return nil
+}
+
+// runInPlaceScaleOp runs a scale operation that keeps the pod, recording the same metrics as managePod
+func (as *AutoScaler) runInPlaceScaleOp(up bool, op func() error) error {
+	start := time.Now()
+	direction := "up"
+	if up {
+		as.setVLLMState(1) // starting
+	} else {
+		direction = "down"
+		as.setVLLMState(3) // stopping
+	}
+
+	if err := op(); err != nil {
+		as.metrics.RecordScaleOp(direction, false, time.Since(start))
+		if up {
+			as.startFailed("ResumeFailed", err.Error())
+			as.setVLLMState(0) // failed to start, still released
+		} else {
+			as.setVLLMState(2) // failed to stop, keep as running
+		}
+		return err
+	}
+
+	as.metrics.RecordScaleOp(direction, true, time.Since(start))
+	if up {
+		as.metrics.UpdateReplicas(1)
+	} else {
+		as.metrics.UpdateReplicas(0)
+		as.setVLLMState(0) // stopped
+	}
+	return nil
+}
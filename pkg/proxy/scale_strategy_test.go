@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newStrategyTestAutoScaler(t *testing.T, strategy string, objects ...*corev1.Pod) (*AutoScaler, *fake.Clientset) {
+	t.Helper()
+	clientset := fake.NewSimpleClientset()
+	for _, pod := range objects {
+		_, err := clientset.CoreV1().Pods(pod.Namespace).Create(context.Background(), pod, metav1.CreateOptions{})
+		require.NoError(t, err)
+	}
+
+	as := &AutoScaler{
+		config:    &Config{Namespace: "vllm", Deployment: "vllm", ScaleStrategy: strategy},
+		crdClient: newFakeCRDClient(t),
+		k8sManager: kubernetes.NewK8sManager(clientset, &kubernetes.Config{
+			Namespace:  "vllm",
+			Deployment: "vllm",
+			PauseImage: kubernetes.DefaultPauseImage,
+		}),
+		metrics: stats.NewMetricsRecorder(),
+	}
+	as.runner = as.k8sManager
+	var err error
+	as.strategy, err = newScaleStrategy(as, strategy)
+	require.NoError(t, err)
+	return as, clientset
+}
+
+func vllmPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm", Namespace: "vllm"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "vllm", Image: "vllm/vllm-openai:latest"}},
+		},
+	}
+}
+
+func TestNewScaleStrategy(t *testing.T) {
+	as := &AutoScaler{}
+
+	for name, want := range map[string]string{
+		"":                      ScaleStrategyDelete,
+		ScaleStrategyDelete:     ScaleStrategyDelete,
+		ScaleStrategyPauseImage: ScaleStrategyPauseImage,
+	} {
+		strategy, err := newScaleStrategy(as, name)
+		require.NoError(t, err)
+		assert.Equal(t, want, strategy.name())
+	}
+
+	_, err := newScaleStrategy(as, "hibernate")
+	assert.Error(t, err)
+}
+
+func TestDeleteStrategy_ScaleDown(t *testing.T) {
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, vllmPod())
+	ctx := context.Background()
+
+	up, err := as.strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.True(t, up)
+
+	require.NoError(t, as.strategy.scaleDown(ctx))
+
+	_, err = clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+	assert.Error(t, err)
+	up, err = as.strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.False(t, up)
+}
+
+func TestPauseImageStrategy_PauseAndResume(t *testing.T) {
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyPauseImage, vllmPod())
+	ctx := context.Background()
+
+	require.NoError(t, as.strategy.scaleDown(ctx))
+
+	pod, err := clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+	require.NoError(t, err, "pause keeps the pod")
+	assert.Equal(t, kubernetes.DefaultPauseImage, pod.Spec.Containers[0].Image)
+	up, err := as.strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.False(t, up)
+
+	require.NoError(t, as.strategy.scaleUp(ctx))
+
+	pod, err = clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "vllm/vllm-openai:latest", pod.Spec.Containers[0].Image)
+	up, err = as.strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.True(t, up)
+}
+
+func TestPauseImageStrategy_NoPod(t *testing.T) {
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyPauseImage)
+
+	up, err := as.strategy.isUp(context.Background())
+	require.NoError(t, err)
+	assert.False(t, up)
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/schedule"
+)
+
+// warmWindow returns the warm-up window open at now, if any
+func (as *AutoScaler) warmWindow(now time.Time) (schedule.Window, bool) {
+	return as.config.GetWarmSchedule().Active(now.In(as.config.GetScheduleLocation()))
+}
+
+// idleTimeout returns the idle timeout in effect at now, shortened during aggressive windows
+func (as *AutoScaler) idleTimeout(now time.Time) time.Duration {
+	if _, ok := as.config.GetAggressiveSchedule().Active(now.In(as.config.GetScheduleLocation())); ok {
+		return as.config.GetAggressiveIdleTimeout()
+	}
+	return as.config.GetIdleTimeout()
+}
+
+// prewarm starts the active model in the background when a warm-up window is open and it is down,
+// so the first request of the window doesn't wait for a cold start
+func (as *AutoScaler) prewarm(ctx context.Context, window schedule.Window) {
+	up, err := as.strategy.isUp(ctx)
+	if err != nil {
+		log.Printf("Failed to check pod existence: %v", err)
+		return
+	}
+	if up || !as.prewarming.CompareAndSwap(false, true) {
+		return
+	}
+
+	log.Printf("Warm-up window %q open, starting %s", window.Spec, as.GetActiveModel())
+	go func() {
+		defer as.prewarming.Store(false)
+		if err := as.ensureScaledUp(context.Background()); err != nil {
+			log.Printf("Failed to pre-warm %s: %v", as.GetActiveModel(), err)
+		}
+	}()
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// scheduleTestAutoScaler returns an autoscaler idle for the given time at now, with the vLLM pod
+// running when pods are given
+func scheduleTestAutoScaler(t *testing.T, now time.Time, idle time.Duration, pods ...*corev1.Pod) (*AutoScaler, *fake.Clientset) {
+	t.Helper()
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, pods...)
+	as.crdClient = newFakeCRDClient(t, replicaModelSpec(0, 1))
+	as.activeModel = "qwen"
+	as.config.IdleTimeout = "5m"
+	as.config.ScheduleTimezone = "Europe/Paris"
+	as.lastActivity = now.Add(-idle)
+	as.scaleUpCond = sync.NewCond(&as.mu)
+	return as, clientset
+}
+
+func podExists(t *testing.T, clientset *fake.Clientset) bool {
+	t.Helper()
+	_, err := clientset.CoreV1().Pods("vllm").Get(context.Background(), "vllm", metav1.GetOptions{})
+	return err == nil
+}
+
+func TestCheckIdleWarmSchedule(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	// Monday at 10:00 in Paris, inside the 08:00-18:00 weekday window
+	monday := time.Date(2025, time.January, 6, 10, 0, 0, 0, paris)
+
+	t.Run("running model kept warm while idle", func(t *testing.T) {
+		as, clientset := scheduleTestAutoScaler(t, monday, time.Hour, vllmPod())
+		as.config.WarmSchedule = "0 8 * * 1-5 10h"
+
+		as.checkIdle(context.Background(), monday)
+		assert.True(t, podExists(t, clientset))
+	})
+
+	t.Run("released once the window closes", func(t *testing.T) {
+		as, clientset := scheduleTestAutoScaler(t, monday, time.Hour, vllmPod())
+		as.config.WarmSchedule = "0 8 * * 1-5 1h"
+
+		as.checkIdle(context.Background(), monday)
+		assert.False(t, podExists(t, clientset))
+	})
+
+	t.Run("stopped model started when the window opens", func(t *testing.T) {
+		as, clientset := scheduleTestAutoScaler(t, monday, time.Hour)
+		as.config.WarmSchedule = "0 8 * * 1-5 10h"
+
+		as.checkIdle(context.Background(), monday)
+		assert.Eventually(t, func() bool { return podExists(t, clientset) }, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("schedule evaluated in its time zone", func(t *testing.T) {
+		as, clientset := scheduleTestAutoScaler(t, monday, time.Hour, vllmPod())
+		as.config.WarmSchedule = "0 10 * * 1-5 1m"
+
+		as.checkIdle(context.Background(), monday.UTC())
+		assert.True(t, podExists(t, clientset), "10:00 in Paris is 09:00 UTC")
+	})
+}
+
+func TestCheckIdleAggressiveSchedule(t *testing.T) {
+	night := time.Date(2025, time.January, 6, 23, 0, 0, 0, time.UTC)
+
+	as, clientset := scheduleTestAutoScaler(t, night, 2*time.Minute, vllmPod())
+	as.config.ScheduleTimezone = "UTC"
+	as.checkIdle(context.Background(), night)
+	assert.True(t, podExists(t, clientset), "idle for less than the idle timeout")
+
+	as.config.AggressiveSchedule = "0 20 * * * 12h"
+	as.config.AggressiveIdleTimeout = "1m"
+	as.checkIdle(context.Background(), night)
+	assert.False(t, podExists(t, clientset), "idle for longer than the aggressive idle timeout")
+}
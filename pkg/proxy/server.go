@@ -0,0 +1,172 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+// exitTimeout bounds the work done on exit, after the connections are drained
+const exitTimeout = 30 * time.Second
+
+// listen returns the socket passed by systemd socket activation (LISTEN_PID/LISTEN_FDS) when there
+// is one, so an upgraded binary takes over the listener without refusing connections, or listens on the port
+func listen(port string) (net.Listener, error) {
+	if os.Getenv("LISTEN_PID") == strconv.Itoa(os.Getpid()) {
+		if fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS")); fds > 0 {
+			// Child processes must not inherit the activation
+			_ = os.Unsetenv("LISTEN_PID")
+			_ = os.Unsetenv("LISTEN_FDS")
+			_ = os.Unsetenv("LISTEN_FDNAMES")
+
+			file := os.NewFile(listenFDsStart, "listen-fd")
+			defer func() {
+				_ = file.Close()
+			}()
+			ln, err := net.FileListener(file)
+			if err != nil {
+				return nil, fmt.Errorf("failed to use the activated socket: %w", err)
+			}
+			log.Printf("Listening on activated socket %s", ln.Addr())
+			return ln, nil
+		}
+	}
+
+	ln, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %s: %w", port, err)
+	}
+	log.Printf("Listening on %s", ln.Addr())
+	return ln, nil
+}
+
+// listenAndServe serves the handler until SIGTERM or an interrupt, then drains
+func (as *AutoScaler) listenAndServe(handler http.Handler) error {
+	ln, err := listen(as.config.Port)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(stop)
+	return as.serve(&http.Server{Handler: handler, TLSConfig: as.serverTLSConfig(), Protocols: serverProtocols()}, ln, stop)
+}
+
+// serve serves HTTP, or HTTPS when the server has TLS settings, until a stop signal, then reports
+// not ready for the drain delay while still accepting connections, so the Service routes new
+// requests to the replacement pod, and finally stops accepting, drains the in-flight requests and
+// runs the exit tasks
+func (as *AutoScaler) serve(server *http.Server, ln net.Listener, stop <-chan os.Signal) error {
+	errs := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			errs <- server.ServeTLS(ln, "", "")
+			return
+		}
+		errs <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-stop:
+		as.draining.Store(true)
+		delay := as.config.GetDrainDelay()
+		log.Printf("Received %s, reporting not ready for %s before closing the listener", sig, delay)
+		select {
+		case <-time.After(delay):
+		case err := <-errs:
+			return err
+		}
+	}
+
+	err := as.drain(server, errs)
+	as.exit()
+	return err
+}
+
+// drain stops accepting connections and waits for the in-flight requests, SSE streams included,
+// to complete, closing the remaining connections once the drain timeout expires
+func (as *AutoScaler) drain(server *http.Server, errs <-chan error) error {
+	ctx := context.Background()
+	if timeout := as.config.GetDrainTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+		log.Printf("Listener closed, waiting up to %s for in-flight requests to complete", timeout)
+	} else {
+		log.Printf("Listener closed, waiting for in-flight requests to complete")
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("failed to drain connections: %w", err)
+		}
+		log.Printf("Drain timeout expired, closing the remaining connections")
+		_ = server.Close()
+	} else {
+		log.Printf("All in-flight requests completed")
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// exit saves the usage accounting, releases vLLM when configured, delivers the queued notifications and audit
+// records and then releases the leader lease; by default vLLM keeps running so the replacement proxy serves the
+// next requests without a cold start
+func (as *AutoScaler) exit() {
+	ctx, cancel := context.WithTimeout(context.Background(), exitTimeout)
+	defer cancel()
+
+	if as.config.UsageConfigMap != "" {
+		as.persistUsage(ctx)
+	}
+	// The other replicas keep using vLLM when this one follows
+	if as.config.ScaleDownOnExit && as.leading() {
+		log.Printf("Releasing vLLM on exit (%s)...", as.strategy.name())
+		as.removeReplicas(ctx)
+		if err := as.strategy.scaleDown(ctx); err != nil {
+			log.Printf("Failed to release vLLM on exit: %v", err)
+		}
+	}
+	// A child vLLM process would outlive the proxy, unlike a pod, a container or a systemd unit
+	if as.config.Backend == BackendProcess && as.config.SystemdUnit == "" {
+		if err := as.runner.DeletePod(ctx); err != nil {
+			log.Printf("Failed to stop the vLLM process on exit: %v", err)
+		}
+	}
+	as.notifier.Close(ctx)
+	as.audit.Close(ctx)
+	// Another replica takes over without waiting for the lease to expire
+	as.leader.release()
+}
+
+// readyzHandler reports the proxy ready until it starts draining for shutdown. With
+// ReadyzRequiresVLLM, the proxy is only ready while vLLM is.
+func (as *AutoScaler) readyzHandler(c *gin.Context) {
+	if as.draining.Load() {
+		c.String(http.StatusServiceUnavailable, "draining")
+		return
+	}
+	if as.config.ReadyzRequiresVLLM && !as.isPodReady(c.Request.Context()) {
+		c.String(http.StatusServiceUnavailable, "vllm "+as.vllmStateName())
+		return
+	}
+	c.String(http.StatusOK, "OK")
+}
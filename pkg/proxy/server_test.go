@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReadyzHandler(t *testing.T) {
+	as := &AutoScaler{config: &Config{}}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", as.readyzHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	as.draining.Store(true)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "a draining proxy leaves the Service endpoints")
+}
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	as := &AutoScaler{config: &Config{DrainDelay: "50ms"}}
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- as.serve(&http.Server{Handler: handler}, ln, stop)
+	}()
+
+	responses := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/v1/chat/completions")
+		if err == nil {
+			responses <- resp
+		}
+		close(responses)
+	}()
+	<-started
+
+	stop <- syscall.SIGTERM
+	require.Eventually(t, as.draining.Load, time.Second, 5*time.Millisecond)
+	select {
+	case err := <-served:
+		t.Fatalf("serve returned with a request in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	resp := <-responses
+	require.NotNil(t, resp, "the in-flight request completes")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+	assert.NoError(t, <-served)
+}
+
+func TestServeCutsStreamsAfterDrainTimeout(t *testing.T) {
+	as := &AutoScaler{config: &Config{DrainTimeout: "50ms"}}
+
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		close(started)
+		<-r.Context().Done()
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- as.serve(&http.Server{Handler: handler}, ln, stop)
+	}()
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/v1/chat/completions")
+	require.NoError(t, err)
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	<-started
+
+	stop <- syscall.SIGTERM
+	select {
+	case err := <-served:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after the drain timeout")
+	}
+	_, err = io.ReadAll(resp.Body)
+	assert.Error(t, err, "the stream is cut")
+}
+
+func TestExit(t *testing.T) {
+	ctx := context.Background()
+
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete, vllmPod())
+	as.exit()
+	_, err := clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+	assert.NoError(t, err, "vLLM keeps running for the next proxy by default")
+
+	as.config.ScaleDownOnExit = true
+	as.exit()
+	_, err = clientset.CoreV1().Pods("vllm").Get(ctx, "vllm", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err), "vLLM is released when configured")
+}
+
+func TestListenWithoutSocketActivation(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	ln, err := listen("0")
+	require.NoError(t, err, "sockets activated for another process are ignored")
+	assert.IsType(t, &net.TCPAddr{}, ln.Addr())
+	_ = ln.Close()
+}
+
+func TestReadyzHandler_RequiresVLLM(t *testing.T) {
+	as := newReadyAutoScaler(t, "http://vllm-api", false)
+	as.config.ReadyzRequiresVLLM = true
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", as.readyzHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	require.NoError(t, as.k8sManager.DeletePod(context.Background()))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code, "a scaled-down vLLM leaves the load balancer")
+	assert.Equal(t, "vllm stopped", w.Body.String())
+}
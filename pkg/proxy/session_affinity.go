@@ -0,0 +1,216 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionAffinityPollInterval is how often a deferred model switch checks the active sessions
+const sessionAffinityPollInterval = time.Second
+
+type affinitySessionKey struct{}
+
+// affinitySession is the model a conversation uses and its last request
+type affinitySession struct {
+	model    string
+	lastSeen time.Time
+}
+
+// sessionAffinity keeps conversations on the model they started with: a request for another
+// model doesn't switch the active model while sessions using it are active, i.e. sent a request
+// within the idle time
+type sessionAffinity struct {
+	mu       sync.Mutex
+	idle     time.Duration
+	sessions map[string]*affinitySession
+	now      func() time.Time
+}
+
+// newSessionAffinity creates a tracker considering sessions idle after idle without requests
+func newSessionAffinity(idle time.Duration) *sessionAffinity {
+	return &sessionAffinity{
+		idle:     idle,
+		sessions: make(map[string]*affinitySession),
+		now:      time.Now,
+	}
+}
+
+// touch records a request of the session for the model, forgetting idle sessions
+func (s *sessionAffinity) touch(session, model string) {
+	if session == "" || model == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sessions[session] = &affinitySession{model: model, lastSeen: now}
+	for id, other := range s.sessions {
+		if now.Sub(other.lastSeen) >= s.idle {
+			delete(s.sessions, id)
+		}
+	}
+}
+
+// holders returns the number of active sessions using the model, besides the given session
+func (s *sessionAffinity) holders(model, session string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	count := 0
+	for id, other := range s.sessions {
+		if id != session && other.model == model && now.Sub(other.lastSeen) < s.idle {
+			count++
+		}
+	}
+	return count
+}
+
+// count returns the number of active sessions
+func (s *sessionAffinity) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	count := 0
+	for _, other := range s.sessions {
+		if now.Sub(other.lastSeen) < s.idle {
+			count++
+		}
+	}
+	return count
+}
+
+// waitIdle waits up to timeout for the sessions using the model, besides the given session, to go
+// idle, and returns the number still active
+func (s *sessionAffinity) waitIdle(ctx context.Context, model, session string, timeout time.Duration) int {
+	holders := s.holders(model, session)
+	if holders == 0 || timeout <= 0 {
+		return holders
+	}
+	log.Printf("Deferring the switch from model %s for up to %s: %d active sessions use it", model, timeout, holders)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(sessionAffinityPollInterval)
+	defer ticker.Stop()
+	for holders > 0 {
+		select {
+		case <-ctx.Done():
+			return holders
+		case <-ticker.C:
+		}
+		holders = s.holders(model, session)
+	}
+	return holders
+}
+
+// SessionActiveError is returned for a request for another model than the one active sessions use
+type SessionActiveError struct {
+	RequestedModel string
+	ActiveModel    string
+	Sessions       int
+	Idle           time.Duration
+}
+
+func (e *SessionActiveError) Error() string {
+	return fmt.Sprintf("model '%s' can't be activated while %d sessions use '%s'", e.RequestedModel, e.Sessions, e.ActiveModel)
+}
+
+// writeSessionActive answers a request for another model than the one active sessions use
+func writeSessionActive(w http.ResponseWriter, err *SessionActiveError) {
+	w.Header().Set("Retry-After", fmt.Sprintf("%d", int(err.Idle.Seconds())))
+	writeAPIError(w, http.StatusConflict,
+		fmt.Sprintf("Model '%s' is in use by %d active sessions, model '%s' can't be activated until they are idle for %s. Please retry later or use model '%s'.",
+			err.ActiveModel, err.Sessions, err.RequestedModel, err.Idle, err.ActiveModel),
+		"invalid_request_error", "model_in_use")
+}
+
+// checkSessionAffinity refuses to switch from the active model while other sessions use it,
+// after waiting for them to go idle for at most the configured time
+func (as *AutoScaler) checkSessionAffinity(ctx context.Context, requestedModel string) error {
+	activeModel := as.GetActiveModel()
+	if as.affinity == nil || requestedModel == "" || requestedModel == activeModel {
+		return nil
+	}
+	session := affinitySessionFrom(ctx)
+	holders := as.affinity.waitIdle(ctx, activeModel, session, as.config.GetSessionAffinityWait())
+	if holders == 0 {
+		return nil
+	}
+	return &SessionActiveError{RequestedModel: requestedModel, ActiveModel: activeModel, Sessions: holders, Idle: as.affinity.idle}
+}
+
+// touchSession records that the request's session uses the active model
+func (as *AutoScaler) touchSession(ctx context.Context) {
+	if as.affinity != nil {
+		as.affinity.touch(affinitySessionFrom(ctx), as.GetActiveModel())
+	}
+}
+
+// affinitySessionFrom returns the session of a request, empty when it names none
+func affinitySessionFrom(ctx context.Context) string {
+	session, _ := ctx.Value(affinitySessionKey{}).(string)
+	return session
+}
+
+// anthropicSession returns the metadata.user_id of an Anthropic request body, which Claude Code
+// sets per session
+func anthropicSession(body []byte) string {
+	var fields struct {
+		Metadata struct {
+			UserID string `json:"user_id"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(fields.Metadata.UserID)
+}
+
+// sessionAffinityMiddleware identifies the session of /v1 requests by its X-Session-ID or
+// X-Conversation-ID header, or else the metadata.user_id of /v1/messages bodies
+func (as *AutoScaler) sessionAffinityMiddleware(c *gin.Context) {
+	r := c.Request
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, "/v1/") {
+		c.Next()
+		return
+	}
+
+	session := sessionID(r)
+	if session == "" && r.URL.Path == "/v1/messages" && r.Body != nil {
+		limit := as.config.GetMaxRequestBodySize()
+		if limit > 0 && r.ContentLength > limit {
+			c.Next()
+			return
+		}
+		reader := io.Reader(r.Body)
+		if limit > 0 {
+			reader = io.LimitReader(r.Body, limit+1)
+		}
+		body, err := io.ReadAll(reader)
+		r.Body = replayBody(body, r.Body)
+		if err == nil && (limit <= 0 || int64(len(body)) <= limit) {
+			if user := anthropicSession(body); user != "" {
+				session = clientID(r) + "/" + user
+			}
+		}
+	}
+	if session == "" {
+		c.Next()
+		return
+	}
+
+	c.Request = r.WithContext(context.WithValue(r.Context(), affinitySessionKey{}, session))
+	c.Next()
+}
@@ -0,0 +1,144 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionAffinity_Holders(t *testing.T) {
+	now := time.Now()
+	affinity := newSessionAffinity(5 * time.Minute)
+	affinity.now = func() time.Time { return now }
+
+	affinity.touch("a", "qwen")
+	affinity.touch("b", "qwen")
+	affinity.touch("c", "llama")
+	affinity.touch("", "qwen")
+	assert.Equal(t, 2, affinity.holders("qwen", ""))
+	assert.Equal(t, 1, affinity.holders("qwen", "a"), "a session doesn't hold the model against itself")
+	assert.Equal(t, 3, affinity.count())
+
+	// Sessions go idle without requests
+	now = now.Add(3 * time.Minute)
+	affinity.touch("a", "qwen")
+	now = now.Add(3 * time.Minute)
+	assert.Equal(t, 1, affinity.holders("qwen", ""))
+	assert.Equal(t, 0, affinity.holders("llama", ""))
+	assert.Equal(t, 1, affinity.count())
+
+	// A session switching models holds the new one
+	affinity.touch("a", "llama")
+	assert.Equal(t, 0, affinity.holders("qwen", ""))
+	assert.Equal(t, 1, affinity.holders("llama", ""))
+}
+
+func TestSessionAffinity_WaitIdle(t *testing.T) {
+	affinity := newSessionAffinity(200 * time.Millisecond)
+	affinity.touch("a", "qwen")
+
+	assert.Equal(t, 1, affinity.waitIdle(context.Background(), "qwen", "b", 0), "no wait refuses at once")
+	assert.Equal(t, 0, affinity.waitIdle(context.Background(), "qwen", "b", 5*time.Second))
+
+	affinity.touch("a", "qwen")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, 1, affinity.waitIdle(ctx, "qwen", "b", 5*time.Second), "the client gave up")
+}
+
+func TestAnthropicSession(t *testing.T) {
+	assert.Equal(t, "user_abc_account__session_123", anthropicSession([]byte(`{"model":"qwen","metadata":{"user_id":"user_abc_account__session_123"}}`)))
+	assert.Empty(t, anthropicSession([]byte(`{"model":"qwen","user":"alice"}`)))
+	assert.Empty(t, anthropicSession([]byte(`not json`)))
+}
+
+func TestSessionAffinityMiddleware(t *testing.T) {
+	as := &AutoScaler{config: &Config{}, affinity: newSessionAffinity(time.Minute)}
+	router := gin.New()
+	router.Use(as.sessionAffinityMiddleware)
+	var session, body string
+	router.POST("/v1/*path", func(c *gin.Context) {
+		session = affinitySessionFrom(c.Request.Context())
+		data, _ := c.GetRawData()
+		body = string(data)
+	})
+
+	send := func(path, payload, header string) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(payload))
+		if header != "" {
+			r.Header.Set("X-Session-ID", header)
+		}
+		r.Header.Set("Authorization", "Bearer sk-a")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	key := httptest.NewRequest(http.MethodPost, "/", nil)
+	key.Header.Set("Authorization", "Bearer sk-a")
+	client := clientID(key)
+
+	payload := `{"model":"qwen","metadata":{"user_id":"session-1"}}`
+	send("/v1/messages", payload, "")
+	assert.Equal(t, client+"/session-1", session)
+	assert.Equal(t, payload, body, "the body is replayed")
+
+	send("/v1/messages", payload, "conversation-1")
+	assert.Equal(t, client+"/conversation-1", session, "the header wins over the metadata")
+
+	send("/v1/chat/completions", `{"model":"qwen","metadata":{"user_id":"session-1"}}`, "")
+	assert.Empty(t, session, "metadata is only read on Anthropic requests")
+}
+
+func TestProxyHandler_SessionAffinity(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	model := func(served string) map[string]interface{} {
+		spec := replicaModelSpec(0, 1)
+		spec["servedModelName"] = served
+		spec["modelName"] = "test/" + served
+		return spec
+	}
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	as.crdClient = newFakeCRDClient(t, model("qwen"), model("llama"))
+	as.affinity = newSessionAffinity(time.Minute)
+
+	send := func(session, model string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","messages":[{"role":"user","content":"Hi"}]}`))
+		r = r.WithContext(context.WithValue(r.Context(), affinitySessionKey{}, session))
+		w := httptest.NewRecorder()
+		as.proxyHandler(w, r)
+		return w
+	}
+
+	require.Equal(t, http.StatusOK, send("a", "qwen").Code)
+	assert.Equal(t, 1, as.affinity.holders("qwen", ""))
+
+	// Another session can't switch the model the first one uses
+	w := send("b", "llama")
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+	var response struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "model_in_use", response.Error.Code)
+	assert.Equal(t, "qwen", as.GetActiveModel())
+
+	// The session using the model may switch it
+	require.Equal(t, http.StatusOK, send("a", "llama").Code)
+	assert.Equal(t, "llama", as.GetActiveModel())
+}
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionHeaders carry the client's session or conversation ID, in order of preference
+var sessionHeaders = []string{"X-Session-ID", "X-Conversation-ID"}
+
+// sessionUsage is the cumulative token usage of a session
+type sessionUsage struct {
+	tokens   int
+	lastSeen time.Time
+}
+
+// sessionBudgets tracks cumulative token usage per session and enforces a budget on it.
+// Sessions idle for the TTL are forgotten, so an abandoned session ID starts over.
+type sessionBudgets struct {
+	mu        sync.Mutex
+	budget    int
+	ttl       time.Duration
+	sessions  map[string]*sessionUsage
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// newSessionBudgets creates a tracker allowing budget tokens per session
+func newSessionBudgets(budget int, ttl time.Duration) *sessionBudgets {
+	return &sessionBudgets{
+		budget:   budget,
+		ttl:      ttl,
+		sessions: make(map[string]*sessionUsage),
+		now:      time.Now,
+	}
+}
+
+// sessionID identifies a session by the client's API key and session header, empty without a header
+func sessionID(r *http.Request) string {
+	for _, header := range sessionHeaders {
+		if session := strings.TrimSpace(r.Header.Get(header)); session != "" {
+			return clientID(r) + "/" + session
+		}
+	}
+	return ""
+}
+
+// used returns the tokens a session used so far, 0 for unknown or expired sessions
+func (s *sessionBudgets) used(session string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	usage, ok := s.sessions[session]
+	if !ok || s.now().Sub(usage.lastSeen) >= s.ttl {
+		return 0
+	}
+	return usage.tokens
+}
+
+// charge adds the tokens of a completed request to its session
+func (s *sessionBudgets) charge(session string, tokens int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	usage, ok := s.sessions[session]
+	if !ok || now.Sub(usage.lastSeen) >= s.ttl {
+		usage = &sessionUsage{}
+		s.sessions[session] = usage
+	}
+	usage.tokens += tokens
+	usage.lastSeen = now
+
+	if now.Sub(s.lastPrune) >= s.ttl {
+		for id, other := range s.sessions {
+			if now.Sub(other.lastSeen) >= s.ttl {
+				delete(s.sessions, id)
+			}
+		}
+		s.lastPrune = now
+	}
+}
+
+// count returns the number of tracked sessions
+func (s *sessionBudgets) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// sessionBudgetMiddleware rejects /v1 and passthrough requests of sessions that used up their token
+// budget and charges the tokens the others used, stopping runaway agent loops
+func (as *AutoScaler) sessionBudgetMiddleware(c *gin.Context) {
+	r := c.Request
+	session := sessionID(r)
+	if session == "" || !as.apiRequest(r) {
+		c.Next()
+		return
+	}
+
+	if used := as.sessions.used(session); used >= as.sessions.budget {
+		log.Printf("Rejected %s %s: session used %d tokens, at its budget of %d", r.Method, r.URL.Path, used, as.sessions.budget)
+		writeAPIError(c.Writer, http.StatusTooManyRequests,
+			fmt.Sprintf("This session used %d tokens and reached its budget of %d tokens. Start a new session, or wait %s without requests for it to reset.",
+				used, as.sessions.budget, as.sessions.ttl),
+			"insufficient_quota", "session_budget_exceeded")
+		as.metrics.RecordRequest(r.Method, r.URL.Path, http.StatusTooManyRequests, 0, 0, int64(c.Writer.Size()))
+		c.Abort()
+		return
+	}
+
+	uw, done := trackUsage(c)
+	defer done()
+	c.Next()
+	as.sessions.charge(session, uw.tokens(r.ContentLength))
+}
@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	assert.Empty(t, sessionID(r), "requests without a session header are not tracked")
+
+	r.Header.Set("X-Conversation-ID", "conv-1")
+	assert.Equal(t, anonymousClient+"/conv-1", sessionID(r))
+
+	r.Header.Set("X-Session-ID", "agent-run")
+	r.Header.Set("Authorization", "Bearer sk-a")
+	keyed := sessionID(r)
+	r.Header.Set("Authorization", "Bearer sk-b")
+	assert.NotEqual(t, keyed, sessionID(r), "sessions are scoped to the API key")
+}
+
+func TestSessionBudgets(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sessions := newSessionBudgets(100, time.Hour)
+	sessions.now = clock.Now
+
+	sessions.charge("a", 60)
+	sessions.charge("a", 50)
+	sessions.charge("b", 10)
+	assert.Equal(t, 110, sessions.used("a"))
+	assert.Equal(t, 10, sessions.used("b"))
+
+	clock.now = clock.now.Add(59 * time.Minute)
+	sessions.charge("b", 10)
+	clock.now = clock.now.Add(time.Minute)
+	assert.Zero(t, sessions.used("a"), "idle sessions are forgotten")
+	assert.Equal(t, 20, sessions.used("b"))
+
+	sessions.charge("b", 5)
+	assert.Equal(t, 1, sessions.count(), "expired sessions are pruned")
+}
+
+func TestSessionBudgetMiddleware(t *testing.T) {
+	as := &AutoScaler{config: &Config{}, sessions: newSessionBudgets(100, time.Hour), metrics: stats.NewMetricsRecorder()}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.sessionBudgetMiddleware)
+	router.NoRoute(func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString(`{"usage":{"total_tokens":60}}`)
+	})
+
+	send := func(session string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`))
+		if session != "" {
+			r.Header.Set("X-Session-ID", session)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, send("loop").Code)
+	assert.Equal(t, http.StatusOK, send("loop").Code, "the request crossing the budget completes")
+
+	w := send("loop")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"session_budget_exceeded"`)
+	assert.Contains(t, w.Body.String(), "used 120 tokens")
+
+	assert.Equal(t, http.StatusOK, send("other").Code, "other sessions keep their budget")
+	for range 3 {
+		assert.Equal(t, http.StatusOK, send("").Code, "requests without a session are not limited")
+	}
+}
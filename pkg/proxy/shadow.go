@@ -0,0 +1,248 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+const (
+	maxShadowRequests    = 16              // Mirrored requests in flight at once, the others are dropped
+	shadowRequestTimeout = 5 * time.Minute // Time the shadow model gets to answer once ready
+	shadowLogLimit       = 4096            // Bytes of a shadow response logged
+)
+
+// shadowPaths are the completion endpoints whose traffic is mirrored to the shadow model
+var shadowPaths = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+	anthropicMessagesPath:  true,
+}
+
+// shadowBackend mirrors a sample of the completion traffic of the primary model to a second model,
+// served by its own pod, to compare them on production traffic. Mirrored requests are sent in the
+// background once the primary model is ready: the clients never wait for the shadow model nor see
+// its responses, which are discarded or logged.
+type shadowBackend struct {
+	k8sManager    podManager
+	crdClient     modelGetter
+	modelID       string
+	targetURL     *url.URL
+	samplePercent int
+	logResponses  bool
+	client        *http.Client
+	slots         chan struct{} // Mirrored requests in flight
+	lastActivity  time.Time
+	mu            sync.Mutex     // Guards lastActivity
+	scaleMu       sync.Mutex     // Serializes pod creation
+	coldStarts    *coldStartGate // Shared with the other models, nil when unlimited
+	leader        *leaderElector // Starts the pod when this replica follows, nil without leader election
+	metrics       *stats.MetricsRecorder
+	apiKey        func(ctx context.Context) string // API key of the shadow pod sent in place of the client's, nil forwards the client's
+}
+
+// newShadowBackend creates the shadow backend mirroring samplePercent% of the requests to the model
+func newShadowBackend(k8sManager podManager, crdClient modelGetter, modelID string, targetURL *url.URL, samplePercent int, logResponses bool) *shadowBackend {
+	return &shadowBackend{
+		k8sManager:    k8sManager,
+		crdClient:     crdClient,
+		modelID:       modelID,
+		targetURL:     targetURL,
+		samplePercent: samplePercent,
+		logResponses:  logResponses,
+		client:        &http.Client{},
+		slots:         make(chan struct{}, maxShadowRequests),
+		lastActivity:  time.Now(),
+	}
+}
+
+// mirror sends a copy of a sampled completion request to the shadow model in the background. The
+// request body is read and restored for the primary model. Requests to the shadow model itself, and
+// those arriving while too many mirrored requests are in flight, are not mirrored.
+func (s *shadowBackend) mirror(r *http.Request, model string) {
+	if r.Method != http.MethodPost || !shadowPaths[r.URL.Path] || model == s.modelID || rand.Intn(100) >= s.samplePercent {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body = newBodyReaderFromBytes(body)
+	if err != nil {
+		log.Printf("[SHADOW] Failed to read the body of %s %s: %v", r.Method, r.URL.Path, err)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.metrics.RecordShadowRequest("dropped", 0)
+		return
+	}
+
+	target := *s.targetURL
+	target.Path = r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+	// The shadow request outlives the client's, it keeps the trace but not the cancellation
+	shadow, err := http.NewRequestWithContext(context.WithoutCancel(r.Context()), http.MethodPost, target.String(), bytes.NewReader(body))
+	if err != nil {
+		<-s.slots
+		log.Printf("[SHADOW] Failed to create the mirrored request: %v", err)
+		return
+	}
+	shadow.Header = r.Header.Clone()
+	// Let the transport negotiate compression, so logged responses are readable
+	shadow.Header.Del("Accept-Encoding")
+	if s.apiKey != nil {
+		if apiKey := s.apiKey(shadow.Context()); apiKey != "" {
+			shadow.Header.Set("Authorization", "Bearer "+apiKey)
+			shadow.Header.Del("X-Api-Key")
+		}
+	}
+	if err := rewriteRequestModel(shadow, s.modelID); err != nil {
+		<-s.slots
+		log.Printf("[SHADOW] Failed to rewrite the mirrored request: %v", err)
+		return
+	}
+
+	go func() {
+		defer func() { <-s.slots }()
+		s.send(shadow)
+	}()
+}
+
+// send scales up the shadow pod if needed and sends it the mirrored request, discarding or logging
+// the response
+func (s *shadowBackend) send(r *http.Request) {
+	s.updateActivity()
+	if err := s.ensureScaledUp(r.Context()); err != nil {
+		log.Printf("[SHADOW] Failed to scale up: %v", err)
+		s.metrics.RecordShadowRequest("error", 0)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), shadowRequestTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := s.client.Do(r.WithContext(ctx))
+	if err != nil {
+		log.Printf("[SHADOW] %s %s failed: %v", r.Method, r.URL.Path, err)
+		s.metrics.RecordShadowRequest("error", 0)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var logged []byte
+	if s.logResponses || resp.StatusCode >= http.StatusBadRequest {
+		logged, _ = io.ReadAll(io.LimitReader(resp.Body, shadowLogLimit))
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	duration := time.Since(start)
+	if err != nil {
+		log.Printf("[SHADOW] %s %s failed after %v: %v", r.Method, r.URL.Path, duration.Round(time.Millisecond), err)
+		s.metrics.RecordShadowRequest("error", duration)
+		return
+	}
+
+	result := "ok"
+	if resp.StatusCode >= http.StatusBadRequest {
+		result = "error"
+	}
+	s.metrics.RecordShadowRequest(result, duration)
+	if s.logResponses || result == "error" {
+		log.Printf("[SHADOW] %s %s: %d in %v: %s", r.Method, r.URL.Path, resp.StatusCode, duration.Round(time.Millisecond), logged)
+	}
+}
+
+// ensureScaledUp creates the shadow pod if needed and waits until it is ready
+func (s *shadowBackend) ensureScaledUp(ctx context.Context) error {
+	// Followers leave the pod to the leader
+	if s.leader.follows() {
+		req := scaleUpRequest{Shadow: true}
+		resp, err := s.leader.requestScaleUp(req)
+		if err != nil {
+			return err
+		}
+		return resp.err(req)
+	}
+
+	s.scaleMu.Lock()
+	defer s.scaleMu.Unlock()
+
+	exists, err := s.k8sManager.PodExists(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		queueCtx, cancelQueue := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+		defer cancelQueue()
+		release, err := s.coldStarts.acquire(queueCtx, s.modelID)
+		if err != nil {
+			return fmt.Errorf("timeout waiting for other models to start: %w", err)
+		}
+		defer release()
+
+		modelConfig, err := s.crdClient.GetModel(ctx, s.modelID)
+		if err != nil {
+			return fmt.Errorf("failed to get shadow model config for '%s': %w", s.modelID, err)
+		}
+		log.Printf("[SHADOW] Creating shadow pod with model: %s (%s)", s.modelID, modelConfig.ModelName)
+		if err := s.k8sManager.CreatePod(ctx, modelConfig); err != nil {
+			return err
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), defaultScaleUpTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if ready, err := s.k8sManager.IsPodReady(waitCtx); err == nil && ready {
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("timeout waiting for shadow pod to be ready")
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateActivity records mirrored traffic
+func (s *shadowBackend) updateActivity() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActivity = time.Now()
+}
+
+// checkIdle deletes the shadow pod once it has been idle longer than the timeout
+func (s *shadowBackend) checkIdle(ctx context.Context, idleTimeout time.Duration) {
+	s.mu.Lock()
+	idleTime := time.Since(s.lastActivity)
+	s.mu.Unlock()
+
+	if idleTime <= idleTimeout {
+		return
+	}
+
+	exists, err := s.k8sManager.PodExists(ctx)
+	if err != nil {
+		log.Printf("[SHADOW] Failed to check pod existence: %v", err)
+		return
+	}
+	if exists {
+		log.Printf("[SHADOW] Idle for %v, deleting shadow pod...", idleTime.Round(time.Second))
+		if err := s.k8sManager.DeletePod(ctx); err != nil {
+			log.Printf("[SHADOW] Failed to delete pod: %v", err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// mirroredRequest is a request received by the shadow model
+type mirroredRequest struct {
+	path  string
+	model string
+}
+
+func newTestShadowBackend(t *testing.T, samplePercent int) (*shadowBackend, chan mirroredRequest) {
+	t.Helper()
+	received := make(chan mirroredRequest, 8)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		received <- mirroredRequest{path: r.URL.Path, model: body.Model}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	t.Cleanup(upstream.Close)
+
+	clientset := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-shadow", Namespace: "test-ns"},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	})
+	manager := kubernetes.NewK8sManager(clientset, &kubernetes.Config{
+		Namespace:   "test-ns",
+		Deployment:  "vllm-shadow",
+		ServiceName: "vllm-shadow-api",
+		AppLabel:    "vllm-shadow",
+	})
+	targetURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	getter := &stubModelGetter{models: map[string]*kubernetes.ModelConfig{
+		"qwen-awq": {ModelName: "Qwen/Qwen3-AWQ", ServedModelName: "qwen-awq"},
+	}}
+	return newShadowBackend(manager, getter, "qwen-awq", targetURL, samplePercent, true), received
+}
+
+func TestShadowBackend_Mirror(t *testing.T) {
+	backend, received := newTestShadowBackend(t, 100)
+	body := `{"model":"qwen","messages":[{"role":"user","content":"Hi"}]}`
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+
+	backend.mirror(r, "qwen")
+
+	replayed, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.Equal(t, body, string(replayed), "the primary model gets the request as sent")
+	select {
+	case mirrored := <-received:
+		assert.Equal(t, "/v1/chat/completions", mirrored.path)
+		assert.Equal(t, "qwen-awq", mirrored.model, "the mirrored request names the shadow model")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not mirrored")
+	}
+}
+
+func TestShadowBackend_MirrorSkips(t *testing.T) {
+	tests := []struct {
+		name          string
+		samplePercent int
+		method        string
+		path          string
+		model         string
+	}{
+		{name: "not sampled", samplePercent: 0, method: http.MethodPost, path: "/v1/chat/completions", model: "qwen"},
+		{name: "other endpoint", samplePercent: 100, method: http.MethodPost, path: embeddingsPath, model: "qwen"},
+		{name: "not a completion", samplePercent: 100, method: http.MethodGet, path: "/v1/completions", model: "qwen"},
+		{name: "shadow model", samplePercent: 100, method: http.MethodPost, path: "/v1/chat/completions", model: "qwen-awq"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, received := newTestShadowBackend(t, tt.samplePercent)
+			backend.mirror(httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"model":"qwen","prompt":"Hi"}`)), tt.model)
+			select {
+			case <-received:
+				t.Fatal("the request was mirrored")
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestShadowBackend_DropsWhenBusy(t *testing.T) {
+	backend, received := newTestShadowBackend(t, 100)
+	for i := 0; i < maxShadowRequests; i++ {
+		backend.slots <- struct{}{}
+	}
+
+	backend.mirror(httptest.NewRequest(http.MethodPost, "/v1/completions", strings.NewReader(`{"model":"qwen","prompt":"Hi"}`)), "qwen")
+	select {
+	case <-received:
+		t.Fatal("the request was mirrored")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestShadowBackend_CheckIdle(t *testing.T) {
+	backend, _ := newTestShadowBackend(t, 100)
+	backend.lastActivity = time.Now().Add(-10 * time.Minute)
+
+	backend.checkIdle(context.Background(), 5*time.Minute)
+
+	exists, err := backend.k8sManager.PodExists(context.Background())
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// startupLogLines is how much of the vLLM log is read to find the startup phase
+	startupLogLines = 50
+
+	// startupPhaseInterval bounds how often the log is read, whatever the number of waiting requests
+	startupPhaseInterval = 2 * time.Second
+)
+
+// startupPhases map vLLM startup log lines to the phase they begin. Phases with progress report
+// the percentage of their tqdm bar.
+var startupPhases = []struct {
+	pattern  *regexp.Regexp
+	phase    string
+	progress bool
+}{
+	{regexp.MustCompile(`\.(safetensors|bin|pt):\s+\d+%`), "downloading weights", true},
+	{regexp.MustCompile(`Starting to load model`), "loading weights", false},
+	{regexp.MustCompile(`Loading \w+ checkpoint shards:\s+\d+%`), "loading weights", true},
+	{regexp.MustCompile(`torch\.compile|Compiling a graph`), "compiling the model", false},
+	{regexp.MustCompile(`Memory profiling|# GPU blocks|KV cache`), "allocating the KV cache", false},
+	{regexp.MustCompile(`(?i)Capturing (CUDA graph|cudagraph)`), "capturing CUDA graphs", true},
+	{regexp.MustCompile(`Starting vLLM API server|Started server process|Waiting for application startup`), "starting the API server", false},
+}
+
+// percentPattern finds the percentage of a tqdm progress line
+var percentPattern = regexp.MustCompile(`(\d+)%`)
+
+// parseStartupPhase returns the phase of the last vLLM log line reporting one, empty when none
+// does. tqdm bars redraw with carriage returns, so each redraw counts as a line.
+func parseStartupPhase(logs string) string {
+	lines := strings.FieldsFunc(logs, func(r rune) bool { return r == '\n' || r == '\r' })
+	for i := len(lines) - 1; i >= 0; i-- {
+		for _, p := range startupPhases {
+			if !p.pattern.MatchString(lines[i]) {
+				continue
+			}
+			if match := percentPattern.FindStringSubmatch(lines[i]); p.progress && match != nil {
+				return p.phase + " " + match[1] + "%"
+			}
+			return p.phase
+		}
+	}
+	return ""
+}
+
+// startupTracker follows the startup phase of the vLLM pod from its log
+type startupTracker struct {
+	mu      sync.Mutex
+	phase   string
+	checked time.Time
+}
+
+// refreshStartupPhase reads the phase from the vLLM log, at most once per interval
+func (as *AutoScaler) refreshStartupPhase(ctx context.Context) {
+	as.startup.mu.Lock()
+	if time.Since(as.startup.checked) < startupPhaseInterval {
+		as.startup.mu.Unlock()
+		return
+	}
+	as.startup.checked = time.Now()
+	as.startup.mu.Unlock()
+
+	// The container may not have started yet, the previous phase stands until it logs
+	logs, err := as.runner.VLLMLogTail(ctx, startupLogLines)
+	if err != nil {
+		return
+	}
+	if phase := parseStartupPhase(logs); phase != "" {
+		as.startup.mu.Lock()
+		as.startup.phase = phase
+		as.startup.mu.Unlock()
+	}
+}
+
+// resetStartupPhase forgets the phase of the previous startup
+func (as *AutoScaler) resetStartupPhase() {
+	as.startup.mu.Lock()
+	defer as.startup.mu.Unlock()
+	as.startup.phase = ""
+	as.startup.checked = time.Time{}
+}
+
+// startupPhase returns the last phase read from the vLLM log, empty when unknown
+func (as *AutoScaler) startupPhase() string {
+	as.startup.mu.Lock()
+	defer as.startup.mu.Unlock()
+	return as.startup.phase
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseStartupPhase(t *testing.T) {
+	tests := []struct {
+		name     string
+		logs     string
+		expected string
+	}{
+		{
+			name:     "downloading",
+			logs:     "INFO Starting to load model Qwen/Qwen3-8B...\nmodel-00001-of-00005.safetensors:  12%|█▏        | 480M/4.0G\rmodel-00001-of-00005.safetensors:  40%|████      | 1.6G/4.0G",
+			expected: "downloading weights 40%",
+		},
+		{
+			name:     "loading shards",
+			logs:     "INFO Starting to load model Qwen/Qwen3-8B...\nLoading safetensors checkpoint shards:  60% Completed | 3/5 [00:04<00:02,  1.31s/it]\n",
+			expected: "loading weights 60%",
+		},
+		{
+			name:     "load started",
+			logs:     "INFO 10-16 08:00:01 [gpu_model_runner.py:1843] Starting to load model Qwen/Qwen3-8B...\n",
+			expected: "loading weights",
+		},
+		{
+			name:     "compiling",
+			logs:     "Loading safetensors checkpoint shards: 100% Completed | 5/5\nINFO Loading weights took 6.10 seconds\nINFO torch.compile takes 21.4 s in total\n",
+			expected: "compiling the model",
+		},
+		{
+			name:     "kv cache",
+			logs:     "INFO torch.compile takes 21.4 s in total\nINFO [kv_cache_utils.py:716] GPU KV cache size: 295,568 tokens\n",
+			expected: "allocating the KV cache",
+		},
+		{
+			name:     "cuda graphs",
+			logs:     "INFO GPU KV cache size: 295,568 tokens\nCapturing CUDA graph shapes:  45%|████▍     | 30/67 [00:05<00:06,  5.88it/s]",
+			expected: "capturing CUDA graphs 45%",
+		},
+		{
+			name:     "api server",
+			logs:     "Capturing CUDA graph shapes: 100%|██████████| 67/67\nINFO Starting vLLM API server 0 on http://0.0.0.0:8000\n",
+			expected: "starting the API server",
+		},
+		{name: "unknown", logs: "INFO Initializing a V1 LLM engine\n", expected: ""},
+		{name: "empty", logs: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, parseStartupPhase(tt.logs))
+		})
+	}
+}
+
+func TestStartupPhase_Refresh(t *testing.T) {
+	as := &AutoScaler{
+		k8sManager: kubernetes.NewK8sManager(fake.NewSimpleClientset(), &kubernetes.Config{Namespace: "vllm", Deployment: "vllm"}),
+	}
+	as.runner = as.k8sManager
+	as.startup.phase = "loading weights 20%"
+
+	// Logs without a phase keep the previous one
+	as.refreshStartupPhase(context.Background())
+	assert.Equal(t, "loading weights 20%", as.startupPhase())
+
+	as.resetStartupPhase()
+	assert.Empty(t, as.startupPhase())
+}
+
+func TestStartupPhase_LoadingMessage(t *testing.T) {
+	as := &AutoScaler{}
+	as.startup.phase = "capturing CUDA graphs 45%"
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","stream":true}`))
+	w := httptest.NewRecorder()
+	as.sendLoadingMessage(w, r, "qwen")
+	assert.Contains(t, w.Body.String(), "Model 'qwen' is loading (capturing CUDA graphs 45%), please wait...")
+
+	recorder := httptest.NewRecorder()
+	hw := newHeartbeatWriter(recorder, "/v1/chat/completions")
+	hw.progress = as.startupPhase
+	waitForHeartbeats(hw)
+	assert.True(t, strings.HasPrefix(recorder.Body.String(), ": processing (capturing CUDA graphs 45%)\n\n"), recorder.Body.String())
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import "time"
+
+// vllmStateNames are the /proxy/status names of the vllm_chill_vllm_state values
+var vllmStateNames = []string{"stopped", "starting", "running", "stopping", "gpu_driver_not_ready", "downloading"}
+
+// setVLLMState records the vLLM state for /proxy/status and the vllm_chill_vllm_state metric
+func (as *AutoScaler) setVLLMState(state int) {
+	as.vllmState.Store(int32(state))
+	as.metrics.SetVLLMState(state)
+	if state == 2 { // running
+		as.modelStatus.started()
+	}
+	as.modelStatus.notify()
+}
+
+// vllmStateName returns the name of the current vLLM state
+func (as *AutoScaler) vllmStateName() string {
+	state := int(as.vllmState.Load())
+	if state < 0 || state >= len(vllmStateNames) {
+		return "unknown"
+	}
+	return vllmStateNames[state]
+}
+
+// startupProgress describes the pod creation in progress, nil when vLLM isn't starting
+func (as *AutoScaler) startupProgress(now time.Time) map[string]interface{} {
+	since := as.startingAt.Load()
+	if since == 0 {
+		return nil
+	}
+	started := time.Unix(0, since)
+	progress := map[string]interface{}{
+		"started_at":      started.UTC(),
+		"elapsed_seconds": now.Sub(started).Seconds(),
+	}
+	// The previous startup is the best estimate of this one
+	if last := time.Duration(as.lastStartup.Load()); last > 0 {
+		progress["last_startup_seconds"] = last.Seconds()
+	}
+	if phase := as.startupPhase(); phase != "" {
+		progress["phase"] = phase
+	}
+	return progress
+}
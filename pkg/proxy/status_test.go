@@ -0,0 +1,53 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusHandler_VLLMState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	as := newReadyAutoScaler(t, "http://vllm-api", false)
+	as.lastActivity = time.Now().Add(-time.Minute)
+	as.coldStarts = newColdStartGate(1, as.metrics)
+	as.setVLLMState(1) // starting
+	as.startingAt.Store(time.Now().Add(-30 * time.Second).UnixNano())
+	as.lastStartup.Store(int64(90 * time.Second))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/proxy/status", nil)
+	as.statusHandler(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		VLLMState      string    `json:"vllm_state"`
+		LastActivity   time.Time `json:"last_activity"`
+		IdleSeconds    float64   `json:"idle_seconds"`
+		ColdStartQueue int       `json:"cold_start_queue"`
+		Startup        *struct {
+			ElapsedSeconds     float64 `json:"elapsed_seconds"`
+			LastStartupSeconds float64 `json:"last_startup_seconds"`
+		} `json:"startup"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "starting", response.VLLMState)
+	assert.WithinDuration(t, as.lastActivity, response.LastActivity, time.Second)
+	assert.InDelta(t, 60, response.IdleSeconds, 5)
+	assert.Equal(t, 0, response.ColdStartQueue)
+	require.NotNil(t, response.Startup)
+	assert.InDelta(t, 30, response.Startup.ElapsedSeconds, 5)
+	assert.Equal(t, float64(90), response.Startup.LastStartupSeconds)
+
+	as.startingAt.Store(0)
+	as.setVLLMState(2) // running
+	assert.Equal(t, "running", as.vllmStateName())
+	assert.Nil(t, as.startupProgress(time.Now()), "no startup in progress")
+}
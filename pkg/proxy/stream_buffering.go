@@ -0,0 +1,277 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// bufferStreamHeader asks for a single JSON response to a streamed request, for clients such as
+// curl scripts that can't read event streams. The Accept header is not used, as SDKs send
+// Accept: application/json on streamed requests too.
+const bufferStreamHeader = "X-Chill-Buffer-Stream"
+
+// bufferedStreamPaths are the OpenAI endpoints whose streams can be aggregated
+var bufferedStreamPaths = map[string]bool{
+	"/v1/chat/completions": true,
+	"/v1/completions":      true,
+}
+
+// wantsBufferedStream reports whether the client asked for its streamed request to be answered
+// with a single JSON response, removing the header from the request
+func wantsBufferedStream(r *http.Request) bool {
+	value := r.Header.Get(bufferStreamHeader)
+	if value == "" {
+		return false
+	}
+	r.Header.Del(bufferStreamHeader)
+	buffered, err := strconv.ParseBool(value)
+	return err == nil && buffered && bufferedStreamPaths[r.URL.Path]
+}
+
+// streamAggregator consumes a streamed OpenAI response and writes it as the single non-streaming
+// response the client would have received without stream=true. Responses that are not event
+// streams, such as errors, are passed through untouched.
+type streamAggregator struct {
+	http.ResponseWriter
+	path        string
+	wroteHeader bool
+	buffering   bool
+	pending     []byte // Incomplete line of the stream
+
+	id, model string
+	created   interface{}
+	usage     interface{}
+	choices   map[int]*aggregatedChoice
+	err       json.RawMessage // Error event ending the stream
+}
+
+// aggregatedChoice accumulates the deltas of a choice
+type aggregatedChoice struct {
+	role         string
+	content      strings.Builder
+	reasoning    strings.Builder
+	toolCalls    map[int]*ToolCall
+	finishReason interface{}
+}
+
+// newStreamAggregator wraps w to aggregate the stream of a request to path
+func newStreamAggregator(w http.ResponseWriter, path string) *streamAggregator {
+	return &streamAggregator{ResponseWriter: w, path: path, choices: make(map[int]*aggregatedChoice)}
+}
+
+// WriteHeader decides whether the response is aggregated
+func (sa *streamAggregator) WriteHeader(code int) {
+	if sa.wroteHeader {
+		return
+	}
+	sa.wroteHeader = true
+
+	header := sa.Header()
+	sa.buffering = code == http.StatusOK &&
+		strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") &&
+		header.Get("Content-Encoding") == ""
+	if sa.buffering {
+		return
+	}
+	sa.ResponseWriter.WriteHeader(code)
+}
+
+// Write aggregates the events of a stream and passes everything else through
+func (sa *streamAggregator) Write(b []byte) (int, error) {
+	if !sa.wroteHeader {
+		sa.WriteHeader(http.StatusOK)
+	}
+	if !sa.buffering {
+		return sa.ResponseWriter.Write(b)
+	}
+
+	sa.pending = append(sa.pending, b...)
+	for {
+		end := bytes.IndexByte(sa.pending, '\n')
+		if end < 0 {
+			break
+		}
+		sa.addLine(strings.TrimSuffix(string(sa.pending[:end]), "\r"))
+		sa.pending = sa.pending[end+1:]
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher, aggregated responses are only flushed by finish
+func (sa *streamAggregator) Flush() {
+	if sa.buffering {
+		return
+	}
+	if flusher, ok := sa.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// addLine accumulates a data line of the stream
+func (sa *streamAggregator) addLine(line string) {
+	data, ok := strings.CutPrefix(line, "data: ")
+	if !ok || data == "[DONE]" {
+		return
+	}
+
+	var chunk struct {
+		ID      string          `json:"id"`
+		Created interface{}     `json:"created"`
+		Model   string          `json:"model"`
+		Usage   interface{}     `json:"usage"`
+		Error   json.RawMessage `json:"error"`
+		Choices []struct {
+			Index        int         `json:"index"`
+			Text         string      `json:"text"`
+			FinishReason interface{} `json:"finish_reason"`
+			Delta        struct {
+				Role             string `json:"role"`
+				Content          string `json:"content"`
+				ReasoningContent string `json:"reasoning_content"`
+				ToolCalls        []struct {
+					Index    int    `json:"index"`
+					ID       string `json:"id"`
+					Type     string `json:"type"`
+					Function struct {
+						Name      string `json:"name"`
+						Arguments string `json:"arguments"`
+					} `json:"function"`
+				} `json:"tool_calls"`
+			} `json:"delta"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+		log.Printf("Skipped an unreadable event while aggregating the stream of %s: %v", sa.path, err)
+		return
+	}
+	if len(chunk.Error) > 0 {
+		sa.err = chunk.Error
+		return
+	}
+
+	if sa.id == "" {
+		sa.id, sa.created, sa.model = chunk.ID, chunk.Created, chunk.Model
+	}
+	if chunk.Usage != nil {
+		sa.usage = chunk.Usage
+	}
+	for _, c := range chunk.Choices {
+		choice := sa.choices[c.Index]
+		if choice == nil {
+			choice = &aggregatedChoice{role: "assistant", toolCalls: make(map[int]*ToolCall)}
+			sa.choices[c.Index] = choice
+		}
+		if c.Delta.Role != "" {
+			choice.role = c.Delta.Role
+		}
+		choice.content.WriteString(c.Text)
+		choice.content.WriteString(c.Delta.Content)
+		choice.reasoning.WriteString(c.Delta.ReasoningContent)
+		for _, tc := range c.Delta.ToolCalls {
+			call := choice.toolCalls[tc.Index]
+			if call == nil {
+				call = &ToolCall{Type: "function"}
+				choice.toolCalls[tc.Index] = call
+			}
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			if tc.Type != "" {
+				call.Type = tc.Type
+			}
+			call.Function.Name += tc.Function.Name
+			call.Function.Arguments += tc.Function.Arguments
+		}
+		if c.FinishReason != nil {
+			choice.finishReason = c.FinishReason
+		}
+	}
+}
+
+// response builds the non-streaming response from the aggregated stream
+func (sa *streamAggregator) response() map[string]interface{} {
+	completion := sa.path == "/v1/completions"
+	choices := make([]interface{}, 0, len(sa.choices))
+	for _, index := range slices.Sorted(maps.Keys(sa.choices)) {
+		choice := sa.choices[index]
+		if completion {
+			choices = append(choices, map[string]interface{}{
+				"index":         index,
+				"text":          choice.content.String(),
+				"finish_reason": choice.finishReason,
+			})
+			continue
+		}
+
+		message := map[string]interface{}{"role": choice.role, "content": nil}
+		if choice.content.Len() > 0 || len(choice.toolCalls) == 0 {
+			message["content"] = choice.content.String()
+		}
+		if choice.reasoning.Len() > 0 {
+			message["reasoning_content"] = choice.reasoning.String()
+		}
+		if len(choice.toolCalls) > 0 {
+			toolCalls := make([]*ToolCall, 0, len(choice.toolCalls))
+			for _, i := range slices.Sorted(maps.Keys(choice.toolCalls)) {
+				toolCalls = append(toolCalls, choice.toolCalls[i])
+			}
+			message["tool_calls"] = toolCalls
+		}
+		choices = append(choices, map[string]interface{}{
+			"index":         index,
+			"message":       message,
+			"finish_reason": choice.finishReason,
+		})
+	}
+
+	object := "chat.completion"
+	if completion {
+		object = "text_completion"
+	}
+	response := map[string]interface{}{
+		"id":      sa.id,
+		"object":  object,
+		"created": sa.created,
+		"model":   sa.model,
+		"choices": choices,
+	}
+	if sa.usage != nil {
+		response["usage"] = sa.usage
+	}
+	return response
+}
+
+// finish writes the aggregated response, or the error event that ended the stream
+func (sa *streamAggregator) finish() error {
+	if !sa.buffering {
+		return nil
+	}
+	if len(sa.pending) > 0 {
+		sa.addLine(string(sa.pending))
+		sa.pending = nil
+	}
+
+	status := http.StatusOK
+	body, err := json.Marshal(sa.response())
+	if sa.err != nil {
+		status = http.StatusBadGateway
+		body, err = json.Marshal(map[string]json.RawMessage{"error": sa.err})
+	}
+	if err != nil {
+		return err
+	}
+
+	header := sa.Header()
+	header.Set("Content-Type", "application/json")
+	header.Del("Content-Length")
+	header.Del("Cache-Control")
+	sa.ResponseWriter.WriteHeader(status)
+	_, err = sa.ResponseWriter.Write(body)
+	return err
+}
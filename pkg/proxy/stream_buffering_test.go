@@ -0,0 +1,111 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWantsBufferedStream(t *testing.T) {
+	for _, tc := range []struct {
+		path, value string
+		want        bool
+	}{
+		{"/v1/chat/completions", "true", true},
+		{"/v1/completions", "1", true},
+		{"/v1/chat/completions", "", false},
+		{"/v1/chat/completions", "false", false},
+		{"/v1/chat/completions", "yes please", false},
+		{"/v1/messages", "true", false},
+	} {
+		r := httptest.NewRequest(http.MethodPost, tc.path, nil)
+		if tc.value != "" {
+			r.Header.Set(bufferStreamHeader, tc.value)
+		}
+		assert.Equal(t, tc.want, wantsBufferedStream(r), "%s with %q", tc.path, tc.value)
+		assert.Empty(t, r.Header.Get(bufferStreamHeader), "the header is not forwarded to vLLM")
+	}
+}
+
+// writeStream writes an SSE response through w, in deliberately uneven writes
+func writeStream(t *testing.T, w http.ResponseWriter, stream string) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	for len(stream) > 0 {
+		n := min(37, len(stream))
+		_, err := w.Write([]byte(stream[:n]))
+		require.NoError(t, err)
+		stream = stream[n:]
+	}
+}
+
+func TestStreamAggregatorChatCompletion(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sa := newStreamAggregator(recorder, "/v1/chat/completions")
+
+	writeStream(t, sa, `data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{"reasoning_content":"Need the files."}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{"content":"Listing."}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"ls","arguments":""}}]}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"path\":\".\""}}]}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"}"}}]}}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`+"\n\n"+
+		`data: {"id":"chatcmpl-1","created":1700000000,"model":"qwen","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":8,"total_tokens":20}}`+"\n\n"+
+		"data: [DONE]\n\n")
+	assert.Zero(t, recorder.Body.Len(), "nothing is sent before the stream ends")
+	require.NoError(t, sa.finish())
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"id": "chatcmpl-1", "object": "chat.completion", "created": 1700000000, "model": "qwen",
+		"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {
+			"role": "assistant", "content": "Listing.", "reasoning_content": "Need the files.",
+			"tool_calls": [{"id": "call_a", "type": "function", "function": {"name": "ls", "arguments": "{\"path\":\".\"}"}}]
+		}}],
+		"usage": {"prompt_tokens": 12, "completion_tokens": 8, "total_tokens": 20}
+	}`, recorder.Body.String())
+}
+
+func TestStreamAggregatorCompletion(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sa := newStreamAggregator(recorder, "/v1/completions")
+
+	writeStream(t, sa, `data: {"id":"cmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"text":"Hello"}]}`+"\n\n"+
+		`data: {"id":"cmpl-1","created":1700000000,"model":"qwen","choices":[{"index":0,"text":" world","finish_reason":"length"}]}`+"\n\n"+
+		"data: [DONE]\n\n")
+	require.NoError(t, sa.finish())
+
+	assert.JSONEq(t, `{
+		"id": "cmpl-1", "object": "text_completion", "created": 1700000000, "model": "qwen",
+		"choices": [{"index": 0, "text": "Hello world", "finish_reason": "length"}]
+	}`, recorder.Body.String())
+}
+
+func TestStreamAggregatorStreamError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sa := newStreamAggregator(recorder, "/v1/chat/completions")
+
+	writeStream(t, sa, `data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"Partial"}}]}`+"\n\n"+
+		`data: {"error":{"message":"upstream closed the stream before it completed","type":"upstream_error","code":"stream_interrupted"}}`+"\n\n"+
+		"data: [DONE]\n\n")
+	require.NoError(t, sa.finish())
+
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.JSONEq(t, `{"error":{"message":"upstream closed the stream before it completed","type":"upstream_error","code":"stream_interrupted"}}`, recorder.Body.String())
+}
+
+func TestStreamAggregatorPassesJSONThrough(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	sa := newStreamAggregator(recorder, "/v1/chat/completions")
+
+	sa.Header().Set("Content-Type", "application/json")
+	writeAPIError(sa, http.StatusServiceUnavailable, "Service is starting up.", "service_unavailable", "scaling_up")
+	require.NoError(t, sa.finish())
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"code":"scaling_up"`)
+}
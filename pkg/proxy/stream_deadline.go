@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// eventStream reports whether a response is an SSE stream the proxy can read
+func eventStream(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusOK && resp.Header.Get("Content-Encoding") == "" &&
+		strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")
+}
+
+// limitStream bounds a streamed response: cancel aborts the upstream request once vLLM sent
+// nothing for idleTimeout, or once the stream has lasted maxDuration (0 disables either). The
+// read then fails, ended with an error event by recoverInterruptedStreams.
+func limitStream(resp *http.Response, cancel context.CancelFunc, idleTimeout, maxDuration time.Duration) {
+	if (idleTimeout <= 0 && maxDuration <= 0) || !eventStream(resp) {
+		return
+	}
+	s := &deadlineStream{body: resp.Body, cancel: cancel, path: resp.Request.URL.Path, idleTimeout: idleTimeout}
+	if idleTimeout > 0 {
+		s.idle = time.AfterFunc(idleTimeout, func() {
+			s.fail(fmt.Errorf("no data from vLLM for %s", idleTimeout))
+		})
+	}
+	if maxDuration > 0 {
+		s.max = time.AfterFunc(maxDuration, func() {
+			s.fail(fmt.Errorf("stream exceeded the model's max duration of %s", maxDuration))
+		})
+	}
+	resp.Body = s
+}
+
+// deadlineStream cancels the upstream request of a stream that stalls or runs too long
+type deadlineStream struct {
+	body        io.ReadCloser
+	cancel      context.CancelFunc
+	path        string
+	idleTimeout time.Duration
+	idle        *time.Timer // Reset on every read returning data, nil without idle timeout
+	max         *time.Timer // nil without max duration
+
+	mu  sync.Mutex
+	err error // Why the stream was cut, nil while it runs
+}
+
+func (s *deadlineStream) Read(p []byte) (int, error) {
+	n, err := s.body.Read(p)
+	if n > 0 && s.idle != nil {
+		s.idle.Reset(s.idleTimeout)
+	}
+	if err != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.err != nil {
+			return n, s.err
+		}
+	}
+	return n, err
+}
+
+func (s *deadlineStream) Close() error {
+	if s.idle != nil {
+		s.idle.Stop()
+	}
+	if s.max != nil {
+		s.max.Stop()
+	}
+	return s.body.Close()
+}
+
+// fail records why the stream is cut and aborts the upstream request, the pending read failing
+func (s *deadlineStream) fail(err error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = err
+	s.mu.Unlock()
+	log.Printf("Cutting stream for %s: %v", s.path, err)
+	s.cancel()
+}
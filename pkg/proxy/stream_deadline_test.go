@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// slowUpstream streams a first event, then one every interval (never when 0) until its request
+// is cancelled, which it reports on cancelled
+func slowUpstream(t *testing.T, interval time.Duration, cancelled chan<- struct{}) *url.URL {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data: {"choices":[{"delta":{"content":"partial"}}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+		var tick <-chan time.Time
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-r.Context().Done():
+				close(cancelled)
+				return
+			case <-tick:
+				_, _ = io.WriteString(w, `data: {"choices":[{"delta":{"content":"."}}]}`+"\n\n")
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	return target
+}
+
+// limitedProxy proxies to target like proxyHandler, with the given stream limits
+func limitedProxy(target *url.URL, idleTimeout, maxDuration time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		proxy := httputil.NewSingleHostReverseProxy(target)
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			limitStream(resp, cancel, idleTimeout, maxDuration)
+			return recoverInterruptedStreams(resp)
+		}
+		proxy.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+func TestLimitStream_IdleTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	target := slowUpstream(t, 0, cancelled)
+
+	recorder := httptest.NewRecorder()
+	limitedProxy(target, 50*time.Millisecond, 0)(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`)))
+
+	assert.Contains(t, recorder.Body.String(), `"content":"partial"`)
+	assert.Contains(t, recorder.Body.String(), `upstream stream failed: no data from vLLM for 50ms`)
+	assert.True(t, strings.HasSuffix(recorder.Body.String(), "data: [DONE]\n\n"))
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the upstream request wasn't cancelled")
+	}
+}
+
+func TestLimitStream_MaxDuration(t *testing.T) {
+	cancelled := make(chan struct{})
+	target := slowUpstream(t, 10*time.Millisecond, cancelled)
+
+	recorder := httptest.NewRecorder()
+	limitedProxy(target, 50*time.Millisecond, 200*time.Millisecond)(recorder, httptest.NewRequest(http.MethodPost, "/v1/messages", strings.NewReader(`{}`)))
+
+	assert.Contains(t, recorder.Body.String(), `"content":"."`, "events keep the idle timeout from firing")
+	assert.Contains(t, recorder.Body.String(), "event: error\n")
+	assert.Contains(t, recorder.Body.String(), `stream exceeded the model's max duration of 200ms`)
+	<-cancelled
+}
+
+func TestLimitStream_ClientDisconnect(t *testing.T) {
+	cancelled := make(chan struct{})
+	target := slowUpstream(t, 0, cancelled)
+	server := httptest.NewServer(limitedProxy(target, 0, 0))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/v1/chat/completions", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	assert.Contains(t, line, "partial")
+
+	// The client going away cancels the generation upstream
+	cancel()
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the upstream request wasn't cancelled")
+	}
+}
+
+func TestLimitStream_OtherResponsesUntouched(t *testing.T) {
+	original := io.NopCloser(strings.NewReader(`{}`))
+	resp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       original,
+	}
+	limitStream(resp, func() {}, time.Minute, time.Hour)
+	assert.Equal(t, original, resp.Body)
+
+	resp = streamResponse("/v1/chat/completions", original)
+	limitStream(resp, func() {}, 0, 0)
+	assert.Equal(t, original, resp.Body, "no limit set")
+}
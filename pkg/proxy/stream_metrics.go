@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/efortin/vllm-chill/pkg/usage"
+)
+
+// tokenDeltaPattern matches the SSE events carrying generated tokens: OpenAI deltas with content,
+// reasoning or tool call arguments, and Anthropic content block deltas
+var tokenDeltaPattern = regexp.MustCompile(`"(?:content|reasoning_content|reasoning|arguments)":\s*"[^"]|"type":\s*"content_block_delta"`)
+
+// streamTimer measures the time to the first token and the generation throughput of a streamed
+// response, from the raw vLLM writes
+type streamTimer struct {
+	forwardedAt  time.Time // The request was sent to vLLM, zero when it wasn't
+	firstTokenAt time.Time
+	lastTokenAt  time.Time
+	deltas       int                 // Events carrying tokens, the token count when no usage is reported
+	counter      *usage.TokenCounter // Usage reported by the stream, nil until the stream starts
+}
+
+// forward starts the timer as the request is sent to vLLM
+func (t *streamTimer) forward() {
+	t.forwardedAt = time.Now()
+}
+
+// observe records the tokens of a write of the response with the given content type
+func (t *streamTimer) observe(contentType string, b []byte) {
+	if t.forwardedAt.IsZero() {
+		return
+	}
+	if t.counter == nil {
+		if !strings.HasPrefix(contentType, "text/event-stream") {
+			return
+		}
+		t.counter = usage.NewTokenCounter()
+	}
+	t.counter.Scan(b)
+
+	deltas := len(tokenDeltaPattern.FindAllIndex(b, -1))
+	if deltas == 0 {
+		return
+	}
+	now := time.Now()
+	if t.firstTokenAt.IsZero() {
+		t.firstTokenAt = now
+	}
+	t.lastTokenAt = now
+	t.deltas += deltas
+}
+
+// record reports the time to the first token and the output tokens per second of a streamed
+// response of the model, then releases the counter
+func (t *streamTimer) record(metrics *stats.MetricsRecorder, model string) {
+	if t.counter == nil {
+		return
+	}
+	ttft, tokensPerSecond := t.measure()
+	t.counter.Release()
+	t.counter = nil
+
+	if ttft > 0 {
+		metrics.RecordTimeToFirstToken(model, ttft)
+	}
+	if tokensPerSecond > 0 {
+		metrics.RecordOutputTokensPerSecond(model, tokensPerSecond)
+	}
+}
+
+// measure returns the time to the first token and the output tokens per second, counted from the
+// reported usage or else the token events. Both are zero without tokens, the throughput with one.
+func (t *streamTimer) measure() (time.Duration, float64) {
+	if t.firstTokenAt.IsZero() {
+		return 0, 0
+	}
+	ttft := t.firstTokenAt.Sub(t.forwardedAt)
+
+	_, tokens := t.counter.Split(0, 0)
+	if tokens == 0 {
+		tokens = t.deltas
+	}
+	// The first token comes with the prefill, the throughput is measured between the others
+	generation := t.lastTokenAt.Sub(t.firstTokenAt)
+	if tokens < 2 || generation <= 0 {
+		return ttft, 0
+	}
+	return ttft, float64(tokens-1) / generation.Seconds()
+}
@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamTimer(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		writes      []string
+		tokens      int // Tokens the throughput is computed from, 0 for none
+	}{
+		{
+			name:        "openai without usage",
+			contentType: "text/event-stream",
+			writes: []string{
+				`data: {"choices":[{"delta":{"role":"assistant","content":""}}]}` + "\n\n",
+				`data: {"choices":[{"delta":{"content":"Hello"}}]}` + "\n\n",
+				`data: {"choices":[{"delta":{"content":" world"}}]}` + "\n\n" + `data: {"choices":[{"delta":{"content":"!"}}]}` + "\n\n",
+				"data: [DONE]\n\n",
+			},
+			tokens: 3,
+		},
+		{
+			name:        "openai with usage",
+			contentType: "text/event-stream",
+			writes: []string{
+				`data: {"choices":[{"delta":{"reasoning_content":"Let me think"}}]}` + "\n\n",
+				`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\""}}]}}]}` + "\n\n",
+				`data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":9,"total_tokens":21}}` + "\n\n",
+			},
+			tokens: 9,
+		},
+		{
+			name:        "anthropic",
+			contentType: "text/event-stream; charset=utf-8",
+			writes: []string{
+				"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"content\":[],\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}\n\n",
+				"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n",
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n",
+				"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\" world\"}}\n\n",
+				"event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":5}}\n\n",
+			},
+			tokens: 5,
+		},
+		{
+			name:        "not streamed",
+			contentType: "application/json",
+			writes:      []string{`{"choices":[{"message":{"content":"Hello"}}],"usage":{"completion_tokens":1}}`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var timer streamTimer
+			timer.forward()
+			for _, write := range tt.writes {
+				time.Sleep(time.Millisecond)
+				timer.observe(tt.contentType, []byte(write))
+			}
+			if tt.tokens == 0 {
+				assert.Nil(t, timer.counter, "only streamed responses are measured")
+				return
+			}
+
+			ttft, tokensPerSecond := timer.measure()
+			assert.Positive(t, ttft)
+			generation := timer.lastTokenAt.Sub(timer.firstTokenAt)
+			assert.InDelta(t, float64(tt.tokens-1)/generation.Seconds(), tokensPerSecond, 0.001)
+			timer.record(stats.NewMetricsRecorder(), "qwen")
+			assert.Nil(t, timer.counter, "the counter is released")
+		})
+	}
+
+	// Responses never forwarded to vLLM are not measured
+	var timer streamTimer
+	timer.observe("text/event-stream", []byte(`data: {"choices":[{"delta":{"content":"Hello"}}]}`))
+	assert.True(t, timer.firstTokenAt.IsZero())
+}
@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+)
+
+// anthropicMessagesPath is vLLM's Anthropic-compatible endpoint, streamed as Anthropic events
+const anthropicMessagesPath = "/v1/messages"
+
+// sseDone terminates OpenAI streams
+var sseDone = []byte("data: [DONE]")
+
+// recoverInterruptedStreams wraps streamed responses so an upstream failure mid-stream (pod
+// eviction, vLLM crash) ends the stream with an error event instead of an abrupt close, letting
+// clients keep the partial output. Use as the reverse proxy's ModifyResponse.
+func recoverInterruptedStreams(resp *http.Response) error {
+	if !eventStream(resp) {
+		return nil
+	}
+	resp.Body = &recoveringStream{
+		body:      resp.Body,
+		path:      resp.Request.URL.Path,
+		anthropic: resp.Request.URL.Path == anthropicMessagesPath,
+	}
+	return nil
+}
+
+// recoveringStream appends a terminal sequence when the upstream stream fails or ends early
+type recoveringStream struct {
+	body      io.ReadCloser
+	path      string
+	anthropic bool
+	tail      []byte        // Last bytes read, to detect the end marker and unterminated events
+	done      bool          // The upstream sent its own terminal event
+	terminal  *bytes.Reader // Terminal sequence, set once the upstream failed
+}
+
+func (s *recoveringStream) Read(p []byte) (int, error) {
+	if s.terminal != nil {
+		return s.terminal.Read(p)
+	}
+
+	n, err := s.body.Read(p)
+	s.track(p[:n])
+	if err == nil {
+		return n, nil
+	}
+	if s.done && errors.Is(err, io.EOF) {
+		return n, err
+	}
+
+	reason := "upstream closed the stream before it completed"
+	if !errors.Is(err, io.EOF) {
+		reason = "upstream stream failed: " + err.Error()
+	}
+	log.Printf("Stream interrupted for %s, sending terminal event: %s", s.path, reason)
+	s.terminal = bytes.NewReader(s.terminalSequence(reason))
+	if n > 0 {
+		return n, nil
+	}
+	return s.terminal.Read(p)
+}
+
+func (s *recoveringStream) Close() error {
+	return s.body.Close()
+}
+
+// track keeps the end of the stream read so far and notes the upstream's own terminal event
+func (s *recoveringStream) track(b []byte) {
+	const keep = 64
+	s.tail = append(s.tail, b...)
+	if bytes.Contains(s.tail, sseDone) || (s.anthropic && bytes.Contains(s.tail, []byte("event: message_stop"))) {
+		s.done = true
+	}
+	if len(s.tail) > keep {
+		s.tail = append(s.tail[:0], s.tail[len(s.tail)-keep:]...)
+	}
+}
+
+// terminalSequence returns an error event followed by the stream's end marker, after closing
+// any event the upstream left unterminated
+func (s *recoveringStream) terminalSequence(reason string) []byte {
+	var buf bytes.Buffer
+	if len(s.tail) > 0 && !bytes.HasSuffix(s.tail, []byte("\n\n")) {
+		if bytes.HasSuffix(s.tail, []byte("\n")) {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString("\n\n")
+		}
+	}
+
+	if s.anthropic {
+		event, _ := json.Marshal(map[string]interface{}{
+			"type":  "error",
+			"error": map[string]string{"type": "api_error", "message": reason},
+		})
+		buf.WriteString("event: error\ndata: ")
+		buf.Write(event)
+		buf.WriteString("\n\n")
+		return buf.Bytes()
+	}
+
+	event, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]string{
+			"message": reason,
+			"type":    "upstream_error",
+			"code":    "stream_interrupted",
+		},
+	})
+	buf.WriteString("data: ")
+	buf.Write(event)
+	buf.WriteString("\n\n")
+	buf.Write(sseDone)
+	buf.WriteString("\n\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingBody returns its data then fails like a connection reset
+type failingBody struct {
+	data io.Reader
+}
+
+func (b *failingBody) Read(p []byte) (int, error) {
+	n, err := b.data.Read(p)
+	if errors.Is(err, io.EOF) {
+		return n, errors.New("connection reset by peer")
+	}
+	return n, err
+}
+
+func (b *failingBody) Close() error {
+	return nil
+}
+
+func streamResponse(path string, body io.ReadCloser) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       body,
+		Request:    httptest.NewRequest(http.MethodPost, path, nil),
+	}
+}
+
+func TestRecoverInterruptedStreams_OpenAI(t *testing.T) {
+	resp := streamResponse("/v1/chat/completions", &failingBody{data: strings.NewReader(`data: {"choices":[{"delta":{"content":"Hel"}}]}` + "\n\n" + `data: {"choices":[{"del`)})
+	require.NoError(t, recoverInterruptedStreams(resp))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(body), `data: {"choices":[{"delta":{"content":"Hel"}}]}`))
+	assert.Contains(t, string(body), `{"del`+"\n\n"+`data: {"error":{"code":"stream_interrupted","message":"upstream stream failed: connection reset by peer","type":"upstream_error"}}`)
+	assert.True(t, strings.HasSuffix(string(body), "data: [DONE]\n\n"))
+}
+
+func TestRecoverInterruptedStreams_Anthropic(t *testing.T) {
+	resp := streamResponse("/v1/messages", io.NopCloser(strings.NewReader("event: content_block_delta\ndata: {\"type\":\"content_block_delta\"}\n\n")))
+	require.NoError(t, recoverInterruptedStreams(resp))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(string(body), "event: error\n"+`data: {"error":{"message":"upstream closed the stream before it completed","type":"api_error"},"type":"error"}`+"\n\n"))
+}
+
+func TestRecoverInterruptedStreams_CompletedStreamsUntouched(t *testing.T) {
+	stream := `data: {"choices":[]}` + "\n\ndata: [DONE]\n\n"
+	resp := streamResponse("/v1/chat/completions", io.NopCloser(strings.NewReader(stream)))
+	require.NoError(t, recoverInterruptedStreams(resp))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, stream, string(body))
+
+	original := io.NopCloser(strings.NewReader(`{}`))
+	resp = &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       original,
+	}
+	require.NoError(t, recoverInterruptedStreams(resp))
+	assert.Equal(t, original, resp.Body)
+}
+
+func TestRecoverInterruptedStreams_UpstreamAbort(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, `data: {"choices":[{"delta":{"content":"partial"}}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	defer upstream.Close()
+
+	target, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ModifyResponse = recoverInterruptedStreams
+
+	recorder := httptest.NewRecorder()
+	proxy.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{}`)))
+
+	assert.Contains(t, recorder.Body.String(), `"content":"partial"`)
+	assert.Contains(t, recorder.Body.String(), `"code":"stream_interrupted"`)
+	assert.True(t, strings.HasSuffix(recorder.Body.String(), "data: [DONE]\n\n"))
+}
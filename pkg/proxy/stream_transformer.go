@@ -0,0 +1,290 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/parser"
+)
+
+// streamTransformer rewrites the events of a streamed response in one API format. Both formats go
+// through the same steps of the responseWriter: native tool call detection and deduplication, XML
+// tool call conversion with the stream parser, and its metrics.
+type streamTransformer interface {
+	// write transforms the bytes of a write and forwards them to the client
+	write(rw *responseWriter, b []byte) error
+}
+
+// streamTransformerFor returns the transformer of the stream starting with content, nil when it isn't SSE
+func streamTransformerFor(content string) streamTransformer {
+	switch {
+	case strings.HasPrefix(content, "data: "):
+		return openAIStream{}
+	case strings.HasPrefix(content, "event: "):
+		return newAnthropicStream()
+	}
+	return nil
+}
+
+// forward writes data to the client, counting and capturing it
+func (rw *responseWriter) forward(data []byte) error {
+	n, err := rw.ResponseWriter.Write(data)
+	rw.bytesWritten += int64(n)
+	if rw.captureBody {
+		rw.body.Write(data)
+	}
+	return err
+}
+
+// nativeToolCalls records that the model calls tools natively, which turns the content conversion off
+func (rw *responseWriter) nativeToolCalls() {
+	if rw.toolCallsDetected {
+		return
+	}
+	rw.toolCallsDetected = true
+	log.Printf("[TOOL-CALLS] Native tool calls detected - passing through")
+
+	// If we had XML mode active, cancel it since native tool calls are being used
+	if rw.xmlDetectionMode {
+		log.Printf("[TOOL-CALLS] Canceling XML mode - native tool calls detected")
+		rw.xmlDetectionMode = false
+		rw.accumulatedContent.Reset()
+		rw.chunkBuffer = nil
+	}
+}
+
+// xmlPatternOverlap is the end of the content searched again with a delta, for markup split across deltas
+const xmlPatternOverlap = len("<function_call")
+
+// observeContent accumulates a content delta, watching for XML tool call markup, and returns the
+// content so far
+func (rw *responseWriter) observeContent(delta string) string {
+	rw.accumulatedContent.WriteString(delta)
+
+	accumulated := rw.accumulatedContent.String()
+	if !rw.xmlPatternSeen {
+		// Only the new content is searched, so that long responses aren't searched again with every delta
+		tail := accumulated[max(0, len(accumulated)-len(delta)-xmlPatternOverlap):]
+		rw.xmlPatternSeen = containsXMLToolCall(tail)
+	}
+	return accumulated
+}
+
+// openAIStream transforms chat completion chunks: "data: " lines ending with [DONE]
+type openAIStream struct{}
+
+func (openAIStream) write(rw *responseWriter, b []byte) error {
+	return rw.writeOpenAI(b)
+}
+
+// anthropicStream transforms Messages API events: "event: " and "data: " lines, as vLLM's
+// /v1/messages streams them. XML tool calls written in the first text block are converted to
+// tool_use blocks as they stream, which shifts the index of the blocks after them. The buffered
+// fallback parsers of other formats only apply to OpenAI chunks.
+type anthropicStream struct {
+	pending bytes.Buffer // Start of an event whose blank line hasn't arrived yet
+	text    int          // Upstream index of the text block fed to the XML stream parser, -1 before it
+	open    string       // Type of the converted block open on the client side, empty when none
+	shift   int          // Blocks inserted by the conversion before the current upstream block
+}
+
+// newAnthropicStream creates the transformer of a Messages API stream
+func newAnthropicStream() *anthropicStream {
+	return &anthropicStream{text: -1}
+}
+
+func (s *anthropicStream) write(rw *responseWriter, b []byte) error {
+	s.pending.Write(b)
+	data := s.pending.String()
+	end := strings.LastIndex(data, "\n\n")
+	if end < 0 {
+		return nil
+	}
+	s.pending.Reset()
+	s.pending.WriteString(data[end+2:])
+
+	start := time.Now()
+	duplicates := rw.duplicateBytes
+	var out strings.Builder
+	for _, event := range strings.Split(data[:end], "\n\n") {
+		out.WriteString(s.transform(rw, event))
+	}
+	if rw.toolCallsDetected {
+		rw.transformTime += time.Since(start)
+	}
+	if filtered := rw.duplicateBytes - duplicates; filtered > 0 {
+		log.Printf("[DEDUP] Filtered %d duplicate bytes from vLLM tensor parallelism", filtered)
+	}
+	if out.Len() == 0 {
+		return nil
+	}
+	return rw.forward([]byte(out.String()))
+}
+
+// transform returns the events replacing an upstream event, empty to drop it
+func (s *anthropicStream) transform(rw *responseWriter, event string) string {
+	raw := event + "\n\n"
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(sseData(event)), &payload); err != nil {
+		return raw
+	}
+	index := -1
+	if i, ok := payload["index"].(float64); ok {
+		index = int(i)
+	}
+
+	switch payload["type"] {
+	case "content_block_start":
+		block, _ := payload["content_block"].(map[string]interface{})
+		switch block["type"] {
+		case "tool_use":
+			rw.nativeToolCalls()
+			id, _ := block["id"].(string)
+			if rw.duplicateToolCall(index, id, "") {
+				rw.duplicateBytes += int64(len(raw))
+				return ""
+			}
+		case "text":
+			if s.text < 0 && rw.streamsXML() {
+				s.text, s.open = index, "text"
+			}
+		}
+
+	case "content_block_delta":
+		delta, _ := payload["delta"].(map[string]interface{})
+		switch delta["type"] {
+		case "input_json_delta":
+			args, _ := delta["partial_json"].(string)
+			if rw.duplicateToolCall(index, "", args) {
+				rw.duplicateBytes += int64(len(raw))
+				return ""
+			}
+		case "text_delta":
+			text, _ := delta["text"].(string)
+			rw.observeContent(text)
+			if s.converts(rw, index) && text != "" {
+				// Text passes through unchanged unless a tool call block is open
+				if events := rw.feedXMLStream(text); s.open != "text" || !passesThrough(events, text) {
+					return s.events(events)
+				}
+			}
+		}
+
+	case "content_block_stop":
+		if s.converts(rw, index) {
+			return s.finish(rw, raw)
+		}
+
+	case "message_delta":
+		var out string
+		if s.converts(rw, s.text) {
+			// The text block wasn't closed, flush what the parser holds back
+			out = s.finish(rw, "")
+		}
+		delta, _ := payload["delta"].(map[string]interface{})
+		if rw.parsedToolCalls > 0 && delta != nil && delta["stop_reason"] == "end_turn" {
+			delta["stop_reason"] = "tool_use"
+			return out + anthropicEvent(payload)
+		}
+		return out + raw
+	}
+	return s.reindex(payload, index, raw)
+}
+
+// converts reports whether the upstream block at index goes through the XML stream parser
+func (s *anthropicStream) converts(rw *responseWriter, index int) bool {
+	return index >= 0 && index == s.text && s.open != "" && rw.streamsXML()
+}
+
+// events builds the Messages API events of the stream parser's events, opening a block for each
+// tool call and for the text after one
+func (s *anthropicStream) events(events []parser.StreamEvent) string {
+	var out strings.Builder
+	for _, event := range events {
+		toolCall := event.ToolCall
+		switch {
+		case toolCall == nil:
+			if event.Text == "" {
+				continue
+			}
+			if s.open != "text" {
+				out.WriteString(s.startBlock("text", map[string]interface{}{"type": "text", "text": ""}))
+			}
+			out.WriteString(s.delta(map[string]interface{}{"type": "text_delta", "text": event.Text}))
+		case toolCall.ID != "":
+			out.WriteString(s.startBlock("tool_use", map[string]interface{}{
+				"type": "tool_use", "id": toolCall.ID, "name": toolCall.Name, "input": map[string]interface{}{},
+			}))
+			fallthrough
+		default:
+			if toolCall.Arguments != "" {
+				out.WriteString(s.delta(map[string]interface{}{"type": "input_json_delta", "partial_json": toolCall.Arguments}))
+			}
+		}
+	}
+	return out.String()
+}
+
+// startBlock closes the open converted block and starts the next one
+func (s *anthropicStream) startBlock(blockType string, block map[string]interface{}) string {
+	var out string
+	if s.open != "" {
+		out = s.stopBlock()
+		s.shift++
+	}
+	s.open = blockType
+	return out + anthropicEvent(map[string]interface{}{
+		"type": "content_block_start", "index": s.text + s.shift, "content_block": block,
+	})
+}
+
+// stopBlock closes the open converted block
+func (s *anthropicStream) stopBlock() string {
+	s.open = ""
+	return anthropicEvent(map[string]interface{}{"type": "content_block_stop", "index": s.text + s.shift})
+}
+
+// delta builds a delta of the open converted block
+func (s *anthropicStream) delta(delta map[string]interface{}) string {
+	return anthropicEvent(map[string]interface{}{"type": "content_block_delta", "index": s.text + s.shift, "delta": delta})
+}
+
+// finish flushes the XML stream parser at the end of the text block and closes the open block.
+// The upstream stop event, if any, is kept when nothing was converted.
+func (s *anthropicStream) finish(rw *responseWriter, stop string) string {
+	out := s.events(rw.finishXMLStream())
+	if out == "" && s.shift == 0 && stop != "" {
+		s.open = ""
+		return stop
+	}
+	return out + s.stopBlock()
+}
+
+// reindex moves an upstream block event after the blocks the conversion inserted
+func (s *anthropicStream) reindex(payload map[string]interface{}, index int, raw string) string {
+	if index < 0 || s.shift == 0 || index < s.text {
+		return raw
+	}
+	payload["index"] = index + s.shift
+	return anthropicEvent(payload)
+}
+
+// sseData returns the data of an SSE event, its "data: " lines joined
+func sseData(event string) string {
+	var data []string
+	for _, line := range strings.Split(event, "\n") {
+		if strings.HasPrefix(line, "data: ") {
+			data = append(data, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	return strings.Join(data, "\n")
+}
+
+// anthropicEvent builds a Messages API SSE event, named after its type
+func anthropicEvent(payload map[string]interface{}) string {
+	data, _ := json.Marshal(payload)
+	return "event: " + payload["type"].(string) + "\ndata: " + string(data) + "\n\n"
+}
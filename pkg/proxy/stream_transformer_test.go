@@ -0,0 +1,173 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	anthropicMessageStart = "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"id\":\"msg_1\",\"role\":\"assistant\",\"content\":[]}}\n\n"
+	anthropicTextStart    = "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n"
+	anthropicTextStop     = "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"
+	anthropicMessageStop  = "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+)
+
+func anthropicTextDelta(t *testing.T, text string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": 0,
+		"delta": map[string]interface{}{"type": "text_delta", "text": text},
+	})
+	require.NoError(t, err)
+	return "event: content_block_delta\ndata: " + string(data) + "\n\n"
+}
+
+func anthropicMessageDelta(stopReason string) string {
+	return "event: message_delta\ndata: {\"type\":\"message_delta\",\"delta\":{\"stop_reason\":\"" + stopReason + "\"},\"usage\":{\"output_tokens\":12}}\n\n"
+}
+
+// anthropicEvents parses the data of the events of a Messages API stream
+func anthropicEvents(t *testing.T, body string) []map[string]interface{} {
+	t.Helper()
+	var events []map[string]interface{}
+	for _, event := range strings.Split(strings.TrimSuffix(body, "\n\n"), "\n\n") {
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(sseData(event)), &payload), event)
+		require.True(t, strings.HasPrefix(event, "event: "+payload["type"].(string)+"\n"), event)
+		events = append(events, payload)
+	}
+	return events
+}
+
+func TestStreamTransformerFor(t *testing.T) {
+	assert.IsType(t, openAIStream{}, streamTransformerFor("data: {}"))
+	assert.IsType(t, &anthropicStream{}, streamTransformerFor("event: message_start\n"))
+	assert.Nil(t, streamTransformerFor(`{"object":"chat.completion"}`))
+}
+
+func TestAnthropicStream_ConvertsXMLToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+
+	writes := []string{
+		anthropicMessageStart + anthropicTextStart,
+		anthropicTextDelta(t, "Listing.\n<tool_call>\n<function=ls>\n<parameter=path>."),
+		anthropicTextDelta(t, "</parameter>\n</function>\n</tool_call>"),
+		anthropicTextStop,
+		anthropicMessageDelta("end_turn") + anthropicMessageStop,
+	}
+	for _, write := range writes {
+		_, err := rw.Write([]byte(write))
+		require.NoError(t, err)
+	}
+	assert.NotContains(t, recorder.Body.String(), "<tool_call>")
+
+	events := anthropicEvents(t, recorder.Body.String())
+	var types []string
+	for _, event := range events {
+		types = append(types, event["type"].(string))
+	}
+	assert.Equal(t, []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}, types)
+
+	assert.Equal(t, "Listing.\n", events[2]["delta"].(map[string]interface{})["text"])
+	assert.EqualValues(t, 0, events[3]["index"])
+
+	toolUse := events[4]["content_block"].(map[string]interface{})
+	assert.EqualValues(t, 1, events[4]["index"])
+	assert.Equal(t, "tool_use", toolUse["type"])
+	assert.Equal(t, "ls", toolUse["name"])
+	assert.NotEmpty(t, toolUse["id"])
+	var arguments string
+	for _, event := range events[5:7] {
+		assert.EqualValues(t, 1, event["index"])
+		delta := event["delta"].(map[string]interface{})
+		assert.Equal(t, "input_json_delta", delta["type"])
+		arguments += delta["partial_json"].(string)
+	}
+	assert.JSONEq(t, `{"path":"."}`, arguments)
+	assert.EqualValues(t, 1, events[7]["index"])
+
+	assert.Equal(t, "tool_use", events[8]["delta"].(map[string]interface{})["stop_reason"])
+	assert.Equal(t, 1, rw.parsedToolCalls)
+	assert.True(t, rw.xmlPatternSeen)
+}
+
+func TestAnthropicStream_ShiftsLaterBlocks(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+
+	stream := anthropicMessageStart + anthropicTextStart +
+		anthropicTextDelta(t, "<function=ls>\n<parameter=path>.</parameter>\n</function>") +
+		anthropicTextStop +
+		"event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":1,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n" +
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":1}\n\n" +
+		anthropicMessageDelta("end_turn") + anthropicMessageStop
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	var indexes []float64
+	for _, event := range anthropicEvents(t, recorder.Body.String()) {
+		if index, ok := event["index"].(float64); ok && (len(indexes) == 0 || indexes[len(indexes)-1] != index) {
+			indexes = append(indexes, index)
+		}
+	}
+	// The tool call follows the empty text block, the upstream block 1 follows the tool call
+	assert.Equal(t, []float64{0, 1, 2}, indexes)
+}
+
+func TestAnthropicStream_PassesTextThrough(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+
+	stream := anthropicMessageStart + anthropicTextStart + anthropicTextDelta(t, "if a < b {") +
+		anthropicTextDelta(t, " return }") + anthropicTextStop + anthropicMessageDelta("end_turn") + anthropicMessageStop
+	// Events split across writes are held until complete
+	for _, write := range []string{stream[:50], stream[50:300], stream[300:]} {
+		_, err := rw.Write([]byte(write))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, stream, recorder.Body.String(), "content that opens no tool call is written unchanged")
+	assert.Zero(t, rw.parsedToolCalls)
+}
+
+func TestAnthropicStream_XMLFallbackOff(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.xmlFallbackOff = true
+
+	stream := anthropicMessageStart + anthropicTextStart +
+		anthropicTextDelta(t, "<function=ls>\n<parameter=path>.</parameter>\n</function>") +
+		anthropicTextStop + anthropicMessageDelta("end_turn") + anthropicMessageStop
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	assert.Equal(t, stream, recorder.Body.String())
+	assert.True(t, rw.xmlPatternSeen, "detection still runs")
+}
+
+func TestAnthropicStream_DeduplicatesNativeToolUse(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+
+	start := "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"tool_use\",\"id\":\"toolu_1\",\"name\":\"ls\",\"input\":{}}}\n\n"
+	delta := "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"input_json_delta\",\"partial_json\":\"{\\\"path\\\":\\\".\\\"}\"}}\n\n"
+	stop := "event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}\n\n"
+	_, err := rw.Write([]byte(anthropicMessageStart + start + start + delta + delta + stop + anthropicMessageDelta("tool_use")))
+	require.NoError(t, err)
+
+	assert.Equal(t, anthropicMessageStart+start+delta+stop+anthropicMessageDelta("tool_use"), recorder.Body.String())
+	assert.True(t, rw.toolCallsDetected)
+	assert.EqualValues(t, len(start)+len(delta), rw.duplicateBytes)
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Model switch policies, deciding whether a request for another model switches the active model
+const (
+	SwitchPolicyAuto      = "auto"      // Requests switch to any model (default)
+	SwitchPolicyManual    = "manual"    // Requests never switch, only the admin and model APIs do
+	SwitchPolicyAllowlist = "allowlist" // Requests switch only to the models of the allowlist
+)
+
+// noSwitchHeader pins a request to the active model, whatever the switch policy
+const noSwitchHeader = "X-VLLM-Chill-No-Switch"
+
+// pinnedToActiveModel reports whether the client forbade its request from switching models,
+// removing the header from the request
+func pinnedToActiveModel(r *http.Request) bool {
+	value := r.Header.Get(noSwitchHeader)
+	if value == "" {
+		return false
+	}
+	r.Header.Del(noSwitchHeader)
+	pinned, err := strconv.ParseBool(value)
+	return err == nil && pinned
+}
+
+// modelSwitchNotAllowed is the error code of requests for a model they may not switch to
+const modelSwitchNotAllowed = "model_switch_not_allowed"
+
+// SwitchNotAllowedError reports a switch the leader refused to make for another replica's request
+type SwitchNotAllowedError struct {
+	RequestedModel string
+	Reason         string
+}
+
+func (e *SwitchNotAllowedError) Error() string {
+	return fmt.Sprintf("switching to model '%s' is not allowed: %s", e.RequestedModel, e.Reason)
+}
+
+type switchRequestKey struct{}
+
+// withSwitchRequest marks the context of a client request whose switch was checked against the
+// switch policy, for followers to have the leader check it again
+func withSwitchRequest(ctx context.Context, pinned bool) context.Context {
+	return context.WithValue(ctx, switchRequestKey{}, pinned)
+}
+
+// switchRequestFrom reports whether the context is a client request's, and whether it is pinned
+// to the active model. Admin and operations API switches are not checked against the policy.
+func switchRequestFrom(ctx context.Context) (pinned, ok bool) {
+	pinned, ok = ctx.Value(switchRequestKey{}).(bool)
+	return pinned, ok
+}
+
+// switchAllowlist returns the served model names requests may switch to with the allowlist policy
+func (c *Config) switchAllowlist() []string {
+	var models []string
+	for _, model := range strings.Split(c.SwitchAllowlist, ",") {
+		if model = strings.TrimSpace(model); model != "" {
+			models = append(models, model)
+		}
+	}
+	return models
+}
+
+// switchAllowed reports whether the switch policy lets requests switch the active model to the
+// given served model name
+func (c *Config) switchAllowed(model string) bool {
+	switch c.SwitchPolicy {
+	case SwitchPolicyManual:
+		return false
+	case SwitchPolicyAllowlist:
+		return slices.Contains(c.switchAllowlist(), model)
+	default:
+		return true
+	}
+}
+
+// refuseModelSwitch answers a request for another model than the active one when it may not
+// switch: a 409 listing the models it may use, or a 404 when the model doesn't exist
+func (as *AutoScaler) refuseModelSwitch(ctx context.Context, w http.ResponseWriter, requestedModel string, pinned bool) {
+	if _, err := as.crdClient.GetModel(ctx, requestedModel); err != nil {
+		as.returnAvailableModels(ctx, w, requestedModel)
+		return
+	}
+
+	activeModel := as.GetActiveModel()
+	reason := "the switch policy doesn't allow it"
+	if pinned {
+		reason = fmt.Sprintf("the request was sent with %s", noSwitchHeader)
+	}
+	log.Printf("Refused to switch from model %s to %s: %s", activeModel, requestedModel, reason)
+	as.writeModelList(ctx, w, http.StatusConflict, modelSwitchNotAllowed,
+		fmt.Sprintf("Model '%s' is not active and switching to it is not allowed (%s). Active model: %s", requestedModel, reason, activeModel),
+		func(model ModelInfo) bool {
+			return model.ServedModelName == activeModel || (!pinned && as.config.switchAllowed(model.ServedModelName))
+		})
+}
+
+// writeSwitchNotAllowed answers a request whose switch the leader refused
+func writeSwitchNotAllowed(w http.ResponseWriter, err *SwitchNotAllowedError) {
+	writeAPIError(w, http.StatusConflict,
+		fmt.Sprintf("Model '%s' is not active and switching to it is not allowed (%s).", err.RequestedModel, err.Reason),
+		"invalid_request_error", modelSwitchNotAllowed)
+}
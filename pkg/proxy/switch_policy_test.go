@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinnedToActiveModel(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "true": true, "1": true, "false": false, "yes": false} {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		if value != "" {
+			r.Header.Set(noSwitchHeader, value)
+		}
+		assert.Equal(t, want, pinnedToActiveModel(r), "header %q", value)
+		assert.Empty(t, r.Header.Get(noSwitchHeader), "the header does not reach vLLM")
+	}
+}
+
+func TestConfigSwitchAllowed(t *testing.T) {
+	auto := &Config{SwitchPolicy: SwitchPolicyAuto}
+	assert.True(t, auto.switchAllowed("llama"))
+
+	manual := &Config{SwitchPolicy: SwitchPolicyManual}
+	assert.False(t, manual.switchAllowed("llama"))
+
+	allowlist := &Config{SwitchPolicy: SwitchPolicyAllowlist, SwitchAllowlist: "mistral, llama"}
+	assert.True(t, allowlist.switchAllowed("llama"))
+	assert.False(t, allowlist.switchAllowed("deepseek"))
+}
+
+func TestProxyHandler_SwitchPolicy(t *testing.T) {
+	var forwarded *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded = r
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	model := func(served string) map[string]interface{} {
+		spec := replicaModelSpec(0, 1)
+		spec["servedModelName"] = served
+		spec["modelName"] = "test/" + served
+		return spec
+	}
+
+	send := func(as *AutoScaler, model string, pinned bool) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"`+model+`","messages":[{"role":"user","content":"Hi"}]}`))
+		if pinned {
+			r.Header.Set(noSwitchHeader, "true")
+		}
+		w := httptest.NewRecorder()
+		as.proxyHandler(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name      string
+		policy    string
+		allowlist string
+		model     string
+		pinned    bool
+		status    int
+		code      string
+		available []string
+	}{
+		{name: "manual", policy: SwitchPolicyManual, model: "llama", status: http.StatusConflict, code: "model_switch_not_allowed", available: []string{"qwen"}},
+		{name: "not allowlisted", policy: SwitchPolicyAllowlist, allowlist: "mistral", model: "llama", status: http.StatusConflict, code: "model_switch_not_allowed", available: []string{"mistral", "qwen"}},
+		{name: "pinned", policy: SwitchPolicyAuto, model: "llama", pinned: true, status: http.StatusConflict, code: "model_switch_not_allowed", available: []string{"qwen"}},
+		{name: "unknown model", policy: SwitchPolicyManual, model: "gpt-5", status: http.StatusNotFound, code: "model_not_found", available: []string{"llama", "mistral", "qwen"}},
+		{name: "active model", policy: SwitchPolicyManual, model: "qwen", pinned: true, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwarded = nil
+			as := newReadyAutoScaler(t, upstream.URL, false)
+			as.crdClient = newFakeCRDClient(t, model("qwen"), model("llama"), model("mistral"))
+			as.config.SwitchPolicy = tt.policy
+			as.config.SwitchAllowlist = tt.allowlist
+
+			w := send(as, tt.model, tt.pinned)
+			require.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Equal(t, "qwen", as.GetActiveModel())
+			if tt.status == http.StatusOK {
+				require.NotNil(t, forwarded)
+				assert.Empty(t, forwarded.Header.Get(noSwitchHeader))
+				return
+			}
+			assert.Nil(t, forwarded, "vLLM never sees the refused request")
+
+			var response struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+				AvailableModels struct {
+					Data []struct {
+						ID string `json:"id"`
+					} `json:"data"`
+				} `json:"available_models"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.code, response.Error.Code)
+			var available []string
+			for _, model := range response.AvailableModels.Data {
+				available = append(available, model.ID)
+			}
+			assert.ElementsMatch(t, tt.available, available)
+		})
+	}
+}
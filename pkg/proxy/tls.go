@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// certificateFiles is a certificate and key read from files, reloaded when either changes so a
+// renewed certificate is served without a restart
+type certificateFiles struct {
+	certFile string
+	keyFile  string
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTime  time.Time // Latest modification time of the files loaded
+}
+
+// newCertificateFiles loads the certificate and key files
+func newCertificateFiles(certFile, keyFile string) (*certificateFiles, error) {
+	c := &certificateFiles{certFile: certFile, keyFile: keyFile}
+	if _, err := c.certificate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// certificate returns the certificate, reloading the files when they changed since the last load.
+// The loaded certificate is kept while the files cannot be read, e.g., as they are being replaced.
+func (c *certificateFiles) certificate() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTime, err := latestModTime(c.certFile, c.keyFile)
+	if err == nil && (c.cert == nil || modTime.After(c.modTime)) {
+		var cert tls.Certificate
+		if cert, err = tls.LoadX509KeyPair(c.certFile, c.keyFile); err == nil {
+			c.cert, c.modTime = &cert, modTime
+		}
+	}
+	if c.cert == nil {
+		return nil, fmt.Errorf("failed to load certificate %s: %w", c.certFile, err)
+	}
+	if err != nil {
+		log.Printf("Keeping the current certificate %s: %v", c.certFile, err)
+	}
+	return c.cert, nil
+}
+
+// latestModTime returns the latest modification time of the files
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// loadTLSSecret reads the certificate and key the proxy serves from the TLS Secret
+func (as *AutoScaler) loadTLSSecret(ctx context.Context) error {
+	data, err := as.k8sManager.GetSecretData(ctx, as.config.TLSSecret)
+	if err != nil {
+		return fmt.Errorf("failed to read TLS Secret %s: %w", as.config.TLSSecret, err)
+	}
+	cert, err := tls.X509KeyPair(data[corev1.TLSCertKey], data[corev1.TLSPrivateKeyKey])
+	if err != nil {
+		return fmt.Errorf("invalid TLS Secret %s: %w", as.config.TLSSecret, err)
+	}
+	as.tlsSecretCert.Store(&cert)
+	return nil
+}
+
+// startTLSSecretReload periodically reloads the TLS Secret, keeping the current certificate on errors
+func (as *AutoScaler) startTLSSecretReload(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := as.loadTLSSecret(ctx); err != nil {
+				log.Printf("Keeping the current TLS certificate: %v", err)
+			}
+		}
+	}
+}
+
+// serverProtocols returns the protocols served on the proxy's port: HTTP/1.1, and HTTP/2 negotiated
+// over TLS or with prior knowledge over plain HTTP (h2c), so gRPC clients reach vLLM either way
+func serverProtocols() *http.Protocols {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(true)
+	return protocols
+}
+
+// serverTLSConfig returns the TLS settings of the proxy's port, nil when it serves plain HTTP
+func (as *AutoScaler) serverTLSConfig() *tls.Config {
+	var getCertificate func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+	switch {
+	case as.tlsCert != nil:
+		getCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return as.tlsCert.certificate()
+		}
+	case as.tlsSecretCert.Load() != nil:
+		getCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return as.tlsSecretCert.Load(), nil
+		}
+	default:
+		return nil
+	}
+	return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: getCertificate}
+}
+
+// newVLLMTransport returns the transport of the requests to vLLM. When the vLLM pods serve TLS,
+// their certificate is verified with the CA bundle, or the ca.crt of their TLS Secret, and the
+// client certificate, if any, is presented; HTTP/2 is used when vLLM offers it. Without TLS, vLLM
+// is reached over HTTP/1.1, or over HTTP/2 with prior knowledge with VLLMH2C.
+func (as *AutoScaler) newVLLMTransport(ctx context.Context) (http.RoundTripper, error) {
+	if as.config.VLLMH2C {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Protocols = new(http.Protocols)
+		transport.Protocols.SetUnencryptedHTTP2(true)
+		return transport, nil
+	}
+	if as.config.VLLMTLSSecret == "" {
+		return http.DefaultTransport, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: as.config.VLLMTLSServerName,
+	}
+
+	var ca []byte
+	if as.config.VLLMTLSCA != "" {
+		var err error
+		if ca, err = os.ReadFile(as.config.VLLMTLSCA); err != nil {
+			return nil, fmt.Errorf("failed to read vLLM TLS CA: %w", err)
+		}
+	} else {
+		data, err := as.k8sManager.GetSecretData(ctx, as.config.VLLMTLSSecret)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vLLM TLS Secret %s: %w", as.config.VLLMTLSSecret, err)
+		}
+		ca = data["ca.crt"]
+	}
+	if len(ca) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in the vLLM TLS CA")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if as.config.VLLMTLSCert != "" {
+		clientCert, err := newCertificateFiles(as.config.VLLMTLSCert, as.config.VLLMTLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load vLLM TLS client certificate: %w", err)
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return clientCert.certificate()
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
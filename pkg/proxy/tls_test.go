@@ -0,0 +1,247 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// testCertificate is a certificate and key, PEM-encoded and written to files
+type testCertificate struct {
+	cert              *x509.Certificate
+	key               *ecdsa.PrivateKey
+	certPEM, keyPEM   []byte
+	certFile, keyFile string
+}
+
+// newTestCertificate issues a certificate for name, valid for 127.0.0.1 when ip is set, signed by
+// ca, or a CA when ca is nil, and writes it to dir
+func newTestCertificate(t *testing.T, dir, name string, ip bool, ca *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ip {
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	parent, signer := template, key
+	if ca == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	c := &testCertificate{
+		cert:     cert,
+		key:      key,
+		certPEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:   pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	require.NoError(t, os.WriteFile(c.certFile, c.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(c.keyFile, c.keyPEM, 0o600))
+	return c
+}
+
+func TestCertificateFiles_Reload(t *testing.T) {
+	dir := t.TempDir()
+	first := newTestCertificate(t, dir, "proxy", true, nil)
+	files, err := newCertificateFiles(first.certFile, first.keyFile)
+	require.NoError(t, err)
+
+	cert, err := files.certificate()
+	require.NoError(t, err)
+	assert.Equal(t, first.cert.Raw, cert.Certificate[0])
+
+	// A renewed certificate replaces the files
+	renewed := newTestCertificate(t, t.TempDir(), "proxy", true, nil)
+	require.NoError(t, os.WriteFile(first.certFile, renewed.certPEM, 0o600))
+	require.NoError(t, os.WriteFile(first.keyFile, renewed.keyPEM, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(first.certFile, later, later))
+	cert, err = files.certificate()
+	require.NoError(t, err)
+	assert.Equal(t, renewed.cert.Raw, cert.Certificate[0], "the renewed certificate is served")
+
+	require.NoError(t, os.Remove(first.keyFile))
+	cert, err = files.certificate()
+	require.NoError(t, err)
+	assert.Equal(t, renewed.cert.Raw, cert.Certificate[0], "the loaded certificate is kept while the files are missing")
+
+	_, err = newCertificateFiles(first.certFile, first.keyFile)
+	assert.ErrorContains(t, err, "failed to load certificate")
+}
+
+func TestServeTLS(t *testing.T) {
+	cert := newTestCertificate(t, t.TempDir(), "proxy", true, nil)
+	files, err := newCertificateFiles(cert.certFile, cert.keyFile)
+	require.NoError(t, err)
+	as := &AutoScaler{config: &Config{}, tlsCert: files}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stop := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	go func() {
+		served <- as.serve(&http.Server{Handler: handler, TLSConfig: as.serverTLSConfig()}, ln, stop)
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/readyz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	resp, err = http.Get("http://" + ln.Addr().String() + "/readyz")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "plain HTTP is refused")
+	_ = resp.Body.Close()
+
+	stop <- syscall.SIGTERM
+	assert.NoError(t, <-served)
+}
+
+func TestServerTLSConfig_Secret(t *testing.T) {
+	cert := newTestCertificate(t, t.TempDir(), "proxy", true, nil)
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "proxy-tls", Namespace: "vllm"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{corev1.TLSCertKey: cert.certPEM, corev1.TLSPrivateKeyKey: cert.keyPEM},
+	})
+	as := &AutoScaler{
+		config:     &Config{Namespace: "vllm", TLSSecret: "proxy-tls"},
+		k8sManager: kubernetes.NewK8sManager(clientset, &kubernetes.Config{Namespace: "vllm"}),
+	}
+	assert.Nil(t, as.serverTLSConfig(), "plain HTTP until a certificate is loaded")
+
+	require.NoError(t, as.loadTLSSecret(context.Background()))
+	tlsConfig := as.serverTLSConfig()
+	require.NotNil(t, tlsConfig)
+	served, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, cert.cert.Raw, served.Certificate[0])
+
+	as.config.TLSSecret = "missing"
+	assert.ErrorContains(t, as.loadTLSSecret(context.Background()), "failed to read TLS Secret missing")
+}
+
+func TestVLLMTransport(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, dir, "ca", false, nil)
+	server := newTestCertificate(t, dir, "vllm-api", false, ca)
+	client := newTestCertificate(t, dir, "vllm-chill", false, ca)
+
+	// vLLM requiring client certificates, with a certificate naming the service but not the pod IP
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	var peer string
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		peer = r.TLS.PeerCertificates[0].Subject.CommonName
+		w.WriteHeader(http.StatusOK)
+	}))
+	serverCert, err := tls.X509KeyPair(server.certPEM, server.keyPEM)
+	require.NoError(t, err)
+	backend.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	backend.StartTLS()
+	defer backend.Close()
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "vllm-tls", Namespace: "vllm"},
+		Type:       corev1.SecretTypeTLS,
+		Data:       map[string][]byte{"ca.crt": ca.certPEM, corev1.TLSCertKey: server.certPEM, corev1.TLSPrivateKeyKey: server.keyPEM},
+	})
+	newTransport := func(config *Config) (http.RoundTripper, error) {
+		config.Namespace = "vllm"
+		as := &AutoScaler{config: config, k8sManager: kubernetes.NewK8sManager(clientset, &kubernetes.Config{Namespace: "vllm"})}
+		return as.newVLLMTransport(context.Background())
+	}
+	get := func(transport http.RoundTripper) error {
+		resp, err := (&http.Client{Transport: transport}).Get(backend.URL + "/health")
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return nil
+	}
+
+	t.Run("mutual TLS verified with the Secret's CA", func(t *testing.T) {
+		transport, err := newTransport(&Config{VLLMTLSSecret: "vllm-tls", VLLMTLSCert: client.certFile, VLLMTLSKey: client.keyFile, VLLMTLSServerName: "vllm-api"})
+		require.NoError(t, err)
+		require.NoError(t, get(transport))
+		assert.Equal(t, "vllm-chill", peer, "the client certificate is presented")
+	})
+
+	t.Run("CA file", func(t *testing.T) {
+		transport, err := newTransport(&Config{VLLMTLSSecret: "vllm-tls", VLLMTLSCA: ca.certFile, VLLMTLSCert: client.certFile, VLLMTLSKey: client.keyFile, VLLMTLSServerName: "vllm-api"})
+		require.NoError(t, err)
+		assert.NoError(t, get(transport))
+
+		_, err = newTransport(&Config{VLLMTLSSecret: "vllm-tls", VLLMTLSCA: client.keyFile})
+		assert.ErrorContains(t, err, "no certificate found in the vLLM TLS CA")
+	})
+
+	t.Run("the pod IP is not a name of the certificate", func(t *testing.T) {
+		transport, err := newTransport(&Config{VLLMTLSSecret: "vllm-tls", VLLMTLSCert: client.certFile, VLLMTLSKey: client.keyFile})
+		require.NoError(t, err)
+		assert.Error(t, get(transport))
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		transport, err := newTransport(&Config{VLLMTLSSecret: "vllm-tls", VLLMTLSServerName: "vllm-api"})
+		require.NoError(t, err)
+		assert.Error(t, get(transport))
+	})
+
+	t.Run("plain HTTP", func(t *testing.T) {
+		transport, err := newTransport(&Config{})
+		require.NoError(t, err)
+		assert.Equal(t, http.DefaultTransport, transport)
+	})
+}
@@ -0,0 +1,44 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+// toolChoiceNone reports whether a tool_choice forbids tool use: "none" in the OpenAI API,
+// {"type":"none"} in the Anthropic API
+func toolChoiceNone(raw json.RawMessage) bool {
+	var choice string
+	if json.Unmarshal(raw, &choice) == nil {
+		return choice == "none"
+	}
+	var typed struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(raw, &typed) == nil && typed.Type == "none"
+}
+
+// dropForbiddenTools removes tools and tool_choice from a request that forbids tool use, so the
+// tool schemas don't take up the model's context for a text-only response. It reports whether
+// the body was rewritten; other requests are left as sent.
+func dropForbiddenTools(r *http.Request) (bool, error) {
+	body, err := parsedBody(r)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Contains(body.data, []byte(`"none"`)) {
+		return false, nil
+	}
+	fields, err := body.object()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := fields["tools"]; !ok || !toolChoiceNone(fields["tool_choice"]) {
+		return false, nil
+	}
+
+	delete(fields, "tools")
+	delete(fields, "tool_choice")
+	return true, body.rewrite(r)
+}
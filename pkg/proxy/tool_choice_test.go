@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolChoiceNone(t *testing.T) {
+	tests := []struct {
+		choice string
+		none   bool
+	}{
+		{choice: `"none"`, none: true},
+		{choice: `{"type":"none"}`, none: true},
+		{choice: `"auto"`},
+		{choice: `{"type":"any"}`},
+		{choice: `{"type":"function","function":{"name":"none"}}`},
+		{choice: ``},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.none, toolChoiceNone(json.RawMessage(tt.choice)), tt.choice)
+	}
+}
+
+func TestDropForbiddenTools(t *testing.T) {
+	tools := `[{"type":"function","function":{"name":"get_weather","parameters":{}}}]`
+	tests := []struct {
+		name    string
+		body    string
+		dropped bool
+	}{
+		{name: "openai none", body: `{"model":"qwen","tools":` + tools + `,"tool_choice":"none"}`, dropped: true},
+		{name: "anthropic none", body: `{"model":"qwen","tools":[{"name":"get_weather","input_schema":{}}],"tool_choice":{"type":"none"}}`, dropped: true},
+		{name: "auto", body: `{"model":"qwen","tools":` + tools + `,"tool_choice":"auto"}`},
+		{name: "no tool_choice", body: `{"model":"qwen","tools":` + tools + `,"messages":[{"role":"user","content":"none"}]}`},
+		{name: "no tools", body: `{"model":"qwen","tool_choice":"none"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(tt.body))
+			dropped, err := dropForbiddenTools(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.dropped, dropped)
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			if !tt.dropped {
+				assert.Equal(t, tt.body, string(body), "the body is forwarded as sent")
+				return
+			}
+			assert.JSONEq(t, `{"model":"qwen"}`, string(body))
+			assert.Equal(t, int64(len(body)), r.ContentLength)
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`"none"`))
+	_, err := dropForbiddenTools(r)
+	assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/efortin/vllm-chill/pkg/stats"
+)
+
+const (
+	// mismatchXMLWithoutNative: XML tool call markup in content but no native tool_calls,
+	// the configured parser does not understand the format the model emits
+	mismatchXMLWithoutNative = "xml_without_native"
+	// mismatchNativeWithXML: native tool_calls present but XML markup still leaked into content,
+	// the configured parser only partially matches the model output
+	mismatchNativeWithXML = "native_with_xml"
+
+	parserDetectorWindow    = 20 // Tool-call responses kept per model
+	parserDetectorThreshold = 3  // Minimum mismatches in the window before flagging
+)
+
+// XML fallback modes
+const (
+	XMLFallbackOn   = "on"   // Always convert XML tool calls (default)
+	XMLFallbackOff  = "off"  // Never convert XML tool calls
+	XMLFallbackAuto = "auto" // Convert only once a parser mismatch has been detected
+)
+
+// ToolParserWarning describes a suspected tool-call parser misconfiguration
+type ToolParserWarning struct {
+	Model      string `json:"model"`
+	Kind       string `json:"kind"`
+	Mismatches int    `json:"mismatches"`
+	Samples    int    `json:"samples"`
+	Message    string `json:"message"`
+}
+
+// toolParserDetector samples tool-call responses per model and flags a parser mismatch
+// when most recent samples disagree with the format the configured parser produces
+type toolParserDetector struct {
+	mu       sync.Mutex
+	samples  map[string][]string // model -> recent outcomes ("" for a matching response, mismatch kind otherwise)
+	warnings map[string]ToolParserWarning
+	metrics  *stats.MetricsRecorder
+}
+
+// newToolParserDetector creates a detector, metrics may be nil
+func newToolParserDetector(metrics *stats.MetricsRecorder) *toolParserDetector {
+	return &toolParserDetector{
+		samples:  make(map[string][]string),
+		warnings: make(map[string]ToolParserWarning),
+		metrics:  metrics,
+	}
+}
+
+// observe records the tool call format of a completed response.
+// Responses without any tool call activity are not sampled.
+func (d *toolParserDetector) observe(model string, xmlSeen, nativeSeen bool) {
+	if model == "" || (!xmlSeen && !nativeSeen) {
+		return
+	}
+
+	outcome := ""
+	switch {
+	case xmlSeen && !nativeSeen:
+		outcome = mismatchXMLWithoutNative
+	case xmlSeen && nativeSeen:
+		outcome = mismatchNativeWithXML
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.samples[model] = append(d.samples[model], outcome)
+	if n := len(d.samples[model]); n > parserDetectorWindow {
+		d.samples[model] = d.samples[model][n-parserDetectorWindow:]
+	}
+	window := d.samples[model]
+
+	if outcome != "" && d.metrics != nil {
+		d.metrics.RecordToolParserMismatch(model, outcome)
+	}
+
+	d.evaluate(model, window)
+}
+
+// evaluate raises or clears the warning for a model from its sample window
+func (d *toolParserDetector) evaluate(model string, window []string) {
+	counts := make(map[string]int)
+	for _, outcome := range window {
+		if outcome != "" {
+			counts[outcome]++
+		}
+	}
+
+	kind, mismatches := "", 0
+	for _, k := range []string{mismatchXMLWithoutNative, mismatchNativeWithXML} {
+		if counts[k] > mismatches {
+			kind, mismatches = k, counts[k]
+		}
+	}
+
+	previous, flagged := d.warnings[model]
+	if mismatches < parserDetectorThreshold || mismatches*2 <= len(window) {
+		if flagged {
+			log.Printf("[TOOL-PARSER] Mismatch cleared for model %s", model)
+			delete(d.warnings, model)
+			if d.metrics != nil {
+				d.metrics.SetToolParserWarning(model, previous.Kind, false)
+			}
+		}
+		return
+	}
+
+	warning := ToolParserWarning{
+		Model:      model,
+		Kind:       kind,
+		Mismatches: mismatches,
+		Samples:    len(window),
+		Message:    mismatchMessage(kind),
+	}
+	d.warnings[model] = warning
+
+	if !flagged || previous.Kind != kind {
+		log.Printf("[TOOL-PARSER] WARNING: model %s: %s (%d/%d responses)", model, warning.Message, mismatches, len(window))
+		if d.metrics != nil {
+			if flagged {
+				d.metrics.SetToolParserWarning(model, previous.Kind, false)
+			}
+			d.metrics.SetToolParserWarning(model, kind, true)
+		}
+	}
+}
+
+// mismatchMessage returns a human readable explanation of a mismatch kind
+func mismatchMessage(kind string) string {
+	switch kind {
+	case mismatchXMLWithoutNative:
+		return "model emits XML tool calls that the configured toolCallParser does not convert"
+	case mismatchNativeWithXML:
+		return "configured toolCallParser only partially converts tool calls, XML markup leaks into content"
+	default:
+		return "tool call format does not match the configured toolCallParser"
+	}
+}
+
+// needsXMLFallback reports whether the model is flagged as emitting unconverted XML tool calls
+func (d *toolParserDetector) needsXMLFallback(model string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.warnings[model].Kind == mismatchXMLWithoutNative
+}
+
+// activeWarnings returns the current warnings sorted by model
+func (d *toolParserDetector) activeWarnings() []ToolParserWarning {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	warnings := make([]ToolParserWarning, 0, len(d.warnings))
+	for _, w := range d.warnings {
+		warnings = append(warnings, w)
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return warnings[i].Model < warnings[j].Model
+	})
+	return warnings
+}
@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestToolParserDetector_FlagsXMLWithoutNative(t *testing.T) {
+	d := newToolParserDetector(nil)
+
+	for i := 0; i < parserDetectorThreshold-1; i++ {
+		d.observe("qwen", true, false)
+	}
+	assert.Empty(t, d.activeWarnings())
+	assert.False(t, d.needsXMLFallback("qwen"))
+
+	d.observe("qwen", true, false)
+	warnings := d.activeWarnings()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, "qwen", warnings[0].Model)
+	assert.Equal(t, mismatchXMLWithoutNative, warnings[0].Kind)
+	assert.Equal(t, parserDetectorThreshold, warnings[0].Mismatches)
+	assert.True(t, d.needsXMLFallback("qwen"))
+	assert.False(t, d.needsXMLFallback("deepseek"))
+}
+
+func TestToolParserDetector_FlagsNativeWithXML(t *testing.T) {
+	d := newToolParserDetector(nil)
+
+	for i := 0; i < parserDetectorThreshold; i++ {
+		d.observe("qwen", true, true)
+	}
+
+	warnings := d.activeWarnings()
+	require.Len(t, warnings, 1)
+	assert.Equal(t, mismatchNativeWithXML, warnings[0].Kind)
+	assert.False(t, d.needsXMLFallback("qwen"))
+}
+
+func TestToolParserDetector_IgnoresResponsesWithoutToolCalls(t *testing.T) {
+	d := newToolParserDetector(nil)
+
+	for i := 0; i < parserDetectorWindow; i++ {
+		d.observe("qwen", false, false)
+	}
+	d.observe("", true, false)
+
+	assert.Empty(t, d.samples)
+	assert.Empty(t, d.activeWarnings())
+}
+
+func TestToolParserDetector_ClearsWhenOutputMatches(t *testing.T) {
+	d := newToolParserDetector(nil)
+
+	for i := 0; i < parserDetectorThreshold; i++ {
+		d.observe("qwen", true, false)
+	}
+	require.Len(t, d.activeWarnings(), 1)
+
+	// Native tool calls take over once the parser is fixed
+	for i := 0; i < parserDetectorWindow; i++ {
+		d.observe("qwen", false, true)
+	}
+	assert.Empty(t, d.activeWarnings())
+	assert.Len(t, d.samples["qwen"], parserDetectorWindow)
+}
+
+func TestResponseWriter_XMLFallbackOff(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.xmlFallbackOff = true
+
+	stream := `data: {"choices":[{"index":0,"delta":{"content":"<tool_call><function=read><parameter=path>/tmp</parameter></function></tool_call>"}}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	assert.True(t, rw.xmlPatternSeen)
+	assert.False(t, rw.xmlDetectionMode)
+	assert.Equal(t, stream, recorder.Body.String())
+	assert.False(t, strings.Contains(recorder.Body.String(), `"tool_calls"`))
+}
+
+func TestAutoScaler_XMLFallbackEnabled(t *testing.T) {
+	as := &AutoScaler{config: &Config{}, parserCheck: newToolParserDetector(nil)}
+	assert.True(t, as.xmlFallbackEnabled("qwen"))
+
+	as.config.XMLFallback = XMLFallbackOff
+	assert.False(t, as.xmlFallbackEnabled("qwen"))
+
+	as.config.XMLFallback = XMLFallbackAuto
+	assert.False(t, as.xmlFallbackEnabled("qwen"))
+	for i := 0; i < parserDetectorThreshold; i++ {
+		as.parserCheck.observe("qwen", true, false)
+	}
+	assert.True(t, as.xmlFallbackEnabled("qwen"))
+}
+
+func TestStatusHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	as := &AutoScaler{
+		config:      &Config{},
+		activeModel: "qwen",
+		k8sManager:  kubernetes.NewK8sManager(fake.NewSimpleClientset(), &kubernetes.Config{Namespace: "vllm", Deployment: "vllm"}),
+		parserCheck: newToolParserDetector(nil),
+	}
+	as.runner = as.k8sManager
+	as.strategy = &deleteStrategy{as: as}
+	for i := 0; i < parserDetectorThreshold; i++ {
+		as.parserCheck.observe("qwen", true, false)
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/proxy/status", nil)
+
+	as.statusHandler(c)
+
+	assert.Equal(t, 200, w.Code)
+	var response struct {
+		ActiveModel        string              `json:"active_model"`
+		Ready              bool                `json:"ready"`
+		ScaleStrategy      string              `json:"scale_strategy"`
+		XMLFallback        string              `json:"xml_fallback"`
+		ToolParserWarnings []ToolParserWarning `json:"tool_parser_warnings"`
+	}
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+	assert.Equal(t, "qwen", response.ActiveModel)
+	assert.False(t, response.Ready)
+	assert.Equal(t, ScaleStrategyDelete, response.ScaleStrategy)
+	assert.Equal(t, XMLFallbackOn, response.XMLFallback)
+	require.Len(t, response.ToolParserWarnings, 1)
+	assert.Equal(t, mismatchXMLWithoutNative, response.ToolParserWarnings[0].Kind)
+}
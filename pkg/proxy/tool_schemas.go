@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/efortin/vllm-chill/pkg/parser"
+)
+
+// Tool argument validation modes, for the tool calls converted from content
+const (
+	ToolArgsCoerce = "coerce" // Coerce arguments of the wrong simple type to the tool's schema (default)
+	ToolArgsStrict = "strict" // Also replace tool calls whose arguments don't match by an error text
+	ToolArgsOff    = "off"    // Send the converted arguments as parsed
+)
+
+// toolSchemaPaths are the endpoints whose requests define tools
+var toolSchemaPaths = map[string]bool{
+	"/v1/chat/completions": true,
+	anthropicMessagesPath:  true,
+}
+
+// requestTools returns the tools field of a chat completion or Messages API request, nil when the
+// request defines no tools. Their schemas are only built once a converted tool call needs them.
+func requestTools(r *http.Request) (json.RawMessage, error) {
+	if r.Method != http.MethodPost || !toolSchemaPaths[r.URL.Path] || r.Body == nil {
+		return nil, nil
+	}
+	body, err := parsedBody(r)
+	if err != nil {
+		return nil, err
+	}
+	tools := body.fields["tools"]
+	if len(tools) == 0 || bytes.Equal(tools, []byte("null")) {
+		return nil, nil
+	}
+	return tools, nil
+}
+
+// toolSchemasOf returns the argument schemas of the tools of a request: the parameters of OpenAI
+// function tools, the input_schema of Anthropic tools. It is nil when there are none.
+func toolSchemasOf(rawTools json.RawMessage) parser.ToolSchemas {
+	var tools []struct {
+		Name        string          `json:"name"`
+		InputSchema json.RawMessage `json:"input_schema"`
+		Function    *struct {
+			Name       string          `json:"name"`
+			Parameters json.RawMessage `json:"parameters"`
+		} `json:"function"`
+	}
+	if json.Unmarshal(rawTools, &tools) != nil || len(tools) == 0 {
+		return nil
+	}
+	schemas := make(parser.ToolSchemas, len(tools))
+	for _, tool := range tools {
+		if tool.Function != nil {
+			schemas[tool.Function.Name] = tool.Function.Parameters
+		} else if tool.Name != "" {
+			schemas[tool.Name] = tool.InputSchema
+		}
+	}
+	return schemas
+}
+
+// schemas returns the argument schemas of the request's tools, built on the first converted tool
+// call so responses without one don't pay for them
+func (rw *responseWriter) schemas() parser.ToolSchemas {
+	if rw.toolSchemas == nil && rw.requestTools != nil {
+		rw.toolSchemas = toolSchemasOf(rw.requestTools)
+		rw.requestTools = nil
+	}
+	return rw.toolSchemas
+}
+
+// checkToolCalls checks the tool calls a fallback parser converted against the request's tool
+// schemas, coercing their arguments. In strict mode, calls still not matching are left out, and
+// returned as the error text replacing them.
+func (rw *responseWriter) checkToolCalls(toolCalls []ToolCall) ([]ToolCall, string) {
+	if len(toolCalls) == 0 || len(rw.schemas()) == 0 {
+		return toolCalls, ""
+	}
+	checked := make([]ToolCall, 0, len(toolCalls))
+	var errorText string
+	coerced, invalid := 0, 0
+	for _, toolCall := range toolCalls {
+		arguments, wasCoerced, err := rw.toolSchemas.Check(toolCall.Function.Name, toolCall.Function.Arguments)
+		switch {
+		case err != nil:
+			invalid++
+			log.Printf("[%s] Tool call %s: %v", rw.parserLogPrefix(), toolCall.ID, err)
+			if rw.rejectInvalidCalls {
+				errorText += parser.ToolCallErrorText(err)
+				continue
+			}
+		case wasCoerced:
+			coerced++
+		}
+		toolCall.Function.Arguments = arguments
+		checked = append(checked, toolCall)
+	}
+	rw.recordSchemaChecks(rw.toolParser.Name(), len(toolCalls), coerced, invalid)
+	return checked, errorText
+}
+
+// recordSchemaChecks adds the outcome of checking converted tool calls against their schemas
+func (rw *responseWriter) recordSchemaChecks(parserName string, checked, coerced, invalid int) {
+	rw.coercedCalls += coerced
+	rw.invalidCalls += invalid
+	if rw.metrics != nil && checked > 0 {
+		rw.metrics.RecordToolSchemaChecks(parserName, checked-coerced-invalid, coerced, invalid, rw.rejectInvalidCalls)
+	}
+}
@@ -0,0 +1,168 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestToolSchemas(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		expected parser.ToolSchemas
+	}{
+		{
+			name: "openai",
+			path: "/v1/chat/completions",
+			body: `{"model":"qwen","tools":[{"type":"function","function":{"name":"ls","parameters":{"type":"object"}}}]}`,
+			expected: parser.ToolSchemas{
+				"ls": json.RawMessage(`{"type":"object"}`),
+			},
+		},
+		{
+			name: "anthropic",
+			path: anthropicMessagesPath,
+			body: `{"model":"qwen","tools":[{"name":"ls","input_schema":{"type":"object"}},{"name":"web_search","type":"web_search_20250305"}]}`,
+			expected: parser.ToolSchemas{
+				"ls":         json.RawMessage(`{"type":"object"}`),
+				"web_search": nil,
+			},
+		},
+		{
+			name: "no tools",
+			path: "/v1/chat/completions",
+			body: `{"model":"qwen","messages":[]}`,
+		},
+		{
+			name: "other endpoint",
+			path: "/v1/completions",
+			body: `{"model":"qwen","tools":[{"type":"function","function":{"name":"ls"}}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			tools, err := requestTools(r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, toolSchemasOf(tools))
+
+			replayed, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.body, string(replayed), "the body is replayed")
+		})
+	}
+}
+
+var testRequestToolSchemas = parser.ToolSchemas{
+	"read_file": json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"},"limit":{"type":"integer"}},"required":["path"]}`),
+}
+
+func TestResponseWriter_CoercesStreamedToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolSchemas = testRequestToolSchemas
+
+	for _, part := range []string{"<tool_call>\n<function=read_file>\n<parameter=path>main.go</parameter>\n", "<parameter=limit>20</parameter>\n</function>\n</tool_call>"} {
+		_, err := rw.Write([]byte(sseContentChunk(t, part)))
+		require.NoError(t, err)
+	}
+	_, err := rw.Write([]byte(`data: {"choices":[{"index":0,"delta":{"content":""},"finish_reason":"stop"}]}` + "\n\ndata: [DONE]\n\n"))
+	require.NoError(t, err)
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `"name":"read_file"`)
+	assert.Contains(t, body, `"arguments":"{\"limit\":20,\"path\":\"main.go\"}"`, "the call is sent whole, its limit an integer")
+	assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+	assert.Equal(t, 1, rw.coercedCalls)
+	assert.Zero(t, rw.invalidCalls)
+}
+
+func TestResponseWriter_BuildsToolSchemasOnToolCalls(t *testing.T) {
+	tools := json.RawMessage(`[{"type":"function","function":{"name":"read_file","parameters":{"type":"object","properties":{"limit":{"type":"integer"}}}}}]`)
+	end := `data: {"choices":[{"index":0,"delta":{"content":""},"finish_reason":"stop"}]}` + "\n\ndata: [DONE]\n\n"
+
+	rw := newResponseWriter(httptest.NewRecorder(), false, nil)
+	rw.requestTools = tools
+	_, err := rw.Write([]byte(sseContentChunk(t, "No tool needed.") + end))
+	require.NoError(t, err)
+	assert.Nil(t, rw.toolSchemas, "responses without tool calls don't build the schemas")
+
+	recorder := httptest.NewRecorder()
+	rw = newResponseWriter(recorder, false, nil)
+	rw.requestTools = tools
+	_, err = rw.Write([]byte(sseContentChunk(t, "<tool_call>\n<function=read_file>\n<parameter=limit>20</parameter>\n</function>\n</tool_call>") + end))
+	require.NoError(t, err)
+	assert.Contains(t, rw.toolSchemas, "read_file")
+	assert.Contains(t, recorder.Body.String(), `"arguments":"{\"limit\":20}"`)
+	assert.Equal(t, 1, rw.coercedCalls)
+}
+
+func TestResponseWriter_RejectsInvalidToolCalls(t *testing.T) {
+	t.Run("xml", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		rw := newResponseWriter(recorder, false, nil)
+		rw.toolSchemas = testRequestToolSchemas
+		rw.rejectInvalidCalls = true
+
+		stream := sseContentChunk(t, "<tool_call>\n<function=read_file>\n<parameter=limit>all</parameter>\n</function>\n</tool_call>") +
+			`data: {"choices":[{"index":0,"delta":{"content":""},"finish_reason":"stop"}]}` + "\n\ndata: [DONE]\n\n"
+		_, err := rw.Write([]byte(stream))
+		require.NoError(t, err)
+
+		body := recorder.Body.String()
+		assert.NotContains(t, body, `"tool_calls"`)
+		assert.Contains(t, body, `[tool call error] arguments of tool \"read_file\" don't match its schema`)
+		assert.Contains(t, body, `"finish_reason":"stop"`)
+		assert.Zero(t, rw.parsedToolCalls)
+		assert.Equal(t, 1, rw.invalidCalls)
+	})
+
+	t.Run("buffered", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		rw := newResponseWriter(recorder, false, nil)
+		rw.toolParser = parser.ForModel("", "mistral")
+		rw.toolSchemas = testRequestToolSchemas
+		rw.rejectInvalidCalls = true
+
+		content := `[TOOL_CALLS][{"name": "read_file", "arguments": {"path": "a.go", "limit": "5"}}, {"name": "rm", "arguments": {"path": "/"}}]`
+		_, err := rw.Write([]byte(sseContentChunk(t, content) + "data: [DONE]\n\n"))
+		require.NoError(t, err)
+
+		body := recorder.Body.String()
+		assert.Contains(t, body, `"arguments":"{\"limit\":5,\"path\":\"a.go\"}"`)
+		assert.NotContains(t, body, `"name":"rm"`)
+		assert.Contains(t, body, `arguments of tool \"rm\" don't match its schema: the request defines no such tool`)
+		assert.Contains(t, body, `"finish_reason":"tool_calls"`)
+		assert.Equal(t, 1, rw.parsedToolCalls)
+		assert.Equal(t, 1, rw.coercedCalls)
+		assert.Equal(t, 1, rw.invalidCalls)
+	})
+}
+
+func TestAnthropicStream_RejectsInvalidToolCalls(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	rw := newResponseWriter(recorder, false, nil)
+	rw.toolSchemas = testRequestToolSchemas
+	rw.rejectInvalidCalls = true
+
+	stream := anthropicMessageStart + anthropicTextStart +
+		anthropicTextDelta(t, "<function=read_file>\n<parameter=limit>all</parameter>\n</function>") +
+		anthropicTextStop + anthropicMessageDelta("end_turn") + anthropicMessageStop
+	_, err := rw.Write([]byte(stream))
+	require.NoError(t, err)
+
+	body := recorder.Body.String()
+	assert.NotContains(t, body, "tool_use")
+	assert.Contains(t, body, "[tool call error]")
+	assert.Contains(t, body, `"stop_reason":"end_turn"`)
+}
@@ -0,0 +1,55 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestProxyHandler_Spans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","messages":[]}`))
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	as.proxyHandler(httptest.NewRecorder(), r)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	request, ok := spans["POST /v1/chat/completions"]
+	require.True(t, ok, "the request is traced")
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", request.SpanContext().TraceID().String(), "the request joins the client's trace")
+	for _, name := range []string{"extract_model", "model_switch", "ensure_scaled_up", "upstream"} {
+		require.Contains(t, spans, name)
+		assert.Equal(t, request.SpanContext().SpanID(), spans[name].Parent().SpanID(), name)
+	}
+	assert.Equal(t, spans["ensure_scaled_up"].SpanContext().SpanID(), spans["wait_for_ready"].Parent().SpanID())
+
+	// vLLM joins the trace under the upstream span
+	upstreamSpan := spans["upstream"].SpanContext()
+	assert.Contains(t, traceparent, upstreamSpan.TraceID().String())
+	assert.Contains(t, traceparent, upstreamSpan.SpanID().String())
+}
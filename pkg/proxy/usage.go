@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/admin"
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// usageConfigMapKey is the ConfigMap entry holding the persisted usage
+const usageConfigMapKey = "usage.json"
+
+// requestAccount is filled by the proxy handler once a backend serves the request, and read back
+// by the usage middleware when the response is complete
+type requestAccount struct {
+	model   string
+	gpus    int
+	started time.Time
+}
+
+type requestAccountKey struct{}
+
+// serve records the model and GPUs serving the request, from now on
+func (a *requestAccount) serve(model string, gpus int) {
+	if a == nil {
+		return
+	}
+	a.model = model
+	a.gpus = gpus
+	a.started = time.Now()
+}
+
+// accountFrom returns the account of a request, nil when usage is not tracked
+func accountFrom(ctx context.Context) *requestAccount {
+	account, _ := ctx.Value(requestAccountKey{}).(*requestAccount)
+	return account
+}
+
+// usageKey identifies the client in the usage accounting, by tenant name when keys are validated
+func usageKey(r *http.Request) string {
+	if tenant := auth.TenantFrom(r.Context()); tenant != nil {
+		return tenant.Name
+	}
+	return clientID(r)
+}
+
+// usageMiddleware records the tokens and GPU time of the /v1 and passthrough requests a backend
+// served, and the requests and tokens of their user
+func (as *AutoScaler) usageMiddleware(c *gin.Context) {
+	r := c.Request
+	if !as.apiRequest(r) {
+		c.Next()
+		return
+	}
+
+	// The audit middleware may have set the account already
+	account := accountFrom(r.Context())
+	if account == nil {
+		account = &requestAccount{}
+		c.Request = r.WithContext(context.WithValue(r.Context(), requestAccountKey{}, account))
+	}
+	uw, done := trackUsage(c)
+	defer done()
+	c.Next()
+
+	// Requests rejected or answered by the proxy itself are not billed
+	if account.model == "" {
+		return
+	}
+	prompt, completion := uw.split(r.ContentLength)
+	as.recordUsage(account, usageKey(c.Request), userFrom(c.Request.Context()), prompt, completion)
+}
+
+// recordUsage records the tokens and GPU time of a request served by a backend, and the tokens of
+// its user
+func (as *AutoScaler) recordUsage(account *requestAccount, key, user string, prompt, completion int) {
+	if user != "" {
+		as.metrics.RecordUserUsage(as.userLabels.label(user), prompt, completion)
+	}
+	as.usage.Record(usage.Record{
+		Model:            account.model,
+		Key:              key,
+		User:             user,
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		GPUSeconds:       time.Since(account.started).Seconds() * float64(account.gpus),
+	})
+}
+
+// usageHandler returns the usage per model, per API key and per user, to admin token holders
+func (as *AutoScaler) usageHandler(c *gin.Context) {
+	if !admin.Authenticate(c, as.config.AdminToken) {
+		return
+	}
+	c.JSON(http.StatusOK, as.usage.Summary())
+}
+
+// restoreUsage loads the persisted usage, a missing ConfigMap starts from zero
+func (as *AutoScaler) restoreUsage(ctx context.Context) error {
+	data, err := as.k8sManager.GetConfigMapData(ctx, as.config.UsageConfigMap)
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read usage ConfigMap %s: %w", as.config.UsageConfigMap, err)
+	}
+	snapshot, ok := data[usageConfigMapKey]
+	if !ok {
+		return nil
+	}
+	if err := as.usage.Restore([]byte(snapshot)); err != nil {
+		return fmt.Errorf("usage ConfigMap %s: %w", as.config.UsageConfigMap, err)
+	}
+	return nil
+}
+
+// startUsagePersistence periodically saves the usage to its ConfigMap when it changed
+func (as *AutoScaler) startUsagePersistence(ctx context.Context) {
+	ticker := time.NewTicker(configDriftCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			as.persistUsage(ctx)
+		}
+	}
+}
+
+// persistUsage saves the usage to its ConfigMap if records were added since the last save
+func (as *AutoScaler) persistUsage(ctx context.Context) {
+	data, changed, err := as.usage.Snapshot()
+	if err != nil {
+		log.Printf("Failed to encode usage: %v", err)
+		return
+	}
+	if !changed {
+		return
+	}
+	if err := as.k8sManager.SaveConfigMapData(ctx, as.config.UsageConfigMap, map[string]string{usageConfigMapKey: string(data)}); err != nil {
+		log.Printf("Failed to persist usage: %v", err)
+	}
+}
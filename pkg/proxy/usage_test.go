@@ -0,0 +1,115 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/auth"
+	"github.com/efortin/vllm-chill/pkg/usage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageMiddleware(t *testing.T) {
+	as := &AutoScaler{config: &Config{AdminToken: "s3cret"}, usage: usage.NewTracker()}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(as.usageMiddleware)
+	router.GET("/proxy/usage", as.usageHandler)
+	router.NoRoute(func(c *gin.Context) {
+		if c.Request.URL.Path != "/v1/rejected" {
+			accountFrom(c.Request.Context()).serve("qwen", 2)
+		}
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString(`{"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}`)
+	})
+
+	send := func(path, key string) {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	send("/v1/chat/completions", "sk-a")
+	send("/v1/chat/completions", "sk-a")
+	send("/v1/chat/completions", "")
+	send("/v1/rejected", "sk-a")
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/proxy/usage", nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var summary usage.Summary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, int64(3), summary.Total.Requests, "requests no backend served are not billed")
+	assert.Equal(t, int64(30), summary.Models["qwen"].PromptTokens)
+	assert.Equal(t, int64(15), summary.Models["qwen"].CompletionTokens)
+	assert.Equal(t, int64(2), summary.Keys[auth.Digest("sk-a")].Requests)
+	assert.Equal(t, int64(1), summary.Keys[anonymousClient].Requests)
+}
+
+func TestUsageHandler_RequiresAdminToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, tt := range []struct {
+		name   string
+		token  string
+		header string
+	}{
+		{name: "missing", token: "s3cret"},
+		{name: "wrong", token: "s3cret", header: "Bearer guess"},
+		{name: "client key", token: "s3cret", header: "Bearer sk-a"},
+		{name: "admin API disabled", header: "Bearer "},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			as := &AutoScaler{config: &Config{AdminToken: tt.token}, usage: usage.NewTracker()}
+			router := gin.New()
+			router.GET("/proxy/usage", as.usageHandler)
+
+			r := httptest.NewRequest(http.MethodGet, "/proxy/usage", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Contains(t, w.Body.String(), "invalid_admin_token")
+			assert.NotContains(t, w.Body.String(), "models")
+		})
+	}
+}
+
+func TestUsageKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	assert.Equal(t, anonymousClient, usageKey(r))
+
+	r = r.WithContext(auth.WithTenant(r.Context(), &auth.Tenant{Name: "team-a"}))
+	assert.Equal(t, "team-a", usageKey(r), "validated keys are billed to their tenant")
+}
+
+func TestUsagePersistence(t *testing.T) {
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyDelete)
+	as.config.UsageConfigMap = "vllm-usage"
+	as.usage = usage.NewTracker()
+	ctx := context.Background()
+
+	require.NoError(t, as.restoreUsage(ctx), "a missing ConfigMap starts from zero")
+
+	as.usage.Record(usage.Record{Model: "qwen", Key: "team-a", PromptTokens: 100, CompletionTokens: 20, GPUSeconds: 4})
+	as.persistUsage(ctx)
+
+	as.usage = usage.NewTracker()
+	require.NoError(t, as.restoreUsage(ctx))
+	summary := as.usage.Summary()
+	assert.Equal(t, int64(100), summary.Models["qwen"].PromptTokens)
+	assert.Equal(t, 4.0, summary.Keys["team-a"].GPUSeconds)
+}
@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+)
+
+// vllmAPIKeyTTL is the time an API key read from its Secret is used before being read again, so a
+// rotated key is picked up without a restart
+const vllmAPIKeyTTL = 30 * time.Second
+
+// cachedAPIKey is an API key read from its Secret, empty when the Secret couldn't be read
+type cachedAPIKey struct {
+	key  string
+	read time.Time
+}
+
+// vllmAPIKeySecret returns the Secret holding the API key the model's pods require
+func (as *AutoScaler) vllmAPIKeySecret(ctx context.Context, model string) string {
+	if model != "" {
+		if secret := as.modelOptionsFor(ctx, model).apiKeySecret; secret != "" {
+			return secret
+		}
+	}
+	if as.config.VLLMAPIKeySecret != "" {
+		return as.config.VLLMAPIKeySecret
+	}
+	return kubernetes.VLLMAPIKeySecret
+}
+
+// vllmAPIKey returns the API key the model's pods require, empty when it can't be read. Keys are
+// read again from their Secret once vllmAPIKeyTTL has passed.
+func (as *AutoScaler) vllmAPIKey(ctx context.Context, model string) string {
+	if as.k8sManager == nil {
+		return "" // The local container runs without an API key
+	}
+	secret := as.vllmAPIKeySecret(ctx, model)
+	return as.cachedVLLMAPIKey(ctx, as.k8sManager, secret, secret)
+}
+
+// namespaceAPIKey returns the API key the model's pods of a tenant namespace require, read from
+// the Secret of that namespace
+func (as *AutoScaler) namespaceAPIKey(ctx context.Context, secrets secretReader, namespace, model string) string {
+	secret := as.vllmAPIKeySecret(ctx, model)
+	return as.cachedVLLMAPIKey(ctx, secrets, namespace+"/"+secret, secret)
+}
+
+// secretReader reads the data of a Secret, like K8sManager in its namespace
+type secretReader interface {
+	GetSecretData(ctx context.Context, name string) (map[string][]byte, error)
+}
+
+// cachedVLLMAPIKey returns the API key of the Secret, cached under cacheKey for vllmAPIKeyTTL
+func (as *AutoScaler) cachedVLLMAPIKey(ctx context.Context, secrets secretReader, cacheKey, secret string) string {
+	if cached, ok := as.vllmAPIKeys.Load(cacheKey); ok && time.Since(cached.(cachedAPIKey).read) < vllmAPIKeyTTL {
+		return cached.(cachedAPIKey).key
+	}
+
+	var key string
+	data, err := secrets.GetSecretData(ctx, secret)
+	if err != nil {
+		log.Printf("Failed to read the vLLM API key from Secret %s, calling vLLM without it: %v", secret, err)
+	} else {
+		key = string(data[kubernetes.VLLMAPIKeySecretKey])
+	}
+	as.vllmAPIKeys.Store(cacheKey, cachedAPIKey{key: key, read: time.Now()})
+	return key
+}
+
+// authorizeUpstream makes the proxy send vLLM its API key in place of the client's credentials, so
+// the keys clients use are independent of vLLM's. Without a key, the client's are forwarded.
+func authorizeUpstream(proxy *httputil.ReverseProxy, apiKey string) {
+	if apiKey == "" {
+		return
+	}
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Header.Set("Authorization", "Bearer "+apiKey)
+		r.Header.Del("X-Api-Key")
+	}
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// createAPIKeySecret creates a Secret holding a vLLM API key
+func createAPIKeySecret(t *testing.T, clientset *fake.Clientset, name, key string) {
+	t.Helper()
+	_, err := clientset.CoreV1().Secrets("vllm").Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "vllm"},
+		Data:       map[string][]byte{kubernetes.VLLMAPIKeySecretKey: []byte(key)},
+	}, metav1.CreateOptions{})
+	require.NoError(t, err)
+}
+
+func TestVLLMAPIKey(t *testing.T) {
+	as, clientset := newStrategyTestAutoScaler(t, ScaleStrategyDelete)
+	spec := replicaModelSpec(0, 1)
+	spec["apiKeySecret"] = "qwen-api-key"
+	as.crdClient = newFakeCRDClient(t, spec)
+	ctx := context.Background()
+
+	assert.Empty(t, as.vllmAPIKey(ctx, "qwen"), "a missing Secret forwards the client's key")
+
+	createAPIKeySecret(t, clientset, kubernetes.VLLMAPIKeySecret, "sk-default")
+	createAPIKeySecret(t, clientset, "qwen-api-key", "sk-qwen")
+	assert.Equal(t, "sk-default", as.vllmAPIKey(ctx, "devstral"), "models without a Secret use the proxy's")
+	assert.Empty(t, as.vllmAPIKey(ctx, "qwen"), "keys are cached")
+
+	as.vllmAPIKeys.Delete("qwen-api-key")
+	assert.Equal(t, "sk-qwen", as.vllmAPIKey(ctx, "qwen"))
+
+	// Local backends run vLLM without an API key
+	as.k8sManager = nil
+	assert.Empty(t, as.vllmAPIKey(ctx, "qwen"))
+}
+
+func TestProxyHandler_SendsVLLMAPIKey(t *testing.T) {
+	var authorization, apiKey string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, apiKey = r.Header.Get("Authorization"), r.Header.Get("X-Api-Key")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer upstream.Close()
+
+	for _, raw := range []bool{false, true} {
+		as := newReadyAutoScaler(t, upstream.URL, raw)
+		as.vllmAPIKeys.Store(kubernetes.VLLMAPIKeySecret, cachedAPIKey{key: "sk-vllm", read: time.Now()})
+
+		r := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"qwen","messages":[]}`))
+		r.Header.Set("Authorization", "Bearer sk-client")
+		r.Header.Set("X-Api-Key", "sk-client")
+		w := httptest.NewRecorder()
+		as.proxyHandler(w, r)
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "Bearer sk-vllm", authorization, "vLLM gets its own key, raw=%v", raw)
+		assert.Empty(t, apiKey, "the client's key is not forwarded, raw=%v", raw)
+		assert.Equal(t, "Bearer sk-client", r.Header.Get("Authorization"), "the client's request is left as is")
+	}
+}
+
+func TestAutoScalerBenchmark_SendsModelAPIKey(t *testing.T) {
+	var authorization string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"choices":[{"delta":{"content":"ok"}}]}` + "\n\ndata: [DONE]\n\n"))
+	}))
+	defer upstream.Close()
+
+	as := newReadyAutoScaler(t, upstream.URL, false)
+	spec := replicaModelSpec(0, 1)
+	spec["apiKeySecret"] = "qwen-api-key"
+	as.crdClient = newFakeCRDClient(t, spec)
+	as.vllmAPIKeys.Store(kubernetes.VLLMAPIKeySecret, cachedAPIKey{key: "sk-default", read: time.Now()})
+	as.vllmAPIKeys.Store("qwen-api-key", cachedAPIKey{key: "sk-qwen", read: time.Now()})
+
+	_, err := as.Benchmark(context.Background(), "qwen")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer sk-qwen", authorization, "the benchmark uses the model's key")
+}
@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+)
+
+// vllmMetricsTimeout bounds a scrape of vLLM's /metrics, so a stuck vLLM doesn't hold the
+// Prometheus scrape of the proxy
+const vllmMetricsTimeout = 5 * time.Second
+
+// upstreamMetricsPrefix scopes the vLLM metrics bundled in /proxy/metrics, so vLLM's process and
+// python metrics don't collide with the proxy's own
+const upstreamMetricsPrefix = "upstream_"
+
+// upstreamModelLabel names the model vLLM served when its metrics were scraped
+const upstreamModelLabel = "model"
+
+// relabelVLLMMetrics parses vLLM metrics in the Prometheus text format and writes them back with
+// names prefixed by upstream_ and a model label, kept when vLLM already sets one
+func relabelVLLMMetrics(text, model string) (string, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(text))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse vLLM metrics: %w", err)
+	}
+
+	var out strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(families)) {
+		family := families[name]
+		family.Name = proto.String(upstreamMetricsPrefix + name)
+		for _, metric := range family.Metric {
+			labeled := slices.ContainsFunc(metric.Label, func(label *dto.LabelPair) bool {
+				return label.GetName() == upstreamModelLabel
+			})
+			if !labeled && model != "" {
+				metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(upstreamModelLabel), Value: proto.String(model)})
+			}
+		}
+		if _, err := expfmt.MetricFamilyToText(&out, family); err != nil {
+			return "", fmt.Errorf("failed to write vLLM metric %s: %w", name, err)
+		}
+	}
+	return out.String(), nil
+}
+
+// vllmMetricsCache holds the last vLLM metrics scraped successfully, served while vLLM is down
+type vllmMetricsCache struct {
+	mu   sync.Mutex
+	body string
+	at   time.Time // Zero until a scrape succeeds
+}
+
+// fetchVLLMMetrics scrapes vLLM's /metrics through the transport (nil = http.DefaultTransport)
+func fetchVLLMMetrics(ctx context.Context, transport http.RoundTripper, target string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, vllmMetricsTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target+"/metrics", nil)
+	if err != nil {
+		return "", fmt.Errorf("vLLM metrics unavailable: %w", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vLLM metrics unavailable: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vLLM metrics returned status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vLLM metrics: %w", err)
+	}
+	return string(body), nil
+}
+
+// vllmMetricsText returns the vLLM metrics for /proxy/metrics and whether they are stale. vLLM is
+// only scraped while its pod is ready, so a scrape doesn't wait on a model scaled to zero; the last
+// metrics scraped are served otherwise, after a comment line saying how old they are.
+func (as *AutoScaler) vllmMetricsText(ctx context.Context) (string, bool) {
+	var reason error
+	if as.runner != nil && !as.isPodReady(ctx) {
+		reason = errors.New("vLLM metrics unavailable: vLLM is not running")
+	} else {
+		body, err := fetchVLLMMetrics(ctx, as.vllmTransport, as.targetURL.String())
+		if err == nil && as.config != nil && as.config.RelabelVLLMMetrics {
+			body, err = relabelVLLMMetrics(body, as.GetActiveModel())
+		}
+		if err == nil {
+			as.vllmMetrics.mu.Lock()
+			as.vllmMetrics.body, as.vllmMetrics.at = body, time.Now()
+			as.vllmMetrics.mu.Unlock()
+			return body, false
+		}
+		log.Printf("Warning: Failed to fetch vLLM metrics: %v", err)
+		reason = err
+	}
+
+	as.vllmMetrics.mu.Lock()
+	defer as.vllmMetrics.mu.Unlock()
+	if as.vllmMetrics.at.IsZero() {
+		return fmt.Sprintf("# %v\n", reason), true
+	}
+	age := time.Since(as.vllmMetrics.at).Round(time.Second)
+	return fmt.Sprintf("# %v\n# vLLM metrics stale: last scraped %s ago at %s\n%s",
+		reason, age, as.vllmMetrics.at.UTC().Format(time.RFC3339), as.vllmMetrics.body), true
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/efortin/vllm-chill/pkg/kubernetes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVLLMMetricsText_SkipsStoppedVLLM(t *testing.T) {
+	var scrapes atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		scrapes.Add(1)
+		_, _ = w.Write([]byte("vllm_num_requests_running 2\n"))
+	}))
+	defer upstream.Close()
+	targetURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	runner := &fakeRunner{}
+	as := &AutoScaler{runner: runner, targetURL: targetURL}
+
+	// Nothing scraped yet while scaled to zero
+	text, stale := as.vllmMetricsText(ctx)
+	assert.True(t, stale)
+	assert.Equal(t, "# vLLM metrics unavailable: vLLM is not running\n", text)
+	assert.Zero(t, scrapes.Load())
+
+	require.NoError(t, runner.CreatePod(ctx, &kubernetes.ModelConfig{ServedModelName: "qwen"}))
+	text, stale = as.vllmMetricsText(ctx)
+	assert.False(t, stale)
+	assert.Equal(t, "vllm_num_requests_running 2\n", text)
+	assert.Equal(t, int32(1), scrapes.Load())
+
+	// The last metrics are served with a staleness marker once scaled down
+	require.NoError(t, runner.DeletePod(ctx))
+	text, stale = as.vllmMetricsText(ctx)
+	assert.True(t, stale)
+	assert.Contains(t, text, "# vLLM metrics stale: last scraped 0s ago")
+	assert.Contains(t, text, "vllm_num_requests_running 2\n")
+	assert.Equal(t, int32(1), scrapes.Load())
+
+	// Relabeled metrics name the model running when they were scraped
+	as.config = &Config{RelabelVLLMMetrics: true}
+	as.activeModel = "qwen"
+	require.NoError(t, runner.CreatePod(ctx, &kubernetes.ModelConfig{ServedModelName: "qwen"}))
+	text, _ = as.vllmMetricsText(ctx)
+	assert.Equal(t, "# TYPE upstream_vllm_num_requests_running untyped\nupstream_vllm_num_requests_running{model=\"qwen\"} 2\n", text)
+}
+
+func TestRelabelVLLMMetrics(t *testing.T) {
+	upstream := `# HELP vllm:num_requests_running Number of requests running.
+# TYPE vllm:num_requests_running gauge
+vllm:num_requests_running{model_name="qwen"} 2
+# HELP process_cpu_seconds_total Total user and system CPU time spent in seconds.
+# TYPE process_cpu_seconds_total counter
+process_cpu_seconds_total 12.5
+# TYPE vllm:lora_requests_info gauge
+vllm:lora_requests_info{model="base"} 1
+`
+	text, err := relabelVLLMMetrics(upstream, "qwen")
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP upstream_process_cpu_seconds_total Total user and system CPU time spent in seconds.
+# TYPE upstream_process_cpu_seconds_total counter
+upstream_process_cpu_seconds_total{model="qwen"} 12.5
+# TYPE upstream_vllm:lora_requests_info gauge
+upstream_vllm:lora_requests_info{model="base"} 1
+# HELP upstream_vllm:num_requests_running Number of requests running.
+# TYPE upstream_vllm:num_requests_running gauge
+upstream_vllm:num_requests_running{model_name="qwen",model="qwen"} 2
+`, text)
+
+	_, err = relabelVLLMMetrics("vllm:num_requests_running two\n", "qwen")
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const vllmSleepRequestTimeout = 2 * time.Minute
+
+// vllmSleepClient calls the vLLM sleep mode endpoints (requires --enable-sleep-mode and VLLM_SERVER_DEV_MODE=1)
+type vllmSleepClient struct {
+	baseURL *url.URL
+	client  *http.Client
+}
+
+// newVLLMSleepClient creates a client for the vLLM server at baseURL, reached through the
+// transport (nil = http.DefaultTransport)
+func newVLLMSleepClient(baseURL *url.URL, transport http.RoundTripper) *vllmSleepClient {
+	return &vllmSleepClient{
+		baseURL: baseURL,
+		client:  &http.Client{Timeout: vllmSleepRequestTimeout, Transport: transport},
+	}
+}
+
+// sleep offloads (level 1) or discards (level 2) the model weights and frees the KV cache
+func (c *vllmSleepClient) sleep(ctx context.Context, level int) error {
+	return c.post(ctx, "/sleep", url.Values{"level": {strconv.Itoa(level)}})
+}
+
+// wakeUp reloads the model weights and KV cache
+func (c *vllmSleepClient) wakeUp(ctx context.Context) error {
+	return c.post(ctx, "/wake_up", nil)
+}
+
+// post sends an empty POST request to the given vLLM endpoint
+func (c *vllmSleepClient) post(ctx context.Context, path string, query url.Values) error {
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vLLM %s request failed: %w", path, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("vLLM %s returned %d: %s", path, resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// vllmSleepStrategy puts vLLM to sleep when idle, keeping the process and pod alive for a fast wake up.
+// The pod is deleted once it has been idle for the longer deep idle timeout.
+type vllmSleepStrategy struct {
+	as              *AutoScaler
+	client          *vllmSleepClient
+	level           int
+	deepIdleTimeout time.Duration
+	mu              sync.Mutex
+	asleep          bool
+}
+
+func (s *vllmSleepStrategy) name() string {
+	return ScaleStrategyVLLMSleep
+}
+
+func (s *vllmSleepStrategy) isUp(ctx context.Context) (bool, error) {
+	exists, err := s.as.podExists(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !exists {
+		// A deleted pod comes back awake
+		s.asleep = false
+		return false, nil
+	}
+	return !s.asleep, nil
+}
+
+func (s *vllmSleepStrategy) scaleUp(ctx context.Context) error {
+	s.mu.Lock()
+	asleep := s.asleep
+	s.mu.Unlock()
+
+	if !asleep {
+		return s.as.managePod(ctx, true)
+	}
+
+	log.Printf("Waking up vLLM...")
+	err := s.as.runInPlaceScaleOp(true, func() error {
+		return s.client.wakeUp(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.asleep = false
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *vllmSleepStrategy) scaleDown(ctx context.Context) error {
+	log.Printf("Putting vLLM to sleep (level %d)...", s.level)
+	err := s.as.runInPlaceScaleOp(false, func() error {
+		return s.client.sleep(ctx, s.level)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.asleep = true
+	s.mu.Unlock()
+	return nil
+}
+
+// checkDeepIdle deletes the sleeping pod once the deep idle timeout is exceeded, releasing the GPU
+func (s *vllmSleepStrategy) checkDeepIdle(ctx context.Context, idleTime time.Duration) {
+	if s.deepIdleTimeout <= 0 || idleTime <= s.deepIdleTimeout {
+		return
+	}
+
+	s.mu.Lock()
+	asleep := s.asleep
+	s.mu.Unlock()
+	if !asleep {
+		return
+	}
+
+	log.Printf("Asleep and idle for %v, deleting pod...", idleTime.Round(time.Second))
+	if err := s.as.managePod(ctx, false); err != nil {
+		log.Printf("Failed to delete sleeping pod: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.asleep = false
+	s.mu.Unlock()
+}
@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVLLMSleepServer records calls to the sleep endpoints
+type fakeVLLMSleepServer struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (f *fakeVLLMSleepServer) start(t *testing.T) *url.URL {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.calls = append(f.calls, r.Method+" "+r.URL.RequestURI())
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return u
+}
+
+func (f *fakeVLLMSleepServer) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func newSleepTestStrategy(t *testing.T, deepIdleTimeout time.Duration) (*vllmSleepStrategy, *fakeVLLMSleepServer) {
+	t.Helper()
+	as, _ := newStrategyTestAutoScaler(t, ScaleStrategyDelete, vllmPod())
+	server := &fakeVLLMSleepServer{}
+	strategy := &vllmSleepStrategy{
+		as:              as,
+		client:          newVLLMSleepClient(server.start(t), nil),
+		level:           1,
+		deepIdleTimeout: deepIdleTimeout,
+	}
+	as.strategy = strategy
+	return strategy, server
+}
+
+func TestVLLMSleepStrategy_SleepAndWake(t *testing.T) {
+	strategy, server := newSleepTestStrategy(t, time.Hour)
+	ctx := context.Background()
+
+	require.NoError(t, strategy.scaleDown(ctx))
+	up, err := strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.False(t, up, "sleeping vLLM needs a wake up")
+
+	require.NoError(t, strategy.scaleUp(ctx))
+	up, err = strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.True(t, up)
+
+	assert.Equal(t, []string{"POST /sleep?level=1", "POST /wake_up"}, server.recorded())
+}
+
+func TestVLLMSleepStrategy_DeepIdleDeletesPod(t *testing.T) {
+	strategy, _ := newSleepTestStrategy(t, time.Hour)
+	ctx := context.Background()
+	manager := strategy.as.k8sManager
+
+	require.NoError(t, strategy.scaleDown(ctx))
+
+	// Below the deep idle timeout the pod is kept asleep
+	strategy.checkDeepIdle(ctx, 30*time.Minute)
+	exists, err := manager.PodExists(ctx)
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	strategy.checkDeepIdle(ctx, 2*time.Hour)
+	exists, err = manager.PodExists(ctx)
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.False(t, strategy.asleep, "a recreated pod starts awake")
+}
+
+func TestVLLMSleepStrategy_DeletedPodResetsSleep(t *testing.T) {
+	strategy, _ := newSleepTestStrategy(t, 0)
+	ctx := context.Background()
+
+	require.NoError(t, strategy.scaleDown(ctx))
+	require.NoError(t, strategy.as.k8sManager.DeletePod(ctx))
+
+	up, err := strategy.isUp(ctx)
+	require.NoError(t, err)
+	assert.False(t, up)
+	assert.False(t, strategy.asleep)
+
+	// Deep idle is disabled without a timeout
+	strategy.asleep = true
+	strategy.checkDeepIdle(ctx, 24*time.Hour)
+	assert.True(t, strategy.asleep)
+}
+
+func TestVLLMSleepClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "sleep mode disabled", http.StatusNotFound)
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	err = newVLLMSleepClient(u, nil).sleep(context.Background(), 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sleep mode disabled")
+}
+
+func TestNewScaleStrategy_VLLMSleep(t *testing.T) {
+	as := &AutoScaler{
+		config:    &Config{SleepLevel: 2, DeepIdleTimeout: "2h"},
+		targetURL: &url.URL{Scheme: "http", Host: "vllm-api:80"},
+	}
+
+	strategy, err := newScaleStrategy(as, ScaleStrategyVLLMSleep)
+	require.NoError(t, err)
+	sleep, ok := strategy.(*vllmSleepStrategy)
+	require.True(t, ok)
+	assert.Equal(t, 2, sleep.level)
+	assert.Equal(t, 2*time.Hour, sleep.deepIdleTimeout)
+	_, ok = strategy.(deepIdler)
+	assert.True(t, ok)
+}
@@ -19,3 +19,9 @@ type ToolCallFunction = parser.ToolCallFunction
 func parseXMLToolCalls(content string) []ToolCall {
 	return parser.ParseXMLToolCalls(content)
 }
+
+// parseJSONToolCalls parses JSON tool calls from content
+// This is a wrapper function that delegates to the parser package
+func parseJSONToolCalls(content string) []ToolCall {
+	return parser.ParseJSONToolCalls(content)
+}
@@ -51,14 +51,9 @@ func GetRequiredPermissions(namespace string) []RequiredPermission {
 	}
 }
 
-// VerifyPermissions checks if the current service account has all required permissions
-func VerifyPermissions(ctx context.Context, namespace string) error {
-	// Create in-cluster config
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		return fmt.Errorf("failed to get in-cluster config: %w", err)
-	}
-
+// VerifyPermissions checks if the current service account, or the kubeconfig user outside a
+// cluster, has all required permissions
+func VerifyPermissions(ctx context.Context, config *rest.Config, namespace string) error {
 	// Create clientset
 	clientset, err := kubernetes.NewForConfig(config)
 	if err != nil {
@@ -0,0 +1,181 @@
+// Package schedule parses time windows opened by cron expressions, e.g. business hours during
+// which the model is kept warm regardless of traffic.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embedded so time zones resolve in images without tzdata
+	_ "time/tzdata"
+)
+
+const (
+	// windowSeparator separates the windows of a schedule, commas being part of the cron syntax
+	windowSeparator = ";"
+
+	// maxWindowDuration bounds a window, which is looked up minute by minute
+	maxWindowDuration = 7 * 24 * time.Hour
+)
+
+// field is the range of values of a cron field
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are Sunday
+}
+
+// Expression is a standard five-field cron expression: minute, hour, day of month, month and day
+// of week. Fields accept *, values, ranges (1-5), lists (1,3) and steps (*/15, 8-18/2).
+type Expression struct {
+	minutes, hours, days, months, weekdays uint64 // Bit i set when value i matches
+	anyDay, anyWeekday                     bool
+}
+
+// ParseExpression parses a five-field cron expression
+func ParseExpression(spec string) (*Expression, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", spec, len(fields))
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", spec, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1 // 7 is Sunday as well
+	}
+	return &Expression{
+		minutes:    sets[0],
+		hours:      sets[1],
+		days:       sets[2],
+		months:     sets[3],
+		weekdays:   sets[4],
+		anyDay:     strings.HasPrefix(parts[2], "*"),
+		anyWeekday: strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseField parses a comma-separated list of values, ranges and steps into a bit set
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(spec, ",") {
+		rangeSpec, step := item, 1
+		if idx := strings.Index(item, "/"); idx >= 0 {
+			n, err := strconv.Atoi(item[idx+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s %q", f.name, item)
+			}
+			rangeSpec, step = item[:idx], n
+		}
+
+		start, end := f.min, f.max
+		if rangeSpec != "*" {
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if start, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, item)
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, item)
+				}
+			} else if step > 1 {
+				end = f.max // 5/15 means from 5 to the end, every 15
+			}
+		}
+		if start < f.min || end > f.max || start > end {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := start; v <= end; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// Matches reports whether the expression fires at the minute of t. As in cron, when both the day
+// of month and the day of week are restricted, either one matching is enough.
+func (e *Expression) Matches(t time.Time) bool {
+	if e.minutes&(1<<uint(t.Minute())) == 0 || e.hours&(1<<uint(t.Hour())) == 0 || e.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	day := e.days&(1<<uint(t.Day())) != 0
+	weekday := e.weekdays&(1<<uint(t.Weekday())) != 0
+	switch {
+	case e.anyDay && e.anyWeekday:
+		return true
+	case e.anyDay:
+		return weekday
+	case e.anyWeekday:
+		return day
+	default:
+		return day || weekday
+	}
+}
+
+// Window is a period opened each time its cron expression fires, for a fixed duration
+type Window struct {
+	Spec     string
+	Start    *Expression
+	Duration time.Duration
+}
+
+// Schedule is a set of windows, empty when nothing is scheduled
+type Schedule []Window
+
+// Parse parses a schedule of the form "<cron expression> <duration>; ...", e.g.
+// "0 8 * * 1-5 10h" opens a 10 hour window at 08:00 on weekdays
+func Parse(spec string) (Schedule, error) {
+	var schedule Schedule
+	for _, entry := range strings.Split(spec, windowSeparator) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Fields(entry)
+		if len(parts) != len(fields)+1 {
+			return nil, fmt.Errorf("invalid window %q, expected a cron expression followed by a duration", entry)
+		}
+		duration, err := time.ParseDuration(parts[len(fields)])
+		if err != nil || duration < time.Minute || duration > maxWindowDuration {
+			return nil, fmt.Errorf("invalid window duration %q, must be between 1m and %s", parts[len(fields)], maxWindowDuration)
+		}
+		start, err := ParseExpression(strings.Join(parts[:len(fields)], " "))
+		if err != nil {
+			return nil, err
+		}
+		schedule = append(schedule, Window{Spec: entry, Start: start, Duration: duration})
+	}
+	return schedule, nil
+}
+
+// Active returns the window open at t, looking back minute by minute for the last time each
+// window opened
+func (s Schedule) Active(t time.Time) (Window, bool) {
+	minute := t.Truncate(time.Minute)
+	for _, window := range s {
+		for start := minute; t.Sub(start) < window.Duration; start = start.Add(-time.Minute) {
+			if window.Start.Matches(start) {
+				return window, true
+			}
+		}
+	}
+	return Window{}, false
+}
@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monday is Monday 2025-01-06 at the given time, UTC
+func monday(hour, minute int) time.Time {
+	return time.Date(2025, time.January, 6, hour, minute, 0, 0, time.UTC)
+}
+
+func TestExpressionMatches(t *testing.T) {
+	tests := []struct {
+		spec string
+		at   time.Time
+		want bool
+	}{
+		{spec: "* * * * *", at: monday(3, 17), want: true},
+		{spec: "0 8 * * 1-5", at: monday(8, 0), want: true},
+		{spec: "0 8 * * 1-5", at: monday(8, 1), want: false},
+		{spec: "0 8 * * 1-5", at: monday(8, 0).AddDate(0, 0, 5), want: false}, // Saturday
+		{spec: "*/15 * * * *", at: monday(9, 45), want: true},
+		{spec: "*/15 * * * *", at: monday(9, 50), want: false},
+		{spec: "5/20 * * * *", at: monday(9, 45), want: true},
+		{spec: "0 8-18/2 * * *", at: monday(10, 0), want: true},
+		{spec: "0 8-18/2 * * *", at: monday(11, 0), want: false},
+		{spec: "30 9 1,15 * *", at: time.Date(2025, time.March, 15, 9, 30, 0, 0, time.UTC), want: true},
+		{spec: "0 0 * * 7", at: monday(0, 0).AddDate(0, 0, 6), want: true}, // 7 is Sunday
+		{spec: "0 0 * * 0", at: monday(0, 0).AddDate(0, 0, 6), want: true},
+		// Day of month or day of week when both are restricted
+		{spec: "0 0 13 * 1", at: monday(0, 0), want: true},
+		{spec: "0 0 6 * 5", at: monday(0, 0), want: true},
+		{spec: "0 0 7 * 5", at: monday(0, 0), want: false},
+		{spec: "0 0 * 2 *", at: monday(0, 0), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			expr, err := ParseExpression(tt.spec)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, expr.Matches(tt.at), "at %s", tt.at)
+		})
+	}
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+	} {
+		_, err := ParseExpression(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestParse(t *testing.T) {
+	schedule, err := Parse(" 0 8 * * 1-5 10h ; 0 20 * * 5 2h30m;")
+	require.NoError(t, err)
+	require.Len(t, schedule, 2)
+	assert.Equal(t, "0 8 * * 1-5 10h", schedule[0].Spec)
+	assert.Equal(t, 10*time.Hour, schedule[0].Duration)
+	assert.Equal(t, 150*time.Minute, schedule[1].Duration)
+
+	empty, err := Parse("")
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	for _, spec := range []string{
+		"0 8 * * 1-5",
+		"0 8 * * 1-5 soon",
+		"0 8 * * 1-5 30s",
+		"0 8 * * 1-5 200h",
+		"0 25 * * 1-5 1h",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestScheduleActive(t *testing.T) {
+	schedule, err := Parse("0 8 * * 1-5 10h; 0 22 * * * 4h")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{name: "before opening", at: monday(7, 59)},
+		{name: "at opening", at: monday(8, 0), want: "0 8 * * 1-5 10h"},
+		{name: "during", at: monday(17, 59).Add(59 * time.Second), want: "0 8 * * 1-5 10h"},
+		{name: "closed", at: monday(18, 0)},
+		{name: "across midnight", at: monday(1, 30), want: "0 22 * * * 4h"},
+		{name: "weekend", at: monday(9, 0).AddDate(0, 0, 5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, active := schedule.Active(tt.at)
+			assert.Equal(t, tt.want != "", active)
+			assert.Equal(t, tt.want, window.Spec)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package stats
+
+// GPUActivity is the load of a single GPU, sampled for scale-down decisions
+type GPUActivity struct {
+	Index       int
+	Utilization float64 // Percent of time a kernel ran over the last sample period
+	MemoryUsed  int64   // MiB
+	MemoryTotal int64   // MiB
+}
+
+// MemoryUtil returns the percent of the GPU memory in use
+func (a GPUActivity) MemoryUtil() float64 {
+	if a.MemoryTotal == 0 {
+		return 0
+	}
+	return float64(a.MemoryUsed) / float64(a.MemoryTotal) * 100
+}
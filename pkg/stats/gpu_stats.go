@@ -164,6 +164,24 @@ func (h *GPUStatsHandler) queryGPUStats() (*GPUStats, error) {
 	return stats, nil
 }
 
+// Activity samples the utilization and memory of each GPU, bypassing the cache
+func (h *GPUStatsHandler) Activity() ([]GPUActivity, error) {
+	stats, err := h.queryGPUStats()
+	if err != nil {
+		return nil, err
+	}
+	activity := make([]GPUActivity, 0, len(stats.GPUs))
+	for _, gpu := range stats.GPUs {
+		activity = append(activity, GPUActivity{
+			Index:       gpu.Index,
+			Utilization: gpu.Utilization,
+			MemoryUsed:  gpu.MemoryUsed,
+			MemoryTotal: gpu.MemoryTotal,
+		})
+	}
+	return activity, nil
+}
+
 // queryDeviceStats queries statistics for a single GPU device
 func (h *GPUStatsHandler) queryDeviceStats(index int, device nvml.Device) (*GPU, error) {
 	gpu := &GPU{Index: index}
@@ -3,6 +3,7 @@
 package stats
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +24,11 @@ func (h *GPUStatsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"error":"GPU stats not available (compiled without CGO/NVML support)"}`))
 }
 
+// Activity always fails, no GPU can be queried without NVML
+func (h *GPUStatsHandler) Activity() ([]GPUActivity, error) {
+	return nil, errors.New("GPU stats not available (compiled without CGO/NVML support)")
+}
+
 // Shutdown is a no-op for the stub version
 func (h *GPUStatsHandler) Shutdown() error {
 	return nil
@@ -47,6 +47,23 @@ var (
 		[]string{"method", "path", "status"},
 	)
 
+	// Per-user metrics, users beyond the label limit share the "other" label
+	userRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_user_requests_total",
+			Help: "Total number of requests served per user",
+		},
+		[]string{"user"},
+	)
+
+	userTokens = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_user_tokens_total",
+			Help: "Total number of tokens used per user, by type (prompt or completion)",
+		},
+		[]string{"user", "type"},
+	)
+
 	// Managed operations metrics
 	managedOperations = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -65,6 +82,47 @@ var (
 		[]string{"from_model", "to_model"},
 	)
 
+	modelSwitches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_model_switches_total",
+			Help: "Total number of model switches",
+		},
+		[]string{"from_model", "to_model", "status"},
+	)
+
+	modelSwitchDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_model_switch_duration_seconds",
+			Help:    "Model switch duration in seconds",
+			Buckets: []float64{10, 30, 60, 120, 300, 600},
+		},
+		[]string{"from_model", "to_model"},
+	)
+
+	// Model switches waiting for the in-flight requests of the current model
+	modelSwitchState = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_model_switch_state",
+			Help: "Current model switch state: 0=idle, 1=draining (in-flight requests of the current model completing), 2=switching",
+		},
+	)
+
+	modelSwitchDrainDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_model_switch_drain_seconds",
+			Help:    "Time a model switch waited for the in-flight requests of the current model to complete",
+			Buckets: []float64{0, 1, 5, 10, 30, 60, 120, 300},
+		},
+	)
+
+	modelSwitchRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_model_switch_requests_total",
+			Help: "Requests arriving during a model switch, by result (queued for the new model or rejected)",
+		},
+		[]string{"result"},
+	)
+
 	// Scaling metrics
 	scaleOps = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -115,6 +173,22 @@ var (
 		[]string{"status"},
 	)
 
+	toolCallJSONRepairs = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_tool_call_json_repairs_total",
+			Help: "Total number of tool calls whose malformed JSON arguments were repaired",
+		},
+		[]string{"parser"},
+	)
+
+	toolCallSchemaChecks = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_tool_call_schema_checks_total",
+			Help: "Total number of converted tool calls checked against the request's tool schemas, by result",
+		},
+		[]string{"parser", "result"},
+	)
+
 	xmlToolCallsDetected = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "vllm_chill_xml_tool_calls_detected_total",
@@ -122,6 +196,31 @@ var (
 		},
 	)
 
+	// Tool-call parser mismatch metrics
+	toolParserMismatches = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_tool_parser_mismatches_total",
+			Help: "Total number of responses whose tool call format did not match the configured parser",
+		},
+		[]string{"model", "kind"},
+	)
+
+	toolParserWarning = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_tool_parser_warning",
+			Help: "Tool-call parser misconfiguration suspected (1 if flagged, 0 otherwise)",
+		},
+		[]string{"model", "kind"},
+	)
+
+	configDrift = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_config_drift",
+			Help: "vLLM pod field differing from its VLLMModel config (1 if drifted, 0 otherwise)",
+		},
+		[]string{"field"},
+	)
+
 	// Proxy latency metrics
 	proxyLatency = promauto.NewHistogramVec(
 		prometheus.HistogramOpts{
@@ -132,6 +231,58 @@ var (
 		[]string{"operation"},
 	)
 
+	// Generation metrics of streamed responses
+	timeToFirstToken = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_time_to_first_token_seconds",
+			Help:    "Time from forwarding a streamed request to vLLM to its first generated token",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30, 60},
+		},
+		[]string{"model"},
+	)
+
+	outputTokensPerSecond = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_output_tokens_per_second",
+			Help:    "Tokens generated per second after the first one in streamed responses",
+			Buckets: []float64{1, 5, 10, 20, 30, 50, 75, 100, 150, 200, 300},
+		},
+		[]string{"model"},
+	)
+
+	// Response cache of deterministic requests
+	responseCacheRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_response_cache_requests_total",
+			Help: "Cacheable requests answered from the response cache (hit) or by vLLM (miss)",
+		},
+		[]string{"result"},
+	)
+
+	responseCacheBytes = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_response_cache_bytes",
+			Help: "Size of the responses held in the response cache",
+		},
+	)
+
+	// Shadow traffic mirrored to a second model
+	shadowRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_shadow_requests_total",
+			Help: "Requests mirrored to the shadow model, by result (ok, error or dropped)",
+		},
+		[]string{"result"},
+	)
+
+	shadowRequestDuration = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_shadow_request_duration_seconds",
+			Help:    "Time the shadow model took to answer the mirrored requests, its cold starts excluded",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
 	// vLLM lifecycle metrics
 	vllmStartupDuration = promauto.NewHistogram(
 		prometheus.HistogramOpts{
@@ -149,10 +300,94 @@ var (
 		},
 	)
 
+	coldStartQueueWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_cold_start_queue_wait_seconds",
+			Help:    "Time a model waited for another model's cold start to finish before starting",
+			Buckets: []float64{0, 1, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"model"},
+	)
+
+	coldStartQueueLength = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_cold_start_queue_length",
+			Help: "Number of cold starts waiting for a free slot",
+		},
+	)
+
+	coldStarts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_cold_starts_total",
+			Help: "vLLM pods created to serve a model scaled to zero",
+		},
+		[]string{"model"},
+	)
+
+	coldStartWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "vllm_chill_cold_start_wait_seconds",
+			Help:    "Time a request waited for the model to start before being forwarded, the cold start penalty",
+			Buckets: []float64{1, 5, 10, 30, 60, 90, 120, 180, 300, 600},
+		},
+		[]string{"model"},
+	)
+
+	scaleUpWaitingRequests = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_scale_up_waiting_requests",
+			Help: "Requests waiting for the model to start",
+		},
+	)
+
+	inFlightRequests = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_in_flight_requests",
+			Help: "Requests forwarded to vLLM and not yet answered",
+		},
+	)
+
 	vllmState = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "vllm_chill_vllm_state",
-			Help: "Current vLLM state: 0=stopped, 1=starting, 2=running, 3=stopping",
+			Help: "Current vLLM state: 0=stopped, 1=starting, 2=running, 3=stopping, 4=gpu_driver_not_ready, 5=downloading",
+		},
+	)
+
+	gpuUtilization = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_gpu_utilization_percent",
+			Help: "GPU utilization sampled by the idle checker",
+		},
+		[]string{"gpu"},
+	)
+
+	gpuMemoryUsed = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_gpu_memory_used_bytes",
+			Help: "GPU memory in use sampled by the idle checker",
+		},
+		[]string{"gpu"},
+	)
+
+	gpuState = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_gpu_state",
+			Help: "GPU state seen by the idle checker: 0=idle, 1=warm (model and KV cache loaded, nothing running), 2=busy",
+		},
+	)
+
+	scaleDownsDeferred = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "vllm_chill_scale_down_deferred_total",
+			Help: "Idle scale-downs deferred because the GPU was busy",
+		},
+	)
+
+	vllmMetricsStale = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "vllm_chill_vllm_metrics_stale",
+			Help: "Whether the vLLM metrics of the last /proxy/metrics scrape were not scraped from vLLM (1) or fresh (0)",
 		},
 	)
 )
@@ -201,6 +436,13 @@ func (mr *MetricsRecorder) RecordRequest(method, path string, status int, durati
 	}
 }
 
+// RecordUserUsage records a request served for a user and the tokens it used
+func (mr *MetricsRecorder) RecordUserUsage(user string, promptTokens, completionTokens int) {
+	userRequests.WithLabelValues(user).Inc()
+	userTokens.WithLabelValues(user, "prompt").Add(float64(promptTokens))
+	userTokens.WithLabelValues(user, "completion").Add(float64(completionTokens))
+}
+
 // RecordManagedOperation records a managed operation (model switch)
 func (mr *MetricsRecorder) RecordManagedOperation(fromModel, toModel string, success bool, duration time.Duration) {
 	status := "success"
@@ -209,8 +451,10 @@ func (mr *MetricsRecorder) RecordManagedOperation(fromModel, toModel string, suc
 	}
 
 	managedOperations.WithLabelValues(fromModel, toModel, status).Inc()
+	modelSwitches.WithLabelValues(fromModel, toModel, status).Inc()
 	if success {
 		managedOperationDuration.WithLabelValues(fromModel, toModel).Observe(duration.Seconds())
+		modelSwitchDuration.WithLabelValues(fromModel, toModel).Observe(duration.Seconds())
 	}
 
 	// Update current model
@@ -291,11 +535,104 @@ func (mr *MetricsRecorder) RecordXMLParsing(success bool, toolCallCount int) {
 	}
 }
 
+// RecordJSONRepairs records tool calls whose malformed JSON arguments the parser repaired
+func (mr *MetricsRecorder) RecordJSONRepairs(parser string, count int) {
+	if count > 0 {
+		toolCallJSONRepairs.WithLabelValues(parser).Add(float64(count))
+	}
+}
+
+// RecordToolSchemaChecks records converted tool calls checked against their tool's schema: valid
+// as parsed, valid once coerced, and invalid ones, rejected or passed on
+func (mr *MetricsRecorder) RecordToolSchemaChecks(parser string, valid, coerced, invalid int, rejected bool) {
+	invalidResult := "invalid"
+	if rejected {
+		invalidResult = "rejected"
+	}
+	for result, count := range map[string]int{"valid": valid, "coerced": coerced, invalidResult: invalid} {
+		if count > 0 {
+			toolCallSchemaChecks.WithLabelValues(parser, result).Add(float64(count))
+		}
+	}
+}
+
+// RecordToolParserMismatch records a response whose tool call format did not match the configured parser
+func (mr *MetricsRecorder) RecordToolParserMismatch(model, kind string) {
+	toolParserMismatches.WithLabelValues(model, kind).Inc()
+}
+
+// SetToolParserWarning flags or clears a suspected tool-call parser misconfiguration
+func (mr *MetricsRecorder) SetToolParserWarning(model, kind string, active bool) {
+	value := 0.0
+	if active {
+		value = 1
+	}
+	toolParserWarning.WithLabelValues(model, kind).Set(value)
+}
+
+// SetConfigDrift flags or clears a drift of a vLLM pod field from its model config
+func (mr *MetricsRecorder) SetConfigDrift(field string, drifted bool) {
+	value := 0.0
+	if drifted {
+		value = 1
+	}
+	configDrift.WithLabelValues(field).Set(value)
+}
+
 // RecordProxyLatency records the latency added by the proxy
 func (mr *MetricsRecorder) RecordProxyLatency(operation string, duration time.Duration) {
 	proxyLatency.WithLabelValues(operation).Observe(duration.Seconds())
 }
 
+// RecordTimeToFirstToken records the time a streamed response of the model took to its first token
+func (mr *MetricsRecorder) RecordTimeToFirstToken(model string, duration time.Duration) {
+	timeToFirstToken.WithLabelValues(model).Observe(duration.Seconds())
+}
+
+// RecordOutputTokensPerSecond records the generation throughput of a streamed response of the model
+func (mr *MetricsRecorder) RecordOutputTokensPerSecond(model string, tokensPerSecond float64) {
+	outputTokensPerSecond.WithLabelValues(model).Observe(tokensPerSecond)
+}
+
+// RecordResponseCacheRequest records a cacheable request, answered from the cache or not
+func (mr *MetricsRecorder) RecordResponseCacheRequest(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	responseCacheRequests.WithLabelValues(result).Inc()
+}
+
+// SetResponseCacheBytes sets the size of the responses held in the response cache
+func (mr *MetricsRecorder) SetResponseCacheBytes(size int64) {
+	responseCacheBytes.Set(float64(size))
+}
+
+// RecordShadowRequest records a request mirrored to the shadow model, with the time it took to
+// answer when it was sent
+func (mr *MetricsRecorder) RecordShadowRequest(result string, duration time.Duration) {
+	shadowRequests.WithLabelValues(result).Inc()
+	if duration > 0 {
+		shadowRequestDuration.Observe(duration.Seconds())
+	}
+}
+
+// SetModelSwitchState sets the current model switch state
+// States: 0=idle, 1=draining (waiting for the in-flight requests of the current model), 2=switching
+func (mr *MetricsRecorder) SetModelSwitchState(state int) {
+	modelSwitchState.Set(float64(state))
+}
+
+// RecordModelSwitchDrain records the time a model switch waited for in-flight requests
+func (mr *MetricsRecorder) RecordModelSwitchDrain(duration time.Duration) {
+	modelSwitchDrainDuration.Observe(duration.Seconds())
+}
+
+// RecordModelSwitchRequest records a request arriving during a model switch, queued or rejected
+func (mr *MetricsRecorder) RecordModelSwitchRequest(result string) {
+	modelSwitchRequests.WithLabelValues(result).Inc()
+}
+
 // RecordVLLMStartup records the time taken for vLLM to start
 func (mr *MetricsRecorder) RecordVLLMStartup(duration time.Duration) {
 	vllmStartupDuration.Observe(duration.Seconds())
@@ -306,8 +643,67 @@ func (mr *MetricsRecorder) RecordVLLMShutdown(duration time.Duration) {
 	vllmShutdownDuration.Observe(duration.Seconds())
 }
 
+// RecordColdStartQueueWait records the time a model waited for a cold start slot
+func (mr *MetricsRecorder) RecordColdStartQueueWait(model string, duration time.Duration) {
+	coldStartQueueWait.WithLabelValues(model).Observe(duration.Seconds())
+}
+
+// SetColdStartQueueLength sets the number of cold starts waiting for a slot
+func (mr *MetricsRecorder) SetColdStartQueueLength(length int) {
+	coldStartQueueLength.Set(float64(length))
+}
+
+// RecordColdStart records a vLLM pod created to serve a model scaled to zero
+func (mr *MetricsRecorder) RecordColdStart(model string) {
+	coldStarts.WithLabelValues(model).Inc()
+}
+
+// RecordColdStartWait records the time a request waited for the model to start
+func (mr *MetricsRecorder) RecordColdStartWait(model string, duration time.Duration) {
+	coldStartWait.WithLabelValues(model).Observe(duration.Seconds())
+}
+
+// AddScaleUpWaitingRequests adds delta to the requests waiting for the model to start
+func (mr *MetricsRecorder) AddScaleUpWaitingRequests(delta int) {
+	scaleUpWaitingRequests.Add(float64(delta))
+}
+
+// AddInFlightRequests adds delta to the requests forwarded to vLLM and not yet answered
+func (mr *MetricsRecorder) AddInFlightRequests(delta int) {
+	inFlightRequests.Add(float64(delta))
+}
+
 // SetVLLMState sets the current vLLM state
-// States: 0=stopped, 1=starting, 2=running, 3=stopping
+// States: 0=stopped, 1=starting, 2=running, 3=stopping, 4=gpu_driver_not_ready (waiting for the GPU device plugin),
+// 5=downloading (a Job downloads the model into the cache)
 func (mr *MetricsRecorder) SetVLLMState(state int) {
 	vllmState.Set(float64(state))
 }
+
+// RecordGPUActivity records the utilization and memory use of each GPU
+func (mr *MetricsRecorder) RecordGPUActivity(activity []GPUActivity) {
+	for _, gpu := range activity {
+		index := strconv.Itoa(gpu.Index)
+		gpuUtilization.WithLabelValues(index).Set(gpu.Utilization)
+		gpuMemoryUsed.WithLabelValues(index).Set(float64(gpu.MemoryUsed) * 1024 * 1024)
+	}
+}
+
+// SetGPUState sets the GPU state (0=idle, 1=warm, 2=busy)
+func (mr *MetricsRecorder) SetGPUState(state int) {
+	gpuState.Set(float64(state))
+}
+
+// RecordScaleDownDeferred records an idle scale-down deferred because the GPU was busy
+func (mr *MetricsRecorder) RecordScaleDownDeferred() {
+	scaleDownsDeferred.Inc()
+}
+
+// SetVLLMMetricsStale records whether /proxy/metrics served cached or missing vLLM metrics
+func (mr *MetricsRecorder) SetVLLMMetricsStale(stale bool) {
+	if stale {
+		vllmMetricsStale.Set(1)
+	} else {
+		vllmMetricsStale.Set(0)
+	}
+}
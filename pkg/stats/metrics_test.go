@@ -53,6 +53,47 @@ func TestMetricsRecorder_RecordXMLParsing(t *testing.T) {
 	mr.RecordXMLParsing(false, 0)
 }
 
+func TestMetricsRecorder_RecordJSONRepairs(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording repairs, none is a no-op
+	mr.RecordJSONRepairs("xml", 2)
+	mr.RecordJSONRepairs("json", 0)
+}
+
+func TestMetricsRecorder_RecordToolSchemaChecks(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording checks passing invalid calls on, then rejecting them
+	mr.RecordToolSchemaChecks("xml", 2, 1, 1, false)
+	mr.RecordToolSchemaChecks("json", 0, 0, 1, true)
+}
+
+func TestMetricsRecorder_ToolParserMismatch(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording a mismatch and toggling the warning
+	mr.RecordToolParserMismatch("test-model", "xml_without_native")
+	mr.SetToolParserWarning("test-model", "xml_without_native", true)
+	mr.SetToolParserWarning("test-model", "xml_without_native", false)
+}
+
+func TestMetricsRecorder_SetConfigDrift(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test flagging and clearing a drifted field
+	mr.SetConfigDrift("image", true)
+	mr.SetConfigDrift("image", false)
+}
+
+func TestMetricsRecorder_RecordUserUsage(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording a user's request and tokens
+	mr.RecordUserUsage("alice", 120, 30)
+	mr.RecordUserUsage("other", 0, 0)
+}
+
 func TestMetricsRecorder_RecordProxyLatency(t *testing.T) {
 	mr := NewMetricsRecorder()
 
@@ -74,6 +115,23 @@ func TestMetricsRecorder_RecordVLLMShutdown(t *testing.T) {
 	mr.RecordVLLMShutdown(100 * time.Millisecond)
 }
 
+func TestMetricsRecorder_ColdStartQueue(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording queue wait and length
+	mr.RecordColdStartQueueWait("test-model", 30*time.Second)
+	mr.SetColdStartQueueLength(1)
+	mr.SetColdStartQueueLength(0)
+}
+
+func TestMetricsRecorder_RecordGeneration(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording the time to first token and throughput of a streamed response
+	mr.RecordTimeToFirstToken("test-model", 350*time.Millisecond)
+	mr.RecordOutputTokensPerSecond("test-model", 85.5)
+}
+
 func TestMetricsRecorder_SetVLLMState(t *testing.T) {
 	mr := NewMetricsRecorder()
 
@@ -84,6 +142,33 @@ func TestMetricsRecorder_SetVLLMState(t *testing.T) {
 	mr.SetVLLMState(3) // stopping
 }
 
+func TestMetricsRecorder_RecordGPUActivity(t *testing.T) {
+	mr := NewMetricsRecorder()
+
+	// Test recording the load of two GPUs and a deferred scale-down
+	mr.RecordGPUActivity([]GPUActivity{
+		{Index: 0, Utilization: 97, MemoryUsed: 21504, MemoryTotal: 24576},
+		{Index: 1, Utilization: 0, MemoryUsed: 0, MemoryTotal: 24576},
+	})
+	mr.SetGPUState(2) // busy
+	mr.RecordScaleDownDeferred()
+	assert.InDelta(t, 87.5, GPUActivity{MemoryUsed: 21504, MemoryTotal: 24576}.MemoryUtil(), 0.01)
+	assert.Zero(t, GPUActivity{}.MemoryUtil())
+}
+
+func TestMetricsRecorder_ColdStarts(t *testing.T) {
+	mr := NewMetricsRecorder()
+	defer mr.Stop()
+
+	// Test a cold start with two requests waiting for it
+	mr.RecordColdStart("qwen")
+	mr.AddScaleUpWaitingRequests(2)
+	mr.AddScaleUpWaitingRequests(-2)
+	mr.RecordColdStartWait("qwen", 75*time.Second)
+	mr.AddInFlightRequests(1)
+	mr.AddInFlightRequests(-1)
+}
+
 func TestParseKVCacheInfo(t *testing.T) {
 	// Test with valid data
 	logs := `Available KV cache memory: 16.5 GiB
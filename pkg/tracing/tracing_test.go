@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetup(t *testing.T) {
+	var exported atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && r.URL.Path == "/v1/traces" {
+			exported.Add(1)
+		}
+	}))
+	defer collector.Close()
+
+	shutdown, err := Setup(context.Background(), collector.URL, "v1.2.3")
+	require.NoError(t, err)
+	_, span := Tracer.Start(context.Background(), "ensure_scaled_up")
+	span.End()
+
+	// Shutting down flushes the pending spans
+	require.NoError(t, shutdown(context.Background()))
+	assert.Equal(t, int32(1), exported.Load())
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "create_pod")
+	End(span, errors.New("quota exceeded"))
+	_, span = tracer.Start(context.Background(), "wait_for_ready")
+	End(span, nil)
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 2)
+	assert.Equal(t, codes.Error, ended[0].Status().Code)
+	assert.Equal(t, "quota exceeded", ended[0].Status().Description)
+	assert.Equal(t, codes.Unset, ended[1].Status().Code)
+}
@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+const (
+	// BytesPerToken is the token estimate used when a response reports no usage
+	BytesPerToken = 4
+
+	// tailSize is the bytes kept between writes, so a usage field split across writes is found
+	tailSize = 32
+)
+
+// usagePattern matches the token counts of OpenAI (prompt_tokens, completion_tokens, total_tokens) and
+// Anthropic (input_tokens, output_tokens) usage
+var usagePattern = regexp.MustCompile(`"(prompt_tokens|completion_tokens|total_tokens|input_tokens|output_tokens)":\s*(\d+)`)
+
+// usageMarker is part of every usage field, writes without it skip the pattern
+var usageMarker = []byte(`_tokens"`)
+
+// Usage fields, indexes of TokenCounter.counts
+const (
+	promptTokens = iota
+	completionTokens
+	totalTokens
+	inputTokens
+	outputTokens
+	usageFields
+)
+
+// counterPool recycles counters, one is used per proxied response
+var counterPool = sync.Pool{
+	New: func() interface{} {
+		return &TokenCounter{buf: make([]byte, 0, 4096)}
+	},
+}
+
+// TokenCounter reads the token usage reported in OpenAI and Anthropic responses, streamed or not,
+// as they are written. It is not safe for concurrent use.
+type TokenCounter struct {
+	counts [usageFields]int
+	tail   []byte
+	buf    []byte // Tail and current write, reused across writes
+}
+
+// NewTokenCounter returns an empty counter from the pool, Release returns it
+func NewTokenCounter() *TokenCounter {
+	return counterPool.Get().(*TokenCounter)
+}
+
+// Release resets the counter and returns it to the pool, it must not be used afterwards
+func (c *TokenCounter) Release() {
+	c.counts = [usageFields]int{}
+	c.tail = c.tail[:0]
+	c.buf = c.buf[:0]
+	counterPool.Put(c)
+}
+
+// Scan records the last value of each usage field written, including fields split across writes
+func (c *TokenCounter) Scan(b []byte) {
+	c.buf = append(append(c.buf[:0], c.tail...), b...)
+	if bytes.Contains(c.buf, usageMarker) {
+		for _, match := range usagePattern.FindAllSubmatch(c.buf, -1) {
+			value, _ := strconv.Atoi(string(match[2]))
+			switch string(match[1]) {
+			case "prompt_tokens":
+				c.counts[promptTokens] = value
+			case "completion_tokens":
+				c.counts[completionTokens] = value
+			case "total_tokens":
+				c.counts[totalTokens] = value
+			case "input_tokens":
+				c.counts[inputTokens] = value
+			case "output_tokens":
+				c.counts[outputTokens] = value
+			}
+		}
+	}
+	c.tail = append(c.tail[:0], c.buf[max(len(c.buf)-tailSize, 0):]...)
+}
+
+// Tokens returns the usage the response reported, or an estimate from the request and response sizes
+func (c *TokenCounter) Tokens(requestSize, responseSize int64) int {
+	if total := c.counts[totalTokens]; total > 0 {
+		return total
+	}
+	if used := c.counts[inputTokens] + c.counts[outputTokens]; used > 0 {
+		return used
+	}
+	return int((max(requestSize, 0) + max(responseSize, 0)) / BytesPerToken)
+}
+
+// Split returns the prompt and completion tokens the response reported, or estimates from the
+// request and response sizes
+func (c *TokenCounter) Split(requestSize, responseSize int64) (prompt, completion int) {
+	prompt = max(c.counts[promptTokens], c.counts[inputTokens])
+	completion = max(c.counts[completionTokens], c.counts[outputTokens])
+	if prompt+completion > 0 {
+		return prompt, completion
+	}
+	return int(max(requestSize, 0) / BytesPerToken), int(max(responseSize, 0) / BytesPerToken)
+}
@@ -0,0 +1,104 @@
+package usage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenCounter(t *testing.T) {
+	tests := []struct {
+		name           string
+		writes         []string
+		wantTokens     int
+		wantPrompt     int
+		wantCompletion int
+	}{
+		{
+			name:           "openai usage",
+			writes:         []string{`{"usage":{"prompt_tokens":12,"completion_tokens":30,"total_tokens":42}}`},
+			wantTokens:     42,
+			wantPrompt:     12,
+			wantCompletion: 30,
+		},
+		{
+			name:           "anthropic usage split across writes",
+			writes:         []string{`event: message_start` + "\n" + `data: {"usage":{"input_tok`, `ens":10,"output_tokens":1}}`, `data: {"usage":{"output_tokens":25}}`},
+			wantTokens:     35,
+			wantPrompt:     10,
+			wantCompletion: 25,
+		},
+		{
+			name:           "no usage is estimated from sizes",
+			writes:         []string{"data: [DONE]"},
+			wantTokens:     10,
+			wantPrompt:     4,
+			wantCompletion: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter := NewTokenCounter()
+			defer counter.Release()
+			for _, w := range tt.writes {
+				counter.Scan([]byte(w))
+			}
+			assert.Equal(t, tt.wantTokens, counter.Tokens(16, 24))
+			prompt, completion := counter.Split(16, 24)
+			assert.Equal(t, tt.wantPrompt, prompt)
+			assert.Equal(t, tt.wantCompletion, completion)
+		})
+	}
+}
+
+func TestTokenCounterRelease(t *testing.T) {
+	counter := NewTokenCounter()
+	counter.Scan([]byte(`{"usage":{"total_tokens":`))
+	counter.Release()
+
+	// A recycled counter carries neither counts nor a partial field over
+	counter = NewTokenCounter()
+	defer counter.Release()
+	counter.Scan([]byte(`7}`))
+	assert.Equal(t, 0, counter.Tokens(0, 0))
+}
+
+// streamChunks is a streamed chat completion, one chunk per token then the usage chunk
+func streamChunks(n int) [][]byte {
+	chunks := make([][]byte, 0, n+1)
+	for i := 0; i < n; i++ {
+		chunks = append(chunks, []byte(fmt.Sprintf(`data: {"id":"chatcmpl-1","choices":[{"index":0,"delta":{"content":"token%d"}}]}`+"\n\n", i)))
+	}
+	chunks = append(chunks, []byte(`data: {"id":"chatcmpl-1","choices":[],"usage":{"prompt_tokens":120,"completion_tokens":500,"total_tokens":620}}`+"\n\n"))
+	return chunks
+}
+
+func BenchmarkTokenCounter_Stream(b *testing.B) {
+	chunks := streamChunks(500)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		counter := NewTokenCounter()
+		for _, chunk := range chunks {
+			counter.Scan(chunk)
+		}
+		_ = counter.Tokens(0, 0)
+		counter.Release()
+	}
+}
+
+func BenchmarkTokenCounter_Parallel(b *testing.B) {
+	chunks := streamChunks(500)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			counter := NewTokenCounter()
+			for _, chunk := range chunks {
+				counter.Scan(chunk)
+			}
+			counter.Release()
+		}
+	})
+}
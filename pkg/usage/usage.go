@@ -0,0 +1,185 @@
+// Package usage reads the token usage reported in responses and aggregates request, token and GPU
+// time accounting per model, per API key and per user.
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Counters aggregate the usage of a model or an API key
+type Counters struct {
+	Requests         int64   `json:"requests"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	GPUSeconds       float64 `json:"gpu_seconds"` // Request duration times the GPUs serving it
+}
+
+// add accumulates a record
+func (c *Counters) add(r Record) {
+	c.Requests++
+	c.PromptTokens += int64(r.PromptTokens)
+	c.CompletionTokens += int64(r.CompletionTokens)
+	c.GPUSeconds += r.GPUSeconds
+}
+
+// KeyUsage is the usage of an API key, in total and per model
+type KeyUsage struct {
+	Counters
+	Models map[string]*Counters `json:"models"`
+}
+
+// Summary is the usage accumulated since a point in time
+type Summary struct {
+	Since  time.Time            `json:"since"`
+	Total  Counters             `json:"total"`
+	Models map[string]*Counters `json:"models"`
+	Keys   map[string]*KeyUsage `json:"keys"`  // By tenant name, or key digest when keys are not validated
+	Users  map[string]*Counters `json:"users"` // By the user clients declare in requests
+}
+
+// Record is the usage of a single request
+type Record struct {
+	Model            string
+	Key              string
+	User             string // Empty when the request declares no user
+	PromptTokens     int
+	CompletionTokens int
+	GPUSeconds       float64
+}
+
+// Tracker accumulates usage records, safe for concurrent use
+type Tracker struct {
+	mu      sync.Mutex
+	summary Summary
+	dirty   bool // Records were added since the last snapshot
+}
+
+// NewTracker creates an empty tracker starting now
+func NewTracker() *Tracker {
+	return &Tracker{summary: newSummary(time.Now().UTC().Truncate(time.Second))}
+}
+
+func newSummary(since time.Time) Summary {
+	return Summary{
+		Since:  since,
+		Models: make(map[string]*Counters),
+		Keys:   make(map[string]*KeyUsage),
+		Users:  make(map[string]*Counters),
+	}
+}
+
+// Record adds the usage of a request
+func (t *Tracker) Record(r Record) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.summary.Total.add(r)
+
+	model, ok := t.summary.Models[r.Model]
+	if !ok {
+		model = &Counters{}
+		t.summary.Models[r.Model] = model
+	}
+	model.add(r)
+
+	key, ok := t.summary.Keys[r.Key]
+	if !ok {
+		key = &KeyUsage{Models: make(map[string]*Counters)}
+		t.summary.Keys[r.Key] = key
+	}
+	key.add(r)
+	keyModel, ok := key.Models[r.Model]
+	if !ok {
+		keyModel = &Counters{}
+		key.Models[r.Model] = keyModel
+	}
+	keyModel.add(r)
+
+	if r.User != "" {
+		user, ok := t.summary.Users[r.User]
+		if !ok {
+			user = &Counters{}
+			t.summary.Users[r.User] = user
+		}
+		user.add(r)
+	}
+
+	t.dirty = true
+}
+
+// Summary returns a copy of the accumulated usage
+func (t *Tracker) Summary() Summary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	summary := newSummary(t.summary.Since)
+	summary.Total = t.summary.Total
+	for name, counters := range t.summary.Models {
+		copied := *counters
+		summary.Models[name] = &copied
+	}
+	for name, key := range t.summary.Keys {
+		copied := &KeyUsage{Counters: key.Counters, Models: make(map[string]*Counters, len(key.Models))}
+		for model, counters := range key.Models {
+			modelCopy := *counters
+			copied.Models[model] = &modelCopy
+		}
+		summary.Keys[name] = copied
+	}
+	for name, counters := range t.summary.Users {
+		copied := *counters
+		summary.Users[name] = &copied
+	}
+	return summary
+}
+
+// Snapshot returns the usage as JSON when records were added since the last snapshot
+func (t *Tracker) Snapshot() ([]byte, bool, error) {
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return nil, false, nil
+	}
+	t.dirty = false
+	t.mu.Unlock()
+
+	data, err := json.Marshal(t.Summary())
+	if err != nil {
+		t.mu.Lock()
+		t.dirty = true
+		t.mu.Unlock()
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Restore replaces the usage with a persisted snapshot, so accounting survives restarts
+func (t *Tracker) Restore(data []byte) error {
+	summary := newSummary(time.Time{})
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return fmt.Errorf("invalid usage snapshot: %w", err)
+	}
+	if summary.Models == nil {
+		summary.Models = make(map[string]*Counters)
+	}
+	if summary.Keys == nil {
+		summary.Keys = make(map[string]*KeyUsage)
+	}
+	if summary.Users == nil {
+		summary.Users = make(map[string]*Counters)
+	}
+	for _, key := range summary.Keys {
+		if key.Models == nil {
+			key.Models = make(map[string]*Counters)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.summary = summary
+	t.dirty = false
+	return nil
+}
@@ -0,0 +1,55 @@
+package usage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerRecord(t *testing.T) {
+	tracker := NewTracker()
+	tracker.Record(Record{Model: "qwen", Key: "team-a", User: "alice", PromptTokens: 100, CompletionTokens: 20, GPUSeconds: 2})
+	tracker.Record(Record{Model: "qwen", Key: "team-b", PromptTokens: 10, CompletionTokens: 5, GPUSeconds: 1})
+	tracker.Record(Record{Model: "llama", Key: "team-a", User: "alice", PromptTokens: 50, CompletionTokens: 50, GPUSeconds: 4})
+
+	summary := tracker.Summary()
+	assert.Equal(t, Counters{Requests: 3, PromptTokens: 160, CompletionTokens: 75, GPUSeconds: 7}, summary.Total)
+	assert.Equal(t, Counters{Requests: 2, PromptTokens: 110, CompletionTokens: 25, GPUSeconds: 3}, *summary.Models["qwen"])
+
+	teamA := summary.Keys["team-a"]
+	require.NotNil(t, teamA)
+	assert.Equal(t, int64(2), teamA.Requests)
+	assert.Equal(t, int64(150), teamA.PromptTokens)
+	assert.Equal(t, Counters{Requests: 1, PromptTokens: 50, CompletionTokens: 50, GPUSeconds: 4}, *teamA.Models["llama"])
+
+	assert.Equal(t, Counters{Requests: 2, PromptTokens: 150, CompletionTokens: 70, GPUSeconds: 6}, *summary.Users["alice"])
+	assert.Len(t, summary.Users, 1, "requests without a user are only counted per key")
+
+	summary.Models["qwen"].Requests = 100
+	assert.Equal(t, int64(2), tracker.Summary().Models["qwen"].Requests, "summaries are copies")
+}
+
+func TestTrackerSnapshotRestore(t *testing.T) {
+	tracker := NewTracker()
+	_, changed, err := tracker.Snapshot()
+	require.NoError(t, err)
+	assert.False(t, changed, "nothing to persist without records")
+
+	tracker.Record(Record{Model: "qwen", Key: "team-a", PromptTokens: 100, CompletionTokens: 20, GPUSeconds: 2})
+	data, changed, err := tracker.Snapshot()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	_, changed, _ = tracker.Snapshot()
+	assert.False(t, changed, "a snapshot clears the pending records")
+
+	restored := NewTracker()
+	require.NoError(t, restored.Restore(data))
+	assert.Equal(t, tracker.Summary(), restored.Summary())
+
+	restored.Record(Record{Model: "qwen", Key: "team-a", PromptTokens: 1})
+	assert.Equal(t, int64(2), restored.Summary().Keys["team-a"].Models["qwen"].Requests)
+
+	assert.Error(t, restored.Restore([]byte("not json")))
+	assert.Equal(t, int64(2), restored.Summary().Total.Requests, "an invalid snapshot keeps the current usage")
+}
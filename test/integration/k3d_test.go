@@ -115,7 +115,7 @@ var _ = Describe("K3D Integration Tests", func() {
 	})
 
 	Describe("vLLM API Proxying", func() {
-		It("should proxy /v1/models request", func() {
+		It("should list models on /v1/models", func() {
 			resp, err := httpClient.Get(proxyURL + "/v1/models")
 			Expect(err).NotTo(HaveOccurred())
 			defer resp.Body.Close()
@@ -217,9 +217,10 @@ var _ = Describe("K3D Integration Tests", func() {
 
 			initialActivity := stats1["last_activity"]
 
-			// Make a request
+			// Make a request, listing models is served by the proxy and is not activity
 			time.Sleep(2 * time.Second)
-			_, err = httpClient.Get(proxyURL + "/v1/models")
+			_, err = httpClient.Post(proxyURL+"/v1/completions", "application/json",
+				bytes.NewBufferString(`{"model":"test-model","prompt":"Hello"}`))
 			Expect(err).NotTo(HaveOccurred())
 
 			// Get updated stats